@@ -28,6 +28,14 @@ const (
 	DlStrategy403    DlStrategy = "403"    // 拒绝响应
 )
 
+// ServerType 媒体服务器类型
+type ServerType string
+
+const (
+	ServerTypeEmby     ServerType = "emby"     // Emby
+	ServerTypeJellyfin ServerType = "jellyfin" // Jellyfin
+)
+
 // validPeStrategy 用于校验用户配置的策略是否合法
 var validPeStrategy = map[PeStrategy]struct{}{
 	PeStrategyOrigin: {}, PeStrategyReject: {},
@@ -38,10 +46,17 @@ var validDlStrategy = map[DlStrategy]struct{}{
 	DlStrategyOrigin: {}, DlStrategyDirect: {}, DlStrategy403: {},
 }
 
+// validServerType 用于校验用户配置的服务器类型是否合法
+var validServerType = map[ServerType]struct{}{
+	ServerTypeEmby: {}, ServerTypeJellyfin: {},
+}
+
 // Emby 相关配置
 type Emby struct {
 	// Emby 源服务器地址
 	Host string `yaml:"host"`
+	// ServerType 源服务器类型, emby 或 jellyfin
+	ServerType ServerType `yaml:"server-type"`
 	// rclone 或者 cd 的挂载目录
 	MountPath string `yaml:"mount-path"`
 	// EpisodesUnplayPrior 在获取剧集列表时是否将未播资源优先展示
@@ -64,6 +79,14 @@ func (e *Emby) Init() error {
 	if strs.AnyEmpty(e.Host) {
 		return errors.New("emby.host 配置不能为空")
 	}
+	e.ServerType = ServerType(strings.ToLower(strings.TrimSpace(string(e.ServerType))))
+	if strs.AnyEmpty(string(e.ServerType)) {
+		// 默认为 emby
+		e.ServerType = ServerTypeEmby
+	}
+	if _, ok := validServerType[e.ServerType]; !ok {
+		return fmt.Errorf("emby.server-type 配置错误, 有效值: %v", maps.Keys(validServerType))
+	}
 	if strs.AnyEmpty(string(e.ProxyErrorStrategy)) {
 		// 失败默认回源
 		e.ProxyErrorStrategy = PeStrategyOrigin
@@ -106,6 +129,11 @@ func (e *Emby) Init() error {
 	return nil
 }
 
+// IsJellyfin 源服务器是否是 jellyfin
+func (e *Emby) IsJellyfin() bool {
+	return e.ServerType == ServerTypeJellyfin
+}
+
 // Strm strm 配置
 type Strm struct {
 	// PathMap 远程路径映射
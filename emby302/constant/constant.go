@@ -34,6 +34,9 @@ const (
 	Reg_ItemDownload     = `(?i)^/.*items/\d+/download($|\?)`
 	Reg_ItemSyncDownload = `(?i)^/.*sync/jobitems/\d+/file($|\?)`
 
+	// jellyfin 的 item id 是 32 位的十六进制字符串, 可能带有 - 分隔符
+	Reg_JellyfinItemDownload = `(?i)^/.*items/[0-9a-f-]{32,36}/download($|\?)`
+
 	Reg_Images             = `(?i)^/.*images`
 	Reg_VideoModWebDefined = `(?i)^/web/modules/htmlvideoplayer/plugin.js`
 	Reg_Proxy2Origin       = `^/$|(?i)^.*(/web|/users|/artists|/genres|/similar|/shows|/system|/remote|/scheduledtasks)`
@@ -22,6 +22,11 @@ import (
 // 通过此 uri, 可以判断出客户端传递的 api_key 是否是被 emby 服务器认可的
 const AuthUri = "/emby/Auth/Keys"
 
+// JellyfinAuthUri jellyfin 鉴权地址
+//
+// jellyfin 的 /Auth/Keys 需要管理员权限, 改用任意合法 token 都能访问的系统信息接口
+const JellyfinAuthUri = "/System/Info"
+
 // validApiKeys 已经校验通过的 api_key, 下次就不再校验
 //
 // 这个 map 不会进行大小限制, 考虑到 emby 原服务器中合法的 api_key 个数不是无限个
@@ -41,6 +46,10 @@ const (
 	QueryTokenName     = "X-Emby-Token"
 	HeaderAuthName     = "Authorization"
 	HeaderFullAuthName = "X-Emby-Authorization"
+
+	// jellyfin 新版本的 api key 参数以及 token 请求头
+	QueryJellyfinApiKeyName = "ApiKey"
+	HeaderJellyfinTokenName = "X-MediaBrowser-Token"
 )
 
 const UnauthorizedResp = "Access token is invalid or expired."
@@ -59,6 +68,7 @@ func ApiKeyChecker() gin.HandlerFunc {
 		regexp.MustCompile(constant.Reg_PlaybackInfo),
 		regexp.MustCompile(constant.Reg_ItemDownload),
 		regexp.MustCompile(constant.Reg_ItemSyncDownload),
+		regexp.MustCompile(constant.Reg_JellyfinItemDownload),
 		regexp.MustCompile(constant.Reg_ProxyPlaylist),
 		regexp.MustCompile(constant.Reg_ProxyTs),
 		regexp.MustCompile(constant.Reg_ProxySubtitle),
@@ -90,7 +100,10 @@ func ApiKeyChecker() gin.HandlerFunc {
 		// 4 发出请求, 验证 api_key
 		u := config.C.Emby.Host + AuthUri
 		var header http.Header
-		if kType == Query {
+		if config.C.Emby.IsJellyfin() {
+			u = config.C.Emby.Host + JellyfinAuthUri
+			header = authHeader(kType, kName, apiKey)
+		} else if kType == Query {
 			u = urls.AppendArgs(u, kName, apiKey)
 		} else {
 			header = make(http.Header)
@@ -110,8 +123,8 @@ func ApiKeyChecker() gin.HandlerFunc {
 		}
 		respBody := strings.TrimSpace(string(bodyBytes))
 
-		// 5 判断是否被源服务器拒绝
-		if resp.StatusCode == http.StatusUnauthorized && respBody == UnauthorizedResp {
+		// 5 判断是否被源服务器拒绝, jellyfin 拒绝时响应体为空, 只判断状态码
+		if resp.StatusCode == http.StatusUnauthorized && (respBody == UnauthorizedResp || config.C.Emby.IsJellyfin()) {
 			c.String(http.StatusUnauthorized, "鉴权失败")
 			c.Abort()
 			return
@@ -141,6 +154,12 @@ func getApiKey(c *gin.Context) (keyType ApiKeyType, keyName string, apiKey strin
 		return
 	}
 
+	keyName = QueryJellyfinApiKeyName
+	apiKey = c.Query(keyName)
+	if strs.AllNotEmpty(apiKey) {
+		return
+	}
+
 	keyType = Header
 	apiKey = c.GetHeader(keyName)
 	if strs.AllNotEmpty(apiKey) {
@@ -174,5 +193,34 @@ func getApiKey(c *gin.Context) (keyType ApiKeyType, keyName string, apiKey strin
 		return
 	}
 
+	keyName = HeaderJellyfinTokenName
+	apiKey = c.GetHeader(keyName)
+	if strs.AllNotEmpty(apiKey) {
+		return
+	}
+
 	return
 }
+
+// authHeader 根据 api_key 信息构造请求源服务器时使用的鉴权请求头
+//
+// jellyfin 不识别 query 形式的 X-Emby-Token, 统一使用 MediaBrowser 格式的 Authorization 请求头
+func authHeader(keyType ApiKeyType, keyName, apiKey string) http.Header {
+	header := make(http.Header)
+	if strs.AnyEmpty(apiKey) {
+		return header
+	}
+	if config.C.Emby.IsJellyfin() {
+		header.Set(HeaderAuthName, `MediaBrowser Token="`+apiKey+`"`)
+		return header
+	}
+	switch keyType {
+	case Header:
+		// 带上请求头的 api key
+		header.Set(keyName, apiKey)
+	case Query:
+		// 如果是 query 格式的 api key, 则往请求头中补充信息
+		header.Set(HeaderFullAuthName, "Token="+apiKey)
+	}
+	return header
+}
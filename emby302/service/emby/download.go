@@ -110,6 +110,7 @@ func DownloadStrategyChecker() gin.HandlerFunc {
 	var downloadRoutes = []*regexp.Regexp{
 		regexp.MustCompile(constant.Reg_ItemDownload),
 		regexp.MustCompile(constant.Reg_ItemSyncDownload),
+		regexp.MustCompile(constant.Reg_JellyfinItemDownload),
 	}
 
 	return func(c *gin.Context) {
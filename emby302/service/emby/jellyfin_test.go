@@ -0,0 +1,77 @@
+package emby
+
+import (
+	"Q115-STRM/emby302/config"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 使用指定的服务器类型, 测试结束后恢复
+func setServerType(t *testing.T, serverType config.ServerType) {
+	t.Helper()
+	old := config.C
+	config.C = &config.Config{Emby: &config.Emby{ServerType: serverType}}
+	t.Cleanup(func() {
+		config.C = old
+	})
+}
+
+func TestAuthHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverType config.ServerType
+		keyType    ApiKeyType
+		keyName    string
+		apiKey     string
+		want       map[string]string
+	}{
+		{"emby 请求头", config.ServerTypeEmby, Header, "X-Emby-Token", "abc", map[string]string{"X-Emby-Token": "abc"}},
+		{"emby query", config.ServerTypeEmby, Query, QueryApiKeyName, "abc", map[string]string{HeaderFullAuthName: "Token=abc"}},
+		{"jellyfin 请求头", config.ServerTypeJellyfin, Header, HeaderJellyfinTokenName, "abc", map[string]string{HeaderAuthName: `MediaBrowser Token="abc"`}},
+		{"jellyfin query", config.ServerTypeJellyfin, Query, QueryApiKeyName, "abc", map[string]string{HeaderAuthName: `MediaBrowser Token="abc"`}},
+		{"没有 api_key", config.ServerTypeJellyfin, Query, QueryApiKeyName, "", map[string]string{}},
+	}
+	for _, tt := range tests {
+		setServerType(t, tt.serverType)
+		header := authHeader(tt.keyType, tt.keyName, tt.apiKey)
+		if len(header) != len(tt.want) {
+			t.Errorf("%s: authHeader = %v, want %v", tt.name, header, tt.want)
+			continue
+		}
+		for key, value := range tt.want {
+			if got := header.Get(key); got != value {
+				t.Errorf("%s: %s = %q, want %q", tt.name, key, got, value)
+			}
+		}
+	}
+}
+
+func TestPlaybackInfoRequestBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		serverType config.ServerType
+		body       string
+		wantBody   string
+		wantOrigin string
+	}{
+		{"emby 使用通用的 DeviceProfile", config.ServerTypeEmby, `{"DeviceProfile":{}}`, PlaybackCommonPayload, `{"DeviceProfile":{}}`},
+		{"jellyfin 沿用原始请求体", config.ServerTypeJellyfin, `{"DeviceProfile":{}}`, `{"DeviceProfile":{}}`, `{"DeviceProfile":{}}`},
+		{"jellyfin 空请求体", config.ServerTypeJellyfin, "", "{}", ""},
+	}
+	for _, tt := range tests {
+		setServerType(t, tt.serverType)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/Items/1/PlaybackInfo", strings.NewReader(tt.body))
+		body, origin := playbackInfoRequestBody(c)
+		gotBody, _ := io.ReadAll(body)
+		gotOrigin, _ := io.ReadAll(origin)
+		if string(gotBody) != tt.wantBody || string(gotOrigin) != tt.wantOrigin {
+			t.Errorf("%s: playbackInfoRequestBody = %q, %q, want %q, %q", tt.name, gotBody, gotOrigin, tt.wantBody, tt.wantOrigin)
+		}
+	}
+}
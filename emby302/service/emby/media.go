@@ -34,15 +34,7 @@ const MediaSourceIdSegment = "[[_]]"
 // uri 中必须有 query 参数 MediaSourceId,
 // 如果没有携带该参数, 可能会请求到多个媒体, 默认返回第一个媒体的本地路径
 func getEmbyFileLocalPath(itemInfo ItemInfo) (string, error) {
-	var header http.Header
-	switch itemInfo.ApiKeyType {
-	case Header:
-		// 带上请求头的 api key
-		header = http.Header{itemInfo.ApiKeyName: []string{itemInfo.ApiKey}}
-	case Query:
-		// 如果是 query 格式的 api key, 则往请求头中补充信息
-		header = http.Header{HeaderFullAuthName: []string{"Token=" + itemInfo.ApiKey}}
-	}
+	header := authHeader(itemInfo.ApiKeyType, itemInfo.ApiKeyName, itemInfo.ApiKey)
 
 	innerRequest := func(method string) (*http.Response, error) {
 		resp, err := https.Request(method, config.C.Emby.Host+itemInfo.PlaybackInfoUri).Header(header).Do()
@@ -405,8 +397,8 @@ func resolveItemInfo(c *gin.Context, routeType RouteType) (ItemInfo, error) {
 		return ItemInfo{}, fmt.Errorf("构建 PlaybackInfo uri 失败, err: %v", err)
 	}
 	q := u.Query()
-	// 默认只携带 query 形式的 api key
-	if itemInfo.ApiKeyType == Query {
+	// 默认只携带 query 形式的 api key, jellyfin 统一通过请求头鉴权
+	if itemInfo.ApiKeyType == Query && !config.C.Emby.IsJellyfin() {
 		q.Set(itemInfo.ApiKeyName, itemInfo.ApiKey)
 	}
 	q.Set("reqformat", "json")
@@ -429,10 +421,11 @@ func getRequestMediaSourceId(c *gin.Context) string {
 		return ""
 	}
 
-	// 1 从请求参数中获取
-	q := c.Query("MediaSourceId")
-	if strs.AllNotEmpty(q) {
-		return q
+	// 1 从请求参数中获取, jellyfin 客户端使用的是小写开头的 mediaSourceId
+	for _, key := range []string{"MediaSourceId", "mediaSourceId"} {
+		if q := c.Query(key); strs.AllNotEmpty(q) {
+			return q
+		}
 	}
 
 	// 2 从请求体中获取
@@ -458,7 +451,8 @@ func resolveMediaSourceId(id string) (MsInfo, error) {
 	}
 	res.Empty = false
 
-	if len(id) <= 32 {
+	// jellyfin 的 id 可能带有 - 分隔符, 超过 32 位, 不含自定义分隔符时都视为原始 id
+	if len(id) <= 32 || !strings.Contains(id, MediaSourceIdSegment) {
 		res.OriginId = id
 		return res, nil
 	}
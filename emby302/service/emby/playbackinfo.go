@@ -66,8 +66,8 @@ func TransferPlaybackInfo(c *gin.Context) {
 
 	// 2 请求 emby 源服务器的 PlaybackInfo 信息
 	c.Request.Header.Del("Accept-Encoding")
-	originRequestBody := c.Request.Body
-	c.Request.Body = io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))
+	reqBody, originRequestBody := playbackInfoRequestBody(c)
+	c.Request.Body = reqBody
 	res, respHeader := RawFetch(itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
 	if res.Code != http.StatusOK {
 		checkErr(c, errors.New(res.Msg))
@@ -126,9 +126,14 @@ func TransferPlaybackInfo(c *gin.Context) {
 		// 转换直链链接
 		source.Put("SupportsDirectPlay", jsons.FromValue(true))
 		source.Put("SupportsDirectStream", jsons.FromValue(true))
+		apiKeyName := itemInfo.ApiKeyName
+		if config.C.Emby.IsJellyfin() {
+			// jellyfin 的请求头 token 无法作为 query 参数识别, 统一使用 api_key
+			apiKeyName = QueryApiKeyName
+		}
 		newUrl := fmt.Sprintf(
 			"/videos/%s/stream?MediaSourceId=%s&%s=%s&Static=true",
-			itemInfo.Id, source.Attr("Id").Val(), itemInfo.ApiKeyName, itemInfo.ApiKey,
+			itemInfo.Id, source.Attr("Id").Val(), apiKeyName, itemInfo.ApiKey,
 		)
 		if strings.Contains(path, "baidupan/url") {
 			logs.Success("发现百度网盘链接，发送一个请求拿到直链：%s", path)
@@ -193,6 +198,23 @@ func TransferPlaybackInfo(c *gin.Context) {
 	jsons.OkResp(c.Writer, resJson)
 }
 
+// playbackInfoRequestBody 构造请求源服务器 PlaybackInfo 接口的请求体
+//
+// emby 使用通用的 DeviceProfile 防止客户端转码,
+// jellyfin 的 DeviceProfile 结构与 emby 不兼容, 沿用客户端的原始请求体, 直链信息在响应中统一改写
+//
+// 第二个返回值是可以再次使用的原始请求体, 用于回源
+func playbackInfoRequestBody(c *gin.Context) (io.ReadCloser, io.ReadCloser) {
+	if !config.C.Emby.IsJellyfin() {
+		return io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload)), c.Request.Body
+	}
+	bodyBytes, originBody, err := https.ExtractReqBody(c.Request.Body)
+	if err != nil || len(bodyBytes) == 0 {
+		return io.NopCloser(bytes.NewBufferString("{}")), io.NopCloser(bytes.NewBuffer(bodyBytes))
+	}
+	return io.NopCloser(bytes.NewBuffer(bodyBytes)), originBody
+}
+
 // handleSpecialPlayback 判断如果请求的 PlaybackInfo 信息是特殊类型, 直接代理回源服务
 func handleSpecialPlayback(c *gin.Context, itemInfo ItemInfo) bool {
 	// 请求必须携带 MediaSourceId
@@ -201,8 +223,8 @@ func handleSpecialPlayback(c *gin.Context, itemInfo ItemInfo) bool {
 	}
 
 	c.Request.Header.Del("Accept-Encoding")
-	originRequestBody := c.Request.Body
-	c.Request.Body = io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))
+	reqBody, originRequestBody := playbackInfoRequestBody(c)
+	c.Request.Body = reqBody
 	res, _ := RawFetch(itemInfo.PlaybackInfoUri, c.Request.Method, c.Request.Header, c.Request.Body)
 	if res.Code != http.StatusOK {
 		return false
@@ -479,12 +501,17 @@ func fetchFullPlaybackInfo(itemInfo ItemInfo) (*jsons.Item, error) {
 	q.Del("MediaSourceId")
 	u.RawQuery = q.Encode()
 
-	reqBody := io.NopCloser(bytes.NewBufferString(PlaybackCommonPayload))
+	payload := PlaybackCommonPayload
 	header := make(http.Header)
-	header.Set("Content-Type", "text/plain")
-	if itemInfo.ApiKeyType == Header {
+	if config.C.Emby.IsJellyfin() {
+		// jellyfin 不使用 emby 的 DeviceProfile, api key 统一放在请求头中
+		payload = "{}"
+		header = authHeader(itemInfo.ApiKeyType, itemInfo.ApiKeyName, itemInfo.ApiKey)
+	} else if itemInfo.ApiKeyType == Header {
 		header.Set(itemInfo.ApiKeyName, itemInfo.ApiKey)
 	}
+	header.Set("Content-Type", "text/plain")
+	reqBody := io.NopCloser(bytes.NewBufferString(payload))
 	resp, err := https.Post(u.String()).Header(header).Body(reqBody).Do()
 	if err != nil {
 		return nil, fmt.Errorf("获取全量 PlaybackInfo 失败: %v", err)
@@ -134,6 +134,11 @@ func Redirect2OpenlistLink(c *gin.Context) {
 	// \\开头是Emby网络共享地址
 	if strings.HasPrefix(embyPath, "/") || matchedWin || strings.HasPrefix(embyPath, "\\") || isProxyUrl != "" {
 		logs.Info("本地或代理路径: %s, 回源处理", embyPath)
+		if config.C.Emby.IsJellyfin() {
+			// jellyfin 没有 original 接口, stream 接口本身就支持静态文件
+			ProxyOrigin(c)
+			return
+		}
 		newUri := strings.Replace(c.Request.RequestURI, "stream", "original", 1)
 		newUri = strings.Replace(newUri, "universal", "original", 1)
 		c.Redirect(http.StatusTemporaryRedirect, newUri)
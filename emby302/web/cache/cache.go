@@ -85,6 +85,7 @@ func RequestCacher() gin.HandlerFunc {
 
 		// 3 尝试获取缓存
 		if rc, ok := getCache(cacheKey); ok {
+			hitCount.Add(1)
 			if https.IsRedirectCode(rc.code) {
 				// 适配重定向请求
 				c.Redirect(rc.code, rc.header.header.Get("Location"))
@@ -97,6 +98,8 @@ func RequestCacher() gin.HandlerFunc {
 			return
 		}
 
+		missCount.Add(1)
+
 		// 4 使用自定义的响应器
 		customWriter := &respCacheWriter{body: &bytes.Buffer{}, ResponseWriter: c.Writer}
 		c.Writer = customWriter
@@ -0,0 +1,26 @@
+package cache
+
+import "sync/atomic"
+
+// hitCount 缓存命中次数
+var hitCount atomic.Int64
+
+// missCount 可缓存请求未命中的次数
+var missCount atomic.Int64
+
+// Stats 请求缓存的命中统计
+type Stats struct {
+	Hits    int64 // 命中次数
+	Misses  int64 // 未命中次数
+	Entries int   // 当前缓存的请求数
+}
+
+// GetStats 获取请求缓存的命中统计
+func GetStats() Stats {
+	entries := 0
+	cacheMap.Range(func(key, value any) bool {
+		entries++
+		return true
+	})
+	return Stats{Hits: hitCount.Load(), Misses: missCount.Load(), Entries: entries}
+}
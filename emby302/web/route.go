@@ -1,6 +1,7 @@
 package web
 
 import (
+	"Q115-STRM/emby302/config"
 	"Q115-STRM/emby302/constant"
 	"Q115-STRM/emby302/service/emby"
 	"Q115-STRM/emby302/service/m3u8"
@@ -16,6 +17,11 @@ var rules [][2]any
 
 func initRulePatterns() {
 	logs.Info("正在初始化路由规则...")
+	if config.C.Emby.IsJellyfin() {
+		rules = compileRules(jellyfinRules())
+		logs.Success("jellyfin 路由规则初始化完成")
+		return
+	}
 	rules = compileRules([][2]any{
 		// websocket
 		{constant.Reg_Socket, emby.ProxySocket()},
@@ -90,6 +96,47 @@ func initRulePatterns() {
 	logs.Success("路由规则初始化完成")
 }
 
+// jellyfinRules jellyfin 的路由拦截规则
+//
+// 只拦截播放相关的接口, emby 特有的接口 (original, sync 下载, 网页播放器插件等)
+// 以及依赖 emby 数据结构的列表改写都直接回源
+func jellyfinRules() [][2]any {
+	return [][2]any{
+		// websocket
+		{constant.Reg_Socket, emby.ProxySocket()},
+
+		// PlaybackInfo 接口
+		{constant.Reg_PlaybackInfo, emby.TransferPlaybackInfo},
+
+		// 字幕长时间缓存
+		{constant.Reg_VideoSubtitles, emby.ProxySubtitles},
+
+		// 资源重定向到直链
+		{constant.Reg_ResourceStream, emby.Redirect2OpenlistLink},
+		// master 重定向到本地 m3u8 代理, 非 openlist 转码资源回源
+		{constant.Reg_ResourceMaster, emby.Redirect2Transcode},
+		{constant.Reg_ResourceMain, emby.Redirect2Transcode},
+		// m3u8 转码播放列表
+		{constant.Reg_ProxyPlaylist, m3u8.ProxyPlaylist},
+		// ts 重定向到直链
+		{constant.Reg_ProxyTs, m3u8.ProxyTsLink},
+		// m3u8 字幕
+		{constant.Reg_ProxySubtitle, m3u8.ProxySubtitle},
+
+		// 资源下载, 重定向到直链
+		{constant.Reg_JellyfinItemDownload, emby.Redirect2OpenlistLink},
+
+		// 处理图片请求
+		{constant.Reg_Images, emby.HandleImages},
+
+		// 根路径重定向到首页
+		{constant.Reg_Root, emby.ProxyRoot},
+
+		// 其余资源走重定向回源
+		{constant.Reg_All, emby.ProxyOrigin},
+	}
+}
+
 // initRoutes 初始化路由
 func initRoutes(r *gin.Engine) {
 	r.Any("/*vars", globalDftHandler)
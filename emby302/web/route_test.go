@@ -0,0 +1,45 @@
+package web
+
+import (
+	"Q115-STRM/emby302/constant"
+	"regexp"
+	"testing"
+)
+
+func TestJellyfinRules(t *testing.T) {
+	rs := jellyfinRules()
+	// 返回第一个匹配的规则, 与 globalDftHandler 的匹配顺序一致
+	match := func(uri string) string {
+		for _, rule := range rs {
+			pattern := rule[0].(string)
+			if regexp.MustCompile(pattern).MatchString(uri) {
+				return pattern
+			}
+		}
+		return ""
+	}
+	tests := []struct {
+		name string
+		uri  string
+		want string
+	}{
+		{"PlaybackInfo", "/Items/0123456789abcdef0123456789abcdef/PlaybackInfo?UserId=1", constant.Reg_PlaybackInfo},
+		{"直链播放", "/Videos/0123456789abcdef0123456789abcdef/stream.mkv?Static=true", constant.Reg_ResourceStream},
+		{"转码 master", "/videos/0123456789abcdef0123456789abcdef/master.m3u8", constant.Reg_ResourceMaster},
+		{"转码 main", "/videos/0123456789abcdef0123456789abcdef/main.m3u8", constant.Reg_ResourceMain},
+		{"字幕", "/Videos/0123456789abcdef0123456789abcdef/0123/Subtitles/1/Stream.srt", constant.Reg_VideoSubtitles},
+		{"下载", "/Items/01234567-89ab-cdef-0123-456789abcdef/Download", constant.Reg_JellyfinItemDownload},
+		{"图片", "/Items/0123456789abcdef0123456789abcdef/Images/Primary", constant.Reg_Images},
+		{"根路径", "/", constant.Reg_Root},
+		// emby 特有的接口直接回源
+		{"original 资源", "/Videos/123/original.mkv", constant.Reg_All},
+		{"sync 下载", "/Sync/JobItems/1/File", constant.Reg_All},
+		{"emby 下载", "/Items/123/Download", constant.Reg_All},
+		{"Items 列表", "/Users/1/Items/123", constant.Reg_All},
+	}
+	for _, tt := range tests {
+		if got := match(tt.uri); got != tt.want {
+			t.Errorf("%s: %s 匹配规则 %s, want %s", tt.name, tt.uri, got, tt.want)
+		}
+	}
+}
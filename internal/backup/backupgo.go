@@ -6,6 +6,7 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/synccron"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -95,7 +96,7 @@ func startAllTasks() error {
 
 // 遍历每一个模型，生成json格式的备份文件
 func Backup(backupType string, reason string) error {
-	totalTable := 35
+	totalTable := 37
 	count := 0
 	// config := models.GetOrCreateBackupConfig()
 	backupDir := filepath.Join(helpers.ConfigDir, "backups")
@@ -229,10 +230,16 @@ func Backup(backupType string, reason string) error {
 	if err := backupToJsonFile(backupRecordDir, "BackupRecord", totalTable, &count, models.BackupRecord{}); err != nil {
 		return err
 	}
+	if err := backupToJsonFile(backupRecordDir, "BackupTarget", totalTable, &count, models.BackupTarget{}); err != nil {
+		return err
+	}
 
 	if err := backupToJsonFile(backupRecordDir, "BarkChannelConfig", totalTable, &count, models.BarkChannelConfig{}); err != nil {
 		return err
 	}
+	if err := backupToJsonFile(backupRecordDir, "CategoryRule", totalTable, &count, models.CategoryRule{}); err != nil {
+		return err
+	}
 	if err := backupToJsonFile(backupRecordDir, "CustomWebhookChannelConfig", totalTable, &count, models.CustomWebhookChannelConfig{}); err != nil {
 		return err
 	}
@@ -267,6 +274,15 @@ func Backup(backupType string, reason string) error {
 		helpers.AppLogger.Errorf("打包备份目录失败: %v", err)
 		return err
 	}
+	// 加密备份文件，没有开启加密或者加密失败时不上传到异地备份目标，避免未加密的备份离开本机
+	filePath, encErr := encryptForUpload(filePath, models.GetBackupService().GetBackupConfig())
+	if errors.Is(encErr, errBackupNotEncrypted) {
+		if len(models.GetEnabledBackupTargets()) > 0 {
+			helpers.AppLogger.Warnf("没有开启备份加密，备份文件中包含账号token，跳过上传到备份目标")
+		}
+	} else if encErr != nil {
+		helpers.AppLogger.Errorf("%v，跳过上传到备份目标", encErr)
+	}
 	stat, err := os.Stat(filePath)
 	if err != nil {
 		helpers.AppLogger.Errorf("获取备份文件状态失败: %v", err)
@@ -279,6 +295,10 @@ func Backup(backupType string, reason string) error {
 	db.Db.Save(record)
 	// 删除目录
 	os.RemoveAll(backupRecordDir)
+	// 上传比较耗时，在恢复所有任务之后进行
+	if encErr == nil {
+		go uploadToTargets(filePath)
+	}
 	return nil
 }
 
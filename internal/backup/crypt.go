@@ -0,0 +1,103 @@
+package backup
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// 加密后的备份文件在zip后面加上这个扩展名
+const encryptedExt = ".enc"
+
+// 加密备份压缩包，成功后删除未加密的压缩包，返回加密文件的路径
+func encryptBackupFile(zipPath string, passphrase string) (string, error) {
+	encPath := zipPath + encryptedExt
+	src, err := os.Open(zipPath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	dst, err := os.Create(encPath)
+	if err != nil {
+		return "", err
+	}
+	if err := helpers.EncryptWithPassphrase(dst, src, passphrase); err != nil {
+		dst.Close()
+		os.Remove(encPath)
+		return "", fmt.Errorf("加密备份文件失败: %v", err)
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(encPath)
+		return "", err
+	}
+	src.Close()
+	os.Remove(zipPath)
+	return encPath, nil
+}
+
+// 没有开启备份加密，这时不上传到异地备份目标
+var errBackupNotEncrypted = errors.New("没有开启备份加密")
+
+// 按备份设置加密备份文件，只有加密成功的文件才能上传到异地备份目标
+// 返回错误时文件没有加密，返回的路径仍然是原文件
+func encryptForUpload(zipPath string, config *models.BackupConfig) (string, error) {
+	if config.BackupEncrypt != 1 {
+		return zipPath, errBackupNotEncrypted
+	}
+	if config.BackupPassphrase == "" {
+		return zipPath, errors.New("已开启备份加密但是没有设置加密密码，备份文件未加密")
+	}
+	encPath, err := encryptBackupFile(zipPath, config.BackupPassphrase)
+	if err != nil {
+		return zipPath, err
+	}
+	return encPath, nil
+}
+
+// IsEncryptedBackup 根据文件头判断备份文件是否已加密
+func IsEncryptedBackup(filePath string) bool {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	return helpers.IsPassphraseEncrypted(header)
+}
+
+// 解密备份文件到同目录下的临时zip文件，passphrase为空时使用备份设置中的密码
+func decryptBackupFile(filePath string, passphrase string) (string, error) {
+	if passphrase == "" {
+		passphrase = models.GetBackupService().GetBackupConfig().BackupPassphrase
+	}
+	if passphrase == "" {
+		return "", fmt.Errorf("备份文件已加密，请提供加密密码")
+	}
+	src, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	zipPath := strings.TrimSuffix(filePath, encryptedExt) + ".decrypted.zip"
+	dst, err := os.Create(zipPath)
+	if err != nil {
+		return "", err
+	}
+	if err := helpers.DecryptWithPassphrase(dst, src, passphrase); err != nil {
+		dst.Close()
+		os.Remove(zipPath)
+		return "", err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(zipPath)
+		return "", err
+	}
+	return zipPath, nil
+}
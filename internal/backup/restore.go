@@ -14,7 +14,12 @@ import (
 
 // 从文件还原到数据库
 func Restore(filePath string) error {
-	totalTable := 35
+	return RestoreWithPassphrase(filePath, "")
+}
+
+// RestoreWithPassphrase 从备份文件还原到数据库，加密的备份文件使用passphrase解密，为空时使用备份设置中的密码
+func RestoreWithPassphrase(filePath string, passphrase string) error {
+	totalTable := 37
 	count := 0
 	// 检查是否正在运行
 	if IsRunning() {
@@ -35,8 +40,21 @@ func Restore(filePath string) error {
 		return fmt.Errorf("创建临时目录失败: %v", err)
 	}
 	defer os.RemoveAll(tempDir)
+	// 加密的备份先解密成zip
+	zipPath := filePath
+	if IsEncryptedBackup(filePath) {
+		decrypted, err := decryptBackupFile(filePath, passphrase)
+		if err != nil {
+			SetRunningResult("restore", "解密备份文件失败", totalTable, count, err.Error(), true)
+			return err
+		}
+		defer os.Remove(decrypted)
+		zipPath = decrypted
+	}
+	// 还原后的备份设置中没有加密密码，先记下当前的密码
+	currentPassphrase := models.GetBackupService().GetBackupConfig().BackupPassphrase
 	// 解压文件
-	if err := helpers.ExtractZip(filePath, tempDir); err != nil {
+	if err := helpers.ExtractZip(zipPath, tempDir); err != nil {
 		return fmt.Errorf("解压文件失败: %v", err)
 	}
 	// 开始还原
@@ -126,10 +144,16 @@ func Restore(filePath string) error {
 	if err := restoreFromJsonFile(tempDir, "BackupRecord", totalTable, &count, models.BackupRecord{}); err != nil {
 		return err
 	}
+	if err := restoreFromJsonFile(tempDir, "BackupTarget", totalTable, &count, models.BackupTarget{}); err != nil {
+		return err
+	}
 
 	if err := restoreFromJsonFile(tempDir, "BarkChannelConfig", totalTable, &count, models.BarkChannelConfig{}); err != nil {
 		return err
 	}
+	if err := restoreFromJsonFile(tempDir, "CategoryRule", totalTable, &count, models.CategoryRule{}); err != nil {
+		return err
+	}
 	if err := restoreFromJsonFile(tempDir, "CustomWebhookChannelConfig", totalTable, &count, models.CustomWebhookChannelConfig{}); err != nil {
 		return err
 	}
@@ -152,6 +176,12 @@ func Restore(filePath string) error {
 	if err := restoreFromJsonFile(tempDir, "Migrator", totalTable, &count, models.Migrator{}); err != nil {
 		return err
 	}
+	// 恢复加密密码并刷新备份服务中缓存的设置
+	config := models.GetOrCreateBackupConfig()
+	config.BackupPassphrase = currentPassphrase
+	if err := models.GetBackupService().UpdateBackupConfig(config); err != nil {
+		helpers.AppLogger.Warnf("恢复备份加密密码失败: %v", err)
+	}
 	helpers.AppLogger.Infof("完成恢复任务")
 	return nil
 }
@@ -0,0 +1,378 @@
+package backup
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/openlist"
+	"Q115-STRM/internal/s3"
+	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RemoteBackup 备份目标中的备份文件
+type RemoteBackup struct {
+	Id       string `json:"id"`       // 115网盘的文件ID，其他网盘为完整路径
+	Name     string `json:"name"`     // 文件名
+	Size     int64  `json:"size"`     // 文件大小
+	ModTime  int64  `json:"mod_time"` // 修改时间
+	PickCode string `json:"-"`        // 115网盘下载文件使用的提取码
+}
+
+// 各种网盘的备份目标都实现这个接口
+type targetStorage interface {
+	Upload(ctx context.Context, localFile string) error
+	List(ctx context.Context) ([]*RemoteBackup, error)
+	Download(ctx context.Context, file *RemoteBackup, localFile string) error
+	Delete(ctx context.Context, file *RemoteBackup) error
+}
+
+func newTargetStorage(target *models.BackupTarget) (targetStorage, error) {
+	account, err := target.GetAccount()
+	if err != nil {
+		return nil, err
+	}
+	switch target.SourceType {
+	case models.SourceType115:
+		client := account.Get115Client()
+		if client == nil {
+			return nil, fmt.Errorf("账户 %s 115客户端不存在", account.Name)
+		}
+		return &storage115{client: client, cid: target.RemotePathId}, nil
+	case models.SourceTypeOpenList:
+		client := account.GetOpenListClient()
+		if client == nil {
+			return nil, fmt.Errorf("账户 %s OpenList客户端不存在", account.Name)
+		}
+		return &storageOpenList{client: client, dir: cleanRemoteDir(target.RemotePath)}, nil
+	case models.SourceTypeWebDav:
+		return &storageWebDav{client: account.GetWebDavClient(), dir: cleanRemoteDir(target.RemotePath)}, nil
+	case models.SourceTypeS3:
+		return &storageS3{client: account.GetS3Client(), dir: cleanRemoteDir(target.RemotePath)}, nil
+	}
+	return nil, fmt.Errorf("不支持的备份目标类型: %s", target.SourceType)
+}
+
+func cleanRemoteDir(dir string) string {
+	return path.Clean("/" + strings.ReplaceAll(dir, "\\", "/"))
+}
+
+// 只处理本程序生成的备份文件，避免误删目录中的其他文件
+func isBackupFileName(name string) bool {
+	return strings.HasPrefix(name, "backup_") && (strings.HasSuffix(name, ".zip") || strings.HasSuffix(name, encryptedExt))
+}
+
+// 下载链接的内容保存到本地文件
+func downloadUrlToFile(ctx context.Context, url, ua, localFile string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载备份文件失败: %s", resp.Status)
+	}
+	f, err := os.Create(localFile)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+type storage115 struct {
+	client *v115open.OpenClient
+	cid    string
+}
+
+func (s *storage115) Upload(ctx context.Context, localFile string) error {
+	// 备份文件可能比较大，使用分片上传
+	fileId, err := s.client.UploadResume(ctx, localFile, s.cid, nil, nil)
+	if err != nil {
+		return err
+	}
+	if fileId == "" {
+		return fmt.Errorf("115上传文件 %s 失败: 返回空文件ID", filepath.Base(localFile))
+	}
+	return nil
+}
+
+func (s *storage115) List(ctx context.Context) ([]*RemoteBackup, error) {
+	files := make([]*RemoteBackup, 0)
+	offset, limit := 0, 1000
+	for {
+		resp, err := s.client.GetFsList(ctx, s.cid, true, false, false, offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range resp.Data {
+			if f.FileCategory != v115open.TypeFile || !isBackupFileName(f.FileName) {
+				continue
+			}
+			files = append(files, &RemoteBackup{Id: f.FileId, Name: f.FileName, Size: f.FileSize, ModTime: f.Ptime, PickCode: f.PickCode})
+		}
+		offset += len(resp.Data)
+		if len(resp.Data) == 0 || offset >= resp.Count {
+			break
+		}
+	}
+	return files, nil
+}
+
+func (s *storage115) Download(ctx context.Context, file *RemoteBackup, localFile string) error {
+	url := s.client.GetDownloadUrl(ctx, file.PickCode, v115open.DEFAULTUA, false)
+	if url == "" {
+		return fmt.Errorf("获取115下载链接失败: %s", file.Name)
+	}
+	return downloadUrlToFile(ctx, url, v115open.DEFAULTUA, localFile)
+}
+
+func (s *storage115) Delete(ctx context.Context, file *RemoteBackup) error {
+	_, err := s.client.Del(ctx, []string{file.Id}, s.cid)
+	return err
+}
+
+type storageOpenList struct {
+	client *openlist.Client
+	dir    string
+}
+
+func (s *storageOpenList) Upload(ctx context.Context, localFile string) error {
+	// 目录已存在时OpenList会返回错误，忽略即可，上传失败时会再报告
+	s.client.Mkdir(s.dir)
+	_, err := s.client.Upload(localFile, path.Join(s.dir, filepath.Base(localFile)))
+	return err
+}
+
+func (s *storageOpenList) List(ctx context.Context) ([]*RemoteBackup, error) {
+	files := make([]*RemoteBackup, 0)
+	for page := 1; ; page++ {
+		resp, err := s.client.FileList(ctx, s.dir, page, 200)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range resp.Content {
+			if f.IsDir || !isBackupFileName(f.Name) {
+				continue
+			}
+			modTime := int64(0)
+			if t, err := time.Parse(time.RFC3339, f.Modified); err == nil {
+				modTime = t.Unix()
+			}
+			files = append(files, &RemoteBackup{Id: path.Join(s.dir, f.Name), Name: f.Name, Size: f.Size, ModTime: modTime})
+		}
+		if len(resp.Content) == 0 || int64(page*200) >= resp.Total {
+			break
+		}
+	}
+	return files, nil
+}
+
+func (s *storageOpenList) Download(ctx context.Context, file *RemoteBackup, localFile string) error {
+	url := s.client.GetRawUrl(file.Id)
+	if url == "" {
+		return fmt.Errorf("获取OpenList下载链接失败: %s", file.Name)
+	}
+	return downloadUrlToFile(ctx, url, "", localFile)
+}
+
+func (s *storageOpenList) Delete(ctx context.Context, file *RemoteBackup) error {
+	return s.client.Del(s.dir, []string{file.Name})
+}
+
+type storageWebDav struct {
+	client *webdav.Client
+	dir    string
+}
+
+func (s *storageWebDav) Upload(ctx context.Context, localFile string) error {
+	if err := s.client.MkdirAll(ctx, s.dir); err != nil {
+		return err
+	}
+	return s.client.Upload(ctx, localFile, path.Join(s.dir, filepath.Base(localFile)))
+}
+
+func (s *storageWebDav) List(ctx context.Context) ([]*RemoteBackup, error) {
+	list, err := s.client.List(ctx, s.dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*RemoteBackup, 0, len(list))
+	for _, f := range list {
+		if f.IsDir || !isBackupFileName(f.Name) {
+			continue
+		}
+		files = append(files, &RemoteBackup{Id: f.Path, Name: f.Name, Size: f.Size, ModTime: f.ModTime.Unix()})
+	}
+	return files, nil
+}
+
+func (s *storageWebDav) Download(ctx context.Context, file *RemoteBackup, localFile string) error {
+	return s.client.Download(ctx, file.Id, localFile)
+}
+
+func (s *storageWebDav) Delete(ctx context.Context, file *RemoteBackup) error {
+	return s.client.Delete(ctx, file.Id)
+}
+
+type storageS3 struct {
+	client *s3.Client
+	dir    string
+}
+
+func (s *storageS3) Upload(ctx context.Context, localFile string) error {
+	return s.client.Upload(ctx, localFile, path.Join(s.dir, filepath.Base(localFile)))
+}
+
+func (s *storageS3) List(ctx context.Context) ([]*RemoteBackup, error) {
+	list, err := s.client.List(ctx, s.dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]*RemoteBackup, 0, len(list))
+	for _, f := range list {
+		if f.IsDir || !isBackupFileName(f.Name) {
+			continue
+		}
+		files = append(files, &RemoteBackup{Id: f.Path, Name: f.Name, Size: f.Size, ModTime: f.LastModified.Unix()})
+	}
+	return files, nil
+}
+
+func (s *storageS3) Download(ctx context.Context, file *RemoteBackup, localFile string) error {
+	return s.client.Download(ctx, file.Id, localFile)
+}
+
+func (s *storageS3) Delete(ctx context.Context, file *RemoteBackup) error {
+	return s.client.Delete(ctx, file.Id)
+}
+
+// ListRemoteBackups 列出备份目标中的备份文件，按时间从新到旧排列
+func ListRemoteBackups(ctx context.Context, target *models.BackupTarget) ([]*RemoteBackup, error) {
+	storage, err := newTargetStorage(target)
+	if err != nil {
+		return nil, err
+	}
+	files, err := storage.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sortRemoteBackups(files)
+	return files, nil
+}
+
+// 按修改时间从新到旧排列，时间相同时按文件名（文件名中有时间戳）
+func sortRemoteBackups(files []*RemoteBackup) {
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].ModTime != files[j].ModTime {
+			return files[i].ModTime > files[j].ModTime
+		}
+		return files[i].Name > files[j].Name
+	})
+}
+
+// 需要按保留天数和保留数量删除的远程备份，files需要已经按时间从新到旧排列
+func expiredRemoteBackups(files []*RemoteBackup, retentionDays int, maxCount int, now time.Time) []*RemoteBackup {
+	expired := make([]*RemoteBackup, 0)
+	for i, f := range files {
+		if maxCount > 0 && i >= maxCount {
+			expired = append(expired, f)
+			continue
+		}
+		if retentionDays > 0 && f.ModTime > 0 && now.Unix()-f.ModTime > int64(retentionDays)*24*60*60 {
+			expired = append(expired, f)
+		}
+	}
+	return expired
+}
+
+// 上传备份文件到所有启用的备份目标，单个目标失败不影响其他目标
+func uploadToTargets(localFile string) {
+	for _, target := range models.GetEnabledBackupTargets() {
+		err := uploadToTarget(target, localFile)
+		target.UpdateUploadResult(err)
+		if err != nil {
+			helpers.AppLogger.Errorf("上传备份文件到备份目标 %s 失败: %v", target.Name, err)
+			continue
+		}
+		helpers.AppLogger.Infof("已上传备份文件 %s 到备份目标 %s", filepath.Base(localFile), target.Name)
+	}
+}
+
+func uploadToTarget(target *models.BackupTarget, localFile string) error {
+	storage, err := newTargetStorage(target)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+	if err := storage.Upload(ctx, localFile); err != nil {
+		return err
+	}
+	// 清理远程旧备份，失败只记录日志
+	files, err := storage.List(ctx)
+	if err != nil {
+		helpers.AppLogger.Warnf("列出备份目标 %s 的备份文件失败，跳过清理: %v", target.Name, err)
+		return nil
+	}
+	sortRemoteBackups(files)
+	for _, f := range expiredRemoteBackups(files, target.Retention, target.MaxCount, time.Now()) {
+		if err := storage.Delete(ctx, f); err != nil {
+			helpers.AppLogger.Warnf("删除备份目标 %s 的旧备份 %s 失败: %v", target.Name, f.Name, err)
+			continue
+		}
+		helpers.AppLogger.Infof("已删除备份目标 %s 的旧备份 %s", target.Name, f.Name)
+	}
+	return nil
+}
+
+// DownloadRemoteBackup 从备份目标下载指定名称的备份文件到本地临时目录，返回本地路径
+func DownloadRemoteBackup(ctx context.Context, target *models.BackupTarget, name string) (string, error) {
+	storage, err := newTargetStorage(target)
+	if err != nil {
+		return "", err
+	}
+	files, err := storage.List(ctx)
+	if err != nil {
+		return "", err
+	}
+	var file *RemoteBackup
+	for _, f := range files {
+		if f.Name == name {
+			file = f
+			break
+		}
+	}
+	if file == nil {
+		return "", fmt.Errorf("备份目标 %s 中不存在备份文件 %s", target.Name, name)
+	}
+	tempDir := filepath.Join(helpers.ConfigDir, "backups", "temp")
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		return "", err
+	}
+	localFile := filepath.Join(tempDir, fmt.Sprintf("remote_%d_%s", time.Now().UnixNano(), file.Name))
+	if err := storage.Download(ctx, file, localFile); err != nil {
+		os.Remove(localFile)
+		return "", err
+	}
+	return localFile, nil
+}
@@ -0,0 +1,76 @@
+package backup
+
+import (
+	"Q115-STRM/internal/models"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestIsBackupFileName(t *testing.T) {
+	cases := map[string]bool{
+		"backup_manual_20240101_030000.zip":     true,
+		"backup_定时_20240101_030000.zip.enc":     true,
+		"backup_manual_20240101_030000.zip.tmp": false,
+		"other.zip":                             false,
+	}
+	for name, want := range cases {
+		if got := isBackupFileName(name); got != want {
+			t.Errorf("isBackupFileName(%s) = %v, 期望 %v", name, got, want)
+		}
+	}
+}
+
+func TestExpiredRemoteBackups(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	day := int64(24 * 60 * 60)
+	files := []*RemoteBackup{
+		{Name: "backup_c.zip", ModTime: now.Unix()},
+		{Name: "backup_b.zip", ModTime: now.Unix() - 2*day},
+		{Name: "backup_a.zip", ModTime: now.Unix() - 10*day},
+		{Name: "backup_0.zip", ModTime: now.Unix() - 20*day},
+	}
+	sortRemoteBackups(files)
+	names := func(list []*RemoteBackup) []string {
+		result := make([]string, 0, len(list))
+		for _, f := range list {
+			result = append(result, f.Name)
+		}
+		return result
+	}
+	if got := names(expiredRemoteBackups(files, 0, 0, now)); len(got) != 0 {
+		t.Errorf("不限制时不应该删除: %v", got)
+	}
+	if got := names(expiredRemoteBackups(files, 0, 2, now)); len(got) != 2 || got[0] != "backup_a.zip" || got[1] != "backup_0.zip" {
+		t.Errorf("按数量清理错误: %v", got)
+	}
+	if got := names(expiredRemoteBackups(files, 7, 0, now)); len(got) != 2 || got[0] != "backup_a.zip" || got[1] != "backup_0.zip" {
+		t.Errorf("按天数清理错误: %v", got)
+	}
+	if got := names(expiredRemoteBackups(files, 1, 3, now)); len(got) != 3 {
+		t.Errorf("同时按天数和数量清理错误: %v", got)
+	}
+}
+
+func TestEncryptForUpload(t *testing.T) {
+	newZip := func() string {
+		p := filepath.Join(t.TempDir(), "backup_manual_20240101_030000.zip")
+		if err := os.WriteFile(p, []byte("token"), 0644); err != nil {
+			t.Fatalf("写入测试文件失败: %v", err)
+		}
+		return p
+	}
+	zipPath := newZip()
+	if p, err := encryptForUpload(zipPath, &models.BackupConfig{}); !errors.Is(err, errBackupNotEncrypted) || p != zipPath {
+		t.Errorf("没有开启加密时不能上传: %s %v", p, err)
+	}
+	if p, err := encryptForUpload(zipPath, &models.BackupConfig{BackupEncrypt: 1}); err == nil || p != zipPath {
+		t.Errorf("没有设置密码时不能上传: %s %v", p, err)
+	}
+	p, err := encryptForUpload(zipPath, &models.BackupConfig{BackupEncrypt: 1, BackupPassphrase: "secret"})
+	if err != nil || p != zipPath+encryptedExt || !IsEncryptedBackup(p) {
+		t.Errorf("加密后才能上传: %s %v", p, err)
+	}
+}
@@ -0,0 +1,213 @@
+package bangumi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"resty.dev/v3"
+)
+
+const (
+	// Bangumi API地址
+	DefaultBaseURL = "https://api.bgm.tv"
+	// Bangumi要求设置可识别的User-Agent
+	DEFAULTUA = "qmediasync/1.0 (https://github.com/qicfan/qmediasync)"
+	// 超时配置
+	DEFAULT_TIMEOUT = 30 // 秒
+)
+
+// 条目类型
+const (
+	SubjectTypeBook  = 1
+	SubjectTypeAnime = 2
+	SubjectTypeMusic = 3
+	SubjectTypeGame  = 4
+	SubjectTypeReal  = 6
+)
+
+// 章节类型：0-本篇 1-SP 2-OP 3-ED
+const EpisodeTypeMain = 0
+
+// Bangumi API客户端，access token可选，设置后可以查询到受限条目
+type Client struct {
+	resty       *resty.Client
+	accessToken string
+	proxyUrl    string
+	rateLimiter *rate.Limiter
+}
+
+var GlobalBangumiClient *Client
+var globalBangumiClientMu sync.Mutex
+
+func NewClient(accessToken, proxyUrl string) *Client {
+	globalBangumiClientMu.Lock()
+	defer globalBangumiClientMu.Unlock()
+	if GlobalBangumiClient != nil {
+		GlobalBangumiClient.accessToken = accessToken
+		GlobalBangumiClient.SetProxyUrl(proxyUrl)
+		return GlobalBangumiClient
+	}
+	rc := resty.New()
+	rc.SetBaseURL(DefaultBaseURL)
+	rc.SetTimeout(DEFAULT_TIMEOUT * time.Second)
+	rc.SetHeader("User-Agent", DEFAULTUA)
+	rc.SetHeader("Accept", "application/json")
+	GlobalBangumiClient = &Client{
+		resty:       rc,
+		accessToken: accessToken,
+		rateLimiter: rate.NewLimiter(rate.Every(200*time.Millisecond), 5), // 每秒5个请求
+	}
+	GlobalBangumiClient.SetProxyUrl(proxyUrl)
+	return GlobalBangumiClient
+}
+
+func (c *Client) SetProxyUrl(proxyUrl string) {
+	if c.proxyUrl == proxyUrl {
+		return
+	}
+	c.proxyUrl = proxyUrl
+	if proxyUrl != "" {
+		c.resty.SetProxy(proxyUrl)
+	} else {
+		c.resty.RemoveProxy()
+	}
+}
+
+func (c *Client) newRequest(result any) (*resty.Request, error) {
+	if err := c.rateLimiter.Wait(context.Background()); err != nil {
+		return nil, err
+	}
+	req := c.resty.R().SetResult(result)
+	if c.accessToken != "" {
+		req.SetAuthToken(c.accessToken)
+	}
+	return req, nil
+}
+
+func checkResponse(url string, resp *resty.Response) error {
+	if resp.StatusCode() == http.StatusNotFound {
+		return fmt.Errorf("Bangumi没有数据: %s", url)
+	}
+	if !resp.IsSuccess() {
+		return fmt.Errorf("请求Bangumi接口 %s 失败: %s", url, resp.String())
+	}
+	return nil
+}
+
+// 按关键词搜索条目，subjectType为0时不限制类型
+func (c *Client) SearchSubjects(keyword string, subjectType int) ([]Subject, error) {
+	result := SearchResponse{}
+	req, err := c.newRequest(&result)
+	if err != nil {
+		return nil, err
+	}
+	body := map[string]any{
+		"keyword": keyword,
+		"sort":    "match",
+	}
+	if subjectType > 0 {
+		body["filter"] = map[string]any{"type": []int{subjectType}}
+	}
+	url := "/v0/search/subjects"
+	resp, err := req.SetQueryParam("limit", "10").SetBody(body).Post(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(url, resp); err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// 查询条目详情
+func (c *Client) GetSubject(subjectId int64) (*Subject, error) {
+	result := Subject{}
+	req, err := c.newRequest(&result)
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("/v0/subjects/%d", subjectId)
+	resp, err := req.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(url, resp); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// 查询条目的章节列表，episodeType为-1时返回全部类型
+func (c *Client) GetEpisodes(subjectId int64, episodeType int) ([]Episode, error) {
+	episodes := make([]Episode, 0)
+	limit := 100
+	for offset := 0; ; offset += limit {
+		result := EpisodesResponse{}
+		req, err := c.newRequest(&result)
+		if err != nil {
+			return nil, err
+		}
+		req.SetQueryParams(map[string]string{
+			"subject_id": strconv.FormatInt(subjectId, 10),
+			"limit":      strconv.Itoa(limit),
+			"offset":     strconv.Itoa(offset),
+		})
+		if episodeType >= 0 {
+			req.SetQueryParam("type", strconv.Itoa(episodeType))
+		}
+		url := "/v0/episodes"
+		resp, err := req.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkResponse(url, resp); err != nil {
+			return nil, err
+		}
+		episodes = append(episodes, result.Data...)
+		if len(result.Data) < limit || len(episodes) >= result.Total {
+			break
+		}
+	}
+	return episodes, nil
+}
+
+// 查询条目的角色和声优
+func (c *Client) GetCharacters(subjectId int64) ([]Character, error) {
+	result := make([]Character, 0)
+	req, err := c.newRequest(&result)
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("/v0/subjects/%d/characters", subjectId)
+	resp, err := req.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(url, resp); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// 查询条目的制作人员
+func (c *Client) GetPersons(subjectId int64) ([]Person, error) {
+	result := make([]Person, 0)
+	req, err := c.newRequest(&result)
+	if err != nil {
+		return nil, err
+	}
+	url := fmt.Sprintf("/v0/subjects/%d/persons", subjectId)
+	resp, err := req.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(url, resp); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
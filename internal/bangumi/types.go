@@ -0,0 +1,88 @@
+package bangumi
+
+type Images struct {
+	Large  string `json:"large"`
+	Common string `json:"common"`
+	Medium string `json:"medium"`
+	Small  string `json:"small"`
+	Grid   string `json:"grid"`
+}
+
+type Rating struct {
+	Score float64 `json:"score"`
+	Total int64   `json:"total"`
+}
+
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// 条目
+type Subject struct {
+	ID            int64  `json:"id"`
+	Type          int    `json:"type"`
+	Name          string `json:"name"`    // 原名
+	NameCn        string `json:"name_cn"` // 中文名
+	Summary       string `json:"summary"`
+	Date          string `json:"date"`     // 放送开始日期
+	Platform      string `json:"platform"` // TV、剧场版、OVA、WEB等
+	Images        Images `json:"images"`
+	Eps           int    `json:"eps"`
+	TotalEpisodes int    `json:"total_episodes"`
+	Rating        Rating `json:"rating"`
+	Tags          []Tag  `json:"tags"`
+	Nsfw          bool   `json:"nsfw"`
+}
+
+type SearchResponse struct {
+	Total  int       `json:"total"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+	Data   []Subject `json:"data"`
+}
+
+// 章节
+type Episode struct {
+	ID              int64   `json:"id"`
+	Type            int     `json:"type"`
+	Name            string  `json:"name"`
+	NameCn          string  `json:"name_cn"`
+	Sort            float64 `json:"sort"` // 在条目内的排序
+	Ep              float64 `json:"ep"`   // 在本篇中的集数
+	Airdate         string  `json:"airdate"`
+	Desc            string  `json:"desc"`
+	Duration        string  `json:"duration"`
+	DurationSeconds int     `json:"duration_seconds"`
+}
+
+type EpisodesResponse struct {
+	Total  int       `json:"total"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+	Data   []Episode `json:"data"`
+}
+
+type Actor struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Images Images `json:"images"`
+}
+
+// 角色
+type Character struct {
+	ID       int64   `json:"id"`
+	Name     string  `json:"name"`
+	Relation string  `json:"relation"` // 主角、配角、客串
+	Images   Images  `json:"images"`
+	Actors   []Actor `json:"actors"` // 声优
+}
+
+// 制作人员
+type Person struct {
+	ID       int64    `json:"id"`
+	Name     string   `json:"name"`
+	Relation string   `json:"relation"` // 导演、原作、音乐等
+	Career   []string `json:"career"`
+	Images   Images   `json:"images"`
+}
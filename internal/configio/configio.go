@@ -0,0 +1,679 @@
+// 配置即代码：把同步目录、刮削目录、分类、通知渠道、Emby、系统设置和备份设置导出为一个YAML文档
+// 导入时按自然键匹配已有记录，只新增和更新，不删除文档中没有的记录，所以重复导入同一个文档结果不变
+// 记录之间的ID引用（账号、通知渠道）在文档中使用名称，不同实例之间可以直接复用同一个文档
+package configio
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/models"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	Version  = 1            // 文档格式版本
+	Redacted = "<redacted>" // 导出时隐藏的敏感字段值，导入时遇到该值保留原有配置
+)
+
+const (
+	ActionCreate    = "create"
+	ActionUpdate    = "update"
+	ActionUnchanged = "unchanged"
+)
+
+// 所有表都有的自增ID和时间戳，不导出
+var systemColumns = []string{"id", "created_at", "updated_at"}
+
+// 导入计划中有错误时回滚事务
+var errPlanInvalid = errors.New("导入计划存在错误")
+
+// Change 导入计划中的一条记录
+type Change struct {
+	Resource string   `json:"resource"`         // 资源名称，即文档中的顶级字段
+	Key      string   `json:"key"`              // 记录的自然键，单例资源为空
+	Action   string   `json:"action"`           // create、update或者unchanged
+	Fields   []string `json:"fields,omitempty"` // 新增或者变化的字段
+}
+
+// Plan 导入计划，Applied为false时只是预览，没有修改数据库
+type Plan struct {
+	Applied bool     `json:"applied"`
+	Changes []Change `json:"changes"`
+	Errors  []string `json:"errors"`
+}
+
+// Changed 是否有新增或者更新的记录
+func (p *Plan) Changed() bool {
+	for _, change := range p.Changes {
+		if change.Action != ActionUnchanged {
+			return true
+		}
+	}
+	return false
+}
+
+// Touched 指定资源是否有新增或者更新的记录
+func (p *Plan) Touched(resources ...string) bool {
+	for _, change := range p.Changes {
+		if change.Action != ActionUnchanged && slices.Contains(resources, change.Resource) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Plan) errorf(format string, args ...any) {
+	p.Errors = append(p.Errors, fmt.Sprintf(format, args...))
+}
+
+// 一个ID列在文档中使用被引用记录的名称
+type reference struct {
+	column   string // 数据库中的ID列
+	name     string // 文档中的字段名
+	resource string // 被引用的资源，被引用记录在同一个文档中新建时预览也能通过
+	toName   func(tx *gorm.DB, id uint) (string, bool)
+	toId     func(tx *gorm.DB, name string) (uint, bool)
+}
+
+// 账号使用备注名称引用，本地目录没有账号
+var accountRef = reference{
+	column: "account_id",
+	name:   "account",
+	toName: func(tx *gorm.DB, id uint) (string, bool) {
+		if id == 0 {
+			return "", true
+		}
+		account := models.Account{}
+		if err := tx.Where("id = ?", id).First(&account).Error; err != nil {
+			return "", false
+		}
+		return account.Name, true
+	},
+	toId: func(tx *gorm.DB, name string) (uint, bool) {
+		if name == "" {
+			return 0, true
+		}
+		account := models.Account{}
+		if err := tx.Where("name = ?", name).First(&account).Error; err != nil {
+			return 0, false
+		}
+		return account.ID, true
+	},
+}
+
+// 通知渠道使用渠道类型引用，每种类型只有一个渠道
+var channelRef = reference{
+	column:   "channel_id",
+	name:     "channel",
+	resource: "notification_channels",
+	toName: func(tx *gorm.DB, id uint) (string, bool) {
+		channel := models.NotificationChannel{}
+		if err := tx.Where("id = ?", id).First(&channel).Error; err != nil {
+			return "", false
+		}
+		return channel.ChannelType, true
+	},
+	toId: func(tx *gorm.DB, name string) (uint, bool) {
+		channel := models.NotificationChannel{}
+		if err := tx.Where("channel_type = ?", name).First(&channel).Error; err != nil {
+			return 0, false
+		}
+		return channel.ID, true
+	},
+}
+
+// 一种可以导入导出的配置
+type resource struct {
+	name    string     // 文档中的顶级字段
+	model   func() any // 返回模型指针
+	keys    []string   // 自然键字段（文档中的字段名），为空表示只有一条记录
+	secrets []string   // 敏感字段，导出时可以隐藏
+	skip    []string   // 运行状态或者已废弃的字段，不导入导出
+	refs    []reference
+}
+
+// 按依赖顺序排列，通知渠道配置和规则引用通知渠道
+var resources = []*resource{
+	{
+		name:    "settings",
+		model:   func() any { return &models.Settings{} },
+		secrets: []string{"http_proxy"}, // 代理地址中可能包含用户名和密码
+		skip:    []string{"use_telegram", "telegram_bot_token", "telegram_chat_id", "meo_w_name", "emby_url", "emby_api_key", "sign_secret"},
+	},
+	{
+		name:    "backup_config",
+		model:   func() any { return &models.BackupConfig{} },
+		secrets: []string{"backup_passphrase"},
+	},
+	{
+		name:    "emby_config",
+		model:   func() any { return &models.EmbyConfig{} },
+		secrets: []string{"emby_api_key"},
+		skip:    []string{"last_sync_time"},
+	},
+	{
+		name:  "sync_paths",
+		model: func() any { return &models.SyncPath{} },
+		keys:  []string{"source_type", "base_cid"},
+		skip:  []string{"last_sync_at", "is_full_sync", "confirm_delete"},
+		refs:  []reference{accountRef},
+	},
+	{
+		name:  "scrape_paths",
+		model: func() any { return &models.ScrapePath{} },
+		keys:  []string{"source_type", "account", "source_path"},
+		skip:  []string{"is_scraping"},
+		refs:  []reference{accountRef},
+	},
+	{
+		name:  "movie_categories",
+		model: func() any { return &models.MovieCategory{} },
+		keys:  []string{"name"},
+	},
+	{
+		name:  "tvshow_categories",
+		model: func() any { return &models.TvShowCategory{} },
+		keys:  []string{"name"},
+	},
+	{
+		name:  "notification_channels",
+		model: func() any { return &models.NotificationChannel{} },
+		keys:  []string{"channel_type"},
+	},
+	{
+		name:    "telegram_configs",
+		model:   func() any { return &models.TelegramChannelConfig{} },
+		keys:    []string{"channel"},
+		secrets: []string{"bot_token"},
+		refs:    []reference{channelRef},
+	},
+	{
+		name:    "meow_configs",
+		model:   func() any { return &models.MeoWChannelConfig{} },
+		keys:    []string{"channel"},
+		secrets: []string{"nickname"},
+		refs:    []reference{channelRef},
+	},
+	{
+		name:    "bark_configs",
+		model:   func() any { return &models.BarkChannelConfig{} },
+		keys:    []string{"channel"},
+		secrets: []string{"device_key"},
+		refs:    []reference{channelRef},
+	},
+	{
+		name:    "serverchan_configs",
+		model:   func() any { return &models.ServerChanChannelConfig{} },
+		keys:    []string{"channel"},
+		secrets: []string{"sc_key"},
+		refs:    []reference{channelRef},
+	},
+	{
+		name:    "custom_webhook_configs",
+		model:   func() any { return &models.CustomWebhookChannelConfig{} },
+		keys:    []string{"channel"},
+		secrets: []string{"auth_token", "auth_pass"},
+		refs:    []reference{channelRef},
+	},
+	{
+		name:  "notification_rules",
+		model: func() any { return &models.NotificationRule{} },
+		keys:  []string{"channel", "event_type"},
+		refs:  []reference{channelRef},
+	},
+}
+
+// Resources 支持导入导出的资源名称，按导入顺序排列
+func Resources() []string {
+	names := make([]string, 0, len(resources))
+	for _, r := range resources {
+		names = append(names, r.name)
+	}
+	return names
+}
+
+// NotificationResources 通知相关的资源，导入后需要重新加载通知渠道
+func NotificationResources() []string {
+	names := make([]string, 0)
+	for _, r := range resources {
+		if strings.HasPrefix(r.name, "notification_") || strings.HasSuffix(r.name, "_configs") {
+			names = append(names, r.name)
+		}
+	}
+	return names
+}
+
+func (r *resource) singleton() bool {
+	return len(r.keys) == 0
+}
+
+func (r *resource) ref(column string) *reference {
+	for i := range r.refs {
+		if r.refs[i].column == column {
+			return &r.refs[i]
+		}
+	}
+	return nil
+}
+
+// 数据库列在文档中的字段名
+func (r *resource) docName(column string) string {
+	if ref := r.ref(column); ref != nil {
+		return ref.name
+	}
+	return column
+}
+
+// 使用gorm解析出的模型字段，按结构体定义顺序排列
+func (r *resource) fields(tx *gorm.DB) ([]*schema.Field, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(r.model()); err != nil {
+		return nil, fmt.Errorf("解析模型 %s 失败: %w", r.name, err)
+	}
+	fields := make([]*schema.Field, 0, len(stmt.Schema.Fields))
+	for _, field := range stmt.Schema.Fields {
+		// 嵌入结构体中被覆盖的同名字段只保留生效的那个
+		if field.DBName == "" || field.PrimaryKey || stmt.Schema.FieldsByDBName[field.DBName] != field {
+			continue
+		}
+		if slices.Contains(systemColumns, field.DBName) || slices.Contains(r.skip, field.DBName) {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// 数据库中的一条记录
+type row struct {
+	model  any            // 模型指针
+	values map[string]any // 文档字段名 -> 值，引用字段为名称
+}
+
+// 加载资源的全部记录，引用的记录不存在时跳过该记录
+func (r *resource) load(tx *gorm.DB, fields []*schema.Field) ([]*row, error) {
+	slice := reflect.New(reflect.SliceOf(reflect.TypeOf(r.model())))
+	if err := tx.Order("id").Find(slice.Interface()).Error; err != nil {
+		return nil, fmt.Errorf("查询 %s 失败: %w", r.name, err)
+	}
+	ctx := context.Background()
+	rows := make([]*row, 0, slice.Elem().Len())
+	for i := 0; i < slice.Elem().Len(); i++ {
+		ptr := slice.Elem().Index(i)
+		rw := &row{model: ptr.Interface(), values: make(map[string]any, len(fields))}
+		valid := true
+		for _, field := range fields {
+			value := field.ReflectValueOf(ctx, ptr.Elem())
+			if ref := r.ref(field.DBName); ref != nil {
+				name, ok := ref.toName(tx, uint(value.Uint()))
+				if !ok {
+					valid = false
+					break
+				}
+				rw.values[ref.name] = name
+				continue
+			}
+			rw.values[field.DBName] = value.Interface()
+		}
+		if valid {
+			rows = append(rows, rw)
+		}
+	}
+	return rows, nil
+}
+
+// 自然键，多个字段使用/连接
+func keyOf(keys []string, values map[string]any) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := values[key]
+		if value == nil {
+			value = ""
+		}
+		parts = append(parts, fmt.Sprint(value))
+	}
+	return strings.Join(parts, "/")
+}
+
+// Export 导出YAML文档，redact为true时隐藏敏感字段
+func Export(redact bool) ([]byte, error) {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	appendPair(doc, "version", Version)
+	appendPair(doc, "exported_at", time.Now().Format(time.RFC3339))
+	for _, r := range resources {
+		fields, err := r.fields(db.Db)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := r.load(db.Db, fields)
+		if err != nil {
+			return nil, err
+		}
+		if r.singleton() {
+			if len(rows) == 0 {
+				continue
+			}
+			node, err := r.node(rows[0], fields, redact)
+			if err != nil {
+				return nil, err
+			}
+			doc.Content = append(doc.Content, scalar(r.name), node)
+			continue
+		}
+		list := &yaml.Node{Kind: yaml.SequenceNode}
+		for _, rw := range rows {
+			node, err := r.node(rw, fields, redact)
+			if err != nil {
+				return nil, err
+			}
+			list.Content = append(list.Content, node)
+		}
+		doc.Content = append(doc.Content, scalar(r.name), list)
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// 一条记录的YAML节点，字段顺序和模型定义一致，保证多次导出的结果可以直接diff
+func (r *resource) node(rw *row, fields []*schema.Field, redact bool) (*yaml.Node, error) {
+	node := &yaml.Node{Kind: yaml.MappingNode}
+	for _, field := range fields {
+		name := r.docName(field.DBName)
+		value := rw.values[name]
+		if redact && slices.Contains(r.secrets, field.DBName) && !reflect.ValueOf(value).IsZero() {
+			value = Redacted
+		}
+		valueNode := &yaml.Node{}
+		if err := valueNode.Encode(value); err != nil {
+			return nil, fmt.Errorf("导出 %s.%s 失败: %w", r.name, name, err)
+		}
+		node.Content = append(node.Content, scalar(name), valueNode)
+	}
+	return node, nil
+}
+
+func scalar(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+func appendPair(node *yaml.Node, key string, value any) {
+	valueNode := &yaml.Node{}
+	valueNode.Encode(value)
+	node.Content = append(node.Content, scalar(key), valueNode)
+}
+
+// 把YAML解析出的值转换为模型字段的类型
+func convert(raw any, t reflect.Type) (any, error) {
+	data, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	ptr := reflect.New(t)
+	if err := yaml.Unmarshal(data, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// 解析文档并检查顶级字段
+func parse(data []byte) (map[string]any, error) {
+	doc := map[string]any{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("解析YAML失败: %w", err)
+	}
+	if version, ok := doc["version"]; ok && fmt.Sprint(version) != fmt.Sprint(Version) {
+		return nil, fmt.Errorf("不支持的配置版本: %v", version)
+	}
+	names := Resources()
+	unknown := make([]string, 0)
+	for key := range doc {
+		if key != "version" && key != "exported_at" && !slices.Contains(names, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("未知的配置项: %s", strings.Join(unknown, ", "))
+	}
+	return doc, nil
+}
+
+// Import 导入YAML文档，apply为false时只生成导入计划，不修改数据库
+// 计划中有任何错误时不会导入任何记录
+func Import(data []byte, apply bool) (*Plan, error) {
+	doc, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	plan := &Plan{Changes: make([]Change, 0), Errors: make([]string, 0)}
+	run := func(tx *gorm.DB) error {
+		im := &importer{tx: tx, apply: apply, plan: plan, created: map[string]map[string]bool{}}
+		for _, r := range resources {
+			value, ok := doc[r.name]
+			if !ok || value == nil {
+				continue
+			}
+			if err := im.importResource(r, value); err != nil {
+				return err
+			}
+		}
+		if len(plan.Errors) > 0 {
+			return errPlanInvalid
+		}
+		return nil
+	}
+	if !apply {
+		err = run(db.Db)
+	} else {
+		err = db.Db.Transaction(run)
+		plan.Applied = err == nil
+	}
+	if err != nil && !errors.Is(err, errPlanInvalid) {
+		return plan, err
+	}
+	return plan, nil
+}
+
+type importer struct {
+	tx      *gorm.DB
+	apply   bool
+	plan    *Plan
+	created map[string]map[string]bool // 资源名称 -> 本次新建的记录的自然键
+}
+
+func (im *importer) importResource(r *resource, value any) error {
+	items := make([]map[string]any, 0)
+	if r.singleton() {
+		item, ok := value.(map[string]any)
+		if !ok {
+			im.plan.errorf("%s: 应该是一个对象", r.name)
+			return nil
+		}
+		items = append(items, item)
+	} else {
+		list, ok := value.([]any)
+		if !ok {
+			im.plan.errorf("%s: 应该是一个列表", r.name)
+			return nil
+		}
+		for i, v := range list {
+			item, ok := v.(map[string]any)
+			if !ok {
+				im.plan.errorf("%s[%d]: 应该是一个对象", r.name, i)
+				continue
+			}
+			items = append(items, item)
+		}
+	}
+	fields, err := r.fields(im.tx)
+	if err != nil {
+		return err
+	}
+	rows, err := r.load(im.tx, fields)
+	if err != nil {
+		return err
+	}
+	index := make(map[string]*row, len(rows))
+	for _, rw := range rows {
+		index[keyOf(r.keys, rw.values)] = rw
+	}
+	seen := make(map[string]bool, len(items))
+	for i, item := range items {
+		label := r.name
+		if !r.singleton() {
+			label = fmt.Sprintf("%s[%d]", r.name, i)
+		}
+		missing := make([]string, 0)
+		for _, key := range r.keys {
+			if _, ok := item[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+		if len(missing) > 0 {
+			im.plan.errorf("%s: 缺少字段 %s", label, strings.Join(missing, ", "))
+			continue
+		}
+		key := keyOf(r.keys, item)
+		if seen[key] {
+			im.plan.errorf("%s: 重复的记录 %s", label, key)
+			continue
+		}
+		seen[key] = true
+		var existing *row
+		if r.singleton() {
+			if len(rows) > 0 {
+				existing = rows[0]
+			}
+		} else {
+			existing = index[key]
+		}
+		if err := im.importItem(r, fields, label, key, item, existing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (im *importer) importItem(r *resource, fields []*schema.Field, label, key string, item map[string]any, existing *row) error {
+	known := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		known[r.docName(field.DBName)] = true
+	}
+	unknown := make([]string, 0)
+	for name := range item {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		im.plan.errorf("%s: 未知字段 %s", label, strings.Join(unknown, ", "))
+		return nil
+	}
+	updates := make(map[string]any)
+	changed := make([]string, 0)
+	valid := true
+	for _, field := range fields {
+		name := r.docName(field.DBName)
+		raw, ok := item[name]
+		if !ok {
+			continue
+		}
+		if s, isString := raw.(string); isString && s == Redacted && slices.Contains(r.secrets, field.DBName) {
+			continue
+		}
+		var value, compare any
+		if ref := r.ref(field.DBName); ref != nil {
+			refName := ""
+			if raw != nil {
+				refName = fmt.Sprint(raw)
+			}
+			id, found := ref.toId(im.tx, refName)
+			if !found && !im.created[ref.resource][refName] {
+				im.plan.errorf("%s: %s %s 不存在", label, ref.name, refName)
+				valid = false
+				continue
+			}
+			value, compare = reflect.ValueOf(id).Convert(field.FieldType).Interface(), refName
+		} else {
+			converted, err := convert(raw, field.FieldType)
+			if err != nil {
+				im.plan.errorf("%s: 字段 %s 的值无效: %v", label, name, err)
+				valid = false
+				continue
+			}
+			value, compare = converted, converted
+		}
+		if existing == nil || !reflect.DeepEqual(existing.values[name], compare) {
+			changed = append(changed, name)
+			updates[field.DBName] = value
+		}
+	}
+	if !valid {
+		return nil
+	}
+	change := Change{Resource: r.name, Key: key, Action: ActionUnchanged}
+	switch {
+	case existing == nil:
+		change.Action = ActionCreate
+		change.Fields = changed
+	case len(changed) > 0:
+		change.Action = ActionUpdate
+		change.Fields = changed
+	}
+	im.plan.Changes = append(im.plan.Changes, change)
+	if change.Action == ActionCreate && !r.singleton() {
+		if im.created[r.name] == nil {
+			im.created[r.name] = map[string]bool{}
+		}
+		im.created[r.name][key] = true
+	}
+	if !im.apply || change.Action == ActionUnchanged {
+		return nil
+	}
+	if change.Action == ActionUpdate {
+		if err := im.tx.Model(existing.model).Updates(updates).Error; err != nil {
+			return fmt.Errorf("更新 %s 失败: %w", label, err)
+		}
+		return nil
+	}
+	model := r.model()
+	ctx := context.Background()
+	rv := reflect.ValueOf(model).Elem()
+	for _, field := range fields {
+		if value, ok := updates[field.DBName]; ok {
+			if err := field.Set(ctx, rv, value); err != nil {
+				return fmt.Errorf("设置 %s.%s 失败: %w", label, field.DBName, err)
+			}
+		}
+	}
+	if err := im.tx.Create(model).Error; err != nil {
+		return fmt.Errorf("新建 %s 失败: %w", label, err)
+	}
+	// 字段有数据库默认值时零值不会写入，新建后再更新一次
+	if len(updates) > 0 {
+		if err := im.tx.Model(model).Updates(updates).Error; err != nil {
+			return fmt.Errorf("新建 %s 失败: %w", label, err)
+		}
+	}
+	return nil
+}
@@ -41,6 +41,8 @@ func GetAccountList(c *gin.Context) {
 		TokenFailedReason string            `json:"token_failed_reason"`
 		BaseUrl           string            `json:"base_url"`
 		AuthType          string            `json:"auth_type"`
+		Bucket            string            `json:"bucket"`
+		Region            string            `json:"region"`
 	}
 	resp := make([]accountResp, 0, len(accounts))
 	for _, account := range accounts {
@@ -56,6 +58,8 @@ func GetAccountList(c *gin.Context) {
 			CreatedAt:         account.CreatedAt,
 			TokenFailedReason: account.TokenFailedReason,
 			BaseUrl:           account.BaseUrl,
+			Bucket:            account.Bucket,
+			Region:            account.Region,
 		}
 		switch account.AppId {
 		case "Q115-STRM":
@@ -236,3 +240,137 @@ func CreateOpenListAccount(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "创建openlist账号成功", Data: nil})
 }
+
+// CreateWebDavAccount 创建或更新WebDAV账号
+// @Summary 创建/更新WebDAV账号
+// @Description 创建新的WebDAV账号或更新现有账号，保存前会校验能否访问根目录
+// @Tags 账号管理
+// @Accept json
+// @Produce json
+// @Param id query integer false "账号ID（指定则为更新操作）"
+// @Param name query string false "账号备注"
+// @Param base_url query string true "WebDAV地址，可以包含路径"
+// @Param username query string false "用户名"
+// @Param password query string false "密码"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /account/webdav [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CreateWebDavAccount(c *gin.Context) {
+	type createWebDavAccountReq struct {
+		Id       uint   `json:"id" form:"id"`
+		Name     string `json:"name" form:"name"`
+		BaseUrl  string `json:"base_url" form:"base_url"`
+		Username string `json:"username" form:"username"`
+		Password string `json:"password" form:"password"`
+	}
+	req := &createWebDavAccountReq{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	if req.BaseUrl == "" {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	// 如果不以http开头则添加http://
+	if !strings.HasPrefix(req.BaseUrl, "http://") && !strings.HasPrefix(req.BaseUrl, "https://") {
+		req.BaseUrl = "http://" + req.BaseUrl
+	}
+	// 如果结尾有/则删除
+	req.BaseUrl = strings.TrimSuffix(req.BaseUrl, "/")
+	if req.Id != 0 {
+		account, err := models.GetAccountById(req.Id)
+		if err != nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("查询WebDAV账号失败: %s", err.Error()), Data: nil})
+			return
+		}
+		if account.SourceType != models.SourceTypeWebDav {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "该账号不是WebDAV账号", Data: nil})
+			return
+		}
+		if err := account.UpdateWebDav(req.Name, req.BaseUrl, req.Username, req.Password); err != nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("更新WebDAV账号失败: %s", err.Error()), Data: nil})
+			return
+		}
+		c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "更新WebDAV账号成功", Data: nil})
+		return
+	}
+	_, err := models.CreateWebDavAccount(req.Name, req.BaseUrl, req.Username, req.Password)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("创建WebDAV账号失败: %s", err.Error()), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "创建WebDAV账号成功", Data: nil})
+}
+
+// CreateS3Account 创建或更新S3兼容对象存储账号
+// @Summary 创建/更新S3账号
+// @Description 创建新的S3兼容对象存储账号（MinIO、Cloudflare R2、Backblaze B2等）或更新现有账号，保存前会校验能否列举bucket
+// @Tags 账号管理
+// @Accept json
+// @Produce json
+// @Param id query integer false "账号ID（指定则为更新操作）"
+// @Param name query string false "账号备注"
+// @Param endpoint query string true "服务地址，例如http://127.0.0.1:9000"
+// @Param region query string false "区域，为空时使用us-east-1"
+// @Param bucket query string true "bucket名称"
+// @Param access_key query string true "AccessKey"
+// @Param secret_key query string true "SecretKey"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /account/s3 [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CreateS3Account(c *gin.Context) {
+	type createS3AccountReq struct {
+		Id        uint   `json:"id" form:"id"`
+		Name      string `json:"name" form:"name"`
+		Endpoint  string `json:"endpoint" form:"endpoint"`
+		Region    string `json:"region" form:"region"`
+		Bucket    string `json:"bucket" form:"bucket"`
+		AccessKey string `json:"access_key" form:"access_key"`
+		SecretKey string `json:"secret_key" form:"secret_key"`
+	}
+	req := &createS3AccountReq{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	if req.Endpoint == "" || req.Bucket == "" || req.AccessKey == "" || req.SecretKey == "" {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "服务地址、bucket、AccessKey和SecretKey不能为空", Data: nil})
+		return
+	}
+	// 如果不以http开头则添加https://
+	if !strings.HasPrefix(req.Endpoint, "http://") && !strings.HasPrefix(req.Endpoint, "https://") {
+		req.Endpoint = "https://" + req.Endpoint
+	}
+	// 如果结尾有/则删除
+	req.Endpoint = strings.TrimSuffix(req.Endpoint, "/")
+	if req.Id != 0 {
+		account, err := models.GetAccountById(req.Id)
+		if err != nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("查询S3账号失败: %s", err.Error()), Data: nil})
+			return
+		}
+		if account.SourceType != models.SourceTypeS3 {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "该账号不是S3账号", Data: nil})
+			return
+		}
+		if err := account.UpdateS3(req.Name, req.Endpoint, req.Region, req.Bucket, req.AccessKey, req.SecretKey); err != nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("更新S3账号失败: %s", err.Error()), Data: nil})
+			return
+		}
+		ReloadStreamProxy()
+		c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "更新S3账号成功", Data: nil})
+		return
+	}
+	_, err := models.CreateS3Account(req.Name, req.Endpoint, req.Region, req.Bucket, req.AccessKey, req.SecretKey)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("创建S3账号失败: %s", err.Error()), Data: nil})
+		return
+	}
+	ReloadStreamProxy()
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "创建S3账号成功", Data: nil})
+}
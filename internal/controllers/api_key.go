@@ -54,13 +54,14 @@ func CreateAPIKey(c *gin.Context) {
 	}
 
 	// 获取当前登录用户
-	if LoginedUser == nil {
+	loginUser := GetLoginUser(c)
+	if loginUser == nil {
 		c.JSON(http.StatusUnauthorized, APIResponse[any]{Code: BadRequest, Message: "用户未登录", Data: nil})
 		return
 	}
 
 	// 创建API Key
-	apiKey, rawKey, err := models.CreateAPIKey(LoginedUser.ID, req.Name)
+	apiKey, rawKey, err := models.CreateAPIKey(loginUser.ID, req.Name)
 	if err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("创建API Key失败：%v", err), Data: nil})
 		return
@@ -96,13 +97,14 @@ func CreateAPIKey(c *gin.Context) {
 // @Security ApiKeyAuth
 func ListAPIKeys(c *gin.Context) {
 	// 获取当前登录用户
-	if LoginedUser == nil {
+	loginUser := GetLoginUser(c)
+	if loginUser == nil {
 		c.JSON(http.StatusUnauthorized, APIResponse[any]{Code: BadRequest, Message: "用户未登录", Data: nil})
 		return
 	}
 
 	// 查询用户的API Keys
-	apiKeys, err := models.GetAPIKeysByUserID(LoginedUser.ID)
+	apiKeys, err := models.GetAPIKeysByUserID(loginUser.ID)
 	if err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("查询API Keys失败：%v", err), Data: nil})
 		return
@@ -142,7 +144,8 @@ func ListAPIKeys(c *gin.Context) {
 // @Security ApiKeyAuth
 func DeleteAPIKey(c *gin.Context) {
 	// 获取当前登录用户
-	if LoginedUser == nil {
+	loginUser := GetLoginUser(c)
+	if loginUser == nil {
 		c.JSON(http.StatusUnauthorized, APIResponse[any]{Code: BadRequest, Message: "用户未登录", Data: nil})
 		return
 	}
@@ -156,7 +159,7 @@ func DeleteAPIKey(c *gin.Context) {
 	}
 
 	// 删除API Key（确保只能删除自己的）
-	err = models.DeleteAPIKey(uint(id), LoginedUser.ID)
+	err = models.DeleteAPIKey(uint(id), loginUser.ID)
 	if err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("删除API Key失败：%v", err), Data: nil})
 		return
@@ -189,7 +192,8 @@ type UpdateAPIKeyStatusRequest struct {
 // @Security ApiKeyAuth
 func UpdateAPIKeyStatus(c *gin.Context) {
 	// 获取当前登录用户
-	if LoginedUser == nil {
+	loginUser := GetLoginUser(c)
+	if loginUser == nil {
 		c.JSON(http.StatusUnauthorized, APIResponse[any]{Code: BadRequest, Message: "用户未登录", Data: nil})
 		return
 	}
@@ -210,7 +214,7 @@ func UpdateAPIKeyStatus(c *gin.Context) {
 	}
 
 	// 更新API Key状态（确保只能更新自己的）
-	err = models.UpdateAPIKeyStatus(uint(id), LoginedUser.ID, req.IsActive)
+	err = models.UpdateAPIKeyStatus(uint(id), loginUser.ID, req.IsActive)
 	if err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("更新API Key状态失败：%v", err), Data: nil})
 		return
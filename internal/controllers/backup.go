@@ -23,15 +23,18 @@ type BackupCreateRequest struct {
 }
 
 type BackupRestoreRequest struct {
-	RecordID uint `json:"record_id"`
+	RecordID   uint   `json:"record_id"`
+	Passphrase string `json:"passphrase"` // 加密备份的密码，为空时使用备份设置中的密码
 }
 
 type BackupConfigUpdateRequest struct {
-	BackupEnabled   int    `json:"backup_enabled"`
-	BackupCron      string `json:"backup_cron"`
-	BackupRetention int    `json:"backup_retention"`
-	BackupMaxCount  int    `json:"backup_max_count"`
-	BackupCompress  int    `json:"backup_compress"`
+	BackupEnabled    int     `json:"backup_enabled"`
+	BackupCron       string  `json:"backup_cron"`
+	BackupRetention  int     `json:"backup_retention"`
+	BackupMaxCount   int     `json:"backup_max_count"`
+	BackupCompress   int     `json:"backup_compress"`
+	BackupEncrypt    *int    `json:"backup_encrypt"`    // 不传时保留原设置
+	BackupPassphrase *string `json:"backup_passphrase"` // 不传时保留原密码
 }
 
 func CreateBackup(c *gin.Context) {
@@ -204,6 +207,7 @@ func DownloadBackup(c *gin.Context) {
 func GetBackupConfig(c *gin.Context) {
 	service := models.GetBackupService()
 	config := service.GetBackupConfig()
+	config.HasPassphrase = config.BackupPassphrase != ""
 
 	c.JSON(http.StatusOK, APIResponse[models.BackupConfig]{
 		Code:    Success,
@@ -239,6 +243,20 @@ func UpdateBackupConfig(c *gin.Context) {
 		config.BackupCompress = req.BackupCompress
 	}
 	config.BackupEnabled = req.BackupEnabled
+	if req.BackupPassphrase != nil {
+		config.BackupPassphrase = *req.BackupPassphrase
+	}
+	if req.BackupEncrypt != nil {
+		config.BackupEncrypt = *req.BackupEncrypt
+	}
+	if config.BackupEncrypt == 1 && config.BackupPassphrase == "" {
+		c.JSON(http.StatusOK, APIResponse[any]{
+			Code:    BadRequest,
+			Message: "开启备份加密需要设置加密密码",
+			Data:    nil,
+		})
+		return
+	}
 
 	if err := service.UpdateBackupConfig(config); err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{
@@ -312,7 +330,7 @@ func RestoreFromBackup(c *gin.Context) {
 	}
 
 	go func() {
-		backup.Restore(record.FilePath)
+		backup.RestoreWithPassphrase(record.FilePath, req.Passphrase)
 	}()
 
 	c.JSON(http.StatusOK, APIResponse[any]{
@@ -322,7 +340,13 @@ func RestoreFromBackup(c *gin.Context) {
 	})
 }
 
+// UploadAndRestore 上传备份文件并恢复，传了target_id和name时直接从备份目标下载备份文件恢复
 func UploadAndRestore(c *gin.Context) {
+	passphrase := c.PostForm("passphrase")
+	if targetId := helpers.StringToInt(c.PostForm("target_id")); targetId > 0 {
+		restoreFromBackupTarget(c, uint(targetId), c.PostForm("name"), passphrase)
+		return
+	}
 	file, header, err := c.Request.FormFile("file")
 	if err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{
@@ -335,10 +359,10 @@ func UploadAndRestore(c *gin.Context) {
 	defer file.Close()
 
 	ext := strings.ToLower(filepath.Ext(header.Filename))
-	if ext != ".zip" {
+	if ext != ".zip" && ext != ".enc" {
 		c.JSON(http.StatusOK, APIResponse[any]{
 			Code:    BadRequest,
-			Message: "仅支持.zip格式的备份文件",
+			Message: "仅支持.zip和.zip.enc格式的备份文件",
 			Data:    nil,
 		})
 		return
@@ -380,7 +404,7 @@ func UploadAndRestore(c *gin.Context) {
 	}
 
 	go func() {
-		backup.Restore(tempPath)
+		backup.RestoreWithPassphrase(tempPath, passphrase)
 		os.Remove(tempPath)
 	}()
 
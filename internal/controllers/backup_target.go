@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/backup"
+	"Q115-STRM/internal/models"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BackupTargetRequest struct {
+	ID           uint              `json:"id"` // 为0时新建
+	Name         string            `json:"name"`
+	SourceType   models.SourceType `json:"source_type"`
+	AccountId    uint              `json:"account_id"`
+	RemotePath   string            `json:"remote_path"`
+	RemotePathId string            `json:"remote_path_id"`
+	Enabled      bool              `json:"enabled"`
+	Retention    int               `json:"retention"`
+	MaxCount     int               `json:"max_count"`
+}
+
+// GetBackupTargets 获取异地备份目标列表
+func GetBackupTargets(c *gin.Context) {
+	c.JSON(http.StatusOK, APIResponse[[]*models.BackupTarget]{
+		Code:    Success,
+		Message: "success",
+		Data:    models.GetBackupTargets(),
+	})
+}
+
+// SaveBackupTarget 新建或更新异地备份目标
+func SaveBackupTarget(c *gin.Context) {
+	var req BackupTargetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数无效", Data: nil})
+		return
+	}
+	target := &models.BackupTarget{}
+	if req.ID > 0 {
+		existing, err := models.GetBackupTargetById(req.ID)
+		if err != nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+			return
+		}
+		target = existing
+	}
+	if req.Retention < 0 || req.MaxCount < 0 {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "保留天数和保留数量不能小于0", Data: nil})
+		return
+	}
+	target.Name = req.Name
+	target.SourceType = req.SourceType
+	target.AccountId = req.AccountId
+	target.RemotePath = req.RemotePath
+	target.RemotePathId = req.RemotePathId
+	target.Enabled = req.Enabled
+	target.Retention = req.Retention
+	target.MaxCount = req.MaxCount
+	if err := models.SaveBackupTarget(target); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("保存备份目标失败: %v", err), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[*models.BackupTarget]{Code: Success, Message: "备份目标已保存", Data: target})
+}
+
+// DeleteBackupTarget 删除异地备份目标，不会删除远程已上传的备份文件
+func DeleteBackupTarget(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "无效的备份目标ID", Data: nil})
+		return
+	}
+	if err := models.DeleteBackupTarget(uint(id)); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("删除备份目标失败: %v", err), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "备份目标已删除", Data: nil})
+}
+
+// GetBackupTargetFiles 列出备份目标中的备份文件，可以选择其中一个直接恢复
+func GetBackupTargetFiles(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "无效的备份目标ID", Data: nil})
+		return
+	}
+	target, err := models.GetBackupTargetById(uint(id))
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Minute)
+	defer cancel()
+	files, err := backup.ListRemoteBackups(ctx, target)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("获取备份文件列表失败: %v", err), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[[]*backup.RemoteBackup]{Code: Success, Message: "success", Data: files})
+}
+
+// 从备份目标下载备份文件后恢复，下载和恢复都在后台进行
+func restoreFromBackupTarget(c *gin.Context, targetId uint, name string, passphrase string) {
+	if name == "" {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请选择要恢复的备份文件", Data: nil})
+		return
+	}
+	if backup.IsRunning() {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "备份或恢复任务正在运行中", Data: nil})
+		return
+	}
+	target, err := models.GetBackupTargetById(targetId)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	go func() {
+		backup.SetRunningResult("restore", fmt.Sprintf("正在从备份目标 %s 下载 %s", target.Name, name), 0, 0, "", true)
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+		defer cancel()
+		localFile, err := backup.DownloadRemoteBackup(ctx, target, name)
+		if err != nil {
+			backup.SetRunningResult("restore", "下载备份文件失败", 0, 0, err.Error(), false)
+			return
+		}
+		defer os.Remove(localFile)
+		backup.RestoreWithPassphrase(localFile, passphrase)
+	}()
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "已触发数据恢复任务", Data: nil})
+}
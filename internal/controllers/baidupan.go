@@ -258,7 +258,7 @@ func GetBaiduPanUrlByPickCode(c *gin.Context) {
 		// 检查是否开启了本地播放代理，如果开启则跳转到代理链接
 		if models.SettingsGlobal.LocalProxy == 1 {
 			// 跳转到本地代理
-			proxyUrl := fmt.Sprintf("/proxy-115?baidupan=1&url=%s", url.QueryEscape(cachedUrl))
+			proxyUrl := makeProxyUrl(cachedUrl, models.SourceTypeBaiduPan, pickCode, account.UserId)
 			helpers.AppLogger.Infof("通过本地代理访问百度网盘下载链接播放: %s", url.QueryEscape(cachedUrl))
 			c.Redirect(http.StatusFound, proxyUrl)
 			return
@@ -3,12 +3,10 @@ package controllers
 import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
-	"Q115-STRM/internal/v115open"
 	"fmt"
-	"io"
 	"net/http"
+	"slices"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -38,25 +36,15 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		// 优先检查 API Key（GET 参数 api_key）
 		apiKey := c.Query("api_key")
-		if apiKey != "" {
-			// 验证 API Key
-			apiKeyModel, err := models.ValidateAPIKey(apiKey)
-			if err == nil && apiKeyModel != nil {
-				// API Key 验证成功
-				// 获取关联的用户信息
-				user, err := models.GetUserById(apiKeyModel.UserID)
-				if err == nil && user != nil {
-					LoginedUser = user
-					// 将用户名保存到上下文
-					c.Set("username", user.Username)
-					// 异步更新最后使用时间
-					go func() {
-						apiKeyModel.UpdateLastUsedAt()
-					}()
-					c.Next()
-					return
-				}
+		if user := apiKeyUser(apiKey); user != nil {
+			// API Key 继承所属用户的角色
+			if !checkRoleMethod(c, user) {
+				return
 			}
+			// 将用户保存到上下文
+			setLoginUser(c, user)
+			c.Next()
+			return
 		}
 
 		// 回退到 JWT Token 验证
@@ -82,18 +70,114 @@ func JWTAuthMiddleware() func(c *gin.Context) {
 			return
 		}
 		// helpers.AppLogger.Debugf("Authenticated user: %s", loginUser.Username)
-		LoginedUser, err = models.GetUserById(loginUser.ID)
+		user, err := models.GetUserById(loginUser.ID)
 		if err != nil {
 			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("获取用户信息失败：%v", err), Data: nil})
 			c.Abort()
 			return
-		} else {
-			// helpers.AppLogger.Debugf("获取用户信息成功: %+v", LoginedUser)
 		}
-		// 将当前请求的username信息保存到请求的上下文c上
-		c.Set("username", loginUser.Username)
-		c.Next() // 后续的处理函数可以用过c.Get("username")来获取当前请求的用户信息
+		if !checkRoleMethod(c, user) {
+			return
+		}
+		// 将当前请求的用户信息保存到请求的上下文c上
+		setLoginUser(c, user)
+		c.Next() // 后续的处理函数可以用过GetLoginUser(c)来获取当前请求的用户信息
+	}
+}
+
+// 只读用户允许访问的非GET接口
+var viewerAllowedPaths = []string{"/api/user/change"}
+
+// 将当前请求的用户保存到上下文，不使用全局变量避免并发请求互相覆盖
+func setLoginUser(c *gin.Context, user *models.User) {
+	c.Set("username", user.Username)
+	c.Set("user", user)
+}
+
+// GetLoginUser 获取当前请求的登录用户，未登录返回nil
+func GetLoginUser(c *gin.Context) *models.User {
+	value, ok := c.Get("user")
+	if !ok {
+		return nil
+	}
+	user, _ := value.(*models.User)
+	return user
+}
+
+// 验证API Key，返回API Key所属的用户，无效时返回nil
+func apiKeyUser(apiKey string) *models.User {
+	if apiKey == "" {
+		return nil
+	}
+	apiKeyModel, err := models.ValidateAPIKey(apiKey)
+	if err != nil || apiKeyModel == nil {
+		return nil
+	}
+	user, err := models.GetUserById(apiKeyModel.UserID)
+	if err != nil || user == nil {
+		return nil
+	}
+	// 异步更新最后使用时间
+	go func() {
+		apiKeyModel.UpdateLastUsedAt()
+	}()
+	return user
+}
+
+// 只读用户只能发起GET请求，没有权限时中止请求
+func checkRoleMethod(c *gin.Context, user *models.User) bool {
+	if user.GetRole() != models.UserRoleViewer {
+		return true
+	}
+	if c.Request.Method == http.MethodGet || slices.Contains(viewerAllowedPaths, c.FullPath()) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "只读用户没有权限执行该操作", Data: nil})
+	c.Abort()
+	return false
+}
+
+// RequireRole 限制只有指定角色的用户可以访问，需要在JWTAuthMiddleware之后使用
+func RequireRole(roles ...models.UserRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := GetLoginUser(c)
+		if user == nil || !slices.Contains(roles, user.GetRole()) {
+			c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "没有权限执行该操作", Data: nil})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// 检查当前用户是否可以操作同步目录，没有权限时返回403
+func checkSyncPathPermission(c *gin.Context, syncPathId uint) bool {
+	user := GetLoginUser(c)
+	if user != nil && user.CanAccessSyncPath(syncPathId) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "没有权限操作该同步目录", Data: nil})
+	return false
+}
+
+// 检查当前用户是否可以操作刮削目录，没有权限时返回403
+func checkScrapePathPermission(c *gin.Context, scrapePathId uint) bool {
+	user := GetLoginUser(c)
+	if user != nil && user.CanAccessScrapePath(scrapePathId) {
+		return true
+	}
+	c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "没有权限操作该刮削目录", Data: nil})
+	return false
+}
+
+// 操作员只能修改有权限的目录中除来源、账号和路径以外的设置，changed为true时返回403
+func checkOperatorPathChange(c *gin.Context, changed bool) bool {
+	user := GetLoginUser(c)
+	if !changed || user == nil || user.GetRole() != models.UserRoleOperator {
+		return true
 	}
+	c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "操作员不能修改目录的来源类型、账号和路径", Data: nil})
+	return false
 }
 
 // ValidateJWT 校验JWT
@@ -113,57 +197,6 @@ func ValidateJWT(tokenString string) (*LoginUser, error) {
 	return claims, nil
 }
 
-func Proxy115(c *gin.Context) {
-	// 获取原始url参数
-	target := c.Request.URL.Query().Get("url")
-	baidupan := c.Request.URL.Query().Get("baidupan")
-	if target == "" {
-		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "缺少url参数", Data: nil})
-		return
-	}
-	helpers.AppLogger.Infof("反代网盘下载链接: %s", target)
-	// // 只允许反代 cdnfhnfile.115cdn.net 域名
-	// if !strings.HasPrefix(target, "https://cdnfhnfile.115cdn.net/") {
-	// 	c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "只允许反代115CDN链接", Data: nil})
-	// 	return
-	// }
-	// 创建请求
-	req, err := http.NewRequest("GET", target, nil)
-	if err != nil {
-		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求创建失败", Data: nil})
-		return
-	}
-	// 复制客户端的 Range、Cookie、Referer 等头部
-	for k, v := range c.Request.Header {
-		if k == "Range" || k == "Cookie" || k == "Referer" {
-			// helpers.AppLogger.Infof("响应头: %s=%s", k, v)
-			req.Header[k] = v
-		}
-	}
-	if baidupan != "" {
-		req.Header.Set("User-Agent", "pan.baidu.com")
-	} else {
-		req.Header.Set("User-Agent", v115open.DEFAULTUA)
-	}
-	// 发起请求
-	client := &http.Client{Timeout: 60 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		c.JSON(http.StatusBadGateway, APIResponse[any]{Code: BadRequest, Message: "反代请求失败: " + err.Error(), Data: nil})
-		return
-	}
-	defer resp.Body.Close()
-	// 复制响应头
-	for k, v := range resp.Header {
-		for _, vv := range v {
-			c.Header(k, vv)
-		}
-	}
-	c.Status(resp.StatusCode)
-	// 复制响应内容
-	_, _ = io.Copy(c.Writer, resp.Body)
-}
-
 func Cors() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		method := c.Request.Method               //请求方法
@@ -191,8 +224,8 @@ func Cors() gin.HandlerFunc {
 			c.Set("content-type", "application/json")                                                                                                                                                              // 设置返回格式是json
 		}
 
-		// 放行所有OPTIONS方法
-		if method == "OPTIONS" {
+		// 放行所有OPTIONS方法，内置WebDAV服务需要自己响应OPTIONS（DAV头）
+		if method == "OPTIONS" && !strings.HasPrefix(c.Request.URL.Path, webDavServerPrefix+"/") {
 			c.JSON(http.StatusOK, "Options Request!")
 		}
 		// 处理请求
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 创建带登录用户的测试路由，user为nil时模拟未登录
+func newRoleTestRouter(user *models.User, handlers ...gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if user != nil {
+			setLoginUser(c, user)
+		}
+	})
+	r.GET("/test/:id", handlers...)
+	return r
+}
+
+func TestRequireRole(t *testing.T) {
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	tests := []struct {
+		name string
+		user *models.User
+		code int
+	}{
+		{"未登录", nil, http.StatusForbidden},
+		{"旧用户默认管理员", &models.User{Username: "old"}, http.StatusOK},
+		{"管理员", &models.User{Username: "admin", Role: models.UserRoleAdmin}, http.StatusOK},
+		{"操作员", &models.User{Username: "op", Role: models.UserRoleOperator}, http.StatusForbidden},
+		{"只读用户", &models.User{Username: "viewer", Role: models.UserRoleViewer}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := newRoleTestRouter(tt.user, RequireRole(models.UserRoleAdmin), ok)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test/1", nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: code = %d, want %d", tt.name, w.Code, tt.code)
+		}
+	}
+}
+
+func TestCheckSyncPathPermission(t *testing.T) {
+	handler := func(c *gin.Context) {
+		if checkSyncPathPermission(c, uint(helpers.StringToInt(c.Param("id")))) {
+			c.Status(http.StatusOK)
+		}
+	}
+	operator := &models.User{Username: "op", Role: models.UserRoleOperator, SyncPathIdArray: []uint{1}}
+	viewer := &models.User{Username: "viewer", Role: models.UserRoleViewer}
+	tests := []struct {
+		name string
+		user *models.User
+		path string
+		code int
+	}{
+		{"操作员访问有权限的目录", operator, "/test/1", http.StatusOK},
+		{"操作员访问其他目录", operator, "/test/2", http.StatusForbidden},
+		{"只读用户可以查看所有目录", viewer, "/test/2", http.StatusOK},
+		{"未登录", nil, "/test/1", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := newRoleTestRouter(tt.user, handler)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: code = %d, want %d", tt.name, w.Code, tt.code)
+		}
+	}
+	if operator.CanAccessScrapePath(1) {
+		t.Errorf("操作员没有分配刮削目录时不能访问")
+	}
+}
+
+func TestCheckOperatorPathChange(t *testing.T) {
+	changed := func(c *gin.Context) {
+		if checkOperatorPathChange(c, c.Param("id") == "1") {
+			c.Status(http.StatusOK)
+		}
+	}
+	operator := &models.User{Username: "op", Role: models.UserRoleOperator}
+	tests := []struct {
+		name string
+		user *models.User
+		path string
+		code int
+	}{
+		{"操作员修改路径", operator, "/test/1", http.StatusForbidden},
+		{"操作员修改其他设置", operator, "/test/0", http.StatusOK},
+		{"旧用户默认管理员", &models.User{Username: "old"}, "/test/1", http.StatusOK},
+		{"管理员修改路径", &models.User{Username: "admin", Role: models.UserRoleAdmin}, "/test/1", http.StatusOK},
+	}
+	for _, tt := range tests {
+		r := newRoleTestRouter(tt.user, changed)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: code = %d, want %d", tt.name, w.Code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/scrape"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryRuleDryRunRequest struct {
+	ScrapeMediaFileId uint                   `json:"scrape_media_file_id"`
+	Rules             []*models.CategoryRule `json:"rules"` // 要测试的规则，为空时使用已启用的规则
+}
+
+type CategoryRuleDryRunResponse struct {
+	Facts        *models.CategoryFacts       `json:"facts"`         // 参与匹配的影视剧信息
+	Results      []models.CategoryRuleResult `json:"results"`       // 到命中为止每条规则的匹配结果
+	MatchedRule  *models.CategoryRule        `json:"matched_rule"`  // 命中的规则，为空时刮削使用流派和语言（国家）匹配
+	CategoryName string                      `json:"category_name"` // 命中规则对应的分类名称
+}
+
+// GetCategoryRules 获取分类规则列表，media_type为空时返回所有规则
+func GetCategoryRules(c *gin.Context) {
+	c.JSON(http.StatusOK, APIResponse[[]*models.CategoryRule]{
+		Code:    Success,
+		Message: "success",
+		Data:    models.GetCategoryRules(models.MediaType(c.Query("media_type"))),
+	})
+}
+
+// SaveCategoryRule 新建或更新分类规则，id为0时新建
+func SaveCategoryRule(c *gin.Context) {
+	var rule models.CategoryRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数无效", Data: nil})
+		return
+	}
+	if err := models.SaveCategoryRule(&rule); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("保存分类规则失败: %v", err), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[*models.CategoryRule]{Code: Success, Message: "分类规则已保存", Data: &rule})
+}
+
+// DeleteCategoryRule 删除分类规则
+func DeleteCategoryRule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "无效的分类规则ID", Data: nil})
+		return
+	}
+	if err := models.DeleteCategoryRule(uint(id)); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("删除分类规则失败: %v", err), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "分类规则已删除", Data: nil})
+}
+
+// DryRunCategoryRules 用已识别的刮削文件测试分类规则，返回每条规则每个条件的匹配结果
+func DryRunCategoryRules(c *gin.Context) {
+	var req CategoryRuleDryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil || req.ScrapeMediaFileId == 0 {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数无效", Data: nil})
+		return
+	}
+	mediaFile := models.GetScrapeMediaFileById(req.ScrapeMediaFileId)
+	if mediaFile == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "刮削文件不存在", Data: nil})
+		return
+	}
+	if mediaFile.Media == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "刮削文件还没有识别出影视剧信息", Data: nil})
+		return
+	}
+	rules := req.Rules
+	if len(rules) == 0 {
+		rules = models.GetEnabledCategoryRules(mediaFile.MediaType)
+	}
+	facts := scrape.BuildCategoryFacts(mediaFile, rules)
+	rule, results := models.EvaluateCategoryRules(rules, facts)
+	resp := CategoryRuleDryRunResponse{Facts: facts, Results: results, MatchedRule: rule}
+	if rule != nil {
+		resp.CategoryName = models.GetCategoryName(mediaFile.MediaType, rule.CategoryId)
+	}
+	c.JSON(http.StatusOK, APIResponse[CategoryRuleDryRunResponse]{Code: Success, Message: "success", Data: resp})
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/configio"
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/notificationmanager"
+	"Q115-STRM/internal/synccron"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 配置文件大小上限
+const maxConfigDocumentSize = 10 << 20
+
+// ExportConfig 导出配置为YAML文档，默认隐藏密码和密钥，redact=0时导出完整配置
+func ExportConfig(c *gin.Context) {
+	redact := c.DefaultQuery("redact", "1") != "0"
+	data, err := configio.Export(redact)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "导出配置失败: " + err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=qms-config-%s.yaml", time.Now().Format("20060102_150405")))
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", data)
+}
+
+// ImportConfig 导入YAML配置，可以直接作为请求体提交，也可以作为file字段上传
+// plan=1时只返回导入计划，不修改数据库
+func ImportConfig(c *gin.Context) {
+	data, err := readConfigDocument(c)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "读取配置文件失败: " + err.Error()})
+		return
+	}
+	if len(data) == 0 {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "配置文件为空"})
+		return
+	}
+	plan, err := configio.Import(data, c.Query("plan") != "1")
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[*configio.Plan]{Code: BadRequest, Message: "导入配置失败: " + err.Error(), Data: plan})
+		return
+	}
+	if len(plan.Errors) > 0 {
+		c.JSON(http.StatusOK, APIResponse[*configio.Plan]{Code: BadRequest, Message: "配置文件存在错误，没有导入任何配置", Data: plan})
+		return
+	}
+	if !plan.Applied {
+		c.JSON(http.StatusOK, APIResponse[*configio.Plan]{Code: Success, Message: "导入计划", Data: plan})
+		return
+	}
+	if plan.Changed() {
+		reloadImportedConfig(plan)
+	}
+	c.JSON(http.StatusOK, APIResponse[*configio.Plan]{Code: Success, Message: "配置已导入", Data: plan})
+}
+
+func readConfigDocument(c *gin.Context) ([]byte, error) {
+	if file, _, err := c.Request.FormFile("file"); err == nil {
+		defer file.Close()
+		return io.ReadAll(io.LimitReader(file, maxConfigDocumentSize))
+	}
+	return io.ReadAll(io.LimitReader(c.Request.Body, maxConfigDocumentSize))
+}
+
+// 导入后刷新内存中的配置和定时任务
+func reloadImportedConfig(plan *configio.Plan) {
+	if plan.Touched("settings") {
+		models.LoadSettings()
+	}
+	if plan.Touched("emby_config") {
+		models.GlobalEmbyConfig = nil
+		models.GetEmbyConfig()
+	}
+	if plan.Touched("backup_config") {
+		service := models.GetBackupService()
+		if err := service.UpdateBackupConfig(models.GetOrCreateBackupConfig()); err != nil {
+			helpers.AppLogger.Warnf("导入配置后刷新备份配置失败: %v", err)
+		}
+	}
+	if plan.Touched(configio.NotificationResources()...) && notificationmanager.GlobalEnhancedNotificationManager != nil {
+		var channels []models.NotificationChannel
+		db.Db.Find(&channels)
+		for _, channel := range channels {
+			if err := notificationmanager.GlobalEnhancedNotificationManager.ReloadChannel(channel.ID); err != nil {
+				helpers.AppLogger.Warnf("导入配置后重新加载通知渠道 %s 失败: %v", channel.ChannelName, err)
+			}
+		}
+	}
+	if plan.Touched("settings", "sync_paths") {
+		synccron.InitSyncCron()
+	}
+	synccron.InitCron()
+	helpers.AppLogger.Info("已导入配置并刷新相关服务")
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/syncstrm"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DuplicateReportResponse struct {
+	Running bool                    `json:"running"` // 是否正在扫描或者删除
+	Report  *models.DuplicateReport `json:"report"`  // 最近一次扫描结果，从未扫描时为空
+}
+
+type DuplicateDeleteRequest struct {
+	ReportId    int64  `json:"report_id"`     // 扫描结果的ID，必须是最近一次扫描
+	SyncFileIds []uint `json:"sync_file_ids"` // 要删除的副本，每组至少保留一个
+	Confirm     bool   `json:"confirm"`       // 为false时只返回将要删除的文件，不执行删除
+}
+
+// GetDuplicateReport 查询重复文件扫描状态和最近一次扫描结果
+func GetDuplicateReport(c *gin.Context) {
+	running, report := syncstrm.GetDuplicateReport()
+	c.JSON(http.StatusOK, APIResponse[DuplicateReportResponse]{Code: Success, Message: "success", Data: DuplicateReportResponse{Running: running, Report: report}})
+}
+
+// StartDuplicateScan 启动重复文件扫描，扫描在后台执行
+func StartDuplicateScan(c *gin.Context) {
+	if err := syncstrm.StartDuplicateScan(); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "重复文件扫描已启动", Data: nil})
+}
+
+// DeleteDuplicateFiles 通过网盘删除选中的重复副本，confirm为false时只预览
+func DeleteDuplicateFiles(c *gin.Context) {
+	var req DuplicateDeleteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数无效", Data: nil})
+		return
+	}
+	result, err := syncstrm.DeleteDuplicateCopies(c.Request.Context(), req.ReportId, req.SyncFileIds, req.Confirm)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[*syncstrm.DuplicateDeleteResult]{Code: Success, Message: "success", Data: result})
+}
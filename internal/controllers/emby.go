@@ -192,26 +192,18 @@ func Webhook(ctx *gin.Context) {
 			}
 		}()
 		if event.Item.Type == "Movie" || event.Item.Type == "Episode" || event.Item.Type == "Season" || event.Item.Type == "Series" {
-			// 触发联动删除
+			// 触发联动删除，先加入待删除队列，到期后由定时任务执行，到期前可以取消
 			if models.GlobalEmbyConfig != nil && models.GlobalEmbyConfig.EnableDeleteNetdisk == 1 {
-				switch event.Item.Type {
-				case "Movie":
-					// 电影：在网盘中将视频文件的父目录一起删除
-					// 查找Item.Id对应的SyncFileId
-					models.DeleteNetdiskMovieByEmbyItemId(event.Item.ID)
-				case "Episode":
-					// 集：删除视频文件+元数据（nfo、封面)
-					// 查找Item.Id对应的SyncFileId
-					models.DeleteNetdiskEpisodeByEmbyItemId(event.Item.ID)
-				case "Season":
-					// 季：先检查视频文件的父目录，如果父目录是季文件夹则删除该文件夹；如果父目录是有tvshow的目录则仅删除季下所有集对应的视频文件+元数据（nfo、封面)
-					// 查找EmbyMediaItem.SeasonId = item.Id的记录，取其中一条的ItemId对应的SyncFileId的SyncFile.Path作为季目录来处理
-					models.DeleteNetdiskSeasonByItemId(event.Item.ID)
-				case "Series":
-					// 剧：在网盘中将tvshow.nfo的父目录删除
-					// 查找EmbyMediaItem.SeriesId = item.Id的记录，取其中一条的ItemId对应的SyncFileId的SyncFile.Path作为季目录来处理
-					models.DeleteNetdiskTvshowByItemId(event.Item.ID)
-				default:
+				// 电影：在网盘中将视频文件的父目录一起删除
+				// 集：删除视频文件+元数据（nfo、封面)
+				// 季：如果父目录是季文件夹则删除该文件夹，否则仅删除季下所有集对应的视频文件+元数据（nfo、封面)
+				// 剧：在网盘中将tvshow.nfo的父目录删除
+				itemName := event.Item.Name
+				if event.Item.SeriesName != "" && event.Item.Type != "Series" {
+					itemName = event.Item.SeriesName + " " + event.Item.Name
+				}
+				if _, err := models.ScheduleNetdiskDeletion(event.Item.ID, event.Item.Type, itemName); err != nil {
+					helpers.AppLogger.Errorf("添加Emby Item %s 的联动删除计划失败: %v", event.Item.ID, err)
 				}
 			}
 		}
@@ -5,6 +5,7 @@ import (
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/synccron"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -45,6 +46,7 @@ func GetEmbyConfig(c *gin.Context) {
 type updateEmbyConfigRequest struct {
 	EmbyUrl                 string `json:"emby_url"`
 	EmbyApiKey              string `json:"emby_api_key"`
+	ServerType              string `json:"server_type"`
 	EnableDeleteNetdisk     int    `json:"enable_delete_netdisk"`
 	EnableRefreshLibrary    int    `json:"enable_refresh_library"`
 	EnableMediaNotification int    `json:"enable_media_notification"`
@@ -52,6 +54,8 @@ type updateEmbyConfigRequest struct {
 	EnableAuth              int    `json:"enable_auth"`
 	SyncEnabled             int    `json:"sync_enabled"`
 	SyncCron                string `json:"sync_cron"`
+	DeleteNetdiskDelay      *int   `json:"delete_netdisk_delay"`
+	DeleteNetdiskTrashPath  string `json:"delete_netdisk_trash_path"`
 }
 
 // UpdateEmbyConfig 更新Emby配置
@@ -62,6 +66,7 @@ type updateEmbyConfigRequest struct {
 // @Produce json
 // @Param emby_url body string false "Emby服务器地址"
 // @Param emby_api_key body string false "Emby API密钥"
+// @Param server_type body string false "媒体服务器类型：emby、jellyfin，默认emby，修改后重启生效"
 // @Param enable_delete_netdisk body integer false "是否启用网盘删除"
 // @Param enable_refresh_library body integer false "是否启用库刷新"
 // @Param enable_media_notification body integer false "是否启用媒体通知"
@@ -69,6 +74,8 @@ type updateEmbyConfigRequest struct {
 // @Param enable_auth body integer false "是否启用Webhook鉴权"
 // @Param sync_enabled body integer false "是否启用同步"
 // @Param sync_cron body string false "同步Cron表达式"
+// @Param delete_netdisk_delay body integer false "联动删除网盘文件的延迟（分钟），0表示下一分钟执行"
+// @Param delete_netdisk_trash_path body string false "115网盘回收目录，不为空时联动删除改为移动到该目录"
 // @Success 200 {object} object
 // @Failure 200 {object} object
 // @Router /emby/config [put]
@@ -96,6 +103,10 @@ func UpdateEmbyConfig(c *gin.Context) {
 	}
 	config.EmbyUrl = req.EmbyUrl
 	config.EmbyApiKey = req.EmbyApiKey
+	config.ServerType = req.ServerType
+	if config.ServerType != "jellyfin" {
+		config.ServerType = "emby"
+	}
 	config.EnableDeleteNetdisk = req.EnableDeleteNetdisk
 	config.EnableRefreshLibrary = req.EnableRefreshLibrary
 	config.EnableMediaNotification = req.EnableMediaNotification
@@ -103,6 +114,11 @@ func UpdateEmbyConfig(c *gin.Context) {
 	config.EnableAuth = req.EnableAuth
 	config.SyncEnabled = req.SyncEnabled
 	config.SyncCron = "0 * * * *"
+	if req.DeleteNetdiskDelay != nil {
+		// 不传时保留原来的延迟，避免部分更新时变成立即删除
+		config.DeleteNetdiskDelay = max(*req.DeleteNetdiskDelay, 0)
+	}
+	config.DeleteNetdiskTrashPath = strings.TrimSpace(req.DeleteNetdiskTrashPath)
 	if config.SyncEnabled == 0 {
 		config.EnableDeleteNetdisk = 0
 		config.EnableRefreshLibrary = 0
@@ -117,12 +133,15 @@ func UpdateEmbyConfig(c *gin.Context) {
 		updates := map[string]interface{}{
 			"emby_url":                  config.EmbyUrl,
 			"emby_api_key":              config.EmbyApiKey,
+			"server_type":               config.ServerType,
 			"enable_delete_netdisk":     config.EnableDeleteNetdisk,
 			"enable_refresh_library":    config.EnableRefreshLibrary,
 			"enable_media_notification": config.EnableMediaNotification,
 			"enable_extract_media_info": config.EnableExtractMediaInfo,
 			"enable_auth":               config.EnableAuth,
 			"sync_enabled":              config.SyncEnabled,
+			"delete_netdisk_delay":      config.DeleteNetdiskDelay,
+			"delete_netdisk_trash_path": config.DeleteNetdiskTrashPath,
 		}
 		if err := config.Update(updates); err != nil {
 			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "更新Emby配置失败: " + err.Error()})
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEmbyPendingDeletions 获取待删除的网盘文件列表
+// @Summary 获取待删除的网盘文件列表
+// @Description 分页获取Emby删除媒体后等待联动删除网盘文件的计划
+// @Tags Emby管理
+// @Accept json
+// @Produce json
+// @Param status query string false "状态：pending、cancelled、done、failed，为空时查询全部"
+// @Param page query integer false "页码，默认1"
+// @Param page_size query integer false "每页数量，默认20"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /emby/pending-deletions [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetEmbyPendingDeletions(c *gin.Context) {
+	type pendingDeletionRequest struct {
+		Status   string `form:"status" json:"status"`                                 // 状态
+		Page     int    `form:"page" json:"page" binding:"omitempty,min=1"`           // 页码，默认1
+		PageSize int    `form:"page_size" json:"page_size" binding:"omitempty,min=1"` // 每页数量，默认20
+	}
+	var req pendingDeletionRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	page := req.Page
+	pageSize := req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 20
+	}
+	list, total, err := models.GetEmbyPendingDeletionList(req.Status, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取待删除列表失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取待删除列表成功", Data: map[string]any{
+		"list":      list,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}})
+}
+
+// CancelEmbyPendingDeletions 取消待删除的网盘文件
+// @Summary 取消待删除的网盘文件
+// @Description 取消还没有到期执行的联动删除计划，已经执行的无法取消
+// @Tags Emby管理
+// @Accept json
+// @Produce json
+// @Param ids body []integer true "待删除记录ID列表"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /emby/pending-deletions/cancel [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CancelEmbyPendingDeletions(c *gin.Context) {
+	type cancelRequest struct {
+		Ids []uint `json:"ids" form:"ids" binding:"required,min=1"` // 待删除记录ID列表
+	}
+	var req cancelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	count, err := models.CancelEmbyPendingDeletions(req.Ids)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "取消删除失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "取消删除成功", Data: map[string]any{"cancelled": count}})
+}
@@ -9,7 +9,7 @@ import (
 
 // UploadList 获取上传队列列表
 // @Summary 获取上传队列
-// @Description 按状态分页获取上传队列任务列表
+// @Description 按状态分页获取上传队列任务列表，每个任务包含已上传字节数uploaded_size和进度百分比progress
 // @Tags 队列管理
 // @Accept json
 // @Produce json
@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"Q115-STRM/emby302/web/cache"
+	"Q115-STRM/internal/metrics"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/synccron"
+	"Q115-STRM/internal/v115open"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 刮削文件的所有状态，没有文件的状态也输出0，方便Prometheus计算差值
+var metricsScrapeStatuses = []models.ScrapeMediaStatus{
+	models.ScrapeMediaStatusUnscanned,
+	models.ScrapeMediaStatusScanned,
+	models.ScrapeMediaStatusScraping,
+	models.ScrapeMediaStatusScraped,
+	models.ScrapeMediaStatusRenaming,
+	models.ScrapeMediaStatusRenamed,
+	models.ScrapeMediaStatusRenameFailed,
+	models.ScrapeMediaStatusIgnore,
+	models.ScrapeMediaStatusScrapeFailed,
+	models.ScrapeMediaStatusRollbacking,
+}
+
+var metricsUploadStatuses = []models.UploadStatus{
+	models.UploadStatusPending,
+	models.UploadStatusUploading,
+	models.UploadStatusCompleted,
+	models.UploadStatusFailed,
+	models.UploadStatusCancelled,
+}
+
+// 下载状态的String()返回中文，标签值使用和上传状态一致的英文
+var metricsDownloadStatuses = []struct {
+	status models.DownloadStatus
+	label  string
+}{
+	{models.DownloadStatusPending, "pending"},
+	{models.DownloadStatusDownloading, "downloading"},
+	{models.DownloadStatusCompleted, "completed"},
+	{models.DownloadStatusFailed, "failed"},
+	{models.DownloadStatusCancelled, "cancelled"},
+}
+
+// Metrics Prometheus监控指标
+// @Summary Prometheus监控指标
+// @Description 以Prometheus文本格式输出同步、刮削、上传下载队列、115接口、本地代理和Emby缓存的指标，需要管理员的API Key，可以通过api_key参数、X-API-Key请求头或者Bearer Token传递
+// @Tags 系统设置
+// @Produce plain
+// @Param api_key query string false "API Key"
+// @Success 200 {string} string "Prometheus文本格式的指标"
+// @Failure 401 {string} string "API Key无效"
+// @Router /metrics [get]
+// @Security ApiKeyAuth
+func Metrics(c *gin.Context) {
+	user := metricsAuth(c)
+	if user == nil {
+		c.String(http.StatusUnauthorized, "Unauthorized: invalid api_key")
+		return
+	}
+	if user.GetRole() != models.UserRoleAdmin {
+		c.String(http.StatusForbidden, "Forbidden: admin api_key required")
+		return
+	}
+	w := metrics.NewWriter()
+	writeSyncMetrics(w)
+	writeScrapeMetrics(w)
+	writeQueueMetrics(w)
+	write115Metrics(w)
+	writeProxyMetrics(w)
+	writeEmbyCacheMetrics(w)
+	c.Data(http.StatusOK, metrics.ContentType, w.Bytes())
+}
+
+// 从api_key参数、X-API-Key请求头或者Bearer Token中取API Key认证
+func metricsAuth(c *gin.Context) *models.User {
+	apiKey := c.Query("api_key")
+	if apiKey == "" {
+		apiKey = c.GetHeader("X-API-Key")
+	}
+	if apiKey == "" {
+		apiKey = strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	}
+	return apiKeyUser(apiKey)
+}
+
+// 同步目录的标签和最后一次结束的同步任务
+type metricsSyncPath struct {
+	labels []metrics.Label
+	sync   *models.Sync
+}
+
+// 最后一次同步的数值，没有同步记录时为0
+func (m metricsSyncPath) value(get func(sync *models.Sync) int64) float64 {
+	if m.sync == nil {
+		return 0
+	}
+	return float64(get(m.sync))
+}
+
+// 每个同步目录最后一次同步的耗时、新增数量和失败次数，以及同步队列的等待数量
+func writeSyncMetrics(w *metrics.Writer) {
+	latest := models.GetLatestSyncPerPath()
+	failures := models.CountSyncFailuresPerPath()
+	paths := make([]metricsSyncPath, 0)
+	pathIds := make([]uint, 0)
+	for _, sp := range models.GetAllSyncPaths() {
+		paths = append(paths, metricsSyncPath{
+			labels: []metrics.Label{
+				metrics.L("sync_path_id", fmt.Sprintf("%d", sp.ID)),
+				metrics.L("source_type", string(sp.SourceType)),
+				metrics.L("local_path", sp.LocalPath),
+			},
+			sync: latest[sp.ID],
+		})
+		pathIds = append(pathIds, sp.ID)
+	}
+	gauges := []struct {
+		name string
+		help string
+		get  func(sync *models.Sync) int64
+	}{
+		{"qms_sync_last_finish_timestamp_seconds", "同步目录最后一次同步结束的时间", func(s *models.Sync) int64 { return s.FinishAt }},
+		{"qms_sync_last_duration_seconds", "同步目录最后一次同步的耗时", func(s *models.Sync) int64 {
+			if s.FinishAt == 0 {
+				return 0
+			}
+			return s.FinishAt - s.CreatedAt
+		}},
+		{"qms_sync_last_success", "同步目录最后一次同步是否成功", func(s *models.Sync) int64 {
+			if s.Status == models.SyncStatusCompleted {
+				return 1
+			}
+			return 0
+		}},
+		{"qms_sync_last_total_files", "同步目录最后一次同步处理的文件数量", func(s *models.Sync) int64 { return int64(s.Total) }},
+		{"qms_sync_last_new_strm", "同步目录最后一次同步新增的STRM文件数量", func(s *models.Sync) int64 { return int64(s.NewStrm) }},
+		{"qms_sync_last_new_meta", "同步目录最后一次同步新增的元数据文件数量", func(s *models.Sync) int64 { return int64(s.NewMeta) }},
+		{"qms_sync_last_new_upload", "同步目录最后一次同步新增的上传文件数量", func(s *models.Sync) int64 { return int64(s.NewUpload) }},
+	}
+	for _, gauge := range gauges {
+		w.Describe(gauge.name, gauge.help, metrics.TypeGauge)
+		for _, path := range paths {
+			w.Gauge(gauge.name, gauge.help, path.value(gauge.get), path.labels...)
+		}
+	}
+	w.Describe("qms_sync_failures", "同步目录保留的同步记录中失败的次数", metrics.TypeGauge)
+	for i, path := range paths {
+		w.Gauge("qms_sync_failures", "同步目录保留的同步记录中失败的次数", float64(failures[pathIds[i]]), path.labels...)
+	}
+
+	queues := synccron.GetAllNewQueueStatus()
+	w.Describe("qms_sync_queue_waiting", "同步队列中等待执行的任务数量", metrics.TypeGauge)
+	for _, sourceType := range metrics.SortedKeys(queues) {
+		waiting, _ := queues[sourceType]["waiting_count"].(int)
+		w.Gauge("qms_sync_queue_waiting", "同步队列中等待执行的任务数量", float64(waiting), metrics.L("source_type", string(sourceType)))
+	}
+	w.Describe("qms_sync_queue_running", "同步队列是否正在执行任务", metrics.TypeGauge)
+	for _, sourceType := range metrics.SortedKeys(queues) {
+		running, _ := queues[sourceType]["is_running"].(bool)
+		w.Gauge("qms_sync_queue_running", "同步队列是否正在执行任务", metrics.Bool(running), metrics.L("source_type", string(sourceType)))
+	}
+}
+
+// 按状态统计的刮削文件数量
+func writeScrapeMetrics(w *metrics.Writer) {
+	counts := models.CountScrapeMediaFilesByStatus()
+	for _, status := range metricsScrapeStatuses {
+		w.Gauge("qms_scrape_media_files", "按状态统计的刮削文件数量", float64(counts[status]), metrics.L("status", string(status)))
+	}
+}
+
+// 上传和下载队列按状态统计的任务数量
+func writeQueueMetrics(w *metrics.Writer) {
+	uploads := models.CountUploadTasksByStatus()
+	for _, status := range metricsUploadStatuses {
+		w.Gauge("qms_upload_tasks", "上传队列按状态统计的任务数量", float64(uploads[status]), metrics.L("status", status.String()))
+	}
+	downloads := models.CountDownloadTasksByStatus()
+	for _, item := range metricsDownloadStatuses {
+		w.Gauge("qms_download_tasks", "下载队列按状态统计的任务数量", float64(downloads[item.status]), metrics.L("status", item.label))
+	}
+}
+
+// 每个115账号的接口请求统计和限流状态
+func write115Metrics(w *metrics.Writer) {
+	executors := v115open.GetExecutors()
+	accountIds := metrics.SortedKeys(executors)
+	stats := make(map[uint]*v115open.StatsSnapshot, len(executors))
+	throttles := make(map[uint]v115open.ThrottleStatus, len(executors))
+	for _, id := range accountIds {
+		stats[id] = executors[id].GetStats(time.Hour)
+		throttles[id] = executors[id].GetThrottleStatus()
+	}
+	label := func(id uint) metrics.Label {
+		return metrics.L("account_id", fmt.Sprintf("%d", id))
+	}
+	w.Describe("qms_115_api_requests_total", "115开放平台接口的请求总数", metrics.TypeCounter)
+	for _, id := range accountIds {
+		w.Counter("qms_115_api_requests_total", "115开放平台接口的请求总数", float64(stats[id].TotalRequests), label(id))
+	}
+	w.Describe("qms_115_api_requests_last_minute", "最近1分钟的115接口请求数", metrics.TypeGauge)
+	for _, id := range accountIds {
+		w.Gauge("qms_115_api_requests_last_minute", "最近1分钟的115接口请求数", float64(stats[id].QPMCount), label(id))
+	}
+	w.Describe("qms_115_api_requests_last_hour", "最近1小时的115接口请求数", metrics.TypeGauge)
+	for _, id := range accountIds {
+		w.Gauge("qms_115_api_requests_last_hour", "最近1小时的115接口请求数", float64(stats[id].QPHCount), label(id))
+	}
+	w.Describe("qms_115_api_throttled_last_hour", "最近1小时被115限流的请求数", metrics.TypeGauge)
+	for _, id := range accountIds {
+		w.Gauge("qms_115_api_throttled_last_hour", "最近1小时被115限流的请求数", float64(stats[id].ThrottledCount), label(id))
+	}
+	w.Describe("qms_115_api_avg_response_seconds", "最近1小时115接口的平均响应时间", metrics.TypeGauge)
+	for _, id := range accountIds {
+		w.Gauge("qms_115_api_avg_response_seconds", "最近1小时115接口的平均响应时间", float64(stats[id].AvgResponseTime)/1000, label(id))
+	}
+	w.Describe("qms_115_api_throttled", "115账号当前是否处于限流状态", metrics.TypeGauge)
+	for _, id := range accountIds {
+		w.Gauge("qms_115_api_throttled", "115账号当前是否处于限流状态", metrics.Bool(throttles[id].IsThrottled), label(id))
+	}
+	w.Describe("qms_115_api_throttle_remaining_seconds", "115账号限流剩余的时间", metrics.TypeGauge)
+	for _, id := range accountIds {
+		w.Gauge("qms_115_api_throttle_remaining_seconds", "115账号限流剩余的时间", throttles[id].RemainingTime.Seconds(), label(id))
+	}
+}
+
+// 本地代理转发的流量和连接数
+func writeProxyMetrics(w *metrics.Writer) {
+	stats := getStreamProxy().Stats()
+	w.Counter("qms_proxy_bytes_total", "本地代理转发的字节总数", float64(stats.TotalBytes))
+	w.Counter("qms_proxy_requests_total", "本地代理处理的请求总数", float64(stats.TotalRequests))
+	w.Counter("qms_proxy_failures_total", "本地代理转发失败的次数", float64(stats.TotalFailures))
+	w.Counter("qms_proxy_rejected_total", "本地代理拒绝的请求数", float64(stats.TotalRejected))
+	w.Gauge("qms_proxy_active_streams", "本地代理正在转发的连接数", float64(stats.ActiveCount))
+	w.Gauge("qms_proxy_active_rate_bytes", "本地代理正在转发的连接速度之和，字节/秒", float64(stats.ActiveRate))
+}
+
+// Emby302请求缓存的命中情况
+func writeEmbyCacheMetrics(w *metrics.Writer) {
+	stats := cache.GetStats()
+	ratio := 0.0
+	if total := stats.Hits + stats.Misses; total > 0 {
+		ratio = float64(stats.Hits) / float64(total)
+	}
+	w.Counter("qms_emby_cache_hits_total", "Emby302请求缓存命中的次数", float64(stats.Hits))
+	w.Counter("qms_emby_cache_misses_total", "Emby302请求缓存未命中的次数", float64(stats.Misses))
+	w.Gauge("qms_emby_cache_hit_ratio", "Emby302请求缓存的命中率", ratio)
+	w.Gauge("qms_emby_cache_entries", "Emby302当前缓存的请求数", float64(stats.Entries))
+}
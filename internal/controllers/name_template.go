@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NameTemplatePreviewRequest struct {
+	Template          string           `json:"template"`             // 要预览的模板，旧格式的{title}模板会自动转换
+	MediaType         models.MediaType `json:"media_type"`           // 没有指定刮削文件时使用该类型的示例数据
+	ScrapeMediaFileId uint             `json:"scrape_media_file_id"` // 使用已识别的刮削文件预览，为0时使用示例数据
+}
+
+type NameTemplatePreviewResponse struct {
+	Template string                   `json:"template"` // 转换后的模板
+	Name     string                   `json:"name"`     // 生成的名称
+	Data     *models.NameTemplateData `json:"data"`     // 模板可以使用的字段和值
+}
+
+// PreviewNameTemplate 预览文件夹或者文件名称模板
+func PreviewNameTemplate(c *gin.Context) {
+	var req NameTemplatePreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数无效", Data: nil})
+		return
+	}
+	tpl := req.Template
+	if tpl == "" {
+		tpl = models.DefaultFolderNameTemplate
+	}
+	var data *models.NameTemplateData
+	if req.ScrapeMediaFileId > 0 {
+		mediaFile := models.GetScrapeMediaFileById(req.ScrapeMediaFileId)
+		if mediaFile == nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "刮削文件不存在", Data: nil})
+			return
+		}
+		data = mediaFile.NameTemplateData()
+	} else {
+		data = models.SampleNameTemplateData(req.MediaType)
+	}
+	name, err := models.RenderNameTemplate(tpl, data)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "模板错误: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[NameTemplatePreviewResponse]{Code: Success, Message: "success", Data: NameTemplatePreviewResponse{
+		Template: models.ConvertLegacyNameTemplate(tpl),
+		Name:     name,
+		Data:     data,
+	}})
+}
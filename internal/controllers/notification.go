@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/notification"
 	"Q115-STRM/internal/notificationmanager"
@@ -55,6 +56,7 @@ func GetNotificationChannels(c *gin.Context) {
 // @Param channel_name body string true "渠道名称"
 // @Param bot_token body string true "机器人Token"
 // @Param chat_id body string true "聊天ID"
+// @Param allowed_chat_ids body string false "额外允许管理任务的会话ID，逗号分隔"
 // @Success 200 {object} object
 // @Failure 200 {object} object
 // @Router /setting/notification/channels/telegram [post]
@@ -62,9 +64,10 @@ func GetNotificationChannels(c *gin.Context) {
 // @Security ApiKeyAuth
 func CreateTelegramChannel(c *gin.Context) {
 	type req struct {
-		ChannelName string `json:"channel_name" binding:"required"`
-		BotToken    string `json:"bot_token" binding:"required"`
-		ChatID      string `json:"chat_id" binding:"required"`
+		ChannelName    string `json:"channel_name" binding:"required"`
+		BotToken       string `json:"bot_token" binding:"required"`
+		ChatID         string `json:"chat_id" binding:"required"`
+		AllowedChatIDs string `json:"allowed_chat_ids"` // 除ChatID外允许管理任务的会话ID，逗号分隔
 	}
 
 	var r req
@@ -95,9 +98,10 @@ func CreateTelegramChannel(c *gin.Context) {
 
 	// 创建配置
 	config := models.TelegramChannelConfig{
-		ChannelID: channel.ID,
-		BotToken:  r.BotToken,
-		ChatID:    r.ChatID,
+		ChannelID:      channel.ID,
+		BotToken:       r.BotToken,
+		ChatID:         r.ChatID,
+		AllowedChatIDs: strings.Join(helpers.SplitTelegramChatIDs(r.AllowedChatIDs), ","),
 	}
 	if err := db.Db.Create(&config).Error; err != nil {
 		// 回滚
@@ -820,6 +824,7 @@ func UpdateCustomWebhookChannel(c *gin.Context) {
 // @Param bot_token body string false "机器人Token"
 // @Param chat_id body string false "聊天ID"
 // @Param description body string false "描述"
+// @Param allowed_chat_ids body string false "额外允许管理任务的会话ID，逗号分隔，传空字符串清空"
 // @Success 200 {object} object
 // @Failure 200 {object} object
 // @Router /setting/notification/channels/telegram [put]
@@ -832,6 +837,8 @@ func UpdateTelegramChannel(c *gin.Context) {
 		BotToken    string `json:"bot_token"`
 		ChatID      string `json:"chat_id"`
 		Description string `json:"description"`
+		// 除ChatID外允许管理任务的会话ID，逗号分隔，传空字符串清空
+		AllowedChatIDs *string `json:"allowed_chat_ids"`
 	}
 
 	var r req
@@ -874,6 +881,9 @@ func UpdateTelegramChannel(c *gin.Context) {
 	if r.ChatID != "" {
 		updates["chat_id"] = r.ChatID
 	}
+	if r.AllowedChatIDs != nil {
+		updates["allowed_chat_ids"] = strings.Join(helpers.SplitTelegramChatIDs(*r.AllowedChatIDs), ",")
+	}
 
 	// 更新配置
 	if len(updates) > 0 {
@@ -9,7 +9,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"net/url"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
@@ -190,7 +190,7 @@ func Get115FileUrl(c *gin.Context) {
 			if models.SettingsGlobal.LocalProxy == 1 {
 				// 跳转到本地代理
 				helpers.AppLogger.Infof("通过本地代理访问115下载链接，非302播放: %s", cachedUrl)
-				proxyUrl := fmt.Sprintf("/proxy-115?url=%s", url.QueryEscape(cachedUrl))
+				proxyUrl := makeProxyUrl(cachedUrl, models.SourceType115, req.PickCode, account.UserId)
 				c.Redirect(http.StatusFound, proxyUrl)
 			} else {
 				helpers.AppLogger.Infof("302重定向到115下载链接，非302播放: %s", cachedUrl)
@@ -283,7 +283,7 @@ func Get115UrlByPickCode(c *gin.Context) {
 			if models.SettingsGlobal.LocalProxy == 1 {
 				// 跳转到本地代理
 				helpers.AppLogger.Infof("通过本地代理访问115下载链接，emby端口播放: %s", cachedUrl)
-				proxyUrl := fmt.Sprintf("/proxy-115?url=%s", url.QueryEscape(cachedUrl))
+				proxyUrl := makeProxyUrl(cachedUrl, models.SourceType115, pickCode, account.UserId)
 				c.Redirect(http.StatusFound, proxyUrl)
 			} else {
 				helpers.AppLogger.Infof("302重定向到115下载链接，emby端口播放: %s", cachedUrl)
@@ -503,6 +503,7 @@ func ConfirmOAuthCode(c *gin.Context) {
 }
 
 // GetQueueStats 获取115 OpenAPI请求队列的统计数据
+// 每个账号有独立的请求队列，传入account_id时只返回该账号的统计，否则返回所有账号的汇总和每个账号的统计
 func GetQueueStats(c *gin.Context) {
 	// 获取查询参数，支持查询不同时间窗口的统计
 	timeWindowStr := c.DefaultQuery("time_window", "3600") // 默认3600秒（1小时）
@@ -511,20 +512,87 @@ func GetQueueStats(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "time_window参数无效", Data: nil})
 		return
 	}
+	accountId, err := strconv.ParseUint(c.DefaultQuery("account_id", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "account_id参数无效", Data: nil})
+		return
+	}
 
 	duration := time.Duration(timeWindow) * time.Second
 
-	// 获取全局队列执行器
-	executor := v115open.GetGlobalExecutor()
+	if accountId > 0 {
+		account, err := models.GetAccountById(uint(accountId))
+		if err != nil || account.SourceType != models.SourceType115 {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "115账号不存在", Data: nil})
+			return
+		}
+		// 账号还没有发起过请求时没有执行器，返回一个未启动的空执行器的统计，不为查询创建和启动执行器
+		executor, ok := v115open.LookupExecutor(account.ID)
+		if !ok {
+			config := v115open.GetExecutorConfig(account.ID)
+			executor = v115open.NewQueueExecutor(config.QPS, config.QPM, config.QPH)
+		}
+		responseData := queueStatsData(account.ID, executor, duration)
+		responseData["time_window_seconds"] = timeWindow
+		c.JSON(http.StatusOK, APIResponse[gin.H]{Code: Success, Message: "获取队列统计数据成功", Data: responseData})
+		return
+	}
+
+	// 汇总所有账号的统计
+	var totalRequests, qpsCount, qpmCount, qphCount, throttledCount, totalResponseTime, windowAccounts int64
+	isThrottled := false
+	accounts := make([]gin.H, 0)
+	executors := v115open.GetExecutors()
+	accountIds := make([]uint, 0, len(executors))
+	for id := range executors {
+		accountIds = append(accountIds, id)
+	}
+	slices.Sort(accountIds)
+	for _, id := range accountIds {
+		executor := executors[id]
+		stats := executor.GetStats(duration)
+		totalRequests += stats.TotalRequests
+		qpsCount += stats.QPSCount
+		qpmCount += stats.QPMCount
+		qphCount += stats.QPHCount
+		throttledCount += stats.ThrottledCount
+		if stats.AvgResponseTime > 0 {
+			totalResponseTime += stats.AvgResponseTime
+			windowAccounts++
+		}
+		if executor.GetThrottleStatus().IsThrottled {
+			isThrottled = true
+		}
+		accounts = append(accounts, queueStatsData(id, executor, duration))
+	}
+	var avgResponseTime int64
+	if windowAccounts > 0 {
+		avgResponseTime = totalResponseTime / windowAccounts
+	}
+	responseData := gin.H{
+		"total_requests":       totalRequests,
+		"qps_count":            qpsCount,
+		"qpm_count":            qpmCount,
+		"qph_count":            qphCount,
+		"throttled_count":      throttledCount,
+		"avg_response_time_ms": avgResponseTime,
+		"time_window_seconds":  timeWindow,
+		"is_throttled":         isThrottled,
+		"rate_limit":           v115open.GetDefaultExecutorConfig(),
+		"accounts":             accounts,
+	}
+
+	c.JSON(http.StatusOK, APIResponse[gin.H]{Code: Success, Message: "获取队列统计数据成功", Data: responseData})
+}
 
+// 单个账号请求队列的统计数据
+func queueStatsData(accountId uint, executor *v115open.QueueExecutor, duration time.Duration) gin.H {
 	// 获取统计数据
 	stats := executor.GetStats(duration)
-
 	// 获取限流状态
 	throttleStatus := executor.GetThrottleStatus()
-
-	// 构建响应数据
-	responseData := gin.H{
+	return gin.H{
+		"account_id":               accountId,
 		"total_requests":           stats.TotalRequests,
 		"qps_count":                stats.QPSCount,
 		"qpm_count":                stats.QPMCount,
@@ -534,21 +602,21 @@ func GetQueueStats(c *gin.Context) {
 		"last_throttle_time":       stats.LastThrottleTime,
 		"throttle_wait_time":       stats.ThrottledWaitTime.String(),
 		"throttle_recover_time":    stats.ThrottleRecoverTime,
-		"time_window_seconds":      timeWindow,
 		"is_throttled":             throttleStatus.IsThrottled,
 		"throttled_elapsed_time":   throttleStatus.ElapsedTime.String(),
 		"throttled_remaining_time": throttleStatus.RemainingTime.String(),
+		"rate_limit":               executor.GetRateLimitConfig(),
 	}
-
-	c.JSON(http.StatusOK, APIResponse[gin.H]{Code: Success, Message: "获取队列统计数据成功", Data: responseData})
 }
 
 // SetQueueRateLimit 设置115 OpenAPI请求队列的速率限制参数
+// 传入account_id时只设置该账号，否则设置所有没有单独配置的账号
 func SetQueueRateLimit(c *gin.Context) {
 	var req struct {
-		QPS int `json:"qps" binding:"required,min=1,max=1000"`
-		QPM int `json:"qpm" binding:"required,min=1,max=100000"`
-		QPH int `json:"qph" binding:"required,min=1,max=1000000"`
+		AccountId uint `json:"account_id"`
+		QPS       int  `json:"qps" binding:"required,min=1,max=1000"`
+		QPM       int  `json:"qpm" binding:"required,min=1,max=100000"`
+		QPH       int  `json:"qph" binding:"required,min=1,max=1000000"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -556,15 +624,27 @@ func SetQueueRateLimit(c *gin.Context) {
 		return
 	}
 
-	// 设置全局执行器的速率限制配置
-	v115open.SetGlobalExecutorConfig(req.QPS, req.QPM, req.QPH)
-
-	helpers.AppLogger.Infof("115 OpenAPI队列速率限制已更新: QPS=%d, QPM=%d, QPH=%d", req.QPS, req.QPM, req.QPH)
+	if req.AccountId > 0 {
+		account, err := models.GetAccountById(req.AccountId)
+		if err != nil || account.SourceType != models.SourceType115 {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "115账号不存在", Data: nil})
+			return
+		}
+		if err := account.UpdateQueueRateLimit(req.QPS, req.QPM, req.QPH); err != nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "保存速率限制失败: " + err.Error(), Data: nil})
+			return
+		}
+		helpers.AppLogger.Infof("115账号 %d 的OpenAPI队列速率限制已更新: QPS=%d, QPM=%d, QPH=%d", req.AccountId, req.QPS, req.QPM, req.QPH)
+	} else {
+		v115open.SetDefaultExecutorConfig(req.QPS, req.QPM, req.QPH)
+		helpers.AppLogger.Infof("115 OpenAPI队列默认速率限制已更新: QPS=%d, QPM=%d, QPH=%d", req.QPS, req.QPM, req.QPH)
+	}
 
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "速率限制配置成功", Data: gin.H{
-		"qps": req.QPS,
-		"qpm": req.QPM,
-		"qph": req.QPH,
+		"account_id": req.AccountId,
+		"qps":        req.QPS,
+		"qpm":        req.QPM,
+		"qph":        req.QPH,
 	}})
 }
 
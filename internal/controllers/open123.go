@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Open123StatusResp 123云盘状态响应
+type Open123StatusResp struct {
+	UserId      int64  `json:"user_id"`
+	Username    string `json:"username"`
+	UsedSpace   int64  `json:"used_space"`
+	TotalSpace  int64  `json:"total_space"`
+	MemberLevel string `json:"member_level"`
+	ExpiredAt   int64  `json:"expired_at"`
+}
+
+// Create123Account 创建或更新123云盘账号
+// @Summary 创建/更新123云盘账号
+// @Description 使用123云盘开放平台的clientID和clientSecret创建账号，保存前会校验凭据是否有效
+// @Tags 123云盘
+// @Accept json
+// @Produce json
+// @Param id query integer false "账号ID（指定则为更新操作）"
+// @Param name query string false "账号备注，为空则使用123云盘昵称"
+// @Param client_id query string true "123云盘开放平台clientID"
+// @Param client_secret query string true "123云盘开放平台clientSecret"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /account/123 [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func Create123Account(c *gin.Context) {
+	type create123AccountReq struct {
+		Id           uint   `json:"id" form:"id"`
+		Name         string `json:"name" form:"name"`
+		ClientId     string `json:"client_id" form:"client_id"`
+		ClientSecret string `json:"client_secret" form:"client_secret"`
+	}
+	req := &create123AccountReq{}
+	if err := c.ShouldBind(req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	if req.ClientId == "" || req.ClientSecret == "" {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "clientID和clientSecret不能为空", Data: nil})
+		return
+	}
+	if req.Id != 0 {
+		account, err := models.GetAccountById(req.Id)
+		if err != nil || account.SourceType != models.SourceType123 {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "123云盘账号不存在", Data: nil})
+			return
+		}
+		if err := account.UpdateOpen123(req.ClientId, req.ClientSecret); err != nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("更新123云盘账号失败: %s", err.Error()), Data: nil})
+			return
+		}
+		c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "更新123云盘账号成功", Data: nil})
+		return
+	}
+	account, err := models.CreateOpen123Account(req.Name, req.ClientId, req.ClientSecret)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("创建123云盘账号失败: %s", err.Error()), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[models.Account]{Code: Success, Message: "创建123云盘账号成功", Data: *account})
+}
+
+// Get123Status 查询123云盘账号状态
+// @Summary 查询123云盘账号状态
+// @Description 获取指定123云盘账号的用户信息及存储空间
+// @Tags 123云盘
+// @Accept json
+// @Produce json
+// @Param account_id query integer true "账号ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /123/status [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func Get123Status(c *gin.Context) {
+	type statusReq struct {
+		AccountId uint `json:"account_id" form:"account_id"`
+	}
+	var req statusReq
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "参数错误", Data: nil})
+		return
+	}
+	account, err := models.GetAccountById(req.AccountId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "账号ID不存在", Data: nil})
+		return
+	}
+	client := account.GetOpen123Client()
+	userInfo, err := client.GetUserInfo(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取123云盘用户信息失败: " + err.Error(), Data: nil})
+		return
+	}
+	memberLevel := "非会员"
+	if userInfo.Vip {
+		memberLevel = "VIP"
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "成功", Data: Open123StatusResp{
+		UserId:      userInfo.UID,
+		Username:    userInfo.Nickname,
+		MemberLevel: memberLevel,
+		UsedSpace:   userInfo.SpaceUsed,
+		TotalSpace:  userInfo.SpacePermanent + userInfo.SpaceTemp,
+		ExpiredAt:   client.GetExpiredAt().Unix(),
+	}})
+}
+
+// 通过123云盘文件的fileId（参数名叫pickcode，跟115保持一致）获取下载链接
+func Get123UrlByPickCode(c *gin.Context) {
+	type fileIdReq struct {
+		UserId   string `json:"userid" form:"userid"`
+		PickCode string `json:"pickcode" form:"pickcode"`
+		Force    int    `json:"force" form:"force"`
+	}
+	var req fileIdReq
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "参数错误", Data: nil})
+		return
+	}
+	pickCode := req.PickCode
+	userId := req.UserId
+	fileId, err := strconv.ParseInt(pickCode, 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "文件PickCode格式错误", Data: nil})
+		return
+	}
+	var account *models.Account
+	if userId == "" {
+		// 查询SyncFile
+		syncFile := models.GetFileByPickCode(pickCode)
+		if syncFile == nil {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "文件PickCode不存在", Data: nil})
+			return
+		}
+		account, err = models.GetAccountById(syncFile.AccountId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "账号ID不存在", Data: nil})
+			return
+		}
+	} else {
+		// 通过userId查询账号
+		account, err = models.GetAccountByUserId(userId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "用户ID不存在", Data: nil})
+			return
+		}
+	}
+	client := account.GetOpen123Client()
+	cacheKey := fmt.Sprintf("123url:%s", pickCode)
+	if !keyLock.LockWithTimeout(cacheKey, 10*time.Second) {
+		helpers.AppLogger.Warnf("等待获取123云盘下载链接超时: %s", pickCode)
+		c.JSON(http.StatusServiceUnavailable, APIResponse[any]{Code: BadRequest, Message: "获取下载链接超时，请稍后重试", Data: nil})
+		return
+	}
+	defer keyLock.Unlock(cacheKey)
+	cachedUrl := ""
+	if req.Force == 0 {
+		cachedUrl = string(db.Cache.Get(cacheKey))
+	}
+	if cachedUrl == "" {
+		cachedUrl, err = client.GetDirectLink(context.Background(), fileId)
+		if err != nil || cachedUrl == "" {
+			helpers.AppLogger.Errorf("获取123云盘下载链接失败: %s %v", pickCode, err)
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取123云盘下载链接失败", Data: nil})
+			return
+		}
+		helpers.AppLogger.Infof("从接口中查询到123云盘下载链接: %s => %s", pickCode, cachedUrl)
+		// 下载链接有效期较短，缓存30分钟
+		db.Cache.Set(cacheKey, []byte(cachedUrl), 1800)
+	} else {
+		helpers.AppLogger.Infof("从缓存中查询到123云盘下载链接: %s => %s", pickCode, cachedUrl)
+	}
+	// 检查是否开启了本地播放代理，如果开启则跳转到代理链接
+	// 本地代理只能刷新115和百度网盘的链接，所以不带pickcode和userid
+	if models.SettingsGlobal.LocalProxy == 1 {
+		helpers.AppLogger.Infof("通过本地代理访问123云盘下载链接播放: %s", url.QueryEscape(cachedUrl))
+		c.Redirect(http.StatusFound, makeProxyUrl(cachedUrl, models.SourceType123, "", ""))
+		return
+	}
+	helpers.AppLogger.Infof("302重定向到123云盘下载链接播放: %s", url.QueryEscape(cachedUrl))
+	c.Redirect(http.StatusFound, cachedUrl)
+}
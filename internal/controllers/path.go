@@ -10,6 +10,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -59,10 +60,16 @@ func GetPathList(c *gin.Context) {
 		pathes, err = GetLocalPath(req.ParentId)
 	case models.SourceTypeOpenList:
 		pathes, err = GetOpenListPath(req.ParentId, req.AccountId)
+	case models.SourceTypeWebDav:
+		pathes, err = GetWebDavPath(req.ParentId, req.AccountId)
+	case models.SourceTypeS3:
+		pathes, err = GetS3Path(req.ParentId, req.AccountId)
 	case models.SourceType115:
 		pathes, err = Get115PathList(req.ParentId, req.AccountId)
 	case models.SourceTypeBaiduPan:
 		pathes, err = GetBaiduPanPathList(req.ParentId, req.AccountId)
+	case models.SourceType123:
+		pathes, err = Get123PathList(req.ParentId, req.ParentPath, req.AccountId)
 	default:
 		// 报错
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "未知的同步源类型", Data: nil})
@@ -193,6 +200,57 @@ func GetOpenListPath(parentPath string, accountId uint) ([]DirResp, error) {
 	return folders, nil
 }
 
+// 获取WebDAV目录列表，目录ID就是完整路径
+func GetWebDavPath(parentPath string, accountId uint) ([]DirResp, error) {
+	account, err := models.GetAccountById(accountId)
+	if err != nil {
+		return nil, err
+	}
+	helpers.AppLogger.Infof("开始获取WebDAV目录列表, 父目录路径: %s", parentPath)
+	client := account.GetWebDavClient()
+	list, err := client.List(context.Background(), parentPath)
+	if err != nil {
+		return nil, err
+	}
+	// 只返回文件夹列表
+	folders := make([]DirResp, 0)
+	for _, item := range list {
+		if item.IsDir {
+			folders = append(folders, DirResp{
+				Id:   item.Path,
+				Name: item.Name,
+				Path: item.Path,
+			})
+		}
+	}
+	return folders, nil
+}
+
+// 获取S3目录（公共前缀）列表，目录ID就是以/开头的路径
+func GetS3Path(parentPath string, accountId uint) ([]DirResp, error) {
+	account, err := models.GetAccountById(accountId)
+	if err != nil {
+		return nil, err
+	}
+	helpers.AppLogger.Infof("开始获取S3目录列表, 父目录路径: %s", parentPath)
+	client := account.GetS3Client()
+	list, err := client.List(context.Background(), parentPath)
+	if err != nil {
+		return nil, err
+	}
+	folders := make([]DirResp, 0)
+	for _, item := range list {
+		if item.IsDir {
+			folders = append(folders, DirResp{
+				Id:   item.Path,
+				Name: item.Name,
+				Path: item.Path,
+			})
+		}
+	}
+	return folders, nil
+}
+
 func Get115PathList(parentId string, accountId uint) ([]DirResp, error) {
 	// 获取115目录列表
 	account, err := models.GetAccountById(accountId)
@@ -255,6 +313,40 @@ func GetBaiduPanPathList(parentId string, accountId uint) ([]DirResp, error) {
 	return items, nil
 }
 
+// 123云盘的目录ID是数字，根目录为0，接口不返回完整路径，需要前端传入父目录路径
+func Get123PathList(parentId, parentPath string, accountId uint) ([]DirResp, error) {
+	account, err := models.GetAccountById(accountId)
+	if err != nil {
+		return nil, err
+	}
+	if parentId == "" {
+		parentId = "0"
+		parentPath = ""
+	}
+	parentFileId, err := strconv.ParseInt(parentId, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("父目录ID格式错误: %s", parentId)
+	}
+	client := account.GetOpen123Client()
+	files, err := client.ListAllFiles(context.Background(), parentFileId)
+	if err != nil {
+		helpers.AppLogger.Warnf("获取123云盘目录列表失败: 父目录：%s, 错误:%v", parentId, err)
+		return nil, err
+	}
+	folders := make([]DirResp, 0)
+	for _, item := range files {
+		if !item.IsDir() {
+			continue
+		}
+		folders = append(folders, DirResp{
+			Id:   fmt.Sprintf("%d", item.FileID),
+			Name: item.FileName,
+			Path: filepath.ToSlash(filepath.Join(parentPath, item.FileName)),
+		})
+	}
+	return folders, nil
+}
+
 type FileItem struct {
 	Id          string `json:"id"`
 	IsDirectory bool   `json:"is_directory"`
@@ -291,10 +383,16 @@ func GetNetFileList(c *gin.Context) {
 	switch account.SourceType {
 	case models.SourceTypeOpenList:
 		list, err = getOpenlistDirs(req.ParentId, account, req.Page, req.PageSize)
+	case models.SourceTypeWebDav:
+		list, err = getWebDavDirs(req.ParentId, account, req.Page, req.PageSize)
+	case models.SourceTypeS3:
+		list, err = getS3Dirs(req.ParentId, account, req.Page, req.PageSize)
 	case models.SourceType115:
 		list, err = get115Dirs(req.ParentId, account, req.Page, req.PageSize)
 	case models.SourceTypeBaiduPan:
 		list, err = getBaiduPanDirs(req.ParentId, account, req.Page, req.PageSize)
+	case models.SourceType123:
+		list, err = get123Dirs(req.ParentId, account, req.Page, req.PageSize)
 	default:
 		// 报错
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "未知的网盘类型", Data: nil})
@@ -338,6 +436,66 @@ func getOpenlistDirs(parentPath string, account *models.Account, page, pageSize
 	return items, nil
 }
 
+// WebDAV一次返回整个目录，在这里做分页
+func getWebDavDirs(parentPath string, account *models.Account, page, pageSize int) ([]*FileItem, error) {
+	helpers.AppLogger.Infof("开始获取WebDAV目录列表, 父目录路径: %s", parentPath)
+	client := account.GetWebDavClient()
+	list, err := client.List(context.Background(), parentPath)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*FileItem, 0)
+	start := (page - 1) * pageSize
+	if start >= len(list) {
+		return items, nil
+	}
+	end := min(start+pageSize, len(list))
+	for _, item := range list[start:end] {
+		var mtime int64
+		if !item.ModTime.IsZero() {
+			mtime = item.ModTime.Unix()
+		}
+		items = append(items, &FileItem{
+			Id:          item.Path,
+			IsDirectory: item.IsDir,
+			Name:        item.Name,
+			Size:        item.Size,
+			ModifiedAt:  mtime,
+		})
+	}
+	return items, nil
+}
+
+// S3客户端一次取完整个目录，在这里做分页
+func getS3Dirs(parentPath string, account *models.Account, page, pageSize int) ([]*FileItem, error) {
+	helpers.AppLogger.Infof("开始获取S3目录列表, 父目录路径: %s", parentPath)
+	client := account.GetS3Client()
+	list, err := client.List(context.Background(), parentPath)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]*FileItem, 0)
+	start := (page - 1) * pageSize
+	if start >= len(list) {
+		return items, nil
+	}
+	end := min(start+pageSize, len(list))
+	for _, item := range list[start:end] {
+		var mtime int64
+		if !item.LastModified.IsZero() {
+			mtime = item.LastModified.Unix()
+		}
+		items = append(items, &FileItem{
+			Id:          item.Path,
+			IsDirectory: item.IsDir,
+			Name:        item.Name,
+			Size:        item.Size,
+			ModifiedAt:  mtime,
+		})
+	}
+	return items, nil
+}
+
 func get115Dirs(parentId string, account *models.Account, page, pageSize int) ([]*FileItem, error) {
 	client := account.Get115Client()
 	ctx := context.Background()
@@ -388,6 +546,39 @@ func getBaiduPanDirs(parentId string, account *models.Account, page, pageSize in
 	return items, nil
 }
 
+// 123云盘按lastFileId翻页，这里取全部后再按page切分
+func get123Dirs(parentId string, account *models.Account, page, pageSize int) ([]*FileItem, error) {
+	if parentId == "" {
+		parentId = "0"
+	}
+	parentFileId, err := strconv.ParseInt(parentId, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("父目录ID格式错误: %s", parentId)
+	}
+	client := account.GetOpen123Client()
+	files, err := client.ListAllFiles(context.Background(), parentFileId)
+	if err != nil {
+		helpers.AppLogger.Warnf("获取123云盘目录列表失败: 父目录：%s, 错误:%v", parentId, err)
+		return nil, err
+	}
+	items := make([]*FileItem, 0)
+	start := (page - 1) * pageSize
+	if start >= len(files) {
+		return items, nil
+	}
+	end := min(start+pageSize, len(files))
+	for _, item := range files[start:end] {
+		items = append(items, &FileItem{
+			Id:          fmt.Sprintf("%d", item.FileID),
+			IsDirectory: item.IsDir(),
+			Name:        item.FileName,
+			Size:        item.Size,
+			ModifiedAt:  item.GetMtime(),
+		})
+	}
+	return items, nil
+}
+
 // 创建文件夹
 func CreateDir(c *gin.Context) {
 	type createDirReq struct {
@@ -423,10 +614,16 @@ func CreateDir(c *gin.Context) {
 		pathId, err = makeLocalPath(req.ParentId, req.Name)
 	case models.SourceTypeOpenList:
 		pathId, err = makeOpenListPath(req.ParentId, req.Name, req.AccountId)
+	case models.SourceTypeWebDav:
+		pathId, err = makeWebDavPath(req.ParentId, req.Name, req.AccountId)
+	case models.SourceTypeS3:
+		pathId, err = makeS3Path(req.ParentId, req.Name, req.AccountId)
 	case models.SourceType115:
 		pathId, err = make115PathList(req.ParentId, req.ParentPath, req.Name, req.AccountId)
 	case models.SourceTypeBaiduPan:
 		pathId, err = makeBaiduPanPathList(req.ParentId, req.Name, req.AccountId)
+	case models.SourceType123:
+		pathId, err = make123Path(req.ParentId, req.Name, req.AccountId)
 	default:
 		// 报错
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "未知的同步源类型", Data: nil})
@@ -479,6 +676,37 @@ func makeOpenListPath(parentId string, folderName string, accountId uint) (strin
 	return newDir, nil
 }
 
+func makeWebDavPath(parentId string, folderName string, accountId uint) (string, error) {
+	account, err := models.GetAccountById(accountId)
+	if err != nil {
+		return "", fmt.Errorf("获取账号失败: %v", err)
+	}
+	client := account.GetWebDavClient()
+	// 检查父目录是否存在
+	if _, err = client.Stat(context.Background(), parentId); err != nil {
+		return "", fmt.Errorf("获取WebDAV目录详情失败，目录可能不存在: %v", err)
+	}
+	newDir := filepath.ToSlash(filepath.Join(parentId, folderName))
+	if err = client.Mkdir(context.Background(), newDir); err != nil {
+		return "", fmt.Errorf("创建WebDAV目录失败: %s, 错误: %v", newDir, err)
+	}
+	return newDir, nil
+}
+
+func makeS3Path(parentId string, folderName string, accountId uint) (string, error) {
+	account, err := models.GetAccountById(accountId)
+	if err != nil {
+		return "", fmt.Errorf("获取账号失败: %v", err)
+	}
+	client := account.GetS3Client()
+	newDir := filepath.ToSlash(filepath.Join(parentId, folderName))
+	// S3没有目录，写入占位对象
+	if err = client.Mkdir(context.Background(), newDir); err != nil {
+		return "", fmt.Errorf("创建S3目录失败: %s, 错误: %v", newDir, err)
+	}
+	return newDir, nil
+}
+
 // 创建115目录
 func make115PathList(parentId, parentPath, folderName string, accountId uint) (string, error) {
 	// 检查父目录是否存在
@@ -537,3 +765,21 @@ func UpdateFNPath(c *gin.Context) {
 	helpers.AppLogger.Infof("更新飞牛有权限的目录为: %s", req.Path)
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "更新目录成功", Data: nil})
 }
+
+// 创建123云盘目录
+func make123Path(parentId string, folderName string, accountId uint) (string, error) {
+	account, err := models.GetAccountById(accountId)
+	if err != nil {
+		return "", fmt.Errorf("获取账号失败: %v", err)
+	}
+	parentFileId, err := strconv.ParseInt(parentId, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("父目录ID格式错误: %s", parentId)
+	}
+	client := account.GetOpen123Client()
+	folder, err := client.CreateFolder(context.Background(), folderName, parentFileId)
+	if err != nil {
+		return "", fmt.Errorf("创建123云盘目录失败: %s, 错误: %v", folderName, err)
+	}
+	return fmt.Sprintf("%d", folder.DirID), nil
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/streamproxy"
+	"Q115-STRM/internal/v115open"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var streamProxy *streamproxy.Proxy
+var streamProxyOnce sync.Once
+
+// 本地代理实例，第一次使用时按设置创建
+func getStreamProxy() *streamproxy.Proxy {
+	streamProxyOnce.Do(func() {
+		streamProxy = streamproxy.New(streamProxyConfig())
+	})
+	return streamProxy
+}
+
+// ReloadStreamProxy 设置或者S3账号变化后重新加载本地代理配置
+func ReloadStreamProxy() {
+	getStreamProxy().SetConfig(streamProxyConfig())
+}
+
+// 白名单包含内置网盘CDN域名、设置中的额外域名和S3账号的服务地址
+func streamProxyConfig() streamproxy.Config {
+	hosts := append([]string{}, streamproxy.DefaultAllowHosts...)
+	hosts = append(hosts, splitProxyHosts(models.SettingsGlobal.ProxyAllowHosts)...)
+	if accounts, err := models.GetAccountBySourceType(models.SourceTypeS3); err == nil {
+		for _, account := range accounts {
+			if u, err := url.Parse(account.BaseUrl); err == nil && u.Hostname() != "" {
+				hosts = append(hosts, u.Hostname())
+			}
+		}
+	}
+	return streamproxy.Config{
+		AllowHosts:  hosts,
+		GlobalLimit: models.SettingsGlobal.ProxyGlobalLimit * 1024,
+		ClientLimit: models.SettingsGlobal.ProxyClientLimit * 1024,
+	}
+}
+
+func splitProxyHosts(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r' || r == ' '
+	})
+}
+
+// Proxy115 本地播放代理，反代网盘CDN下载链接
+// 带pickcode和userid时，链接过期或者播放中途断开会自动刷新115/百度网盘的下载链接并从断开的位置继续
+func Proxy115(c *gin.Context) {
+	target := c.Query("url")
+	baidupan := c.Query("baidupan")
+	pickCode := c.Query("pickcode")
+	userId := c.Query("userid")
+	if target == "" {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "缺少url参数", Data: nil})
+		return
+	}
+	helpers.AppLogger.Infof("反代网盘下载链接: %s", target)
+	req := &streamproxy.Request{
+		Target:    target,
+		UserAgent: v115open.DEFAULTUA,
+		Client:    c.ClientIP(),
+		PickCode:  pickCode,
+	}
+	if baidupan != "" {
+		req.UserAgent = "pan.baidu.com"
+		req.Source = string(models.SourceTypeBaiduPan)
+	} else if pickCode != "" {
+		req.Source = string(models.SourceType115)
+	}
+	if pickCode != "" && userId != "" {
+		req.Refresh = proxyRefreshFunc(req.Source, pickCode, userId, c.Request.UserAgent())
+	}
+	err := getStreamProxy().Serve(c.Writer, c.Request, req)
+	if err == nil || c.Writer.Written() {
+		if err != nil && c.Request.Context().Err() == nil {
+			helpers.AppLogger.Warnf("反代网盘下载链接中断: %s %v", pickCode, err)
+		}
+		return
+	}
+	if errors.Is(err, streamproxy.ErrHostNotAllowed) {
+		helpers.AppLogger.Warnf("拒绝反代不在白名单中的地址: %s", target)
+		c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "只允许反代网盘CDN链接", Data: nil})
+		return
+	}
+	c.JSON(http.StatusBadGateway, APIResponse[any]{Code: BadRequest, Message: "反代请求失败: " + err.Error(), Data: nil})
+}
+
+// 重新获取下载链接并更新缓存，缓存键和跳转接口保持一致
+func proxyRefreshFunc(source, pickCode, userId, ua string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		account, err := models.GetAccountByUserId(userId)
+		if err != nil {
+			return "", err
+		}
+		switch source {
+		case string(models.SourceType115):
+			downloadUrl := account.Get115Client().GetDownloadUrl(ctx, pickCode, v115open.DEFAULTUA, true)
+			if downloadUrl == "" {
+				return "", fmt.Errorf("获取115下载链接失败")
+			}
+			// 本地代理统一使用默认UA获取链接
+			db.Cache.Set(fmt.Sprintf("115url:%s, ua=%s", pickCode, v115open.DEFAULTUA), []byte(downloadUrl), 7200)
+			helpers.AppLogger.Infof("本地代理刷新115下载链接: %s", pickCode)
+			return downloadUrl, nil
+		case string(models.SourceTypeBaiduPan):
+			fsDetail, err := account.GetBaiDuPanClient().GetFileDetail(ctx, pickCode, 1)
+			if err != nil {
+				return "", err
+			}
+			downloadUrl := fmt.Sprintf("%s&access_token=%s", fsDetail.Dlink, account.Token)
+			db.Cache.Set(fmt.Sprintf("baidupanurl:%s, ua=%s", pickCode, ua), []byte(downloadUrl), 28800)
+			helpers.AppLogger.Infof("本地代理刷新百度网盘下载链接: %s", pickCode)
+			return downloadUrl, nil
+		}
+		return "", fmt.Errorf("不支持刷新的来源: %s", source)
+	}
+}
+
+// 跳转到本地代理的地址，带上pickcode和userid用于刷新下载链接
+func makeProxyUrl(cachedUrl string, source models.SourceType, pickCode, userId string) string {
+	params := url.Values{}
+	if source == models.SourceTypeBaiduPan {
+		params.Set("baidupan", "1")
+	}
+	params.Set("url", cachedUrl)
+	if pickCode != "" && userId != "" {
+		params.Set("pickcode", pickCode)
+		params.Set("userid", userId)
+	}
+	return "/proxy-115?" + params.Encode()
+}
+
+// GetProxyStats 获取本地代理的实时统计
+// @Summary 本地代理统计
+// @Description 获取本地播放代理正在进行的连接、速度、刷新链接和续传次数等统计
+// @Tags 系统设置
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /proxy/stats [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetProxyStats(c *gin.Context) {
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取本地代理统计成功", Data: getStreamProxy().Stats()})
+}
+
+// GetProxyConfig 获取本地代理设置
+// @Summary 获取本地代理设置
+// @Description 获取本地播放代理的域名白名单和带宽限制
+// @Tags 系统设置
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/proxy [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetProxyConfig(c *gin.Context) {
+	models.LoadSettings() // 确保设置已加载
+	data := make(map[string]any)
+	data["proxy_allow_hosts"] = models.SettingsGlobal.ProxyAllowHosts
+	data["proxy_global_limit"] = models.SettingsGlobal.ProxyGlobalLimit
+	data["proxy_client_limit"] = models.SettingsGlobal.ProxyClientLimit
+	data["default_allow_hosts"] = streamproxy.DefaultAllowHosts
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取本地代理设置成功", Data: data})
+}
+
+// UpdateProxyConfig 更新本地代理设置
+// @Summary 更新本地代理设置
+// @Description 更新本地播放代理额外允许的域名和带宽限制，立即生效
+// @Tags 系统设置
+// @Accept json
+// @Produce json
+// @Param proxy_allow_hosts body string false "额外允许反代的域名，逗号或换行分隔"
+// @Param proxy_global_limit body integer false "总带宽限制，单位KB/s，0不限制"
+// @Param proxy_client_limit body integer false "单个客户端带宽限制，单位KB/s，0不限制"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/proxy [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateProxyConfig(c *gin.Context) {
+	type updateProxyRequest struct {
+		ProxyAllowHosts  string `form:"proxy_allow_hosts" json:"proxy_allow_hosts"`
+		ProxyGlobalLimit int64  `form:"proxy_global_limit" json:"proxy_global_limit"`
+		ProxyClientLimit int64  `form:"proxy_client_limit" json:"proxy_client_limit"`
+	}
+	var req updateProxyRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	if req.ProxyGlobalLimit < 0 || req.ProxyClientLimit < 0 {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "带宽限制不能小于0", Data: nil})
+		return
+	}
+	allowHosts := strings.Join(splitProxyHosts(req.ProxyAllowHosts), ",")
+	if !models.SettingsGlobal.UpdateProxy(allowHosts, req.ProxyGlobalLimit, req.ProxyClientLimit) {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "更新本地代理设置失败", Data: nil})
+		return
+	}
+	ReloadStreamProxy()
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "更新本地代理设置成功", Data: nil})
+}
@@ -0,0 +1,97 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"crypto/hmac"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 预签名地址的有效期，缓存时间要比有效期短
+const s3PresignExpires = time.Hour
+
+// 通过S3对象路径（参数名叫pickcode，跟115保持一致）跳转到预签名下载地址
+// sign用来防止篡改路径给bucket中的其他对象生成预签名地址
+func GetS3UrlByPickCode(c *gin.Context) {
+	type fileIdReq struct {
+		UserId   string `json:"userid" form:"userid"`
+		PickCode string `json:"pickcode" form:"pickcode"`
+		Sign     string `json:"sign" form:"sign"`
+		Force    int    `json:"force" form:"force"`
+	}
+	var req fileIdReq
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "参数错误", Data: nil})
+		return
+	}
+	pickCode := req.PickCode
+	if pickCode == "" {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "文件PickCode不能为空", Data: nil})
+		return
+	}
+	var account *models.Account
+	var err error
+	if req.UserId == "" {
+		// 查询SyncFile
+		syncFile := models.GetFileByPickCode(pickCode)
+		if syncFile == nil {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "文件PickCode不存在", Data: nil})
+			return
+		}
+		account, err = models.GetAccountById(syncFile.AccountId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "账号ID不存在", Data: nil})
+			return
+		}
+	} else {
+		// 通过userId查询账号
+		account, err = models.GetAccountByUserId(req.UserId)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "用户ID不存在", Data: nil})
+			return
+		}
+	}
+	if account.SourceType != models.SourceTypeS3 {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "该账号不是S3账号", Data: nil})
+		return
+	}
+	if req.Sign == "" || !hmac.Equal([]byte(account.SignS3Path(pickCode)), []byte(req.Sign)) {
+		helpers.AppLogger.Warnf("S3播放链接签名错误: %s", pickCode)
+		c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "签名错误", Data: nil})
+		return
+	}
+	client := account.GetS3Client()
+	cacheKey := fmt.Sprintf("s3url:%d:%s", account.ID, pickCode)
+	if !keyLock.LockWithTimeout(cacheKey, 10*time.Second) {
+		helpers.AppLogger.Warnf("等待生成S3预签名下载链接超时: %s", pickCode)
+		c.JSON(http.StatusServiceUnavailable, APIResponse[any]{Code: BadRequest, Message: "获取下载链接超时，请稍后重试", Data: nil})
+		return
+	}
+	defer keyLock.Unlock(cacheKey)
+	cachedUrl := ""
+	if req.Force == 0 {
+		cachedUrl = string(db.Cache.Get(cacheKey))
+	}
+	if cachedUrl == "" {
+		cachedUrl = client.PresignGetUrl(pickCode, s3PresignExpires)
+		helpers.AppLogger.Infof("生成S3预签名下载链接: %s", pickCode)
+		// 预签名地址有效期1小时，缓存50分钟
+		db.Cache.Set(cacheKey, []byte(cachedUrl), 3000)
+	} else {
+		helpers.AppLogger.Infof("从缓存中查询到S3预签名下载链接: %s", pickCode)
+	}
+	// 检查是否开启了本地播放代理，如果开启则跳转到代理链接
+	// 预签名地址不能通过pickcode刷新，所以不带pickcode和userid
+	if models.SettingsGlobal.LocalProxy == 1 {
+		helpers.AppLogger.Infof("通过本地代理访问S3下载链接播放: %s", pickCode)
+		c.Redirect(http.StatusFound, makeProxyUrl(cachedUrl, models.SourceTypeS3, "", ""))
+		return
+	}
+	helpers.AppLogger.Infof("302重定向到S3下载链接播放: %s", pickCode)
+	c.Redirect(http.StatusFound, cachedUrl)
+}
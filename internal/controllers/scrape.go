@@ -3,6 +3,7 @@ package controllers
 import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/subtitle"
 	"Q115-STRM/internal/synccron"
 	"encoding/json"
 	"io"
@@ -39,6 +40,20 @@ type TmdbSettings struct {
 	TmdbEnableProxy   bool   `json:"tmdb_enable_proxy" form:"tmdb_enable_proxy"`
 }
 
+type MetadataProviderSettings struct {
+	TvdbApiKey   string `json:"tvdb_api_key" form:"tvdb_api_key"`
+	TvdbPin      string `json:"tvdb_pin" form:"tvdb_pin"`
+	BangumiToken string `json:"bangumi_token" form:"bangumi_token"`
+}
+
+type SubtitleSettings struct {
+	SubtitleProvider      string `json:"subtitle_provider" form:"subtitle_provider"`
+	SubtitleLanguages     string `json:"subtitle_languages" form:"subtitle_languages"`
+	OpenSubtitlesApiKey   string `json:"opensubtitles_api_key" form:"opensubtitles_api_key"`
+	OpenSubtitlesUsername string `json:"opensubtitles_username" form:"opensubtitles_username"`
+	OpenSubtitlesPassword string `json:"opensubtitles_password" form:"opensubtitles_password"`
+}
+
 type AiSettings struct {
 	EnableAi    models.AiAction `json:"enable_ai" form:"enable_ai"`
 	AiApiKey    string          `json:"ai_api_key" form:"ai_api_key"`
@@ -46,6 +61,15 @@ type AiSettings struct {
 	AiModelName string          `json:"ai_model_name" form:"ai_model_name"`
 	AiPrompt    string          `json:"ai_prompt" form:"ai_prompt"`
 	AiTimeout   int             `json:"ai_timeout" form:"ai_timeout"`
+	// 每百万token的单价，用来估算费用
+	AiInputPrice  float64 `json:"ai_input_price" form:"ai_input_price"`
+	AiOutputPrice float64 `json:"ai_output_price" form:"ai_output_price"`
+	// 用量统计，仅查询时返回
+	AiRequests         int64   `json:"ai_requests"`
+	AiPromptTokens     int64   `json:"ai_prompt_tokens"`
+	AiCompletionTokens int64   `json:"ai_completion_tokens"`
+	AiCost             float64 `json:"ai_cost"`
+	AiUsageResetAt     int64   `json:"ai_usage_reset_at"`
 }
 
 type MovieCategoryReq struct {
@@ -151,6 +175,180 @@ func TestTmdbSettings(c *gin.Context) {
 	c.JSON(http.StatusOK, APIResponse[bool]{Code: Success, Message: "", Data: testResult})
 }
 
+// GetMetadataProviderSettings 获取元数据提供者设置
+// @Summary 获取元数据提供者设置
+// @Description 获取TVDB和Bangumi的配置以及可用的元数据提供者列表
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/metadata-providers [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetMetadataProviderSettings(c *gin.Context) {
+	settings := MetadataProviderSettings{
+		TvdbApiKey:   models.GlobalScrapeSettings.TvdbApiKey,
+		TvdbPin:      models.GlobalScrapeSettings.TvdbPin,
+		BangumiToken: models.GlobalScrapeSettings.BangumiToken,
+	}
+	c.JSON(http.StatusOK, APIResponse[map[string]any]{Code: Success, Message: "", Data: map[string]any{
+		"settings":  settings,
+		"providers": models.MetadataProviders,
+	}})
+}
+
+// SaveMetadataProviderSettings 保存元数据提供者设置
+// @Summary 保存元数据提供者设置
+// @Description 保存TVDB的API Key、PIN和Bangumi的Access Token
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param tvdb_api_key body string false "TVDB API Key"
+// @Param tvdb_pin body string false "TVDB订阅PIN"
+// @Param bangumi_token body string false "Bangumi Access Token"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/metadata-providers [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func SaveMetadataProviderSettings(c *gin.Context) {
+	reqData := MetadataProviderSettings{}
+	if err := c.ShouldBindJSON(&reqData); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	if err := models.GlobalScrapeSettings.SaveMetadataProviders(reqData.TvdbApiKey, reqData.TvdbPin, reqData.BangumiToken); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "保存元数据提供者设置成功", Data: nil})
+}
+
+// TestTvdbSettings 测试TVDB设置
+// @Summary 测试TVDB连接
+// @Description 使用指定的API Key和PIN登录TVDB
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param tvdb_api_key body string true "TVDB API Key"
+// @Param tvdb_pin body string false "TVDB订阅PIN"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/tvdb-test [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func TestTvdbSettings(c *gin.Context) {
+	reqData := MetadataProviderSettings{}
+	if err := c.ShouldBindJSON(&reqData); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	tmpScrapeSetting := &models.ScrapeSettings{
+		TvdbApiKey:      reqData.TvdbApiKey,
+		TvdbPin:         reqData.TvdbPin,
+		TmdbEnableProxy: models.GlobalScrapeSettings.TmdbEnableProxy,
+	}
+	testResult := tmpScrapeSetting.TestTvdb()
+	c.JSON(http.StatusOK, APIResponse[bool]{Code: Success, Message: "", Data: testResult})
+}
+
+// GetSubtitleSettings 获取字幕下载设置
+// @Summary 获取字幕下载设置
+// @Description 获取字幕提供者、语言优先级和OpenSubtitles账号，以及可用的字幕提供者列表
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/subtitle-settings [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetSubtitleSettings(c *gin.Context) {
+	settings := SubtitleSettings{
+		SubtitleProvider:      models.GlobalScrapeSettings.SubtitleProvider,
+		SubtitleLanguages:     strings.Join(models.GlobalScrapeSettings.GetSubtitleLanguages(), ","),
+		OpenSubtitlesApiKey:   models.GlobalScrapeSettings.OpenSubtitlesApiKey,
+		OpenSubtitlesUsername: models.GlobalScrapeSettings.OpenSubtitlesUsername,
+	}
+	if models.GlobalScrapeSettings.OpenSubtitlesPassword != "" {
+		settings.OpenSubtitlesPassword = models.SubtitlePasswordMask
+	}
+	c.JSON(http.StatusOK, APIResponse[map[string]any]{Code: Success, Message: "", Data: map[string]any{
+		"settings":  settings,
+		"providers": subtitle.Providers(),
+	}})
+}
+
+// SaveSubtitleSettings 保存字幕下载设置
+// @Summary 保存字幕下载设置
+// @Description 保存字幕提供者、语言优先级和OpenSubtitles账号，字幕提供者为空表示关闭字幕下载
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param subtitle_provider body string false "字幕提供者，为空关闭"
+// @Param subtitle_languages body string false "字幕语言优先级，逗号分隔，例如 zh-cn,zh-tw,en"
+// @Param opensubtitles_api_key body string false "OpenSubtitles API Key"
+// @Param opensubtitles_username body string false "OpenSubtitles 用户名"
+// @Param opensubtitles_password body string false "OpenSubtitles 密码"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/subtitle-settings [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func SaveSubtitleSettings(c *gin.Context) {
+	reqData := SubtitleSettings{}
+	if err := c.ShouldBindJSON(&reqData); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	if reqData.SubtitleProvider == subtitle.ProviderOpenSubtitles && reqData.OpenSubtitlesApiKey == "" {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "使用OpenSubtitles需要填写API Key", Data: nil})
+		return
+	}
+	if err := models.GlobalScrapeSettings.SaveSubtitle(reqData.SubtitleProvider, reqData.SubtitleLanguages, reqData.OpenSubtitlesApiKey, reqData.OpenSubtitlesUsername, reqData.OpenSubtitlesPassword); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "保存字幕下载设置成功", Data: nil})
+}
+
+// TestSubtitleSettings 测试字幕提供者设置
+// @Summary 测试字幕提供者连接
+// @Description 使用指定的API Key登录OpenSubtitles，填写了用户名时测试账号登录
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param opensubtitles_api_key body string true "OpenSubtitles API Key"
+// @Param opensubtitles_username body string false "OpenSubtitles 用户名"
+// @Param opensubtitles_password body string false "OpenSubtitles 密码"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/subtitle-test [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func TestSubtitleSettings(c *gin.Context) {
+	reqData := SubtitleSettings{}
+	if err := c.ShouldBindJSON(&reqData); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
+		return
+	}
+	if reqData.OpenSubtitlesPassword == models.SubtitlePasswordMask {
+		reqData.OpenSubtitlesPassword = models.GlobalScrapeSettings.OpenSubtitlesPassword
+	}
+	client := subtitle.NewOpenSubtitles(subtitle.Config{
+		ApiKey:   reqData.OpenSubtitlesApiKey,
+		Username: reqData.OpenSubtitlesUsername,
+		Password: reqData.OpenSubtitlesPassword,
+		ProxyUrl: models.GlobalScrapeSettings.GetTmdbProxyUrl(),
+	})
+	if err := client.TestConnection(c.Request.Context()); err != nil {
+		c.JSON(http.StatusOK, APIResponse[bool]{Code: Success, Message: err.Error(), Data: false})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[bool]{Code: Success, Message: "", Data: true})
+}
+
 // SaveAiSettings 保存AI识别设置
 // @Summary 保存AI识别设置
 // @Description 保存或更新AI识别模型的配置
@@ -162,6 +360,8 @@ func TestTmdbSettings(c *gin.Context) {
 // @Param ai_base_url body string false "AI服务器地址"
 // @Param ai_model_name body string false "AI模型名称"
 // @Param ai_timeout body integer false "AI超时时间"
+// @Param ai_input_price body number false "输入每百万token单价"
+// @Param ai_output_price body number false "输出每百万token单价"
 // @Success 200 {object} object
 // @Failure 200 {object} object
 // @Router /scrape/ai-settings [post]
@@ -173,7 +373,7 @@ func SaveAiSettings(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
 		return
 	}
-	if err := models.GlobalScrapeSettings.SaveAi(reqData.AiApiKey, reqData.AiBaseUrl, reqData.AiModelName, reqData.AiTimeout); err != nil {
+	if err := models.GlobalScrapeSettings.SaveAi(reqData.AiApiKey, reqData.AiBaseUrl, reqData.AiModelName, reqData.AiTimeout, reqData.AiInputPrice, reqData.AiOutputPrice); err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
 		return
 	}
@@ -230,14 +430,40 @@ func TestAiSettings(c *gin.Context) {
 // @Security ApiKeyAuth
 func GetAiSettings(c *gin.Context) {
 	aiSettings := AiSettings{
-		AiApiKey:    models.GlobalScrapeSettings.AiApiKey,
-		AiBaseUrl:   models.GlobalScrapeSettings.AiBaseUrl,
-		AiModelName: models.GlobalScrapeSettings.AiModelName,
-		AiTimeout:   models.GlobalScrapeSettings.AiTimeout,
+		AiApiKey:           models.GlobalScrapeSettings.AiApiKey,
+		AiBaseUrl:          models.GlobalScrapeSettings.AiBaseUrl,
+		AiModelName:        models.GlobalScrapeSettings.AiModelName,
+		AiTimeout:          models.GlobalScrapeSettings.AiTimeout,
+		AiInputPrice:       models.GlobalScrapeSettings.AiInputPrice,
+		AiOutputPrice:      models.GlobalScrapeSettings.AiOutputPrice,
+		AiRequests:         models.GlobalScrapeSettings.AiRequests,
+		AiPromptTokens:     models.GlobalScrapeSettings.AiPromptTokens,
+		AiCompletionTokens: models.GlobalScrapeSettings.AiCompletionTokens,
+		AiCost:             models.GlobalScrapeSettings.GetAiCost(),
+		AiUsageResetAt:     models.GlobalScrapeSettings.AiUsageResetAt,
 	}
 	c.JSON(http.StatusOK, APIResponse[AiSettings]{Code: Success, Message: "", Data: aiSettings})
 }
 
+// ResetAiUsage 清空AI识别用量统计
+// @Summary 清空AI识别用量统计
+// @Description 清空累计的AI识别请求次数和token用量
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/ai-usage/reset [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func ResetAiUsage(c *gin.Context) {
+	if err := models.GlobalScrapeSettings.ResetAiUsage(); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "清空AI识别用量失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "已清空AI识别用量", Data: nil})
+}
+
 // GetMovieGenre 获取电影分类
 // @Summary 获取电影分类
 // @Description 获取TMDB电影分类列表
@@ -458,6 +684,12 @@ func DeleteTvshowCategory(c *gin.Context) {
 // @Security ApiKeyAuth
 func GetScrapePathes(c *gin.Context) {
 	scrapePathes := models.GetScrapePathes()
+	if loginUser := GetLoginUser(c); loginUser.GetRole() == models.UserRoleOperator {
+		// 操作员只能看到有权限的刮削目录
+		scrapePathes = slices.DeleteFunc(scrapePathes, func(scrapePath *models.ScrapePath) bool {
+			return !loginUser.CanAccessScrapePath(scrapePath.ID)
+		})
+	}
 	for _, scrapePath := range scrapePathes {
 		// 检查是否正在运行
 		scrapePath.IsTaskRunning = synccron.CheckNewTaskStatus(scrapePath.ID, synccron.SyncTaskTypeScrape)
@@ -484,6 +716,9 @@ func GetScrapePath(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "刮削目录不存在", Data: nil})
 		return
 	}
+	if !checkScrapePathPermission(c, scrapePath.ID) {
+		return
+	}
 	if scrapePath.EnableAi == "" {
 		scrapePath.EnableAi = models.AiActionOff
 	}
@@ -511,6 +746,25 @@ func SaveScrapePath(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: err.Error(), Data: nil})
 		return
 	}
+	// 操作员只能修改有权限的刮削目录，不能新增
+	if !checkScrapePathPermission(c, reqData.ID) {
+		return
+	}
+	if reqData.ID > 0 {
+		old := models.GetScrapePathByID(reqData.ID)
+		if old == nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "刮削目录不存在", Data: nil})
+			return
+		}
+		changed := reqData.SourceType != old.SourceType || reqData.AccountId != old.AccountId || reqData.SourcePathId != old.SourcePathId || reqData.DestPathId != old.DestPathId
+		if reqData.SourceType != models.SourceType115 {
+			// 115的路径由目录ID查询，其他来源直接使用路径
+			changed = changed || reqData.SourcePath != old.SourcePath || reqData.DestPath != old.DestPath
+		}
+		if !checkOperatorPathChange(c, changed) {
+			return
+		}
+	}
 	// 如果是115，用ID查询实际的目录
 	if reqData.SourceType == models.SourceType115 {
 		// 用ID查询实际的目录
@@ -591,6 +845,9 @@ func ScanScrapePath(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "刮削目录不存在", Data: nil})
 		return
 	}
+	if !checkScrapePathPermission(c, scrapePath.ID) {
+		return
+	}
 	if err := synccron.AddNewSyncTask(scrapePath.ID, synccron.SyncTaskTypeScrape); err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "添加刮削任务失败: " + err.Error(), Data: nil})
 		return
@@ -628,7 +885,12 @@ func GetScrapeRecords(c *gin.Context) {
 	status := c.Query("status")
 	name := c.Query("name")
 	scrapePathesCache := make(map[uint]*models.ScrapePath)
-	total, scrapeRecords := models.GetScrapeMediaFiles(page, pageSize, mediaType, status, name)
+	var scrapePathIds []uint
+	if loginUser := GetLoginUser(c); loginUser != nil && loginUser.GetRole() == models.UserRoleOperator {
+		// 操作员只能看到有权限的刮削目录的记录
+		scrapePathIds = loginUser.ScrapePathIdArray
+	}
+	total, scrapeRecords := models.GetScrapeMediaFiles(page, pageSize, mediaType, status, name, scrapePathIds)
 	type scrapeMediaResp struct {
 		ID              uint   `json:"id"`
 		Type            string `json:"type"`
@@ -885,7 +1147,8 @@ func ExportScrapeRecords(c *gin.Context) {
 // @Param id body integer true "记录ID"
 // @Param name body string true "新名称"
 // @Param year body integer true "新年份"
-// @Param tmdb_id body integer false "TMDBid"
+// @Param tmdb_id body integer false "TMDBid，metadata_provider不是tmdb时为对应提供者的ID"
+// @Param metadata_provider body string false "元数据提供者：tmdb、tvdb、bangumi，默认tmdb"
 // @Param season body integer false "季数"
 // @Param episode body integer false "集数"
 // @Success 200 {object} object
@@ -895,10 +1158,11 @@ func ExportScrapeRecords(c *gin.Context) {
 // @Security ApiKeyAuth
 func ReScrape(c *gin.Context) {
 	type reScrapeReq struct {
-		ID      uint  `json:"id"`
-		TmdbId  int64 `json:"tmdb_id"`
-		Season  int   `json:"season"`
-		Episode int   `json:"episode"`
+		ID               uint   `json:"id"`
+		TmdbId           int64  `json:"tmdb_id"`
+		MetadataProvider string `json:"metadata_provider"`
+		Season           int    `json:"season"`
+		Episode          int    `json:"episode"`
 	}
 	var req reScrapeReq
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -916,8 +1180,11 @@ func ReScrape(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "没有找到要重新刮削的记录的刮削目录", Data: nil})
 		return
 	}
+	if !checkScrapePathPermission(c, scrapePath.ID) {
+		return
+	}
 	oldStatus := scrapeMedia.Status
-	err := scrapeMedia.ReScrape("", 0, req.TmdbId, req.Season, req.Episode)
+	err := scrapeMedia.ReScrape("", 0, req.TmdbId, req.Season, req.Episode, req.MetadataProvider)
 	if err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "重新刮削失败: " + err.Error(), Data: nil})
 		return
@@ -930,6 +1197,7 @@ func ReScrape(c *gin.Context) {
 		data["name"] = scrapeMedia.Name
 		data["year"] = scrapeMedia.Year
 		data["tmdb_id"] = scrapeMedia.TmdbId
+		data["metadata_provider"] = scrapeMedia.GetMetadataProvider()
 		c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "操作成功，下次扫描时会使用新的名称和年份进行刮削", Data: data})
 	}
 }
@@ -971,6 +1239,9 @@ func FinishScrapeMediaFile(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "没有找到要整理的记录", Data: nil})
 		return
 	}
+	if !checkScrapePathPermission(c, scrapeMedia.ScrapePathId) {
+		return
+	}
 	scrapeMedia.FinishFromRenaming()
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "操作成功，记录已标记为已整理", Data: nil})
 }
@@ -1072,6 +1343,9 @@ func ToggleScrapePathCron(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "没有找到要操作的记录", Data: nil})
 		return
 	}
+	if !checkScrapePathPermission(c, scrapePath.ID) {
+		return
+	}
 	// 切换定时刮削
 	err := scrapePath.ToggleCron()
 	if err != nil {
@@ -1102,6 +1376,9 @@ func StopScrape(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
 		return
 	}
+	if !checkScrapePathPermission(c, req.ID) {
+		return
+	}
 	synccron.CancelNewSyncTask(req.ID, synccron.SyncTaskTypeScrape)
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "操作成功，刮削任务已停止", Data: nil})
 }
@@ -1142,6 +1419,9 @@ func SaveScrapeStrmPath(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "刮削目录不存在", Data: nil})
 		return
 	}
+	if !checkScrapePathPermission(c, scrapePath.ID) {
+		return
+	}
 	if err := scrapePath.SaveStrmPath(req.SyncPathIDs); err != nil {
 		helpers.AppLogger.Errorf("保存刮削目录关联的同步目录失败: %v", err)
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "保存刮削目录关联的同步目录失败: " + err.Error(), Data: nil})
@@ -1164,6 +1444,9 @@ func GetScrapeStrmPaths(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "没有找到要操作的记录", Data: nil})
 		return
 	}
+	if !checkScrapePathPermission(c, scrapePath.ID) {
+		return
+	}
 	ssp := scrapePath.GetRelatStrmPath()
 	syncPathIds := make([]uint, 0)
 	for _, sp := range ssp {
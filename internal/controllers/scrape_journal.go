@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/scrape"
+	"Q115-STRM/internal/synccron"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetScrapeJournals 获取整理日志
+// @Summary 获取整理日志
+// @Description 分页获取刮削目录整理时执行的移动、复制和改名操作
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param scrape_path_id query integer true "刮削目录ID"
+// @Param batch_no query string false "批次号"
+// @Param page query integer false "页码，默认1"
+// @Param page_size query integer false "每页数量，默认100"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/journals [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetScrapeJournals(c *gin.Context) {
+	type scrapeJournalRequest struct {
+		ScrapePathId uint   `form:"scrape_path_id" json:"scrape_path_id" binding:"required"` // 刮削目录ID
+		BatchNo      string `form:"batch_no" json:"batch_no"`                                // 批次号
+		Page         int    `form:"page" json:"page" binding:"omitempty,min=1"`              // 页码，默认1
+		PageSize     int    `form:"page_size" json:"page_size" binding:"omitempty,min=1"`    // 每页数量，默认100
+	}
+	var req scrapeJournalRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	if !checkScrapePathPermission(c, req.ScrapePathId) {
+		return
+	}
+	page := req.Page
+	pageSize := req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+	journals, total, err := models.GetScrapeJournalList(req.ScrapePathId, req.BatchNo, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "获取整理日志失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取整理日志成功", Data: map[string]any{
+		"list":      journals,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}})
+}
+
+// StartScrapeJournalRollback 批量撤销整理
+// @Summary 批量撤销整理
+// @Description 按批次号或者时间范围，按相反顺序撤销整理时执行的移动、复制和改名操作，刮削任务运行中不能撤销
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param scrape_path_id body integer true "刮削目录ID"
+// @Param batch_no body string false "批次号，不为空时忽略时间范围"
+// @Param start_time body integer false "开始时间（秒级时间戳）"
+// @Param end_time body integer false "结束时间（秒级时间戳）"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/journals/rollback [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func StartScrapeJournalRollback(c *gin.Context) {
+	type rollbackRequest struct {
+		ScrapePathId uint   `json:"scrape_path_id" form:"scrape_path_id" binding:"required"` // 刮削目录ID
+		BatchNo      string `json:"batch_no" form:"batch_no"`                                // 批次号
+		StartTime    int64  `json:"start_time" form:"start_time"`                            // 开始时间
+		EndTime      int64  `json:"end_time" form:"end_time"`                                // 结束时间
+	}
+	var req rollbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	scrapePath := models.GetScrapePathByID(req.ScrapePathId)
+	if scrapePath == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "刮削目录不存在", Data: nil})
+		return
+	}
+	if !checkScrapePathPermission(c, scrapePath.ID) {
+		return
+	}
+	if synccron.CheckNewTaskStatus(scrapePath.ID, synccron.SyncTaskTypeScrape) != synccron.TaskStatusNone {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "刮削任务正在运行或排队中，请先停止刮削任务", Data: nil})
+		return
+	}
+	rollback, err := scrape.StartJournalRollback(scrapePath, req.BatchNo, req.StartTime, req.EndTime)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "启动批量撤销失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "批量撤销已启动", Data: rollback.Snapshot()})
+}
+
+// GetScrapeJournalRollback 查询批量撤销结果
+// @Summary 查询批量撤销结果
+// @Description 查询刮削目录最近一次批量撤销的进度，以及无法撤销的操作和原因
+// @Tags 刮削管理
+// @Accept json
+// @Produce json
+// @Param scrape_path_id query integer true "刮削目录ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /scrape/journals/rollback [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetScrapeJournalRollback(c *gin.Context) {
+	type rollbackStatusRequest struct {
+		ScrapePathId uint `form:"scrape_path_id" json:"scrape_path_id" binding:"required"` // 刮削目录ID
+	}
+	var req rollbackStatusRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	if !checkScrapePathPermission(c, req.ScrapePathId) {
+		return
+	}
+	rollback := scrape.GetJournalRollback(req.ScrapePathId)
+	if rollback == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "该刮削目录还没有批量撤销记录", Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取批量撤销结果成功", Data: rollback.Snapshot()})
+}
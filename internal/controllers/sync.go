@@ -4,6 +4,7 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/synccron"
+	"Q115-STRM/internal/syncstrm"
 	"fmt"
 	"net/http"
 	"path/filepath"
@@ -64,7 +65,12 @@ func GetSyncRecords(c *gin.Context) {
 	}
 
 	// 获取同步记录
-	records, total, err := models.GetSyncRecords(page, pageSize)
+	var syncPathIds []uint
+	if loginUser := GetLoginUser(c); loginUser != nil && loginUser.GetRole() == models.UserRoleOperator {
+		// 操作员只能看到有权限的同步目录的记录
+		syncPathIds = loginUser.SyncPathIdArray
+	}
+	records, total, err := models.GetSyncRecords(page, pageSize, syncPathIds)
 	if err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: 500, Message: "获取同步记录失败", Data: nil})
 		return
@@ -108,6 +114,9 @@ func GetSyncTask(c *gin.Context) {
 		c.JSON(http.StatusNotFound, APIResponse[any]{Code: 404, Message: "未找到对应的同步任务", Data: nil})
 		return
 	}
+	if !checkSyncPathPermission(c, sync.SyncPathId) {
+		return
+	}
 
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取同步任务详情成功", Data: sync})
 }
@@ -145,7 +154,14 @@ func GetSyncPathList(c *gin.Context) {
 		pageSize = 20
 	}
 
-	syncPaths, total := models.GetSyncPathList(page, pageSize, false, req.SourceType)
+	var syncPaths []*models.SyncPath
+	var total int64
+	if loginUser := GetLoginUser(c); loginUser != nil && loginUser.GetRole() == models.UserRoleOperator {
+		// 操作员只能看到有权限的同步目录
+		syncPaths, total = models.GetSyncPathListByIds(page, pageSize, req.SourceType, loginUser.SyncPathIdArray)
+	} else {
+		syncPaths, total = models.GetSyncPathList(page, pageSize, false, req.SourceType)
+	}
 
 	for _, sp := range syncPaths {
 		status := synccron.CheckNewTaskStatus(sp.ID, synccron.SyncTaskTypeStrm)
@@ -161,16 +177,34 @@ func GetSyncPathList(c *gin.Context) {
 }
 
 type addSyncPathRequest struct {
-	SourceType   models.SourceType `json:"source_type" form:"source_type" binding:"required"` // 来源类型
-	AccountId    uint              `json:"account_id" form:"account_id"`                      // 网盘账号ID
-	BaseCid      string            `json:"base_cid" form:"base_cid" binding:"required"`       // 来源路径ID或者本地路径
-	LocalPath    string            `json:"local_path" form:"local_path" binding:"required"`   // 本地路径
-	RemotePath   string            `json:"remote_path" form:"remote_path" binding:"required"` // 同步源路径，115网盘和123网盘需要该字段
-	EnableCron   bool              `json:"enable_cron" form:"enable_cron"`                    // 是否启用定时任务
-	CustomConfig bool              `json:"custom_config" form:"custom_config"`                // 自定义配置
+	SourceType       models.SourceType `json:"source_type" form:"source_type" binding:"required"` // 来源类型
+	AccountId        uint              `json:"account_id" form:"account_id"`                      // 网盘账号ID
+	BaseCid          string            `json:"base_cid" form:"base_cid" binding:"required"`       // 来源路径ID或者本地路径
+	LocalPath        string            `json:"local_path" form:"local_path" binding:"required"`   // 本地路径
+	RemotePath       string            `json:"remote_path" form:"remote_path" binding:"required"` // 同步源路径，115网盘和123网盘需要该字段
+	EnableCron       bool              `json:"enable_cron" form:"enable_cron"`                    // 是否启用定时任务
+	EnableWatch      bool              `json:"enable_watch" form:"enable_watch"`                  // 是否启用实时监控，仅本地同步源支持
+	MaxDeleteCount   *int64            `json:"max_delete_count" form:"max_delete_count"`          // 单次同步最多删除的本地文件数量，0为不限制，不传则不修改
+	MaxDeletePercent *int64            `json:"max_delete_percent" form:"max_delete_percent"`      // 单次同步最多删除的本地文件百分比，0为不限制，不传则不修改
+	CustomConfig     bool              `json:"custom_config" form:"custom_config"`                // 自定义配置
 	models.SettingStrm
 }
 
+// 请求中传了删除阈值才修改，没传的保持原值
+func (req *addSyncPathRequest) applyDeleteLimit(syncPath *models.SyncPath) {
+	if req.MaxDeleteCount == nil && req.MaxDeletePercent == nil {
+		return
+	}
+	maxDeleteCount, maxDeletePercent := syncPath.MaxDeleteCount, syncPath.MaxDeletePercent
+	if req.MaxDeleteCount != nil {
+		maxDeleteCount = *req.MaxDeleteCount
+	}
+	if req.MaxDeletePercent != nil {
+		maxDeletePercent = *req.MaxDeletePercent
+	}
+	syncPath.SetDeleteLimit(maxDeleteCount, maxDeletePercent)
+}
+
 // AddSyncPath 添加同步路径
 // @Summary 添加同步路径
 // @Description 创建新的同步路径配置
@@ -183,6 +217,9 @@ type addSyncPathRequest struct {
 // @Param local_path body string true "本地路径"
 // @Param remote_path body string true "同步源路径"
 // @Param enable_cron body boolean false "是否启用定时任务"
+// @Param enable_watch body boolean false "是否启用实时监控，仅本地同步源支持"
+// @Param max_delete_count body integer false "单次同步最多删除的本地文件数量，0为不限制"
+// @Param max_delete_percent body integer false "单次同步最多删除的本地文件百分比，0为不限制"
 // @Param custom_config body boolean false "是否自定义配置"
 // @Success 200 {object} object
 // @Failure 200 {object} object
@@ -236,6 +273,11 @@ func AddSyncPath(c *gin.Context) {
 	if syncPath.EnableCron && syncPath.Cron != "" {
 		synccron.InitSyncCron()
 	}
+	req.applyDeleteLimit(syncPath)
+	if req.EnableWatch {
+		syncPath.SetEnableWatch(true)
+		synccron.RefreshLocalWatcher(syncPath.ID)
+	}
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "添加同步路径成功", Data: syncPath})
 }
 
@@ -252,6 +294,9 @@ func AddSyncPath(c *gin.Context) {
 // @Param local_path body string true "本地路径"
 // @Param remote_path body string true "同步源路径"
 // @Param enable_cron body boolean false "是否启用定时任务"
+// @Param enable_watch body boolean false "是否启用实时监控，仅本地同步源支持"
+// @Param max_delete_count body integer false "单次同步最多删除的本地文件数量，0为不限制"
+// @Param max_delete_percent body integer false "单次同步最多删除的本地文件百分比，0为不限制"
 // @Param custom_config body boolean false "是否自定义配置"
 // @Success 200 {object} object
 // @Failure 200 {object} object
@@ -275,6 +320,9 @@ func UpdateSyncPath(c *gin.Context) {
 		c.JSON(http.StatusNotFound, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
 		return
 	}
+	if !checkSyncPathPermission(c, syncPath.ID) {
+		return
+	}
 	if req.SourceType != models.SourceTypeLocal {
 		// 检查accountId是否存在
 		account, err := models.GetAccountById(syncPath.AccountId)
@@ -300,11 +348,15 @@ func UpdateSyncPath(c *gin.Context) {
 			remotePath = "/" + remotePath
 		}
 	}
-	if req.SourceType == models.SourceTypeOpenList {
+	if req.SourceType == models.SourceTypeOpenList || req.SourceType == models.SourceTypeWebDav || req.SourceType == models.SourceTypeS3 {
 		// 将remotepath中的\都替换为/
 		req.RemotePath = strings.ReplaceAll(req.RemotePath, "\\", "/")
 		req.BaseCid = strings.ReplaceAll(req.BaseCid, "\\", "/")
 	}
+	changed := req.SourceType != syncPath.SourceType || req.AccountId != syncPath.AccountId || req.BaseCid != syncPath.BaseCid || req.LocalPath != syncPath.LocalPath || remotePath != syncPath.RemotePath
+	if !checkOperatorPathChange(c, changed) {
+		return
+	}
 	// helpers.AppLogger.Infof("更新同步路径 %d 定时任务: %s", syncPath.ID, req.Cron)
 	success := syncPath.Update(req.SourceType, req.AccountId, req.BaseCid, req.LocalPath, remotePath, req.EnableCron, req.CustomConfig, req.SettingStrm)
 	if !success {
@@ -314,6 +366,10 @@ func UpdateSyncPath(c *gin.Context) {
 	if syncPath.EnableCron && syncPath.Cron != "" {
 		synccron.InitSyncCron()
 	}
+	req.applyDeleteLimit(syncPath)
+	syncPath.SetEnableWatch(req.EnableWatch)
+	// 路径或扩展名可能变化，重启实时监控
+	synccron.RefreshLocalWatcher(syncPath.ID)
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "更新同步路径成功", Data: syncPath})
 }
 
@@ -349,6 +405,7 @@ func DeleteSyncPath(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "删除同步路径失败", Data: nil})
 		return
 	}
+	synccron.StopLocalWatcher(id)
 
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "删除同步路径成功", Data: nil})
 }
@@ -379,6 +436,9 @@ func GetSyncPathById(c *gin.Context) {
 		c.JSON(http.StatusNotFound, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
 		return
 	}
+	if !checkSyncPathPermission(c, syncPath.ID) {
+		return
+	}
 
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取同步路径详情成功", Data: syncPath})
 }
@@ -426,6 +486,7 @@ func DelSyncRecords(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id body integer true "同步路径ID"
+// @Param confirm_delete body boolean false "确认本次同步可以超过删除阈值"
 // @Success 200 {object} object
 // @Failure 200 {object} object
 // @Router /sync/path/start [post]
@@ -433,7 +494,8 @@ func DelSyncRecords(c *gin.Context) {
 // @Security ApiKeyAuth
 func StartSyncByPath(c *gin.Context) {
 	type startSyncRequest struct {
-		ID uint `form:"id" json:"id" binding:"required"` // 同步路径ID
+		ID            uint `form:"id" json:"id" binding:"required"`      // 同步路径ID
+		ConfirmDelete bool `form:"confirm_delete" json:"confirm_delete"` // 确认本次同步可以超过删除阈值
 	}
 	var req startSyncRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -451,7 +513,13 @@ func StartSyncByPath(c *gin.Context) {
 		c.JSON(http.StatusNotFound, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
 		return
 	}
+	if !checkSyncPathPermission(c, syncPath.ID) {
+		return
+	}
 	// syncPath.SetIsFullSync(false)
+	if req.ConfirmDelete {
+		syncPath.SetConfirmDelete(true)
+	}
 	if err := synccron.AddNewSyncTask(syncPath.ID, synccron.SyncTaskTypeStrm); err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "添加同步任务失败: " + err.Error(), Data: nil})
 		return
@@ -492,6 +560,9 @@ func StopSyncByPath(c *gin.Context) {
 		c.JSON(http.StatusNotFound, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
 		return
 	}
+	if !checkSyncPathPermission(c, syncPath.ID) {
+		return
+	}
 	// syncPath.SetIsFullSync(false)
 	synccron.CancelNewSyncTask(syncPath.ID, synccron.SyncTaskTypeStrm)
 
@@ -529,6 +600,9 @@ func ToggleSyncByPath(c *gin.Context) {
 		c.JSON(http.StatusNotFound, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
 		return
 	}
+	if !checkSyncPathPermission(c, syncPath.ID) {
+		return
+	}
 	syncPath.ToggleCron()
 	// 重启自定义定时任务
 	if syncPath.Cron != "" {
@@ -542,6 +616,48 @@ func ToggleSyncByPath(c *gin.Context) {
 
 }
 
+// ToggleWatchByPath 切换同步路径的实时监控
+// @Summary 切换实时监控
+// @Description 开启或关闭本地同步目录的实时监控，开启后新增、重命名、删除的文件会在几秒内同步
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "同步路径ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/path/toggle-watch [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func ToggleWatchByPath(c *gin.Context) {
+	type toggleWatchRequest struct {
+		ID uint `form:"id" json:"id" binding:"required"` // 同步路径ID
+	}
+	var req toggleWatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	syncPath := models.GetSyncPathById(req.ID)
+	if syncPath == nil {
+		c.JSON(http.StatusNotFound, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
+		return
+	}
+	if !checkSyncPathPermission(c, syncPath.ID) {
+		return
+	}
+	if syncPath.SourceType != models.SourceTypeLocal {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "只有本地同步源支持实时监控", Data: nil})
+		return
+	}
+	syncPath.ToggleWatch()
+	synccron.RefreshLocalWatcher(syncPath.ID)
+	if syncPath.EnableWatch {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "实时监控已开启", Data: nil})
+	} else {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "实时监控已关闭", Data: nil})
+	}
+}
+
 // FullStart115Sync 启动115全量同步
 // @Summary 启动115全量同步
 // @Description 删除本地缓存数据并触发115的全量同步
@@ -573,6 +689,9 @@ func FullStart115Sync(c *gin.Context) {
 		c.JSON(http.StatusNotFound, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
 		return
 	}
+	if !checkSyncPathPermission(c, syncPath.ID) {
+		return
+	}
 	// 删除所有的数据库记录，重新查询接口
 	// if syncPath.SourceType == models.SourceType115 {
 	// 	// 清空数据表
@@ -589,3 +708,94 @@ func FullStart115Sync(c *gin.Context) {
 
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "同步任务已添加到队列", Data: nil})
 }
+
+// StartSyncPreview 启动同步预览
+// @Summary 启动同步预览
+// @Description 在后台完整对比网盘和本地文件，生成同步的变更计划，不会写入本地文件、操作网盘或添加上传下载任务
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "同步路径ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/path/preview [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func StartSyncPreview(c *gin.Context) {
+	type startPreviewRequest struct {
+		ID uint `form:"id" json:"id" binding:"required"` // 同步路径ID
+	}
+	var req startPreviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	syncPath := models.GetSyncPathById(req.ID)
+	if syncPath == nil {
+		c.JSON(http.StatusNotFound, APIResponse[any]{Code: BadRequest, Message: "同步路径不存在", Data: nil})
+		return
+	}
+	if !checkSyncPathPermission(c, syncPath.ID) {
+		return
+	}
+	preview, err := syncstrm.StartSyncPreview(syncPath)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "启动同步预览失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "同步预览已开始", Data: preview.Snapshot()})
+}
+
+// GetSyncPreview 获取同步预览结果
+// @Summary 获取同步预览
+// @Description 分页获取同步目录最近一次预览的变更计划和各类变更的数量，预览运行中返回已发现的变更
+// @Tags 同步管理
+// @Accept json
+// @Produce json
+// @Param id query integer true "同步路径ID"
+// @Param action query string false "变更类型：create_strm、update_strm、rename、download_meta、upload_meta、delete_local、delete_local_dir"
+// @Param page query integer false "页码"
+// @Param page_size query integer false "每页数量"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /sync/path/preview [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetSyncPreview(c *gin.Context) {
+	type syncPreviewRequest struct {
+		ID       uint                       `form:"id" json:"id" binding:"required"`                      // 同步路径ID
+		Action   syncstrm.SyncPreviewAction `form:"action" json:"action"`                                 // 变更类型
+		Page     int                        `form:"page" json:"page" binding:"omitempty,min=1"`           // 页码，默认1
+		PageSize int                        `form:"page_size" json:"page_size" binding:"omitempty,min=1"` // 每页数量，默认100
+	}
+	var req syncPreviewRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "请求参数错误", Data: nil})
+		return
+	}
+	if !checkSyncPathPermission(c, req.ID) {
+		return
+	}
+	page := req.Page
+	pageSize := req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+	preview := syncstrm.GetSyncPreview(req.ID)
+	if preview == nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "该同步目录还没有预览，请先启动预览", Data: nil})
+		return
+	}
+	items, total := preview.GetItems(req.Action, page, pageSize)
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取同步预览成功", Data: map[string]any{
+		"preview":   preview.Snapshot(),
+		"counts":    preview.Counts(),
+		"list":      items,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	}})
+}
@@ -452,5 +452,20 @@ func StartListenTelegramBot() {
 	}
 
 	mgr.RegisterTelegramCommands(myCommands)
+	mgr.RegisterTelegramInteractive(&helpers.TelegramInteractive{
+		Commands: map[string]func([]string) *helpers.TelegramReply{
+			"menu":   TelegramMenu,
+			"search": TelegramSearch,
+			"fix":    TelegramFix,
+		},
+		Callbacks: map[string]func([]string) *helpers.TelegramReply{
+			tgPrefixMenu:       TelegramMenu,
+			tgPrefixSyncPath:   TelegramSyncPathCallback,
+			tgPrefixScrapePath: TelegramScrapePathCallback,
+			tgPrefixQueue:      TelegramQueueCallback,
+			tgPrefixUpload:     TelegramUploadCallback,
+			tgPrefixRecord:     TelegramRecordCallback,
+		},
+	})
 	mgr.StartAll()
 }
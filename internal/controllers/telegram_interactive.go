@@ -0,0 +1,443 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/synccron"
+	"fmt"
+	"html"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Telegram 管理菜单每页显示的目录和记录数量
+const telegramPageSize = 8
+
+// Telegram 按钮回调前缀
+const (
+	tgPrefixMenu       = "menu" // 主菜单
+	tgPrefixSyncPath   = "sp"   // 同步目录
+	tgPrefixScrapePath = "sc"   // 刮削目录
+	tgPrefixQueue      = "q"    // 队列状态
+	tgPrefixUpload     = "up"   // 失败的上传任务
+	tgPrefixRecord     = "rec"  // 刮削记录
+)
+
+func tgButton(text, prefix string, args ...string) helpers.TelegramButton {
+	return helpers.TelegramButton{Text: text, Data: helpers.TelegramCallbackData(prefix, args...)}
+}
+
+func tgBackButton() helpers.TelegramButton {
+	return tgButton("🏠 主菜单", tgPrefixMenu, "main")
+}
+
+func tgText(text string) *helpers.TelegramReply {
+	return &helpers.TelegramReply{Text: text, Keyboard: [][]helpers.TelegramButton{{tgBackButton()}}}
+}
+
+// 从回调参数中取第index个数字，不存在或格式错误时返回0
+func tgArgUint(args []string, index int) uint {
+	if len(args) <= index {
+		return 0
+	}
+	id, err := strconv.ParseUint(args[index], 10, 32)
+	if err != nil {
+		return 0
+	}
+	return uint(id)
+}
+
+// 翻页按钮
+func tgPageButtons(prefix string, page int, total int64) []helpers.TelegramButton {
+	var row []helpers.TelegramButton
+	if page > 1 {
+		row = append(row, tgButton("⬅️ 上一页", prefix, "list", strconv.Itoa(page-1)))
+	}
+	if int64(page*telegramPageSize) < total {
+		row = append(row, tgButton("下一页 ➡️", prefix, "list", strconv.Itoa(page+1)))
+	}
+	return row
+}
+
+func tgTaskStatusText(status int) string {
+	switch status {
+	case synccron.TaskStatusWaiting:
+		return "⏳ 排队中"
+	case synccron.TaskStatusRunning:
+		return "▶️ 运行中"
+	}
+	return "⏸ 空闲"
+}
+
+// TelegramMenu 主菜单
+func TelegramMenu(args []string) *helpers.TelegramReply {
+	return &helpers.TelegramReply{
+		Text: "🧭 <b>管理菜单</b>\n请选择要管理的内容，也可以发送 /search 关键词 搜索刮削记录",
+		Keyboard: [][]helpers.TelegramButton{
+			{tgButton("📁 同步目录", tgPrefixSyncPath, "list", "1"), tgButton("🎬 刮削目录", tgPrefixScrapePath, "list", "1")},
+			{tgButton("📊 队列状态", tgPrefixQueue, "status"), tgButton("♻️ 失败的上传", tgPrefixUpload, "view")},
+		},
+	}
+}
+
+// TelegramSyncPathCallback 同步目录列表、详情、启动和停止
+func TelegramSyncPathCallback(args []string) *helpers.TelegramReply {
+	if len(args) == 0 {
+		return TelegramMenu(nil)
+	}
+	id := tgArgUint(args, 1)
+	switch args[0] {
+	case "list":
+		page := max(int(tgArgUint(args, 1)), 1)
+		syncPaths, total := models.GetSyncPathList(page, telegramPageSize, false, "")
+		if total == 0 {
+			return tgText("📁 还没有添加同步目录")
+		}
+		reply := &helpers.TelegramReply{Text: fmt.Sprintf("📁 <b>同步目录</b>（共 %d 个，第 %d 页）\n点击目录查看详情和操作", total, page)}
+		for _, syncPath := range syncPaths {
+			text := fmt.Sprintf("#%d %s", syncPath.ID, syncPath.RemotePath)
+			reply.Keyboard = append(reply.Keyboard, []helpers.TelegramButton{tgButton(text, tgPrefixSyncPath, "view", strconv.Itoa(int(syncPath.ID)))})
+		}
+		if row := tgPageButtons(tgPrefixSyncPath, page, total); len(row) > 0 {
+			reply.Keyboard = append(reply.Keyboard, row)
+		}
+		reply.Keyboard = append(reply.Keyboard, []helpers.TelegramButton{tgBackButton()})
+		return reply
+	case "run", "full":
+		if models.GetSyncPathById(id) == nil {
+			return tgText("❌ 同步目录不存在")
+		}
+		return telegramSyncPathView(id, runStrmTask(id, args[0] == "full"))
+	case "stop":
+		if err := synccron.CancelNewSyncTask(id, synccron.SyncTaskTypeStrm); err != nil {
+			return telegramSyncPathView(id, "❌ 停止失败: "+html.EscapeString(err.Error()))
+		}
+		return telegramSyncPathView(id, "⏹ 已停止同步任务")
+	}
+	return telegramSyncPathView(id, "")
+}
+
+func telegramSyncPathView(id uint, notice string) *helpers.TelegramReply {
+	syncPath := models.GetSyncPathById(id)
+	if syncPath == nil {
+		return tgText("❌ 同步目录不存在")
+	}
+	idStr := strconv.Itoa(int(id))
+	var sb strings.Builder
+	if notice != "" {
+		sb.WriteString(notice + "\n\n")
+	}
+	sb.WriteString(fmt.Sprintf("📁 <b>同步目录 #%d</b>\n", syncPath.ID))
+	sb.WriteString(fmt.Sprintf("来源: %s\n", syncPath.SourceType))
+	sb.WriteString(fmt.Sprintf("同步源路径: %s\n", html.EscapeString(syncPath.RemotePath)))
+	sb.WriteString(fmt.Sprintf("本地路径: %s\n", html.EscapeString(syncPath.LocalPath)))
+	if syncPath.LastSyncAt > 0 {
+		sb.WriteString(fmt.Sprintf("上次同步: %s\n", helpers.FormatTimestamp(syncPath.LastSyncAt)))
+	}
+	sb.WriteString("状态: " + tgTaskStatusText(synccron.CheckNewTaskStatus(id, synccron.SyncTaskTypeStrm)))
+	return &helpers.TelegramReply{
+		Text: sb.String(),
+		Keyboard: [][]helpers.TelegramButton{
+			{tgButton("🔄 增量同步", tgPrefixSyncPath, "run", idStr), tgButton("🚀 全量同步", tgPrefixSyncPath, "full", idStr)},
+			{tgButton("⏹ 停止", tgPrefixSyncPath, "stop", idStr), tgButton("🔃 刷新", tgPrefixSyncPath, "view", idStr)},
+			{tgButton("⬅️ 返回列表", tgPrefixSyncPath, "list", "1"), tgBackButton()},
+		},
+	}
+}
+
+// TelegramScrapePathCallback 刮削目录列表、详情、启动和停止
+func TelegramScrapePathCallback(args []string) *helpers.TelegramReply {
+	if len(args) == 0 {
+		return TelegramMenu(nil)
+	}
+	id := tgArgUint(args, 1)
+	switch args[0] {
+	case "list":
+		page := max(int(tgArgUint(args, 1)), 1)
+		scrapePaths := models.GetScrapePathes()
+		total := int64(len(scrapePaths))
+		if total == 0 {
+			return tgText("🎬 还没有添加刮削目录")
+		}
+		start := min((page-1)*telegramPageSize, len(scrapePaths))
+		end := min(start+telegramPageSize, len(scrapePaths))
+		reply := &helpers.TelegramReply{Text: fmt.Sprintf("🎬 <b>刮削目录</b>（共 %d 个，第 %d 页）\n点击目录查看详情和操作", total, page)}
+		for _, scrapePath := range scrapePaths[start:end] {
+			text := fmt.Sprintf("#%d %s", scrapePath.ID, scrapePath.SourcePath)
+			reply.Keyboard = append(reply.Keyboard, []helpers.TelegramButton{tgButton(text, tgPrefixScrapePath, "view", strconv.Itoa(int(scrapePath.ID)))})
+		}
+		if row := tgPageButtons(tgPrefixScrapePath, page, total); len(row) > 0 {
+			reply.Keyboard = append(reply.Keyboard, row)
+		}
+		reply.Keyboard = append(reply.Keyboard, []helpers.TelegramButton{tgBackButton()})
+		return reply
+	case "run":
+		if models.GetScrapePathByID(id) == nil {
+			return tgText("❌ 刮削目录不存在")
+		}
+		return telegramScrapePathView(id, runScrapeTask(id))
+	case "stop":
+		if err := synccron.CancelNewSyncTask(id, synccron.SyncTaskTypeScrape); err != nil {
+			return telegramScrapePathView(id, "❌ 停止失败: "+html.EscapeString(err.Error()))
+		}
+		return telegramScrapePathView(id, "⏹ 已停止刮削任务")
+	}
+	return telegramScrapePathView(id, "")
+}
+
+func telegramScrapePathView(id uint, notice string) *helpers.TelegramReply {
+	scrapePath := models.GetScrapePathByID(id)
+	if scrapePath == nil {
+		return tgText("❌ 刮削目录不存在")
+	}
+	idStr := strconv.Itoa(int(id))
+	var sb strings.Builder
+	if notice != "" {
+		sb.WriteString(notice + "\n\n")
+	}
+	sb.WriteString(fmt.Sprintf("🎬 <b>刮削目录 #%d</b>\n", scrapePath.ID))
+	sb.WriteString(fmt.Sprintf("来源: %s，类型: %s\n", scrapePath.SourceType, scrapePath.MediaType))
+	sb.WriteString(fmt.Sprintf("源路径: %s\n", html.EscapeString(scrapePath.SourcePath)))
+	sb.WriteString(fmt.Sprintf("目标路径: %s\n", html.EscapeString(scrapePath.DestPath)))
+	sb.WriteString(fmt.Sprintf("待刮削: %d\n", models.GetScannedScrapeMediaFilesTotal(scrapePath.ID, scrapePath.MediaType)))
+	sb.WriteString("状态: " + tgTaskStatusText(synccron.CheckNewTaskStatus(id, synccron.SyncTaskTypeScrape)))
+	return &helpers.TelegramReply{
+		Text: sb.String(),
+		Keyboard: [][]helpers.TelegramButton{
+			{tgButton("🎬 开始刮削", tgPrefixScrapePath, "run", idStr), tgButton("⏹ 停止", tgPrefixScrapePath, "stop", idStr)},
+			{tgButton("🔃 刷新", tgPrefixScrapePath, "view", idStr)},
+			{tgButton("⬅️ 返回列表", tgPrefixScrapePath, "list", "1"), tgBackButton()},
+		},
+	}
+}
+
+// TelegramQueueCallback 各来源同步队列状态和上传队列数量
+func TelegramQueueCallback(args []string) *helpers.TelegramReply {
+	var sb strings.Builder
+	sb.WriteString("📊 <b>队列状态</b>\n")
+	queues := synccron.GetAllNewQueueStatus()
+	if len(queues) == 0 {
+		sb.WriteString("同步队列未启动\n")
+	}
+	sourceTypes := make([]string, 0, len(queues))
+	for sourceType := range queues {
+		sourceTypes = append(sourceTypes, string(sourceType))
+	}
+	sort.Strings(sourceTypes)
+	for _, sourceType := range sourceTypes {
+		status := queues[models.SourceType(sourceType)]
+		sb.WriteString(fmt.Sprintf("\n<b>%s</b>: %v，等待 %v 个", sourceType, status["status"], status["waiting_count"]))
+		if taskId, ok := status["current_task_id"].(uint); ok && taskId > 0 {
+			sb.WriteString(fmt.Sprintf("，正在执行 %v #%d", status["current_task_type"], taskId))
+		}
+	}
+	_, pending := models.GetUploadTaskList(models.UploadStatusPending, 1, 1)
+	_, failed := models.GetUploadTaskList(models.UploadStatusFailed, 1, 1)
+	sb.WriteString(fmt.Sprintf("\n\n⬆️ 上传队列: 上传中 %d，等待 %d，失败 %d", models.GetUploadingCount(), pending, failed))
+	return &helpers.TelegramReply{
+		Text: sb.String(),
+		Keyboard: [][]helpers.TelegramButton{
+			{tgButton("🔃 刷新", tgPrefixQueue, "status"), tgButton("♻️ 失败的上传", tgPrefixUpload, "view")},
+			{tgBackButton()},
+		},
+	}
+}
+
+// TelegramUploadCallback 查看并重试失败的上传任务
+func TelegramUploadCallback(args []string) *helpers.TelegramReply {
+	if len(args) > 0 && args[0] == "retry" {
+		if err := models.RetryFailedUploadTasks(); err != nil {
+			return tgText("❌ 重试失败的上传任务失败: " + html.EscapeString(err.Error()))
+		}
+		return tgText("✅ 已将失败的上传任务重新加入上传队列")
+	}
+	tasks, failed := models.GetUploadTaskList(models.UploadStatusFailed, 1, 5)
+	if failed == 0 {
+		return tgText("✅ 没有失败的上传任务")
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("♻️ <b>失败的上传任务</b>（共 %d 个）\n", failed))
+	for _, task := range tasks {
+		sb.WriteString(fmt.Sprintf("\n• %s\n  %s", html.EscapeString(task.FileName), html.EscapeString(task.Error)))
+	}
+	return &helpers.TelegramReply{
+		Text: sb.String(),
+		Keyboard: [][]helpers.TelegramButton{
+			{tgButton("♻️ 全部重试", tgPrefixUpload, "retry")},
+			{tgBackButton()},
+		},
+	}
+}
+
+// TelegramSearch 搜索刮削记录，格式: /search 关键词
+func TelegramSearch(args []string) *helpers.TelegramReply {
+	keyword := strings.TrimSpace(strings.Join(args, " "))
+	if keyword == "" {
+		return tgText("❌ 请输入要搜索的名称、路径或文件名，格式: /search 关键词")
+	}
+	total, records := models.GetScrapeMediaFiles(1, telegramPageSize, "", "", keyword, nil)
+	if total == 0 {
+		return tgText(fmt.Sprintf("🔍 没有找到包含 %s 的刮削记录", html.EscapeString(keyword)))
+	}
+	reply := &helpers.TelegramReply{Text: fmt.Sprintf("🔍 找到 %d 条刮削记录，显示最新的 %d 条\n点击记录查看详情或修正识别结果", total, len(records))}
+	for _, record := range records {
+		reply.Keyboard = append(reply.Keyboard, []helpers.TelegramButton{tgButton(telegramRecordTitle(record), tgPrefixRecord, "view", strconv.Itoa(int(record.ID)))})
+	}
+	reply.Keyboard = append(reply.Keyboard, []helpers.TelegramButton{tgBackButton()})
+	return reply
+}
+
+// TelegramFix 使用指定的TMDB ID修正识别结果，格式: /fix #记录ID TMDB_ID [季 集]
+func TelegramFix(args []string) *helpers.TelegramReply {
+	if len(args) < 2 {
+		return tgText("❌ 参数格式错误，格式: /fix #记录ID TMDB_ID [季 集]")
+	}
+	errMsg, id := checkAndExtractSingleParam(args)
+	if errMsg != "" {
+		return tgText(errMsg)
+	}
+	nums := make([]int64, 0, 3)
+	for _, arg := range args[1:] {
+		n, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || n < 0 {
+			return tgText("❌ TMDB ID、季和集必须是数字")
+		}
+		nums = append(nums, n)
+	}
+	nums = append(nums, 0, 0)
+	return telegramReScrape(id, nums[0], int(nums[1]), int(nums[2]))
+}
+
+// TelegramRecordCallback 刮削记录详情、TMDB候选和修正
+func TelegramRecordCallback(args []string) *helpers.TelegramReply {
+	if len(args) == 0 {
+		return TelegramMenu(nil)
+	}
+	id := tgArgUint(args, 1)
+	switch args[0] {
+	case "cand":
+		return telegramRecordCandidates(id)
+	case "fix":
+		if len(args) < 3 {
+			return tgText("❌ 参数错误")
+		}
+		tmdbId, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return tgText("❌ 参数错误")
+		}
+		return telegramReScrape(id, tmdbId, 0, 0)
+	}
+	return telegramRecordView(id, "")
+}
+
+func telegramRecordTitle(record *models.ScrapeMediaFile) string {
+	name := record.Name
+	if name == "" {
+		name = filepath.Base(record.VideoFilename)
+	}
+	if record.Year > 0 {
+		name = fmt.Sprintf("%s (%d)", name, record.Year)
+	}
+	if record.MediaType == models.MediaTypeTvShow && record.SeasonNumber > 0 {
+		name = fmt.Sprintf("%s S%02dE%02d", name, record.SeasonNumber, record.EpisodeNumber)
+	}
+	return fmt.Sprintf("#%d %s", record.ID, name)
+}
+
+func telegramRecordView(id uint, notice string) *helpers.TelegramReply {
+	record := models.GetScrapeMediaFileById(id)
+	if record == nil {
+		return tgText("❌ 刮削记录不存在")
+	}
+	idStr := strconv.Itoa(int(id))
+	var sb strings.Builder
+	if notice != "" {
+		sb.WriteString(notice + "\n\n")
+	}
+	sb.WriteString(fmt.Sprintf("🎞 <b>%s</b>\n", html.EscapeString(telegramRecordTitle(record))))
+	sb.WriteString(fmt.Sprintf("类型: %s，状态: %s\n", record.MediaType, record.Status))
+	sb.WriteString(fmt.Sprintf("%s ID: %d\n", record.GetMetadataProvider(), record.TmdbId))
+	sb.WriteString(fmt.Sprintf("文件: %s\n", html.EscapeString(filepath.Join(record.Path, record.VideoFilename))))
+	if record.FailedReason != "" {
+		sb.WriteString(fmt.Sprintf("失败原因: %s\n", html.EscapeString(record.FailedReason)))
+	}
+	sb.WriteString(fmt.Sprintf("\n识别错误时可以从TMDB候选中选择，或者发送 /fix #%d TMDB_ID [季 集] 修正", record.ID))
+	return &helpers.TelegramReply{
+		Text: sb.String(),
+		Keyboard: [][]helpers.TelegramButton{
+			{tgButton("🔍 TMDB候选", tgPrefixRecord, "cand", idStr), tgButton("🔃 刷新", tgPrefixRecord, "view", idStr)},
+			{tgBackButton()},
+		},
+	}
+}
+
+// 使用记录的名称在TMDB搜索候选，不限制年份
+func telegramRecordCandidates(id uint) *helpers.TelegramReply {
+	record := models.GetScrapeMediaFileById(id)
+	if record == nil {
+		return tgText("❌ 刮削记录不存在")
+	}
+	idStr := strconv.Itoa(int(id))
+	name := record.Name
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(record.VideoFilename), filepath.Ext(record.VideoFilename))
+	}
+	tmdbClient := models.GlobalScrapeSettings.GetTmdbClient()
+	language := models.GlobalScrapeSettings.GetTmdbLanguage()
+	var buttons [][]helpers.TelegramButton
+	if record.MediaType == models.MediaTypeTvShow {
+		result, err := tmdbClient.SearchTv(name, 0, language, true)
+		if err != nil {
+			return telegramRecordView(id, "❌ 查询TMDB失败: "+html.EscapeString(err.Error()))
+		}
+		for i, tv := range result.Results {
+			if i >= 6 {
+				break
+			}
+			text := fmt.Sprintf("%s (%d) %d", tv.Name, helpers.ParseYearFromDate(tv.FirstAirDate), tv.ID)
+			buttons = append(buttons, []helpers.TelegramButton{tgButton(text, tgPrefixRecord, "fix", idStr, strconv.FormatInt(tv.ID, 10))})
+		}
+	} else {
+		result, err := tmdbClient.SearchMovie(name, 0, language, true, false)
+		if err != nil {
+			return telegramRecordView(id, "❌ 查询TMDB失败: "+html.EscapeString(err.Error()))
+		}
+		for i, movie := range result.Results {
+			if i >= 6 {
+				break
+			}
+			text := fmt.Sprintf("%s (%d) %d", movie.Title, helpers.ParseYearFromDate(movie.ReleaseDate), movie.ID)
+			buttons = append(buttons, []helpers.TelegramButton{tgButton(text, tgPrefixRecord, "fix", idStr, strconv.FormatInt(movie.ID, 10))})
+		}
+	}
+	if len(buttons) == 0 {
+		return telegramRecordView(id, fmt.Sprintf("🔍 TMDB中没有找到 %s", html.EscapeString(name)))
+	}
+	buttons = append(buttons, []helpers.TelegramButton{tgButton("⬅️ 返回记录", tgPrefixRecord, "view", idStr)})
+	return &helpers.TelegramReply{
+		Text:     fmt.Sprintf("🔍 <b>%s</b> 的TMDB候选\n点击正确的结果修正识别", html.EscapeString(name)),
+		Keyboard: buttons,
+	}
+}
+
+// 与重新刮削接口的处理一致，已重命名的记录先回滚到源目录
+func telegramReScrape(id uint, tmdbId int64, season, episode int) *helpers.TelegramReply {
+	record := models.GetScrapeMediaFileById(id)
+	if record == nil {
+		return tgText("❌ 刮削记录不存在")
+	}
+	if models.GetScrapePathByID(record.ScrapePathId) == nil {
+		return tgText("❌ 没有找到刮削记录的刮削目录")
+	}
+	oldStatus := record.Status
+	if err := record.ReScrape("", 0, tmdbId, season, episode, models.MetadataProviderTmdb); err != nil {
+		return telegramRecordView(id, "❌ 重新刮削失败: "+html.EscapeString(err.Error()))
+	}
+	if oldStatus == models.ScrapeMediaStatusRenamed {
+		synccron.StartScrapeRollbackCron() // 触发一次
+		return telegramRecordView(id, "✅ 已将文件移动并重命名到源目录，下次扫描时会使用新的识别结果刮削")
+	}
+	return telegramRecordView(id, "✅ 已修正识别结果，下次扫描时会使用新的识别结果刮削")
+}
@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTgArgUint(t *testing.T) {
+	args := []string{"view", "12", "abc"}
+	if got := tgArgUint(args, 1); got != 12 {
+		t.Errorf("tgArgUint = %d", got)
+	}
+	if got := tgArgUint(args, 2); got != 0 {
+		t.Errorf("格式错误的参数应该返回0，实际 %d", got)
+	}
+	if got := tgArgUint(args, 5); got != 0 {
+		t.Errorf("不存在的参数应该返回0，实际 %d", got)
+	}
+}
+
+func TestTgPageButtons(t *testing.T) {
+	if row := tgPageButtons(tgPrefixSyncPath, 1, telegramPageSize); len(row) != 0 {
+		t.Errorf("只有一页时不应该有翻页按钮: %+v", row)
+	}
+	row := tgPageButtons(tgPrefixSyncPath, 2, telegramPageSize*3)
+	if len(row) != 2 || row[0].Data != "sp:list:1" || row[1].Data != "sp:list:3" {
+		t.Errorf("翻页按钮错误: %+v", row)
+	}
+}
+
+func TestTelegramCommandArgs(t *testing.T) {
+	// 参数错误时直接返回提示，不查询数据库
+	cases := []struct {
+		name  string
+		reply string
+	}{
+		{"search", TelegramSearch([]string{" "}).Text},
+		{"fix缺少参数", TelegramFix([]string{"#1"}).Text},
+		{"fix记录ID格式错误", TelegramFix([]string{"1", "550"}).Text},
+		{"fix TMDB ID格式错误", TelegramFix([]string{"#1", "abc"}).Text},
+		{"rec fix缺少参数", TelegramRecordCallback([]string{"fix", "1"}).Text},
+		{"rec fix TMDB ID格式错误", TelegramRecordCallback([]string{"fix", "1", "abc"}).Text},
+	}
+	for _, c := range cases {
+		if !strings.HasPrefix(c.reply, "❌") {
+			t.Errorf("%s 应该返回错误提示，实际: %s", c.name, c.reply)
+		}
+	}
+	// 没有参数的回调返回主菜单
+	for _, reply := range []string{TelegramSyncPathCallback(nil).Text, TelegramScrapePathCallback(nil).Text, TelegramRecordCallback(nil).Text} {
+		if reply != TelegramMenu(nil).Text {
+			t.Errorf("没有参数时应该返回主菜单，实际: %s", reply)
+		}
+	}
+}
@@ -16,8 +16,6 @@ type LoginRequest struct {
 	Password string `json:"password" form:"password"`
 }
 
-var LoginedUser *models.User = nil
-
 // LoginAction 用户登录
 // @Summary 用户登录
 // @Description 用户登录并返回JWT Token
@@ -63,13 +61,12 @@ func LoginAction(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "登录失败，请重试", Data: nil})
 		return
 	}
-	LoginedUser = user
 	res := make(map[string]interface{})
 	u := make(map[string]string)
 	u["id"] = fmt.Sprintf("%d", user.ID)
 	u["username"] = user.Username
 	u["email"] = ""
-	u["role"] = "admin"
+	u["role"] = string(user.GetRole())
 	res["user"] = u
 	res["token"] = tokenString
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "登录成功", Data: res})
@@ -101,13 +98,14 @@ func ChangePassword(c *gin.Context) {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "用户名不能为空", Data: nil})
 		return
 	}
+	loginUser := GetLoginUser(c)
 	isChange := false
 	isChange2 := false
 	var err error
-	if req.Username != LoginedUser.Username {
+	if req.Username != loginUser.Username {
 		isChange = true
 	}
-	isChange2, err = LoginedUser.ChangeUsernameAndPassword(req.Username, req.NewPassword)
+	isChange2, err = loginUser.ChangeUsernameAndPassword(req.Username, req.NewPassword)
 	if err != nil {
 		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "修改失败: " + err.Error(), Data: nil})
 		return
@@ -117,7 +115,7 @@ func ChangePassword(c *gin.Context) {
 
 // GetUserInfo 获取当前用户信息
 // @Summary 获取用户信息
-// @Description 获取当前登录用户的ID和用户名
+// @Description 获取当前登录用户的ID、用户名、角色和可操作的目录
 // @Tags 用户管理
 // @Accept json
 // @Produce json
@@ -127,9 +125,142 @@ func ChangePassword(c *gin.Context) {
 // @Security JwtAuth
 // @Security ApiKeyAuth
 func GetUserInfo(c *gin.Context) {
-	// 返回当前用户ID和用户名
-	respData := make(map[string]string)
-	respData["id"] = fmt.Sprintf("%d", LoginedUser.ID)
-	respData["username"] = LoginedUser.Username
+	// 返回当前用户ID、用户名和权限
+	loginUser := GetLoginUser(c)
+	respData := make(map[string]any)
+	respData["id"] = fmt.Sprintf("%d", loginUser.ID)
+	respData["username"] = loginUser.Username
+	respData["role"] = loginUser.GetRole()
+	respData["sync_path_ids"] = loginUser.SyncPathIdArray
+	respData["scrape_path_ids"] = loginUser.ScrapePathIdArray
 	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取用户信息成功", Data: respData})
 }
+
+// GetUserList 获取用户列表
+// @Summary 获取用户列表
+// @Description 获取所有用户及其角色和可操作的目录，仅管理员可用
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /user/list [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetUserList(c *gin.Context) {
+	users := models.GetUserList()
+	c.JSON(http.StatusOK, APIResponse[[]*models.User]{Code: Success, Message: "获取用户列表成功", Data: users})
+}
+
+type userRoleRequest struct {
+	Role          models.UserRole `json:"role" form:"role" binding:"required"`    // 角色：admin、operator、viewer
+	SyncPathIds   []uint          `json:"sync_path_ids" form:"sync_path_ids"`     // 操作员可操作的同步目录ID
+	ScrapePathIds []uint          `json:"scrape_path_ids" form:"scrape_path_ids"` // 操作员可操作的刮削目录ID
+}
+
+// CreateUser 创建用户
+// @Summary 创建用户
+// @Description 创建新用户并设置角色，操作员需要指定可操作的同步目录和刮削目录，仅管理员可用
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param username body string true "用户名"
+// @Param password body string true "密码"
+// @Param role body string true "角色：admin、operator、viewer"
+// @Param sync_path_ids body []integer false "操作员可操作的同步目录ID"
+// @Param scrape_path_ids body []integer false "操作员可操作的刮削目录ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /user/create [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func CreateUser(c *gin.Context) {
+	var req struct {
+		Username string `json:"username" form:"username" binding:"required"`
+		Password string `json:"password" form:"password" binding:"required"`
+		userRoleRequest
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("参数错误：%v", err), Data: nil})
+		return
+	}
+	user, err := models.CreateUser(req.Username, req.Password, req.Role, req.SyncPathIds, req.ScrapePathIds)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "创建用户失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[*models.User]{Code: Success, Message: "创建用户成功", Data: user})
+}
+
+// UpdateUser 修改用户角色和权限
+// @Summary 修改用户
+// @Description 修改用户的角色和可操作的目录，密码不为空时重置密码，仅管理员可用
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param id body integer true "用户ID"
+// @Param role body string true "角色：admin、operator、viewer"
+// @Param sync_path_ids body []integer false "操作员可操作的同步目录ID"
+// @Param scrape_path_ids body []integer false "操作员可操作的刮削目录ID"
+// @Param password body string false "新密码，为空则不修改"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /user/update [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateUser(c *gin.Context) {
+	var req struct {
+		ID       uint   `json:"id" form:"id" binding:"required"`
+		Password string `json:"password" form:"password"`
+		userRoleRequest
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: fmt.Sprintf("参数错误：%v", err), Data: nil})
+		return
+	}
+	user, err := models.GetUserById(req.ID)
+	if err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "用户不存在", Data: nil})
+		return
+	}
+	if err := user.UpdateRole(req.Role, req.SyncPathIds, req.ScrapePathIds); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "修改用户失败: " + err.Error(), Data: nil})
+		return
+	}
+	if req.Password != "" {
+		if err := user.ResetPassword(req.Password); err != nil {
+			c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "重置密码失败: " + err.Error(), Data: nil})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, APIResponse[*models.User]{Code: Success, Message: "修改用户成功", Data: user})
+}
+
+// DeleteUser 删除用户
+// @Summary 删除用户
+// @Description 删除指定用户及其API Key，不能删除自己和最后一个管理员，仅管理员可用
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param id path integer true "用户ID"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /user/{id} [delete]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func DeleteUser(c *gin.Context) {
+	id := uint(helpers.StringToInt(c.Param("id")))
+	if id == 0 {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "id 参数格式错误", Data: nil})
+		return
+	}
+	if id == GetLoginUser(c).ID {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "不能删除当前登录的用户", Data: nil})
+		return
+	}
+	if err := models.DeleteUser(id); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "删除用户失败: " + err.Error(), Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "删除用户成功", Data: nil})
+}
@@ -0,0 +1,42 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"crypto/hmac"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWebDavFileUrl 播放WebDAV文件
+// STRM中保存的是本接口地址，不包含账号密码，校验签名后跳转到带账号密码的WebDAV文件地址
+// 路径最后一部分是.扩展名格式，pickcode是文件的完整路径，sign用来防止篡改路径访问其他文件
+func GetWebDavFileUrl(c *gin.Context) {
+	type fileUrlReq struct {
+		UserId   string `json:"userid" form:"userid"`
+		PickCode string `json:"pickcode" form:"pickcode"`
+		Sign     string `json:"sign" form:"sign"`
+	}
+	var req fileUrlReq
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "参数错误", Data: nil})
+		return
+	}
+	if req.PickCode == "" || req.UserId == "" || req.Sign == "" {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "参数错误", Data: nil})
+		return
+	}
+	account, err := models.GetAccountByUserId(req.UserId)
+	if err != nil || account.SourceType != models.SourceTypeWebDav {
+		c.JSON(http.StatusBadRequest, APIResponse[any]{Code: BadRequest, Message: "用户ID不存在", Data: nil})
+		return
+	}
+	if !hmac.Equal([]byte(account.SignWebDavPath(req.PickCode)), []byte(req.Sign)) {
+		helpers.AppLogger.Warnf("WebDAV播放链接签名错误: %s", req.PickCode)
+		c.JSON(http.StatusForbidden, APIResponse[any]{Code: BadRequest, Message: "签名错误", Data: nil})
+		return
+	}
+	helpers.AppLogger.Infof("302重定向到WebDAV文件播放: %s", req.PickCode)
+	c.Redirect(http.StatusFound, account.GetWebDavClient().PlayUrl(req.PickCode))
+}
@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/webdavserver"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/webdav"
+)
+
+// 内置WebDAV服务挂载的路径前缀
+const webDavServerPrefix = "/dav"
+
+// WebDAV锁只在内存中保存，服务只读，锁只是为了兼容部分客户端
+var webDavServerLockSystem = webdav.NewMemLS()
+
+// WebDavServer 内置只读WebDAV服务，提供同步目录生成的STRM和元数据文件
+// 认证使用API Key，可以作为Basic认证的密码（用户名任意），也可以通过api_key参数传递
+func WebDavServer(c *gin.Context) {
+	if models.SettingsGlobal.WebDavServer != 1 {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	user := webDavServerAuth(c)
+	if user == nil {
+		c.Header("WWW-Authenticate", `Basic realm="QMediaSync"`)
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+	fs := webdavserver.NewFileSystem(webDavServerRoots(user), models.SettingsGlobal.WebDavServerMode, models.GetFileSizeByLocalFilePaths)
+	if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+		// 虚拟视频文件302到STRM中的直链地址，和播放STRM走同样的直链逻辑
+		if strmUrl, ok := fs.StrmUrl(c.Param("path")); ok {
+			c.Redirect(http.StatusFound, strmUrl)
+			return
+		}
+	}
+	handler := &webdav.Handler{
+		Prefix:     webDavServerPrefix,
+		FileSystem: fs,
+		LockSystem: webDavServerLockSystem,
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				helpers.AppLogger.Debugf("WebDAV服务请求 %s %s 失败: %v", r.Method, r.URL.Path, err)
+			}
+		},
+	}
+	handler.ServeHTTP(c.Writer, c.Request)
+}
+
+// WebDavServerReadOnly 写入类请求统一返回405
+func WebDavServerReadOnly(c *gin.Context) {
+	c.Header("Allow", "OPTIONS, GET, HEAD, PROPFIND")
+	c.Status(http.StatusMethodNotAllowed)
+}
+
+// 通过API Key认证，返回API Key所属的用户
+func webDavServerAuth(c *gin.Context) *models.User {
+	apiKey := c.Query("api_key")
+	if _, password, ok := c.Request.BasicAuth(); ok && password != "" {
+		apiKey = password
+	}
+	return apiKeyUser(apiKey)
+}
+
+// 用户有权限的同步目录，本地路径相同的只保留一个，目录名重复时加上同步目录ID
+func webDavServerRoots(user *models.User) []webdavserver.Root {
+	roots := make([]webdavserver.Root, 0)
+	names := make(map[string]bool)
+	paths := make(map[string]bool)
+	for _, sp := range models.GetAllSyncPaths() {
+		if sp.LocalPath == "" || !user.CanAccessSyncPath(sp.ID) {
+			continue
+		}
+		localPath := filepath.Clean(sp.LocalPath)
+		if paths[localPath] {
+			continue
+		}
+		paths[localPath] = true
+		name := filepath.Base(localPath)
+		if name == "" || name == "." || name == string(filepath.Separator) || names[name] {
+			name = fmt.Sprintf("%s (%d)", name, sp.ID)
+		}
+		names[name] = true
+		roots = append(roots, webdavserver.Root{Name: name, Path: localPath})
+	}
+	return roots
+}
+
+// GetWebDavServerConfig 获取内置WebDAV服务设置
+// @Summary 获取内置WebDAV服务设置
+// @Description 获取内置只读WebDAV服务的开关和视频呈现方式
+// @Tags 系统设置
+// @Accept json
+// @Produce json
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/webdav-server [get]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func GetWebDavServerConfig(c *gin.Context) {
+	models.LoadSettings() // 确保设置已加载
+	data := make(map[string]any)
+	data["webdav_server"] = models.SettingsGlobal.WebDavServer
+	data["webdav_server_mode"] = models.SettingsGlobal.WebDavServerMode
+	data["webdav_server_path"] = webDavServerPrefix
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "获取WebDAV服务设置成功", Data: data})
+}
+
+// UpdateWebDavServerConfig 更新内置WebDAV服务设置
+// @Summary 更新内置WebDAV服务设置
+// @Description 开启或关闭内置只读WebDAV服务，设置视频以.strm文件还是虚拟视频文件呈现
+// @Tags 系统设置
+// @Accept json
+// @Produce json
+// @Param webdav_server body integer true "是否启用，1启用 0禁用"
+// @Param webdav_server_mode body string false "视频呈现方式：strm-原样提供.strm文件，video-虚拟视频文件，播放时302到直链"
+// @Success 200 {object} object
+// @Failure 200 {object} object
+// @Router /setting/webdav-server [post]
+// @Security JwtAuth
+// @Security ApiKeyAuth
+func UpdateWebDavServerConfig(c *gin.Context) {
+	type updateWebDavServerRequest struct {
+		WebDavServer     int    `form:"webdav_server" json:"webdav_server"`
+		WebDavServerMode string `form:"webdav_server_mode" json:"webdav_server_mode"`
+	}
+	var req updateWebDavServerRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "请求参数错误: " + err.Error(), Data: nil})
+		return
+	}
+	if req.WebDavServer != 0 && req.WebDavServer != 1 {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "webdav_server只能是0或1", Data: nil})
+		return
+	}
+	if req.WebDavServerMode == "" {
+		req.WebDavServerMode = webdavserver.ModeStrm
+	}
+	if req.WebDavServerMode != webdavserver.ModeStrm && req.WebDavServerMode != webdavserver.ModeVideo {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "webdav_server_mode只能是strm或video", Data: nil})
+		return
+	}
+	if !models.SettingsGlobal.UpdateWebDavServer(req.WebDavServer, req.WebDavServerMode) {
+		c.JSON(http.StatusOK, APIResponse[any]{Code: BadRequest, Message: "更新WebDAV服务设置失败", Data: nil})
+		return
+	}
+	c.JSON(http.StatusOK, APIResponse[any]{Code: Success, Message: "更新WebDAV服务设置成功", Data: nil})
+}
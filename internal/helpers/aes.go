@@ -3,6 +3,7 @@ package helpers
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/pbkdf2"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
@@ -92,3 +93,147 @@ func Decrypt(encrypted string) (string, error) {
 
 	return string(ciphertext[:len(ciphertext)-paddingLen]), nil
 }
+
+// 使用密码加密的文件格式：
+// 魔数(8字节) + 盐(16字节) + 基础nonce(12字节)，之后是若干个分块，
+// 每个分块是4字节大端长度加上AES-256-GCM密文，nonce由基础nonce和分块序号异或得到，
+// 最后一个分块的附加数据为1，用来发现文件被截断
+const (
+	passphraseMagic      = "QMSENC01"
+	passphraseSaltSize   = 16
+	passphraseChunkSize  = 1024 * 1024
+	passphraseIterations = 100000
+)
+
+// IsPassphraseEncrypted 判断文件头是否是EncryptWithPassphrase生成的格式
+func IsPassphraseEncrypted(header []byte) bool {
+	return len(header) >= len(passphraseMagic) && string(header[:len(passphraseMagic)]) == passphraseMagic
+}
+
+// 从密码和盐派生AES-256-GCM
+func passphraseAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	if passphrase == "" {
+		return nil, errors.New("加密密码不能为空")
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, passphraseIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func chunkNonce(base []byte, index uint64) []byte {
+	nonce := make([]byte, len(base))
+	copy(nonce, base)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(index >> (8 * i))
+	}
+	return nonce
+}
+
+// EncryptWithPassphrase 使用密码分块加密，适合备份文件这种较大的文件
+func EncryptWithPassphrase(dst io.Writer, src io.Reader, passphrase string) error {
+	salt := make([]byte, passphraseSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return err
+	}
+	gcm, err := passphraseAEAD(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	baseNonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, baseNonce); err != nil {
+		return err
+	}
+	header := append(append([]byte(passphraseMagic), salt...), baseNonce...)
+	if _, err := dst.Write(header); err != nil {
+		return err
+	}
+	// 预读下一个分块，读不到数据时说明当前分块是最后一个
+	current := make([]byte, passphraseChunkSize)
+	next := make([]byte, passphraseChunkSize)
+	n, err := io.ReadFull(src, current)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	for index := uint64(0); ; index++ {
+		m := 0
+		if n == passphraseChunkSize {
+			m, err = io.ReadFull(src, next)
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return err
+			}
+		}
+		final := m == 0
+		aad := []byte{0}
+		if final {
+			aad[0] = 1
+		}
+		sealed := gcm.Seal(nil, chunkNonce(baseNonce, index), current[:n], aad)
+		length := []byte{byte(len(sealed) >> 24), byte(len(sealed) >> 16), byte(len(sealed) >> 8), byte(len(sealed))}
+		if _, err := dst.Write(length); err != nil {
+			return err
+		}
+		if _, err := dst.Write(sealed); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+		current, next = next, current
+		n = m
+	}
+}
+
+// DecryptWithPassphrase 解密EncryptWithPassphrase生成的内容，密码错误或者内容被修改时返回错误
+func DecryptWithPassphrase(dst io.Writer, src io.Reader, passphrase string) error {
+	header := make([]byte, len(passphraseMagic)+passphraseSaltSize)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return errors.New("不是加密的备份文件")
+	}
+	if !IsPassphraseEncrypted(header) {
+		return errors.New("不是加密的备份文件")
+	}
+	gcm, err := passphraseAEAD(passphrase, header[len(passphraseMagic):])
+	if err != nil {
+		return err
+	}
+	baseNonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(src, baseNonce); err != nil {
+		return errors.New("加密文件已损坏")
+	}
+	length := make([]byte, 4)
+	for index := uint64(0); ; index++ {
+		if _, err := io.ReadFull(src, length); err != nil {
+			return errors.New("加密文件不完整")
+		}
+		size := int(length[0])<<24 | int(length[1])<<16 | int(length[2])<<8 | int(length[3])
+		if size < gcm.Overhead() || size > passphraseChunkSize+gcm.Overhead() {
+			return errors.New("加密文件已损坏")
+		}
+		sealed := make([]byte, size)
+		if _, err := io.ReadFull(src, sealed); err != nil {
+			return errors.New("加密文件不完整")
+		}
+		nonce := chunkNonce(baseNonce, index)
+		final := true
+		plain, err := gcm.Open(nil, nonce, sealed, []byte{1})
+		if err != nil {
+			final = false
+			plain, err = gcm.Open(nil, nonce, sealed, []byte{0})
+			if err != nil {
+				return errors.New("解密失败，密码错误或者文件已损坏")
+			}
+		}
+		if _, err := dst.Write(plain); err != nil {
+			return err
+		}
+		if final {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package helpers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestEncryptWithPassphrase(t *testing.T) {
+	for _, size := range []int{0, 10, passphraseChunkSize, passphraseChunkSize*2 + 7} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+		var encrypted bytes.Buffer
+		if err := EncryptWithPassphrase(&encrypted, bytes.NewReader(plain), "secret"); err != nil {
+			t.Fatalf("加密失败: %v", err)
+		}
+		if !IsPassphraseEncrypted(encrypted.Bytes()) {
+			t.Fatalf("加密后的文件头不正确")
+		}
+		var decrypted bytes.Buffer
+		if err := DecryptWithPassphrase(&decrypted, bytes.NewReader(encrypted.Bytes()), "secret"); err != nil {
+			t.Fatalf("解密失败: %v", err)
+		}
+		if !bytes.Equal(decrypted.Bytes(), plain) {
+			t.Fatalf("解密后的内容不一致, 大小: %d", size)
+		}
+		if err := DecryptWithPassphrase(&bytes.Buffer{}, bytes.NewReader(encrypted.Bytes()), "wrong"); err == nil {
+			t.Fatalf("密码错误时应该解密失败")
+		}
+		// 去掉最后一个分块，模拟文件被截断
+		if size > passphraseChunkSize {
+			truncated := encrypted.Bytes()[:len(encrypted.Bytes())-(size-passphraseChunkSize*2)-4-16]
+			if err := DecryptWithPassphrase(&bytes.Buffer{}, bytes.NewReader(truncated), "secret"); err == nil {
+				t.Fatalf("文件被截断时应该解密失败")
+			}
+		}
+	}
+}
@@ -28,6 +28,8 @@ type FFprobeStream struct {
 	Channels           int64             `json:"channels"`
 	SampleRate         string            `json:"sample_rate"`
 	PixelFormat        string            `json:"pix_fmt"`
+	ColorTransfer      string            `json:"color_transfer"`
+	SideDataList       []map[string]any  `json:"side_data_list"`
 	DisplayAspectRatio string            `json:"display_aspect_ratio"`
 	BitRate            string            `json:"bit_rate"`
 	NB_Frames          string            `json:"nb_frames"`
@@ -98,6 +100,25 @@ func IsHDRFormat(pixelFormat string) bool {
 	return hdrFormats[pixelFormat]
 }
 
+// DetectHDRType 识别HDR类型：DV、HDR10、HLG，其他10bit以上的视频为HDR，SDR视频返回空
+func DetectHDRType(stream *FFprobeStream) string {
+	for _, sideData := range stream.SideDataList {
+		if sideDataType, ok := sideData["side_data_type"].(string); ok && strings.Contains(sideDataType, "DOVI") {
+			return "DV"
+		}
+	}
+	switch stream.ColorTransfer {
+	case "smpte2084":
+		return "HDR10"
+	case "arib-std-b67":
+		return "HLG"
+	}
+	if IsHDRFormat(stream.PixelFormat) {
+		return "HDR"
+	}
+	return ""
+}
+
 // 常见宽高比映射
 var commonAspectRatios = map[string][]string{
 	"16:9":  {"16:9", "1.78:1", "1.78"},
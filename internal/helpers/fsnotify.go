@@ -1,51 +1,111 @@
 package helpers
 
 import (
-	"log"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
 
+// 防抖后合并出的文件变化
+type FolderEvent struct {
+	Path    string // 发生变化的完整路径
+	Removed bool   // true-已删除或被移走，false-新建、修改或移入
+	IsDir   bool   // 是否是目录，Removed为true时无法判断，始终为false
+}
+
+// 文件变化回调，同一批次内同一路径只会出现一次
+type FolderEventHandler func(events []FolderEvent)
+
 type AdvancedFolderWatcher struct {
 	watcher   *fsnotify.Watcher
 	watchPath string
 	// 文件过滤
-	extensions []string // 监控的文件扩展名
+	extensions []string // 监控的文件扩展名，为空则监控所有文件
 	ignoreDirs []string // 忽略的目录
-	// 事件去重
-	eventCache map[string]time.Time
-	debounce   time.Duration
+	// 事件防抖：路径在debounce时间内没有新事件才会触发回调，持续有事件时最多等待maxWait
+	debounce time.Duration
+	maxWait  time.Duration
+	handler  FolderEventHandler
+
+	mu        sync.Mutex
+	pending   map[string]struct{} // 等待处理的路径
+	firstAt   time.Time           // 本批次第一个事件的时间
+	timer     *time.Timer
+	flushMu   sync.Mutex // 保证回调串行执行，上一批没处理完时下一批等待
+	done      chan struct{}
+	closeOnce sync.Once
 }
 
-func NewAdvancedFolderWatcher(path string) *AdvancedFolderWatcher {
+func NewAdvancedFolderWatcher(path string) (*AdvancedFolderWatcher, error) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	return &AdvancedFolderWatcher{
 		watcher:    watcher,
 		watchPath:  path,
-		extensions: []string{".txt", ".go", ".json", ".yaml", ".yml"},
-		ignoreDirs: []string{".git", ".vscode", "node_modules", "__pycache__"},
-		eventCache: make(map[string]time.Time),
-		debounce:   100 * time.Millisecond,
+		extensions: []string{},
+		ignoreDirs: []string{".git", ".vscode", "node_modules", "__pycache__", "@eaDir", "#recycle", "$RECYCLE.BIN"},
+		debounce:   2 * time.Second,
+		maxWait:    30 * time.Second,
+		pending:    make(map[string]struct{}),
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// 设置监控的文件扩展名，为空则监控所有文件
+func (afw *AdvancedFolderWatcher) SetExtensions(extensions []string) {
+	afw.extensions = make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		afw.extensions = append(afw.extensions, strings.ToLower(ext))
 	}
 }
 
-// shouldIgnore 检查是否应该忽略该路径
-func (afw *AdvancedFolderWatcher) shouldIgnore(path string) bool {
-	// 检查是否在忽略目录中
-	for _, ignoreDir := range afw.ignoreDirs {
-		if strings.Contains(path, ignoreDir) {
-			return true
+// 设置忽略的目录名
+func (afw *AdvancedFolderWatcher) SetIgnoreDirs(ignoreDirs []string) {
+	afw.ignoreDirs = ignoreDirs
+}
+
+// 设置防抖时间，maxWait为持续有事件时最长的等待时间
+func (afw *AdvancedFolderWatcher) SetDebounce(debounce, maxWait time.Duration) {
+	afw.debounce = debounce
+	afw.maxWait = maxWait
+}
+
+// 设置文件变化的回调
+func (afw *AdvancedFolderWatcher) SetHandler(handler FolderEventHandler) {
+	afw.handler = handler
+}
+
+// isIgnoredDir 检查路径中是否含有忽略的目录
+func (afw *AdvancedFolderWatcher) isIgnoredDir(path string) bool {
+	parts := strings.Split(filepath.ToSlash(path), "/")
+	for _, part := range parts {
+		for _, ignoreDir := range afw.ignoreDirs {
+			if part == ignoreDir {
+				return true
+			}
 		}
 	}
+	return false
+}
 
+// shouldIgnore 检查是否应该忽略该路径
+func (afw *AdvancedFolderWatcher) shouldIgnore(path string, isDir bool) bool {
+	// 检查是否在忽略目录中
+	if afw.isIgnoredDir(path) {
+		return true
+	}
+	// 目录不检查扩展名
+	if isDir {
+		return false
+	}
 	// 检查文件扩展名
 	if !afw.isWatchedExtension(path) {
 		return true
@@ -70,139 +130,174 @@ func (afw *AdvancedFolderWatcher) isWatchedExtension(path string) bool {
 }
 
 // addWatchRecursive 递归添加监控
-func (afw *AdvancedFolderWatcher) addWatchRecursive(path string) error {
+// emitFiles 为true时将目录下已有的文件作为新文件加入待处理列表（用于整个目录被移入的情况）
+func (afw *AdvancedFolderWatcher) addWatchRecursive(path string, emitFiles bool) error {
 	return filepath.Walk(path, func(walkPath string, info os.FileInfo, err error) error {
 		if err != nil {
-			return err
+			// 遍历过程中文件被删除等情况，跳过即可
+			if walkPath == path {
+				return err
+			}
+			return nil
 		}
 
 		if info.IsDir() {
 			// 跳过忽略的目录
-			if afw.shouldIgnore(walkPath) {
+			if afw.isIgnoredDir(walkPath) {
 				return filepath.SkipDir
 			}
 
 			err = afw.watcher.Add(walkPath)
 			if err != nil {
-				log.Printf("警告: 无法监控目录 %s: %v", walkPath, err)
+				AppLogger.Warnf("无法监控目录 %s: %v", walkPath, err)
 			}
+			return nil
+		}
+		if emitFiles && !afw.shouldIgnore(walkPath, false) {
+			afw.enqueue(walkPath)
 		}
 		return nil
 	})
 }
 
-// processEvent 处理事件（带去重）
-func (afw *AdvancedFolderWatcher) processEvent(event fsnotify.Event) {
-	// 检查是否应该忽略
-	if afw.shouldIgnore(event.Name) {
-		return
-	}
-
-	// 事件去重
+// enqueue 加入待处理列表，并重置防抖定时器
+func (afw *AdvancedFolderWatcher) enqueue(path string) {
+	afw.mu.Lock()
+	defer afw.mu.Unlock()
 	now := time.Now()
-	if lastTime, exists := afw.eventCache[event.Name]; exists {
-		if now.Sub(lastTime) < afw.debounce {
-			return // 忽略短时间内重复的事件
-		}
+	if len(afw.pending) == 0 {
+		afw.firstAt = now
 	}
-	afw.eventCache[event.Name] = now
-
-	// 清理过期的缓存
-	for path, lastTime := range afw.eventCache {
-		if now.Sub(lastTime) > time.Minute {
-			delete(afw.eventCache, path)
-		}
+	afw.pending[path] = struct{}{}
+	wait := afw.debounce
+	// 持续有事件时不能无限推迟
+	if deadline := afw.firstAt.Add(afw.maxWait); now.Add(wait).After(deadline) {
+		wait = max(deadline.Sub(now), 0)
+	}
+	if afw.timer == nil {
+		afw.timer = time.AfterFunc(wait, afw.flush)
+	} else {
+		afw.timer.Reset(wait)
 	}
+}
 
-	// 处理事件
-	afw.handleEvent(event)
+// RequeueAfter 暂时无法处理的事件延迟一段时间后重新加入待处理列表，是否被删除在处理时重新判断
+func (afw *AdvancedFolderWatcher) RequeueAfter(events []FolderEvent, delay time.Duration) {
+	time.AfterFunc(delay, func() {
+		select {
+		case <-afw.done:
+			return
+		default:
+		}
+		for _, event := range events {
+			afw.enqueue(event.Path)
+		}
+	})
 }
 
-// handleEvent 处理具体事件
-func (afw *AdvancedFolderWatcher) handleEvent(event fsnotify.Event) {
-	switch {
-	case event.Op&fsnotify.Create == fsnotify.Create:
-		afw.handleCreate(event)
-	case event.Op&fsnotify.Write == fsnotify.Write:
-		afw.handleWrite(event)
-	case event.Op&fsnotify.Remove == fsnotify.Remove:
-		afw.handleRemove(event)
-	case event.Op&fsnotify.Rename == fsnotify.Rename:
-		afw.handleRename(event)
+// flush 将待处理列表交给回调
+// 文件是否被删除以处理时的状态为准，这样重命名（旧路径Rename+新路径Create）和先删后建都能得到正确结果
+func (afw *AdvancedFolderWatcher) flush() {
+	afw.flushMu.Lock()
+	defer afw.flushMu.Unlock()
+	afw.mu.Lock()
+	pending := afw.pending
+	afw.pending = make(map[string]struct{})
+	afw.mu.Unlock()
+	if len(pending) == 0 || afw.handler == nil {
+		return
+	}
+	select {
+	case <-afw.done:
+		return
+	default:
 	}
+	events := make([]FolderEvent, 0, len(pending))
+	for path := range pending {
+		info, err := os.Stat(path)
+		if err != nil {
+			events = append(events, FolderEvent{Path: path, Removed: true})
+			continue
+		}
+		events = append(events, FolderEvent{Path: path, IsDir: info.IsDir()})
+	}
+	afw.handler(events)
 }
 
-func (afw *AdvancedFolderWatcher) handleCreate(event fsnotify.Event) {
-	info, err := os.Stat(event.Name)
+// processEvent 处理单个fsnotify事件
+func (afw *AdvancedFolderWatcher) processEvent(event fsnotify.Event) {
+	// Chmod事件不关心
+	if event.Op == fsnotify.Chmod {
+		return
+	}
+	path := filepath.ToSlash(event.Name)
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		// 已经不存在了，无法判断是否是目录，交给回调处理
+		if afw.isIgnoredDir(path) {
+			return
+		}
+		afw.enqueue(path)
+		return
+	}
+	info, err := os.Stat(path)
 	if err != nil {
 		return
 	}
-
 	if info.IsDir() {
-		log.Printf("📁 新目录创建: %s", event.Name)
-		afw.addWatchRecursive(event.Name)
-	} else {
-		log.Printf("📄 新文件创建: %s", event.Name)
+		if afw.isIgnoredDir(path) {
+			return
+		}
+		if event.Op&fsnotify.Create != 0 {
+			// 新目录需要加入监控，目录可能是整个移入的，里面已有的文件也要处理
+			if err := afw.addWatchRecursive(path, true); err != nil {
+				AppLogger.Warnf("监控新目录 %s 失败: %v", path, err)
+			}
+		}
+		return
 	}
-}
-
-func (afw *AdvancedFolderWatcher) handleWrite(event fsnotify.Event) {
-	info, err := os.Stat(event.Name)
-	if err != nil || info.IsDir() {
+	if afw.shouldIgnore(path, false) {
 		return
 	}
-	log.Printf("✏️  文件修改: %s (大小: %d bytes)", event.Name, info.Size())
+	afw.enqueue(path)
 }
 
-func (afw *AdvancedFolderWatcher) handleRemove(event fsnotify.Event) {
-	log.Printf("🗑️  文件/目录删除: %s", event.Name)
-}
-
-func (afw *AdvancedFolderWatcher) handleRename(event fsnotify.Event) {
-	log.Printf("📝 文件重命名: %s", event.Name)
-}
-
-// Start 开始监控
-func (afw *AdvancedFolderWatcher) Start() {
+// Start 开始监控，阻塞直到Close被调用
+func (afw *AdvancedFolderWatcher) Start() error {
 	// 初始添加监控
-	if err := afw.addWatchRecursive(afw.watchPath); err != nil {
-		log.Fatal(err)
+	if err := afw.addWatchRecursive(afw.watchPath, false); err != nil {
+		return fmt.Errorf("监控目录 %s 失败: %w", afw.watchPath, err)
 	}
 
-	log.Printf("🚀 开始高级监控目录: %s", afw.watchPath)
-	log.Printf("📊 监控文件类型: %v", afw.extensions)
-	log.Printf("🚫 忽略目录: %v", afw.ignoreDirs)
+	AppLogger.Infof("开始监控目录: %s，文件类型: %v，忽略目录: %v", afw.watchPath, afw.extensions, afw.ignoreDirs)
 
 	for {
 		select {
+		case <-afw.done:
+			return nil
 		case event, ok := <-afw.watcher.Events:
 			if !ok {
-				return
+				return nil
 			}
 			afw.processEvent(event)
 
 		case err, ok := <-afw.watcher.Errors:
 			if !ok {
-				return
+				return nil
 			}
-			log.Printf("❌ 监控错误: %v", err)
+			AppLogger.Errorf("监控目录 %s 出错: %v", afw.watchPath, err)
 		}
 	}
 }
 
-// Close 关闭
+// Close 关闭，未处理的事件会被丢弃
 func (afw *AdvancedFolderWatcher) Close() {
-	afw.watcher.Close()
+	afw.closeOnce.Do(func() {
+		close(afw.done)
+		afw.mu.Lock()
+		if afw.timer != nil {
+			afw.timer.Stop()
+		}
+		afw.mu.Unlock()
+		afw.watcher.Close()
+	})
 }
-
-// func main() {
-//     watchPath := "."
-//     if len(os.Args) > 1 {
-//         watchPath = os.Args[1]
-//     }
-
-//     watcher := NewAdvancedFolderWatcher(watchPath)
-//     defer watcher.Close()
-
-//     watcher.Start()
-// }
@@ -0,0 +1,30 @@
+package helpers
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRequeueAfter(t *testing.T) {
+	afw, err := NewAdvancedFolderWatcher(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer afw.Close()
+	afw.SetDebounce(10*time.Millisecond, 100*time.Millisecond)
+	received := make(chan []FolderEvent, 1)
+	afw.SetHandler(func(events []FolderEvent) {
+		received <- events
+	})
+	path := filepath.ToSlash(filepath.Join(t.TempDir(), "removed.mkv"))
+	afw.RequeueAfter([]FolderEvent{{Path: path, Removed: true}}, 20*time.Millisecond)
+	select {
+	case events := <-received:
+		if len(events) != 1 || events[0].Path != path || !events[0].Removed {
+			t.Errorf("重新处理的事件错误: %+v", events)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("延迟后应该重新处理事件")
+	}
+}
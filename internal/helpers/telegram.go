@@ -12,9 +12,105 @@ import (
 
 // TelegramBot 结构体用于处理Telegram机器人操作
 type TelegramBot struct {
-	Token  string
-	ChatID string
-	Client *tgbotapi.BotAPI
+	Token          string
+	ChatID         string
+	AllowedChatIDs []string // 除ChatID外允许控制机器人的会话ID
+	Client         *tgbotapi.BotAPI
+}
+
+// TelegramButton 内联键盘按钮，Data是按钮回调数据，Telegram限制最长64字节
+type TelegramButton struct {
+	Text string
+	Data string
+}
+
+// TelegramReply 带内联键盘的回复
+type TelegramReply struct {
+	Text     string
+	Keyboard [][]TelegramButton
+}
+
+// TelegramInteractive 交互式命令和按钮回调
+// 回调数据格式为 前缀:参数1:参数2，按前缀分发到Callbacks，不含冒号的回调数据仍按文字命令处理
+type TelegramInteractive struct {
+	Commands  map[string]func(args []string) *TelegramReply
+	Callbacks map[string]func(args []string) *TelegramReply
+}
+
+// TelegramCallbackData 生成按钮回调数据
+func TelegramCallbackData(prefix string, args ...string) string {
+	return strings.Join(append([]string{prefix}, args...), ":")
+}
+
+// ParseTelegramCallbackData 解析按钮回调数据，返回前缀和参数
+func ParseTelegramCallbackData(data string) (string, []string, bool) {
+	if !strings.Contains(data, ":") {
+		return "", nil, false
+	}
+	parts := strings.Split(data, ":")
+	return parts[0], parts[1:], true
+}
+
+// SplitTelegramChatIDs 解析逗号、空格或换行分隔的会话ID列表
+func SplitTelegramChatIDs(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == '，' || r == ' ' || r == '\n' || r == '\r'
+	})
+}
+
+// IsAllowedChat 是否允许该会话控制机器人，没有配置ChatID和允许的会话时拒绝所有会话
+func (bot *TelegramBot) IsAllowedChat(chatID int64) bool {
+	id := fmt.Sprintf("%d", chatID)
+	if bot.ChatID != "" && id == bot.ChatID {
+		return true
+	}
+	for _, allowed := range bot.AllowedChatIDs {
+		if id == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// 转换为Telegram内联键盘
+func (r *TelegramReply) markup() *tgbotapi.InlineKeyboardMarkup {
+	if len(r.Keyboard) == 0 {
+		return nil
+	}
+	rows := make([][]tgbotapi.InlineKeyboardButton, 0, len(r.Keyboard))
+	for _, row := range r.Keyboard {
+		buttons := make([]tgbotapi.InlineKeyboardButton, 0, len(row))
+		for _, button := range row {
+			buttons = append(buttons, tgbotapi.NewInlineKeyboardButtonData(button.Text, button.Data))
+		}
+		rows = append(rows, buttons)
+	}
+	markup := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	return &markup
+}
+
+// 发送交互式回复，按钮回调时编辑原消息，避免刷屏
+func (bot *TelegramBot) sendReply(chatID int64, messageID int, reply *TelegramReply) {
+	if reply == nil || reply.Text == "" {
+		return
+	}
+	markup := reply.markup()
+	if messageID > 0 {
+		edit := tgbotapi.NewEditMessageText(chatID, messageID, reply.Text)
+		edit.ParseMode = "HTML"
+		edit.ReplyMarkup = markup
+		if _, err := bot.Client.Send(edit); err == nil {
+			return
+		}
+	}
+	msg := tgbotapi.NewMessage(chatID, reply.Text)
+	msg.ParseMode = "HTML"
+	if markup != nil {
+		msg.ReplyMarkup = *markup
+	}
+	if _, err := bot.Client.Send(msg); err != nil {
+		AppLogger.Warnf("发送Telegram交互消息失败: %v", err)
+	}
 }
 
 // TelegramResponse Telegram API响应结构
@@ -286,7 +382,10 @@ func TestTelegramBot(token, chatID, httpProxy string) error {
 		return bot.TestConnection()
 	}
 }
-func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[string]func([]string) string) {
+func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[string]func([]string) string, interactive *TelegramInteractive) {
+	if interactive == nil {
+		interactive = &TelegramInteractive{}
+	}
 	if bot.Client == nil {
 		AppLogger.Errorf("Bot Client 未初始化")
 		return
@@ -311,6 +410,8 @@ func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[st
 		var cmd string
 		var args []string
 		var chatID int64
+		var callbackData string
+		var messageID int
 
 		if update.Message != nil && update.Message.IsCommand() {
 			// 处理文字命令 /xxxx
@@ -323,13 +424,32 @@ func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[st
 			cmd = update.CallbackQuery.Data
 			args = []string{}
 			chatID = update.CallbackQuery.Message.Chat.ID
+			callbackData = update.CallbackQuery.Data
+			messageID = update.CallbackQuery.Message.MessageID
 		} else {
 			continue
 		}
 
 		// --- 权限检查 ---
-		// 重点：只响应你在配置中指定的 ChatID，防止其他人控制你的程序
-		if bot.ChatID != "" && fmt.Sprintf("%d", chatID) != bot.ChatID {
+		// 重点：只响应配置中指定的 ChatID 和允许的会话，防止其他人控制你的程序
+		if !bot.IsAllowedChat(chatID) {
+			if bot.ChatID == "" && len(bot.AllowedChatIDs) == 0 {
+				AppLogger.Warnf("没有配置Telegram ChatID和允许的会话，忽略会话 %d 的命令: %s", chatID, cmd)
+				continue
+			}
+			AppLogger.Warnf("忽略未授权会话的Telegram命令: %d %s", chatID, cmd)
+			continue
+		}
+
+		// --- 交互式按钮和命令 ---
+		if prefix, callbackArgs, ok := ParseTelegramCallbackData(callbackData); ok {
+			if logic, ok := interactive.Callbacks[prefix]; ok {
+				bot.sendReply(chatID, messageID, logic(callbackArgs))
+			}
+			continue
+		}
+		if logic, ok := interactive.Commands[cmd]; ok {
+			bot.sendReply(chatID, 0, logic(args))
 			continue
 		}
 
@@ -344,6 +464,9 @@ func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[st
 
 					📋 <b>命令列表：</b>  
 					📊/status - <b>查看系统运行状态</b>  
+					🧭/menu - <b>打开管理菜单（同步/刮削目录、队列、失败上传）</b>  
+					🔍/search - <b>搜索刮削记录，格式: /search 关键词</b>  
+					🛠/fix - <b>修正识别结果，格式: /fix #记录ID TMDB_ID [季 集]</b>  
 					🚀/strm_sync - <b>执行全量 STRM 同步</b>  
 					🔄/strm_inc - <b>执行增量 STRM 同步</b>  
 					🎬/scrape - <b>执行刮削任务</b>  
@@ -382,6 +505,9 @@ func (bot *TelegramBot) StartListening(ctx context.Context, handleCommand map[st
 						tgbotapi.NewInlineKeyboardButtonData("🚀 全量同步", "strm_sync"),
 						tgbotapi.NewInlineKeyboardButtonData("🔄 增量同步", "strm_inc"),
 					),
+					tgbotapi.NewInlineKeyboardRow(
+						tgbotapi.NewInlineKeyboardButtonData("🧭 管理菜单", "menu:main"),
+					),
 					tgbotapi.NewInlineKeyboardRow(
 						tgbotapi.NewInlineKeyboardButtonData("🎬 刮削任务", "scrape"),
 						tgbotapi.NewInlineKeyboardButtonData("🎬🔄 刮削后同步", "scrape_strm"),
@@ -408,6 +534,8 @@ func (bot *TelegramBot) SetMenuContent() {
 		{"strm_inc", "🔄 执行 STRM 增量同步"},
 		{"scrape", "🎬 执行刮削任务"},
 		{"strm_scrape", "🔄🎬 先同步后刮削"},
+		{"menu", "🧭 打开管理菜单"},
+		{"search", "🔍 搜索刮削记录"},
 		{"help", "📋 显示功能操作指南"},
 		{"status", "📊 查看系统运行状态"},
 	}
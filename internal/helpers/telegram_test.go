@@ -0,0 +1,37 @@
+package helpers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTelegramCallbackData(t *testing.T) {
+	data := TelegramCallbackData("rec", "fix", "12", "550")
+	if data != "rec:fix:12:550" {
+		t.Fatalf("回调数据错误: %s", data)
+	}
+	prefix, args, ok := ParseTelegramCallbackData(data)
+	if !ok || prefix != "rec" || !reflect.DeepEqual(args, []string{"fix", "12", "550"}) {
+		t.Errorf("解析回调数据错误: %s %v %v", prefix, args, ok)
+	}
+	if _, _, ok := ParseTelegramCallbackData("strm_sync"); ok {
+		t.Errorf("不含冒号的回调数据应按文字命令处理")
+	}
+}
+
+func TestTelegramIsAllowedChat(t *testing.T) {
+	bot := &TelegramBot{ChatID: "100", AllowedChatIDs: SplitTelegramChatIDs("200, -300\n400")}
+	for _, id := range []int64{100, 200, -300, 400} {
+		if !bot.IsAllowedChat(id) {
+			t.Errorf("会话 %d 应被允许", id)
+		}
+	}
+	if bot.IsAllowedChat(500) {
+		t.Errorf("会话 500 不应被允许")
+	}
+	// 没有配置允许的会话时拒绝所有会话
+	empty := &TelegramBot{}
+	if empty.IsAllowedChat(100) {
+		t.Errorf("没有配置允许的会话时不应该允许任何会话")
+	}
+}
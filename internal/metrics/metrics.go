@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType Prometheus文本格式的Content-Type
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// 指标类型
+const (
+	TypeCounter = "counter"
+	TypeGauge   = "gauge"
+)
+
+// Label 指标标签
+type Label struct {
+	Name  string
+	Value string
+}
+
+// L 创建标签
+func L(name, value string) Label {
+	return Label{Name: name, Value: value}
+}
+
+// Writer 按Prometheus文本格式输出指标
+// 同名指标的样本需要连续写入，HELP和TYPE只在第一次写入时输出
+type Writer struct {
+	buf       bytes.Buffer
+	described map[string]bool
+}
+
+func NewWriter() *Writer {
+	return &Writer{described: make(map[string]bool)}
+}
+
+// Counter 写入只增不减的计数器
+func (w *Writer) Counter(name, help string, value float64, labels ...Label) {
+	w.write(name, help, TypeCounter, value, labels)
+}
+
+// Gauge 写入可增可减的指标
+func (w *Writer) Gauge(name, help string, value float64, labels ...Label) {
+	w.write(name, help, TypeGauge, value, labels)
+}
+
+// Describe 只输出HELP和TYPE，用于没有样本的指标
+func (w *Writer) Describe(name, help, typ string) {
+	if w.described[name] {
+		return
+	}
+	w.described[name] = true
+	w.buf.WriteString("# HELP " + name + " " + escapeHelp(help) + "\n")
+	w.buf.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// Bytes 返回已写入的内容
+func (w *Writer) Bytes() []byte {
+	return w.buf.Bytes()
+}
+
+func (w *Writer) write(name, help, typ string, value float64, labels []Label) {
+	w.Describe(name, help, typ)
+	w.buf.WriteString(name)
+	if len(labels) > 0 {
+		w.buf.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.buf.WriteByte(',')
+			}
+			w.buf.WriteString(label.Name + "=\"" + escapeLabelValue(label.Value) + "\"")
+		}
+		w.buf.WriteByte('}')
+	}
+	w.buf.WriteByte(' ')
+	w.buf.WriteString(formatValue(value))
+	w.buf.WriteByte('\n')
+}
+
+// Bool 布尔值转换为0和1
+func Bool(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// SortedKeys 返回排好序的map键，保证每次输出的样本顺序一致
+func SortedKeys[K ~string | ~int | ~uint, V any](m map[K]V) []K {
+	keys := make([]K, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsNaN(value):
+		return "NaN"
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeHelp(s string) string {
+	return helpReplacer.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	w := NewWriter()
+	w.Counter("qms_requests_total", "请求总数", 3, L("account_id", "1"))
+	w.Counter("qms_requests_total", "请求总数", 5, L("account_id", "2"))
+	w.Gauge("qms_ratio", "命中率", 0.25)
+	w.Gauge("qms_path", "路径", 1, L("path", "C:\\a\"b\nc"), L("type", "115"))
+	w.Gauge("qms_nan", "无数据", math.NaN())
+	w.Describe("qms_empty", "没有样本", TypeGauge)
+	want := `# HELP qms_requests_total 请求总数
+# TYPE qms_requests_total counter
+qms_requests_total{account_id="1"} 3
+qms_requests_total{account_id="2"} 5
+# HELP qms_ratio 命中率
+# TYPE qms_ratio gauge
+qms_ratio 0.25
+# HELP qms_path 路径
+# TYPE qms_path gauge
+qms_path{path="C:\\a\"b\nc",type="115"} 1
+# HELP qms_nan 无数据
+# TYPE qms_nan gauge
+qms_nan NaN
+# HELP qms_empty 没有样本
+# TYPE qms_empty gauge
+`
+	if got := string(w.Bytes()); got != want {
+		t.Errorf("输出不一致:\n%s\n期望:\n%s", got, want)
+	}
+}
+
+func TestSortedKeys(t *testing.T) {
+	keys := SortedKeys(map[string]int{"b": 1, "a": 2, "c": 3})
+	if len(keys) != 3 || keys[0] != "a" || keys[1] != "b" || keys[2] != "c" {
+		t.Errorf("排序错误: %v", keys)
+	}
+}
@@ -5,9 +5,16 @@ import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/notificationmanager"
+	"Q115-STRM/internal/open123"
 	"Q115-STRM/internal/openlist"
+	"Q115-STRM/internal/s3"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"time"
 )
@@ -17,14 +24,20 @@ type Account struct {
 	Name              string     `json:"name"` // 账号备注，仅供用户自己识别账号使用，唯一
 	SourceType        SourceType `json:"source_type"`
 	AppId             string     `json:"app_id"`
+	AppSecret         string     `json:"-" gorm:"type:string;size:256"` // 123云盘开放平台的clientSecret，不返回给前端
 	Token             string     `json:"token" gorm:"type:string;size:512"`
 	RefreshToken      string     `json:"refresh_token" gorm:"type:string;size:512"`
 	TokenExpiriesTime int64      `json:"token_expiries_time"`
 	UserId            string     `json:"user_id"`                                         // 账号对应的用户id，唯一
-	Username          string     `json:"username" gorm:"type:string;size:32"`             // 网盘对应的用户名或者openlist的登录用户名
-	Password          string     `json:"password" gorm:"type:string;size:256"`            // openlist的用户密码
-	BaseUrl           string     `json:"base_url" gorm:"type:string;size:1024"`           // openlist的访问地址http[s]://ip:port
+	Username          string     `json:"username" gorm:"type:string;size:256"`            // 网盘对应的用户名、openlist的登录用户名或者S3的AccessKey
+	Password          string     `json:"password" gorm:"type:string;size:256"`            // openlist或WebDAV的用户密码，S3的SecretKey
+	BaseUrl           string     `json:"base_url" gorm:"type:string;size:1024"`           // openlist、WebDAV或S3的访问地址http[s]://ip:port
+	Bucket            string     `json:"bucket" gorm:"type:string;size:256"`              // S3的bucket
+	Region            string     `json:"region" gorm:"type:string;size:64"`               // S3的区域，为空时使用us-east-1
 	TokenFailedReason string     `json:"token_failed_reason" gorm:"type:string;size:256"` // 刷新token失败的原因
+	QueueQps          int        `json:"queue_qps"`                                       // 115请求队列单独设置的每秒请求数，0表示使用默认设置
+	QueueQpm          int        `json:"queue_qpm"`                                       // 115请求队列单独设置的每分钟请求数
+	QueueQph          int        `json:"queue_qph"`                                       // 115请求队列单独设置的每小时请求数
 }
 
 func (account *Account) TableName() string {
@@ -52,6 +65,30 @@ func (account *Account) UpdateToken(token string, refreshToken string, expiresTi
 	return true
 }
 
+// 保存115请求队列单独设置的速率限制，重启后由LoadQueueRateLimits恢复
+func (account *Account) UpdateQueueRateLimit(qps, qpm, qph int) error {
+	updateData := map[string]any{"queue_qps": qps, "queue_qpm": qpm, "queue_qph": qph}
+	if err := db.Db.Model(account).Where("id = ?", account.ID).Updates(updateData).Error; err != nil {
+		helpers.AppLogger.Errorf("保存115账号 %d 的请求队列速率限制失败: %v", account.ID, err)
+		return err
+	}
+	account.QueueQps, account.QueueQpm, account.QueueQph = qps, qpm, qph
+	v115open.SetExecutorConfig(account.ID, qps, qpm, qph)
+	return nil
+}
+
+// LoadQueueRateLimits 启动时恢复115账号单独设置的请求队列速率限制
+func LoadQueueRateLimits() {
+	var accounts []*Account
+	if err := db.Db.Where("source_type = ? AND queue_qps > 0", SourceType115).Find(&accounts).Error; err != nil {
+		helpers.AppLogger.Errorf("查询115账号的请求队列速率限制失败: %v", err)
+		return
+	}
+	for _, account := range accounts {
+		v115open.SetExecutorConfig(account.ID, account.QueueQps, account.QueueQpm, account.QueueQph)
+	}
+}
+
 // 更新开放平台账号对应的用户信息
 func (account *Account) UpdateUser(userId string, username string) bool {
 	account.UserId = userId
@@ -77,10 +114,35 @@ func (account *Account) GetOpenListClient() *openlist.Client {
 	return openlist.NewClient(account.ID, account.BaseUrl, account.Username, account.Password, account.Token)
 }
 
+func (account *Account) GetWebDavClient() *webdav.Client {
+	return webdav.NewClient(account.ID, account.BaseUrl, account.Username, account.Password)
+}
+
+// WebDAV文件路径的签名，STRM链接不包含账号密码，播放时用签名校验路径没有被篡改
+func (account *Account) SignWebDavPath(path string) string {
+	return account.signPath(path)
+}
+
+// S3对象路径的签名，防止通过跳转接口给任意对象生成预签名地址
+func (account *Account) SignS3Path(path string) string {
+	return account.signPath(path)
+}
+
+// 签名的密钥是每次安装随机生成的密钥加上账号密码（S3是SecretKey），修改密码后旧的STRM链接失效
+func (account *Account) signPath(path string) string {
+	mac := hmac.New(sha256.New, []byte(SettingsGlobal.SignSecret+account.Password))
+	mac.Write([]byte(path))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
 func (account *Account) GetBaiDuPanClient() *baidupan.Client {
 	return baidupan.NewBaiDuPanClient(account.ID, account.Token)
 }
 
+func (account *Account) GetOpen123Client() *open123.Client {
+	return open123.GetClient(account.ID, account.AppId, account.AppSecret, account.Token, account.RefreshToken, account.TokenExpiriesTime)
+}
+
 func (account *Account) Delete() error {
 	// 检查是否有关联的同步目录没有删除
 	syncPaths := GetAllSyncPathByAccountId(account.ID)
@@ -94,6 +156,18 @@ func (account *Account) Delete() error {
 		helpers.AppLogger.Errorf("删除开放平台账号失败: %v", err)
 		return err
 	}
+	if account.SourceType == SourceType115 {
+		v115open.RemoveExecutor(account.ID)
+	}
+	if account.SourceType == SourceType123 {
+		open123.RemoveClient(account.ID)
+	}
+	if account.SourceType == SourceTypeWebDav {
+		webdav.RemoveClient(account.ID)
+	}
+	if account.SourceType == SourceTypeS3 {
+		s3.RemoveClient(account.ID)
+	}
 	return nil
 }
 
@@ -159,6 +233,221 @@ func (account *Account) UpdateOpenList(baseUrl string, username string, password
 	return nil
 }
 
+func (account *Account) GetS3Client() *s3.Client {
+	return s3.NewClient(account.ID, account.BaseUrl, account.Region, account.Bucket, account.Username, account.Password)
+}
+
+// 校验S3账号能否列举bucket，UserId由地址、bucket和AccessKey生成
+func (account *Account) verifyS3() error {
+	client := s3.NewClient(0, account.BaseUrl, account.Region, account.Bucket, account.Username, account.Password)
+	if _, err := client.Stat(context.Background(), "/"); err != nil {
+		helpers.AppLogger.Errorf("验证S3账号失败: %s/%s %v", account.BaseUrl, account.Bucket, err)
+		return err
+	}
+	hash := sha1.Sum([]byte(account.BaseUrl + "|" + account.Bucket + "|" + account.Username))
+	account.UserId = "s3-" + hex.EncodeToString(hash[:])[:16]
+	return nil
+}
+
+// 创建S3兼容对象存储账号（MinIO、Cloudflare R2、Backblaze B2等）
+// endpoint: 服务地址，例如http://127.0.0.1:9000，统一使用path-style访问
+func CreateS3Account(name, endpoint, region, bucket, accessKey, secretKey string) (*Account, error) {
+	account := &Account{}
+	account.SourceType = SourceTypeS3
+	account.BaseUrl = endpoint
+	account.Region = region
+	account.Bucket = bucket
+	account.Username = accessKey
+	account.Password = secretKey
+	if err := account.verifyS3(); err != nil {
+		return nil, err
+	}
+	if _, err := GetAccountByUserId(account.UserId); err == nil {
+		return nil, fmt.Errorf("S3账号 %s/%s 已经添加过了", endpoint, bucket)
+	}
+	account.Name = name
+	if account.Name == "" {
+		account.Name = bucket
+	}
+	err := db.Db.Create(account).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("创建S3账号失败: %v", err)
+		return nil, err
+	}
+	helpers.AppLogger.Infof("创建S3账号成功，地址：%s，bucket：%s", account.BaseUrl, account.Bucket)
+	return account, nil
+}
+
+// 更新S3账号，校验失败时保持原配置
+func (account *Account) UpdateS3(name, endpoint, region, bucket, accessKey, secretKey string) error {
+	old := *account
+	account.BaseUrl = endpoint
+	account.Region = region
+	account.Bucket = bucket
+	account.Username = accessKey
+	account.Password = secretKey
+	if err := account.verifyS3(); err != nil {
+		*account = old
+		return err
+	}
+	if name != "" {
+		account.Name = name
+	}
+	err := db.Db.Save(account).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("更新S3账号失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// 校验WebDAV账号能否访问根目录，UserId由地址和用户名生成，用来区分不同的WebDAV账号
+func (account *Account) verifyWebDav() error {
+	client := webdav.NewClient(0, account.BaseUrl, account.Username, account.Password)
+	if _, err := client.Stat(context.Background(), "/"); err != nil {
+		helpers.AppLogger.Errorf("验证WebDAV账号失败: %s %v", account.BaseUrl, err)
+		return err
+	}
+	hash := sha1.Sum([]byte(account.BaseUrl + "|" + account.Username))
+	account.UserId = "webdav-" + hex.EncodeToString(hash[:])[:16]
+	return nil
+}
+
+// 创建WebDAV账号
+// name: 账号备注，为空则使用用户名
+// baseUrl: WebDAV的访问地址，可以包含路径，例如http://nas:5005/dav
+func CreateWebDavAccount(name string, baseUrl string, username string, password string) (*Account, error) {
+	account := &Account{}
+	account.SourceType = SourceTypeWebDav
+	account.BaseUrl = baseUrl
+	account.Username = username
+	account.Password = password
+	if err := account.verifyWebDav(); err != nil {
+		return nil, err
+	}
+	if _, err := GetAccountByUserId(account.UserId); err == nil {
+		return nil, fmt.Errorf("WebDAV账号 %s@%s 已经添加过了", username, baseUrl)
+	}
+	account.Name = name
+	if account.Name == "" {
+		account.Name = username
+	}
+	if account.Name == "" {
+		account.Name = baseUrl
+	}
+	err := db.Db.Create(account).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("创建WebDAV账号失败: %v", err)
+		return nil, err
+	}
+	helpers.AppLogger.Infof("创建WebDAV账号成功，地址：%s，用户名：%s", account.BaseUrl, account.Username)
+	return account, nil
+}
+
+// 更新WebDAV账号，地址和用户名变化时UserId也会变化，已生成的STRM会在下次同步时更新
+func (account *Account) UpdateWebDav(name string, baseUrl string, username string, password string) error {
+	oldBaseUrl := account.BaseUrl
+	oldUsername := account.Username
+	oldPassword := account.Password
+	oldUserId := account.UserId
+	account.BaseUrl = baseUrl
+	account.Username = username
+	account.Password = password
+	if err := account.verifyWebDav(); err != nil {
+		account.BaseUrl = oldBaseUrl
+		account.Username = oldUsername
+		account.Password = oldPassword
+		account.UserId = oldUserId
+		return err
+	}
+	if name != "" {
+		account.Name = name
+	}
+	err := db.Db.Save(account).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("更新WebDAV账号失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// 使用123云盘开放平台凭据获取token并校验，成功后写入用户信息
+func (account *Account) verifyOpen123(client *open123.Client) error {
+	if err := client.RefreshToken(); err != nil {
+		helpers.AppLogger.Errorf("获取123云盘访问凭证失败: %v", err)
+		return err
+	}
+	account.Token = client.GetAccessToken()
+	account.RefreshToken = client.GetRefreshToken()
+	account.TokenExpiriesTime = client.GetExpiredAt().Unix()
+	account.TokenFailedReason = ""
+	userInfo, err := client.GetUserInfo(context.Background())
+	if err != nil {
+		helpers.AppLogger.Errorf("获取123云盘用户信息失败: %v", err)
+		return err
+	}
+	account.UserId = fmt.Sprintf("%d", userInfo.UID)
+	account.Username = userInfo.Nickname
+	return nil
+}
+
+// 更新123云盘账号的clientID和clientSecret，凭据变化时重新校验
+func (account *Account) UpdateOpen123(clientId string, clientSecret string) error {
+	if clientId == account.AppId && clientSecret == account.AppSecret && account.Token != "" {
+		return nil
+	}
+	oldAppId := account.AppId
+	oldAppSecret := account.AppSecret
+	open123.RemoveClient(account.ID)
+	account.AppId = clientId
+	account.AppSecret = clientSecret
+	account.Token = ""
+	account.RefreshToken = ""
+	account.TokenExpiriesTime = 0
+	if err := account.verifyOpen123(account.GetOpen123Client()); err != nil {
+		open123.RemoveClient(account.ID)
+		account.AppId = oldAppId
+		account.AppSecret = oldAppSecret
+		return err
+	}
+	err := db.Db.Save(account).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("更新123云盘账号失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// 创建123云盘账号
+// name: 账号备注，为空则使用123云盘昵称
+// clientId: 123云盘开放平台的clientID
+// clientSecret: 123云盘开放平台的clientSecret
+func CreateOpen123Account(name string, clientId string, clientSecret string) (*Account, error) {
+	account := &Account{}
+	account.SourceType = SourceType123
+	account.AppId = clientId
+	account.AppSecret = clientSecret
+	// 校验阶段账号还没有ID，使用不缓存的临时客户端，也不会触发保存token事件
+	if err := account.verifyOpen123(open123.NewClient(clientId, clientSecret)); err != nil {
+		return nil, err
+	}
+	if _, err := GetAccountByUserId(account.UserId); err == nil {
+		return nil, fmt.Errorf("123云盘用户 %s 已经添加过了", account.Username)
+	}
+	account.Name = name
+	if account.Name == "" {
+		account.Name = account.Username
+	}
+	helpers.AppLogger.Infof("创建123云盘账号成功，用户ID：%s，用户名：%s", account.UserId, account.Username)
+
+	err := db.Db.Create(account).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("创建123云盘账号失败: %v", err)
+		return nil, err
+	}
+	return account, nil
+}
+
 // 使用name创建一个临时账号，用户后续授权绑定
 // name: 账号备注
 func CreateAccountByName(name string, srouceType SourceType, appId string) (*Account, error) {
@@ -405,3 +694,34 @@ func HandleOpenListTokenSaveSync(event helpers.Event) helpers.EventResult {
 		}
 	}
 }
+
+// 处理123云盘访问凭证保存事件（同步版本）
+func HandleOpen123TokenSaveSync(event helpers.Event) helpers.EventResult {
+	eventData := event.Data.(map[string]any)
+	account, err := GetAccountById(eventData["account_id"].(uint))
+	if err != nil {
+		helpers.AppLogger.Errorf("查询123云盘账号失败: %v", err)
+		return helpers.EventResult{
+			Success: false,
+			Error:   err,
+			Data:    nil,
+		}
+	}
+	// UpdateToken接收的是有效时长，这里换算一下
+	expiresTime := eventData["expired_at"].(int64) - time.Now().Unix()
+	suc := account.UpdateToken(eventData["token"].(string), eventData["refresh_token"].(string), expiresTime)
+	if !suc {
+		helpers.AppLogger.Warn("123云盘访问凭证保存失败")
+		return helpers.EventResult{
+			Success: false,
+			Error:   fmt.Errorf("123云盘访问凭证保存失败"),
+			Data:    nil,
+		}
+	}
+	helpers.AppLogger.Infof("123云盘访问凭证保存成功，账号ID：%d", account.ID)
+	return helpers.EventResult{
+		Success: true,
+		Error:   nil,
+		Data:    nil,
+	}
+}
@@ -60,6 +60,10 @@ type BackupConfig struct {
 	BackupRetention int    `json:"backup_retention" gorm:"default:7"`  // 备份保留天数
 	BackupMaxCount  int    `json:"backup_max_count" gorm:"default:10"` // 最多保留的备份数量
 	BackupCompress  int    `json:"backup_compress" gorm:"default:1"`   // 是否压缩备份，0表示不压缩，1表示压缩
+	// 加密后的备份文件可以放心上传到网盘，恢复时需要同样的密码
+	BackupEncrypt    int    `json:"backup_encrypt" gorm:"default:0"` // 是否加密备份，0表示不加密，1表示使用密码加密
+	BackupPassphrase string `json:"-"`                               // 加密密码，不返回给前端
+	HasPassphrase    bool   `json:"has_passphrase" gorm:"-"`         // 是否已设置加密密码，仅供前端使用
 }
 
 func (*BackupConfig) TableName() string {
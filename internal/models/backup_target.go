@@ -0,0 +1,116 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"fmt"
+	"time"
+)
+
+// 支持作为异地备份目标的网盘类型
+var BackupTargetSourceTypes = []SourceType{SourceType115, SourceTypeOpenList, SourceTypeWebDav, SourceTypeS3}
+
+// BackupTarget 异地备份目标，备份完成后把备份文件上传到网盘目录
+// 每个目标单独按保留天数和保留数量清理远程的旧备份
+type BackupTarget struct {
+	BaseModel
+	Name         string     `json:"name"`                                    // 目标名称，仅供用户识别
+	SourceType   SourceType `json:"source_type"`                             // 网盘类型：115、openlist、webdav、s3
+	AccountId    uint       `json:"account_id"`                              // 网盘账号ID
+	RemotePath   string     `json:"remote_path"`                             // 存放备份文件的远程目录
+	RemotePathId string     `json:"remote_path_id"`                          // 115网盘的目录ID
+	Enabled      bool       `json:"enabled"`                                 // 是否启用
+	Retention    int        `json:"retention" gorm:"default:30"`             // 远程备份保留天数，0为不限制
+	MaxCount     int        `json:"max_count" gorm:"default:10"`             // 远程最多保留的备份数量，0为不限制
+	LastUploadAt int64      `json:"last_upload_at"`                          // 最后一次上传成功的时间
+	LastError    string     `json:"last_error" gorm:"type:string;size:1024"` // 最后一次上传失败的原因，成功后清空
+}
+
+func (*BackupTarget) TableName() string {
+	return "backup_target"
+}
+
+// Validate 检查目标配置是否完整
+func (t *BackupTarget) Validate() error {
+	supported := false
+	for _, sourceType := range BackupTargetSourceTypes {
+		if t.SourceType == sourceType {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return fmt.Errorf("不支持的备份目标类型: %s", t.SourceType)
+	}
+	if t.AccountId == 0 {
+		return fmt.Errorf("请选择网盘账号")
+	}
+	account, err := GetAccountById(t.AccountId)
+	if err != nil || account == nil {
+		return fmt.Errorf("网盘账号 %d 不存在", t.AccountId)
+	}
+	if account.SourceType != t.SourceType {
+		return fmt.Errorf("网盘账号 %s 的类型和备份目标不一致", account.Name)
+	}
+	if t.SourceType == SourceType115 && t.RemotePathId == "" {
+		return fmt.Errorf("请选择115网盘的备份目录")
+	}
+	if t.SourceType != SourceType115 && t.RemotePath == "" {
+		return fmt.Errorf("请填写备份目录")
+	}
+	return nil
+}
+
+// GetAccount 目标使用的网盘账号
+func (t *BackupTarget) GetAccount() (*Account, error) {
+	account, err := GetAccountById(t.AccountId)
+	if err != nil || account == nil {
+		return nil, fmt.Errorf("网盘账号 %d 不存在", t.AccountId)
+	}
+	return account, nil
+}
+
+// UpdateUploadResult 记录最后一次上传的结果
+func (t *BackupTarget) UpdateUploadResult(err error) {
+	updates := map[string]any{"last_error": ""}
+	if err != nil {
+		updates["last_error"] = err.Error()
+	} else {
+		updates["last_upload_at"] = time.Now().Unix()
+	}
+	db.Db.Model(t).Updates(updates)
+}
+
+func GetBackupTargets() []*BackupTarget {
+	var targets []*BackupTarget
+	db.Db.Order("id ASC").Find(&targets)
+	return targets
+}
+
+func GetEnabledBackupTargets() []*BackupTarget {
+	var targets []*BackupTarget
+	db.Db.Where("enabled = ?", true).Order("id ASC").Find(&targets)
+	return targets
+}
+
+func GetBackupTargetById(id uint) (*BackupTarget, error) {
+	var target BackupTarget
+	if err := db.Db.First(&target, id).Error; err != nil {
+		return nil, fmt.Errorf("备份目标 %d 不存在", id)
+	}
+	return &target, nil
+}
+
+// SaveBackupTarget 新建或更新备份目标
+func SaveBackupTarget(target *BackupTarget) error {
+	if err := target.Validate(); err != nil {
+		return err
+	}
+	if target.ID == 0 {
+		return db.Db.Create(target).Error
+	}
+	return db.Db.Model(target).Select("name", "source_type", "account_id", "remote_path", "remote_path_id", "enabled", "retention", "max_count").Updates(target).Error
+}
+
+func DeleteBackupTarget(id uint) error {
+	return db.Db.Delete(&BackupTarget{}, id).Error
+}
@@ -0,0 +1,345 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CategoryRule 二级分类规则，按优先级从小到大依次匹配，第一个满足所有条件的规则决定分类
+// 没有规则命中时使用分类自带的流派和语言（国家）匹配
+// 例如：动画（流派16+语言ja）、纪录片（流派99）、4K（分辨率UHD）可以各自建一条规则指向单独的分类
+type CategoryRule struct {
+	BaseModel
+	Name           string             `json:"name"`                    // 规则名称
+	MediaType      MediaType          `json:"media_type" gorm:"index"` // movie或者tvshow
+	CategoryId     uint               `json:"category_id"`             // 命中后使用的分类，电影为MovieCategory的ID，电视剧为TvShowCategory的ID
+	Priority       int                `json:"priority"`                // 优先级，越小越先匹配
+	Enabled        bool               `json:"enabled"`                 // 是否启用
+	ConditionsJson string             `json:"-" gorm:"type:text"`      // 条件，json对象
+	Conditions     CategoryConditions `json:"conditions" gorm:"-"`     // 条件
+}
+
+// CategoryConditions 规则的条件，所有设置了的条件都满足时规则命中，没有设置的条件不限制
+type CategoryConditions struct {
+	GenresAll       []int    `json:"genres_all,omitempty"`        // 必须包含所有的流派ID
+	GenresAny       []int    `json:"genres_any,omitempty"`        // 包含任意一个流派ID
+	GenresNone      []int    `json:"genres_none,omitempty"`       // 不能包含任何一个流派ID
+	Languages       []string `json:"languages,omitempty"`         // 原始语言，任意一个
+	Countries       []string `json:"countries,omitempty"`         // 出品国家，任意一个
+	YearMin         int      `json:"year_min,omitempty"`          // 最小年份
+	YearMax         int      `json:"year_max,omitempty"`          // 最大年份
+	RatingMin       float64  `json:"rating_min,omitempty"`        // 最低评分
+	RatingMax       float64  `json:"rating_max,omitempty"`        // 最高评分
+	RuntimeMin      int64    `json:"runtime_min,omitempty"`       // 最短时长，单位：分钟
+	RuntimeMax      int64    `json:"runtime_max,omitempty"`       // 最长时长，单位：分钟
+	Keywords        []string `json:"keywords,omitempty"`          // TMDB关键词名称或者ID，任意一个
+	Resolutions     []string `json:"resolutions,omitempty"`       // 分辨率等级，SD/HD/FHD/QHD/UHD/FUHD，任意一个
+	Hdr             *bool    `json:"hdr,omitempty"`               // 是否HDR，为空不限制
+	SourcePathRegex string   `json:"source_path_regex,omitempty"` // 源文件夹正则，匹配相对刮削目录的路径
+}
+
+// CategoryFacts 匹配分类规则使用的影视剧信息
+type CategoryFacts struct {
+	Name            string   `json:"name"`
+	GenreIds        []int    `json:"genre_ids"`
+	Language        string   `json:"language"`
+	Countries       []string `json:"countries"`
+	Year            int      `json:"year"`
+	Rating          float64  `json:"rating"`
+	Runtime         int64    `json:"runtime"`
+	Keywords        []string `json:"keywords"`         // TMDB关键词，名称和ID
+	KeywordsLoaded  bool     `json:"keywords_loaded"`  // 是否查询到了关键词
+	ResolutionLevel string   `json:"resolution_level"` // 分辨率等级，没有视频信息时为空
+	IsHDR           bool     `json:"is_hdr"`
+	HasVideoInfo    bool     `json:"has_video_info"` // 是否已经通过ffprobe获取视频信息
+	SourcePath      string   `json:"source_path"`
+}
+
+// CategoryCheck 单个条件的匹配结果
+type CategoryCheck struct {
+	Condition string `json:"condition"` // 条件名称
+	Expect    string `json:"expect"`    // 规则要求的值
+	Actual    string `json:"actual"`    // 影视剧的值
+	Passed    bool   `json:"passed"`    // 是否满足
+}
+
+// CategoryRuleResult 单条规则的匹配结果
+type CategoryRuleResult struct {
+	RuleId     uint            `json:"rule_id"`
+	RuleName   string          `json:"rule_name"`
+	CategoryId uint            `json:"category_id"`
+	Matched    bool            `json:"matched"`
+	Checks     []CategoryCheck `json:"checks"`
+}
+
+func (*CategoryRule) TableName() string {
+	return "category_rule"
+}
+
+// NewCategoryFacts 从刮削文件中取出匹配规则需要的信息，不包括TMDB关键词
+func NewCategoryFacts(mediaFile *ScrapeMediaFile) *CategoryFacts {
+	facts := &CategoryFacts{
+		ResolutionLevel: mediaFile.ResolutionLevel,
+		IsHDR:           mediaFile.IsHDR,
+		HasVideoInfo:    mediaFile.VideoCodec != nil || mediaFile.ResolutionLevel != "",
+		SourcePath:      mediaFile.Path,
+	}
+	if mediaFile.MediaType == MediaTypeTvShow && mediaFile.TvshowPath != "" {
+		facts.SourcePath = mediaFile.TvshowPath
+	}
+	if mediaFile.Media == nil {
+		return facts
+	}
+	media := mediaFile.Media
+	facts.Name = media.Name
+	facts.Language = media.OriginalLanguage
+	facts.Countries = media.OriginCountry
+	facts.Year = media.Year
+	facts.Rating = media.VoteAverage
+	facts.Runtime = media.Runtime
+	for _, genre := range media.Genres {
+		facts.GenreIds = append(facts.GenreIds, genre.ID)
+	}
+	return facts
+}
+
+// NeedKeywords 是否需要查询TMDB关键词
+func (c *CategoryConditions) NeedKeywords() bool {
+	return len(c.Keywords) > 0
+}
+
+// Evaluate 检查规则的所有条件，返回每个条件的匹配结果，方便解释为什么命中或者没有命中
+func (r *CategoryRule) Evaluate(facts *CategoryFacts) CategoryRuleResult {
+	result := CategoryRuleResult{RuleId: r.ID, RuleName: r.Name, CategoryId: r.CategoryId, Matched: true, Checks: make([]CategoryCheck, 0)}
+	add := func(condition string, expect any, actual any, passed bool) {
+		result.Checks = append(result.Checks, CategoryCheck{Condition: condition, Expect: fmt.Sprint(expect), Actual: fmt.Sprint(actual), Passed: passed})
+		if !passed {
+			result.Matched = false
+		}
+	}
+	c := &r.Conditions
+	if len(c.GenresAll) > 0 {
+		passed := true
+		for _, id := range c.GenresAll {
+			if !slices.Contains(facts.GenreIds, id) {
+				passed = false
+				break
+			}
+		}
+		add("genres_all", c.GenresAll, facts.GenreIds, passed)
+	}
+	if len(c.GenresAny) > 0 {
+		add("genres_any", c.GenresAny, facts.GenreIds, containsAny(c.GenresAny, facts.GenreIds))
+	}
+	if len(c.GenresNone) > 0 {
+		add("genres_none", c.GenresNone, facts.GenreIds, !containsAny(c.GenresNone, facts.GenreIds))
+	}
+	if len(c.Languages) > 0 {
+		add("languages", c.Languages, facts.Language, facts.Language != "" && containsFold(c.Languages, facts.Language))
+	}
+	if len(c.Countries) > 0 {
+		passed := false
+		for _, country := range facts.Countries {
+			if containsFold(c.Countries, country) {
+				passed = true
+				break
+			}
+		}
+		add("countries", c.Countries, facts.Countries, passed)
+	}
+	if c.YearMin > 0 || c.YearMax > 0 {
+		add("year", rangeText(c.YearMin, c.YearMax), facts.Year, facts.Year > 0 && inRange(facts.Year, c.YearMin, c.YearMax))
+	}
+	if c.RatingMin > 0 || c.RatingMax > 0 {
+		add("rating", rangeText(c.RatingMin, c.RatingMax), facts.Rating, inRange(facts.Rating, c.RatingMin, c.RatingMax))
+	}
+	if c.RuntimeMin > 0 || c.RuntimeMax > 0 {
+		add("runtime", rangeText(c.RuntimeMin, c.RuntimeMax), facts.Runtime, facts.Runtime > 0 && inRange(facts.Runtime, c.RuntimeMin, c.RuntimeMax))
+	}
+	if len(c.Keywords) > 0 {
+		passed := false
+		for _, keyword := range facts.Keywords {
+			if containsFold(c.Keywords, keyword) {
+				passed = true
+				break
+			}
+		}
+		actual := any(facts.Keywords)
+		if !facts.KeywordsLoaded {
+			actual = "未查询到关键词"
+		}
+		add("keywords", c.Keywords, actual, passed)
+	}
+	if len(c.Resolutions) > 0 {
+		actual := any(facts.ResolutionLevel)
+		if facts.ResolutionLevel == "" {
+			actual = "未知"
+		}
+		add("resolutions", c.Resolutions, actual, facts.ResolutionLevel != "" && containsFold(c.Resolutions, facts.ResolutionLevel))
+	}
+	if c.Hdr != nil {
+		actual := any(facts.IsHDR)
+		if !facts.HasVideoInfo {
+			actual = "未知"
+		}
+		add("hdr", *c.Hdr, actual, facts.HasVideoInfo && facts.IsHDR == *c.Hdr)
+	}
+	if c.SourcePathRegex != "" {
+		re, err := regexp.Compile(c.SourcePathRegex)
+		passed := err == nil && re.MatchString(facts.SourcePath)
+		add("source_path_regex", c.SourcePathRegex, facts.SourcePath, passed)
+	}
+	return result
+}
+
+// EvaluateCategoryRules 按顺序匹配规则，返回第一个命中的规则和到命中为止每条规则的匹配结果
+func EvaluateCategoryRules(rules []*CategoryRule, facts *CategoryFacts) (*CategoryRule, []CategoryRuleResult) {
+	results := make([]CategoryRuleResult, 0, len(rules))
+	for _, rule := range rules {
+		result := rule.Evaluate(facts)
+		results = append(results, result)
+		if result.Matched {
+			return rule, results
+		}
+	}
+	return nil, results
+}
+
+func containsAny(expect []int, actual []int) bool {
+	for _, id := range expect {
+		if slices.Contains(actual, id) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(strings.TrimSpace(item), value) {
+			return true
+		}
+	}
+	return false
+}
+
+// 最小值或者最大值为0时不限制
+func inRange[T int | int64 | float64](value, minValue, maxValue T) bool {
+	if minValue > 0 && value < minValue {
+		return false
+	}
+	if maxValue > 0 && value > maxValue {
+		return false
+	}
+	return true
+}
+
+func rangeText[T int | int64 | float64](minValue, maxValue T) string {
+	text := func(v T) string {
+		if v <= 0 {
+			return ""
+		}
+		return strconv.FormatFloat(float64(v), 'f', -1, 64)
+	}
+	return text(minValue) + "-" + text(maxValue)
+}
+
+// Validate 检查规则是否完整
+func (r *CategoryRule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("请填写规则名称")
+	}
+	if r.MediaType != MediaTypeMovie && r.MediaType != MediaTypeTvShow {
+		return fmt.Errorf("规则只支持电影和电视剧")
+	}
+	if GetCategoryName(r.MediaType, r.CategoryId) == "" {
+		return fmt.Errorf("分类 %d 不存在", r.CategoryId)
+	}
+	if r.Conditions.SourcePathRegex != "" {
+		if _, err := regexp.Compile(r.Conditions.SourcePathRegex); err != nil {
+			return fmt.Errorf("源文件夹正则错误: %v", err)
+		}
+	}
+	return nil
+}
+
+// GetCategoryName 查询电影或者电视剧分类的名称，分类不存在时返回空
+func GetCategoryName(mediaType MediaType, categoryId uint) string {
+	if mediaType == MediaTypeMovie {
+		for _, category := range GetMovieCategory() {
+			if category.ID == categoryId {
+				return category.Name
+			}
+		}
+		return ""
+	}
+	for _, category := range GetTvshowCategory() {
+		if category.ID == categoryId {
+			return category.Name
+		}
+	}
+	return ""
+}
+
+func (r *CategoryRule) encode() error {
+	data, err := json.Marshal(r.Conditions)
+	if err != nil {
+		return err
+	}
+	r.ConditionsJson = string(data)
+	return nil
+}
+
+func (r *CategoryRule) decode() {
+	r.Conditions = CategoryConditions{}
+	if r.ConditionsJson != "" {
+		json.Unmarshal([]byte(r.ConditionsJson), &r.Conditions)
+	}
+}
+
+// GetCategoryRules 查询规则列表，按优先级排序，mediaType为空时查询所有
+func GetCategoryRules(mediaType MediaType) []*CategoryRule {
+	var rules []*CategoryRule
+	query := db.Db.Model(&CategoryRule{})
+	if mediaType != "" {
+		query = query.Where("media_type = ?", mediaType)
+	}
+	query.Order("priority ASC, id ASC").Find(&rules)
+	for _, rule := range rules {
+		rule.decode()
+	}
+	return rules
+}
+
+// GetEnabledCategoryRules 查询启用的规则，按优先级排序
+func GetEnabledCategoryRules(mediaType MediaType) []*CategoryRule {
+	var rules []*CategoryRule
+	db.Db.Where("media_type = ? AND enabled = ?", mediaType, true).Order("priority ASC, id ASC").Find(&rules)
+	for _, rule := range rules {
+		rule.decode()
+	}
+	return rules
+}
+
+// SaveCategoryRule 新建或更新规则
+func SaveCategoryRule(rule *CategoryRule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	if err := rule.encode(); err != nil {
+		return err
+	}
+	if rule.ID == 0 {
+		return db.Db.Create(rule).Error
+	}
+	return db.Db.Model(rule).Select("name", "media_type", "category_id", "priority", "enabled", "conditions_json").Updates(rule).Error
+}
+
+func DeleteCategoryRule(id uint) error {
+	return db.Db.Delete(&CategoryRule{}, id).Error
+}
@@ -0,0 +1,51 @@
+package models
+
+import "testing"
+
+func TestEvaluateCategoryRules(t *testing.T) {
+	hdr := true
+	rules := []*CategoryRule{
+		{Name: "4K HDR", CategoryId: 3, Conditions: CategoryConditions{Resolutions: []string{ResolutionUHD}, Hdr: &hdr}},
+		{Name: "动画", CategoryId: 1, Conditions: CategoryConditions{GenresAny: []int{16}, Languages: []string{"ja"}, GenresNone: []int{99}}},
+		{Name: "纪录片", CategoryId: 2, Conditions: CategoryConditions{GenresAll: []int{99}, YearMin: 2000, RuntimeMax: 120}},
+	}
+	anime := &CategoryFacts{GenreIds: []int{16, 10759}, Language: "JA", Year: 2019, ResolutionLevel: ResolutionFHD, HasVideoInfo: true}
+	rule, results := EvaluateCategoryRules(rules, anime)
+	if rule == nil || rule.CategoryId != 1 || len(results) != 2 {
+		t.Fatalf("动画应该命中第二条规则: %+v", results)
+	}
+	if results[0].Matched || len(results[0].Checks) != 2 || results[0].Checks[0].Passed || results[0].Checks[1].Passed {
+		t.Errorf("4K规则的匹配结果错误: %+v", results[0])
+	}
+
+	documentary := &CategoryFacts{GenreIds: []int{99}, Year: 2015, Runtime: 90}
+	if rule, _ := EvaluateCategoryRules(rules, documentary); rule == nil || rule.CategoryId != 2 {
+		t.Errorf("纪录片应该命中第三条规则")
+	}
+	documentary.Runtime = 150
+	if rule, _ := EvaluateCategoryRules(rules, documentary); rule != nil {
+		t.Errorf("时长超过上限不应该命中规则: %s", rule.Name)
+	}
+
+	// 没有视频信息时分辨率和HDR条件不满足
+	uhd := &CategoryFacts{ResolutionLevel: ResolutionUHD, IsHDR: true, HasVideoInfo: true}
+	if rule, _ := EvaluateCategoryRules(rules, uhd); rule == nil || rule.CategoryId != 3 {
+		t.Errorf("4K HDR应该命中第一条规则")
+	}
+	uhd.HasVideoInfo = false
+	if rule, _ := EvaluateCategoryRules(rules, uhd); rule != nil {
+		t.Errorf("没有视频信息时不应该命中4K规则")
+	}
+}
+
+func TestCategoryRuleKeywordsAndPath(t *testing.T) {
+	rule := &CategoryRule{Conditions: CategoryConditions{Keywords: []string{"anime", "210024"}, SourcePathRegex: `^动漫/`}}
+	facts := &CategoryFacts{Keywords: []string{"Anime", "1"}, KeywordsLoaded: true, SourcePath: "动漫/鬼灭之刃"}
+	if result := rule.Evaluate(facts); !result.Matched {
+		t.Errorf("关键词和路径都满足时应该命中: %+v", result)
+	}
+	facts.SourcePath = "电视剧/鬼灭之刃"
+	if result := rule.Evaluate(facts); result.Matched {
+		t.Errorf("路径不满足时不应该命中")
+	}
+}
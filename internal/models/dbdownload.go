@@ -3,12 +3,14 @@ package models
 import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/open123"
 	"Q115-STRM/internal/v115open"
 	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -130,6 +132,11 @@ func (task *DbDownloadTask) Download() {
 		case SourceTypeBaiduPan:
 			task.DownloadBaiduPanFile()
 		case SourceType123:
+			task.Download123File()
+		case SourceTypeWebDav:
+			task.DownloadWebDavFile()
+		case SourceTypeS3:
+			task.DownloadS3File()
 		}
 	case DownloadSourceEmbyMedia:
 		// emby媒体信息提取，从emby下载
@@ -246,6 +253,50 @@ func (task *DbDownloadTask) DownloadOpenListFile() {
 	task.Complete()
 }
 
+// 下载WebDAV的文件，RemoteFileId是文件的完整路径，需要带上账号密码访问
+func (task *DbDownloadTask) DownloadWebDavFile() {
+	account := task.GetAccount()
+	if account == nil {
+		task.Fail(fmt.Errorf("账户不存在，无法下载文件%s", task.LocalFullPath))
+		return
+	}
+	// 标记为下载中
+	task.Downloading()
+	client := account.GetWebDavClient()
+	downloadErr := client.Download(context.Background(), task.RemoteFileId, task.LocalFullPath)
+	if downloadErr != nil {
+		helpers.AppLogger.Warnf("[下载] 下载WebDAV文件失败: %s", downloadErr.Error())
+		task.Fail(downloadErr)
+		return
+	}
+	// 设置文件修改时间
+	task.SetMTime()
+	// 下载完成
+	task.Complete()
+}
+
+// 下载S3的对象，RemoteFileId是对象路径
+func (task *DbDownloadTask) DownloadS3File() {
+	account := task.GetAccount()
+	if account == nil {
+		task.Fail(fmt.Errorf("账户不存在，无法下载文件%s", task.LocalFullPath))
+		return
+	}
+	// 标记为下载中
+	task.Downloading()
+	client := account.GetS3Client()
+	downloadErr := client.Download(context.Background(), task.RemoteFileId, task.LocalFullPath)
+	if downloadErr != nil {
+		helpers.AppLogger.Warnf("[下载] 下载S3文件失败: %s", downloadErr.Error())
+		task.Fail(downloadErr)
+		return
+	}
+	// 设置文件修改时间
+	task.SetMTime()
+	// 下载完成
+	task.Complete()
+}
+
 // 下载百度网盘的文件
 func (task *DbDownloadTask) DownloadBaiduPanFile() {
 	account := task.GetAccount()
@@ -282,6 +333,41 @@ func (task *DbDownloadTask) DownloadBaiduPanFile() {
 	task.Complete()
 }
 
+func (task *DbDownloadTask) Download123File() {
+	account := task.GetAccount()
+	if account == nil {
+		task.Fail(fmt.Errorf("账户不存在，无法下载文件%s", task.LocalFullPath))
+		return
+	}
+	// 123云盘的pickcode就是文件ID
+	fileId, err := strconv.ParseInt(task.RemoteFileId, 10, 64)
+	if err != nil {
+		task.Fail(fmt.Errorf("123云盘文件ID %s 格式错误: %v", task.RemoteFileId, err))
+		return
+	}
+	// 标记为下载中
+	task.Downloading()
+	// 查询下载链接
+	client := account.GetOpen123Client()
+	url, err := client.GetDirectLink(context.Background(), fileId)
+	if err != nil || url == "" {
+		helpers.AppLogger.Warnf("[下载] 获取123云盘下载链接失败: %s %v", task.RemoteFileId, err)
+		task.Fail(fmt.Errorf("获取 %s => %s 的下载链接失败: %v", task.RemoteFileId, task.FileName, err))
+		return
+	}
+	// 下载文件到指定位置
+	downloadErr := helpers.DownloadFile(url, task.LocalFullPath, open123.DEFAULTUA)
+	if downloadErr != nil {
+		helpers.AppLogger.Warnf("[下载] 下载文件失败: %s", downloadErr.Error())
+		task.Fail(downloadErr)
+		return
+	}
+	// 设置文件修改时间
+	task.SetMTime()
+	// 下载完成
+	task.Complete()
+}
+
 // 访问Emby下载链接
 func (task *DbDownloadTask) DownloadEmbyMedia() {
 	// 标记为下载中
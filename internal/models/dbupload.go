@@ -3,11 +3,14 @@ package models
 import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/v115open"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 )
 
@@ -47,6 +50,9 @@ type DbUploadTask struct {
 	StartTime            int64            `json:"start_time"`                                       // 开始时间
 	EndTime              int64            `json:"end_time"`                                         // 结束时间
 	IsSeasonOrTvshowFile bool             `json:"is_season_or_tvshow_file"`                         // 是否是剧集或电视剧文件
+	UploadedSize         int64            `json:"uploaded_size"`                                    // 已上传的字节数
+	MultipartState       string           `json:"-" gorm:"type:text"`                               // 115分片上传状态，JSON格式，用于断点续传
+	Progress             int              `json:"progress" gorm:"-"`                                // 上传进度百分比
 	SyncFile             *SyncFile        `json:"-" gorm:"-"`                                       // 同步文件
 	ScrapeMediaFile      *ScrapeMediaFile `json:"-" gorm:"-"`                                       // 刮削文件
 	Account              *Account         `json:"-" gorm:"-"`                                       // 账户
@@ -74,6 +80,8 @@ func (task *DbUploadTask) Complete() {
 	// 标记为已完成
 	task.Status = UploadStatusCompleted
 	task.EndTime = time.Now().Unix()
+	task.UploadedSize = task.FileSize
+	task.MultipartState = ""
 	err := db.Db.Save(task).Error
 	if err != nil {
 		helpers.AppLogger.Warnf("[上传] 标记为已完成失败: %s", err.Error())
@@ -110,6 +118,37 @@ func (task *DbUploadTask) Uploading() {
 	}
 }
 
+// 读取保存的115分片上传状态
+func (task *DbUploadTask) GetMultipartState() *v115open.MultipartState {
+	state := &v115open.MultipartState{}
+	if task.MultipartState == "" {
+		return state
+	}
+	if err := json.Unmarshal([]byte(task.MultipartState), state); err != nil {
+		helpers.AppLogger.Warnf("[上传] 解析分片上传状态失败，将重新上传: %v", err)
+		return &v115open.MultipartState{}
+	}
+	return state
+}
+
+// 保存115分片上传状态和已上传的字节数
+func (task *DbUploadTask) SaveMultipartState(state *v115open.MultipartState) {
+	stateJson, err := json.Marshal(state)
+	if err != nil {
+		helpers.AppLogger.Warnf("[上传] 序列化分片上传状态失败: %v", err)
+		return
+	}
+	task.MultipartState = string(stateJson)
+	task.UploadedSize = state.UploadedSize
+	updateData := map[string]interface{}{
+		"multipart_state": task.MultipartState,
+		"uploaded_size":   task.UploadedSize,
+	}
+	if err := db.Db.Model(task).Updates(updateData).Error; err != nil {
+		helpers.AppLogger.Warnf("[上传] 保存分片上传状态失败: %v", err)
+	}
+}
+
 func (task *DbUploadTask) GetAccount() *Account {
 	if task.Account != nil {
 		return task.Account
@@ -147,6 +186,18 @@ func (task *DbUploadTask) Upload() {
 		if !task.UploadBaiduPanFile() {
 			return
 		}
+	case SourceType123:
+		if !task.Upload123File() {
+			return
+		}
+	case SourceTypeWebDav:
+		if !task.UploadWebDavFile() {
+			return
+		}
+	case SourceTypeS3:
+		if !task.UploadS3File() {
+			return
+		}
 	default:
 		task.Fail(fmt.Errorf("未知的上传来源类型 %s", task.SourceType))
 		return
@@ -218,8 +269,8 @@ func (task *DbUploadTask) Upload115File() bool {
 		return false
 	}
 	helpers.AppLogger.Infof("准备将文件 %s 上传到115目录 %s", task.LocalFullPath, task.RemotePathId)
-	// 上传文件
-	fileId, err := client.Upload(context.Background(), task.LocalFullPath, task.RemotePathId, "", "")
+	// 上传文件，大文件分片上传，中断后从已上传的分片继续
+	fileId, err := client.UploadResume(context.Background(), task.LocalFullPath, task.RemotePathId, task.GetMultipartState(), task.SaveMultipartState)
 	if err != nil {
 		task.Fail(fmt.Errorf("调用115上传API失败: %v", err))
 		return false
@@ -284,6 +335,49 @@ func (task *DbUploadTask) UploadBaiduPanFile() bool {
 	return true
 }
 
+func (task *DbUploadTask) Upload123File() bool {
+	// 检查账户是否存在
+	account := task.GetAccount()
+	if account == nil {
+		task.Fail(fmt.Errorf("账户 %d 不存在", task.AccountId))
+		return false
+	}
+	// 上传文件
+	client := account.GetOpen123Client()
+	if client == nil {
+		task.Fail(fmt.Errorf("账户 %s 123云盘客户端不存在", account.Name))
+		return false
+	}
+	parentId, err := strconv.ParseInt(task.RemotePathId, 10, 64)
+	if err != nil {
+		task.Fail(fmt.Errorf("123云盘父目录ID %s 格式错误: %v", task.RemotePathId, err))
+		return false
+	}
+	task.Uploading()
+	// 同名文件会被覆盖
+	resp, err := client.UploadFile(context.Background(), task.LocalFullPath, parentId)
+	if err != nil {
+		task.Fail(fmt.Errorf("123云盘上传文件 %s 失败: %v", task.FileName, err))
+		return false
+	}
+	if task.Source == UploadSourceStrm {
+		// 查询文件详情，用网盘的创建时间作为本地文件的修改时间
+		detail, err := client.GetFileDetail(context.Background(), resp.FileID)
+		if err != nil {
+			helpers.AppLogger.Warnf("123云盘查询文件详情 %d 失败: %v", resp.FileID, err)
+			return true
+		}
+		t := time.Unix(detail.GetCtime(), 0)
+		// 更新本地文件的修改时间
+		err = os.Chtimes(task.LocalFullPath, t, t)
+		if err != nil {
+			task.Fail(fmt.Errorf("更新本地文件 %s 修改时间失败: %v", task.LocalFullPath, err))
+			return false
+		}
+	}
+	return true
+}
+
 func (task *DbUploadTask) UploadOpenListFile() bool {
 	// 检查账户是否存在
 	account := task.GetAccount()
@@ -326,6 +420,70 @@ func (task *DbUploadTask) UploadOpenListFile() bool {
 	return true
 }
 
+func (task *DbUploadTask) UploadWebDavFile() bool {
+	// 检查账户是否存在
+	account := task.GetAccount()
+	if account == nil {
+		task.Fail(fmt.Errorf("账户 %d 不存在", task.AccountId))
+		return false
+	}
+	client := account.GetWebDavClient()
+	task.Uploading()
+	ctx := context.Background()
+	if err := client.Upload(ctx, task.LocalFullPath, task.RemoteFileId); err != nil {
+		task.Fail(fmt.Errorf("WebDAV上传文件 %s 失败: %v", task.FileName, err))
+		return false
+	}
+	if task.Source == UploadSourceStrm {
+		// 本地文件的修改时间和网盘保持一致，避免下次同步时重复上传
+		info, err := client.Stat(ctx, task.RemoteFileId)
+		if err != nil {
+			task.Fail(fmt.Errorf("WebDAV查询文件详情 %s 失败: %s", task.RemoteFileId, err.Error()))
+			return false
+		}
+		if info.ModTime.IsZero() {
+			return true
+		}
+		if err := os.Chtimes(task.LocalFullPath, info.ModTime, info.ModTime); err != nil {
+			task.Fail(fmt.Errorf("更新本地文件 %s 修改时间失败: %v", task.LocalFullPath, err))
+			return false
+		}
+	}
+	return true
+}
+
+func (task *DbUploadTask) UploadS3File() bool {
+	// 检查账户是否存在
+	account := task.GetAccount()
+	if account == nil {
+		task.Fail(fmt.Errorf("账户 %d 不存在", task.AccountId))
+		return false
+	}
+	client := account.GetS3Client()
+	task.Uploading()
+	ctx := context.Background()
+	if err := client.Upload(ctx, task.LocalFullPath, task.RemoteFileId); err != nil {
+		task.Fail(fmt.Errorf("S3上传文件 %s 失败: %v", task.FileName, err))
+		return false
+	}
+	if task.Source == UploadSourceStrm {
+		// 本地文件的修改时间和对象的LastModified保持一致，避免下次同步时重复上传
+		obj, err := client.Stat(ctx, task.RemoteFileId)
+		if err != nil {
+			task.Fail(fmt.Errorf("S3查询文件详情 %s 失败: %s", task.RemoteFileId, err.Error()))
+			return false
+		}
+		if obj.LastModified.IsZero() {
+			return true
+		}
+		if err := os.Chtimes(task.LocalFullPath, obj.LastModified, obj.LastModified); err != nil {
+			task.Fail(fmt.Errorf("更新本地文件 %s 修改时间失败: %v", task.LocalFullPath, err))
+			return false
+		}
+	}
+	return true
+}
+
 func (task *DbUploadTask) UploadLocalFile() bool {
 	task.Uploading()
 	err := helpers.CopyFile(task.LocalFullPath, task.RemoteFileId)
@@ -468,6 +626,11 @@ func GetUploadTaskList(status UploadStatus, page, pageSize int) ([]*DbUploadTask
 		Offset((page - 1) * pageSize).
 		Order("id DESC").
 		Find(&tasks)
+	for _, task := range tasks {
+		if task.FileSize > 0 {
+			task.Progress = int(task.UploadedSize * 100 / task.FileSize)
+		}
+	}
 	return tasks, total
 }
 
@@ -0,0 +1,425 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/v115open"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// 重复文件的匹配方式
+const (
+	DuplicateMatchHash      = "hash"       // 网盘返回的文件哈希相同（115是SHA1，百度网盘和123云盘是MD5）
+	DuplicateMatchSizeMedia = "size_media" // 文件大小相同并且刮削后是同一部电影或者同一集
+)
+
+// 每次按ID批量查询的数量，避免IN条件过长
+const duplicateQueryBatch = 500
+
+// DuplicateCopy 重复文件中的一个副本，对应一条SyncFile记录
+type DuplicateCopy struct {
+	SyncFileId  uint       `json:"sync_file_id"`
+	SourceType  SourceType `json:"source_type"`
+	AccountId   uint       `json:"account_id"`
+	AccountName string     `json:"account_name"`
+	SyncPathId  uint       `json:"sync_path_id"`
+	FileId      string     `json:"file_id"`
+	ParentId    string     `json:"parent_id"`
+	FileName    string     `json:"file_name"`
+	Path        string     `json:"path"`
+	FileSize    int64      `json:"file_size"`
+	Sha1        string     `json:"sha1"`
+	Md5         string     `json:"md5"`       // 123云盘只有MD5
+	TmdbId      int64      `json:"tmdb_id"`   // 刮削后的TMDB ID，未刮削或者使用其他元数据提供者为0
+	MediaKey    string     `json:"media_key"` // 刮削后的媒体标识，例如：movie-603 tv-1399-s1e1 tvdb-tv-81189-s1e1
+}
+
+// DuplicateGroup 一组内容相同的文件，除了保留的第一个副本，其他副本都是浪费的空间
+type DuplicateGroup struct {
+	Id         int              `json:"id"`
+	MatchBy    []string         `json:"match_by"`
+	FileSize   int64            `json:"file_size"`
+	TmdbId     int64            `json:"tmdb_id"`
+	Copies     []*DuplicateCopy `json:"copies"` // 按同步时间排序，第一个是建议保留的副本
+	WastedSize int64            `json:"wasted_size"`
+}
+
+// DuplicateAccountSummary 每个账号的重复文件统计
+type DuplicateAccountSummary struct {
+	AccountId     uint       `json:"account_id"`
+	AccountName   string     `json:"account_name"`
+	SourceType    SourceType `json:"source_type"`
+	Copies        int        `json:"copies"`         // 该账号中属于重复组的文件数量
+	DuplicateSize int64      `json:"duplicate_size"` // 该账号中属于重复组的文件总大小
+	WastedSize    int64      `json:"wasted_size"`    // 该账号中不是建议保留副本的文件总大小
+}
+
+// DuplicateReport 一次重复文件扫描的结果
+type DuplicateReport struct {
+	Id          int64                      `json:"id"` // 扫描开始的毫秒时间戳，删除时用来确认是基于同一次扫描的结果
+	StartedAt   int64                      `json:"started_at"`
+	FinishedAt  int64                      `json:"finished_at"`
+	Groups      []*DuplicateGroup          `json:"groups"`
+	Accounts    []*DuplicateAccountSummary `json:"accounts"`
+	TotalWasted int64                      `json:"total_wasted"`
+	Error       string                     `json:"error"`
+}
+
+// 同一个网盘文件可能被多个同步目录同步，账号+文件ID相同的记录是同一个文件
+func (c *DuplicateCopy) physicalKey() string {
+	return fmt.Sprintf("%d:%s", c.AccountId, c.FileId)
+}
+
+// 文件哈希，SHA1和MD5不能互相比较，所以带上哈希类型
+func (c *DuplicateCopy) hashKey() string {
+	if c.Sha1 != "" {
+		return "sha1:" + c.Sha1
+	}
+	if c.Md5 != "" {
+		return "md5:" + c.Md5
+	}
+	return ""
+}
+
+// GroupDuplicateCopies 哈希相同或者大小+媒体相同的文件归为一组，只返回包含多个文件的组
+func GroupDuplicateCopies(copies []*DuplicateCopy) []*DuplicateGroup {
+	// 合并同一个文件的多条记录
+	files := make([]*DuplicateCopy, 0, len(copies))
+	fileIndex := make(map[string]int, len(copies))
+	for _, c := range copies {
+		key := c.physicalKey()
+		if i, ok := fileIndex[key]; ok {
+			exists := files[i]
+			if exists.Sha1 == "" && exists.Md5 == "" {
+				exists.Sha1, exists.Md5 = c.Sha1, c.Md5
+			}
+			if exists.MediaKey == "" {
+				exists.MediaKey, exists.TmdbId = c.MediaKey, c.TmdbId
+			}
+			if c.SyncFileId < exists.SyncFileId {
+				c.Sha1, c.Md5, c.MediaKey, c.TmdbId = exists.Sha1, exists.Md5, exists.MediaKey, exists.TmdbId
+				files[i] = c
+			}
+			continue
+		}
+		fileIndex[key] = len(files)
+		files = append(files, c)
+	}
+	// 并查集：哈希或者大小+媒体任意一个相同就是同一组
+	parent := make([]int, len(files))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	firstByKey := make(map[string]int)
+	link := func(key string, i int) {
+		if first, ok := firstByKey[key]; ok {
+			parent[find(i)] = find(first)
+			return
+		}
+		firstByKey[key] = i
+	}
+	for i, f := range files {
+		if hash := f.hashKey(); hash != "" {
+			link(DuplicateMatchHash+":"+hash, i)
+		}
+		if f.MediaKey != "" && f.FileSize > 0 {
+			link(fmt.Sprintf("%s:%s:%d", DuplicateMatchSizeMedia, f.MediaKey, f.FileSize), i)
+		}
+	}
+	members := make(map[int][]*DuplicateCopy)
+	roots := make([]int, 0)
+	for i, f := range files {
+		root := find(i)
+		if _, ok := members[root]; !ok {
+			roots = append(roots, root)
+		}
+		members[root] = append(members[root], f)
+	}
+	groups := make([]*DuplicateGroup, 0)
+	for _, root := range roots {
+		groupCopies := members[root]
+		if len(groupCopies) < 2 {
+			continue
+		}
+		slices.SortFunc(groupCopies, func(a, b *DuplicateCopy) int { return int(a.SyncFileId) - int(b.SyncFileId) })
+		group := &DuplicateGroup{Copies: groupCopies}
+		group.refresh()
+		groups = append(groups, group)
+	}
+	// 浪费空间多的排在前面
+	slices.SortStableFunc(groups, func(a, b *DuplicateGroup) int {
+		if a.WastedSize > b.WastedSize {
+			return -1
+		}
+		if a.WastedSize < b.WastedSize {
+			return 1
+		}
+		return 0
+	})
+	for i, group := range groups {
+		group.Id = i + 1
+	}
+	return groups
+}
+
+// 根据组内的副本重新计算匹配方式和浪费的空间
+func (g *DuplicateGroup) refresh() {
+	g.MatchBy = []string{}
+	g.WastedSize = 0
+	hashes := make(map[string]int)
+	mediaKeys := make(map[string]int)
+	for i, c := range g.Copies {
+		if i == 0 {
+			g.FileSize = c.FileSize
+		} else {
+			g.WastedSize += c.FileSize
+		}
+		if c.TmdbId > 0 {
+			g.TmdbId = c.TmdbId
+		}
+		if hash := c.hashKey(); hash != "" {
+			hashes[hash]++
+		}
+		if c.MediaKey != "" {
+			mediaKeys[fmt.Sprintf("%s:%d", c.MediaKey, c.FileSize)]++
+		}
+	}
+	for _, count := range hashes {
+		if count > 1 {
+			g.MatchBy = append(g.MatchBy, DuplicateMatchHash)
+			break
+		}
+	}
+	for _, count := range mediaKeys {
+		if count > 1 {
+			g.MatchBy = append(g.MatchBy, DuplicateMatchSizeMedia)
+			break
+		}
+	}
+}
+
+// Summarize 统计每个账号的重复文件和总共浪费的空间
+func (r *DuplicateReport) Summarize() {
+	r.Accounts = []*DuplicateAccountSummary{}
+	r.TotalWasted = 0
+	accountIndex := make(map[uint]*DuplicateAccountSummary)
+	for _, group := range r.Groups {
+		r.TotalWasted += group.WastedSize
+		for i, c := range group.Copies {
+			summary, ok := accountIndex[c.AccountId]
+			if !ok {
+				summary = &DuplicateAccountSummary{AccountId: c.AccountId, AccountName: c.AccountName, SourceType: c.SourceType}
+				accountIndex[c.AccountId] = summary
+				r.Accounts = append(r.Accounts, summary)
+			}
+			summary.Copies++
+			summary.DuplicateSize += c.FileSize
+			if i > 0 {
+				summary.WastedSize += c.FileSize
+			}
+		}
+	}
+	slices.SortStableFunc(r.Accounts, func(a, b *DuplicateAccountSummary) int {
+		if a.WastedSize > b.WastedSize {
+			return -1
+		}
+		if a.WastedSize < b.WastedSize {
+			return 1
+		}
+		return 0
+	})
+}
+
+// PlanDuplicateDelete 检查要删除的副本：必须是本次扫描结果中的副本，并且每组至少保留一个副本
+func (r *DuplicateReport) PlanDuplicateDelete(syncFileIds []uint) ([]*DuplicateCopy, error) {
+	if len(syncFileIds) == 0 {
+		return nil, fmt.Errorf("没有选择要删除的文件")
+	}
+	copyIndex := make(map[uint]*DuplicateGroup)
+	for _, group := range r.Groups {
+		for _, c := range group.Copies {
+			copyIndex[c.SyncFileId] = group
+		}
+	}
+	selected := make(map[*DuplicateGroup]map[uint]bool)
+	plan := make([]*DuplicateCopy, 0, len(syncFileIds))
+	for _, id := range syncFileIds {
+		group, ok := copyIndex[id]
+		if !ok {
+			return nil, fmt.Errorf("文件 %d 不在重复文件扫描结果中，请重新扫描", id)
+		}
+		if selected[group] == nil {
+			selected[group] = make(map[uint]bool)
+		}
+		if selected[group][id] {
+			continue
+		}
+		selected[group][id] = true
+		for _, c := range group.Copies {
+			if c.SyncFileId == id {
+				plan = append(plan, c)
+				break
+			}
+		}
+	}
+	for group, ids := range selected {
+		if len(ids) >= len(group.Copies) {
+			return nil, fmt.Errorf("不能删除第 %d 组的全部 %d 个副本，至少保留一个", group.Id, len(group.Copies))
+		}
+	}
+	return plan, nil
+}
+
+// RemoveCopy 删除成功后从扫描结果中移除副本，组内只剩一个副本时移除整组
+func (r *DuplicateReport) RemoveCopy(syncFileId uint) {
+	groups := r.Groups[:0]
+	for _, group := range r.Groups {
+		group.Copies = slices.DeleteFunc(group.Copies, func(c *DuplicateCopy) bool { return c.SyncFileId == syncFileId })
+		if len(group.Copies) < 2 {
+			continue
+		}
+		group.refresh()
+		groups = append(groups, group)
+	}
+	r.Groups = groups
+	r.Summarize()
+}
+
+func newDuplicateCopy(sf *SyncFile) *DuplicateCopy {
+	return &DuplicateCopy{
+		SyncFileId: sf.ID,
+		SourceType: sf.SourceType,
+		AccountId:  sf.AccountId,
+		SyncPathId: sf.SyncPathId,
+		FileId:     sf.FileId,
+		ParentId:   sf.ParentId,
+		FileName:   sf.FileName,
+		Path:       sf.Path,
+		FileSize:   sf.FileSize,
+		Sha1:       sf.Sha1,
+		Md5:        sf.Md5,
+	}
+}
+
+// 刮削整理后的视频文件ID -> 媒体标识
+type duplicateMedia struct {
+	tmdbId   int64
+	mediaKey string
+}
+
+// 媒体标识，不同元数据提供者的ID可能相同，tmdb以外的提供者加上前缀
+func newDuplicateMedia(provider string, tmdbId int64, key string) duplicateMedia {
+	if provider == "" || provider == MetadataProviderTmdb {
+		return duplicateMedia{tmdbId: tmdbId, mediaKey: key}
+	}
+	return duplicateMedia{mediaKey: provider + "-" + key}
+}
+
+// 查询已经刮削的电影和剧集，视频文件ID和SyncFile的FileId一致
+func getDuplicateMediaByFileId() map[string]duplicateMedia {
+	result := make(map[string]duplicateMedia)
+	var movies []*Media
+	db.Db.Model(&Media{}).Select("id", "media_type", "metadata_provider", "tmdb_id", "video_file_id").Where("tmdb_id > 0 AND video_file_id != ''").Find(&movies)
+	for _, media := range movies {
+		if media.MediaType == MediaTypeMovie {
+			result[media.VideoFileId] = newDuplicateMedia(media.MetadataProvider, media.TmdbId, fmt.Sprintf("movie-%d", media.TmdbId))
+		}
+	}
+	var tvshows []*Media
+	db.Db.Model(&Media{}).Select("id", "metadata_provider", "tmdb_id").Where("tmdb_id > 0 AND media_type = ?", MediaTypeTvShow).Find(&tvshows)
+	tvshowById := make(map[uint]*Media)
+	for _, media := range tvshows {
+		tvshowById[media.ID] = media
+	}
+	var episodes []*MediaEpisode
+	db.Db.Model(&MediaEpisode{}).Select("media_id", "season_number", "episode_number", "video_file_id").Where("video_file_id != ''").Find(&episodes)
+	for _, episode := range episodes {
+		media, ok := tvshowById[episode.MediaId]
+		if !ok {
+			continue
+		}
+		result[episode.VideoFileId] = newDuplicateMedia(media.MetadataProvider, media.TmdbId, fmt.Sprintf("tv-%d-s%de%d", media.TmdbId, episode.SeasonNumber, episode.EpisodeNumber))
+	}
+	return result
+}
+
+// FindDuplicateFiles 扫描所有同步目录的视频文件，按哈希或者大小+TMDB ID查找重复文件
+func FindDuplicateFiles() *DuplicateReport {
+	report := &DuplicateReport{Id: time.Now().UnixMilli(), StartedAt: time.Now().Unix()}
+	defer func() {
+		report.FinishedAt = time.Now().Unix()
+	}()
+	copies := make([]*DuplicateCopy, 0)
+	// 1. 哈希相同的文件，123云盘只有MD5，SHA1和MD5分别查询
+	for _, column := range []string{"sha1", "md5"} {
+		var hashes []string
+		if err := db.Db.Model(&SyncFile{}).Where("is_video = ? AND file_type = ? AND "+column+" != ''", true, v115open.TypeFile).Group(column).Having("COUNT(*) > 1").Pluck(column, &hashes).Error; err != nil {
+			report.Error = fmt.Sprintf("查询文件哈希失败: %v", err)
+			return report
+		}
+		for batch := range slices.Chunk(hashes, duplicateQueryBatch) {
+			var files []*SyncFile
+			if err := db.Db.Where("is_video = ? AND file_type = ? AND "+column+" IN ?", true, v115open.TypeFile, batch).Find(&files).Error; err != nil {
+				report.Error = fmt.Sprintf("查询重复文件失败: %v", err)
+				return report
+			}
+			for _, file := range files {
+				copies = append(copies, newDuplicateCopy(file))
+			}
+		}
+	}
+	// 2. 已刮削的文件，包括没有哈希的来源，例如本地、WebDAV、OpenList
+	mediaByFileId := getDuplicateMediaByFileId()
+	fileIds := make([]string, 0, len(mediaByFileId))
+	for fileId := range mediaByFileId {
+		fileIds = append(fileIds, fileId)
+	}
+	for batch := range slices.Chunk(fileIds, duplicateQueryBatch) {
+		var files []*SyncFile
+		if err := db.Db.Where("is_video = ? AND file_type = ? AND file_id IN ?", true, v115open.TypeFile, batch).Find(&files).Error; err != nil {
+			report.Error = fmt.Sprintf("查询已刮削的文件失败: %v", err)
+			return report
+		}
+		for _, file := range files {
+			c := newDuplicateCopy(file)
+			media := mediaByFileId[file.FileId]
+			c.TmdbId, c.MediaKey = media.tmdbId, media.mediaKey
+			copies = append(copies, c)
+		}
+	}
+	report.Groups = GroupDuplicateCopies(copies)
+	// 填充账号名称
+	accountNames := make(map[uint]string)
+	if accounts, err := GetAllAccount(); err == nil {
+		for _, account := range accounts {
+			accountNames[account.ID] = account.Name
+		}
+	}
+	for _, group := range report.Groups {
+		for _, c := range group.Copies {
+			c.AccountName = accountNames[c.AccountId]
+		}
+	}
+	report.Summarize()
+	return report
+}
+
+// GetSyncFilesByFileId 查询同一个网盘文件在所有同步目录中的记录
+func GetSyncFilesByFileId(accountId uint, fileId string) []*SyncFile {
+	var files []*SyncFile
+	db.Db.Where("account_id = ? AND file_id = ?", accountId, fileId).Find(&files)
+	return files
+}
+
+// DeleteSyncFilesByFileId 网盘文件删除后删除所有同步目录中对应的记录
+func DeleteSyncFilesByFileId(accountId uint, fileId string) error {
+	return db.Db.Where("account_id = ? AND file_id = ?", accountId, fileId).Delete(&SyncFile{}).Error
+}
@@ -0,0 +1,109 @@
+package models
+
+import "testing"
+
+func TestGroupDuplicateCopies(t *testing.T) {
+	copies := []*DuplicateCopy{
+		// 115两个账号中SHA1相同的文件
+		{SyncFileId: 1, AccountId: 1, FileId: "a", FileSize: 100, Sha1: "SHA"},
+		{SyncFileId: 2, AccountId: 2, FileId: "b", FileSize: 100, Sha1: "SHA", MediaKey: "movie-603", TmdbId: 603},
+		// 同一个文件被两个同步目录同步，不算重复
+		{SyncFileId: 3, AccountId: 2, FileId: "b", FileSize: 100, Sha1: "SHA"},
+		// 本地没有哈希，大小和电影相同
+		{SyncFileId: 4, AccountId: 3, FileId: "/movies/a.mkv", FileSize: 100, MediaKey: "movie-603", TmdbId: 603},
+		// 同一部电影但是大小不同，不是重复文件
+		{SyncFileId: 5, AccountId: 3, FileId: "/movies/b.mkv", FileSize: 200, MediaKey: "movie-603", TmdbId: 603},
+		// 只有一个文件的哈希
+		{SyncFileId: 6, AccountId: 1, FileId: "c", FileSize: 50, Sha1: "OTHER"},
+	}
+	groups := GroupDuplicateCopies(copies)
+	if len(groups) != 1 {
+		t.Fatalf("groups = %d, want 1", len(groups))
+	}
+	group := groups[0]
+	if len(group.Copies) != 3 || group.WastedSize != 200 || group.TmdbId != 603 || len(group.MatchBy) != 2 {
+		t.Fatalf("group = %+v", group)
+	}
+	report := &DuplicateReport{Groups: groups}
+	report.Summarize()
+	if report.TotalWasted != 200 || len(report.Accounts) != 3 || report.Accounts[0].WastedSize != 100 {
+		t.Fatalf("summary = %+v", report.Accounts)
+	}
+
+	if _, err := report.PlanDuplicateDelete([]uint{1, 2, 4}); err == nil {
+		t.Errorf("删除全部副本应该返回错误")
+	}
+	if _, err := report.PlanDuplicateDelete([]uint{5}); err == nil {
+		t.Errorf("不在扫描结果中的文件应该返回错误")
+	}
+	plan, err := report.PlanDuplicateDelete([]uint{2, 4, 2})
+	if err != nil || len(plan) != 2 {
+		t.Fatalf("plan = %v, %v", plan, err)
+	}
+	report.RemoveCopy(2)
+	if report.TotalWasted != 100 || len(report.Groups[0].Copies) != 2 {
+		t.Errorf("RemoveCopy 后 = %+v", report.Groups[0])
+	}
+	report.RemoveCopy(4)
+	if len(report.Groups) != 0 || report.TotalWasted != 0 {
+		t.Errorf("只剩一个副本时应该移除整组")
+	}
+}
+
+func TestGroupDuplicateCopiesMd5(t *testing.T) {
+	copies := []*DuplicateCopy{
+		// 123云盘两个账号中MD5相同的文件
+		{SyncFileId: 1, AccountId: 1, FileId: "1", FileSize: 100, Md5: "HASH"},
+		{SyncFileId: 2, AccountId: 2, FileId: "2", FileSize: 100, Md5: "HASH"},
+		// SHA1和MD5的值相同也不能归为一组
+		{SyncFileId: 3, AccountId: 3, FileId: "c", FileSize: 100, Sha1: "HASH"},
+	}
+	groups := GroupDuplicateCopies(copies)
+	if len(groups) != 1 || len(groups[0].Copies) != 2 || len(groups[0].MatchBy) != 1 {
+		t.Fatalf("groups = %+v", groups)
+	}
+	for _, c := range groups[0].Copies {
+		if c.Sha1 != "" {
+			t.Errorf("SHA1文件不应该和MD5文件归为一组: %+v", c)
+		}
+	}
+}
+
+func TestGetDuplicateMediaByFileId(t *testing.T) {
+	conn := openModelsTestDb(t, &Media{})
+	// Media和MediaEpisode的索引同名，sqlite中只创建查询用到的剧集字段
+	type episode struct {
+		ID            uint `gorm:"primarykey"`
+		MediaId       uint
+		SeasonNumber  int
+		EpisodeNumber int
+		VideoFileId   string
+	}
+	if err := conn.Table("media_episodes").AutoMigrate(&episode{}); err != nil {
+		t.Fatalf("创建表失败: %v", err)
+	}
+	// TMDB和TVDB的ID相同，但不是同一部作品
+	conn.Create(&Media{MediaType: MediaTypeMovie, TmdbId: 603, VideoFileId: "a"})
+	conn.Create(&Media{MediaType: MediaTypeMovie, TmdbId: 603, VideoFileId: "b", MetadataProvider: MetadataProviderTvdb})
+	tmdbShow := &Media{MediaType: MediaTypeTvShow, TmdbId: 1399}
+	tvdbShow := &Media{MediaType: MediaTypeTvShow, TmdbId: 1399, MetadataProvider: MetadataProviderTvdb}
+	conn.Create(tmdbShow)
+	conn.Create(tvdbShow)
+	conn.Table("media_episodes").Create(&episode{MediaId: tmdbShow.ID, SeasonNumber: 1, EpisodeNumber: 1, VideoFileId: "c"})
+	conn.Table("media_episodes").Create(&episode{MediaId: tvdbShow.ID, SeasonNumber: 1, EpisodeNumber: 1, VideoFileId: "d"})
+	want := map[string]duplicateMedia{
+		"a": {tmdbId: 603, mediaKey: "movie-603"},
+		"b": {mediaKey: "tvdb-movie-603"},
+		"c": {tmdbId: 1399, mediaKey: "tv-1399-s1e1"},
+		"d": {mediaKey: "tvdb-tv-1399-s1e1"},
+	}
+	got := getDuplicateMediaByFileId()
+	if len(got) != len(want) {
+		t.Fatalf("getDuplicateMediaByFileId = %+v", got)
+	}
+	for fileId, media := range want {
+		if got[fileId] != media {
+			t.Errorf("文件 %s 的媒体标识 = %+v, want %+v", fileId, got[fileId], media)
+		}
+	}
+}
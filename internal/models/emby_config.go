@@ -7,6 +7,7 @@ type EmbyConfig struct {
 	BaseModel
 	EmbyUrl                 string `json:"emby_url" gorm:"type:varchar(500)"`
 	EmbyApiKey              string `json:"emby_api_key" gorm:"type:varchar(200)"`
+	ServerType              string `json:"server_type" gorm:"type:varchar(20);default:'emby'"` // 媒体服务器类型：emby、jellyfin，决定302代理使用的接口格式
 	EnableDeleteNetdisk     int    `json:"enable_delete_netdisk" gorm:"default:0"`
 	EnableRefreshLibrary    int    `json:"enable_refresh_library" gorm:"default:0"`
 	EnableMediaNotification int    `json:"enable_media_notification" gorm:"default:0"`
@@ -15,6 +16,10 @@ type EmbyConfig struct {
 	SyncEnabled             int    `json:"sync_enabled" gorm:"default:1"`
 	SyncCron                string `json:"sync_cron" gorm:"type:varchar(100);default:'*/5 * * * *'"`
 	LastSyncTime            int64  `json:"last_sync_time" gorm:"default:0"`
+	// 联动删除网盘文件的延迟（分钟），到期前可以取消
+	DeleteNetdiskDelay int `json:"delete_netdisk_delay" gorm:"default:60"`
+	// 115网盘回收目录，不为空时联动删除改为移动到该目录，不调用删除接口
+	DeleteNetdiskTrashPath string `json:"delete_netdisk_trash_path" gorm:"type:varchar(500);default:''"`
 }
 
 func (*EmbyConfig) TableName() string {
@@ -6,7 +6,10 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/openlist"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
+	"errors"
+	"fmt"
 	"path/filepath"
 	"strings"
 	"time"
@@ -105,6 +108,8 @@ func GetEmbyMediaItemsCount() (int64, error) {
 }
 
 func CleanupOrphanedEmbyMediaItems(validItemIds []string) error {
+	// 等待联动删除的媒体项需要保留，执行删除时还要用到
+	validItemIds = append(validItemIds, getPendingDeletionEmbyItemIds()...)
 	if len(validItemIds) == 0 {
 		return db.Db.Where("1 = 1").Delete(&EmbyMediaItem{}).Error
 	}
@@ -311,6 +316,16 @@ func DeleteNetdiskMovieByEmbyItemId(itemId string) error {
 			// 删除视频文件+元数据
 			success, delErr = deleteOpenListFiles(client, syncFile, metaFiles)
 		}
+	case SourceTypeWebDav:
+		// 执行WebDAV删除逻辑
+		client := account.GetWebDavClient()
+		if videoFileCount == 1 {
+			// 删除目录
+			success, delErr = deleteWebDavFolders(client, syncFile.Path)
+		} else {
+			// 删除视频文件+元数据
+			success, delErr = deleteWebDavFiles(client, syncFile, metaFiles)
+		}
 
 	}
 	if delErr != nil {
@@ -377,6 +392,9 @@ func DeleteNetdiskEpisodeByEmbyItemId(itemId string) error {
 		// 执行OpenList网盘删除逻辑
 		client := account.GetOpenListClient()
 		success, delErr = deleteOpenListFiles(client, syncFile, filesToDelete)
+	case SourceTypeWebDav:
+		client := account.GetWebDavClient()
+		success, delErr = deleteWebDavFiles(client, syncFile, filesToDelete)
 	}
 	if delErr != nil {
 		helpers.AppLogger.Errorf("删除Emby Item %s 关联的网盘集视频文件+元数据失败: %v", itemId, delErr)
@@ -447,6 +465,9 @@ func DeleteNetdiskSeasonByItemId(itemId string) error {
 		case SourceTypeOpenList:
 			client := account.GetOpenListClient()
 			_, delErr = deleteOpenListFolders(client, seasonPath)
+		case SourceTypeWebDav:
+			client := account.GetWebDavClient()
+			_, delErr = deleteWebDavFolders(client, seasonPath)
 		}
 		// // 查询path的file_id
 		// path := Sync115Path{}
@@ -566,6 +587,9 @@ func DeleteNetdiskTvshowByItemId(itemId string) error {
 	case SourceTypeOpenList:
 		client := account.GetOpenListClient()
 		_, delErr = deleteOpenListFolders(client, tvshowPath)
+	case SourceTypeWebDav:
+		client := account.GetWebDavClient()
+		_, delErr = deleteWebDavFolders(client, tvshowPath)
 	}
 	if delErr != nil {
 		helpers.AppLogger.Errorf("删除Emby Item %s 关联的网盘电视剧 目录 %s=>%s失败: %v", itemId, tvshowPathId, tvshowPath, delErr)
@@ -592,7 +616,7 @@ func delete115Files(client *v115open.OpenClient, syncFile SyncFile, metaFiles []
 	for _, mf := range metaFiles {
 		fileIdsToDelete = append(fileIdsToDelete, mf.FileId)
 	}
-	success, delErr := client.Del(context.Background(), fileIdsToDelete, syncFile.ParentId)
+	success, delErr := remove115Files(client, fileIdsToDelete, syncFile.ParentId)
 	return success, delErr
 }
 
@@ -631,7 +655,7 @@ func delete115Folders(client *v115open.OpenClient, delPath string, syncPathId ui
 		helpers.AppLogger.Errorf("查询网盘路径 %s 失败: %v", delPath, err)
 		return false, err
 	}
-	success, delErr := client.Del(context.Background(), []string{path.FileId}, pathParentId)
+	success, delErr := remove115Files(client, []string{path.FileId}, pathParentId)
 	if delErr != nil {
 		return success, delErr
 	}
@@ -639,6 +663,56 @@ func delete115Folders(client *v115open.OpenClient, delPath string, syncPathId ui
 	return success, delErr
 }
 
+// 删除115网盘文件或目录，配置了回收目录时改为移动到回收目录，方便找回
+func remove115Files(client *v115open.OpenClient, fileIds []string, parentId string) (bool, error) {
+	trashPath := ""
+	if GlobalEmbyConfig != nil {
+		trashPath = GlobalEmbyConfig.DeleteNetdiskTrashPath
+	}
+	if trashPath == "" {
+		return client.Del(context.Background(), fileIds, parentId)
+	}
+	trashId, err := get115TrashDirId(client, trashPath)
+	if err != nil {
+		return false, fmt.Errorf("获取115回收目录 %s 失败: %v", trashPath, err)
+	}
+	success, err := client.Move(context.Background(), fileIds, trashId)
+	if err == nil {
+		helpers.AppLogger.Infof("已将115网盘文件 %v 移动到回收目录 %s", fileIds, trashPath)
+	}
+	return success, err
+}
+
+// 查询115回收目录的ID，不存在时从根目录开始逐级创建
+func get115TrashDirId(client *v115open.OpenClient, trashPath string) (string, error) {
+	trashPath = strings.Trim(strings.ReplaceAll(trashPath, "\\", "/"), "/")
+	if trashPath == "" {
+		return "", fmt.Errorf("回收目录不能是根目录")
+	}
+	currentPath := ""
+	currentId := "0"
+	for _, p := range strings.Split(trashPath, "/") {
+		if p == "" {
+			continue
+		}
+		currentPath = currentPath + "/" + p
+		detail, err := client.GetFsDetailByPath(context.Background(), currentPath)
+		if err == nil && detail != nil && detail.FileId != "" {
+			currentId = detail.FileId
+			continue
+		}
+		newId, mErr := client.MkDir(context.Background(), currentId, p)
+		if mErr != nil {
+			return "", mErr
+		}
+		if newId == "" {
+			return "", fmt.Errorf("创建目录 %s 失败", currentPath)
+		}
+		currentId = newId
+	}
+	return currentId, nil
+}
+
 func deleteOpenListFiles(client *openlist.Client, syncFile SyncFile, metaFiles []SyncFile) (bool, error) {
 	fileNameToDelete := []string{syncFile.FileName}
 	for _, mf := range metaFiles {
@@ -665,3 +739,27 @@ func deleteOpenListFolders(client *openlist.Client, path string) (bool, error) {
 	}
 	return true, nil
 }
+
+func deleteWebDavFiles(client *webdav.Client, syncFile SyncFile, metaFiles []SyncFile) (bool, error) {
+	filesToDelete := append([]SyncFile{syncFile}, metaFiles...)
+	for _, f := range filesToDelete {
+		err := client.Delete(context.Background(), filepath.ToSlash(filepath.Join(f.Path, f.FileName)))
+		if err != nil && !errors.Is(err, webdav.ErrNotFound) {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func deleteWebDavFolders(client *webdav.Client, path string) (bool, error) {
+	if path == "" || path == "." || path == "/" {
+		// 到了根目录，不能删除
+		helpers.AppLogger.Errorf("删除网盘目录失败: 已到达根目录 %s", path)
+		return false, nil
+	}
+	err := client.Delete(context.Background(), path)
+	if err != nil && !errors.Is(err, webdav.ErrNotFound) {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,196 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/notificationmanager"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 待删除记录的状态
+type PendingDeletionStatus string
+
+const (
+	PendingDeletionStatusPending   PendingDeletionStatus = "pending"   // 等待执行
+	PendingDeletionStatusRunning   PendingDeletionStatus = "running"   // 执行中
+	PendingDeletionStatusCancelled PendingDeletionStatus = "cancelled" // 已取消
+	PendingDeletionStatusDone      PendingDeletionStatus = "done"      // 已删除
+	PendingDeletionStatusFailed    PendingDeletionStatus = "failed"    // 删除失败
+)
+
+// Emby删除媒体后等待联动删除网盘文件的记录，到期前可以取消
+type EmbyPendingDeletion struct {
+	BaseModel
+	ItemId    string                `json:"item_id" gorm:"index"`
+	ItemType  string                `json:"item_type"` // Movie、Episode、Season、Series
+	ItemName  string                `json:"item_name"`
+	ExecuteAt int64                 `json:"execute_at" gorm:"index"` // 计划执行时间（秒级时间戳）
+	Status    PendingDeletionStatus `json:"status" gorm:"index"`
+	Error     string                `json:"error"`
+	FinishAt  int64                 `json:"finish_at"`
+}
+
+func (*EmbyPendingDeletion) TableName() string {
+	return "emby_pending_deletions"
+}
+
+var processPendingDeletionLock sync.Mutex
+
+// 添加联动删除计划，同一个媒体项已经在等待时直接返回已有记录
+func ScheduleNetdiskDeletion(itemId, itemType, itemName string) (*EmbyPendingDeletion, error) {
+	existing := &EmbyPendingDeletion{}
+	if err := db.Db.Where("item_id = ? AND status = ?", itemId, PendingDeletionStatusPending).Limit(1).Find(existing).Error; err != nil {
+		return nil, err
+	}
+	if existing.ID > 0 {
+		return existing, nil
+	}
+	delay := 0
+	if GlobalEmbyConfig != nil && GlobalEmbyConfig.DeleteNetdiskDelay > 0 {
+		delay = GlobalEmbyConfig.DeleteNetdiskDelay
+	}
+	pending := &EmbyPendingDeletion{
+		ItemId:    itemId,
+		ItemType:  itemType,
+		ItemName:  itemName,
+		ExecuteAt: time.Now().Add(time.Duration(delay) * time.Minute).Unix(),
+		Status:    PendingDeletionStatusPending,
+	}
+	if err := db.Db.Create(pending).Error; err != nil {
+		return nil, err
+	}
+	helpers.AppLogger.Infof("Emby Item %s (%s) 的网盘文件将在 %d 分钟后删除", itemId, itemName, delay)
+	pending.notify(delay)
+	return pending, nil
+}
+
+// 发送待删除通知，有延迟时提醒用户可以在到期前取消，没有延迟时下一分钟就会执行
+func (p *EmbyPendingDeletion) notify(delay int) {
+	action := "删除"
+	if GlobalEmbyConfig != nil && GlobalEmbyConfig.DeleteNetdiskTrashPath != "" {
+		action = "移动到回收目录（仅115网盘）或删除"
+	}
+	title := "⏳ 网盘文件待删除通知"
+	tip := "到期前可以在待删除列表中取消"
+	if delay <= 0 {
+		title = "🗑️ 网盘文件即将删除通知"
+		tip = "没有设置删除延迟，将在下一分钟执行"
+	}
+	notif := &Notification{
+		Type:      MediaRemoved,
+		Title:     title,
+		Content:   fmt.Sprintf("媒体名称：%s\n类型：%s\n操作：%s\n⏰ 执行时间: %s\n%s", p.ItemName, p.ItemType, action, time.Unix(p.ExecuteAt, 0).Format("2006-01-02 15:04:05"), tip),
+		Timestamp: time.Now(),
+		Priority:  HighPriority,
+	}
+	if notificationmanager.GlobalEnhancedNotificationManager != nil {
+		if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(context.Background(), notif); err != nil {
+			helpers.AppLogger.Errorf("发送网盘文件待删除通知失败: %s (%s) 错误:%v", p.ItemId, p.ItemName, err)
+		}
+	}
+}
+
+// 分页查询待删除记录，status为空时查询全部
+func GetEmbyPendingDeletionList(status string, page, pageSize int) ([]*EmbyPendingDeletion, int64, error) {
+	query := db.Db.Model(&EmbyPendingDeletion{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var list []*EmbyPendingDeletion
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	return list, total, err
+}
+
+// 取消等待中的删除计划，返回取消的数量
+func CancelEmbyPendingDeletions(ids []uint) (int64, error) {
+	result := db.Db.Model(&EmbyPendingDeletion{}).Where("id IN ? AND status = ?", ids, PendingDeletionStatusPending).Updates(map[string]any{
+		"status":    PendingDeletionStatusCancelled,
+		"finish_at": time.Now().Unix(),
+	})
+	return result.RowsAffected, result.Error
+}
+
+// 执行已经到期的删除计划，由定时任务调用
+func ProcessDueNetdiskDeletions() {
+	if !processPendingDeletionLock.TryLock() {
+		return
+	}
+	defer processPendingDeletionLock.Unlock()
+	var list []*EmbyPendingDeletion
+	if err := db.Db.Where("status = ? AND execute_at <= ?", PendingDeletionStatusPending, time.Now().Unix()).Order("id ASC").Find(&list).Error; err != nil {
+		helpers.AppLogger.Errorf("查询到期的网盘删除计划失败: %v", err)
+		return
+	}
+	for _, p := range list {
+		// 前面的删除执行期间这条计划可能已经被取消，只执行还在等待中的
+		if !p.claim() {
+			continue
+		}
+		if GlobalEmbyConfig == nil || GlobalEmbyConfig.EnableDeleteNetdisk != 1 {
+			// 联动删除已关闭，不再执行
+			p.finish(PendingDeletionStatusCancelled, "联动删除网盘文件已关闭")
+			continue
+		}
+		var err error
+		switch p.ItemType {
+		case "Movie":
+			err = DeleteNetdiskMovieByEmbyItemId(p.ItemId)
+		case "Episode":
+			err = DeleteNetdiskEpisodeByEmbyItemId(p.ItemId)
+		case "Season":
+			err = DeleteNetdiskSeasonByItemId(p.ItemId)
+		case "Series":
+			err = DeleteNetdiskTvshowByItemId(p.ItemId)
+		default:
+			err = fmt.Errorf("不支持的媒体类型 %s", p.ItemType)
+		}
+		if err != nil {
+			p.finish(PendingDeletionStatusFailed, err.Error())
+			continue
+		}
+		p.finish(PendingDeletionStatusDone, "")
+	}
+}
+
+// 将等待中的计划标记为执行中，已经被取消或者被其他地方执行时返回false
+func (p *EmbyPendingDeletion) claim() bool {
+	result := db.Db.Model(&EmbyPendingDeletion{}).Where("id = ? AND status = ?", p.ID, PendingDeletionStatusPending).Update("status", PendingDeletionStatusRunning)
+	if result.Error != nil {
+		helpers.AppLogger.Errorf("标记网盘删除计划 %d 为执行中失败: %v", p.ID, result.Error)
+		return false
+	}
+	if result.RowsAffected == 0 {
+		return false
+	}
+	p.Status = PendingDeletionStatusRunning
+	return true
+}
+
+func (p *EmbyPendingDeletion) finish(status PendingDeletionStatus, reason string) {
+	p.Status = status
+	p.Error = reason
+	p.FinishAt = time.Now().Unix()
+	db.Db.Model(p).Updates(map[string]any{
+		"status":    p.Status,
+		"error":     p.Error,
+		"finish_at": p.FinishAt,
+	})
+}
+
+// 等待删除的媒体项以及其下的季、集，Emby同步清理时需要保留
+func getPendingDeletionEmbyItemIds() []string {
+	var pendingIds []string
+	if err := db.Db.Model(&EmbyPendingDeletion{}).Where("status IN ?", []PendingDeletionStatus{PendingDeletionStatusPending, PendingDeletionStatusRunning}).Pluck("item_id", &pendingIds).Error; err != nil || len(pendingIds) == 0 {
+		return nil
+	}
+	var itemIds []string
+	db.Db.Model(&EmbyMediaItem{}).Where("item_id IN ? OR season_id IN ? OR series_id IN ?", pendingIds, pendingIds, pendingIds).Pluck("item_id", &itemIds)
+	return itemIds
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// 使用临时的sqlite数据库和日志文件，测试结束后恢复
+func openPendingDeletionTestDb(t *testing.T) {
+	t.Helper()
+	dir := t.TempDir()
+	conn, err := gorm.Open(sqlite.Open(filepath.Join(dir, "test.db")), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	if err := conn.AutoMigrate(&EmbyPendingDeletion{}); err != nil {
+		t.Fatalf("创建表失败: %v", err)
+	}
+	oldDb, oldConfigDir, oldLogger, oldConfig := db.Db, helpers.ConfigDir, helpers.AppLogger, GlobalEmbyConfig
+	db.Db = conn
+	helpers.ConfigDir = dir
+	helpers.AppLogger = helpers.NewLogger("test.log", false, false)
+	t.Cleanup(func() {
+		db.Db, helpers.ConfigDir, helpers.AppLogger, GlobalEmbyConfig = oldDb, oldConfigDir, oldLogger, oldConfig
+	})
+}
+
+func TestScheduleNetdiskDeletionDelay(t *testing.T) {
+	openPendingDeletionTestDb(t)
+	GlobalEmbyConfig = &EmbyConfig{EnableDeleteNetdisk: 1, DeleteNetdiskDelay: 30}
+	pending, err := ScheduleNetdiskDeletion("100", "Movie", "电影")
+	if err != nil {
+		t.Fatalf("添加删除计划失败: %v", err)
+	}
+	if wait := pending.ExecuteAt - time.Now().Unix(); wait < 29*60 || wait > 30*60 {
+		t.Errorf("执行时间应该在30分钟后，实际 %d 秒", wait)
+	}
+	again, err := ScheduleNetdiskDeletion("100", "Movie", "电影")
+	if err != nil || again.ID != pending.ID {
+		t.Errorf("同一个媒体项等待中时应该返回已有记录: %+v %v", again, err)
+	}
+	// 未到期的计划不会执行
+	ProcessDueNetdiskDeletions()
+	if list, total, _ := GetEmbyPendingDeletionList(string(PendingDeletionStatusPending), 1, 10); total != 1 || list[0].ID != pending.ID {
+		t.Errorf("未到期的计划应该保持等待: %d", total)
+	}
+	count, err := CancelEmbyPendingDeletions([]uint{pending.ID})
+	if err != nil || count != 1 {
+		t.Fatalf("取消删除计划失败: %d %v", count, err)
+	}
+	if count, _ := CancelEmbyPendingDeletions([]uint{pending.ID}); count != 0 {
+		t.Errorf("已取消的计划不能重复取消")
+	}
+	if _, total, _ := GetEmbyPendingDeletionList(string(PendingDeletionStatusCancelled), 1, 10); total != 1 {
+		t.Errorf("取消后的状态错误")
+	}
+}
+
+func TestScheduleNetdiskDeletionImmediate(t *testing.T) {
+	openPendingDeletionTestDb(t)
+	// 没有设置延迟时立即到期，联动删除关闭后执行时会取消
+	GlobalEmbyConfig = &EmbyConfig{EnableDeleteNetdisk: 0}
+	pending, err := ScheduleNetdiskDeletion("200", "Episode", "剧集")
+	if err != nil {
+		t.Fatalf("添加删除计划失败: %v", err)
+	}
+	if pending.ExecuteAt > time.Now().Unix() {
+		t.Errorf("没有延迟时应该立即到期")
+	}
+	ProcessDueNetdiskDeletions()
+	list, total, _ := GetEmbyPendingDeletionList(string(PendingDeletionStatusCancelled), 1, 10)
+	if total != 1 || list[0].Error == "" {
+		t.Errorf("联动删除关闭后应该取消计划: %d", total)
+	}
+}
+
+func TestClaimPendingDeletion(t *testing.T) {
+	openPendingDeletionTestDb(t)
+	GlobalEmbyConfig = &EmbyConfig{EnableDeleteNetdisk: 0}
+	first, _ := ScheduleNetdiskDeletion("300", "Movie", "电影1")
+	second, _ := ScheduleNetdiskDeletion("301", "Movie", "电影2")
+	if !first.claim() {
+		t.Fatalf("等待中的计划应该可以标记为执行中")
+	}
+	if first.claim() {
+		t.Errorf("执行中的计划不能重复执行")
+	}
+	// 执行期间取消的计划不再执行，也不会被覆盖为其他状态
+	if n, _ := CancelEmbyPendingDeletions([]uint{first.ID, second.ID}); n != 1 {
+		t.Errorf("只能取消等待中的计划，实际取消 %d 条", n)
+	}
+	if second.claim() {
+		t.Errorf("已取消的计划不能执行")
+	}
+	ProcessDueNetdiskDeletions()
+	list, total, _ := GetEmbyPendingDeletionList(string(PendingDeletionStatusCancelled), 1, 10)
+	if total != 1 || list[0].ID != second.ID || list[0].Error != "" {
+		t.Errorf("已取消的计划状态被修改: %+v", list)
+	}
+}
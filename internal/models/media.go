@@ -23,6 +23,7 @@ type Media struct {
 	BaseModel
 	ScrapePathId        uint               `json:"scrape_path_id" gorm:"index:scrapepathid"` // 刮削路径ID
 	TmdbId              int64              `json:"tmdb_id" gorm:"index:tmdbid"`              // TMDB ID
+	MetadataProvider    string             `json:"metadata_provider" gorm:"default:'tmdb'"`  // 元数据提供者，TmdbId字段保存的是该提供者中的ID
 	ImdbId              string             `json:"imdb_id"`                                  // IMDB ID
 	Name                string             `json:"name" gorm:"index:nameyear"`               // TMDB名称
 	Year                int                `json:"year" gorm:"index:nameyear"`               // 年份
@@ -194,6 +195,29 @@ func (me *MediaEpisode) DecodeJson() {
 	}
 }
 
+// TMDB返回的是图片的相对路径，TVDB、Bangumi等返回的是完整的图片地址
+func tmdbImageUrl(path string) string {
+	if strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://") {
+		return path
+	}
+	return fmt.Sprintf("%s/t/p/original%s", GlobalScrapeSettings.GetTmdbImageUrl(), path)
+}
+
+func (m *Media) GetMetadataProvider() string {
+	if m.MetadataProvider == "" {
+		return MetadataProviderTmdb
+	}
+	return m.MetadataProvider
+}
+
+// nfo中的tmdbid，非TMDB的元数据不填写，避免媒体服务器误用
+func (m *Media) GetNfoTmdbId() int64 {
+	if m.GetMetadataProvider() != MetadataProviderTmdb {
+		return 0
+	}
+	return m.TmdbId
+}
+
 func (m *Media) FillInfoByTmdbInfo(tmdbInfo *TmdbInfo) {
 	if m.MediaType == MediaTypeTvShow {
 		m.TmdbId = tmdbInfo.TvShowDetail.ID
@@ -237,15 +261,18 @@ func (m *Media) FillInfoByTmdbInfo(tmdbInfo *TmdbInfo) {
 	if tmdbInfo.Credits != nil && tmdbInfo.Credits.Cast != nil {
 		for _, actor := range tmdbInfo.Credits.Cast {
 			act := helpers.Actor{
-				Name:    actor.Name,
-				Role:    actor.Character,
-				TmdbId:  actor.ID,
-				Order:   actor.Order,
-				Thumb:   "",
-				Profile: fmt.Sprintf("https://www.themoviedb.org/person/%d", actor.ID),
+				Name:   actor.Name,
+				Role:   actor.Character,
+				TmdbId: actor.ID,
+				Order:  actor.Order,
+				Thumb:  "",
+			}
+			// 非TMDB的元数据没有TMDB的人员ID
+			if actor.ID > 0 {
+				act.Profile = fmt.Sprintf("https://www.themoviedb.org/person/%d", actor.ID)
 			}
 			if actor.ProfilePath != "" {
-				act.Thumb = fmt.Sprintf("%s/t/p/original%s", GlobalScrapeSettings.GetTmdbImageUrl(), actor.ProfilePath)
+				act.Thumb = tmdbImageUrl(actor.ProfilePath)
 			}
 			actors = append(actors, act)
 		}
@@ -268,7 +295,7 @@ func (m *Media) FillInfoByTmdbInfo(tmdbInfo *TmdbInfo) {
 	if tmdbInfo.Images != nil && len(tmdbInfo.Images.Posters) > 0 {
 		for _, poster := range tmdbInfo.Images.Posters {
 			if poster.FilePath != "" {
-				m.PosterPath = fmt.Sprintf("%s/t/p/original%s", GlobalScrapeSettings.GetTmdbImageUrl(), poster.FilePath)
+				m.PosterPath = tmdbImageUrl(poster.FilePath)
 				break
 			}
 		}
@@ -276,7 +303,7 @@ func (m *Media) FillInfoByTmdbInfo(tmdbInfo *TmdbInfo) {
 	if tmdbInfo.Images != nil && len(tmdbInfo.Images.Backdrops) > 0 {
 		for _, backdrop := range tmdbInfo.Images.Backdrops {
 			if backdrop.FilePath != "" {
-				m.BackdropPath = fmt.Sprintf("%s/t/p/original%s", GlobalScrapeSettings.GetTmdbImageUrl(), backdrop.FilePath)
+				m.BackdropPath = tmdbImageUrl(backdrop.FilePath)
 				break
 			}
 		}
@@ -284,7 +311,7 @@ func (m *Media) FillInfoByTmdbInfo(tmdbInfo *TmdbInfo) {
 	if tmdbInfo.Images != nil && len(tmdbInfo.Images.Logos) > 0 {
 		for _, logo := range tmdbInfo.Images.Logos {
 			if logo.FilePath != "" {
-				m.LogoPath = fmt.Sprintf("%s/t/p/original%s", GlobalScrapeSettings.GetTmdbImageUrl(), logo.FilePath)
+				m.LogoPath = tmdbImageUrl(logo.FilePath)
 				break
 			}
 		}
@@ -300,7 +327,7 @@ func (ms *MediaSeason) FillInfoByTmdbInfo(seasonDetail *tmdb.SeasonDetail) {
 	}
 	ms.SeasonName = seasonDetail.Name
 	ms.Overview = seasonDetail.Overview
-	ms.PosterPath = fmt.Sprintf("%s/t/p/original%s", GlobalScrapeSettings.GetTmdbImageUrl(), seasonDetail.PosterPath)
+	ms.PosterPath = tmdbImageUrl(seasonDetail.PosterPath)
 	ms.ReleaseDate = seasonDetail.AirDate
 	ms.VoteAverage = seasonDetail.VoteAverage
 	ms.Year = helpers.ParseYearFromDate(ms.ReleaseDate)
@@ -314,7 +341,7 @@ func (me *MediaEpisode) FillInfoByTmdbInfo(episodeDetail *tmdb.Episode) {
 	}
 	me.EpisodeName = episodeDetail.Name
 	me.Overview = episodeDetail.Overview
-	me.PosterPath = fmt.Sprintf("%s/t/p/original%s", GlobalScrapeSettings.GetTmdbImageUrl(), episodeDetail.StillPath)
+	me.PosterPath = tmdbImageUrl(episodeDetail.StillPath)
 	me.ReleaseDate = episodeDetail.AirDate
 	me.VoteAverage = episodeDetail.VoteAverage
 	me.VoteCount = episodeDetail.VoteCount
@@ -324,15 +351,18 @@ func (me *MediaEpisode) FillInfoByTmdbInfo(episodeDetail *tmdb.Episode) {
 	if len(episodeDetail.Cast) > 0 {
 		for _, actor := range episodeDetail.Cast {
 			act := helpers.Actor{
-				Name:    actor.Name,
-				Role:    actor.Character,
-				TmdbId:  actor.ID,
-				Order:   actor.Order,
-				Thumb:   "",
-				Profile: fmt.Sprintf("https://www.themoviedb.org/person/%d", actor.ID),
+				Name:   actor.Name,
+				Role:   actor.Character,
+				TmdbId: actor.ID,
+				Order:  actor.Order,
+				Thumb:  "",
+			}
+			// 非TMDB的元数据没有TMDB的人员ID
+			if actor.ID > 0 {
+				act.Profile = fmt.Sprintf("https://www.themoviedb.org/person/%d", actor.ID)
 			}
 			if actor.ProfilePath != "" {
-				act.Thumb = fmt.Sprintf("%s/t/p/original%s", GlobalScrapeSettings.GetTmdbImageUrl(), actor.ProfilePath)
+				act.Thumb = tmdbImageUrl(actor.ProfilePath)
 			}
 			actors = append(actors, act)
 		}
@@ -374,9 +404,9 @@ func GetMediaEpisodeById(id uint) (*MediaEpisode, error) {
 	return &mediaEpisode, nil
 }
 
-func GetMediaByTmdbId(tmdbId int64) (*Media, error) {
+func GetMediaByTmdbId(provider string, tmdbId int64) (*Media, error) {
 	var media Media
-	if err := db.Db.Where("tmdb_id = ?", tmdbId).First(&media).Error; err != nil {
+	if err := db.Db.Scopes(WhereTmdbId(provider, tmdbId)).First(&media).Error; err != nil {
 		return nil, err
 	}
 	// 解码JSON字符串
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+)
+
+// 监控指标使用的统计查询
+
+// GetLatestSyncPerPath 每个同步目录最后一次结束（完成或失败）的同步任务，键为同步目录ID
+func GetLatestSyncPerPath() map[uint]*Sync {
+	var syncs []*Sync
+	latest := db.Db.Model(&Sync{}).
+		Select("MAX(id)").
+		Where("status IN (?, ?)", SyncStatusCompleted, SyncStatusFailed).
+		Group("sync_path_id")
+	db.Db.Where("id IN (?)", latest).Find(&syncs)
+	result := make(map[uint]*Sync, len(syncs))
+	for _, s := range syncs {
+		result[s.SyncPathId] = s
+	}
+	return result
+}
+
+// CountSyncFailuresPerPath 每个同步目录保留的同步记录中失败的次数
+func CountSyncFailuresPerPath() map[uint]int64 {
+	var rows []struct {
+		SyncPathId uint
+		Count      int64
+	}
+	db.Db.Model(&Sync{}).
+		Select("sync_path_id, COUNT(*) AS count").
+		Where("status = ?", SyncStatusFailed).
+		Group("sync_path_id").
+		Scan(&rows)
+	result := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		result[row.SyncPathId] = row.Count
+	}
+	return result
+}
+
+// CountScrapeMediaFilesByStatus 按状态统计刮削文件数量
+func CountScrapeMediaFilesByStatus() map[ScrapeMediaStatus]int64 {
+	var rows []struct {
+		Status ScrapeMediaStatus
+		Count  int64
+	}
+	db.Db.Model(&ScrapeMediaFile{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows)
+	result := make(map[ScrapeMediaStatus]int64, len(rows))
+	for _, row := range rows {
+		result[row.Status] = row.Count
+	}
+	return result
+}
+
+// CountUploadTasksByStatus 按状态统计上传队列的任务数量
+func CountUploadTasksByStatus() map[UploadStatus]int64 {
+	var rows []struct {
+		Status UploadStatus
+		Count  int64
+	}
+	db.Db.Model(&DbUploadTask{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows)
+	result := make(map[UploadStatus]int64, len(rows))
+	for _, row := range rows {
+		result[row.Status] = row.Count
+	}
+	return result
+}
+
+// CountDownloadTasksByStatus 按状态统计下载队列的任务数量
+func CountDownloadTasksByStatus() map[DownloadStatus]int64 {
+	var rows []struct {
+		Status DownloadStatus
+		Count  int64
+	}
+	db.Db.Model(&DbDownloadTask{}).
+		Select("status, COUNT(*) AS count").
+		Group("status").
+		Scan(&rows)
+	result := make(map[DownloadStatus]int64, len(rows))
+	for _, row := range rows {
+		result[row.Status] = row.Count
+	}
+	return result
+}
@@ -26,7 +26,7 @@ func (*Migrator) TableName() string {
 // 如果已有数据库则从数据库中获取版本，根据版本执行变更
 func Migrate() {
 	// sqliteDb := db.InitSqlite3(dbFile)
-	maxVersion := 30
+	maxVersion := 53
 	// 先初始化所有表和基础数据
 	if !InitDB(maxVersion) {
 		// 初始化数据库版本表
@@ -353,6 +353,133 @@ func Migrate() {
 		db.Db.AutoMigrate(EmbyLibrarySyncPath{})
 		migrator.UpdateVersionCode(db.Db)
 	}
+	if migrator.VersionCode == 30 {
+		// 123云盘账号需要保存clientSecret
+		db.Db.AutoMigrate(Account{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 31 {
+		// 本地同步路径支持实时监控
+		db.Db.AutoMigrate(SyncPath{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 32 {
+		// 302代理支持Jellyfin
+		db.Db.AutoMigrate(EmbyConfig{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 33 {
+		// 支持TVDB和Bangumi元数据
+		db.Db.AutoMigrate(ScrapeSettings{}, ScrapePath{}, ScrapeMediaFile{}, Media{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 34 {
+		// 115上传支持分片续传
+		db.Db.AutoMigrate(DbUploadTask{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 35 {
+		// 用户角色和目录权限，已有用户保持管理员
+		db.Db.AutoMigrate(User{})
+		db.Db.Model(&User{}).Where("role = '' OR role IS NULL").Update("role", UserRoleAdmin)
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 36 {
+		// 同步目录的删除保护阈值
+		db.Db.AutoMigrate(SyncPath{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 37 {
+		// 刮削整理日志
+		db.Db.AutoMigrate(ScrapeJournal{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 38 {
+		// 联动删除网盘文件的延迟和回收目录，待删除记录
+		db.Db.AutoMigrate(EmbyConfig{}, EmbyPendingDeletion{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 39 {
+		// WebDAV来源的ETag
+		db.Db.AutoMigrate(SyncFile{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 40 {
+		// S3账号的bucket和区域，用户名加长以保存AccessKey
+		db.Db.AutoMigrate(Account{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 41 {
+		// 内置WebDAV服务开关和视频呈现方式
+		db.Db.AutoMigrate(Settings{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 42 {
+		// 本地代理的域名白名单和带宽限制
+		db.Db.AutoMigrate(Settings{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 43 {
+		// Telegram机器人允许管理任务的会话ID
+		db.Db.AutoMigrate(TelegramChannelConfig{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 44 {
+		// 字幕下载设置和刮削目录的字幕下载开关
+		db.Db.AutoMigrate(ScrapeSettings{}, ScrapePath{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 45 {
+		// 备份加密设置和异地备份目标
+		db.Db.AutoMigrate(BackupConfig{}, BackupTarget{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 46 {
+		// AI识别用量统计和刮削目录的批量AI识别开关
+		db.Db.AutoMigrate(ScrapeSettings{}, ScrapePath{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 47 {
+		// 二级分类规则
+		db.Db.AutoMigrate(CategoryRule{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 48 {
+		// 刮削目录的名称模板从{title}格式转换为Go模板语法
+		var scrapePaths []*ScrapePath
+		db.Db.Model(&ScrapePath{}).Find(&scrapePaths)
+		for _, sp := range scrapePaths {
+			folderTemplate := ConvertLegacyNameTemplate(sp.FolderNameTemplate)
+			fileTemplate := ConvertLegacyNameTemplate(sp.FileNameTemplate)
+			if folderTemplate == sp.FolderNameTemplate && fileTemplate == sp.FileNameTemplate {
+				continue
+			}
+			db.Db.Model(&ScrapePath{}).Where("id = ?", sp.ID).Updates(map[string]interface{}{"folder_name_template": folderTemplate, "file_name_template": fileTemplate})
+			helpers.AppLogger.Infof("刮削目录 %d 的名称模板已转换：%s => %s, %s => %s", sp.ID, sp.FolderNameTemplate, folderTemplate, sp.FileNameTemplate, fileTemplate)
+		}
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 49 {
+		// 电影多版本设置和刮削文件的版本信息
+		db.Db.AutoMigrate(ScrapePath{}, ScrapeMediaFile{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 50 {
+		// 115账号单独设置的请求队列速率限制
+		db.Db.AutoMigrate(Account{})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 51 {
+		// 123云盘的etag是MD5，从sha1字段移到单独的md5字段
+		db.Db.AutoMigrate(SyncFile{})
+		db.Db.Model(&SyncFile{}).Where("source_type = ?", SourceType123).Updates(map[string]any{"md5": gorm.Expr("sha1"), "sha1": ""})
+		migrator.UpdateVersionCode(db.Db)
+	}
+	if migrator.VersionCode == 52 {
+		// 播放链接签名的密钥，加载设置时生成
+		db.Db.AutoMigrate(Settings{})
+		migrator.UpdateVersionCode(db.Db)
+	}
 	helpers.AppLogger.Infof("当前数据库版本 %d", migrator.VersionCode)
 }
 
@@ -364,11 +491,11 @@ func BatchCreateTable() {
 	db.Db.AutoMigrate(Settings{}, Sync{}, User{}, SyncPath{}, Account{})
 	db.Db.AutoMigrate(SyncFile{})
 	// 刮削相关表
-	db.Db.AutoMigrate(ScrapeSettings{}, ScrapePath{}, MovieCategory{}, TvShowCategory{}, ScrapePathCategory{}, ScrapeMediaFile{}, Media{}, MediaSeason{}, MediaEpisode{})
+	db.Db.AutoMigrate(ScrapeSettings{}, ScrapePath{}, MovieCategory{}, TvShowCategory{}, ScrapePathCategory{}, ScrapeMediaFile{}, Media{}, MediaSeason{}, MediaEpisode{}, ScrapeJournal{}, CategoryRule{})
 	// 115请求统计表
 	db.Db.AutoMigrate(&RequestStat{})
 	// Emby 同步相关表
-	db.Db.AutoMigrate(EmbyConfig{}, EmbyMediaItem{}, EmbyMediaSyncFile{}, EmbyLibrary{}, EmbyLibrarySyncPath{})
+	db.Db.AutoMigrate(EmbyConfig{}, EmbyMediaItem{}, EmbyMediaSyncFile{}, EmbyLibrary{}, EmbyLibrarySyncPath{}, EmbyPendingDeletion{})
 	// 下载队列
 	db.Db.AutoMigrate(DbDownloadTask{}, DbUploadTask{})
 	// 通知渠道表
@@ -376,7 +503,7 @@ func BatchCreateTable() {
 	// API Key认证表
 	db.Db.AutoMigrate(ApiKey{})
 	// 备份恢复相关表
-	db.Db.AutoMigrate(BackupConfig{}, BackupRecord{})
+	db.Db.AutoMigrate(BackupConfig{}, BackupRecord{}, BackupTarget{})
 }
 
 func InitMigrationTable(version int) {
@@ -453,6 +580,7 @@ func InitUser() {
 		// 设置默认值
 		Username: helpers.GlobalConfig.AdminUsername,
 		Password: string(password),
+		Role:     UserRoleAdmin,
 	}
 	uerr := db.Db.Model(&User{}).First(&defaultUser).Error
 	if errors.Is(uerr, gorm.ErrRecordNotFound) {
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// 多版本时被淘汰的版本存放的文件夹，相对刮削目录的目标路径
+const DuplicateVersionFolder = "duplicates"
+
+// 版本质量排序规则
+const (
+	VersionRankResolution    = "resolution"     // 分辨率越高越好
+	VersionRankHdr           = "hdr"            // DV > HDR10 > HLG > HDR > SDR
+	VersionRankVideoCodec    = "video_codec"    // AV1 > H265 > H264 > 其他
+	VersionRankBitrate       = "bitrate"        // 视频码率越高越好
+	VersionRankAudioChannels = "audio_channels" // 第一条音轨的声道越多越好
+)
+
+var VersionRankCriteria = []string{VersionRankResolution, VersionRankHdr, VersionRankVideoCodec, VersionRankBitrate, VersionRankAudioChannels}
+
+// 没有设置排序规则时使用的默认规则
+var DefaultVersionRanking = []string{VersionRankResolution, VersionRankHdr, VersionRankBitrate}
+
+var resolutionRank = map[string]int{ResolutionSD: 1, ResolutionHD: 2, ResolutionFHD: 3, ResolutionQHD: 4, ResolutionUHD: 5, ResolutionFUHD: 6}
+var hdrRank = map[string]int{"HDR": 1, "HLG": 2, "HDR10": 3, "DV": 4}
+var videoCodecRank = map[string]int{"H264": 1, "H265": 2, "AV1": 3}
+
+// BuildVersionLabel 多版本文件名中的版本名称，由版本、分辨率和HDR类型组成，例如：Director's Cut 2160p DV
+func (sm *ScrapeMediaFile) BuildVersionLabel() string {
+	hdrType := ""
+	if sm.VideoCodec != nil {
+		hdrType = sm.VideoCodec.HdrType
+	}
+	if hdrType == "" && sm.IsHDR {
+		hdrType = "HDR"
+	}
+	return joinNotEmpty(" ", []string{ParseEdition(filepath.Base(sm.VideoFilename)), sm.Resolution, hdrType})
+}
+
+func versionScore(sm *ScrapeMediaFile, criterion string) int64 {
+	switch criterion {
+	case VersionRankResolution:
+		return int64(resolutionRank[sm.ResolutionLevel])
+	case VersionRankHdr:
+		if sm.VideoCodec != nil && sm.VideoCodec.HdrType != "" {
+			return int64(hdrRank[sm.VideoCodec.HdrType])
+		}
+		if sm.IsHDR {
+			return int64(hdrRank["HDR"])
+		}
+	case VersionRankVideoCodec:
+		if sm.VideoCodec != nil {
+			return int64(videoCodecRank[videoCodecName(sm.VideoCodec.Codec)])
+		}
+	case VersionRankBitrate:
+		if sm.VideoCodec != nil {
+			return sm.VideoCodec.Bitrate
+		}
+	case VersionRankAudioChannels:
+		if len(sm.AudioCodec) > 0 {
+			return sm.AudioCodec[0].Channels
+		}
+	}
+	return 0
+}
+
+// CompareVersions 按排序规则比较两个版本，a更好时返回1，b更好时返回-1，相同返回0
+func CompareVersions(a, b *ScrapeMediaFile, ranking []string) int {
+	if len(ranking) == 0 {
+		ranking = DefaultVersionRanking
+	}
+	for _, criterion := range ranking {
+		sa, sb := versionScore(a, criterion), versionScore(b, criterion)
+		if sa > sb {
+			return 1
+		}
+		if sa < sb {
+			return -1
+		}
+	}
+	return 0
+}
+
+// BetterVersion a比b更好时返回true，质量相同时ID更小（先入库）的版本更好，保证结果与处理顺序无关
+func BetterVersion(a, b *ScrapeMediaFile, ranking []string) bool {
+	if c := CompareVersions(a, b, ranking); c != 0 {
+		return c > 0
+	}
+	return a.ID < b.ID
+}
+
+// UniqueVersionLabel 同一个文件夹中已经有相同的版本名称时加上序号，例如：1080p 2
+func UniqueVersionLabel(label string, used []string) string {
+	if label == "" {
+		label = "Version"
+	}
+	candidate := label
+	for i := 2; slices.ContainsFunc(used, func(u string) bool { return strings.EqualFold(u, candidate) }); i++ {
+		candidate = fmt.Sprintf("%s %d", label, i)
+	}
+	return candidate
+}
+
+// GetMovieVersions 查询刮削目录中同一部电影已经确定了版本的其他文件，不同元数据提供者的ID相同时不是同一部电影
+func GetMovieVersions(scrapePathId uint, provider string, tmdbId int64, excludeId uint) []*ScrapeMediaFile {
+	var mediaFiles []*ScrapeMediaFile
+	statuses := []ScrapeMediaStatus{ScrapeMediaStatusScraping, ScrapeMediaStatusScraped, ScrapeMediaStatusRenaming, ScrapeMediaStatusRenamed, ScrapeMediaStatusRenameFailed}
+	db.Db.Scopes(WhereTmdbId(provider, tmdbId)).Where("scrape_path_id = ? AND media_type = ? AND id != ? AND version_label != '' AND status IN ?", scrapePathId, MediaTypeMovie, excludeId, statuses).Order("id ASC").Find(&mediaFiles)
+	for _, mediaFile := range mediaFiles {
+		mediaFile.DecodeJson()
+	}
+	return mediaFiles
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	uhd := &ScrapeMediaFile{ResolutionLevel: ResolutionUHD, VideoCodec: &VideoCodec{HdrType: "DV", Bitrate: 20000000}}
+	fhd := &ScrapeMediaFile{ResolutionLevel: ResolutionFHD, VideoCodec: &VideoCodec{Bitrate: 30000000}}
+	if CompareVersions(uhd, fhd, nil) != 1 || CompareVersions(fhd, uhd, nil) != -1 {
+		t.Errorf("默认规则应该优先比较分辨率")
+	}
+	if CompareVersions(uhd, fhd, []string{VersionRankBitrate}) != -1 {
+		t.Errorf("只比较码率时码率高的版本更好")
+	}
+	if CompareVersions(fhd, fhd, nil) != 0 {
+		t.Errorf("相同版本应该返回0")
+	}
+}
+
+func TestBetterVersion(t *testing.T) {
+	first := &ScrapeMediaFile{ResolutionLevel: ResolutionFHD}
+	first.ID = 1
+	second := &ScrapeMediaFile{ResolutionLevel: ResolutionFHD}
+	second.ID = 2
+	uhd := &ScrapeMediaFile{ResolutionLevel: ResolutionUHD}
+	uhd.ID = 3
+	if !BetterVersion(uhd, first, nil) || BetterVersion(first, uhd, nil) {
+		t.Errorf("分辨率更高的版本应该更好，与ID无关")
+	}
+	if !BetterVersion(first, second, nil) || BetterVersion(second, first, nil) {
+		t.Errorf("质量相同时ID更小的版本更好")
+	}
+}
+
+func TestVersionLabel(t *testing.T) {
+	sm := &ScrapeMediaFile{VideoFilename: "Blade.Runner.1982.Final.Cut.2160p.mkv", Resolution: "2160p", VideoCodec: &VideoCodec{HdrType: "HDR10"}}
+	if got := sm.BuildVersionLabel(); got != "Final Cut 2160p HDR10" {
+		t.Errorf("BuildVersionLabel = %q", got)
+	}
+	if got := UniqueVersionLabel("1080p", []string{"1080P", "1080p 2"}); got != "1080p 3" {
+		t.Errorf("UniqueVersionLabel = %q", got)
+	}
+	if got := UniqueVersionLabel("", nil); got != "Version" {
+		t.Errorf("UniqueVersionLabel = %q", got)
+	}
+}
+
+func TestGetMovieVersions(t *testing.T) {
+	conn := openModelsTestDb(t, &ScrapeMediaFile{})
+	// TMDB和TVDB的ID相同，但不是同一部电影
+	for i, provider := range []string{MetadataProviderTmdb, MetadataProviderTmdb, MetadataProviderTvdb} {
+		conn.Create(&ScrapeMediaFile{ScrapePathId: 1, MediaType: MediaTypeMovie, TmdbId: 603, MetadataProvider: provider, VersionLabel: fmt.Sprintf("v%d", i), Status: ScrapeMediaStatusRenamed})
+	}
+	versions := GetMovieVersions(1, MetadataProviderTmdb, 603, 1)
+	if len(versions) != 1 || versions[0].VersionLabel != "v1" {
+		t.Fatalf("tmdb versions = %+v", versions)
+	}
+	versions = GetMovieVersions(1, MetadataProviderTvdb, 603, 0)
+	if len(versions) != 1 || versions[0].VersionLabel != "v2" {
+		t.Fatalf("tvdb versions = %+v", versions)
+	}
+}
@@ -0,0 +1,334 @@
+package models
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// 默认的文件夹名称模板
+const DefaultFolderNameTemplate = "{{.Title}}{{if .Year}} ({{.Year}}){{end}}"
+
+// NameTemplateData 文件夹和文件名称模板中可以使用的字段，例如：{{.Title}}、{{pad 2 .Episode}}
+type NameTemplateData struct {
+	Title           string `json:"title"`            // 名称
+	OriginalTitle   string `json:"original_title"`   // 原始名称
+	Year            int    `json:"year"`             // 年份，没有为0
+	TmdbId          int64  `json:"tmdb_id"`          // 元数据提供者中的ID
+	IdTag           string `json:"id_tag"`           // ID标记，例如：{tmdbid-123}
+	Actors          string `json:"actors"`           // 演员，超过2个时为"多人演员"
+	Num             string `json:"num"`              // 番号
+	Season          int    `json:"season"`           // 季编号，电影为0
+	Episode         int    `json:"episode"`          // 集编号，电影为0
+	SeasonEpisode   string `json:"season_episode"`   // S01E01
+	EpisodeName     string `json:"episode_name"`     // 集名称
+	Resolution      string `json:"resolution"`       // 分辨率，例如：1080p
+	ResolutionLevel string `json:"resolution_level"` // 分辨率等级，例如：FHD
+	Bitrate         string `json:"bitrate"`          // 视频码率，例如：8Mbps
+	VideoCodec      string `json:"video_codec"`      // 视频编码，例如：H265
+	HdrType         string `json:"hdr_type"`         // HDR类型：DV、HDR10、HLG、HDR，SDR为空
+	AudioCodec      string `json:"audio_codec"`      // 第一条音轨的编码，例如：DTS
+	AudioChannels   string `json:"audio_channels"`   // 第一条音轨的声道，例如：5.1
+	Edition         string `json:"edition"`          // 版本，从文件名中识别，例如：Director's Cut
+	ReleaseGroup    string `json:"release_group"`    // 发布组，从文件名中识别
+}
+
+// 旧格式的占位符和新模板语法的对应关系
+var legacyNameTokens = []struct {
+	token string
+	tpl   string
+}{
+	{"{title}", "{{.Title}}"},
+	{"{year}", "{{if .Year}}{{.Year}}{{end}}"},
+	{"{resolution}", "{{.Resolution}}"},
+	{"{resolution_level}", "{{.ResolutionLevel}}"},
+	{"{bitrate}", "{{.Bitrate}}"},
+	{"{tmdb_id}", "{{.IdTag}}"},
+	{"{actors}", "{{.Actors}}"},
+	{"{num}", "{{.Num}}"},
+	{"{season_number}", "{{.Season}}"},
+	{"{episode_number}", "{{if .Episode}}{{.Episode}}{{end}}"},
+	{"{season_episode}", "{{.SeasonEpisode}}"},
+	{"{episode_name}", "{{.EpisodeName}}"},
+}
+
+// pad补零的最大宽度，避免模板中写很大的宽度时占用大量内存
+const maxPadWidth = 10
+
+var nameTemplateFuncs = template.FuncMap{
+	// 补零，例如：{{pad 2 .Episode}} => 01
+	"pad": func(width int, value any) string {
+		s := fmt.Sprint(value)
+		width = min(width, maxPadWidth)
+		if len(s) >= width {
+			return s
+		}
+		return strings.Repeat("0", width-len(s)) + s
+	},
+	// 值不为空时加上前后缀，例如：{{wrap "[" "]" .ReleaseGroup}}
+	"wrap": func(prefix, suffix string, value any) string {
+		s := fmt.Sprint(value)
+		if s == "" || s == "0" {
+			return ""
+		}
+		return prefix + s + suffix
+	},
+	// 值为空时使用默认值
+	"default": func(def string, value any) string {
+		s := fmt.Sprint(value)
+		if s == "" || s == "0" {
+			return def
+		}
+		return s
+	},
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"trim":    strings.TrimSpace,
+	"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"join":    func(sep string, values ...string) string { return joinNotEmpty(sep, values) },
+}
+
+var (
+	emptyBracketsRe  = regexp.MustCompile(`\(\s*\)|\[\s*\]|\{\s*\}|（\s*）|【\s*】`)
+	repeatedDashRe   = regexp.MustCompile(`\s*-\s*(-\s*)+`)
+	repeatedSpaceRe  = regexp.MustCompile(`\s{2,}`)
+	releaseGroupRe   = regexp.MustCompile(`-([A-Za-z0-9@&]+)$`)
+	leadingGroupRe   = regexp.MustCompile(`^\[([^\]]+)\]`)
+	editionRe        = regexp.MustCompile(`(?i)\b(director'?s[ ._-]?cut|extended(?:[ ._-]?(?:cut|edition))?|unrated|uncut|remastered|theatrical(?:[ ._-]?cut)?|imax|criterion|special[ ._-]?edition|final[ ._-]?cut)\b|(导演剪辑版|加长版|未删减版|重制版|剧场版|IMAX版)`)
+	notReleaseGroups = map[string]bool{"DL": true, "RIP": true, "DTS": true, "HD": true, "MA": true, "X": true, "AUDIO": true}
+)
+
+// IsLegacyNameTemplate 是否是旧格式的模板，旧格式使用{title}这样的占位符
+func IsLegacyNameTemplate(tpl string) bool {
+	if strings.Contains(tpl, "{{") {
+		return false
+	}
+	for _, t := range legacyNameTokens {
+		if strings.Contains(tpl, t.token) {
+			return true
+		}
+	}
+	return false
+}
+
+// ConvertLegacyNameTemplate 把旧格式的模板转换为新的模板语法，新格式的模板原样返回
+func ConvertLegacyNameTemplate(tpl string) string {
+	if !IsLegacyNameTemplate(tpl) {
+		return tpl
+	}
+	for _, t := range legacyNameTokens {
+		tpl = strings.ReplaceAll(tpl, t.token, t.tpl)
+	}
+	return tpl
+}
+
+func parseNameTemplate(tpl string) (*template.Template, error) {
+	return template.New("name").Funcs(nameTemplateFuncs).Option("missingkey=zero").Parse(ConvertLegacyNameTemplate(tpl))
+}
+
+// ValidateNameTemplate 检查模板语法，空模板不检查
+func ValidateNameTemplate(tpl string) error {
+	if tpl == "" {
+		return nil
+	}
+	t, err := parseNameTemplate(tpl)
+	if err != nil {
+		return err
+	}
+	var sb strings.Builder
+	return t.Execute(&sb, &NameTemplateData{})
+}
+
+// RenderNameTemplate 生成名称，并清理空值留下的括号和分隔符
+func RenderNameTemplate(tpl string, data *NameTemplateData) (string, error) {
+	t, err := parseNameTemplate(tpl)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return cleanRenderedName(sb.String()), nil
+}
+
+// 去掉空的括号、重复的横杠和首尾的分隔符
+func cleanRenderedName(name string) string {
+	for {
+		cleaned := emptyBracketsRe.ReplaceAllString(name, "")
+		if cleaned == name {
+			break
+		}
+		name = cleaned
+	}
+	name = repeatedDashRe.ReplaceAllString(name, " - ")
+	name = repeatedSpaceRe.ReplaceAllString(name, " ")
+	// 只去掉首尾的分隔符，不影响中间的内容
+	return strings.Trim(name, "-_ ")
+}
+
+func joinNotEmpty(sep string, values []string) string {
+	parts := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// 视频编码的常用写法
+func videoCodecName(codec string) string {
+	switch strings.ToLower(codec) {
+	case "":
+		return ""
+	case "hevc", "h265":
+		return "H265"
+	case "h264", "avc":
+		return "H264"
+	default:
+		return strings.ToUpper(codec)
+	}
+}
+
+// 音频编码的常用写法
+func audioCodecName(codec string) string {
+	switch strings.ToLower(codec) {
+	case "":
+		return ""
+	case "eac3":
+		return "DDP"
+	case "ac3":
+		return "DD"
+	case "truehd":
+		return "TrueHD"
+	default:
+		return strings.ToUpper(codec)
+	}
+}
+
+// 声道数转换为常用写法，例如：6 => 5.1
+func audioChannelsName(channels int64) string {
+	switch {
+	case channels <= 0:
+		return ""
+	case channels >= 6:
+		return fmt.Sprintf("%d.1", channels-1)
+	default:
+		return fmt.Sprintf("%d.0", channels)
+	}
+}
+
+// ParseEdition 从文件名中识别版本，例如：Director's Cut、Extended
+func ParseEdition(filename string) string {
+	match := editionRe.FindString(filename)
+	if match == "" {
+		return ""
+	}
+	return strings.NewReplacer(".", " ", "_", " ").Replace(match)
+}
+
+// ParseReleaseGroup 从文件名中识别发布组，支持结尾的-GROUP和开头的[GROUP]
+func ParseReleaseGroup(filename string) string {
+	base := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+	if m := leadingGroupRe.FindStringSubmatch(base); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	if m := releaseGroupRe.FindStringSubmatch(base); m != nil && !notReleaseGroups[strings.ToUpper(m[1])] {
+		if _, err := strconv.Atoi(m[1]); err != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// NameTemplateData 生成模板使用的数据
+func (sm *ScrapeMediaFile) NameTemplateData() *NameTemplateData {
+	data := &NameTemplateData{
+		Title:           sm.Name,
+		Year:            sm.Year,
+		TmdbId:          sm.TmdbId,
+		Resolution:      sm.Resolution,
+		ResolutionLevel: sm.ResolutionLevel,
+		Edition:         ParseEdition(filepath.Base(sm.VideoFilename)),
+		ReleaseGroup:    ParseReleaseGroup(sm.VideoFilename),
+	}
+	if sm.TmdbId != 0 {
+		data.IdTag = sm.GetProviderIdTag()
+	}
+	if sm.VideoCodec != nil {
+		if sm.VideoCodec.Bitrate != 0 {
+			data.Bitrate = fmt.Sprintf("%dMbps", sm.VideoCodec.Bitrate/1000000)
+		}
+		data.VideoCodec = videoCodecName(sm.VideoCodec.Codec)
+		data.HdrType = sm.VideoCodec.HdrType
+	}
+	if data.HdrType == "" && sm.IsHDR {
+		data.HdrType = "HDR"
+	}
+	if len(sm.AudioCodec) > 0 {
+		data.AudioCodec = audioCodecName(sm.AudioCodec[0].Codec)
+		data.AudioChannels = audioChannelsName(sm.AudioCodec[0].Channels)
+	}
+	if sm.Media != nil {
+		data.OriginalTitle = sm.Media.OriginalName
+		data.Num = sm.Media.Num
+		actorCount := len(sm.Media.Actors)
+		if actorCount >= 3 {
+			data.Actors = "多人演员"
+		} else if actorCount > 0 {
+			names := make([]string, 0, actorCount)
+			for _, actor := range sm.Media.Actors {
+				names = append(names, actor.Name)
+			}
+			data.Actors = strings.Join(names, ", ")
+		}
+	}
+	if sm.MediaType == MediaTypeTvShow {
+		data.Season = sm.SeasonNumber
+		data.Episode = sm.EpisodeNumber
+		if sm.SeasonNumber >= 0 && sm.EpisodeNumber > 0 {
+			data.SeasonEpisode = fmt.Sprintf("S%02dE%02d", sm.SeasonNumber, sm.EpisodeNumber)
+		}
+		if sm.MediaEpisode != nil {
+			data.EpisodeName = sm.MediaEpisode.EpisodeName
+		}
+	}
+	return data
+}
+
+// SampleNameTemplateData 没有刮削文件时预览模板使用的示例数据
+func SampleNameTemplateData(mediaType MediaType) *NameTemplateData {
+	data := &NameTemplateData{
+		Title:           "沙丘2",
+		OriginalTitle:   "Dune: Part Two",
+		Year:            2024,
+		TmdbId:          693134,
+		IdTag:           "{tmdbid-693134}",
+		Actors:          "多人演员",
+		Resolution:      "2160p",
+		ResolutionLevel: ResolutionUHD,
+		Bitrate:         "25Mbps",
+		VideoCodec:      "H265",
+		HdrType:         "DV",
+		AudioCodec:      "TrueHD",
+		AudioChannels:   "7.1",
+		Edition:         "IMAX",
+		ReleaseGroup:    "FRDS",
+	}
+	if mediaType == MediaTypeTvShow {
+		data.Title = "三体"
+		data.OriginalTitle = "3 Body Problem"
+		data.TmdbId = 108545
+		data.IdTag = "{tmdbid-108545}"
+		data.Season = 1
+		data.Episode = 3
+		data.SeasonEpisode = "S01E03"
+		data.EpisodeName = "宇宙闪烁"
+		data.Edition = ""
+	}
+	return data
+}
@@ -0,0 +1,69 @@
+package models
+
+import "testing"
+
+func TestConvertLegacyNameTemplate(t *testing.T) {
+	got := ConvertLegacyNameTemplate("{title} ({year}) - {season_episode}")
+	want := "{{.Title}} ({{if .Year}}{{.Year}}{{end}}) - {{.SeasonEpisode}}"
+	if got != want {
+		t.Errorf("ConvertLegacyNameTemplate = %s", got)
+	}
+	// 新格式和没有占位符的模板不转换
+	for _, tpl := range []string{"{{.Title}} {title}", "固定名称"} {
+		if got := ConvertLegacyNameTemplate(tpl); got != tpl {
+			t.Errorf("ConvertLegacyNameTemplate(%s) = %s", tpl, got)
+		}
+	}
+}
+
+func TestRenderNameTemplate(t *testing.T) {
+	data := &NameTemplateData{Title: "三体", Season: 1, Episode: 3, VideoCodec: "H265", IdTag: "{tmdbid-108545}"}
+	cases := []struct {
+		tpl  string
+		want string
+	}{
+		{"{{.Title}} - S{{pad 2 .Season}}E{{pad 2 .Episode}}", "三体 - S01E03"},
+		{"{{.Title}} ({{.Year}})", "三体 (0)"},
+		{DefaultFolderNameTemplate, "三体"},
+		{"{title} ({year}) [{resolution}] {tmdb_id}", "三体 {tmdbid-108545}"},
+		{"{{.Title}} - {{.Resolution}} - {{.VideoCodec}}{{wrap \"-\" \"\" .ReleaseGroup}}", "三体 - H265"},
+		{"{{.Title}}{{if .HdrType}} {{.HdrType}}{{end}} {{join \".\" .VideoCodec .AudioCodec}}", "三体 H265"},
+		{"{{default \"未知\" .Edition | upper}}", "未知"},
+		{"{{pad 2000000000 .Season}}", "0000000001"},
+	}
+	for _, c := range cases {
+		got, err := RenderNameTemplate(c.tpl, data)
+		if err != nil || got != c.want {
+			t.Errorf("RenderNameTemplate(%s) = %q, %v, want %q", c.tpl, got, err, c.want)
+		}
+	}
+	if err := ValidateNameTemplate("{{.Unknown}}"); err == nil {
+		t.Errorf("不存在的字段应该返回错误")
+	}
+	if err := ValidateNameTemplate("{{if .Title}}"); err == nil {
+		t.Errorf("语法错误应该返回错误")
+	}
+}
+
+func TestParseReleaseGroupAndEdition(t *testing.T) {
+	groups := map[string]string{
+		"Dune.Part.Two.2024.2160p.UHD.BluRay.x265-FRDS.mkv": "FRDS",
+		"[Nekomoe kissaten] Frieren - 03 [1080p].mp4":       "Nekomoe kissaten",
+		"Movie.2020.1080p.WEB-DL.mkv":                       "",
+		"三体 第03集.mp4":                                       "",
+	}
+	for name, want := range groups {
+		if got := ParseReleaseGroup(name); got != want {
+			t.Errorf("ParseReleaseGroup(%s) = %q, want %q", name, got, want)
+		}
+	}
+	if got := ParseEdition("Blade.Runner.1982.Final.Cut.1080p.mkv"); got != "Final Cut" {
+		t.Errorf("ParseEdition = %q", got)
+	}
+	if got := ParseEdition("Avatar.2009.Extended.Edition.mkv"); got != "Extended Edition" {
+		t.Errorf("ParseEdition = %q", got)
+	}
+	if got := ParseEdition("Avatar.2009.1080p.mkv"); got != "" {
+		t.Errorf("ParseEdition = %q", got)
+	}
+}
@@ -1,12 +1,21 @@
 package models
 
 import (
+	"Q115-STRM/internal/bangumi"
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/openai"
+	"Q115-STRM/internal/subtitle"
 	"Q115-STRM/internal/tmdb"
+	"Q115-STRM/internal/tvdb"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 type AiAction string
@@ -33,6 +42,39 @@ type ScrapeSettings struct {
 	AiModelName       string   `json:"ai_model_name" form:"ai_model_name"`             // AI识别模型名称
 	AiPrompt          string   `json:"ai_prompt" form:"ai_prompt"`                     // AI识别提示词，如果留空则使用默认值
 	AiTimeout         int      `json:"ai_timeout" form:"ai_timeout"`                   // AI识别超时时间，单位秒，默认值为:120
+	TvdbApiKey        string   `json:"tvdb_api_key" form:"tvdb_api_key"`               // TheTVDB API KEY，不设置则无法使用TVDB刮削
+	TvdbPin           string   `json:"tvdb_pin" form:"tvdb_pin"`                       // TheTVDB 订阅PIN，用户自己订阅的API KEY需要
+	BangumiToken      string   `json:"bangumi_token" form:"bangumi_token"`             // Bangumi Access Token，可选，设置后可以查询受限条目
+	// 字幕下载，刮削目录开启字幕下载后，刮削完成时按语言优先级下载缺少的字幕
+	SubtitleProvider      string `json:"subtitle_provider" form:"subtitle_provider"`           // 字幕提供者，为空表示关闭
+	SubtitleLanguages     string `json:"subtitle_languages" form:"subtitle_languages"`         // 字幕语言优先级，逗号分隔，例如 zh-cn,zh-tw,en
+	OpenSubtitlesApiKey   string `json:"opensubtitles_api_key" form:"opensubtitles_api_key"`   // OpenSubtitles API Key
+	OpenSubtitlesUsername string `json:"opensubtitles_username" form:"opensubtitles_username"` // OpenSubtitles 用户名，可选，登录后使用账号的下载配额
+	OpenSubtitlesPassword string `json:"opensubtitles_password" form:"opensubtitles_password"` // OpenSubtitles 密码
+	// AI识别用量，每次请求成功后累加，费用按每百万token的单价计算
+	AiInputPrice       float64 `json:"ai_input_price" form:"ai_input_price"`   // 输入每百万token的单价
+	AiOutputPrice      float64 `json:"ai_output_price" form:"ai_output_price"` // 输出每百万token的单价
+	AiRequests         int64   `json:"ai_requests"`                            // AI识别请求次数
+	AiPromptTokens     int64   `json:"ai_prompt_tokens"`                       // 累计输入token数
+	AiCompletionTokens int64   `json:"ai_completion_tokens"`                   // 累计输出token数
+	AiUsageResetAt     int64   `json:"ai_usage_reset_at"`                      // 用量统计开始时间
+}
+
+// 默认字幕语言优先级
+var DefaultSubtitleLanguages = []string{"zh-cn", "zh-tw", "en"}
+
+// 元数据提供者
+const (
+	MetadataProviderTmdb    = "tmdb"
+	MetadataProviderTvdb    = "tvdb"
+	MetadataProviderBangumi = "bangumi"
+)
+
+var MetadataProviders = []string{MetadataProviderTmdb, MetadataProviderTvdb, MetadataProviderBangumi}
+
+// 检查元数据提供者是否有效
+func IsValidMetadataProvider(provider string) bool {
+	return slices.Contains(MetadataProviders, provider)
 }
 
 const (
@@ -104,6 +146,114 @@ func (s *ScrapeSettings) GetTmdbClient() *tmdb.Client {
 	return tmdb.NewClient(s.GetTmdbApiKey(), s.GetTmdbAccessToken(), s.GetTmdbApiUrl(), s.GetTmdbLanguage(), s.GetTmdbProxyUrl())
 }
 
+// TVDB和Bangumi与TMDB共用代理设置
+func (s *ScrapeSettings) GetTvdbClient() *tvdb.Client {
+	return tvdb.NewClient(s.TvdbApiKey, s.TvdbPin, s.GetTmdbProxyUrl())
+}
+
+func (s *ScrapeSettings) GetBangumiClient() *bangumi.Client {
+	return bangumi.NewClient(s.BangumiToken, s.GetTmdbProxyUrl())
+}
+
+// 保存TVDB和Bangumi设置
+func (s *ScrapeSettings) SaveMetadataProviders(tvdbApiKey, tvdbPin, bangumiToken string) error {
+	s.TvdbApiKey = tvdbApiKey
+	s.TvdbPin = tvdbPin
+	s.BangumiToken = bangumiToken
+	updateData := make(map[string]interface{})
+	updateData["tvdb_api_key"] = tvdbApiKey
+	updateData["tvdb_pin"] = tvdbPin
+	updateData["bangumi_token"] = bangumiToken
+	err := db.Db.Model(ScrapeSettings{}).Where("id = ?", s.ID).Updates(updateData).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("更新元数据提供者设置失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// 字幕语言优先级
+func (s *ScrapeSettings) GetSubtitleLanguages() []string {
+	languages := strings.FieldsFunc(s.SubtitleLanguages, func(r rune) bool {
+		return r == ',' || r == '，' || r == ' '
+	})
+	if len(languages) == 0 {
+		return DefaultSubtitleLanguages
+	}
+	return languages
+}
+
+// 获取字幕设置时隐藏OpenSubtitles密码，保存时收到该值表示密码没有修改
+const SubtitlePasswordMask = "******"
+
+var (
+	subtitleProvider       subtitle.Provider
+	subtitleProviderName   string
+	subtitleProviderConfig subtitle.Config
+	subtitleProviderMu     sync.Mutex
+)
+
+// 创建字幕提供者，未开启字幕下载时返回nil
+func (s *ScrapeSettings) GetSubtitleProvider() (subtitle.Provider, error) {
+	if s.SubtitleProvider == "" {
+		return nil, nil
+	}
+	config := subtitle.Config{
+		ApiKey:   s.OpenSubtitlesApiKey,
+		Username: s.OpenSubtitlesUsername,
+		Password: s.OpenSubtitlesPassword,
+		ProxyUrl: s.GetTmdbProxyUrl(),
+	}
+	// 所有刮削任务共用一个字幕提供者，复用登录token和限速器，设置变化时重新创建
+	subtitleProviderMu.Lock()
+	defer subtitleProviderMu.Unlock()
+	if subtitleProvider != nil && subtitleProviderName == s.SubtitleProvider && subtitleProviderConfig == config {
+		return subtitleProvider, nil
+	}
+	provider, err := subtitle.New(s.SubtitleProvider, config)
+	if err != nil {
+		return nil, err
+	}
+	subtitleProvider, subtitleProviderName, subtitleProviderConfig = provider, s.SubtitleProvider, config
+	return provider, nil
+}
+
+// 清空缓存的字幕提供者，下次使用时按最新的设置创建
+func resetSubtitleProvider() {
+	subtitleProviderMu.Lock()
+	defer subtitleProviderMu.Unlock()
+	subtitleProvider = nil
+}
+
+// 保存字幕下载设置
+func (s *ScrapeSettings) SaveSubtitle(provider, languages, apiKey, username, password string) error {
+	if provider != "" && !slices.Contains(subtitle.Providers(), provider) {
+		return fmt.Errorf("不支持的字幕提供者: %s", provider)
+	}
+	// 前端显示的是隐藏后的密码，没有修改时保留原来的密码
+	if password == SubtitlePasswordMask {
+		password = s.OpenSubtitlesPassword
+	}
+	s.SubtitleProvider = provider
+	s.SubtitleLanguages = languages
+	s.OpenSubtitlesApiKey = apiKey
+	s.OpenSubtitlesUsername = username
+	s.OpenSubtitlesPassword = password
+	updateData := make(map[string]interface{})
+	updateData["subtitle_provider"] = provider
+	updateData["subtitle_languages"] = languages
+	updateData["open_subtitles_api_key"] = apiKey
+	updateData["open_subtitles_username"] = username
+	updateData["open_subtitles_password"] = password
+	err := db.Db.Model(ScrapeSettings{}).Where("id = ?", s.ID).Updates(updateData).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("更新字幕下载设置失败: %v", err)
+		return err
+	}
+	resetSubtitleProvider()
+	return nil
+}
+
 // 保存tmdb设置
 func (s *ScrapeSettings) SaveTmdb(apiKey, accessToken string, apiUrl string, imageUrl string, language string, imageLanguage string, enableProxy bool) error {
 	s.TmdbApiKey = apiKey
@@ -171,16 +321,20 @@ func (s *ScrapeSettings) GetAiClient() *openai.Client {
 }
 
 // 保存AI识别设置
-func (s *ScrapeSettings) SaveAi(apiKey string, baseUrl string, modelName string, timeout int) error {
+func (s *ScrapeSettings) SaveAi(apiKey string, baseUrl string, modelName string, timeout int, inputPrice float64, outputPrice float64) error {
 	s.AiApiKey = apiKey
 	s.AiBaseUrl = baseUrl
 	s.AiModelName = modelName
 	s.AiTimeout = timeout
+	s.AiInputPrice = max(inputPrice, 0)
+	s.AiOutputPrice = max(outputPrice, 0)
 	updateData := make(map[string]interface{})
 	updateData["ai_api_key"] = apiKey
 	updateData["ai_base_url"] = baseUrl
 	updateData["ai_model_name"] = modelName
 	updateData["ai_timeout"] = s.AiTimeout
+	updateData["ai_input_price"] = s.AiInputPrice
+	updateData["ai_output_price"] = s.AiOutputPrice
 	// helpers.AppLogger.Infof("更新AI识别设置: %+v", updateData)
 	err := db.Db.Model(s).Where("id = ?", s.ID).Updates(updateData).Error
 	if err != nil {
@@ -196,6 +350,13 @@ func (s *ScrapeSettings) TestTmdb() bool {
 	return client.TestToken()
 }
 
+// 测试TVDB是否配置正确
+// 使用独立的客户端测试，不影响已保存设置的全局客户端
+func (s *ScrapeSettings) TestTvdb() bool {
+	client := tvdb.NewStandaloneClient(s.TvdbApiKey, s.TvdbPin, s.GetTmdbProxyUrl())
+	return client.TestToken()
+}
+
 // 测试AI是否配置正确
 func (s *ScrapeSettings) TestAi() error {
 	if s.EnableAi == AiActionOff {
@@ -216,6 +377,50 @@ func (s *ScrapeSettings) TestAi() error {
 	return fmt.Errorf("测试AI识别失败，识别出的电影名称不是名侦探柯南")
 }
 
+var aiUsageMutex sync.Mutex
+
+// 累加AI识别的token用量，作为openai的用量回调
+func RecordAiUsage(usage openai.Usage) {
+	aiUsageMutex.Lock()
+	defer aiUsageMutex.Unlock()
+	s := GlobalScrapeSettings
+	if s == nil || s.ID == 0 {
+		return
+	}
+	s.AiRequests++
+	s.AiPromptTokens += int64(usage.PromptTokens)
+	s.AiCompletionTokens += int64(usage.CompletionTokens)
+	err := db.Db.Model(&ScrapeSettings{}).Where("id = ?", s.ID).Updates(map[string]interface{}{
+		"ai_requests":          gorm.Expr("ai_requests + ?", 1),
+		"ai_prompt_tokens":     gorm.Expr("ai_prompt_tokens + ?", usage.PromptTokens),
+		"ai_completion_tokens": gorm.Expr("ai_completion_tokens + ?", usage.CompletionTokens),
+	}).Error
+	if err != nil {
+		helpers.AppLogger.Warnf("保存AI识别用量失败: %v", err)
+	}
+}
+
+// 清空AI识别用量统计
+func (s *ScrapeSettings) ResetAiUsage() error {
+	aiUsageMutex.Lock()
+	defer aiUsageMutex.Unlock()
+	s.AiRequests = 0
+	s.AiPromptTokens = 0
+	s.AiCompletionTokens = 0
+	s.AiUsageResetAt = time.Now().Unix()
+	return db.Db.Model(&ScrapeSettings{}).Where("id = ?", s.ID).Updates(map[string]interface{}{
+		"ai_requests":          0,
+		"ai_prompt_tokens":     0,
+		"ai_completion_tokens": 0,
+		"ai_usage_reset_at":    s.AiUsageResetAt,
+	}).Error
+}
+
+// 按单价估算的AI识别费用
+func (s *ScrapeSettings) GetAiCost() float64 {
+	return (float64(s.AiPromptTokens)*s.AiInputPrice + float64(s.AiCompletionTokens)*s.AiOutputPrice) / 1000000
+}
+
 func (s *ScrapeSettings) ExtractByAi(filename string) (*openai.MediaInfoAI, error) {
 	client := s.GetAiClient()
 	return client.TakeMoiveName(filename, s.GetAiPrompt())
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+)
+
+// 整理日志的操作类型
+type ScrapeJournalAction string
+
+const (
+	ScrapeJournalActionMove   ScrapeJournalAction = "move"   // 移动（可能同时改名）
+	ScrapeJournalActionCopy   ScrapeJournalAction = "copy"   // 复制（可能同时改名）
+	ScrapeJournalActionLink   ScrapeJournalAction = "link"   // 硬链接或软链接
+	ScrapeJournalActionRename ScrapeJournalAction = "rename" // 原地改名
+)
+
+// 整理日志的状态
+type ScrapeJournalStatus string
+
+const (
+	ScrapeJournalStatusDone           ScrapeJournalStatus = "done"            // 已执行
+	ScrapeJournalStatusRolledBack     ScrapeJournalStatus = "rolled_back"     // 已撤销
+	ScrapeJournalStatusRollbackFailed ScrapeJournalStatus = "rollback_failed" // 撤销失败
+)
+
+// 整理日志，记录刮削整理时对文件执行的每一次移动、复制和改名，用来批量撤销
+// 115网盘的FileId和ParentId是文件ID，其他来源都是完整路径
+type ScrapeJournal struct {
+	BaseModel
+	ScrapePathId      uint                `json:"scrape_path_id" gorm:"index"`
+	ScrapeMediaFileId uint                `json:"scrape_media_file_id" gorm:"index"`
+	BatchNo           string              `json:"batch_no" gorm:"index"`
+	SourceType        SourceType          `json:"source_type"`
+	Action            ScrapeJournalAction `json:"action"`
+	FileId            string              `json:"file_id"`            // 操作后的文件ID
+	OriginalPath      string              `json:"original_path"`      // 操作前的完整路径
+	OriginalParentId  string              `json:"original_parent_id"` // 操作前所在目录的ID
+	NewPath           string              `json:"new_path"`           // 操作后的完整路径
+	NewParentId       string              `json:"new_parent_id"`      // 操作后所在目录的ID
+	Status            ScrapeJournalStatus `json:"status" gorm:"index"`
+	RollbackError     string              `json:"rollback_error"` // 撤销失败的原因
+}
+
+func (*ScrapeJournal) TableName() string {
+	return "scrape_journals"
+}
+
+// 记录一次整理操作，写入失败只记录日志，不影响整理流程
+func AddScrapeJournal(mediaFile *ScrapeMediaFile, journal *ScrapeJournal) {
+	journal.ScrapePathId = mediaFile.ScrapePathId
+	journal.ScrapeMediaFileId = mediaFile.ID
+	journal.BatchNo = mediaFile.BatchNo
+	journal.SourceType = mediaFile.SourceType
+	journal.Status = ScrapeJournalStatusDone
+	if err := db.Db.Create(journal).Error; err != nil {
+		helpers.AppLogger.Errorf("写入整理日志失败: %s => %s %v", journal.OriginalPath, journal.NewPath, err)
+	}
+}
+
+// 查询需要撤销的整理日志，按执行的相反顺序返回
+// batchNo不为空时按批次查询，否则按时间范围查询（秒级时间戳，0表示不限制）
+func GetScrapeJournalsForRollback(scrapePathId uint, batchNo string, startTime, endTime int64) ([]*ScrapeJournal, error) {
+	query := db.Db.Model(&ScrapeJournal{}).Where("scrape_path_id = ? AND status IN ?", scrapePathId, []ScrapeJournalStatus{ScrapeJournalStatusDone, ScrapeJournalStatusRollbackFailed})
+	if batchNo != "" {
+		query = query.Where("batch_no = ?", batchNo)
+	} else {
+		if startTime > 0 {
+			query = query.Where("created_at >= ?", startTime)
+		}
+		if endTime > 0 {
+			query = query.Where("created_at <= ?", endTime)
+		}
+	}
+	var journals []*ScrapeJournal
+	err := query.Order("id DESC").Find(&journals).Error
+	return journals, err
+}
+
+// 分页查询整理日志
+func GetScrapeJournalList(scrapePathId uint, batchNo string, page, pageSize int) ([]*ScrapeJournal, int64, error) {
+	query := db.Db.Model(&ScrapeJournal{}).Where("scrape_path_id = ?", scrapePathId)
+	if batchNo != "" {
+		query = query.Where("batch_no = ?", batchNo)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var journals []*ScrapeJournal
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&journals).Error
+	return journals, total, err
+}
+
+// 更新撤销结果
+func (j *ScrapeJournal) SetRollbackResult(err error) {
+	j.Status = ScrapeJournalStatusRolledBack
+	j.RollbackError = ""
+	if err != nil {
+		j.Status = ScrapeJournalStatusRollbackFailed
+		j.RollbackError = err.Error()
+	}
+	db.Db.Model(j).Updates(map[string]any{
+		"status":         j.Status,
+		"rollback_error": j.RollbackError,
+	})
+}
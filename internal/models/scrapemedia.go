@@ -9,7 +9,6 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
-	"strconv"
 	"strings"
 	"time"
 
@@ -74,6 +73,7 @@ type VideoCodec struct {
 	Framerate         string  `json:"framerate"`           // 视频帧率，格式: 25.000
 	Scantype          string  `json:"scantype"`            // 视频扫描类型
 	PixelFormat       string  `json:"pixel_format"`        // 视频像素格式
+	HdrType           string  `json:"hdr_type"`            // HDR类型：DV、HDR10、HLG、HDR，SDR为空
 	Default           string  `json:"default"`             // 默认视频
 	Forced            string  `json:"forced"`              // 强制视频
 }
@@ -158,6 +158,7 @@ type ScrapeMediaFile struct {
 	Name                 string            `json:"name"`                                            // TMDB名称，如果没有Media数据则使用该字段
 	Year                 int               `json:"year"`                                            // TMDB年份，如果没有Media数据则使用该字段
 	TmdbId               int64             `json:"tmdb_id"`                                         // TMDB ID，如果没有Media数据则使用该字段
+	MetadataProvider     string            `json:"metadata_provider" gorm:"default:'tmdb'"`         // 识别使用的元数据提供者，TmdbId字段保存的是该提供者中的ID
 	SeasonNumber         int               `json:"season_number"`                                   // 季编号，例如：S01E01中的S01
 	EpisodeNumber        int               `json:"episode_number"`                                  // 集编号，例如：S01E01中的E01
 	Path                 string            `json:"path"`                                            // 媒体文件夹路径，相对ScrapePath.SourcePath的路径
@@ -174,6 +175,8 @@ type ScrapeMediaFile struct {
 	Resolution           string            `json:"resolution"`                                      // 分辨率
 	ResolutionLevel      string            `json:"resolution_level"`                                // 分辨率等级
 	IsHDR                bool              `json:"is_hdr"`                                          // 是否HDR
+	VersionLabel         string            `json:"version_label"`                                   // 电影多版本时的版本名称，例如：2160p DV
+	IsDuplicateVersion   bool              `json:"is_duplicate_version"`                            // 是否是多版本中被淘汰的版本，淘汰的版本放在duplicates文件夹
 	VideoCodec           *VideoCodec       `json:"video_codec" gorm:"-"`                            // 视频编码，使用ffprobe提取
 	AudioCodec           []*AudioCodec     `json:"audio_codec" gorm:"-"`                            // 音频编码，使用ffprobe提取
 	SubtitleCodec        []*Subtitle       `json:"subtitle_codec" gorm:"-"`                         // 内封字幕流，使用ffprobe提取
@@ -581,93 +584,50 @@ func (sm *ScrapeMediaFile) FinishFromRenaming() {
 	sm.Save()
 }
 
+// GenerateNameByTemplate 使用模板生成文件夹或者文件名称，模板语法见NameTemplateData，旧格式的{title}模板会自动转换
 func (sm *ScrapeMediaFile) GenerateNameByTemplate(template string) string {
 	if template == "" {
-		// 替换占位符
-		template = "{title} ({year})"
+		template = DefaultFolderNameTemplate
 	}
-	newName := strings.ReplaceAll(template, "{title}", sm.Name)
-	newName = strings.ReplaceAll(newName, "{year}", strconv.Itoa(sm.Year))
-	if sm.Resolution != "" {
-		newName = strings.ReplaceAll(newName, "{resolution}", sm.Resolution)
-	} else {
-		newName = strings.ReplaceAll(newName, "{resolution}", "")
-	}
-	if sm.ResolutionLevel != "" {
-		newName = strings.ReplaceAll(newName, "{resolution_level}", sm.ResolutionLevel)
-	} else {
-		newName = strings.ReplaceAll(newName, "{resolution_level}", "")
-	}
-	if sm.VideoCodec != nil && sm.VideoCodec.Bitrate != 0 {
-		newName = strings.ReplaceAll(newName, "{bitrate}", fmt.Sprintf("%dMbps", sm.VideoCodec.Bitrate/1000000))
-	} else {
-		newName = strings.ReplaceAll(newName, "{bitrate}", "")
+	newName, err := RenderNameTemplate(template, sm.NameTemplateData())
+	if err != nil || newName == "" {
+		helpers.AppLogger.Errorf("使用模板 %s 生成名称失败，使用默认模板: %v", template, err)
+		newName, _ = RenderNameTemplate(DefaultFolderNameTemplate, sm.NameTemplateData())
 	}
-	if sm.TmdbId != 0 {
-		newName = strings.ReplaceAll(newName, "{tmdb_id}", fmt.Sprintf("{tmdbid-%d}", sm.TmdbId))
-	} else {
-		newName = strings.ReplaceAll(newName, "{tmdb_id}", "")
-	}
-	// 处理演员
-	actorName := ""
-	if sm.Media != nil && len(sm.Media.Actors) > 0 {
-		actorCount := len(sm.Media.Actors)
-		if actorCount >= 3 {
-			actorName = "多人演员"
-		} else if actorCount > 1 {
-			actorNames := make([]string, 0)
-			for _, actor := range sm.Media.Actors {
-				actorNames = append(actorNames, actor.Name)
-			}
-			newName = strings.ReplaceAll(newName, "{actors}", strings.Join(actorNames, ", "))
-		} else if actorCount == 1 {
-			actorName = sm.Media.Actors[0].Name
-		}
-	}
-	if actorName != "" {
-		newName = strings.ReplaceAll(newName, "{actors}", actorName)
-	} else {
-		newName = strings.ReplaceAll(newName, "{actors}", "")
+	return newName
+}
+
+// 使用指定的名字和年份重新刮削
+func (sm *ScrapeMediaFile) GetMetadataProvider() string {
+	if sm.MetadataProvider == "" {
+		return MetadataProviderTmdb
 	}
-	if sm.Media != nil && sm.Media.Num != "" {
-		newName = strings.ReplaceAll(newName, "{num}", sm.Media.Num)
-	} else {
-		newName = strings.ReplaceAll(newName, "{num}", "")
+	return sm.MetadataProvider
+}
+
+// 文件名中的ID标记，例如：{tmdbid-123}、{tvdbid-123}
+// Emby和Jellyfin只识别tmdbid、tvdbid等标记，Bangumi没有对应的标记，返回空字符串
+func (sm *ScrapeMediaFile) GetProviderIdTag() string {
+	switch sm.GetMetadataProvider() {
+	case MetadataProviderTmdb, MetadataProviderTvdb:
+		return fmt.Sprintf("{%sid-%d}", sm.GetMetadataProvider(), sm.TmdbId)
 	}
-	if sm.MediaType == MediaTypeTvShow {
-		if sm.SeasonNumber >= 0 {
-			// 季
-			newName = strings.ReplaceAll(newName, "{season_number}", fmt.Sprintf("%d", sm.SeasonNumber))
-		} else {
-			newName = strings.ReplaceAll(newName, "{season_number}", "")
-		}
-		// 集
-		if sm.EpisodeNumber > 0 {
-			newName = strings.ReplaceAll(newName, "{episode_number}", fmt.Sprintf("%d", sm.EpisodeNumber))
-		} else {
-			newName = strings.ReplaceAll(newName, "{episode_number}", "")
-		}
-		if sm.SeasonNumber >= 0 && sm.EpisodeNumber > 0 {
-			newName = strings.ReplaceAll(newName, "{season_episode}", fmt.Sprintf("S%02dE%02d", sm.SeasonNumber, sm.EpisodeNumber))
-		} else {
-			newName = strings.ReplaceAll(newName, "{season_episode}", "")
-		}
-		if sm.MediaEpisode != nil && sm.MediaEpisode.EpisodeName != "" {
-			newName = strings.ReplaceAll(newName, "{episode_name}", sm.MediaEpisode.EpisodeName)
-		} else {
-			newName = strings.ReplaceAll(newName, "{episode_name}", "")
+	return ""
+}
+
+// WhereTmdbId 按元数据提供者和ID查询，TmdbId字段保存的是该提供者中的ID，不同提供者的ID可能相同
+// 旧数据没有metadata_provider，按tmdb处理，Media和ScrapeMediaFile都可以使用
+func WhereTmdbId(provider string, tmdbId int64) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		if provider == "" || provider == MetadataProviderTmdb {
+			return tx.Where("tmdb_id = ? AND (metadata_provider = ? OR metadata_provider = '' OR metadata_provider IS NULL)", tmdbId, MetadataProviderTmdb)
 		}
+		return tx.Where("tmdb_id = ? AND metadata_provider = ?", tmdbId, provider)
 	}
-	return newName
 }
 
-// 使用指定的名字和年份重新刮削
-func (sm *ScrapeMediaFile) ReScrape(name string, year int, tmdbId int64, season int, episode int) error {
-	sm.TmdbId = tmdbId
-	sm.Name = name
-	sm.Year = year
-	// 使用该参数在tmdb搜索, 如果能搜到则更新tmdb_id
-	tmdbClient := GlobalScrapeSettings.GetTmdbClient()
+// 重新识别时使用输入的tmdbid或者名称+年份在tmdb查询
+func (sm *ScrapeMediaFile) reScrapeCheckTmdb(tmdbClient *tmdb.Client, tmdbId int64) error {
 	if sm.MediaType == MediaTypeTvShow {
 		if tmdbId > 0 {
 			// 使用tmdb直接查询详情
@@ -726,6 +686,31 @@ func (sm *ScrapeMediaFile) ReScrape(name string, year int, tmdbId int64, season
 			}
 		}
 	}
+	return nil
+}
+
+func (sm *ScrapeMediaFile) ReScrape(name string, year int, tmdbId int64, season int, episode int, provider string) error {
+	if provider == "" {
+		provider = MetadataProviderTmdb
+	}
+	if !IsValidMetadataProvider(provider) {
+		return fmt.Errorf("不支持的元数据提供者: %s", provider)
+	}
+	if provider != MetadataProviderTmdb && tmdbId == 0 {
+		return fmt.Errorf("使用 %s 重新识别时需要输入对应的ID", provider)
+	}
+	sm.TmdbId = tmdbId
+	sm.Name = name
+	sm.Year = year
+	sm.MetadataProvider = provider
+	tmdbClient := GlobalScrapeSettings.GetTmdbClient()
+	// 使用该参数在tmdb搜索, 如果能搜到则更新tmdb_id
+	// 其他元数据提供者直接使用输入的ID，名称和年份在刮削时补全
+	if provider == MetadataProviderTmdb {
+		if err := sm.reScrapeCheckTmdb(tmdbClient, tmdbId); err != nil {
+			return err
+		}
+	}
 	oldStatus := sm.Status
 
 	if oldStatus == ScrapeMediaStatusScrapeFailed || oldStatus == ScrapeMediaStatusScanned {
@@ -738,6 +723,7 @@ func (sm *ScrapeMediaFile) ReScrape(name string, year int, tmdbId int64, season
 			updateData["name"] = sm.Name
 			updateData["year"] = sm.Year
 			updateData["tmdb_id"] = sm.TmdbId
+			updateData["metadata_provider"] = sm.MetadataProvider
 			updateData["status"] = ScrapeMediaStatusScanned
 			updateData["media_season_id"] = 0
 			updateData["media_episode_id"] = 0
@@ -765,7 +751,14 @@ func (sm *ScrapeMediaFile) ReScrape(name string, year int, tmdbId int64, season
 
 			hasEdit := false
 			// 检查输入的季是否存在
-			if season > 0 {
+			if season > 0 && provider != MetadataProviderTmdb {
+				// 其他元数据提供者在刮削时才会查询季和集
+				sm.SeasonNumber = season
+				if episode > 0 {
+					sm.EpisodeNumber = episode
+				}
+				sm.Save()
+			} else if season > 0 {
 				tvSeason, err := tmdbClient.GetTvSeasonDetail(sm.TmdbId, season, GlobalScrapeSettings.GetTmdbLanguage())
 				if err != nil || tvSeason == nil {
 					serr := fmt.Errorf("查询tmdb剧集 季 %d 查询失败: %v", season, err)
@@ -811,6 +804,7 @@ func (sm *ScrapeMediaFile) ReScrape(name string, year int, tmdbId int64, season
 			updateData["name"] = sm.Name
 			updateData["year"] = sm.Year
 			updateData["tmdb_id"] = sm.TmdbId
+			updateData["metadata_provider"] = sm.MetadataProvider
 			updateData["status"] = ScrapeMediaStatusRollbacking
 			updateData["failed_reason"] = ""
 			if sm.TvshowPathId != "" {
@@ -1051,7 +1045,8 @@ func GetScrapeMediaByVideoFileId(videoFileId string) (*ScrapeMediaFile, error) {
 // id倒序
 // 先查询总数
 // 再查询列表
-func GetScrapeMediaFiles(page int, pageSize int, mediaType string, status string, name string) (int64, []*ScrapeMediaFile) {
+// scrapePathIds为nil时不限制刮削目录，操作员只能查询有权限的目录
+func GetScrapeMediaFiles(page int, pageSize int, mediaType string, status string, name string, scrapePathIds []uint) (int64, []*ScrapeMediaFile) {
 	offset := (page - 1) * pageSize
 	var scrapeMediaFiles []*ScrapeMediaFile
 	tx := db.Db.Order("id desc").Offset(offset).Limit(pageSize).Order("id DESC")
@@ -1071,6 +1066,10 @@ func GetScrapeMediaFiles(page int, pageSize int, mediaType string, status string
 		tx.Where("(path LIKE ? OR video_filename LIKE ? OR name LIKE ?)", fmt.Sprintf("%%%s%%", name), fmt.Sprintf("%%%s%%", name), fmt.Sprintf("%%%s%%", name))
 		txc.Where("(path LIKE ? OR video_filename LIKE ? OR name LIKE ?)", fmt.Sprintf("%%%s%%", name), fmt.Sprintf("%%%s%%", name), fmt.Sprintf("%%%s%%", name))
 	}
+	if scrapePathIds != nil {
+		tx.Where("scrape_path_id IN ?", scrapePathIds)
+		txc.Where("scrape_path_id IN ?", scrapePathIds)
+	}
 	err := tx.Find(&scrapeMediaFiles).Error
 	if err != nil {
 		helpers.AppLogger.Errorf("查询scrapemedia失败: %v", err)
@@ -1107,6 +1106,16 @@ func GetScannedScrapeMediaFiles(scrapePathId uint, mediaType MediaType, limit in
 	return DecodeScrapeMediaFile(scrapeMediaFiles)
 }
 
+// 查询待识别的记录，批量AI识别使用
+func GetUnidentifiedScrapeMediaFiles(scrapePathId uint, mediaType MediaType) []*ScrapeMediaFile {
+	var scrapeMediaFiles []*ScrapeMediaFile
+	if err := db.Db.Where("scrape_path_id = ? AND status = ? AND media_type = ? AND tmdb_id = 0 AND is_re_scrape = ?", scrapePathId, ScrapeMediaStatusScanned, mediaType, false).Order("id asc").Find(&scrapeMediaFiles).Error; err != nil {
+		helpers.AppLogger.Errorf("查询待识别文件失败: %v", err)
+		return nil
+	}
+	return scrapeMediaFiles
+}
+
 // 查询所有待刮削或者待整理的记录总数
 func GetScannedScrapeMediaFilesTotal(scrapePathId uint, mediaType MediaType) int64 {
 	var total int64
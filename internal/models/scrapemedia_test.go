@@ -0,0 +1,72 @@
+package models
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// 使用临时的sqlite数据库和日志文件，测试结束后恢复
+func openModelsTestDb(t *testing.T, tables ...any) *gorm.DB {
+	t.Helper()
+	dir := t.TempDir()
+	conn, err := gorm.Open(sqlite.Open(filepath.Join(dir, "test.db")), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	if err := conn.AutoMigrate(tables...); err != nil {
+		t.Fatalf("创建表失败: %v", err)
+	}
+	oldDb, oldConfigDir, oldLogger := db.Db, helpers.ConfigDir, helpers.AppLogger
+	db.Db = conn
+	helpers.ConfigDir = dir
+	helpers.AppLogger = helpers.NewLogger("test.log", false, false)
+	t.Cleanup(func() {
+		db.Db, helpers.ConfigDir, helpers.AppLogger = oldDb, oldConfigDir, oldLogger
+	})
+	return conn
+}
+
+func TestGetProviderIdTag(t *testing.T) {
+	cases := map[string]string{
+		"":                      "{tmdbid-100}",
+		MetadataProviderTmdb:    "{tmdbid-100}",
+		MetadataProviderTvdb:    "{tvdbid-100}",
+		MetadataProviderBangumi: "",
+	}
+	for provider, want := range cases {
+		sm := &ScrapeMediaFile{MetadataProvider: provider, TmdbId: 100}
+		if got := sm.GetProviderIdTag(); got != want {
+			t.Errorf("GetProviderIdTag(%s) = %q, want %q", provider, got, want)
+		}
+	}
+}
+
+func TestWhereTmdbId(t *testing.T) {
+	conn := openModelsTestDb(t, &Media{})
+	// 旧数据没有metadata_provider
+	conn.Create(&Media{Name: "旧数据", TmdbId: 100})
+	conn.Model(&Media{}).Where("name = ?", "旧数据").Update("metadata_provider", "")
+	conn.Create(&Media{Name: "TVDB", TmdbId: 100, MetadataProvider: MetadataProviderTvdb})
+	conn.Create(&Media{Name: "Bangumi", TmdbId: 100, MetadataProvider: MetadataProviderBangumi})
+	cases := map[string]string{
+		MetadataProviderTmdb:    "旧数据",
+		"":                      "旧数据",
+		MetadataProviderTvdb:    "TVDB",
+		MetadataProviderBangumi: "Bangumi",
+	}
+	for provider, want := range cases {
+		var list []*Media
+		conn.Scopes(WhereTmdbId(provider, 100)).Find(&list)
+		if len(list) != 1 || list[0].Name != want {
+			t.Errorf("WhereTmdbId(%q) = %+v, want %s", provider, list, want)
+		}
+	}
+	if media, err := GetMediaByTmdbId(MetadataProviderTvdb, 100); err != nil || media.Name != "TVDB" {
+		t.Errorf("GetMediaByTmdbId = %+v, %v", media, err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"Q115-STRM/internal/openai"
 	"Q115-STRM/internal/openlist"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -75,8 +76,8 @@ type ScrapePath struct {
 	DestPathId            string                       `json:"dest_path_id" form:"dest_path_id"`                         // 目标路径ID，如果是115则是FileId，如果是Local则为空字符串，如果是openlist则是远程路径ID
 	ScrapeType            ScrapeType                   `json:"scrape_type" form:"scrape_type"`                           // 刮削类型
 	RenameType            RenameType                   `json:"rename_type" form:"rename_type"`                           // 重命名类型，非本地仅支持移动重命名
-	FolderNameTemplate    string                       `json:"folder_name_template" form:"folder_name_template"`         // 文件夹名称模板，支持{{title}}、{{year}}、{{season}}、{{episode}}
-	FileNameTemplate      string                       `json:"file_name_template" form:"file_name_template"`             // 文件名称模板，支持{{title}}、{{year}}、{{season}}、{{episode}}
+	FolderNameTemplate    string                       `json:"folder_name_template" form:"folder_name_template"`         // 文件夹名称模板，Go模板语法，字段见NameTemplateData，例如：{{.Title}} ({{.Year}})
+	FileNameTemplate      string                       `json:"file_name_template" form:"file_name_template"`             // 文件名称模板，Go模板语法，字段见NameTemplateData，例如：{{.Title}} - S{{pad 2 .Season}}E{{pad 2 .Episode}}
 	DeletedKeyword        string                       `json:"-" form:"-"`                                               // 要删除的关键词，json字符串数组，识别时会将数组中包含的关键字全部替换为空字符串
 	DeleteKeyword         []string                     `json:"delete_keyword" form:"delete_keyword" gorm:"-"`            // 要删除的关键词，字符串数组，识别时会将数组中包含的关键字全部替换为空字符串
 	EnableCategory        bool                         `json:"enable_category" form:"enable_category"`                   // 是否启用分类，开启时会根据分类名称创建文件夹
@@ -89,21 +90,32 @@ type ScrapePath struct {
 	ForceDeleteSourcePath bool                         `json:"force_delete_source_path" form:"force_delete_source_path"` // 是否强制删除源路径，开启时会强制删除源路径下的所有文件，包括子目录
 	EnableCron            bool                         `json:"enable_cron" form:"enable_cron"`                           // 是否启用定时任务，开启时会根据定时任务规则定时刮削
 	EnableFanartTv        bool                         `json:"enable_fanart_tv" form:"enable_fanart_tv"`                 // 是否启用 fanart.tv，开启时会从 fanart.tv 下载高清图
+	EnableSubtitle        bool                         `json:"enable_subtitle" form:"enable_subtitle"`                   // 是否下载字幕，开启时刮削完成后为没有外挂字幕的视频下载字幕
+	AiBatch               bool                         `json:"ai_batch" form:"ai_batch"`                                 // 是否批量AI识别，开启时按文件夹分组一次请求识别多个文件，电视剧同时使用AI识别的季和集
 	IsScraping            bool                         `json:"is_scraping" form:"is_scraping"`                           // 是否正在刮削
 	MaxThreads            int                          `json:"max_threads" form:"max_threads"`                           // 刮削最大线程数，默认值为5
+	MetadataProviders     string                       `json:"-" form:"-"`                                               // 元数据提供者优先级，json字符串数组，例如："[\"tmdb\",\"tvdb\"]"
+	MetadataProviderList  []string                     `json:"metadata_providers" form:"metadata_providers" gorm:"-"`    // 元数据提供者优先级列表，识别时按顺序查询，前一个查询不到时使用下一个，为空则只使用tmdb
+	MultiVersion          bool                         `json:"multi_version" form:"multi_version"`                       // 电影多版本，开启时同一部电影的多个文件放在同一个文件夹，文件名加上 - [版本] 后缀，共用nfo和图片
+	KeepBestVersion       bool                         `json:"keep_best_version" form:"keep_best_version"`               // 多版本时只保留质量最好的版本，其他版本移动到duplicates文件夹
+	VersionRanking        string                       `json:"-" form:"-"`                                               // 版本质量排序规则，json字符串数组
+	VersionRankingList    []string                     `json:"version_ranking" form:"version_ranking" gorm:"-"`          // 版本质量排序规则，按顺序比较，为空时使用默认规则
 	V115Client            *v115open.OpenClient         `json:"-" gorm:"-"`                                               // 115客户端
 	BaiduPanClient        *baidupan.Client             `json:"-" gorm:"-"`                                               // 百度网盘客户端
 	OpenListClient        *openlist.Client             `json:"-" gorm:"-"`                                               // openlist客户端
+	WebDavClient          *webdav.Client               `json:"-" gorm:"-"`                                               // WebDAV客户端
 	ExistsFiles           map[string]bool              `json:"-" gorm:"-"`                                               // 已存在的文件，key为文件路径，value为是否存在
 	ScrapeRootPath        string                       `json:"-" gorm:"-"`                                               // 刮削根路径
 	Category              ScrapePathCategoryCollection `json:"-" gorm:"-"`
 	CategoryMap           map[uint]string              `json:"-" gorm:"-"`
 	// 完成的电视剧缓存，每次启动整理时清除，防止多次操作电视剧完成
-	TvshowRenamedCache   map[uint]bool         `json:"-" gorm:"-"`
-	EpisodeFinishChannel chan *ScrapeMediaFile `json:"-" gorm:"-"`
-	Running              bool                  `json:"-" gorm:"-"`                   // 是否运行中
-	mutex                sync.RWMutex          `json:"-" gorm:"-"`                   // 读写锁
-	IsTaskRunning        int                   `json:"is_running" form:"-" gorm:"-"` // 是否正在运行
+	TvshowRenamedCache   map[uint]bool                `json:"-" gorm:"-"`
+	EpisodeFinishChannel chan *ScrapeMediaFile        `json:"-" gorm:"-"`
+	Running              bool                         `json:"-" gorm:"-"` // 是否运行中
+	AiBatchResults       map[uint]*openai.MediaFileAI `json:"-" gorm:"-"` // 批量AI识别的结果，key为刮削文件ID
+	aiBatchMutex         sync.RWMutex                 `json:"-" gorm:"-"`
+	mutex                sync.RWMutex                 `json:"-" gorm:"-"`                   // 读写锁
+	IsTaskRunning        int                          `json:"is_running" form:"-" gorm:"-"` // 是否正在运行
 }
 
 type ScrapeStrmPath struct {
@@ -141,6 +153,15 @@ func (sp *ScrapePath) SetNotRunning() {
 // 添加或者编辑同步目录
 // 不能编辑同步源类型、网盘账号、媒体类型
 func (m *ScrapePath) Save() error {
+	// 旧格式的模板转换为新的模板语法
+	m.FolderNameTemplate = ConvertLegacyNameTemplate(m.FolderNameTemplate)
+	m.FileNameTemplate = ConvertLegacyNameTemplate(m.FileNameTemplate)
+	if err := ValidateNameTemplate(m.FolderNameTemplate); err != nil {
+		return fmt.Errorf("文件夹名称模板错误: %v", err)
+	}
+	if err := ValidateNameTemplate(m.FileNameTemplate); err != nil {
+		return fmt.Errorf("文件名称模板错误: %v", err)
+	}
 	// 转换媒体文件扩展名列表为json字符串
 	if len(m.VideoExtList) == 0 {
 		m.VideoExtList = helpers.GlobalConfig.Strm.VideoExt
@@ -162,6 +183,37 @@ func (m *ScrapePath) Save() error {
 	} else {
 		m.DeletedKeyword = ""
 	}
+	// 转换元数据提供者列表为json字符串
+	providers := make([]string, 0, len(m.MetadataProviderList))
+	for _, provider := range m.MetadataProviderList {
+		if !IsValidMetadataProvider(provider) {
+			return fmt.Errorf("不支持的元数据提供者: %s", provider)
+		}
+		if !slices.Contains(providers, provider) {
+			providers = append(providers, provider)
+		}
+	}
+	if len(providers) > 0 {
+		providerJson, err := json.Marshal(providers)
+		if err != nil {
+			helpers.AppLogger.Errorf("转换元数据提供者列表失败: %v", err)
+			return err
+		}
+		m.MetadataProviders = string(providerJson)
+	} else {
+		m.MetadataProviders = ""
+	}
+	// 转换版本排序规则为json字符串
+	for _, criterion := range m.VersionRankingList {
+		if !slices.Contains(VersionRankCriteria, criterion) {
+			return fmt.Errorf("不支持的版本排序规则: %s", criterion)
+		}
+	}
+	if len(m.VersionRankingList) > 0 {
+		m.VersionRanking = helpers.JsonString(m.VersionRankingList)
+	} else {
+		m.VersionRanking = ""
+	}
 	if m.ID == 0 {
 		if m.MaxThreads > DEFAULT_LOCAL_MAX_THREADS {
 			if m.SourceType != SourceTypeLocal || GlobalScrapeSettings.TmdbApiKey == "" {
@@ -213,7 +265,13 @@ func (m *ScrapePath) Save() error {
 			"exclude_no_image_actor":   m.ExcludeNoImageActor,
 			"force_delete_source_path": m.ForceDeleteSourcePath,
 			"enable_fanart_tv":         m.EnableFanartTv,
+			"enable_subtitle":          m.EnableSubtitle,
+			"ai_batch":                 m.AiBatch,
 			"max_threads":              m.MaxThreads,
+			"metadata_providers":       m.MetadataProviders,
+			"multi_version":            m.MultiVersion,
+			"keep_best_version":        m.KeepBestVersion,
+			"version_ranking":          m.VersionRanking,
 		}
 		if oldScrapePath.ScrapeType != ScrapeTypeOnly && m.ScrapeType == ScrapeTypeOnly {
 			updates["dest_path"] = m.SourcePath
@@ -265,6 +323,8 @@ func (sp *ScrapePath) Init() bool {
 				return false
 			}
 			// helpers.AppLogger.Infof("获取OpenList客户端成功")
+		case SourceTypeWebDav:
+			sp.WebDavClient = account.GetWebDavClient()
 		}
 	}
 	// 创建临时目录
@@ -365,6 +425,9 @@ func (sp *ScrapePath) GetDownloadUrl(videoPathOrUrl string) string {
 		videoPathOrUrl = sp.V115Client.GetDownloadUrl(context.Background(), videoPathOrUrl, v115open.DEFAULTUA, false)
 	case SourceTypeOpenList:
 		videoPathOrUrl = sp.OpenListClient.GetRawUrl(videoPathOrUrl)
+	case SourceTypeWebDav:
+		// 带账号密码的地址，只给ffprobe使用
+		videoPathOrUrl = sp.WebDavClient.GetRawUrl(videoPathOrUrl)
 	case SourceType123:
 	}
 	return videoPathOrUrl
@@ -503,6 +566,13 @@ func (sp *ScrapePath) GenerateCategory() {
 				helpers.AppLogger.Errorf("创建OpenList目录失败: %v", err)
 				continue
 			}
+		case SourceTypeWebDav:
+			fileId = sp.DestPathId + "/" + category.Name
+			err = sp.WebDavClient.MkdirAll(context.Background(), fileId)
+			if err != nil {
+				helpers.AppLogger.Errorf("创建WebDAV目录失败: %v", err)
+				continue
+			}
 		case SourceTypeLocal:
 			fileId = filepath.Join(sp.DestPathId, category.Name)
 			os.MkdirAll(fileId, 0777)
@@ -591,6 +661,30 @@ func (sp *ScrapePath) GetAiPrompt() string {
 	return prompt
 }
 
+// 保存批量AI识别的结果
+func (sp *ScrapePath) SetAiBatchResult(mediaFileId uint, result *openai.MediaFileAI) {
+	sp.aiBatchMutex.Lock()
+	defer sp.aiBatchMutex.Unlock()
+	if sp.AiBatchResults == nil {
+		sp.AiBatchResults = make(map[uint]*openai.MediaFileAI)
+	}
+	sp.AiBatchResults[mediaFileId] = result
+}
+
+// 清空批量AI识别的结果，每次刮削开始时调用，避免重新刮削时使用上一次的结果
+func (sp *ScrapePath) ClearAiBatchResults() {
+	sp.aiBatchMutex.Lock()
+	defer sp.aiBatchMutex.Unlock()
+	sp.AiBatchResults = nil
+}
+
+// 查询批量AI识别的结果，没有识别过返回nil
+func (sp *ScrapePath) GetAiBatchResult(mediaFileId uint) *openai.MediaFileAI {
+	sp.aiBatchMutex.RLock()
+	defer sp.aiBatchMutex.RUnlock()
+	return sp.AiBatchResults[mediaFileId]
+}
+
 // 打开或关闭定时任务
 func (sp *ScrapePath) ToggleCron() error {
 	if sp.EnableCron {
@@ -714,9 +808,41 @@ func (sp *ScrapePath) Decode() error {
 	} else {
 		sp.DeleteKeyword = []string{}
 	}
+	sp.decodeMetadataProvider()
+	sp.VersionRankingList = []string{}
+	if sp.VersionRanking != "" {
+		if err := json.Unmarshal([]byte(sp.VersionRanking), &sp.VersionRankingList); err != nil {
+			return fmt.Errorf("转换版本排序规则失败: %v", err)
+		}
+	}
 	return nil
 }
 
+func (sp *ScrapePath) decodeMetadataProvider() {
+	sp.MetadataProviderList = []string{}
+	if sp.MetadataProviders == "" {
+		return
+	}
+	if err := json.Unmarshal([]byte(sp.MetadataProviders), &sp.MetadataProviderList); err != nil {
+		helpers.AppLogger.Errorf("转换元数据提供者列表失败: %v", err)
+	}
+}
+
+// 获取元数据提供者的优先级列表，没有设置时只使用tmdb
+// 其他类型（nfo）不需要元数据提供者，也返回tmdb保持兼容
+func (sp *ScrapePath) GetMetadataProviders() []string {
+	providers := make([]string, 0, len(sp.MetadataProviderList))
+	for _, provider := range sp.MetadataProviderList {
+		if IsValidMetadataProvider(provider) && !slices.Contains(providers, provider) {
+			providers = append(providers, provider)
+		}
+	}
+	if len(providers) == 0 || sp.MediaType == MediaTypeOther {
+		return []string{MetadataProviderTmdb}
+	}
+	return providers
+}
+
 func GetScrapePathCategoryById(id uint) *ScrapePathCategory {
 	var spc ScrapePathCategory
 	if err := db.Db.Where("id = ?", id).First(&spc).Error; err != nil {
@@ -749,6 +875,7 @@ func GetScrapePathes() []*ScrapePath {
 			} else {
 				scrapePath.DeleteKeyword = []string{}
 			}
+			scrapePath.decodeMetadataProvider()
 		}
 	}
 	return scrapePathes
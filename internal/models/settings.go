@@ -4,6 +4,8 @@ import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/notificationmanager"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"strings"
 )
@@ -40,14 +42,20 @@ type Settings struct {
 	BaseModel
 	SettingThreads
 	SettingStrm
-	UseTelegram      int8   `json:"use_telegram"`                 // @deprecated 已迁移到TelegramChannelConfig 是否使用Telegram Bot通知
-	TelegramBotToken string `json:"telegram_bot_token"`           // @deprecated 已迁移到TelegramChannelConfig Telegram Bot Token
-	TelegramChatId   string `json:"telegram_chat_id"`             // @deprecated 已迁移到TelegramChannelConfig Telegram Chat ID
-	MeoWName         string `json:"meow_name"`                    // @deprecated 已迁移到MeoWChannelConfig MeoW昵称，用于发送MeoW消息
-	EmbyUrl          string `json:"emby_url"`                     // @deprecated 已迁移到EmbyConfig Emby的主机地址
-	EmbyApiKey       string `json:"emby_api_key"`                 // @deprecated 已迁移到EmbyConfig Emby的API Key
-	HttpProxy        string `json:"http_proxy"`                   // HTTP代理地址
-	LocalProxy       int    `json:"local_proxy" gorm:"default:0"` // 是否启用本地代理，0表示不启用，1表示启用
+	UseTelegram      int8   `json:"use_telegram"`                           // @deprecated 已迁移到TelegramChannelConfig 是否使用Telegram Bot通知
+	TelegramBotToken string `json:"telegram_bot_token"`                     // @deprecated 已迁移到TelegramChannelConfig Telegram Bot Token
+	TelegramChatId   string `json:"telegram_chat_id"`                       // @deprecated 已迁移到TelegramChannelConfig Telegram Chat ID
+	MeoWName         string `json:"meow_name"`                              // @deprecated 已迁移到MeoWChannelConfig MeoW昵称，用于发送MeoW消息
+	EmbyUrl          string `json:"emby_url"`                               // @deprecated 已迁移到EmbyConfig Emby的主机地址
+	EmbyApiKey       string `json:"emby_api_key"`                           // @deprecated 已迁移到EmbyConfig Emby的API Key
+	HttpProxy        string `json:"http_proxy"`                             // HTTP代理地址
+	LocalProxy       int    `json:"local_proxy" gorm:"default:0"`           // 是否启用本地代理，0表示不启用，1表示启用
+	WebDavServer     int    `json:"webdav_server" gorm:"default:0"`         // 是否启用内置WebDAV服务，0表示不启用，1表示启用
+	WebDavServerMode string `json:"webdav_server_mode" gorm:"default:strm"` // 内置WebDAV服务的视频呈现方式，strm或者video
+	ProxyAllowHosts  string `json:"proxy_allow_hosts"`                      // 本地代理额外允许反代的域名，逗号或换行分隔，内置网盘CDN域名不需要填写
+	ProxyGlobalLimit int64  `json:"proxy_global_limit"`                     // 本地代理总带宽限制，单位KB/s，0表示不限制
+	ProxyClientLimit int64  `json:"proxy_client_limit"`                     // 本地代理单个客户端（IP）的带宽限制，单位KB/s，0表示不限制
+	SignSecret       string `json:"-"`                                      // 播放链接签名的密钥，每次安装随机生成
 }
 
 func (t SettingThreads) ToMap() map[string]any {
@@ -229,6 +237,38 @@ func (settings *Settings) UpdateHttpProxy(httpProxy string) bool {
 	return true
 }
 
+// 更新内置WebDAV服务设置
+func (settings *Settings) UpdateWebDavServer(enable int, mode string) bool {
+	settings.WebDavServer = enable
+	settings.WebDavServerMode = mode
+	updateData := make(map[string]any)
+	updateData["web_dav_server"] = enable
+	updateData["web_dav_server_mode"] = mode
+	err := db.Db.Model(settings).Where("id = ?", settings.ID).Updates(updateData).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("更新WebDAV服务设置失败: %v", err)
+		return false
+	}
+	return true
+}
+
+// 更新本地代理的白名单和带宽限制
+func (settings *Settings) UpdateProxy(allowHosts string, globalLimit, clientLimit int64) bool {
+	settings.ProxyAllowHosts = allowHosts
+	settings.ProxyGlobalLimit = globalLimit
+	settings.ProxyClientLimit = clientLimit
+	updateData := make(map[string]any)
+	updateData["proxy_allow_hosts"] = allowHosts
+	updateData["proxy_global_limit"] = globalLimit
+	updateData["proxy_client_limit"] = clientLimit
+	err := db.Db.Model(settings).Where("id = ?", settings.ID).Updates(updateData).Error
+	if err != nil {
+		helpers.AppLogger.Errorf("更新本地代理设置失败: %v", err)
+		return false
+	}
+	return true
+}
+
 func (settings *Settings) UpdateStrm(req SettingStrm) bool {
 	strm := req.EncodeArr()
 	if strm == nil {
@@ -259,6 +299,18 @@ func LoadSettings() {
 		SettingsGlobal.MinVideoSize = 100
 		db.Db.Save(SettingsGlobal)
 	}
+	if SettingsGlobal.SignSecret == "" {
+		// 配置文件中的JwtSecret默认值是公开的，不能用来签名播放链接
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			helpers.AppLogger.Errorf("生成播放链接签名密钥失败: %v", err)
+			return
+		}
+		SettingsGlobal.SignSecret = hex.EncodeToString(secret)
+		if err := db.Db.Model(SettingsGlobal).Update("sign_secret", SettingsGlobal.SignSecret).Error; err != nil {
+			helpers.AppLogger.Errorf("保存播放链接签名密钥失败: %v", err)
+		}
+	}
 }
 
 func InitNotificationManager() {
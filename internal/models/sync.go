@@ -207,14 +207,19 @@ func (s *Sync) InitLogger() {
 }
 
 // 获取所有同步记录
-func GetSyncRecords(page, pageSize int) ([]*Sync, int64, error) {
+// syncPathIds为nil时不限制同步目录，操作员只能查询有权限的目录
+func GetSyncRecords(page, pageSize int, syncPathIds []uint) ([]*Sync, int64, error) {
+	tx := db.Db.Model(&Sync{})
+	if syncPathIds != nil {
+		tx = tx.Where("sync_path_id IN ?", syncPathIds)
+	}
 	var count int64
-	if err := db.Db.Model(&Sync{}).Count(&count).Error; err != nil {
+	if err := tx.Count(&count).Error; err != nil {
 		helpers.AppLogger.Errorf("统计同步记录总数失败: %v", err)
 		return nil, 0, err
 	}
 	var syncs []*Sync
-	if err := db.Db.Offset((page - 1) * pageSize).Limit(pageSize).Order("id DESC").Find(&syncs).Error; err != nil {
+	if err := tx.Offset((page - 1) * pageSize).Limit(pageSize).Order("id DESC").Find(&syncs).Error; err != nil {
 		helpers.AppLogger.Errorf("获取同步记录失败: %v", err)
 		return nil, 0, err
 	}
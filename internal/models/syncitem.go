@@ -25,6 +25,7 @@ type SyncFile struct {
 	FileType      v115open.FileType `json:"file_type"`
 	PickCode      string            `json:"pick_code" gorm:"index:pick_code"`
 	Sha1          string            `json:"sha1"`
+	Md5           string            `json:"md5"`                                          // 123云盘返回的etag是文件的MD5，不能和SHA1混用
 	MTime         int64             `json:"mtime"`                                        // 最后修改时间
 	LocalFilePath string            `json:"local_file_path" gorm:"index:local_file_path"` // 本地文件路径，包含文件名
 	Path          string            `json:"path"`                                         // 绝对路径，不包含FileName
@@ -37,6 +38,7 @@ type SyncFile struct {
 	Uploaded      bool              `json:"uploaded"`      // 是否上传完成，未上传完成的记录不触发删除
 	ThumbUrl      string            `json:"thumb_url"`     // 缩略图URL
 	Processed     bool              `json:"processed"`     // 是否已处理
+	ETag          string            `json:"etag"`          // WebDAV返回的ETag，用于判断文件内容是否变化
 }
 
 func (sf *SyncFile) GetAccount() *Account {
@@ -74,6 +76,27 @@ func GetFileByPickCode(pickCode string) *SyncFile {
 	return db115File
 }
 
+// 按STRM文件的本地路径批量查询网盘文件大小，内置WebDAV服务的虚拟视频文件使用
+func GetFileSizeByLocalFilePaths(localFilePaths []string) map[string]int64 {
+	sizes := make(map[string]int64, len(localFilePaths))
+	if len(localFilePaths) == 0 {
+		return sizes
+	}
+	var files []*SyncFile
+	db.Db.Model(&SyncFile{}).Select("local_file_path", "file_size").Where("local_file_path IN ?", localFilePaths).Find(&files)
+	for _, file := range files {
+		sizes[file.LocalFilePath] = file.FileSize
+	}
+	return sizes
+}
+
+// 统计同步目录已知的文件数量（不含目录）
+func CountFilesBySyncPathId(syncPathId uint) int64 {
+	var count int64
+	db.Db.Model(&SyncFile{}).Where("sync_path_id = ? AND file_type = ?", syncPathId, v115open.TypeFile).Count(&count)
+	return count
+}
+
 func GetFilesBySyncPathId(syncPathId uint, offset, limit int) ([]*SyncFile, error) {
 	var syncFiles []*SyncFile
 	err := db.Db.Model(&SyncFile{}).Where("sync_path_id = ?", syncPathId).Offset(offset).Limit(limit).Find(&syncFiles).Error
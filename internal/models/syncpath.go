@@ -20,6 +20,8 @@ const (
 	SourceType123       SourceType = "123"
 	SourceTypeOpenList  SourceType = "openlist"
 	SourceTypeBaiduPan  SourceType = "baidupan"
+	SourceTypeWebDav    SourceType = "webdav"
+	SourceTypeS3        SourceType = "s3"
 	SourceTypeEmbyMedia SourceType = "emby媒体信息提取" // emby媒体信息提取专用
 )
 
@@ -35,6 +37,10 @@ func (s SourceType) String() string {
 		return "开放列表"
 	case SourceTypeBaiduPan:
 		return "百度网盘"
+	case SourceTypeWebDav:
+		return "WebDAV"
+	case SourceTypeS3:
+		return "S3"
 	case SourceTypeEmbyMedia:
 		return "Emby媒体信息提取"
 	default:
@@ -52,10 +58,15 @@ type SyncPath struct {
 	SourceType   SourceType `json:"source_type"`            // 同步源类型，主要分为：115网盘，本地目录，123网盘，无法编辑
 	AccountId    uint       `json:"account_id"`             // 115账号ID或者123账号ID，根据SourceType决定，无法编辑
 	EnableCron   bool       `json:"enable_cron"`            // 是否启用定时同步
+	EnableWatch  bool       `json:"enable_watch"`           // 是否启用实时监控，仅本地同步源支持
 	LastSyncAt   int64      `json:"last_sync_at"`           // 上次同步时间
 	AccountName  string     `json:"account_name" gorm:"-"`  // 115账号名或者123账号名，不参与数据库操作，仅供前端使用
 	IsFullSync   bool       `json:"is_full_sync"`           // 是否全量同步，默认false
 	IsRunning    int        `json:"is_running" gorm:"-"`    // 是否正在运行 0-未运行，1-已在队列，2-正在运行
+	// 删除保护：单次同步要删除的本地文件超过阈值时中止同步，避免网盘接口异常或挂载失效时清空本地文件
+	MaxDeleteCount   int64 `json:"max_delete_count"`   // 单次同步最多删除的本地文件数量，0为不限制
+	MaxDeletePercent int64 `json:"max_delete_percent"` // 单次同步最多删除的本地文件占已知文件的百分比，0为不限制
+	ConfirmDelete    bool  `json:"confirm_delete"`     // 确认下一次同步可以超过删除阈值，同步完成后自动还原
 }
 
 func GetStrmSettingDefault() SettingStrm {
@@ -257,6 +268,33 @@ func (sp *SyncPath) ToggleCron() {
 	db.Db.Save(sp)
 }
 
+func (sp *SyncPath) ToggleWatch() {
+	sp.EnableWatch = !sp.EnableWatch
+	db.Db.Save(sp)
+}
+
+// 设置删除保护阈值
+func (sp *SyncPath) SetDeleteLimit(maxDeleteCount, maxDeletePercent int64) {
+	sp.MaxDeleteCount = max(maxDeleteCount, 0)
+	sp.MaxDeletePercent = min(max(maxDeletePercent, 0), 100)
+	db.Db.Model(sp).Updates(map[string]any{
+		"max_delete_count":   sp.MaxDeleteCount,
+		"max_delete_percent": sp.MaxDeletePercent,
+	})
+}
+
+// 确认下一次同步可以超过删除阈值
+func (sp *SyncPath) SetConfirmDelete(confirmDelete bool) {
+	sp.ConfirmDelete = confirmDelete
+	db.Db.Model(sp).Update("confirm_delete", confirmDelete)
+}
+
+// 设置是否启用实时监控，只有本地同步源可以开启
+func (sp *SyncPath) SetEnableWatch(enableWatch bool) {
+	sp.EnableWatch = enableWatch && sp.SourceType == SourceTypeLocal
+	db.Db.Model(sp).Update("enable_watch", sp.EnableWatch)
+}
+
 func (sp *SyncPath) IsValidVideoExt(name string) bool {
 	ext := filepath.Ext(name)
 	ext = strings.ToLower(ext)
@@ -287,7 +325,7 @@ func (sp *SyncPath) MakeFullLocalPath(pid, name string) string {
 	switch sp.SourceType {
 	case SourceType115:
 		return filepath.Join(sp.LocalPath, sp.RemotePath, pid, name)
-	case SourceTypeOpenList:
+	case SourceTypeOpenList, SourceTypeWebDav, SourceTypeS3:
 		return filepath.Join(sp.LocalPath, pid, name)
 	case SourceTypeLocal:
 		return filepath.Join(sp.LocalPath, pid, name)
@@ -399,6 +437,19 @@ func GetSyncPathById(id uint) *SyncPath {
 
 // 查询同步路径列表
 func GetSyncPathList(page, pageSize int, enableCron bool, sourceType SourceType) ([]*SyncPath, int64) {
+	return getSyncPathList(page, pageSize, enableCron, sourceType, nil)
+}
+
+// 查询指定ID范围内的同步路径列表，用于操作员只查看有权限的同步目录
+func GetSyncPathListByIds(page, pageSize int, sourceType SourceType, ids []uint) ([]*SyncPath, int64) {
+	if len(ids) == 0 {
+		return []*SyncPath{}, 0
+	}
+	return getSyncPathList(page, pageSize, false, sourceType, ids)
+}
+
+// ids为nil时不限制ID
+func getSyncPathList(page, pageSize int, enableCron bool, sourceType SourceType, ids []uint) ([]*SyncPath, int64) {
 	var syncPaths []*SyncPath
 	var total int64
 
@@ -417,6 +468,9 @@ func GetSyncPathList(page, pageSize int, enableCron bool, sourceType SourceType)
 	if sourceType != "" {
 		query.Where("source_type = ?", sourceType)
 	}
+	if ids != nil {
+		query.Where("id IN ?", ids)
+	}
 	query.Count(&total)
 	query.Offset(offset).Limit(pageSize).Order("id DESC").Find(&syncPaths)
 	accountCache := make(map[uint]*Account)
@@ -448,6 +502,23 @@ func GetSyncPathList(page, pageSize int, enableCron bool, sourceType SourceType)
 	return syncPaths, total
 }
 
+// 获取所有同步路径，按ID升序
+func GetAllSyncPaths() []*SyncPath {
+	var syncPaths []*SyncPath
+	db.Db.Order("id ASC").Find(&syncPaths)
+	return syncPaths
+}
+
+// 获取所有启用了实时监控的本地同步路径
+func GetWatchSyncPaths() []*SyncPath {
+	var syncPaths []*SyncPath
+	db.Db.Where("source_type = ? AND enable_watch = ?", SourceTypeLocal, true).Find(&syncPaths)
+	for _, syncPath := range syncPaths {
+		syncPath.ParseVideoAndMetaExt()
+	}
+	return syncPaths
+}
+
 // 根据账号ID获取同步路径列表
 func GetAllSyncPathByAccountId(accountId uint) []SyncPath {
 	var syncPaths []SyncPath
@@ -3,15 +3,35 @@ package models
 import (
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/helpers"
+	"encoding/json"
+	"errors"
+	"slices"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+type UserRole string
+
+const (
+	UserRoleAdmin    UserRole = "admin"    // 管理员，拥有所有权限
+	UserRoleOperator UserRole = "operator" // 操作员，只能操作指定的同步目录和刮削目录
+	UserRoleViewer   UserRole = "viewer"   // 只读用户，只能查看记录和日志
+)
+
 type User struct {
 	BaseModel
-	Username string `gorm:"unique;not null" json:"username"`
-	Password string `gorm:"not null"`
+	Username          string   `gorm:"unique;not null" json:"username"`
+	Password          string   `gorm:"not null" json:"-"`
+	Role              UserRole `gorm:"default:admin" json:"role"` // 角色，旧用户默认为管理员
+	SyncPathIds       string   `json:"-" gorm:"type:text"`        // 操作员可操作的同步目录ID，json数字数组
+	ScrapePathIds     string   `json:"-" gorm:"type:text"`        // 操作员可操作的刮削目录ID，json数字数组
+	SyncPathIdArray   []uint   `json:"sync_path_ids" gorm:"-"`    // 同步目录ID数组
+	ScrapePathIdArray []uint   `json:"scrape_path_ids" gorm:"-"`  // 刮削目录ID数组
+}
+
+func IsValidUserRole(role UserRole) bool {
+	return role == UserRoleAdmin || role == UserRoleOperator || role == UserRoleViewer
 }
 
 // 表名
@@ -43,15 +63,178 @@ func (user *User) ChangeUsernameAndPassword(username, newPassword string) (bool,
 	return isChange, nil
 }
 
+// 角色为空的旧用户视为管理员
+func (user *User) GetRole() UserRole {
+	if user.Role == "" {
+		return UserRoleAdmin
+	}
+	return user.Role
+}
+
+func (user *User) IsAdmin() bool {
+	return user.GetRole() == UserRoleAdmin
+}
+
+// 将ID的json字符串转为数组
+func (user *User) decodePathIds() {
+	user.SyncPathIdArray = []uint{}
+	user.ScrapePathIdArray = []uint{}
+	if user.SyncPathIds != "" {
+		if err := json.Unmarshal([]byte(user.SyncPathIds), &user.SyncPathIdArray); err != nil {
+			helpers.AppLogger.Errorf("转换用户 %s 的同步目录ID失败: %v", user.Username, err)
+		}
+	}
+	if user.ScrapePathIds != "" {
+		if err := json.Unmarshal([]byte(user.ScrapePathIds), &user.ScrapePathIdArray); err != nil {
+			helpers.AppLogger.Errorf("转换用户 %s 的刮削目录ID失败: %v", user.Username, err)
+		}
+	}
+}
+
+// 是否可以访问同步目录，操作员只能访问指定的目录
+// 只读用户可以查看所有目录，修改类请求已经按角色拦截
+func (user *User) CanAccessSyncPath(syncPathId uint) bool {
+	if user.GetRole() == UserRoleOperator {
+		return slices.Contains(user.SyncPathIdArray, syncPathId)
+	}
+	return true
+}
+
+// 是否可以访问刮削目录，操作员只能访问指定的目录
+// 只读用户可以查看所有目录，修改类请求已经按角色拦截
+func (user *User) CanAccessScrapePath(scrapePathId uint) bool {
+	if user.GetRole() == UserRoleOperator {
+		return slices.Contains(user.ScrapePathIdArray, scrapePathId)
+	}
+	return true
+}
+
+// 修改用户的角色和可操作的目录
+// 非操作员不保存目录限制
+func (user *User) UpdateRole(role UserRole, syncPathIds, scrapePathIds []uint) error {
+	if !IsValidUserRole(role) {
+		return errors.New("无效的用户角色")
+	}
+	if user.IsAdmin() && role != UserRoleAdmin && CountAdminUsers() <= 1 {
+		return errors.New("不能修改最后一个管理员的角色")
+	}
+	if role != UserRoleOperator {
+		syncPathIds = []uint{}
+		scrapePathIds = []uint{}
+	}
+	syncPathIdsJson, _ := json.Marshal(syncPathIds)
+	scrapePathIdsJson, _ := json.Marshal(scrapePathIds)
+	updates := map[string]any{
+		"role":            role,
+		"sync_path_ids":   string(syncPathIdsJson),
+		"scrape_path_ids": string(scrapePathIdsJson),
+	}
+	if err := db.Db.Model(user).Updates(updates).Error; err != nil {
+		helpers.AppLogger.Errorf("修改用户 %s 的角色失败: %v", user.Username, err)
+		return err
+	}
+	user.Role = role
+	user.SyncPathIds = string(syncPathIdsJson)
+	user.ScrapePathIds = string(scrapePathIdsJson)
+	user.decodePathIds()
+	return nil
+}
+
+// 重置用户密码，不校验旧密码，仅管理员可用
+func (user *User) ResetPassword(newPassword string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.MinCost)
+	if err != nil {
+		helpers.AppLogger.Warnf("生成用户新密码失败: %v", err)
+		return err
+	}
+	if err := db.Db.Model(user).Update("password", string(hash)).Error; err != nil {
+		helpers.AppLogger.Errorf("重置用户 %s 的密码失败: %v", user.Username, err)
+		return err
+	}
+	user.Password = string(hash)
+	return nil
+}
+
+// 创建用户
+func CreateUser(username, password string, role UserRole, syncPathIds, scrapePathIds []uint) (*User, error) {
+	if !IsValidUserRole(role) {
+		return nil, errors.New("无效的用户角色")
+	}
+	var count int64
+	db.Db.Model(&User{}).Where("username = ?", username).Count(&count)
+	if count > 0 {
+		return nil, errors.New("用户名已存在")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		helpers.AppLogger.Warnf("生成用户密码失败: %v", err)
+		return nil, err
+	}
+	if role != UserRoleOperator {
+		syncPathIds = []uint{}
+		scrapePathIds = []uint{}
+	}
+	syncPathIdsJson, _ := json.Marshal(syncPathIds)
+	scrapePathIdsJson, _ := json.Marshal(scrapePathIds)
+	user := &User{
+		Username:      username,
+		Password:      string(hash),
+		Role:          role,
+		SyncPathIds:   string(syncPathIdsJson),
+		ScrapePathIds: string(scrapePathIdsJson),
+	}
+	if err := db.Db.Create(user).Error; err != nil {
+		helpers.AppLogger.Errorf("创建用户 %s 失败: %v", username, err)
+		return nil, err
+	}
+	user.decodePathIds()
+	return user, nil
+}
+
+// 删除用户，同时删除用户的API Key，不能删除最后一个管理员
+func DeleteUser(userId uint) error {
+	user, err := GetUserById(userId)
+	if err != nil {
+		return err
+	}
+	if user.IsAdmin() && CountAdminUsers() <= 1 {
+		return errors.New("不能删除最后一个管理员")
+	}
+	return db.Db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userId).Delete(&ApiKey{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&User{}, userId).Error
+	})
+}
+
+// 查询所有用户
+func GetUserList() []*User {
+	var users []*User
+	db.Db.Model(&User{}).Order("id ASC").Find(&users)
+	for _, user := range users {
+		user.decodePathIds()
+	}
+	return users
+}
+
+// 统计管理员数量，角色为空的旧用户也算管理员
+func CountAdminUsers() int64 {
+	var count int64
+	db.Db.Model(&User{}).Where("role = ? OR role = '' OR role IS NULL", UserRoleAdmin).Count(&count)
+	return count
+}
+
 // 根据用户ID查询用户
 func GetUserById(userId uint) (*User, error) {
 	user := &User{}
 	result := db.Db.First(user, userId)
 	if result.Error != nil {
-		helpers.AppLogger.Errorf("查询用户失败: %v", db.Db.Error)
+		helpers.AppLogger.Errorf("查询用户失败: %v", result.Error)
 		// 如果没有，则返回Nil
-		return user, db.Db.Error
+		return user, result.Error
 	}
+	user.decodePathIds()
 	return user, nil
 }
 
@@ -65,6 +248,7 @@ func GetUserByUsername(username string) (*User, error) {
 		// 如果没有，则返回Nil
 		return user, result.Error
 	}
+	user.decodePathIds()
 	return user, nil
 }
 
@@ -20,8 +20,10 @@ type TelegramChannelConfig struct {
 	BotToken  string `json:"bot_token"`
 	ChatID    string `json:"chat_id"`
 	ProxyURL  string `json:"proxy_url"`
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	// 除ChatID外允许通过机器人管理任务的会话ID，逗号分隔
+	AllowedChatIDs string `json:"allowed_chat_ids"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
 }
 
 // MeoWChannelConfig MeoW渠道配置
@@ -35,8 +35,9 @@ type TelegramChannelHandler struct {
 	proxyURL       string // 系统代理URL
 	bot            *helpers.TelegramBot
 	initOnce       sync.Once
-	stopChan       chan struct{}                    // 用于停止信号
+	cancel         context.CancelFunc               // 用于停止监听
 	customCommands map[string]func([]string) string // 保存从外部注入的命令
+	interactive    *helpers.TelegramInteractive     // 保存从外部注入的交互式命令和按钮回调
 }
 
 func NewTelegramChannelHandler(config *notification.TelegramChannelConfig) *TelegramChannelHandler {
@@ -142,6 +143,7 @@ func (h *TelegramChannelHandler) initBot() error {
 	if h.bot == nil {
 		return fmt.Errorf("创建Telegram机器人失败")
 	}
+	h.bot.AllowedChatIDs = helpers.SplitTelegramChatIDs(h.config.AllowedChatIDs)
 	h.bot.SetMenuContent()
 	return err
 }
@@ -150,6 +152,10 @@ func (h *TelegramChannelHandler) SetCommands(cmds map[string]func([]string) stri
 	h.customCommands = cmds
 }
 
+func (h *TelegramChannelHandler) SetInteractive(interactive *helpers.TelegramInteractive) {
+	h.interactive = interactive
+}
+
 // Start 实现 BackgroundHandler 接口
 func (h *TelegramChannelHandler) Start(ctx context.Context) {
 	if err := h.initBot(); err != nil {
@@ -157,21 +163,24 @@ func (h *TelegramChannelHandler) Start(ctx context.Context) {
 		return
 	}
 
+	// 重新加载渠道时停止旧的监听，避免同一个Token重复拉取消息
+	ctx, h.cancel = context.WithCancel(ctx)
+
 	// 在协程中运行监听，避免阻塞主进程
 	go func() {
 		helpers.AppLogger.Infof("Telegram Bot 监听协程启动...")
 
 		// 调用你现有的监听逻辑，并把自定义命令传进去
 		// 注意：我们需要对 StartListening 做一点小改动，让它能感知 ctx
-		h.bot.StartListening(ctx, h.customCommands)
+		h.bot.StartListening(ctx, h.customCommands, h.interactive)
 
 		helpers.AppLogger.Infof("Telegram Bot 监听协程已安全退出")
 	}()
 }
 
 func (h *TelegramChannelHandler) Stop() {
-	if h.stopChan != nil {
-		close(h.stopChan)
+	if h.cancel != nil {
+		h.cancel()
 	}
 }
 
@@ -21,6 +21,9 @@ type EnhancedNotificationManager struct {
 	mu          sync.RWMutex
 	db          *gorm.DB
 	getProxyURL func() string // 获取代理URL的回调函数
+	// 已注册的Telegram命令，重新加载渠道时重新注入
+	telegramCommands    map[string]func([]string) string
+	telegramInteractive *helpers.TelegramInteractive
 }
 
 type channelInfo struct {
@@ -218,6 +221,10 @@ func (m *EnhancedNotificationManager) ReloadChannel(channelID uint) error {
 	if err != nil {
 		return err
 	}
+	if tg, ok := handler.(*TelegramChannelHandler); ok {
+		tg.SetCommands(m.telegramCommands)
+		tg.SetInteractive(m.telegramInteractive)
+	}
 
 	m.handlers[channelID] = &channelInfo{
 		handler: handler,
@@ -249,6 +256,7 @@ func (m *EnhancedNotificationManager) RegisterTelegramCommands(cmds map[string]f
 	m.mu.Lock() // 修改内部状态，加写锁
 	defer m.mu.Unlock()
 
+	m.telegramCommands = cmds
 	for _, info := range m.handlers {
 		// 类型断言：检查这个 handler 是不是 TelegramChannelHandler
 		if tg, ok := info.handler.(*TelegramChannelHandler); ok {
@@ -256,3 +264,16 @@ func (m *EnhancedNotificationManager) RegisterTelegramCommands(cmds map[string]f
 		}
 	}
 }
+
+// RegisterTelegramInteractive 将交互式命令和按钮回调注入到所有 Telegram 渠道中
+func (m *EnhancedNotificationManager) RegisterTelegramInteractive(interactive *helpers.TelegramInteractive) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.telegramInteractive = interactive
+	for _, info := range m.handlers {
+		if tg, ok := info.handler.(*TelegramChannelHandler); ok {
+			tg.SetInteractive(interactive)
+		}
+	}
+}
@@ -1,8 +1,10 @@
 package open123
 
 import (
+	"Q115-STRM/internal/helpers"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"time"
 )
 
@@ -22,7 +24,67 @@ type TokenResult struct {
 	Data    TokenResponse `json:"data"`
 }
 
+// OAuth授权刷新token的响应，没有外层的code和data
+type OAuthTokenResponse struct {
+	TokenType    string `json:"token_type"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	Scope        string `json:"scope"`
+}
+
+// 刷新访问凭证
+// 有refreshToken时走OAuth刷新，否则使用clientID+clientSecret换取
+// 刷新成功后通知models保存新的token
 func (c *Client) performTokenRefresh() error {
+	c.tokenMu.RLock()
+	refreshToken := c.refreshToken
+	c.tokenMu.RUnlock()
+	var accessToken string
+	var expiredAt time.Time
+	if refreshToken != "" {
+		oauthToken, err := c.refreshOAuthToken(refreshToken)
+		if err != nil {
+			return err
+		}
+		accessToken = oauthToken.AccessToken
+		refreshToken = oauthToken.RefreshToken
+		expiredAt = time.Now().Add(time.Duration(oauthToken.ExpiresIn) * time.Second)
+	} else {
+		tokenData, err := c.requestClientToken()
+		if err != nil {
+			return err
+		}
+		accessToken = tokenData.AccessToken
+		expiredAt, err = time.Parse(time.RFC3339, tokenData.ExpiredAt)
+		if err != nil {
+			return fmt.Errorf("parse expired time failed: %w", err)
+		}
+	}
+
+	c.tokenMu.Lock()
+	c.accessToken = accessToken
+	c.refreshToken = refreshToken
+	c.expiredAt = expiredAt
+	c.tokenMu.Unlock()
+
+	if c.AccountId > 0 {
+		helpers.PublishSync(helpers.Save123TokenEvent, map[string]any{
+			"account_id":    c.AccountId,
+			"token":         accessToken,
+			"refresh_token": refreshToken,
+			"expired_at":    expiredAt.Unix(),
+		})
+	}
+	return nil
+}
+
+// 使用clientID+clientSecret换取访问凭证
+// POST /api/v1/access_token
+func (c *Client) requestClientToken() (*TokenResponse, error) {
+	if c.clientID == "" || c.clientSecret == "" {
+		return nil, fmt.Errorf("clientID or clientSecret is empty")
+	}
 	url := fmt.Sprintf("%s/api/v1/access_token", c.baseURL)
 
 	req := TokenRequest{
@@ -32,7 +94,7 @@ func (c *Client) performTokenRefresh() error {
 
 	body, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Errorf("marshal token request failed: %w", err)
+		return nil, fmt.Errorf("marshal token request failed: %w", err)
 	}
 
 	resp, err := c.client.R().
@@ -43,34 +105,66 @@ func (c *Client) performTokenRefresh() error {
 		Post(url)
 
 	if err != nil {
-		return fmt.Errorf("request token failed: %w", err)
+		return nil, fmt.Errorf("request token failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if !resp.IsSuccess() {
-		return fmt.Errorf("token request failed with status: %s", resp.Status())
+		return nil, fmt.Errorf("token request failed with status: %s", resp.Status())
 	}
 
 	result := &TokenResult{}
 	if err := json.Unmarshal(resp.Bytes(), result); err != nil {
-		return fmt.Errorf("unmarshal token response failed: %w", err)
+		return nil, fmt.Errorf("unmarshal token response failed: %w", err)
 	}
 
-	if result.Code != 0 {
-		return fmt.Errorf("token request failed: code=%d, message=%s", result.Code, result.Message)
+	if err := checkCode("token request", result.Code, result.Message); err != nil {
+		return nil, err
 	}
+	return &result.Data, nil
+}
+
+// 使用refresh_token刷新OAuth访问凭证
+// POST /api/v1/oauth2/access_token
+func (c *Client) refreshOAuthToken(refreshToken string) (*OAuthTokenResponse, error) {
+	params := url.Values{}
+	params.Set("client_id", c.clientID)
+	params.Set("client_secret", c.clientSecret)
+	params.Set("grant_type", "refresh_token")
+	params.Set("refresh_token", refreshToken)
+	requestURL := fmt.Sprintf("%s/api/v1/oauth2/access_token?%s", c.baseURL, params.Encode())
 
-	expiredAt, err := time.Parse(time.RFC3339, result.Data.ExpiredAt)
+	resp, err := c.client.R().
+		SetHeader("Platform", "open_platform").
+		SetHeader("User-Agent", c.ua).
+		Post(requestURL)
 	if err != nil {
-		return fmt.Errorf("parse expired time failed: %w", err)
+		return nil, fmt.Errorf("refresh oauth token failed: %w", err)
 	}
+	defer resp.Body.Close()
 
-	c.tokenMu.Lock()
-	c.accessToken = result.Data.AccessToken
-	c.expiredAt = expiredAt
-	c.tokenMu.Unlock()
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("refresh oauth token failed with status: %s", resp.Status())
+	}
 
-	return nil
+	result := &OAuthTokenResponse{}
+	if err := json.Unmarshal(resp.Bytes(), result); err != nil {
+		return nil, fmt.Errorf("unmarshal oauth token response failed: %w", err)
+	}
+	if result.AccessToken == "" {
+		// 失败时返回的是通用结构
+		errResult := &RespBase[any]{}
+		_ = json.Unmarshal(resp.Bytes(), errResult)
+		return nil, fmt.Errorf("refresh oauth token failed: code=%d, message=%s", errResult.Code, errResult.Message)
+	}
+	return result, nil
+}
+
+// 主动刷新一次访问凭证，用于定时任务或者创建账号时校验凭据
+func (c *Client) RefreshToken() error {
+	c.isRefreshing.Lock()
+	defer c.isRefreshing.Unlock()
+	return c.performTokenRefresh()
 }
 
 func (c *Client) GetAccessToken() string {
@@ -79,6 +173,12 @@ func (c *Client) GetAccessToken() string {
 	return c.accessToken
 }
 
+func (c *Client) GetRefreshToken() string {
+	c.tokenMu.RLock()
+	defer c.tokenMu.RUnlock()
+	return c.refreshToken
+}
+
 func (c *Client) GetExpiredAt() time.Time {
 	c.tokenMu.RLock()
 	defer c.tokenMu.RUnlock()
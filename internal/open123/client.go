@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -12,9 +13,11 @@ import (
 )
 
 type Client struct {
+	AccountId    uint
 	clientID     string
 	clientSecret string
 	accessToken  string
+	refreshToken string // OAuth授权时才有，有值则使用refresh_token刷新，否则使用clientID+clientSecret换取
 	expiredAt    time.Time
 	baseURL      string
 	ua           string
@@ -22,13 +25,16 @@ type Client struct {
 
 	tokenMu          sync.RWMutex
 	isRefreshing     sync.Mutex
-	refreshOnce      sync.Once
-	refreshTokenChan chan struct{}
+	refreshTokenChan chan struct{} // 刷新完成时关闭并替换，等待刷新的请求通过它得到通知
 
 	limiterLock sync.RWMutex
 	limiters    map[string]*rate.Limiter
 }
 
+// 全局客户端缓存，key为账号ID
+var cachedClients map[string]*Client = make(map[string]*Client, 0)
+var cachedClientsMutex sync.Mutex
+
 func NewClient(clientID, clientSecret string) *Client {
 	client := resty.New()
 	client.SetTimeout(time.Duration(DEFAULT_TIMEOUT) * time.Second)
@@ -44,8 +50,52 @@ func NewClient(clientID, clientSecret string) *Client {
 	}
 }
 
+// 获取账号对应的客户端，同一个账号共用一个客户端（共享限速器和token）
+// expiredAt: token过期时间戳
+func GetClient(accountId uint, clientID, clientSecret, accessToken, refreshToken string, expiredAt int64) *Client {
+	cachedClientsMutex.Lock()
+	defer cachedClientsMutex.Unlock()
+	clientKey := fmt.Sprintf("%d", accountId)
+	if client, exists := cachedClients[clientKey]; exists {
+		client.clientID = clientID
+		client.clientSecret = clientSecret
+		// 数据库中的token更新时才覆盖，避免用旧token覆盖客户端自己刷新的新token
+		if expiredAt > client.GetExpiredAt().Unix() {
+			client.SetToken(accessToken, refreshToken, expiredAt)
+		}
+		return client
+	}
+	client := NewClient(clientID, clientSecret)
+	client.AccountId = accountId
+	client.initDefaultRateLimits()
+	client.SetToken(accessToken, refreshToken, expiredAt)
+	cachedClients[clientKey] = client
+	return client
+}
+
+// 账号删除或者凭据修改后，从缓存中移除客户端
+func RemoveClient(accountId uint) {
+	cachedClientsMutex.Lock()
+	defer cachedClientsMutex.Unlock()
+	clientKey := fmt.Sprintf("%d", accountId)
+	if client, exists := cachedClients[clientKey]; exists {
+		client.Close()
+		delete(cachedClients, clientKey)
+	}
+}
+
+func (c *Client) SetToken(accessToken, refreshToken string, expiredAt int64) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.accessToken = accessToken
+	c.refreshToken = refreshToken
+	c.expiredAt = time.Unix(expiredAt, 0)
+}
+
+// 123开放平台的限流按接口计算，这里按路径前缀设置
 func (c *Client) initDefaultRateLimits() {
 	c.SetRateLimit("/api/v1/", 10)
+	c.SetRateLimit("/upload/v1/", 5)
 	c.SetRateLimit("/upload/v2/", 5)
 	c.SetRateLimit("/api/v2/", 15)
 }
@@ -64,9 +114,20 @@ func (c *Client) Close() error {
 	return nil
 }
 
+// 查找匹配的限速器，优先完全匹配，然后使用最长的前缀匹配
 func (c *Client) waitForPermission(ctx context.Context, path string) error {
 	c.limiterLock.RLock()
 	limiter, exists := c.limiters[path]
+	if !exists {
+		matchedLen := 0
+		for prefix, l := range c.limiters {
+			if strings.HasPrefix(path, prefix) && len(prefix) > matchedLen {
+				limiter = l
+				matchedLen = len(prefix)
+				exists = true
+			}
+		}
+	}
 	c.limiterLock.RUnlock()
 
 	if exists {
@@ -126,23 +187,27 @@ func (c *Client) isTokenExpiredLocked() bool {
 	return time.Until(c.expiredAt) <= 30*time.Second
 }
 
+// 多个请求同时发现token过期时，只有一个请求执行刷新，其他请求等待刷新完成
 func (c *Client) ensureValidAccessToken(ctx context.Context) error {
-	var refreshErr error
-	c.refreshOnce.Do(func() {
-		refreshErr = c.refreshAccessToken()
-		if refreshErr != nil {
-			c.refreshOnce = sync.Once{}
-		} else {
-			close(c.refreshTokenChan)
-		}
-	})
+	c.tokenMu.RLock()
+	waitChan := c.refreshTokenChan
+	c.tokenMu.RUnlock()
 
-	if refreshErr != nil {
-		return refreshErr
+	if c.isRefreshing.TryLock() {
+		err := c.refreshAccessToken()
+		c.tokenMu.Lock()
+		close(c.refreshTokenChan)
+		c.refreshTokenChan = make(chan struct{})
+		c.tokenMu.Unlock()
+		c.isRefreshing.Unlock()
+		return err
 	}
 
 	select {
-	case <-c.refreshTokenChan:
+	case <-waitChan:
+		if c.isTokenExpired() {
+			return fmt.Errorf("token refresh failed")
+		}
 		return nil
 	case <-ctx.Done():
 		return ctx.Err()
@@ -151,16 +216,10 @@ func (c *Client) ensureValidAccessToken(ctx context.Context) error {
 	}
 }
 
+// 调用方需要持有isRefreshing锁
 func (c *Client) refreshAccessToken() error {
-	c.isRefreshing.Lock()
-	defer c.isRefreshing.Unlock()
-
-	c.tokenMu.RLock()
-	if !c.isTokenExpiredLocked() {
-		c.tokenMu.RUnlock()
+	if !c.isTokenExpired() {
 		return nil
 	}
-	c.tokenMu.RUnlock()
-
-	return nil
+	return c.performTokenRefresh()
 }
@@ -7,4 +7,14 @@ const (
 	DEFAULT_RETRY_DELAY = 1
 	DEFAULT_TIMEOUT     = 30
 	DEFAULTUA           = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/138.0.0.0 Safari/537.36 Edg/138.0.0.0"
+
+	// 单步上传的文件大小上限，1GB
+	SINGLE_UPLOAD_MAX_SIZE = 1 << 30
+	// 文件列表每页最大数量
+	FILE_LIST_MAX_LIMIT = 100
+)
+
+const (
+	FileTypeFile = 0
+	FileTypeDir  = 1
 )
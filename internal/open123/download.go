@@ -6,20 +6,12 @@ import (
 	"fmt"
 )
 
+// 获取文件下载地址，需要开通直链或下载权限
+// GET /api/v1/file/download_info
 func (c *Client) GetFileDownloadInfo(ctx context.Context, fileID int64) (*FileDownloadInfoResponse, error) {
-	url := fmt.Sprintf("%s/api/v1/file/download_info", c.baseURL)
+	url := fmt.Sprintf("%s/api/v1/file/download_info?fileId=%d", c.baseURL, fileID)
 
-	req := FileDownloadInfoRequest{
-		FileID:  fileID,
-		DriveID: 0,
-	}
-
-	body, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("marshal download info request failed: %w", err)
-	}
-
-	resp, err := c.doRequest(ctx, "POST", url, body)
+	resp, err := c.doRequest(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -34,8 +26,8 @@ func (c *Client) GetFileDownloadInfo(ctx context.Context, fileID int64) (*FileDo
 		return nil, fmt.Errorf("unmarshal download info response failed: %w", err)
 	}
 
-	if result.Code != 0 {
-		return nil, fmt.Errorf("get download info failed: code=%d, message=%s", result.Code, result.Message)
+	if err := checkCode("get download info", result.Code, result.Message); err != nil {
+		return nil, err
 	}
 
 	return &result.Data, nil
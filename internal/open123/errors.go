@@ -1,6 +1,9 @@
 package open123
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
 
 type APIError struct {
 	Code    int
@@ -30,15 +33,25 @@ const (
 )
 
 func IsTokenExpired(err error) bool {
-	if apiErr, ok := err.(*APIError); ok {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
 		return apiErr.Code == ErrCodeUnauthorized
 	}
 	return false
 }
 
 func IsRateLimited(err error) bool {
-	if apiErr, ok := err.(*APIError); ok {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
 		return apiErr.Code == ErrCodeRateLimit
 	}
 	return false
 }
+
+// 将接口返回的业务错误码转换为APIError，code为0时返回nil
+func checkCode(action string, code int, message string) error {
+	if code == ErrCodeSuccess {
+		return nil
+	}
+	return fmt.Errorf("%s failed: %w", action, NewAPIError(code, message))
+}
@@ -4,10 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 )
 
-func (c *Client) ListFiles(ctx context.Context, parentFileID int64, page, pageSize int) (*FileListResponse, error) {
-	url := fmt.Sprintf("%s/api/v2/file/list?driveID=0&parentFileID=%d&page=%d&pageSize=%d", c.baseURL, parentFileID, page, pageSize)
+// 获取目录下的文件列表（单页）
+// lastFileID: 上一页返回的lastFileId，首页传0
+// GET /api/v2/file/list
+func (c *Client) ListFiles(ctx context.Context, parentFileID int64, lastFileID int64, limit int) (*FileListResponse, error) {
+	if limit <= 0 || limit > FILE_LIST_MAX_LIMIT {
+		limit = FILE_LIST_MAX_LIMIT
+	}
+	url := fmt.Sprintf("%s/api/v2/file/list?parentFileId=%d&limit=%d", c.baseURL, parentFileID, limit)
+	if lastFileID > 0 {
+		url += fmt.Sprintf("&lastFileId=%d", lastFileID)
+	}
 
 	resp, err := c.doRequest(ctx, "GET", url, nil)
 	if err != nil {
@@ -24,20 +34,144 @@ func (c *Client) ListFiles(ctx context.Context, parentFileID int64, page, pageSi
 		return nil, fmt.Errorf("unmarshal list files response failed: %w", err)
 	}
 
-	if result.Code != 0 {
-		return nil, fmt.Errorf("list files failed: code=%d, message=%s", result.Code, result.Message)
+	if err := checkCode("list files", result.Code, result.Message); err != nil {
+		return nil, err
 	}
 
 	return &result.Data, nil
 }
 
+// 获取目录下的全部文件，自动翻页，过滤掉回收站中的文件
+func (c *Client) ListAllFiles(ctx context.Context, parentFileID int64) ([]FileInfo, error) {
+	files := make([]FileInfo, 0)
+	var lastFileID int64 = 0
+	for {
+		resp, err := c.ListFiles(ctx, parentFileID, lastFileID, FILE_LIST_MAX_LIMIT)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range resp.FileList {
+			if file.Trashed == 1 {
+				continue
+			}
+			files = append(files, file)
+		}
+		if resp.LastFileID == -1 || len(resp.FileList) == 0 {
+			break
+		}
+		lastFileID = resp.LastFileID
+	}
+	return files, nil
+}
+
+// 获取单个文件详情
+// GET /api/v1/file/detail
+func (c *Client) GetFileDetail(ctx context.Context, fileID int64) (*FileDetail, error) {
+	url := fmt.Sprintf("%s/api/v1/file/detail?fileID=%d", c.baseURL, fileID)
+
+	resp, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("get file detail failed with status: %s", resp.Status())
+	}
+
+	result := &RespBase[FileDetail]{}
+	if err := json.Unmarshal(resp.Bytes(), result); err != nil {
+		return nil, fmt.Errorf("unmarshal file detail response failed: %w", err)
+	}
+
+	if err := checkCode("get file detail", result.Code, result.Message); err != nil {
+		return nil, err
+	}
+
+	return &result.Data, nil
+}
+
+// 在父目录下按名称查找文件或目录，找不到返回nil
+func (c *Client) FindChild(ctx context.Context, parentFileID int64, name string) (*FileInfo, error) {
+	files, err := c.ListAllFiles(ctx, parentFileID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		if files[i].FileName == name {
+			return &files[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// 将路径拆分为每一级目录名，忽略空段
+func splitPath(path string) []string {
+	path = strings.ReplaceAll(path, "\\", "/")
+	parts := make([]string, 0)
+	for _, part := range strings.Split(path, "/") {
+		if part == "" || part == "." {
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// 根据完整路径逐级查询目录ID，根目录ID为0
+func (c *Client) GetDirIdByPath(ctx context.Context, path string) (int64, error) {
+	var parentID int64 = 0
+	for _, name := range splitPath(path) {
+		child, err := c.FindChild(ctx, parentID, name)
+		if err != nil {
+			return 0, err
+		}
+		if child == nil || !child.IsDir() {
+			return 0, fmt.Errorf("path %s not found: %s does not exist", path, name)
+		}
+		parentID = child.FileID
+	}
+	return parentID, nil
+}
+
+// 逐级创建目录，已存在的目录直接复用，返回最后一级目录的ID
+// onCreate: 每新建一级目录时回调，参数为目录ID、父目录ID和完整路径
+func (c *Client) MkdirAll(ctx context.Context, path string, onCreate func(dirID, parentID int64, dirPath string)) (int64, error) {
+	var parentID int64 = 0
+	currentPath := ""
+	for _, name := range splitPath(path) {
+		currentPath += "/" + name
+		child, err := c.FindChild(ctx, parentID, name)
+		if err != nil {
+			return 0, err
+		}
+		if child != nil {
+			if !child.IsDir() {
+				return 0, fmt.Errorf("%s is not a directory", currentPath)
+			}
+			parentID = child.FileID
+			continue
+		}
+		folder, err := c.CreateFolder(ctx, name, parentID)
+		if err != nil {
+			return 0, err
+		}
+		if onCreate != nil {
+			onCreate(folder.DirID, parentID, currentPath)
+		}
+		parentID = folder.DirID
+	}
+	return parentID, nil
+}
+
+// 创建目录
+// POST /upload/v1/file/mkdir
 func (c *Client) CreateFolder(ctx context.Context, name string, parentFileID int64) (*CreateFolderResponse, error) {
-	url := fmt.Sprintf("%s/api/v1/dir/create", c.baseURL)
+	url := fmt.Sprintf("%s/upload/v1/file/mkdir", c.baseURL)
 
 	req := CreateFolderRequest{
-		DriveID:      0,
-		ParentFileID: parentFileID,
-		DirName:      name,
+		Name:     name,
+		ParentID: parentFileID,
 	}
 
 	body, err := json.Marshal(req)
@@ -60,24 +194,32 @@ func (c *Client) CreateFolder(ctx context.Context, name string, parentFileID int
 		return nil, fmt.Errorf("unmarshal create folder response failed: %w", err)
 	}
 
-	if result.Code != 0 {
-		return nil, fmt.Errorf("create folder failed: code=%d, message=%s", result.Code, result.Message)
+	if err := checkCode("create folder", result.Code, result.Message); err != nil {
+		return nil, err
 	}
 
 	return &result.Data, nil
 }
 
-func (c *Client) DeleteFile(ctx context.Context, fileID int64) error {
-	url := fmt.Sprintf("%s/api/v1/file/delete", c.baseURL)
-
-	req := DeleteRequest{
-		FileID:  fileID,
-		DriveID: 0,
+// 将文件或目录移入回收站，一次最多100个
+// 123云盘没有直接彻底删除的接口（彻底删除只能删除回收站中的文件），同步删除都走回收站，误删可以找回
+// POST /api/v1/file/trash
+func (c *Client) TrashFiles(ctx context.Context, fileIDs []int64) error {
+	for start := 0; start < len(fileIDs); start += 100 {
+		end := min(start+100, len(fileIDs))
+		if err := c.trashFiles(ctx, fileIDs[start:end]); err != nil {
+			return err
+		}
 	}
+	return nil
+}
 
-	body, err := json.Marshal(req)
+func (c *Client) trashFiles(ctx context.Context, fileIDs []int64) error {
+	url := fmt.Sprintf("%s/api/v1/file/trash", c.baseURL)
+
+	body, err := json.Marshal(TrashRequest{FileIDs: fileIDs})
 	if err != nil {
-		return fmt.Errorf("marshal delete file request failed: %w", err)
+		return fmt.Errorf("marshal trash request failed: %w", err)
 	}
 
 	resp, err := c.doRequest(ctx, "POST", url, body)
@@ -87,52 +229,49 @@ func (c *Client) DeleteFile(ctx context.Context, fileID int64) error {
 	defer resp.Body.Close()
 
 	if !resp.IsSuccess() {
-		return fmt.Errorf("delete file failed with status: %s", resp.Status())
+		return fmt.Errorf("trash files failed with status: %s", resp.Status())
 	}
 
 	result := &RespBase[any]{}
 	if err := json.Unmarshal(resp.Bytes(), result); err != nil {
-		return fmt.Errorf("unmarshal delete file response failed: %w", err)
+		return fmt.Errorf("unmarshal trash response failed: %w", err)
 	}
 
-	if result.Code != 0 {
-		return fmt.Errorf("delete file failed: code=%d, message=%s", result.Code, result.Message)
-	}
+	return checkCode("trash files", result.Code, result.Message)
+}
 
-	return nil
+func (c *Client) DeleteFile(ctx context.Context, fileID int64) error {
+	return c.TrashFiles(ctx, []int64{fileID})
 }
 
+// 123云盘中目录也是文件，删除方式相同
 func (c *Client) DeleteFolder(ctx context.Context, dirID int64) error {
-	url := fmt.Sprintf("%s/api/v1/dir/delete", c.baseURL)
-
-	req := DeleteFolderRequest{
-		DirID:   dirID,
-		DriveID: 0,
-	}
+	return c.TrashFiles(ctx, []int64{dirID})
+}
 
-	body, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("marshal delete folder request failed: %w", err)
-	}
+// 获取用户信息，用于校验凭据和显示账号状态
+// GET /api/v1/user/info
+func (c *Client) GetUserInfo(ctx context.Context) (*UserInfo, error) {
+	url := fmt.Sprintf("%s/api/v1/user/info", c.baseURL)
 
-	resp, err := c.doRequest(ctx, "POST", url, body)
+	resp, err := c.doRequest(ctx, "GET", url, nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if !resp.IsSuccess() {
-		return fmt.Errorf("delete folder failed with status: %s", resp.Status())
+		return nil, fmt.Errorf("get user info failed with status: %s", resp.Status())
 	}
 
-	result := &RespBase[any]{}
+	result := &RespBase[UserInfo]{}
 	if err := json.Unmarshal(resp.Bytes(), result); err != nil {
-		return fmt.Errorf("unmarshal delete folder response failed: %w", err)
+		return nil, fmt.Errorf("unmarshal user info response failed: %w", err)
 	}
 
-	if result.Code != 0 {
-		return fmt.Errorf("delete folder failed: code=%d, message=%s", result.Code, result.Message)
+	if err := checkCode("get user info", result.Code, result.Message); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &result.Data, nil
 }
@@ -1,5 +1,7 @@
 package open123
 
+import "time"
+
 type RespBase[T any] struct {
 	XTraceID string `json:"x-traceID"`
 	Code     int    `json:"code"`
@@ -12,6 +14,7 @@ type FileUploadCreateRequest struct {
 	Filename     string `json:"filename"`
 	Etag         string `json:"etag"`
 	Size         int64  `json:"size"`
+	Duplicate    int    `json:"duplicate,omitempty"` // 1-保留两者 2-覆盖原文件
 }
 
 type FileUploadCreateResponse struct {
@@ -19,65 +22,96 @@ type FileUploadCreateResponse struct {
 	UploadID     string `json:"uploadID"`
 	PartSize     int64  `json:"partSize"`
 	AlreadyExist bool   `json:"alreadyExist"`
+	Completed    bool   `json:"completed"`
 }
 
 type UploadDomainResponse struct {
 	Domains []string `json:"data"`
 }
 
+// 123云盘的时间都是北京时间，格式为 2006-01-02 15:04:05
+var cstZone = time.FixedZone("CST", 8*3600)
+
+const timeLayout = "2006-01-02 15:04:05"
+
 type FileInfo struct {
-	FileID     int64  `json:"fileID"`
-	FileName   string `json:"fileName"`
-	FileSize   int64  `json:"fileSize"`
-	CreateTime int64  `json:"createTime"`
-	UpdateTime int64  `json:"updateTime"`
-	FileType   int    `json:"fileType"`
-	Etag       string `json:"etag"`
+	FileID       int64  `json:"fileId"`
+	FileName     string `json:"filename"`
+	ParentFileID int64  `json:"parentFileId"`
+	Type         int    `json:"type"` // 0-文件 1-文件夹
+	Etag         string `json:"etag"`
+	Size         int64  `json:"size"`
+	Category     int    `json:"category"`
+	Status       int    `json:"status"`
+	Trashed      int    `json:"trashed"` // 1-在回收站中
+	CreateAt     string `json:"createAt"`
+	UpdateAt     string `json:"updateAt"`
+}
+
+func (f *FileInfo) IsDir() bool {
+	return f.Type == FileTypeDir
 }
 
-type FileListRequest struct {
-	DriveID      int64 `json:"driveID"`
-	ParentFileID int64 `json:"parentFileID"`
-	Page         int   `json:"page"`
-	PageSize     int   `json:"pageSize"`
+// 最后修改时间，转换为时间戳
+func (f *FileInfo) GetMtime() int64 {
+	t, err := time.ParseInLocation(timeLayout, f.UpdateAt, cstZone)
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
 }
 
 type FileListResponse struct {
-	Total    int64      `json:"total"`
-	NextPage int        `json:"nextPage"`
-	FileList []FileInfo `json:"fileList"`
+	LastFileID int64      `json:"lastFileId"` // -1代表最后一页
+	FileList   []FileInfo `json:"fileList"`
 }
 
-type CreateFolderRequest struct {
-	DriveID      int64  `json:"driveID"`
+type FileDetail struct {
+	FileID       int64  `json:"fileID"`
+	FileName     string `json:"filename"`
+	Type         int    `json:"type"`
+	Size         int64  `json:"size"`
+	Etag         string `json:"etag"`
+	Status       int    `json:"status"`
 	ParentFileID int64  `json:"parentFileID"`
-	DirName      string `json:"dirName"`
+	CreateAt     string `json:"createAt"`
+	Trashed      int    `json:"trashed"`
 }
 
-type CreateFolderResponse struct {
-	DirID       int64 `json:"dirID"`
-	ParentDirID int64 `json:"parentDirID"`
+func (f *FileDetail) GetCtime() int64 {
+	t, err := time.ParseInLocation(timeLayout, f.CreateAt, cstZone)
+	if err != nil {
+		return 0
+	}
+	return t.Unix()
 }
 
-type DeleteRequest struct {
-	FileID  int64 `json:"fileID"`
-	DriveID int64 `json:"driveID"`
+type CreateFolderRequest struct {
+	Name     string `json:"name"`
+	ParentID int64  `json:"parentID"`
 }
 
-type DeleteFolderRequest struct {
-	DirID   int64 `json:"dirID"`
-	DriveID int64 `json:"driveID"`
+type CreateFolderResponse struct {
+	DirID int64 `json:"dirID"`
 }
 
-type FileDownloadInfoRequest struct {
-	FileID  int64 `json:"fileID" form:"fileID"`
-	DriveID int64 `json:"driveID"`
+type TrashRequest struct {
+	FileIDs []int64 `json:"fileIDs"`
 }
 
 type FileDownloadInfoResponse struct {
-	FileID      int64  `json:"fileID"`
-	FileName    string `json:"fileName"`
-	FileSize    int64  `json:"fileSize"`
 	DownloadURL string `json:"downloadUrl"`
-	ExpireTime  int64  `json:"expireTime"`
+}
+
+type UserInfo struct {
+	UID            int64  `json:"uid"`
+	Nickname       string `json:"nickname"`
+	HeadImage      string `json:"headImage"`
+	Passport       string `json:"passport"`
+	Mail           string `json:"mail"`
+	SpaceUsed      int64  `json:"spaceUsed"`
+	SpacePermanent int64  `json:"spacePermanent"`
+	SpaceTemp      int64  `json:"spaceTemp"`
+	Vip            bool   `json:"vip"`
+	DirectTraffic  int64  `json:"directTraffic"`
 }
@@ -3,6 +3,8 @@ package open123
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -29,8 +31,8 @@ func (c *Client) GetUploadDomain(ctx context.Context) (string, error) {
 		return "", fmt.Errorf("unmarshal upload domain response failed: %w", err)
 	}
 
-	if result.Code != 0 {
-		return "", fmt.Errorf("get upload domain failed: code=%d, message=%s", result.Code, result.Message)
+	if err := checkCode("get upload domain", result.Code, result.Message); err != nil {
+		return "", err
 	}
 
 	if len(result.Data) == 0 {
@@ -40,12 +42,37 @@ func (c *Client) GetUploadDomain(ctx context.Context) (string, error) {
 	return result.Data[0], nil
 }
 
+// 计算文件MD5，123云盘用MD5作为etag
+func fileMd5(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := md5.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// 单步上传文件，同名文件会被覆盖
+// 单步上传最大支持1GB，更大的文件需要分片上传
 func (c *Client) UploadFile(ctx context.Context, filePath string, parentFileID int64) (*FileUploadCreateResponse, error) {
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("get file info failed: %w", err)
 	}
 
+	if fileInfo.Size() > SINGLE_UPLOAD_MAX_SIZE {
+		return nil, fmt.Errorf("file size %d exceeds single upload limit %d", fileInfo.Size(), SINGLE_UPLOAD_MAX_SIZE)
+	}
+
+	etag, err := fileMd5(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("calculate file md5 failed: %w", err)
+	}
+
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("open file failed: %w", err)
@@ -66,7 +93,9 @@ func (c *Client) UploadFile(ctx context.Context, filePath string, parentFileID i
 
 	_ = writer.WriteField("parentFileID", fmt.Sprintf("%d", parentFileID))
 	_ = writer.WriteField("filename", filename)
+	_ = writer.WriteField("etag", etag)
 	_ = writer.WriteField("size", fmt.Sprintf("%d", fileInfo.Size()))
+	_ = writer.WriteField("duplicate", "2")
 
 	part, err := writer.CreateFormFile("file", filename)
 	if err != nil {
@@ -104,8 +133,8 @@ func (c *Client) UploadFile(ctx context.Context, filePath string, parentFileID i
 		return nil, fmt.Errorf("unmarshal upload response failed: %w", err)
 	}
 
-	if result.Code != 0 {
-		return nil, fmt.Errorf("upload file failed: code=%d, message=%s", result.Code, result.Message)
+	if err := checkCode("upload file", result.Code, result.Message); err != nil {
+		return nil, err
 	}
 
 	return &result.Data, nil
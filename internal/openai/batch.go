@@ -0,0 +1,184 @@
+package openai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// 批量识别每次请求最多包含的文件数，文件太多时模型容易漏掉或者错位
+	DEFAULT_BATCH_SIZE = 50
+
+	MediaTypeMovie = "movie"
+	MediaTypeTv    = "tv"
+
+	DEFAULT_BATCH_PROMPT = `你是影视文件名识别助手。输入是同一个文件夹中的视频文件列表（JSON），folder是文件夹名称，files中每个文件有index和name。
+对每个文件输出：
+- index：原样返回输入的index
+- name：官方完整的主标题，有中文标题时只保留中文标题，保留系列序号，不能有点、下划线、横杠等特殊字符
+- year：四位数的发行年份，标题内的数字不算，没有则为0
+- season：季编号，电视剧没有明确季编号时为1，电影为0
+- episode：集编号，没有则为0
+- media_type：电影为movie，电视剧、动画剧集为tv，和TMDB的类型一致
+- confidence：0到1之间的置信度，名称和类型都确定时接近1
+文件名中没有的信息可以参考文件夹名称，同一个文件夹中的文件通常属于同一部电视剧。
+忽略文件扩展名、视频编码、分辨率、音频、字幕、发布组等信息。`
+)
+
+// MediaFileAI 批量识别时单个文件的识别结果
+type MediaFileAI struct {
+	Index      int     `json:"index"`
+	Name       string  `json:"name"`
+	Year       int     `json:"year"`
+	Season     int     `json:"season"`
+	Episode    int     `json:"episode"`
+	MediaType  string  `json:"media_type"` // movie或者tv，对应TMDB的类型
+	Confidence float64 `json:"confidence"` // 置信度，0-1
+}
+
+type batchFile struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+}
+
+type batchInput struct {
+	Folder string      `json:"folder"`
+	Files  []batchFile `json:"files"`
+}
+
+type batchOutput struct {
+	Files []*MediaFileAI `json:"files"`
+}
+
+// 批量识别结果的JSON Schema，strict模式要求列出所有字段并且不允许额外字段
+var batchSchema = map[string]any{
+	"type": "object",
+	"properties": map[string]any{
+		"files": map[string]any{
+			"type": "array",
+			"items": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"index":      map[string]any{"type": "integer"},
+					"name":       map[string]any{"type": "string"},
+					"year":       map[string]any{"type": "integer"},
+					"season":     map[string]any{"type": "integer"},
+					"episode":    map[string]any{"type": "integer"},
+					"media_type": map[string]any{"type": "string", "enum": []string{MediaTypeMovie, MediaTypeTv}},
+					"confidence": map[string]any{"type": "number"},
+				},
+				"required":             []string{"index", "name", "year", "season", "episode", "media_type", "confidence"},
+				"additionalProperties": false,
+			},
+		},
+	},
+	"required":             []string{"files"},
+	"additionalProperties": false,
+}
+
+// IdentifyBatch 批量识别同一个文件夹中的文件，返回的结果和filenames一一对应，模型漏掉的文件为nil
+// prompt是用户自定义的识别规则，会追加到默认规则后面
+func (c *Client) IdentifyBatch(folder string, filenames []string, prompt string) ([]*MediaFileAI, error) {
+	results := make([]*MediaFileAI, len(filenames))
+	for start := 0; start < len(filenames); start += DEFAULT_BATCH_SIZE {
+		end := min(start+DEFAULT_BATCH_SIZE, len(filenames))
+		chunk, err := c.identifyChunk(folder, filenames[start:end], prompt)
+		if err != nil {
+			return results, err
+		}
+		for _, item := range chunk {
+			if item == nil || item.Index < 0 || item.Index >= end-start || results[start+item.Index] != nil {
+				continue
+			}
+			item.Index += start
+			results[item.Index] = item
+		}
+	}
+	return results, nil
+}
+
+func (c *Client) identifyChunk(folder string, filenames []string, prompt string) ([]*MediaFileAI, error) {
+	input := batchInput{Folder: folder, Files: make([]batchFile, 0, len(filenames))}
+	for i, name := range filenames {
+		input.Files = append(input.Files, batchFile{Index: i, Name: name})
+	}
+	inputJson, err := json.Marshal(input)
+	if err != nil {
+		return nil, err
+	}
+	system := DEFAULT_BATCH_PROMPT
+	if prompt != "" {
+		system += "\n补充规则：\n" + prompt
+	}
+	req := ChatCompletionRequest{
+		Model:  c.modelName,
+		Stream: false,
+		Messages: []Message{
+			{Role: "system", Content: system},
+			{Role: "user", Content: string(inputJson)},
+		},
+		ResponseFormat: &ResponseFormat{
+			Type:       "json_schema",
+			JsonSchema: &JsonSchema{Name: "media_files", Strict: true, Schema: batchSchema},
+		},
+	}
+	resp, err := c.createChatCompletion(req, nil)
+	if err != nil {
+		if !isStructuredOutputUnsupported(err) {
+			return nil, err
+		}
+		// 部分接口不支持结构化输出，去掉response_format后按提示词中的格式输出
+		req.ResponseFormat = nil
+		req.Messages[0].Content += "\n请严格输出JSON，不要添加任何其他内容，格式：{\"files\": [{\"index\": 0, \"name\": \"\", \"year\": 0, \"season\": 0, \"episode\": 0, \"media_type\": \"movie\", \"confidence\": 0.9}]}"
+		resp, err = c.createChatCompletion(req, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parseBatchResponse(resp.Choices[0].Message.Content)
+}
+
+// 接口返回的错误是否表示不支持结构化输出，其他错误（网络、鉴权、限流等）不需要去掉response_format重试
+func isStructuredOutputUnsupported(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, keyword := range []string{"response_format", "json_schema", "structured output", "structured_output"} {
+		if strings.Contains(msg, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析批量识别的结果，兼容带代码块的输出和直接返回数组的输出
+func parseBatchResponse(content string) ([]*MediaFileAI, error) {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+	content = strings.TrimSpace(content)
+	var files []*MediaFileAI
+	if strings.HasPrefix(content, "[") {
+		if err := json.Unmarshal([]byte(content), &files); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON response: %v，错误的数据：%s", err, content)
+		}
+	} else {
+		output := batchOutput{}
+		if err := json.Unmarshal([]byte(content), &output); err != nil {
+			return nil, fmt.Errorf("failed to parse JSON response: %v，错误的数据：%s", err, content)
+		}
+		files = output.Files
+	}
+	for _, file := range files {
+		if file == nil {
+			continue
+		}
+		file.Name = strings.TrimSpace(file.Name)
+		file.MediaType = strings.ToLower(strings.TrimSpace(file.MediaType))
+		if file.MediaType == "tvshow" || file.MediaType == "series" {
+			file.MediaType = MediaTypeTv
+		}
+		file.Confidence = max(0, min(file.Confidence, 1))
+	}
+	return files, nil
+}
@@ -0,0 +1,39 @@
+package openai
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseBatchResponse(t *testing.T) {
+	content := "```json\n{\"files\": [{\"index\": 0, \"name\": \" 鬼灭之刃 \", \"year\": 2019, \"season\": 1, \"episode\": 3, \"media_type\": \"TV\", \"confidence\": 1.2}]}\n```"
+	files, err := parseBatchResponse(content)
+	if err != nil {
+		t.Fatalf("parseBatchResponse 返回错误: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("期望1个结果，实际 %d", len(files))
+	}
+	f := files[0]
+	if f.Name != "鬼灭之刃" || f.Year != 2019 || f.Season != 1 || f.Episode != 3 || f.MediaType != MediaTypeTv || f.Confidence != 1 {
+		t.Errorf("解析结果错误: %+v", f)
+	}
+
+	files, err = parseBatchResponse(`[{"index": 1, "name": "Nobody 2", "year": 2025, "media_type": "movie", "confidence": 0.8}]`)
+	if err != nil || len(files) != 1 || files[0].Index != 1 || files[0].MediaType != MediaTypeMovie {
+		t.Errorf("解析数组结果错误: %+v, %v", files, err)
+	}
+
+	if _, err := parseBatchResponse("不是JSON"); err == nil {
+		t.Errorf("无效的内容应该返回错误")
+	}
+}
+
+func TestIsStructuredOutputUnsupported(t *testing.T) {
+	if !isStructuredOutputUnsupported(errors.New("Invalid parameter: 'response_format' of type 'json_schema' is not supported with this model.")) {
+		t.Errorf("不支持结构化输出的错误应该返回true")
+	}
+	if isStructuredOutputUnsupported(errors.New("Incorrect API key provided")) {
+		t.Errorf("鉴权错误不应该去掉response_format重试")
+	}
+}
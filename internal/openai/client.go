@@ -63,11 +63,33 @@ type Client struct {
 // GlobalOpenAIClient is the global instance of the OpenAI client
 var GlobalOpenAIClient *Client
 
+// 每次请求成功后回调，用来累计token用量
+var usageRecorder func(usage Usage)
+
+// SetUsageRecorder 设置token用量的记录函数
+func SetUsageRecorder(recorder func(usage Usage)) {
+	usageRecorder = recorder
+}
+
 // ChatCompletionRequest represents a chat completion request
 type ChatCompletionRequest struct {
-	Model    string    `json:"model"`
-	Stream   bool      `json:"stream"`
-	Messages []Message `json:"messages"`
+	Model          string          `json:"model"`
+	Stream         bool            `json:"stream"`
+	Messages       []Message       `json:"messages"`
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat 结构化输出，type为json_schema时模型按JsonSchema返回JSON
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JsonSchema *JsonSchema `json:"json_schema,omitempty"`
+}
+
+// JsonSchema 结构化输出使用的JSON Schema
+type JsonSchema struct {
+	Name   string         `json:"name"`
+	Strict bool           `json:"strict"`
+	Schema map[string]any `json:"schema"`
 }
 
 // Message represents a message in a chat completion
@@ -165,7 +187,7 @@ type MediaInfoAI struct {
 }
 
 func (c *Client) TakeMoiveName(filename string, prompt string) (*MediaInfoAI, error) {
-	var userMessage string
+	var userMessage string = prompt
 	var message []Message = make([]Message, 0)
 	userMessage += `\n输出格式：请严格按照以下JSON格式输出，不要添加任何其他内容：{"name": "提取的影视剧名称", "year": 年份或0}\n现在请处理文件名：{{filename}}`
 	userMessage = strings.ReplaceAll(userMessage, "{{filename}}", filename)
@@ -193,15 +215,18 @@ func (c *Client) TakeMoiveName(filename string, prompt string) (*MediaInfoAI, er
 
 // CreateChatCompletion creates a chat completion
 func (c *Client) CreateChatCompletion(message []Message, options *RequestConfig) (*ChatCompletionResponse, error) {
+	return c.createChatCompletion(ChatCompletionRequest{
+		Model:    c.modelName,
+		Stream:   false,
+		Messages: message,
+	}, options)
+}
+
+func (c *Client) createChatCompletion(req ChatCompletionRequest, options *RequestConfig) (*ChatCompletionResponse, error) {
 	url := fmt.Sprintf("%s/v1/chat/completions", c.baseURL)
 
 	// Prepare the request
 	r := c.resty.R().SetHeader("Authorization", fmt.Sprintf("Bearer %s", c.apiKey)).SetMethod("POST")
-	req := ChatCompletionRequest{
-		Model:    c.modelName,
-		Stream:   false,
-		Messages: message,
-	}
 
 	// Set the request body
 	r.SetBody(req)
@@ -225,6 +250,12 @@ func (c *Client) CreateChatCompletion(message []Message, options *RequestConfig)
 		}
 		return nil, fmt.Errorf("%s", openAIError.Message)
 	}
+	if usageRecorder != nil {
+		usageRecorder(resp.Usage)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("OpenAI API没有返回结果")
+	}
 	// helpers.AppLogger.Infof("OpenAI API response: %+v", resp)
 	return &resp, nil
 }
@@ -0,0 +1,148 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client S3兼容对象存储客户端，统一使用path-style访问（endpoint/bucket/key），MinIO、R2、B2都支持
+// 对外的路径都以/开头，和其他来源保持一致，内部转换成对象键
+type Client struct {
+	AccountId uint
+	Endpoint  string // 例如 http://127.0.0.1:9000 或 https://<account>.r2.cloudflarestorage.com
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	client    *http.Client // 普通接口使用，带超时
+	stream    *http.Client // 读取和上传文件内容使用，不限制总时长
+}
+
+var cachedClients map[uint]*Client = make(map[uint]*Client, 0)
+var cachedClientsMutex sync.Mutex
+
+// NewClient 创建新的客户端，同一个账号复用同一个客户端
+func NewClient(accountId uint, endpoint, region, bucket, accessKey, secretKey string) *Client {
+	cachedClientsMutex.Lock()
+	defer cachedClientsMutex.Unlock()
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	if region == "" {
+		region = DEFAULT_REGION
+	}
+	// 配置没变时复用缓存的客户端，变化时创建新的客户端替换，不修改正在使用的客户端
+	if client, exists := cachedClients[accountId]; exists && accountId > 0 {
+		if client.Endpoint == endpoint && client.Region == region && client.Bucket == bucket && client.AccessKey == accessKey && client.SecretKey == secretKey {
+			return client
+		}
+	}
+	client := &Client{
+		AccountId: accountId,
+		Endpoint:  endpoint,
+		Region:    region,
+		Bucket:    bucket,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		client:    &http.Client{Timeout: time.Duration(DEFAULT_TIMEOUT) * time.Second},
+		stream:    &http.Client{},
+	}
+	if accountId > 0 {
+		cachedClients[accountId] = client
+	}
+	return client
+}
+
+// 删除缓存的客户端，账号删除时调用
+func RemoveClient(accountId uint) {
+	cachedClientsMutex.Lock()
+	defer cachedClientsMutex.Unlock()
+	delete(cachedClients, accountId)
+}
+
+// 规范化路径，以/开头，不以/结尾
+func cleanPath(p string) string {
+	p = strings.ReplaceAll(p, "\\", "/")
+	return path.Clean("/" + p)
+}
+
+// 路径转换为对象键，去掉开头的/
+func PathToKey(p string) string {
+	return strings.TrimPrefix(cleanPath(p), "/")
+}
+
+// 目录路径转换为列举用的前缀，根目录为空，其他以/结尾
+func dirPrefix(p string) string {
+	key := PathToKey(p)
+	if key == "" {
+		return ""
+	}
+	return key + "/"
+}
+
+// 对象地址，key为空时是bucket地址
+func (c *Client) objectUrl(key string) *url.URL {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		u = &url.URL{}
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.Bucket
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	return u
+}
+
+func (c *Client) newRequest(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Request, error) {
+	u := c.objectUrl(key)
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	// NewRequest会重新解析地址，确保签名和实际发送的路径一致
+	req.URL = u
+	return req, nil
+}
+
+// 执行请求，2xx算成功，404返回ErrNotFound，其他解析S3的错误信息
+func (c *Client) do(httpClient *http.Client, req *http.Request, key string, payloadHash string) (*http.Response, error) {
+	c.signRequest(req, payloadHash, time.Now())
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		io.Copy(io.Discard, resp.Body)
+		return nil, ErrNotFound
+	}
+	statusErr := &StatusError{Method: req.Method, Key: key, StatusCode: resp.StatusCode}
+	var errResp errorResponse
+	if data, readErr := io.ReadAll(io.LimitReader(resp.Body, 64*1024)); readErr == nil && xml.Unmarshal(data, &errResp) == nil {
+		statusErr.Code = errResp.Code
+		statusErr.Message = errResp.Message
+	}
+	return nil, statusErr
+}
+
+// 预签名下载地址，播放器直接访问，不需要账号密钥
+func (c *Client) PresignGetUrl(p string, expires time.Duration) string {
+	return c.presign(http.MethodGet, c.objectUrl(PathToKey(p)), time.Now(), expires)
+}
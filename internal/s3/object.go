@@ -0,0 +1,317 @@
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 分页列举对象，delimiter为/时只列出直接子项，公共前缀作为目录返回
+func (c *Client) listObjects(ctx context.Context, prefix, delimiter, token string, maxKeys int) (*listBucketResult, error) {
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("prefix", prefix)
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+	if maxKeys > 0 {
+		query.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+	req, err := c.newRequest(ctx, http.MethodGet, "", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(c.client, req, prefix, emptyPayload)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var result listBucketResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析S3列表失败: %v", err)
+	}
+	return &result, nil
+}
+
+func toObject(o objectXML) *Object {
+	p := "/" + o.Key
+	obj := &Object{
+		Name: path.Base(p),
+		Path: p,
+		Size: o.Size,
+		ETag: strings.Trim(o.ETag, "\""),
+	}
+	if t, err := time.Parse(time.RFC3339, o.LastModified); err == nil {
+		obj.LastModified = t
+	}
+	return obj
+}
+
+// 列出目录下的直接子项，S3没有真正的目录，公共前缀当作目录返回
+func (c *Client) List(ctx context.Context, dir string) ([]*Object, error) {
+	prefix := dirPrefix(dir)
+	list := make([]*Object, 0)
+	token := ""
+	for {
+		result, err := c.listObjects(ctx, prefix, "/", token, 1000)
+		if err != nil {
+			return nil, err
+		}
+		for _, cp := range result.CommonPrefixes {
+			p := cleanPath(cp.Prefix)
+			list = append(list, &Object{Name: path.Base(p), Path: p, IsDir: true})
+		}
+		for _, o := range result.Contents {
+			// 跳过目录占位对象（dir/）
+			if o.Key == prefix || strings.HasSuffix(o.Key, "/") {
+				continue
+			}
+			list = append(list, toObject(o))
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	return list, nil
+}
+
+// 递归列出前缀下的所有对象键，删除目录时使用
+func (c *Client) listAllKeys(ctx context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	token := ""
+	for {
+		result, err := c.listObjects(ctx, prefix, "", token, 1000)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range result.Contents {
+			keys = append(keys, o.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	return keys, nil
+}
+
+// 查询对象或目录，对象不存在但有以它为前缀的对象时当作目录
+func (c *Client) Stat(ctx context.Context, p string) (*Object, error) {
+	p = cleanPath(p)
+	if p == "/" {
+		// 根目录只检查bucket能否访问
+		if _, err := c.listObjects(ctx, "", "/", "", 1); err != nil {
+			return nil, err
+		}
+		return &Object{Name: "", Path: "/", IsDir: true}, nil
+	}
+	key := PathToKey(p)
+	req, err := c.newRequest(ctx, http.MethodHead, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(c.client, req, key, emptyPayload)
+	if err == nil {
+		resp.Body.Close()
+		obj := &Object{
+			Name: path.Base(p),
+			Path: p,
+			Size: resp.ContentLength,
+			ETag: strings.Trim(resp.Header.Get("ETag"), "\""),
+		}
+		if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+			obj.LastModified = t
+		}
+		return obj, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	result, err := c.listObjects(ctx, key+"/", "/", "", 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Contents) == 0 && len(result.CommonPrefixes) == 0 {
+		return nil, ErrNotFound
+	}
+	return &Object{Name: path.Base(p), Path: p, IsDir: true}, nil
+}
+
+// 检查路径是否存在
+func (c *Client) Exists(ctx context.Context, p string) (bool, error) {
+	_, err := c.Stat(ctx, p)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// 创建目录，S3没有目录，写入一个以/结尾的空对象占位，这样空目录也能列出来
+func (c *Client) Mkdir(ctx context.Context, p string) error {
+	key := PathToKey(p)
+	if key == "" {
+		return nil
+	}
+	req, err := c.newRequest(ctx, http.MethodPut, key+"/", nil, []byte{})
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(c.client, req, key+"/", emptyPayload)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// 删除对象，如果是目录则删除前缀下的所有对象
+func (c *Client) Delete(ctx context.Context, p string) error {
+	key := PathToKey(p)
+	if key == "" {
+		return errors.New("不能删除S3 bucket根目录")
+	}
+	keys, err := c.listAllKeys(ctx, key+"/")
+	if err != nil {
+		return err
+	}
+	keys = append(keys, key)
+	for _, k := range keys {
+		if err := c.deleteObject(ctx, k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) deleteObject(ctx context.Context, key string) error {
+	req, err := c.newRequest(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(c.client, req, key, emptyPayload)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// 复制对象，dst是包含文件名的完整路径
+func (c *Client) Copy(ctx context.Context, src, dst string) error {
+	srcKey := PathToKey(src)
+	dstKey := PathToKey(dst)
+	req, err := c.newRequest(ctx, http.MethodPut, dstKey, nil, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-amz-copy-source", uriEncode("/"+c.Bucket+"/"+srcKey, false))
+	resp, err := c.do(c.client, req, dstKey, emptyPayload)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// CopyObject出错时也可能返回200，需要检查响应体
+	data, _ := io.ReadAll(resp.Body)
+	var errResp errorResponse
+	if xml.Unmarshal(data, &errResp) == nil && errResp.Code != "" {
+		return &StatusError{Method: "COPY", Key: srcKey, StatusCode: resp.StatusCode, Code: errResp.Code, Message: errResp.Message}
+	}
+	return nil
+}
+
+// 移动对象，S3不支持移动，先复制再删除
+func (c *Client) Move(ctx context.Context, src, dst string) error {
+	if err := c.Copy(ctx, src, dst); err != nil {
+		return err
+	}
+	return c.deleteObject(ctx, PathToKey(src))
+}
+
+// 上传本地文件，remotePath是包含文件名的完整路径
+func (c *Client) Upload(ctx context.Context, localFile, remotePath string) error {
+	f, err := os.Open(localFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	key := PathToKey(remotePath)
+	req, err := c.newRequest(ctx, http.MethodPut, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	req.Body = f
+	req.ContentLength = stat.Size()
+	req.GetBody = nil
+	resp, err := c.do(c.stream, req, key, unsignedPayload)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// 读取对象内容，header会原样转发（例如Range），调用方负责关闭Body
+func (c *Client) Open(ctx context.Context, p string, header http.Header) (*http.Response, error) {
+	key := PathToKey(p)
+	req, err := c.newRequest(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, values := range header {
+		for _, v := range values {
+			req.Header.Add(k, v)
+		}
+	}
+	return c.do(c.stream, req, key, emptyPayload)
+}
+
+// 下载对象到本地，先写入临时文件，完成后再改名，修改时间和对象保持一致
+func (c *Client) Download(ctx context.Context, remotePath, localFile string) error {
+	resp, err := c.Open(ctx, remotePath, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := os.MkdirAll(filepath.Dir(localFile), 0777); err != nil {
+		return err
+	}
+	tmpFile := localFile + ".downloading"
+	f, err := os.Create(tmpFile)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		os.Remove(tmpFile)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		os.Chtimes(tmpFile, time.Now(), t)
+	}
+	return os.Rename(tmpFile, localFile)
+}
@@ -0,0 +1,63 @@
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const DEFAULT_TIMEOUT = 60 // 默认超时时间，单位秒
+
+const DEFAULT_REGION = "us-east-1" // MinIO、R2等兼容服务不关心区域，但是签名需要
+
+var ErrNotFound = errors.New("S3对象不存在")
+
+// StatusError S3接口返回的错误
+type StatusError struct {
+	Method     string
+	Key        string
+	StatusCode int
+	Code       string // S3的错误码，例如AccessDenied、NoSuchBucket
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("S3 %s %s 失败，状态码: %d，错误: %s %s", e.Method, e.Key, e.StatusCode, e.Code, e.Message)
+}
+
+// Object 对象或者目录（公共前缀）
+type Object struct {
+	Name         string    `json:"name"`
+	Path         string    `json:"path"` // 以/开头的路径，对象键去掉开头的/就是Key
+	IsDir        bool      `json:"is_dir"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+	ETag         string    `json:"etag"`
+}
+
+type errorResponse struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// ListObjectsV2的返回值
+type listBucketResult struct {
+	XMLName               xml.Name       `xml:"ListBucketResult"`
+	IsTruncated           bool           `xml:"IsTruncated"`
+	NextContinuationToken string         `xml:"NextContinuationToken"`
+	Contents              []objectXML    `xml:"Contents"`
+	CommonPrefixes        []commonPrefix `xml:"CommonPrefixes"`
+}
+
+type objectXML struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified"`
+	ETag         string `xml:"ETag"`
+	Size         int64  `xml:"Size"`
+}
+
+type commonPrefix struct {
+	Prefix string `xml:"Prefix"`
+}
@@ -0,0 +1,119 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/openai"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 批量AI识别结果的最低置信度，低于该值时识别阶段仍然逐个文件请求AI
+const aiBatchMinConfidence = 0.5
+
+// 批量AI识别：开始刮削前把待识别的文件按文件夹分组，每个文件夹一次请求识别所有文件
+// 识别结果缓存在刮削目录中，识别阶段优先使用；电视剧同时使用AI识别的季和集
+func (s *ScrapeBase) AiBatchIdentify() {
+	s.scrapePath.ClearAiBatchResults()
+	if !s.scrapePath.AiBatch || s.scrapePath.EnableAi == models.AiActionOff || s.scrapePath.MediaType == models.MediaTypeOther {
+		return
+	}
+	mediaFiles := models.GetUnidentifiedScrapeMediaFiles(s.scrapePath.ID, s.scrapePath.MediaType)
+	if len(mediaFiles) == 0 {
+		return
+	}
+	isTvshow := s.scrapePath.MediaType == models.MediaTypeTvShow
+	groups := groupMediaFilesByFolder(mediaFiles, isTvshow)
+	folders := make([]string, 0, len(groups))
+	for folder := range groups {
+		folders = append(folders, folder)
+	}
+	sort.Strings(folders)
+	client := models.GlobalScrapeSettings.GetAiClient()
+	identified := 0
+	for _, folder := range folders {
+		if s.ctx.Err() != nil {
+			return
+		}
+		group := groups[folder]
+		names := make([]string, 0, len(group))
+		for _, mediaFile := range group {
+			names = append(names, aiBatchFileName(mediaFile, isTvshow))
+		}
+		results, err := client.IdentifyBatch(filepath.Base(folder), names, s.scrapePath.AiPrompt)
+		if err != nil {
+			helpers.AppLogger.Warnf("批量AI识别文件夹 %s 失败，识别时逐个文件请求AI: %v", folder, err)
+			continue
+		}
+		for i, result := range results {
+			if !acceptAiBatchResult(result, isTvshow) {
+				continue
+			}
+			s.scrapePath.SetAiBatchResult(group[i].ID, result)
+			identified++
+			if isTvshow {
+				s.applyAiEpisode(group[i], result)
+			}
+		}
+	}
+	helpers.AppLogger.Infof("批量AI识别完成，共 %d 个文件夹 %d 个文件，识别成功 %d 个", len(folders), len(mediaFiles), identified)
+}
+
+// 电视剧按电视剧目录分组，其他按视频所在的目录分组
+func groupMediaFilesByFolder(mediaFiles []*models.ScrapeMediaFile, isTvshow bool) map[string][]*models.ScrapeMediaFile {
+	groups := make(map[string][]*models.ScrapeMediaFile)
+	for _, mediaFile := range mediaFiles {
+		folder := mediaFile.Path
+		if isTvshow && mediaFile.TvshowPath != "" {
+			folder = mediaFile.TvshowPath
+		}
+		groups[folder] = append(groups[folder], mediaFile)
+	}
+	return groups
+}
+
+// 发给AI的文件名，电视剧带上季目录，方便AI识别季
+func aiBatchFileName(mediaFile *models.ScrapeMediaFile, isTvshow bool) string {
+	name := filepath.Base(mediaFile.VideoFilename)
+	if !isTvshow || mediaFile.TvshowPath == "" || mediaFile.Path == mediaFile.TvshowPath {
+		return name
+	}
+	rel := strings.Trim(strings.TrimPrefix(mediaFile.Path, mediaFile.TvshowPath), "/\\")
+	if rel == "" {
+		return name
+	}
+	return rel + "/" + name
+}
+
+// 置信度太低或者类型和刮削目录不一致的结果不使用
+func acceptAiBatchResult(result *openai.MediaFileAI, isTvshow bool) bool {
+	if result == nil || result.Name == "" || result.Confidence < aiBatchMinConfidence {
+		return false
+	}
+	if result.MediaType == "" {
+		return true
+	}
+	return (result.MediaType == openai.MediaTypeTv) == isTvshow
+}
+
+// 使用AI识别的季和集：强制AI识别时以AI结果为准，辅助识别时只补充正则没有识别到的集
+func (s *ScrapeBase) applyAiEpisode(mediaFile *models.ScrapeMediaFile, result *openai.MediaFileAI) {
+	if result.Episode <= 0 {
+		return
+	}
+	if s.scrapePath.EnableAi != models.AiActionEnforce && mediaFile.EpisodeNumber > 0 {
+		return
+	}
+	season := result.Season
+	if season <= 0 {
+		season = mediaFile.SeasonNumber
+	}
+	if season == mediaFile.SeasonNumber && result.Episode == mediaFile.EpisodeNumber {
+		return
+	}
+	helpers.AppLogger.Infof("使用AI识别的季集，文件名 %s, 季集 %d-%d 改为 %d-%d", mediaFile.VideoFilename, mediaFile.SeasonNumber, mediaFile.EpisodeNumber, season, result.Episode)
+	mediaFile.SeasonNumber = season
+	mediaFile.EpisodeNumber = result.Episode
+	mediaFile.Save()
+}
@@ -22,6 +22,10 @@ func (cm *CategoryMovieImpl) DoCategory(mediaFile *models.ScrapeMediaFile) (stri
 	if mediaFile.Media == nil {
 		return "", nil
 	}
+	// 优先使用分类规则
+	if name, spC, ok := matchCategoryRule(cm.scrapePath, mediaFile); ok {
+		return name, spC
+	}
 	var c *models.MovieCategory
 	genres := mediaFile.Media.Genres
 	originalLanguage := mediaFile.Media.OriginalLanguage
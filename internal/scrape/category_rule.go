@@ -0,0 +1,81 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"slices"
+	"strconv"
+)
+
+// BuildCategoryFacts 取出匹配分类规则需要的信息，有规则使用关键词条件时查询TMDB关键词
+// 其他元数据提供者的ID不能查询TMDB，关键词条件按未加载处理
+func BuildCategoryFacts(mediaFile *models.ScrapeMediaFile, rules []*models.CategoryRule) *models.CategoryFacts {
+	facts := models.NewCategoryFacts(mediaFile)
+	needKeywords := slices.ContainsFunc(rules, func(rule *models.CategoryRule) bool { return rule.Conditions.NeedKeywords() })
+	if !needKeywords || mediaFile.Media == nil || mediaFile.Media.TmdbId == 0 || mediaFile.Media.GetMetadataProvider() != models.MetadataProviderTmdb {
+		return facts
+	}
+	client := models.GlobalScrapeSettings.GetTmdbClient()
+	if mediaFile.Media.MediaType == models.MediaTypeTvShow {
+		keywords, err := client.GetTvKeywords(mediaFile.Media.TmdbId)
+		if err != nil {
+			helpers.AppLogger.Warnf("查询电视剧 %s 的TMDB关键词失败: %v", mediaFile.Media.Name, err)
+			return facts
+		}
+		for _, keyword := range keywords.Results {
+			facts.Keywords = append(facts.Keywords, keyword.Name, strconv.FormatInt(keyword.ID, 10))
+		}
+	} else {
+		keywords, err := client.GetMovieKeywords(mediaFile.Media.TmdbId)
+		if err != nil {
+			helpers.AppLogger.Warnf("查询电影 %s 的TMDB关键词失败: %v", mediaFile.Media.Name, err)
+			return facts
+		}
+		for _, keyword := range keywords.Keywords {
+			facts.Keywords = append(facts.Keywords, keyword.Name, strconv.FormatInt(keyword.ID, 10))
+		}
+	}
+	facts.KeywordsLoaded = true
+	return facts
+}
+
+// 按分类规则确定二级分类，没有规则命中或者命中的分类不在刮削目录中时返回false，继续使用流派和语言（国家）匹配
+func matchCategoryRule(scrapePath *models.ScrapePath, mediaFile *models.ScrapeMediaFile) (string, *models.ScrapePathCategory, bool) {
+	rules := models.GetEnabledCategoryRules(scrapePath.MediaType)
+	if len(rules) == 0 {
+		return "", nil, false
+	}
+	facts := BuildCategoryFacts(mediaFile, rules)
+	rule, results := models.EvaluateCategoryRules(rules, facts)
+	for _, result := range results {
+		helpers.AppLogger.Debugf("分类规则 %s 匹配结果 %v: %+v", result.RuleName, result.Matched, result.Checks)
+	}
+	if rule == nil {
+		helpers.AppLogger.Infof("影视剧 %s 没有命中任何分类规则，使用流派和语言（国家）匹配", facts.Name)
+		return "", nil, false
+	}
+	name := ""
+	if scrapePath.MediaType == models.MediaTypeMovie {
+		for _, c := range scrapePath.Category.MovieCategory {
+			if c.ID == rule.CategoryId {
+				name = c.Name
+				break
+			}
+		}
+	} else {
+		for _, c := range scrapePath.Category.TvShowCategory {
+			if c.ID == rule.CategoryId {
+				name = c.Name
+				break
+			}
+		}
+	}
+	for _, spC := range scrapePath.Category.PathCategory {
+		if name != "" && spC.CategoryId == rule.CategoryId {
+			helpers.AppLogger.Infof("影视剧 %s 命中分类规则 %s，分类 %s", facts.Name, rule.Name, name)
+			return name, spC, true
+		}
+	}
+	helpers.AppLogger.Warnf("影视剧 %s 命中分类规则 %s，但是分类 %d 不存在，使用流派和语言（国家）匹配", facts.Name, rule.Name, rule.CategoryId)
+	return "", nil, false
+}
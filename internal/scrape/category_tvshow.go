@@ -22,6 +22,10 @@ func (ct *CategoryTvShowImpl) DoCategory(mediaFile *models.ScrapeMediaFile) (str
 	if mediaFile.Media == nil {
 		return "", nil
 	}
+	// 优先使用分类规则
+	if name, spC, ok := matchCategoryRule(ct.scrapePath, mediaFile); ok {
+		return name, spC
+	}
 	var c *models.TvShowCategory
 	genres := mediaFile.Media.Genres
 	originalCountry := mediaFile.Media.OriginCountry
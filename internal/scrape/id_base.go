@@ -2,6 +2,7 @@ package scrape
 
 import (
 	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/openai"
 	"context"
 )
 
@@ -10,3 +11,11 @@ type IdBase struct {
 	scrapePath *models.ScrapePath
 	ctx        context.Context
 }
+
+// AI从文件名中提取名称和年份，批量识别过的文件直接使用批量识别的结果
+func (i *IdBase) takeNameByAI(client *openai.Client, mediaFile *models.ScrapeMediaFile) (*openai.MediaInfoAI, error) {
+	if result := i.scrapePath.GetAiBatchResult(mediaFile.ID); result != nil {
+		return &openai.MediaInfoAI{Name: result.Name, Year: result.Year}, nil
+	}
+	return client.TakeMoiveName(mediaFile.VideoFilename, i.scrapePath.GetAiPrompt())
+}
@@ -105,7 +105,7 @@ func (i *IdMovieImpl) extractInfo(mediaFile *models.ScrapeMediaFile) (*helpers.M
 // AI提取
 func (i *IdMovieImpl) extractInfoByAI(mediaFile *models.ScrapeMediaFile) (*helpers.MediaInfo, error) {
 	client := models.GlobalScrapeSettings.GetAiClient()
-	info, err := client.TakeMoiveName(mediaFile.VideoFilename, i.scrapePath.GetAiPrompt())
+	info, err := i.takeNameByAI(client, mediaFile)
 	if err != nil {
 		helpers.AppLogger.Errorf("强制使用AI从文件名中提取媒体信息失败: %v", err)
 		return nil, err
@@ -15,7 +15,7 @@ type IdTvShowImpl struct {
 	IdBase
 }
 
-func NewIdTvShowImpl(scrapePath *models.ScrapePath, ctx context.Context, tmdbImpl *TmdbTvShowImpl) *IdTvShowImpl {
+func NewIdTvShowImpl(scrapePath *models.ScrapePath, ctx context.Context, tmdbImpl TmdbImpl) *IdTvShowImpl {
 	return &IdTvShowImpl{
 		IdBase: IdBase{
 			tmdbImpl:   tmdbImpl,
@@ -109,7 +109,7 @@ func (i *IdTvShowImpl) extractInfo(mediaFile *models.ScrapeMediaFile) (*helpers.
 // AI提取
 func (i *IdTvShowImpl) extractInfoByAI(mediaFile *models.ScrapeMediaFile) (*helpers.MediaInfo, error) {
 	client := models.GlobalScrapeSettings.GetAiClient()
-	info, err := client.TakeMoiveName(mediaFile.VideoFilename, i.scrapePath.GetAiPrompt())
+	info, err := i.takeNameByAI(client, mediaFile)
 	if err != nil {
 		helpers.AppLogger.Errorf("强制使用AI从文件名中提取媒体信息失败: %v", err)
 		return nil, err
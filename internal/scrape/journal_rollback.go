@@ -0,0 +1,176 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/scrape/rename"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// 按整理日志撤销操作，每种来源的重命名实现都支持
+type journalUndoImpl interface {
+	Undo(journal *models.ScrapeJournal) error
+}
+
+type JournalRollbackStatus string
+
+const (
+	JournalRollbackStatusRunning   JournalRollbackStatus = "running"
+	JournalRollbackStatusCompleted JournalRollbackStatus = "completed"
+	JournalRollbackStatusFailed    JournalRollbackStatus = "failed"
+)
+
+// 无法撤销的操作
+type JournalRollbackFailure struct {
+	JournalId    uint                       `json:"journal_id"`
+	Action       models.ScrapeJournalAction `json:"action"`
+	OriginalPath string                     `json:"original_path"`
+	NewPath      string                     `json:"new_path"`
+	Reason       string                     `json:"reason"`
+}
+
+type JournalRollbackState struct {
+	ScrapePathId uint                     `json:"scrape_path_id"`
+	BatchNo      string                   `json:"batch_no"`
+	StartTime    int64                    `json:"start_time"`
+	EndTime      int64                    `json:"end_time"`
+	Status       JournalRollbackStatus    `json:"status"`
+	FailReason   string                   `json:"fail_reason"`
+	Total        int                      `json:"total"`
+	Success      int                      `json:"success"`
+	Failed       int                      `json:"failed"`
+	Failures     []JournalRollbackFailure `json:"failures"`
+	StartAt      int64                    `json:"start_at"`
+	FinishAt     int64                    `json:"finish_at"`
+}
+
+// 批量撤销任务，按执行的相反顺序撤销整理日志中的操作
+type JournalRollback struct {
+	JournalRollbackState
+
+	mu sync.RWMutex
+}
+
+var (
+	journalRollbacks     = make(map[uint]*JournalRollback)
+	journalRollbacksLock sync.Mutex
+)
+
+func (r *JournalRollback) record(journal *models.ScrapeJournal, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil {
+		r.Success++
+		return
+	}
+	r.Failed++
+	r.Failures = append(r.Failures, JournalRollbackFailure{
+		JournalId:    journal.ID,
+		Action:       journal.Action,
+		OriginalPath: journal.OriginalPath,
+		NewPath:      journal.NewPath,
+		Reason:       err.Error(),
+	})
+}
+
+func (r *JournalRollback) finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.FinishAt = time.Now().Unix()
+	if err != nil {
+		r.Status = JournalRollbackStatusFailed
+		r.FailReason = err.Error()
+		return
+	}
+	r.Status = JournalRollbackStatusCompleted
+}
+
+// 获取状态快照，避免调用方读取时和撤销任务并发写入
+func (r *JournalRollback) Snapshot() JournalRollbackState {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	state := r.JournalRollbackState
+	state.Failures = slices.Clone(r.Failures)
+	return state
+}
+
+// 查询刮削目录最近一次的批量撤销结果
+func GetJournalRollback(scrapePathId uint) *JournalRollback {
+	journalRollbacksLock.Lock()
+	defer journalRollbacksLock.Unlock()
+	return journalRollbacks[scrapePathId]
+}
+
+// 启动批量撤销，batchNo不为空时撤销整个批次，否则撤销时间范围内的操作
+// 同一个刮削目录同时只能有一个撤销任务，调用方需要保证刮削任务没有运行
+func StartJournalRollback(scrapePath *models.ScrapePath, batchNo string, startTime, endTime int64) (*JournalRollback, error) {
+	if batchNo == "" && startTime == 0 && endTime == 0 {
+		return nil, errors.New("批次号和时间范围不能同时为空")
+	}
+	journalRollbacksLock.Lock()
+	defer journalRollbacksLock.Unlock()
+	if old, ok := journalRollbacks[scrapePath.ID]; ok && old.Snapshot().Status == JournalRollbackStatusRunning {
+		return nil, errors.New("该刮削目录的撤销任务正在运行")
+	}
+	journals, err := models.GetScrapeJournalsForRollback(scrapePath.ID, batchNo, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	if len(journals) == 0 {
+		return nil, errors.New("没有可以撤销的整理记录")
+	}
+	rollback := &JournalRollback{
+		JournalRollbackState: JournalRollbackState{
+			ScrapePathId: scrapePath.ID,
+			BatchNo:      batchNo,
+			StartTime:    startTime,
+			EndTime:      endTime,
+			Status:       JournalRollbackStatusRunning,
+			Total:        len(journals),
+			Failures:     make([]JournalRollbackFailure, 0),
+			StartAt:      time.Now().Unix(),
+		},
+	}
+	journalRollbacks[scrapePath.ID] = rollback
+	go func() {
+		err := rollback.run(scrapePath, journals)
+		if err != nil {
+			helpers.AppLogger.Errorf("刮削目录 %d 批量撤销失败: %v", scrapePath.ID, err)
+		} else {
+			state := rollback.Snapshot()
+			helpers.AppLogger.Infof("刮削目录 %d 批量撤销完成，共 %d 个操作，成功 %d 个，失败 %d 个", scrapePath.ID, state.Total, state.Success, state.Failed)
+		}
+		rollback.finish(err)
+	}()
+	return rollback, nil
+}
+
+func (r *JournalRollback) run(scrapePath *models.ScrapePath, journals []*models.ScrapeJournal) error {
+	s := NewScrape(scrapePath)
+	defer s.ctxCancel()
+	if err := s.initOpenClient(); err != nil {
+		return err
+	}
+	var undoImpl journalUndoImpl
+	switch scrapePath.SourceType {
+	case models.SourceType115:
+		undoImpl = rename.NewRename115(s.ctx, scrapePath, s.V115Client)
+	case models.SourceTypeOpenList:
+		undoImpl = rename.NewRenameOpenList(s.ctx, scrapePath, s.OpenlistClient)
+	case models.SourceTypeBaiduPan:
+		undoImpl = rename.NewRenameBaiduPan(s.ctx, scrapePath, s.BaiduPanClient)
+	case models.SourceTypeWebDav:
+		undoImpl = rename.NewRenameWebDav(s.ctx, scrapePath, s.WebDavClient)
+	default:
+		undoImpl = rename.NewRenameLocal(s.ctx, scrapePath)
+	}
+	for _, journal := range journals {
+		err := undoImpl.Undo(journal)
+		journal.SetRollbackResult(err)
+		r.record(journal, err)
+	}
+	return nil
+}
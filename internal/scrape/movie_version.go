@@ -0,0 +1,185 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 电影多版本：同一部电影的多个文件放在同一个文件夹，文件名使用 文件夹名称 - [版本] 的格式，Emby和Jellyfin会识别为同一部电影的多个版本
+// 开启只保留最好的版本时，同一部电影的所有版本一起排序，只有最好的版本留在电影文件夹，其他版本移动到duplicates文件夹
+// 返回true表示当前版本不需要生成nfo和图片：同一个文件夹中已经有其他版本生成，或者当前版本被淘汰
+func (m *movieScrapeImpl) ApplyMovieVersion(mediaFile *models.ScrapeMediaFile) bool {
+	if !m.scrapePath.MultiVersion || mediaFile.MediaType != models.MediaTypeMovie || mediaFile.ScrapeType == models.ScrapeTypeOnly || mediaFile.TmdbId == 0 {
+		// 关闭多版本后重新刮削时按普通电影处理
+		mediaFile.VersionLabel = ""
+		mediaFile.IsDuplicateVersion = false
+		return false
+	}
+	// 同一部电影的多个版本可能在不同的线程中同时处理，确定版本时加锁
+	m.versionMutex.Lock()
+	defer m.versionMutex.Unlock()
+	versions := models.GetMovieVersions(mediaFile.ScrapePathId, mediaFile.GetMetadataProvider(), mediaFile.TmdbId, mediaFile.ID)
+	duplicate := false
+	losers := make([]*models.ScrapeMediaFile, 0)
+	if m.scrapePath.KeepBestVersion {
+		for _, version := range versions {
+			if version.IsDuplicateVersion {
+				continue
+			}
+			if !models.BetterVersion(mediaFile, version, m.scrapePath.VersionRankingList) {
+				duplicate = true
+				helpers.AppLogger.Infof("电影 %s 已有质量更好或相同的版本 %s，当前版本 %s 移动到 %s 文件夹", mediaFile.Name, version.VersionLabel, mediaFile.VideoFilename, models.DuplicateVersionFolder)
+				break
+			}
+			losers = append(losers, version)
+		}
+	}
+	assignMovieVersion(mediaFile, versions, duplicate)
+	mediaFile.Save()
+	mediaFile.Media.Save()
+	helpers.AppLogger.Infof("电影 %s 的版本为 %s，文件名 %s", mediaFile.Name, mediaFile.VersionLabel, mediaFile.NewVideoBaseName)
+	if duplicate {
+		return true
+	}
+	if m.scrapePath.KeepBestVersion {
+		// 当前版本最好，已经整理完成的其他版本移动到duplicates文件夹
+		// 还在处理中的版本由处理它的线程在整理前后检查时淘汰
+		for _, loser := range losers {
+			if loser.Status != models.ScrapeMediaStatusRenamed {
+				continue
+			}
+			helpers.AppLogger.Infof("电影 %s 的版本 %s 比已有的版本 %s 更好，已有的版本移动到 %s 文件夹", mediaFile.Name, mediaFile.VersionLabel, loser.VersionLabel, models.DuplicateVersionFolder)
+			if err := m.moveVersionToDuplicates(loser, versions); err != nil {
+				helpers.AppLogger.Errorf("电影 %s 的版本 %s 移动到 %s 文件夹失败: %v", loser.Name, loser.VersionLabel, models.DuplicateVersionFolder, err)
+			}
+		}
+		// 电影文件夹中只保留当前版本，由当前版本生成nfo和图片
+		return false
+	}
+	for _, version := range versions {
+		if !version.IsDuplicateVersion && version.CategoryName == mediaFile.CategoryName && version.NewPathName == mediaFile.NewPathName {
+			helpers.AppLogger.Infof("电影 %s 的其他版本已经生成nfo和图片，当前版本共用", mediaFile.Name)
+			return true
+		}
+	}
+	return false
+}
+
+// SettleMovieVersion 在整理前和整理完成后再检查一次当前版本是不是最好的版本
+// 确定版本之后才出现的更好版本无法移动还在处理中的文件，由这里淘汰：整理前只需要修改目标路径，整理完成后把文件移动到duplicates文件夹
+func (m *movieScrapeImpl) SettleMovieVersion(mediaFile *models.ScrapeMediaFile, renamed bool) error {
+	if !m.scrapePath.MultiVersion || !m.scrapePath.KeepBestVersion || mediaFile.VersionLabel == "" || mediaFile.IsDuplicateVersion {
+		return nil
+	}
+	m.versionMutex.Lock()
+	defer m.versionMutex.Unlock()
+	// 其他线程确定版本时可能已经把当前版本移动到duplicates文件夹
+	if current := models.GetScrapeMediaFileById(mediaFile.ID); current != nil && current.IsDuplicateVersion {
+		return nil
+	}
+	versions := models.GetMovieVersions(mediaFile.ScrapePathId, mediaFile.GetMetadataProvider(), mediaFile.TmdbId, mediaFile.ID)
+	for _, version := range versions {
+		if version.IsDuplicateVersion || !models.BetterVersion(version, mediaFile, m.scrapePath.VersionRankingList) {
+			continue
+		}
+		helpers.AppLogger.Infof("电影 %s 已有质量更好的版本 %s，当前版本 %s 移动到 %s 文件夹", mediaFile.Name, version.VersionLabel, mediaFile.VersionLabel, models.DuplicateVersionFolder)
+		if renamed {
+			return m.moveVersionToDuplicates(mediaFile, versions)
+		}
+		assignMovieVersion(mediaFile, versions, true)
+		mediaFile.Save()
+		mediaFile.Media.Save()
+		return nil
+	}
+	return nil
+}
+
+// 确定版本名称和文件名，版本名称只需要在同一个文件夹中唯一，淘汰的版本放到duplicates文件夹
+func assignMovieVersion(mediaFile *models.ScrapeMediaFile, versions []*models.ScrapeMediaFile, duplicate bool) {
+	used := make([]string, 0, len(versions))
+	for _, version := range versions {
+		if version.IsDuplicateVersion == duplicate {
+			used = append(used, version.VersionLabel)
+		}
+	}
+	mediaFile.IsDuplicateVersion = duplicate
+	mediaFile.VersionLabel = models.UniqueVersionLabel(mediaFile.BuildVersionLabel(), used)
+	mediaFile.NewVideoBaseName = fmt.Sprintf("%s - [%s]", filepath.Base(mediaFile.NewPathName), mediaFile.VersionLabel)
+	if duplicate {
+		mediaFile.NewPathName = filepath.Join(models.DuplicateVersionFolder, mediaFile.NewPathName)
+		mediaFile.CategoryName = ""
+		mediaFile.ScrapePathCategoryId = 0
+	}
+	mediaFile.Media.Path = filepath.Join(mediaFile.DestPath, mediaFile.CategoryName, mediaFile.NewPathName)
+	mediaFile.Media.VideoFileName = mediaFile.NewVideoBaseName + mediaFile.VideoExt
+}
+
+// 把已经整理完成的版本的视频和字幕移动到duplicates文件夹，并删除STRM同步目录中对应的STRM文件
+func (m *movieScrapeImpl) moveVersionToDuplicates(version *models.ScrapeMediaFile, versions []*models.ScrapeMediaFile) error {
+	if version.Media == nil {
+		version.QueryRelation()
+	}
+	if version.Media == nil {
+		return fmt.Errorf("电影 %s 的版本 %s 没有关联的Media记录", version.Name, version.VersionLabel)
+	}
+	oldPath := version.Media.Path
+	oldPathId := version.Media.PathId
+	oldVideoName := version.Media.VideoFileName
+	oldBaseName := version.NewVideoBaseName
+	assignMovieVersion(version, versions, true)
+	pathId, err := m.renameImpl.CheckAndMkDir(version.Media.Path, version.DestPath, version.DestPathId)
+	if err != nil {
+		return err
+	}
+	move := func(fileId, name, newName string) (string, error) {
+		if err := m.renameImpl.MoveFiles(models.MoveNewFileToSourceFile{FileId: fileId, PathId: pathId, FileFullPath: filepath.Join(version.Media.Path, name)}); err != nil {
+			return fileId, err
+		}
+		if version.SourceType != models.SourceType115 {
+			fileId = strings.Replace(fileId, oldPathId, pathId, 1)
+		}
+		if name == newName {
+			return fileId, nil
+		}
+		if err := m.renameImpl.Rename(fileId, newName); err != nil {
+			return fileId, err
+		}
+		if version.SourceType != models.SourceType115 {
+			fileId = filepath.Join(filepath.Dir(fileId), newName)
+		}
+		return fileId, nil
+	}
+	// 先移动字幕文件，失败不影响视频文件
+	for _, sub := range version.Media.SubtitleFiles {
+		newName := strings.Replace(sub.FileName, oldBaseName, version.NewVideoBaseName, 1)
+		fileId, err := move(sub.FileId, sub.FileName, newName)
+		if err != nil {
+			helpers.AppLogger.Errorf("移动字幕文件 %s 到 %s 失败: %v", sub.FileName, version.Media.Path, err)
+			continue
+		}
+		sub.FileId = fileId
+		sub.FileName = newName
+	}
+	videoFileId, err := move(version.Media.VideoFileId, oldVideoName, version.Media.VideoFileName)
+	if err != nil {
+		return err
+	}
+	version.Media.VideoFileId = videoFileId
+	version.Media.PathId = pathId
+	version.NewPathId = pathId
+	version.Save()
+	version.Media.Save()
+	helpers.AppLogger.Infof("电影 %s 的版本 %s 已移动到 %s", version.Name, version.VersionLabel, version.Media.Path)
+	// 删除电影文件夹中旧版本的STRM文件
+	if syncPath := m.scrapePath.GetSyncPathByPath(oldPath); syncPath != nil {
+		strmFile := filepath.Join(syncPath.LocalPath, oldPath, oldBaseName+".strm")
+		if err := os.Remove(strmFile); err != nil && !os.IsNotExist(err) {
+			helpers.AppLogger.Errorf("删除被淘汰版本的STRM文件 %s 失败: %v", strmFile, err)
+		}
+	}
+	return nil
+}
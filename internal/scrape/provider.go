@@ -0,0 +1,235 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/tmdb"
+	"context"
+	"errors"
+	"strings"
+)
+
+// 元数据提供者
+// TVDB、Bangumi等非TMDB的数据统一转换成tmdb的结构，复用Media的填充、二级分类和nfo生成逻辑
+// ScrapeMediaFile.TmdbId 保存的是 ScrapeMediaFile.MetadataProvider 对应提供者中的ID
+type MetadataProvider interface {
+	Name() string
+	// 识别电影和电视剧时使用
+	MovieImpl() TmdbImpl
+	TvShowImpl() TmdbImpl
+	// onlyDetail 为true时只查询详情，不查询演职人员和图片（仅整理模式）
+	GetMovieInfo(id int64, onlyDetail bool) (*models.TmdbInfo, error)
+	GetTvShowInfo(id int64) (*models.TmdbInfo, error)
+	GetSeasonDetail(id int64, seasonNumber int) (*tmdb.SeasonDetail, error)
+	GetEpisodeDetail(id int64, seasonNumber int, episodeNumber int) (*tmdb.Episode, error)
+}
+
+// 非TMDB的提供者无法使用文件名中的tmdbid查询，返回错误让识别流程退回到名称+年份查询
+var errTmdbIdNotSupported = errors.New("不支持使用TMDB ID查询")
+
+// 创建所有元数据提供者，重新识别时可能指定了刮削目录优先级之外的提供者，所以全部创建
+func newMetadataProviders(scrapePath *models.ScrapePath, ctx context.Context) map[string]MetadataProvider {
+	return map[string]MetadataProvider{
+		models.MetadataProviderTmdb:    newTmdbProvider(scrapePath, ctx),
+		models.MetadataProviderTvdb:    newTvdbProvider(),
+		models.MetadataProviderBangumi: newBangumiProvider(),
+	}
+}
+
+// 获取刮削记录识别时使用的元数据提供者
+func (s *ScrapeBase) getProvider(mediaFile *models.ScrapeMediaFile) MetadataProvider {
+	if provider, ok := s.providers[mediaFile.GetMetadataProvider()]; ok {
+		return provider
+	}
+	return s.providers[models.MetadataProviderTmdb]
+}
+
+// 按刮削目录设置的元数据提供者优先级依次识别，前一个识别失败时使用下一个
+type providerIdentifyImpl struct {
+	names []string
+	impls []IdentifyImpl
+}
+
+func newProviderIdentifyImpl(scrapePath *models.ScrapePath, providers map[string]MetadataProvider, makeImpl func(MetadataProvider) IdentifyImpl) *providerIdentifyImpl {
+	p := &providerIdentifyImpl{}
+	for _, name := range scrapePath.GetMetadataProviders() {
+		provider, ok := providers[name]
+		if !ok {
+			continue
+		}
+		p.names = append(p.names, name)
+		p.impls = append(p.impls, makeImpl(provider))
+	}
+	return p
+}
+
+func (p *providerIdentifyImpl) Identify(mediaFile *models.ScrapeMediaFile) error {
+	// 已经有ID的不需要重新识别，保留原来的元数据提供者
+	if mediaFile.IsReScrape || mediaFile.TmdbId != 0 {
+		return p.impls[0].Identify(mediaFile)
+	}
+	var lastErr error
+	for index, impl := range p.impls {
+		err := impl.Identify(mediaFile)
+		if err == nil {
+			if mediaFile.TmdbId != 0 && mediaFile.MetadataProvider != p.names[index] {
+				mediaFile.MetadataProvider = p.names[index]
+				mediaFile.Save()
+			}
+			return nil
+		}
+		lastErr = err
+		if index < len(p.impls)-1 {
+			helpers.AppLogger.Warnf("使用 %s 识别 %s 失败: %v，尝试使用 %s 识别", p.names[index], mediaFile.VideoFilename, err, p.names[index+1])
+		}
+	}
+	return lastErr
+}
+
+// TMDB
+type tmdbProvider struct {
+	client     *tmdb.Client
+	movieImpl  *TmdbMovieImpl
+	tvShowImpl *TmdbTvShowImpl
+}
+
+func newTmdbProvider(scrapePath *models.ScrapePath, ctx context.Context) *tmdbProvider {
+	movieImpl := NewTmdbMovieImpl(scrapePath, ctx)
+	return &tmdbProvider{
+		client:     movieImpl.Client,
+		movieImpl:  movieImpl,
+		tvShowImpl: NewTmdbTvShowImpl(scrapePath, ctx),
+	}
+}
+
+func (t *tmdbProvider) Name() string {
+	return models.MetadataProviderTmdb
+}
+
+func (t *tmdbProvider) MovieImpl() TmdbImpl {
+	return t.movieImpl
+}
+
+func (t *tmdbProvider) TvShowImpl() TmdbImpl {
+	return t.tvShowImpl
+}
+
+func (t *tmdbProvider) GetMovieInfo(id int64, onlyDetail bool) (*models.TmdbInfo, error) {
+	tmdbInfo := &models.TmdbInfo{}
+	// 查询详情
+	movieDetail, err := t.client.GetMovieDetail(id, models.GlobalScrapeSettings.GetTmdbLanguage())
+	if err != nil {
+		helpers.AppLogger.Errorf("查询tmdb电影详情失败, 下次重试, 失败原因: %v", err)
+		return nil, err
+	}
+	tmdbInfo.MovieDetail = movieDetail
+	if onlyDetail {
+		return tmdbInfo, nil
+	}
+	// 查询演职人员
+	cast, _ := t.client.GetMoviePepoles(id, models.GlobalScrapeSettings.GetTmdbLanguage())
+	tmdbInfo.Credits = cast
+	// 查询图片
+	images, _ := t.client.GetMovieImages(id, models.GlobalScrapeSettings.GetTmdbImageLanguage())
+	if images != nil {
+		helpers.AppLogger.Infof("查询tmdb电影图片成功, tmdbId: %d, 语言: %s", id, models.GlobalScrapeSettings.GetTmdbImageLanguage())
+		// 如果图片为空,则使用详情中的图片
+		if len(images.Posters) == 0 && movieDetail.PosterPath != "" {
+			images.Posters = append(images.Posters, tmdb.Image{
+				FilePath: movieDetail.PosterPath,
+			})
+		}
+		if len(images.Backdrops) == 0 && movieDetail.BackdropPath != "" {
+			images.Backdrops = append(images.Backdrops, tmdb.Image{
+				FilePath: movieDetail.BackdropPath,
+			})
+		}
+	}
+	tmdbInfo.Images = images
+	// 查询分级信息
+	releasesDate, err := t.client.GetReleasesDate(id)
+	if err != nil {
+		helpers.AppLogger.Errorf("查询tmdb电影分级信息失败, 下次重试, 失败原因: %v", err)
+	} else {
+		tmdbInfo.ReleasesDate = releasesDate.Results
+	}
+	return tmdbInfo, nil
+}
+
+func (t *tmdbProvider) GetTvShowInfo(id int64) (*models.TmdbInfo, error) {
+	tmdbInfo := &models.TmdbInfo{}
+	// 查询详情
+	tvDetail, err := t.client.GetTvDetail(id, models.GlobalScrapeSettings.GetTmdbLanguage())
+	if err != nil {
+		helpers.AppLogger.Errorf("查询tmdb电视剧详情失败, 下次重试, 失败原因: %v", err)
+		return nil, err
+	}
+	tmdbInfo.TvShowDetail = tvDetail
+	// 查询演职人员
+	cast, _ := t.client.GetTvCredits(id, models.GlobalScrapeSettings.GetTmdbLanguage())
+	tmdbInfo.Credits = cast
+	// 查询图片
+	images, _ := t.client.GetTvImages(id, models.GlobalScrapeSettings.GetTmdbImageLanguage())
+	if images == nil {
+		images = &tmdb.Images{}
+	}
+	// 如果图片为空,则使用详情中的图片
+	if len(images.Posters) == 0 && tvDetail.PosterPath != "" {
+		images.Posters = append(images.Posters, tmdb.Image{
+			FilePath: tvDetail.PosterPath,
+		})
+	}
+	if len(images.Backdrops) == 0 && tvDetail.BackdropPath != "" {
+		images.Backdrops = append(images.Backdrops, tmdb.Image{
+			FilePath: tvDetail.BackdropPath,
+		})
+	}
+	tmdbInfo.Images = images
+	return tmdbInfo, nil
+}
+
+func (t *tmdbProvider) GetSeasonDetail(id int64, seasonNumber int) (*tmdb.SeasonDetail, error) {
+	seasonDetail, err := t.client.GetTvSeasonDetail(id, seasonNumber, models.GlobalScrapeSettings.GetTmdbLanguage())
+	if err != nil {
+		helpers.AppLogger.Errorf("查询tmdb电视剧季详情失败,下次重试, 失败原因: %v", err)
+		return nil, err
+	}
+	return seasonDetail, nil
+}
+
+func (t *tmdbProvider) GetEpisodeDetail(id int64, seasonNumber int, episodeNumber int) (*tmdb.Episode, error) {
+	episodeDetail, err := t.client.GetTvEpisodeDetail(id, seasonNumber, episodeNumber, models.GlobalScrapeSettings.GetTmdbLanguage())
+	if err != nil {
+		helpers.AppLogger.Errorf("查询tmdb电视剧集详情失败,下次重试, 失败原因: %v", err)
+		return nil, err
+	}
+	// 查询集演员
+	credits, err := t.client.GetTvEpisodeCredits(id, seasonNumber, episodeNumber, models.GlobalScrapeSettings.GetTmdbLanguage())
+	if err != nil {
+		helpers.AppLogger.Errorf("查询tmdb电视剧集演员失败,下次重试, 失败原因: %v", err)
+	} else {
+		episodeDetail.Cast = credits.Cast
+		episodeDetail.Crew = credits.Crew
+	}
+	return episodeDetail, nil
+}
+
+// 根据ID从TMDB流派列表中查询流派
+func findGenre(genres []helpers.Genre, id int) (tmdb.Genre, bool) {
+	for _, genre := range genres {
+		if genre.Id == id {
+			return tmdb.Genre{ID: genre.Id, Name: genre.Name}, true
+		}
+	}
+	return tmdb.Genre{}, false
+}
+
+// 名称是否完全匹配（忽略大小写）
+func nameMatched(name string, candidates ...string) bool {
+	for _, candidate := range candidates {
+		if candidate != "" && strings.EqualFold(strings.TrimSpace(candidate), strings.TrimSpace(name)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,338 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/bangumi"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/tmdb"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// TMDB中动画流派的ID
+const tmdbGenreAnimation = 16
+
+// 从Bangumi刮削元数据，只支持动画条目
+// Bangumi的一个条目对应一季，所以电视剧只有第1季（特别篇除外）
+type bangumiProvider struct {
+	client   *bangumi.Client
+	mutex    sync.Mutex
+	subjects map[int64]*bangumi.Subject
+	episodes map[int64][]bangumi.Episode
+}
+
+func newBangumiProvider() *bangumiProvider {
+	return &bangumiProvider{
+		client:   models.GlobalScrapeSettings.GetBangumiClient(),
+		subjects: make(map[int64]*bangumi.Subject),
+		episodes: make(map[int64][]bangumi.Episode),
+	}
+}
+
+func (b *bangumiProvider) Name() string {
+	return models.MetadataProviderBangumi
+}
+
+func (b *bangumiProvider) MovieImpl() TmdbImpl {
+	return &bangumiCheckImpl{client: b.client}
+}
+
+func (b *bangumiProvider) TvShowImpl() TmdbImpl {
+	return &bangumiCheckImpl{client: b.client}
+}
+
+func (b *bangumiProvider) getSubject(id int64) (*bangumi.Subject, error) {
+	b.mutex.Lock()
+	subject, ok := b.subjects[id]
+	b.mutex.Unlock()
+	if ok {
+		return subject, nil
+	}
+	subject, err := b.client.GetSubject(id)
+	if err != nil {
+		return nil, err
+	}
+	b.mutex.Lock()
+	b.subjects[id] = subject
+	b.mutex.Unlock()
+	return subject, nil
+}
+
+func (b *bangumiProvider) getEpisodes(id int64) ([]bangumi.Episode, error) {
+	b.mutex.Lock()
+	episodes, ok := b.episodes[id]
+	b.mutex.Unlock()
+	if ok {
+		return episodes, nil
+	}
+	episodes, err := b.client.GetEpisodes(id, bangumi.EpisodeTypeMain)
+	if err != nil {
+		return nil, err
+	}
+	b.mutex.Lock()
+	b.episodes[id] = episodes
+	b.mutex.Unlock()
+	return episodes, nil
+}
+
+func (b *bangumiProvider) GetMovieInfo(id int64, onlyDetail bool) (*models.TmdbInfo, error) {
+	subject, err := b.getSubject(id)
+	if err != nil {
+		helpers.AppLogger.Errorf("查询bangumi条目详情失败, 下次重试, 失败原因: %v", err)
+		return nil, err
+	}
+	detail := &tmdb.MovieDetail{
+		SearchMovie: tmdb.SearchMovie{
+			ID:               subject.ID,
+			Title:            bangumiName(subject.NameCn, subject.Name),
+			OriginalTitle:    subject.Name,
+			Overview:         strings.TrimSpace(subject.Summary),
+			PosterPath:       subject.Images.Large,
+			ReleaseDate:      subject.Date,
+			VoteAverage:      subject.Rating.Score,
+			VoteCount:        subject.Rating.Total,
+			OriginalLanguage: "ja",
+			Adult:            subject.Nsfw,
+		},
+		Genres:              bangumiGenres(helpers.MovieGenres),
+		ProductionCountries: []tmdb.Country{{ISO_3166_1: "JP", Name: "Japan"}},
+	}
+	tmdbInfo := &models.TmdbInfo{MovieDetail: detail}
+	if onlyDetail {
+		return tmdbInfo, nil
+	}
+	tmdbInfo.Credits = b.getCredits(id)
+	tmdbInfo.Images = bangumiImages(subject)
+	return tmdbInfo, nil
+}
+
+func (b *bangumiProvider) GetTvShowInfo(id int64) (*models.TmdbInfo, error) {
+	subject, err := b.getSubject(id)
+	if err != nil {
+		helpers.AppLogger.Errorf("查询bangumi条目详情失败, 下次重试, 失败原因: %v", err)
+		return nil, err
+	}
+	episodeCount := subject.TotalEpisodes
+	if episodeCount == 0 {
+		episodeCount = subject.Eps
+	}
+	detail := &tmdb.TvDetail{
+		SearchTv: tmdb.SearchTv{
+			ID:               subject.ID,
+			Name:             bangumiName(subject.NameCn, subject.Name),
+			OriginalName:     subject.Name,
+			Overview:         strings.TrimSpace(subject.Summary),
+			PosterPath:       subject.Images.Large,
+			FirstAirDate:     subject.Date,
+			VoteAverage:      subject.Rating.Score,
+			VoteCount:        subject.Rating.Total,
+			OriginalLanguage: "ja",
+			OriginCountry:    []string{"JP"},
+			Adult:            subject.Nsfw,
+		},
+		Genres:           bangumiGenres(helpers.TvshowGenres),
+		NumberOfSeasons:  1,
+		NumberOfEpisodes: episodeCount,
+		Seasons: []tmdb.Season{{
+			ID:           subject.ID,
+			Name:         seasonName(1),
+			AirDate:      subject.Date,
+			EpisodeCount: episodeCount,
+			PosterPath:   subject.Images.Large,
+			SeasonNumber: 1,
+		}},
+		Type: subject.Platform,
+	}
+	return &models.TmdbInfo{
+		TvShowDetail: detail,
+		Credits:      b.getCredits(id),
+		Images:       bangumiImages(subject),
+	}, nil
+}
+
+func (b *bangumiProvider) GetSeasonDetail(id int64, seasonNumber int) (*tmdb.SeasonDetail, error) {
+	subject, err := b.getSubject(id)
+	if err != nil {
+		helpers.AppLogger.Errorf("查询bangumi条目详情失败,下次重试, 失败原因: %v", err)
+		return nil, err
+	}
+	seasonDetail := &tmdb.SeasonDetail{
+		ID:           subject.ID,
+		Name:         seasonName(seasonNumber),
+		AirDate:      subject.Date,
+		PosterPath:   subject.Images.Large,
+		SeasonNumber: seasonNumber,
+		VoteAverage:  subject.Rating.Score,
+	}
+	if seasonNumber == 1 {
+		seasonDetail.Overview = strings.TrimSpace(subject.Summary)
+		seasonDetail.EpisodeCount = subject.TotalEpisodes
+	}
+	return seasonDetail, nil
+}
+
+func (b *bangumiProvider) GetEpisodeDetail(id int64, seasonNumber int, episodeNumber int) (*tmdb.Episode, error) {
+	episodes, err := b.getEpisodes(id)
+	if err != nil {
+		helpers.AppLogger.Errorf("查询bangumi章节列表失败,下次重试, 失败原因: %v", err)
+		return nil, err
+	}
+	var matched *bangumi.Episode
+	for i := range episodes {
+		if int(episodes[i].Ep) == episodeNumber {
+			matched = &episodes[i]
+			break
+		}
+	}
+	if matched == nil {
+		// 部分条目没有ep字段，使用sort匹配
+		for i := range episodes {
+			if int(episodes[i].Sort) == episodeNumber {
+				matched = &episodes[i]
+				break
+			}
+		}
+	}
+	if matched == nil {
+		return nil, fmt.Errorf("bangumi没有第 %d 集的数据", episodeNumber)
+	}
+	return &tmdb.Episode{
+		ID:            matched.ID,
+		Name:          bangumiName(matched.NameCn, matched.Name),
+		Overview:      strings.TrimSpace(matched.Desc),
+		AirDate:       matched.Airdate,
+		EpisodeNumber: episodeNumber,
+		SeasonNumber:  seasonNumber,
+		Runtime:       matched.DurationSeconds / 60,
+		ShowID:        id,
+	}, nil
+}
+
+// 角色和声优作为演员，导演作为制作人员，Bangumi的人员ID不是TMDB的ID，统一为0
+func (b *bangumiProvider) getCredits(id int64) *tmdb.PepolesRes {
+	credits := &tmdb.PepolesRes{ID: id, Cast: make([]tmdb.Cast, 0), Crew: make([]tmdb.Crew, 0)}
+	characters, err := b.client.GetCharacters(id)
+	if err != nil {
+		helpers.AppLogger.Warnf("查询bangumi角色失败: %v", err)
+	}
+	for index, character := range characters {
+		if len(character.Actors) == 0 {
+			continue
+		}
+		actor := character.Actors[0]
+		credits.Cast = append(credits.Cast, tmdb.Cast{
+			PeopleBase: tmdb.PeopleBase{
+				Name:         actor.Name,
+				OriginalName: actor.Name,
+				ProfilePath:  actor.Images.Large,
+				Order:        int64(index),
+			},
+			Character: character.Name,
+		})
+	}
+	persons, err := b.client.GetPersons(id)
+	if err != nil {
+		helpers.AppLogger.Warnf("查询bangumi制作人员失败: %v", err)
+	}
+	for _, person := range persons {
+		if person.Relation != "导演" {
+			continue
+		}
+		credits.Crew = append(credits.Crew, tmdb.Crew{
+			PeopleBase: tmdb.PeopleBase{
+				Name:         person.Name,
+				OriginalName: person.Name,
+				ProfilePath:  person.Images.Large,
+			},
+			Department: "Directing",
+			Job:        "Director",
+		})
+	}
+	return credits
+}
+
+// 识别时使用Bangumi查询
+type bangumiCheckImpl struct {
+	client *bangumi.Client
+}
+
+func (b *bangumiCheckImpl) CheckByNameAndYear(name string, year int, switchYear bool) (string, int64, int, error) {
+	subjects, err := b.client.SearchSubjects(name, bangumi.SubjectTypeAnime)
+	if err != nil {
+		helpers.AppLogger.Errorf("查询bangumi失败, 下次重试, 失败原因: %v", err)
+		return "", 0, 0, err
+	}
+	candidates := make([]bangumi.Subject, 0, len(subjects))
+	for _, subject := range subjects {
+		if year > 0 && !switchYear && bangumiYear(subject.Date) != year {
+			continue
+		}
+		candidates = append(candidates, subject)
+	}
+	if year > 0 && switchYear {
+		// 优先使用年份匹配的条目，没有时再使用全部结果
+		yearMatched := make([]bangumi.Subject, 0)
+		for _, subject := range candidates {
+			if bangumiYear(subject.Date) == year {
+				yearMatched = append(yearMatched, subject)
+			}
+		}
+		if len(yearMatched) > 0 {
+			candidates = yearMatched
+		}
+	}
+	if len(candidates) == 0 {
+		return "", 0, 0, errors.New("bangumi没有数据")
+	}
+	subject := candidates[0]
+	if len(candidates) > 1 {
+		matched := false
+		for _, candidate := range candidates {
+			if nameMatched(name, candidate.NameCn, candidate.Name) {
+				subject = candidate
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			helpers.AppLogger.Errorf("通过名称 %s 年份 %d 在Bangumi查询到多条记录，需要手工重新识别输入确定的bangumi id", name, year)
+			return "", 0, 0, errors.New("多条记录")
+		}
+	}
+	return bangumiName(subject.NameCn, subject.Name), subject.ID, bangumiYear(subject.Date), nil
+}
+
+func (b *bangumiCheckImpl) CheckByTmdbId(tmdbId int64) (string, int, error) {
+	return "", 0, errTmdbIdNotSupported
+}
+
+func bangumiName(nameCn, name string) string {
+	if nameCn != "" {
+		return nameCn
+	}
+	return name
+}
+
+func bangumiYear(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	return helpers.StringToInt(date[:4])
+}
+
+func bangumiGenres(tmdbGenres []helpers.Genre) []tmdb.Genre {
+	if genre, ok := findGenre(tmdbGenres, tmdbGenreAnimation); ok {
+		return []tmdb.Genre{genre}
+	}
+	return []tmdb.Genre{}
+}
+
+func bangumiImages(subject *bangumi.Subject) *tmdb.Images {
+	images := &tmdb.Images{ID: subject.ID}
+	if subject.Images.Large != "" {
+		images.Posters = append(images.Posters, tmdb.Image{FilePath: subject.Images.Large, ISO_639_1: "ja"})
+	}
+	return images
+}
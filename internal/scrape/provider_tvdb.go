@@ -0,0 +1,488 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/tmdb"
+	"Q115-STRM/internal/tvdb"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TVDB图片类型
+const (
+	tvdbArtworkSeriesPoster     = 2
+	tvdbArtworkSeriesBackground = 3
+	tvdbArtworkSeriesLogo       = 23
+	tvdbArtworkMoviePoster      = 14
+	tvdbArtworkMovieBackground  = 15
+	tvdbArtworkMovieLogo        = 25
+)
+
+// TVDB流派slug对应的TMDB电影流派ID
+var tvdbMovieGenreMap = map[string]int{
+	"action":          28,
+	"adventure":       12,
+	"animation":       16,
+	"anime":           16,
+	"comedy":          35,
+	"crime":           80,
+	"documentary":     99,
+	"drama":           18,
+	"family":          10751,
+	"children":        10751,
+	"fantasy":         14,
+	"history":         36,
+	"horror":          27,
+	"musical":         10402,
+	"mystery":         9648,
+	"romance":         10749,
+	"science-fiction": 878,
+	"thriller":        53,
+	"suspense":        53,
+	"war":             10752,
+	"western":         37,
+}
+
+// TVDB流派slug对应的TMDB电视剧流派ID
+var tvdbTvGenreMap = map[string]int{
+	"action":          10759,
+	"adventure":       10759,
+	"animation":       16,
+	"anime":           16,
+	"comedy":          35,
+	"crime":           80,
+	"documentary":     99,
+	"drama":           18,
+	"family":          10751,
+	"children":        10762,
+	"mystery":         9648,
+	"suspense":        9648,
+	"thriller":        9648,
+	"news":            10763,
+	"reality":         10764,
+	"game-show":       10764,
+	"science-fiction": 10765,
+	"fantasy":         10765,
+	"soap":            10766,
+	"talk-show":       10767,
+	"war":             10768,
+	"western":         37,
+}
+
+// TVDB使用三位的语言和国家代码，TMDB使用两位的
+var tvdbLanguageMap = map[string]string{
+	"zho": "zh",
+	"eng": "en",
+	"jpn": "ja",
+	"kor": "ko",
+	"fra": "fr",
+	"deu": "de",
+	"spa": "es",
+	"ita": "it",
+	"rus": "ru",
+	"por": "pt",
+	"tha": "th",
+}
+
+var tvdbCountryMap = map[string]string{
+	"chn": "CN",
+	"hkg": "HK",
+	"twn": "TW",
+	"usa": "US",
+	"gbr": "GB",
+	"jpn": "JP",
+	"kor": "KR",
+	"fra": "FR",
+	"deu": "DE",
+	"esp": "ES",
+	"ita": "IT",
+	"can": "CA",
+	"aus": "AU",
+	"ind": "IN",
+	"tha": "TH",
+	"rus": "RU",
+}
+
+// 将TMDB的语言设置（例如zh-CN）转换为TVDB的三位语言代码
+func tvdbLanguage() string {
+	lang := strings.ToLower(strings.Split(models.GlobalScrapeSettings.GetTmdbLanguage(), "-")[0])
+	for tvdbLang, isoLang := range tvdbLanguageMap {
+		if isoLang == lang {
+			return tvdbLang
+		}
+	}
+	return "eng"
+}
+
+func tvdbIsoLanguage(lang string) string {
+	if isoLang, ok := tvdbLanguageMap[lang]; ok {
+		return isoLang
+	}
+	return lang
+}
+
+func tvdbIsoCountry(country string) string {
+	if isoCountry, ok := tvdbCountryMap[country]; ok {
+		return isoCountry
+	}
+	return strings.ToUpper(country)
+}
+
+// 从TheTVDB刮削元数据
+type tvdbProvider struct {
+	client *tvdb.Client
+	// 同一次刮削任务中缓存剧集详情和季的集列表，避免每一集都重复查询
+	mutex    sync.Mutex
+	series   map[int64]*tvdb.SeriesExtended
+	episodes map[string][]tvdb.Episode
+}
+
+func newTvdbProvider() *tvdbProvider {
+	return &tvdbProvider{
+		client:   models.GlobalScrapeSettings.GetTvdbClient(),
+		series:   make(map[int64]*tvdb.SeriesExtended),
+		episodes: make(map[string][]tvdb.Episode),
+	}
+}
+
+func (t *tvdbProvider) Name() string {
+	return models.MetadataProviderTvdb
+}
+
+func (t *tvdbProvider) MovieImpl() TmdbImpl {
+	return &tvdbCheckImpl{client: t.client, searchType: "movie"}
+}
+
+func (t *tvdbProvider) TvShowImpl() TmdbImpl {
+	return &tvdbCheckImpl{client: t.client, searchType: "series"}
+}
+
+func (t *tvdbProvider) GetMovieInfo(id int64, onlyDetail bool) (*models.TmdbInfo, error) {
+	movie, err := t.client.GetMovieExtended(id)
+	if err != nil {
+		helpers.AppLogger.Errorf("查询tvdb电影详情失败, 下次重试, 失败原因: %v", err)
+		return nil, err
+	}
+	lang := tvdbLanguage()
+	detail := &tmdb.MovieDetail{
+		SearchMovie: tmdb.SearchMovie{
+			ID:               movie.ID,
+			Title:            movie.Name,
+			OriginalTitle:    movie.Name,
+			PosterPath:       movie.Image,
+			ReleaseDate:      movie.FirstRelease.Date,
+			OriginalLanguage: tvdbIsoLanguage(movie.OriginalLanguage),
+		},
+		Runtime: movie.Runtime,
+		Status:  movie.Status.Name,
+		Genres:  tvdbGenres(movie.Genres, tvdbMovieGenreMap, helpers.MovieGenres),
+		ImdbID:  tvdbImdbId(movie.RemoteIds),
+	}
+	if detail.ReleaseDate == "" && movie.Year != "" {
+		detail.ReleaseDate = movie.Year + "-01-01"
+	}
+	if translation, terr := t.client.GetMovieTranslation(id, lang); terr == nil {
+		if translation.Name != "" {
+			detail.Title = translation.Name
+		}
+		detail.Overview = strings.TrimSpace(translation.Overview)
+		detail.Tagline = translation.Tagline
+	}
+	tmdbInfo := &models.TmdbInfo{MovieDetail: detail}
+	if onlyDetail {
+		return tmdbInfo, nil
+	}
+	tmdbInfo.Credits = tvdbCredits(movie.ID, movie.Characters)
+	tmdbInfo.Images = tvdbImages(movie.ID, movie.Artworks, lang, tvdbArtworkMoviePoster, tvdbArtworkMovieBackground, tvdbArtworkMovieLogo, movie.Image)
+	return tmdbInfo, nil
+}
+
+func (t *tvdbProvider) getSeries(id int64) (*tvdb.SeriesExtended, error) {
+	t.mutex.Lock()
+	series, ok := t.series[id]
+	t.mutex.Unlock()
+	if ok {
+		return series, nil
+	}
+	series, err := t.client.GetSeriesExtended(id)
+	if err != nil {
+		return nil, err
+	}
+	t.mutex.Lock()
+	t.series[id] = series
+	t.mutex.Unlock()
+	return series, nil
+}
+
+func (t *tvdbProvider) getEpisodes(id int64, seasonNumber int) ([]tvdb.Episode, error) {
+	key := fmt.Sprintf("%d-%d", id, seasonNumber)
+	t.mutex.Lock()
+	episodes, ok := t.episodes[key]
+	t.mutex.Unlock()
+	if ok {
+		return episodes, nil
+	}
+	episodes, err := t.client.GetSeriesEpisodes(id, seasonNumber, tvdbLanguage())
+	if err != nil {
+		return nil, err
+	}
+	t.mutex.Lock()
+	t.episodes[key] = episodes
+	t.mutex.Unlock()
+	return episodes, nil
+}
+
+func (t *tvdbProvider) GetTvShowInfo(id int64) (*models.TmdbInfo, error) {
+	series, err := t.getSeries(id)
+	if err != nil {
+		helpers.AppLogger.Errorf("查询tvdb电视剧详情失败, 下次重试, 失败原因: %v", err)
+		return nil, err
+	}
+	lang := tvdbLanguage()
+	detail := &tmdb.TvDetail{
+		SearchTv: tmdb.SearchTv{
+			ID:               series.ID,
+			Name:             series.Name,
+			OriginalName:     series.Name,
+			Overview:         strings.TrimSpace(series.Overview),
+			PosterPath:       series.Image,
+			FirstAirDate:     series.FirstAired,
+			OriginalLanguage: tvdbIsoLanguage(series.OriginalLanguage),
+			OriginCountry:    []string{},
+		},
+		Genres:      tvdbGenres(series.Genres, tvdbTvGenreMap, helpers.TvshowGenres),
+		LastAirDate: series.LastAired,
+		Status:      series.Status.Name,
+		Seasons:     make([]tmdb.Season, 0),
+	}
+	if series.OriginalCountry != "" {
+		detail.OriginCountry = append(detail.OriginCountry, tvdbIsoCountry(series.OriginalCountry))
+	}
+	if series.AverageRuntime > 0 {
+		detail.EpisodeRunTime = []int{series.AverageRuntime}
+	}
+	for _, season := range series.Seasons {
+		if season.Type.Type != "official" {
+			continue
+		}
+		detail.Seasons = append(detail.Seasons, tmdb.Season{
+			ID:           season.ID,
+			Name:         season.Name,
+			PosterPath:   season.Image,
+			SeasonNumber: season.Number,
+		})
+		if season.Number > 0 {
+			detail.NumberOfSeasons++
+		}
+	}
+	if translation, terr := t.client.GetSeriesTranslation(id, lang); terr == nil {
+		if translation.Name != "" {
+			detail.Name = translation.Name
+		}
+		if translation.Overview != "" {
+			detail.Overview = strings.TrimSpace(translation.Overview)
+		}
+		detail.Tagline = translation.Tagline
+	}
+	return &models.TmdbInfo{
+		TvShowDetail: detail,
+		Credits:      tvdbCredits(series.ID, series.Characters),
+		Images:       tvdbImages(series.ID, series.Artworks, lang, tvdbArtworkSeriesPoster, tvdbArtworkSeriesBackground, tvdbArtworkSeriesLogo, series.Image),
+	}, nil
+}
+
+func (t *tvdbProvider) GetSeasonDetail(id int64, seasonNumber int) (*tmdb.SeasonDetail, error) {
+	series, err := t.getSeries(id)
+	if err != nil {
+		helpers.AppLogger.Errorf("查询tvdb电视剧详情失败,下次重试, 失败原因: %v", err)
+		return nil, err
+	}
+	episodes, err := t.getEpisodes(id, seasonNumber)
+	if err != nil {
+		helpers.AppLogger.Errorf("查询tvdb电视剧季详情失败,下次重试, 失败原因: %v", err)
+		return nil, err
+	}
+	seasonDetail := &tmdb.SeasonDetail{
+		Name:         seasonName(seasonNumber),
+		SeasonNumber: seasonNumber,
+		EpisodeCount: len(episodes),
+	}
+	for _, season := range series.Seasons {
+		if season.Type.Type == "official" && season.Number == seasonNumber {
+			seasonDetail.ID = season.ID
+			seasonDetail.PosterPath = season.Image
+			if season.Name != "" {
+				seasonDetail.Name = season.Name
+			}
+			break
+		}
+	}
+	for _, episode := range episodes {
+		if episode.Aired != "" && (seasonDetail.AirDate == "" || episode.Aired < seasonDetail.AirDate) {
+			seasonDetail.AirDate = episode.Aired
+		}
+	}
+	return seasonDetail, nil
+}
+
+func (t *tvdbProvider) GetEpisodeDetail(id int64, seasonNumber int, episodeNumber int) (*tmdb.Episode, error) {
+	episodes, err := t.getEpisodes(id, seasonNumber)
+	if err != nil {
+		helpers.AppLogger.Errorf("查询tvdb电视剧集详情失败,下次重试, 失败原因: %v", err)
+		return nil, err
+	}
+	for _, episode := range episodes {
+		if episode.Number != episodeNumber {
+			continue
+		}
+		return &tmdb.Episode{
+			ID:            episode.ID,
+			Name:          episode.Name,
+			Overview:      strings.TrimSpace(episode.Overview),
+			AirDate:       episode.Aired,
+			EpisodeNumber: episode.Number,
+			SeasonNumber:  episode.SeasonNumber,
+			Runtime:       episode.Runtime,
+			ShowID:        id,
+			StillPath:     episode.Image,
+		}, nil
+	}
+	return nil, fmt.Errorf("tvdb没有第 %d 季第 %d 集的数据", seasonNumber, episodeNumber)
+}
+
+// 识别时使用TVDB查询
+type tvdbCheckImpl struct {
+	client     *tvdb.Client
+	searchType string // series 或 movie
+}
+
+func (t *tvdbCheckImpl) CheckByNameAndYear(name string, year int, switchYear bool) (string, int64, int, error) {
+	lang := tvdbLanguage()
+	results, err := t.client.Search(name, year, t.searchType, "")
+	if err != nil {
+		helpers.AppLogger.Errorf("查询tvdb失败, 下次重试, 失败原因: %v", err)
+		return "", 0, 0, err
+	}
+	if len(results) == 0 {
+		if switchYear && year > 0 {
+			// 不限制年份再查一次
+			return t.CheckByNameAndYear(name, 0, false)
+		}
+		return "", 0, 0, errors.New("tvdb没有数据")
+	}
+	first := results[0]
+	if len(results) > 1 && !nameMatched(name, append([]string{first.Name, first.Translations[lang]}, first.Aliases...)...) {
+		helpers.AppLogger.Errorf("通过名称 %s 年份 %d 在TVDB查询到多条记录，需要手工重新识别输入确定的tvdb id", name, year)
+		return "", 0, 0, errors.New("多条记录")
+	}
+	id, err := strconv.ParseInt(first.TvdbId, 10, 64)
+	if err != nil || id == 0 {
+		return "", 0, 0, fmt.Errorf("tvdb返回的ID无效: %s", first.TvdbId)
+	}
+	title := first.Name
+	if first.Translations[lang] != "" {
+		title = first.Translations[lang]
+	}
+	return title, id, helpers.StringToInt(first.Year), nil
+}
+
+func (t *tvdbCheckImpl) CheckByTmdbId(tmdbId int64) (string, int, error) {
+	return "", 0, errTmdbIdNotSupported
+}
+
+func seasonName(seasonNumber int) string {
+	if seasonNumber == 0 {
+		return "特别篇"
+	}
+	return fmt.Sprintf("第 %d 季", seasonNumber)
+}
+
+func tvdbGenres(genres []tvdb.Genre, genreMap map[string]int, tmdbGenres []helpers.Genre) []tmdb.Genre {
+	result := make([]tmdb.Genre, 0)
+	for _, genre := range genres {
+		id, ok := genreMap[genre.Slug]
+		if !ok {
+			continue
+		}
+		tmdbGenre, ok := findGenre(tmdbGenres, id)
+		if !ok || slices.Contains(result, tmdbGenre) {
+			continue
+		}
+		result = append(result, tmdbGenre)
+	}
+	return result
+}
+
+func tvdbImdbId(remoteIds []tvdb.RemoteId) string {
+	for _, remoteId := range remoteIds {
+		if strings.EqualFold(remoteId.SourceName, "IMDB") {
+			return remoteId.ID
+		}
+	}
+	return ""
+}
+
+// 演职人员，非TMDB的人员ID统一为0
+func tvdbCredits(id int64, characters []tvdb.Character) *tmdb.PepolesRes {
+	credits := &tmdb.PepolesRes{ID: id, Cast: make([]tmdb.Cast, 0), Crew: make([]tmdb.Crew, 0)}
+	for _, character := range characters {
+		people := tmdb.PeopleBase{
+			Name:         character.PersonName,
+			OriginalName: character.PersonName,
+			ProfilePath:  character.PersonImg,
+			Order:        character.Sort,
+		}
+		switch character.PeopleType {
+		case "Actor", "Guest Star":
+			credits.Cast = append(credits.Cast, tmdb.Cast{PeopleBase: people, Character: character.Name})
+		case "Director":
+			credits.Crew = append(credits.Crew, tmdb.Crew{PeopleBase: people, Department: "Directing", Job: "Director"})
+		}
+	}
+	slices.SortStableFunc(credits.Cast, func(a, b tmdb.Cast) int {
+		return int(a.Order - b.Order)
+	})
+	return credits
+}
+
+// 图片，优先使用刮削语言的图片，没有语言的图片次之
+func tvdbImages(id int64, artworks []tvdb.Artwork, lang string, posterType, backgroundType, logoType int, defaultPoster string) *tmdb.Images {
+	images := &tmdb.Images{ID: id}
+	sorted := slices.Clone(artworks)
+	slices.SortStableFunc(sorted, func(a, b tvdb.Artwork) int {
+		return tvdbArtworkRank(a, lang) - tvdbArtworkRank(b, lang)
+	})
+	for _, artwork := range sorted {
+		image := tmdb.Image{FilePath: artwork.Image, Width: artwork.Width, Height: artwork.Height, ISO_639_1: tvdbIsoLanguage(artwork.Language)}
+		switch artwork.Type {
+		case posterType:
+			images.Posters = append(images.Posters, image)
+		case backgroundType:
+			images.Backdrops = append(images.Backdrops, image)
+		case logoType:
+			images.Logos = append(images.Logos, image)
+		}
+	}
+	if len(images.Posters) == 0 && defaultPoster != "" {
+		images.Posters = append(images.Posters, tmdb.Image{FilePath: defaultPoster})
+	}
+	return images
+}
+
+func tvdbArtworkRank(artwork tvdb.Artwork, lang string) int {
+	switch artwork.Language {
+	case lang:
+		return 0
+	case "":
+		return 1
+	case "eng":
+		return 2
+	default:
+		return 3
+	}
+}
@@ -9,3 +9,23 @@ type RenameBase struct {
 	scrapePath *models.ScrapePath
 	ctx        context.Context
 }
+
+// 视频文件所在的来源目录，电视剧没有季目录时使用电视剧目录
+func (r *RenameBase) sourceDir(mediaFile *models.ScrapeMediaFile) (string, string) {
+	if mediaFile.PathId == "" && mediaFile.MediaType == models.MediaTypeTvShow {
+		return mediaFile.TvshowPath, mediaFile.TvshowPathId
+	}
+	return mediaFile.Path, mediaFile.PathId
+}
+
+// 记录整理日志，用来批量撤销
+func (r *RenameBase) journal(mediaFile *models.ScrapeMediaFile, action models.ScrapeJournalAction, fileId, originalPath, originalParentId, newPath, newParentId string) {
+	models.AddScrapeJournal(mediaFile, &models.ScrapeJournal{
+		Action:           action,
+		FileId:           fileId,
+		OriginalPath:     originalPath,
+		OriginalParentId: originalParentId,
+		NewPath:          newPath,
+		NewParentId:      newParentId,
+	})
+}
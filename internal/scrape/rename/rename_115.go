@@ -6,6 +6,7 @@ import (
 	"Q115-STRM/internal/v115open"
 	"context"
 	"errors"
+	"fmt"
 	"path/filepath"
 	"strings"
 )
@@ -45,6 +46,7 @@ func (r *Rename115) RenameAndMove(mediaFile *models.ScrapeMediaFile, destPath, d
 func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath, newName string) error {
 	// helpers.AppLogger.Infof("115整理文件：%s 到 %s", mediaFile.Path+"/"+mediaFile.VideoFilename, destPath+"/"+newName)
 	// 先检查是否已存在，如果已存在，就不移动了
+	sourcePath, sourcePathId := r.sourceDir(mediaFile)
 	detail, detailErr := r.client.GetFsDetailByPath(r.ctx, filepath.Join(destPath, newName))
 	if detail == nil || detailErr != nil || detail.FileId == "" {
 		_, err := r.client.Move(r.ctx, []string{mediaFile.VideoFileId}, destPathId)
@@ -53,6 +55,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 			return err
 		} else {
 			helpers.AppLogger.Infof("文件 %s 成功移动到 %s", mediaFile.Path+"/"+mediaFile.VideoFilename, destPath+"/"+newName)
+			r.journal(mediaFile, models.ScrapeJournalActionMove, mediaFile.VideoFileId, filepath.Join(sourcePath, mediaFile.VideoFilename), sourcePathId, filepath.Join(destPath, mediaFile.VideoFilename), destPathId)
 		}
 		if mediaFile.VideoFilename != newName {
 			// 改名
@@ -62,6 +65,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 				return err
 			} else {
 				helpers.AppLogger.Infof("文件 %s 成功重命名为 %s", mediaFile.VideoFilename, newName)
+				r.journal(mediaFile, models.ScrapeJournalActionRename, mediaFile.VideoFileId, filepath.Join(destPath, mediaFile.VideoFilename), destPathId, filepath.Join(destPath, newName), destPathId)
 			}
 		}
 		if mediaFile.MediaType != models.MediaTypeTvShow {
@@ -107,6 +111,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 				helpers.AppLogger.Errorf("115移动字幕文件 %s 失败: %v", sub.FileName, err)
 				continue
 			}
+			r.journal(mediaFile, models.ScrapeJournalActionMove, sub.FileId, filepath.Join(sourcePath, sub.FileName), sourcePathId, filepath.Join(destPath, sub.FileName), destPathId)
 			// 检查是否需要改名
 			if mediaFile.VideoFilename != newName {
 				// 改名
@@ -119,6 +124,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 						continue
 					} else {
 						helpers.AppLogger.Infof("字幕文件 %s 成功重命名为 %s", sub.FileName, newSubName)
+						r.journal(mediaFile, models.ScrapeJournalActionRename, sub.FileId, filepath.Join(destPath, sub.FileName), destPathId, filepath.Join(destPath, newSubName), destPathId)
 					}
 					newSub.FileName = newSubName
 				}
@@ -143,6 +149,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 					helpers.AppLogger.Errorf("115移动图片文件 %s 失败: %v", imageFile.FileName, err)
 					continue
 				}
+				r.journal(mediaFile, models.ScrapeJournalActionMove, imageFile.FileId, filepath.Join(sourcePath, imageFile.FileName), sourcePathId, filepath.Join(destPath, imageFile.FileName), destPathId)
 				newSubName := strings.Replace(imageFile.FileName, oldBaseName, mediaFile.NewVideoBaseName, 1)
 				// 检查是否需要改名
 				if newSubName != imageFile.FileName {
@@ -153,6 +160,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 						continue
 					} else {
 						helpers.AppLogger.Infof("图片文件 %s 成功重命名为 %s", imageFile.FileName, newSubName)
+						r.journal(mediaFile, models.ScrapeJournalActionRename, imageFile.FileId, filepath.Join(destPath, imageFile.FileName), destPathId, filepath.Join(destPath, newSubName), destPathId)
 					}
 				}
 			}
@@ -164,6 +172,8 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 			_, err := r.client.Move(r.ctx, []string{mediaFile.NfoFileId}, destPathId)
 			if err != nil {
 				helpers.AppLogger.Errorf("115移动nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
+			} else {
+				r.journal(mediaFile, models.ScrapeJournalActionMove, mediaFile.NfoFileId, filepath.Join(sourcePath, mediaFile.NfoFileName), sourcePathId, filepath.Join(destPath, mediaFile.NfoFileName), destPathId)
 			}
 			// 检查是否需要改名
 			if newNfoName != mediaFile.NfoFileName {
@@ -173,6 +183,7 @@ func (r *Rename115) move(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 					helpers.AppLogger.Errorf("115改名nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
 				} else {
 					helpers.AppLogger.Infof("nfo文件 %s 成功重命名为 %s", mediaFile.NfoFileName, newNfoName)
+					r.journal(mediaFile, models.ScrapeJournalActionRename, mediaFile.NfoFileId, filepath.Join(destPath, mediaFile.NfoFileName), destPathId, filepath.Join(destPath, newNfoName), destPathId)
 				}
 
 			}
@@ -186,6 +197,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 	var err error
 	var videoFileId string = mediaFile.VideoFileId
 	var pickcode string = mediaFile.VideoPickCode
+	sourcePath, sourcePathId := r.sourceDir(mediaFile)
 	// 先检查是否已存在，如果已存在，就不移动了
 	detail, detailErr := r.client.GetFsDetailByPath(r.ctx, filepath.Join(destPath, newName))
 	if detail == nil || detailErr != nil || detail.FileId == "" {
@@ -204,6 +216,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 			helpers.AppLogger.Infof("复制文件 %s 到 %s 后，新文件ID为 %s", mediaFile.VideoFilename, filepath.Join(destPath, mediaFile.VideoFilename), newDetail.FileId)
 			videoFileId = newDetail.FileId
 			pickcode = newDetail.PickCode
+			r.journal(mediaFile, models.ScrapeJournalActionCopy, videoFileId, filepath.Join(sourcePath, mediaFile.VideoFilename), sourcePathId, filepath.Join(destPath, mediaFile.VideoFilename), destPathId)
 		}
 		if mediaFile.VideoFilename != newName {
 			// 改名
@@ -213,6 +226,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 				return err
 			} else {
 				helpers.AppLogger.Infof("文件 %s 成功重命名为 %s", filepath.Join(mediaFile.Path, mediaFile.VideoFilename), filepath.Join(destPath, newName))
+				r.journal(mediaFile, models.ScrapeJournalActionRename, videoFileId, filepath.Join(destPath, mediaFile.VideoFilename), destPathId, filepath.Join(destPath, newName), destPathId)
 			}
 		}
 		if mediaFile.MediaType != models.MediaTypeTvShow {
@@ -277,6 +291,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 			newSub.FileId = newSubDetail.FileId
 			newSub.PickCode = newSubDetail.PickCode
 			mediaFile.Media.SubtitleFiles = append(mediaFile.Media.SubtitleFiles, newSub)
+			r.journal(mediaFile, models.ScrapeJournalActionCopy, newSub.FileId, filepath.Join(sourcePath, sub.FileName), sourcePathId, filepath.Join(destPath, sub.FileName), destPathId)
 			// 检查是否需要改名
 			if newSubName != sub.FileName {
 				// 改名
@@ -286,6 +301,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 					continue
 				} else {
 					helpers.AppLogger.Infof("字幕文件 %s 成功重命名为 %s", sub.FileName, newSubName)
+					r.journal(mediaFile, models.ScrapeJournalActionRename, newSub.FileId, filepath.Join(destPath, sub.FileName), destPathId, filepath.Join(destPath, newSubName), destPathId)
 				}
 			}
 		}
@@ -314,6 +330,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 					continue
 				}
 				imageFile.FileId = newImageDetail.FileId
+				r.journal(mediaFile, models.ScrapeJournalActionCopy, imageFile.FileId, filepath.Join(sourcePath, imageFile.FileName), sourcePathId, filepath.Join(destPath, imageFile.FileName), destPathId)
 				newSubName := strings.Replace(imageFile.FileName, oldBaseName, mediaFile.NewVideoBaseName, 1)
 				// 检查是否需要改名
 				if newSubName != imageFile.FileName {
@@ -324,6 +341,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 						continue
 					} else {
 						helpers.AppLogger.Infof("图片文件 %s 成功重命名为 %s", imageFile.FileName, newSubName)
+						r.journal(mediaFile, models.ScrapeJournalActionRename, imageFile.FileId, filepath.Join(destPath, imageFile.FileName), destPathId, filepath.Join(destPath, newSubName), destPathId)
 					}
 				}
 			}
@@ -337,11 +355,12 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 				helpers.AppLogger.Errorf("115复制nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
 			}
 			// 查询新文件ID
-			newNfoDetail, newNfoDetailErr := r.client.GetFsDetailByPath(r.ctx, filepath.Join(destPath, newNfoName))
+			newNfoDetail, newNfoDetailErr := r.client.GetFsDetailByPath(r.ctx, filepath.Join(destPath, mediaFile.NfoFileName))
 			if newNfoDetailErr != nil {
-				helpers.AppLogger.Errorf("复制nfo文件 %s 到 %s 后，查询新文件ID失败: %v", mediaFile.NfoFileId, filepath.Join(destPath, newNfoName), newNfoDetailErr)
+				helpers.AppLogger.Errorf("复制nfo文件 %s 到 %s 后，查询新文件ID失败: %v", mediaFile.NfoFileId, filepath.Join(destPath, mediaFile.NfoFileName), newNfoDetailErr)
 			} else {
 				mediaFile.NfoFileId = newNfoDetail.FileId
+				r.journal(mediaFile, models.ScrapeJournalActionCopy, mediaFile.NfoFileId, filepath.Join(sourcePath, mediaFile.NfoFileName), sourcePathId, filepath.Join(destPath, mediaFile.NfoFileName), destPathId)
 				// 检查是否需要改名
 				if newNfoName != mediaFile.NfoFileName {
 					// 改名
@@ -350,6 +369,7 @@ func (r *Rename115) copy(mediaFile *models.ScrapeMediaFile, destPathId, destPath
 						helpers.AppLogger.Errorf("115改名nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
 					} else {
 						helpers.AppLogger.Infof("nfo文件 %s 成功重命名为 %s", mediaFile.NfoFileName, newNfoName)
+						r.journal(mediaFile, models.ScrapeJournalActionRename, mediaFile.NfoFileId, filepath.Join(destPath, mediaFile.NfoFileName), destPathId, filepath.Join(destPath, newNfoName), destPathId)
 					}
 				}
 			}
@@ -592,3 +612,40 @@ func (r *Rename115) ExistsAndRename(fileId, newName string) (string, error) {
 	helpers.AppLogger.Infof("重命名115文件成功, %s => %s", fileId, newName)
 	return fileId, nil
 }
+
+// 撤销整理日志中记录的操作
+func (r *Rename115) Undo(journal *models.ScrapeJournal) error {
+	detail, err := r.client.GetFsDetailByCid(r.ctx, journal.FileId)
+	if err != nil || detail == nil || detail.FileId == "" {
+		return fmt.Errorf("115文件 %s 已不存在", journal.NewPath)
+	}
+	if journal.Action != models.ScrapeJournalActionCopy {
+		// 原位置已经有同名文件时不覆盖
+		existsDetail, existsErr := r.client.GetFsDetailByPath(r.ctx, journal.OriginalPath)
+		if existsErr == nil && existsDetail != nil && existsDetail.FileId != "" && existsDetail.FileId != journal.FileId {
+			return fmt.Errorf("原位置 %s 已存在同名文件", journal.OriginalPath)
+		}
+	}
+	switch journal.Action {
+	case models.ScrapeJournalActionCopy:
+		// 复制出来的文件直接删除
+		_, err = r.client.Del(r.ctx, []string{journal.FileId}, journal.NewParentId)
+	case models.ScrapeJournalActionRename:
+		_, err = r.client.ReName(r.ctx, journal.FileId, filepath.Base(journal.OriginalPath))
+	case models.ScrapeJournalActionMove:
+		// 整理后来源目录可能已被删除，按原路径重新创建
+		parentId, mkErr := r.CheckAndMkDir(filepath.Dir(journal.OriginalPath), r.scrapePath.SourcePath, r.scrapePath.SourcePathId)
+		if mkErr != nil {
+			return mkErr
+		}
+		_, err = r.client.Move(r.ctx, []string{journal.FileId}, parentId)
+	default:
+		return fmt.Errorf("不支持撤销的操作类型: %s", journal.Action)
+	}
+	if err != nil {
+		helpers.AppLogger.Errorf("撤销115整理操作失败: %s => %s %v", journal.NewPath, journal.OriginalPath, err)
+		return err
+	}
+	helpers.AppLogger.Infof("撤销115整理操作成功: %s => %s", journal.NewPath, journal.OriginalPath)
+	return nil
+}
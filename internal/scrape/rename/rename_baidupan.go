@@ -6,6 +6,7 @@ import (
 	"Q115-STRM/internal/models"
 	"context"
 	"errors"
+	"fmt"
 	"path/filepath"
 	"strings"
 )
@@ -132,11 +133,15 @@ func (r *RenameBaiduPan) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 		}
 	}
 	// 移动文件
+	if len(fileList) == 0 {
+		return nil
+	}
 	err := r.client.MoveBatch(r.ctx, fileList)
 	if err != nil {
 		helpers.AppLogger.Errorf("百度网盘移动文件失败: %v", err)
 		return err
 	}
+	r.journalBatch(mediaFile, models.ScrapeJournalActionMove, fileList)
 	return nil
 }
 
@@ -205,6 +210,7 @@ func (r *RenameBaiduPan) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 		helpers.AppLogger.Errorf("百度网盘 复制文件失败: %v", err)
 		return err
 	}
+	r.journalBatch(mediaFile, models.ScrapeJournalActionCopy, fileList)
 	start := 0
 	for {
 		// 查询新的fsid
@@ -408,3 +414,53 @@ func (r *RenameBaiduPan) ExistsAndRename(fileId, newName string) (string, error)
 	helpers.AppLogger.Infof("重命名百度网盘文件成功, %s => %s", fileId, newName)
 	return filepath.ToSlash(filepath.Join(filepath.Dir(fileId), newName)), nil
 }
+
+// 批量移动或复制成功后记录整理日志，百度网盘的文件ID就是完整路径
+func (r *RenameBaiduPan) journalBatch(mediaFile *models.ScrapeMediaFile, action models.ScrapeJournalAction, fileList []baidupan.MoveOrCopyItem) {
+	for _, item := range fileList {
+		newPath := filepath.ToSlash(filepath.Join(item.Dest, item.NewName))
+		r.journal(mediaFile, action, newPath, filepath.ToSlash(item.Path), filepath.ToSlash(filepath.Dir(item.Path)), newPath, item.Dest)
+	}
+}
+
+// 撤销整理日志中记录的操作
+func (r *RenameBaiduPan) Undo(journal *models.ScrapeJournal) error {
+	fsDetail, _ := r.client.FileExists(r.ctx, journal.NewPath)
+	if fsDetail == nil || fsDetail.ServerFilename == "" {
+		return fmt.Errorf("百度网盘文件 %s 已不存在", journal.NewPath)
+	}
+	if journal.Action != models.ScrapeJournalActionCopy {
+		// 原位置已经有同名文件时不覆盖
+		existsDetail, _ := r.client.FileExists(r.ctx, journal.OriginalPath)
+		if existsDetail != nil && existsDetail.ServerFilename != "" {
+			return fmt.Errorf("原位置 %s 已存在同名文件", journal.OriginalPath)
+		}
+	}
+	var err error
+	switch journal.Action {
+	case models.ScrapeJournalActionCopy:
+		// 复制出来的文件直接删除
+		err = r.client.Del(r.ctx, []string{journal.NewPath})
+	case models.ScrapeJournalActionRename:
+		err = r.client.Rename(r.ctx, journal.NewPath, filepath.Base(journal.OriginalPath))
+	case models.ScrapeJournalActionMove:
+		// 整理后来源目录可能已被删除，按原路径重新创建
+		parentPath := filepath.ToSlash(filepath.Dir(journal.OriginalPath))
+		if _, err = r.CheckAndMkDir(parentPath, r.scrapePath.SourcePath, r.scrapePath.SourcePathId); err != nil {
+			return err
+		}
+		err = r.client.MoveBatch(r.ctx, []baidupan.MoveOrCopyItem{{
+			Path:    journal.NewPath,
+			Dest:    parentPath,
+			NewName: filepath.Base(journal.OriginalPath),
+		}})
+	default:
+		return fmt.Errorf("不支持撤销的操作类型: %s", journal.Action)
+	}
+	if err != nil {
+		helpers.AppLogger.Errorf("撤销百度网盘整理操作失败: %s => %s %v", journal.NewPath, journal.OriginalPath, err)
+		return err
+	}
+	helpers.AppLogger.Infof("撤销百度网盘整理操作成功: %s => %s", journal.NewPath, journal.OriginalPath)
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"context"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -41,6 +42,7 @@ func (r *RenameLocal) RenameAndMove(mediaFile *models.ScrapeMediaFile, destPath,
 }
 
 func (r *RenameLocal) move(mediaFile *models.ScrapeMediaFile, destPathId, newName string) error {
+	action := models.ScrapeJournalActionMove
 	// 将视频文件复制到目标位置
 	sourcePath := mediaFile.PathId
 	if sourcePath == "" && mediaFile.MediaType == models.MediaTypeTvShow {
@@ -57,6 +59,7 @@ func (r *RenameLocal) move(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 			return err
 		} else {
 			helpers.AppLogger.Infof("文件 %s 成功移动到 %s", sourcePath+"/"+mediaFile.VideoFilename, destPathId+"/"+newName)
+			r.journal(mediaFile, action, destFullPath, sourceFullPath, sourcePath, destFullPath, destPathId)
 		}
 	}
 	if mediaFile.MediaType != models.MediaTypeTvShow {
@@ -83,6 +86,7 @@ func (r *RenameLocal) move(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 				helpers.AppLogger.Errorf("移动字幕文件 %s 到 %s 失败: %v", sub.FileName, destPathId+"/"+newSubName, err)
 			} else {
 				helpers.AppLogger.Infof("字幕文件 %s 成功移动到 %s", sub.FileId, destPathId+"/"+newSubName)
+				r.journal(mediaFile, action, newSubFullPath, sub.FileId, filepath.Dir(sub.FileId), newSubFullPath, destPathId)
 				newSub := &models.MediaMetaFiles{
 					FileName: newSubName,
 					FileId:   newSubFullPath,
@@ -114,6 +118,7 @@ func (r *RenameLocal) move(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 					helpers.AppLogger.Errorf("移动图片文件 %s 到 %s 失败: %v", imageFile.FileName, destPathId+"/"+newImageName, err)
 				} else {
 					helpers.AppLogger.Infof("图片文件 %s 成功移动到 %s", imageFile.FileId, destPathId+"/"+newImageName)
+					r.journal(mediaFile, action, filepath.Join(destPathId, newImageName), imageFile.FileId, filepath.Dir(imageFile.FileId), filepath.Join(destPathId, newImageName), destPathId)
 				}
 			}
 		}
@@ -126,6 +131,7 @@ func (r *RenameLocal) move(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 				helpers.AppLogger.Errorf("移动nfo文件 %s 到 %s 失败: %v", mediaFile.NfoFileName, destPathId+"/"+newNfoName, err)
 			} else {
 				helpers.AppLogger.Infof("nfo文件 %s 成功移动到 %s", mediaFile.NfoFileId, destPathId+"/"+newNfoName)
+				r.journal(mediaFile, action, filepath.Join(destPathId, newNfoName), mediaFile.NfoFileId, filepath.Dir(mediaFile.NfoFileId), filepath.Join(destPathId, newNfoName), destPathId)
 			}
 		}
 	}
@@ -133,6 +139,7 @@ func (r *RenameLocal) move(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 }
 
 func (r *RenameLocal) copy(mediaFile *models.ScrapeMediaFile, destPathId, newName string) error {
+	action := models.ScrapeJournalActionCopy
 	sourcePath := mediaFile.PathId
 	if sourcePath == "" && mediaFile.MediaType == models.MediaTypeTvShow {
 		sourcePath = mediaFile.TvshowPathId
@@ -148,6 +155,7 @@ func (r *RenameLocal) copy(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 			return err
 		} else {
 			helpers.AppLogger.Infof("文件 %s 成功移动到 %s", sourcePath+"/"+mediaFile.VideoFilename, destPathId+"/"+newName)
+			r.journal(mediaFile, action, destFullPath, sourceFullPath, sourcePath, destFullPath, destPathId)
 		}
 	}
 	if mediaFile.MediaType != models.MediaTypeTvShow {
@@ -174,6 +182,7 @@ func (r *RenameLocal) copy(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 				helpers.AppLogger.Errorf("移动字幕文件 %s 到 %s 失败: %v", sub.FileName, destPathId+"/"+newSubName, err)
 			} else {
 				helpers.AppLogger.Infof("字幕文件 %s 成功移动到 %s", sub.FileId, destPathId+"/"+newSubName)
+				r.journal(mediaFile, action, newSubFullPath, sub.FileId, filepath.Dir(sub.FileId), newSubFullPath, destPathId)
 				newSub := &models.MediaMetaFiles{
 					FileName: newSubName,
 					FileId:   newSubFullPath,
@@ -206,6 +215,7 @@ func (r *RenameLocal) copy(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 					helpers.AppLogger.Errorf("移动图片文件 %s 到 %s 失败: %v", imageFile.FileName, destPathId+"/"+newImageName, err)
 				} else {
 					helpers.AppLogger.Infof("图片文件 %s 成功移动到 %s", imageFile.FileId, destPathId+"/"+newImageName)
+					r.journal(mediaFile, action, filepath.Join(destPathId, newImageName), imageFile.FileId, filepath.Dir(imageFile.FileId), filepath.Join(destPathId, newImageName), destPathId)
 				}
 			}
 		}
@@ -218,6 +228,7 @@ func (r *RenameLocal) copy(mediaFile *models.ScrapeMediaFile, destPathId, newNam
 				helpers.AppLogger.Errorf("移动nfo文件 %s 到 %s 失败: %v", mediaFile.NfoFileName, destPathId+"/"+newNfoName, err)
 			} else {
 				helpers.AppLogger.Infof("nfo文件 %s 成功移动到 %s", mediaFile.NfoFileId, destPathId+"/"+newNfoName)
+				r.journal(mediaFile, action, filepath.Join(destPathId, newNfoName), mediaFile.NfoFileId, filepath.Dir(mediaFile.NfoFileId), filepath.Join(destPathId, newNfoName), destPathId)
 			}
 		}
 	}
@@ -245,6 +256,7 @@ func (r *RenameLocal) symlink(mediaFile *models.ScrapeMediaFile, destPathId, new
 			return err
 		} else {
 			helpers.AppLogger.Infof("文件 %s 成功链接到 %s", sourceFullPath, destFullPath)
+			r.journal(mediaFile, models.ScrapeJournalActionLink, destFullPath, sourceFullPath, sourcePath, destFullPath, destPathId)
 		}
 	}
 	if mediaFile.MediaType != models.MediaTypeTvShow {
@@ -275,6 +287,7 @@ func (r *RenameLocal) symlink(mediaFile *models.ScrapeMediaFile, destPathId, new
 				helpers.AppLogger.Errorf("创建硬链接字幕文件 %s 到 %s 失败: %v", sub.FileName, destPathId+"/"+newSubName, err)
 			} else {
 				helpers.AppLogger.Infof("字幕文件 %s 成功链接到 %s", sub.FileId, destPathId+"/"+newSubName)
+				r.journal(mediaFile, models.ScrapeJournalActionLink, filepath.Join(destPathId, newSubName), sub.FileId, filepath.Dir(sub.FileId), filepath.Join(destPathId, newSubName), destPathId)
 				newSub := &models.MediaMetaFiles{
 					FileName: newSubName,
 					FileId:   filepath.Join(destPathId, newSubName),
@@ -311,6 +324,7 @@ func (r *RenameLocal) symlink(mediaFile *models.ScrapeMediaFile, destPathId, new
 					helpers.AppLogger.Errorf("创建硬链接图片文件 %s 到 %s 失败: %v", imageFile.FileName, destPathId+"/"+newImageName, err)
 				} else {
 					helpers.AppLogger.Infof("图片文件 %s 成功硬链接到 %s", imageFile.FileId, destPathId+"/"+newImageName)
+					r.journal(mediaFile, models.ScrapeJournalActionLink, filepath.Join(destPathId, newImageName), imageFile.FileId, filepath.Dir(imageFile.FileId), filepath.Join(destPathId, newImageName), destPathId)
 				}
 			}
 		}
@@ -328,6 +342,7 @@ func (r *RenameLocal) symlink(mediaFile *models.ScrapeMediaFile, destPathId, new
 				helpers.AppLogger.Errorf("创建硬链接nfo文件 %s 到 %s 失败: %v", mediaFile.NfoFileName, destPathId+"/"+newNfoName, err)
 			} else {
 				helpers.AppLogger.Infof("nfo文件 %s 成功硬链接到 %s", mediaFile.NfoFileId, destPathId+"/"+newNfoName)
+				r.journal(mediaFile, models.ScrapeJournalActionLink, filepath.Join(destPathId, newNfoName), mediaFile.NfoFileId, filepath.Dir(mediaFile.NfoFileId), filepath.Join(destPathId, newNfoName), destPathId)
 			}
 		}
 	}
@@ -468,3 +483,34 @@ func (r *RenameLocal) ExistsAndRename(fileId, newName string) (string, error) {
 	helpers.AppLogger.Infof("重命名本地文件成功, %s => %s", fileId, newName)
 	return filepath.Join(filepath.Dir(fileId), newName), nil
 }
+
+// 撤销整理日志中记录的操作，本地文件的ID就是完整路径
+func (r *RenameLocal) Undo(journal *models.ScrapeJournal) error {
+	if _, err := os.Lstat(journal.NewPath); err != nil {
+		return fmt.Errorf("本地文件 %s 已不存在", journal.NewPath)
+	}
+	var err error
+	switch journal.Action {
+	case models.ScrapeJournalActionCopy, models.ScrapeJournalActionLink:
+		// 复制或链接出来的文件直接删除
+		err = os.Remove(journal.NewPath)
+	case models.ScrapeJournalActionMove, models.ScrapeJournalActionRename:
+		// 原位置已经有同名文件时不覆盖
+		if _, statErr := os.Lstat(journal.OriginalPath); statErr == nil {
+			return fmt.Errorf("原位置 %s 已存在同名文件", journal.OriginalPath)
+		}
+		// 整理后来源目录可能已被删除，按原路径重新创建
+		if err = os.MkdirAll(filepath.Dir(journal.OriginalPath), 0777); err != nil {
+			return err
+		}
+		err = helpers.MoveFile(journal.NewPath, journal.OriginalPath, false)
+	default:
+		return fmt.Errorf("不支持撤销的操作类型: %s", journal.Action)
+	}
+	if err != nil {
+		helpers.AppLogger.Errorf("撤销本地整理操作失败: %s => %s %v", journal.NewPath, journal.OriginalPath, err)
+		return err
+	}
+	helpers.AppLogger.Infof("撤销本地整理操作成功: %s => %s", journal.NewPath, journal.OriginalPath)
+	return nil
+}
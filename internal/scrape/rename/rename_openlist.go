@@ -7,6 +7,7 @@ import (
 	"Q115-STRM/internal/v115open"
 	"context"
 	"errors"
+	"fmt"
 	"path/filepath"
 	"strings"
 )
@@ -45,6 +46,7 @@ func (r *RenameOpenList) RenameAndMove(mediaFile *models.ScrapeMediaFile, destPa
 			return err
 		} else {
 			helpers.AppLogger.Infof("文件 %s 重命名成功：%s", oldPath+"/"+mediaFile.VideoFilename, newName)
+			r.journalFile(mediaFile, models.ScrapeJournalActionRename, oldPath, mediaFile.VideoFilename, oldPath, newName)
 		}
 	}
 	// 先改名，后移动或复制
@@ -77,6 +79,7 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 			return err
 		} else {
 			helpers.AppLogger.Infof("OpenList 文件 %s 成功从 %s 移动到新文件夹 %s", newName, oldPath, newPathId)
+			r.journalFile(mediaFile, models.ScrapeJournalActionMove, oldPath, newName, newPathId, newName)
 		}
 	}
 	// 查询一下详情
@@ -123,6 +126,10 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 		err := r.client.Move(oldPath, newPathId, files)
 		if err != nil {
 			helpers.AppLogger.Errorf("OpenList移动字幕文件失败: %v", err)
+		} else {
+			for _, file := range files {
+				r.journalFile(mediaFile, models.ScrapeJournalActionMove, oldPath, file, newPathId, file)
+			}
 		}
 		// 改名
 		for idx, sub := range mediaFile.SubtitleFiles {
@@ -135,6 +142,7 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 					helpers.AppLogger.Errorf("OpenList改名字幕文件失败: %v", err)
 				} else {
 					helpers.AppLogger.Infof("字幕文件 %s 重命名成功：%s", newPathId+"/"+sub.FileName, newSubName)
+					r.journalFile(mediaFile, models.ScrapeJournalActionRename, newPathId, sub.FileName, newPathId, newSubName)
 					mediaFile.Media.SubtitleFiles[idx].FileName = newSubName
 					mediaFile.Media.SubtitleFiles[idx].FileId = filepath.Join(newPathId, newSubName)
 					mediaFile.Media.SubtitleFiles[idx].PickCode = filepath.Join(newPathId, newSubName)
@@ -163,6 +171,9 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 				helpers.AppLogger.Errorf("OpenList移动图片文件失败: %v", err)
 				return err
 			}
+			for _, file := range files {
+				r.journalFile(mediaFile, models.ScrapeJournalActionMove, oldPath, file, newPathId, file)
+			}
 			// 改名
 			for _, imageFile := range mediaFile.ImageFiles {
 				// 改名
@@ -175,6 +186,7 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 						return err
 					} else {
 						helpers.AppLogger.Infof("图片文件 %s 重命名成功：%s", newPathId+"/"+imageFile.FileName, newImageName)
+						r.journalFile(mediaFile, models.ScrapeJournalActionRename, newPathId, imageFile.FileName, newPathId, newImageName)
 					}
 				}
 			}
@@ -186,6 +198,8 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 			err := r.client.Move(oldPath, newPathId, []string{mediaFile.NfoFileName})
 			if err != nil {
 				helpers.AppLogger.Errorf("OpenList移动nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
+			} else {
+				r.journalFile(mediaFile, models.ScrapeJournalActionMove, oldPath, mediaFile.NfoFileName, newPathId, mediaFile.NfoFileName)
 			}
 			// 检查是否需要改名
 			if newNfoName != mediaFile.NfoFileName {
@@ -195,6 +209,7 @@ func (r *RenameOpenList) move(mediaFile *models.ScrapeMediaFile, newName, newPat
 					helpers.AppLogger.Errorf("OpenList改名nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
 				} else {
 					helpers.AppLogger.Infof("nfo文件 %s 成功重命名为 %s", mediaFile.NfoFileName, newNfoName)
+					r.journalFile(mediaFile, models.ScrapeJournalActionRename, newPathId, mediaFile.NfoFileName, newPathId, newNfoName)
 				}
 			}
 		}
@@ -209,6 +224,7 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 		return err
 	} else {
 		helpers.AppLogger.Infof("Openlist 文件 %s 成功复制到 %s", oldPath+"/"+newName, newPathId+"/"+newName)
+		r.journalFile(mediaFile, models.ScrapeJournalActionCopy, oldPath, newName, newPathId, newName)
 	}
 	destFullPath := filepath.ToSlash(filepath.Join(newPathId, newName))
 	// 查询一下详情
@@ -255,6 +271,10 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 		err := r.client.Copy(oldPath, newPathId, files)
 		if err != nil {
 			helpers.AppLogger.Errorf("OpenList复制字幕文件失败: %v", err)
+		} else {
+			for _, file := range files {
+				r.journalFile(mediaFile, models.ScrapeJournalActionCopy, oldPath, file, newPathId, file)
+			}
 		}
 		// 改名
 		for _, sub := range mediaFile.SubtitleFiles {
@@ -267,6 +287,7 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 					helpers.AppLogger.Errorf("OpenList改名字幕文件失败: %v", err)
 				} else {
 					helpers.AppLogger.Infof("字幕文件 %s 重命名成功：%s", newPathId+"/"+sub.FileName, newSubName)
+					r.journalFile(mediaFile, models.ScrapeJournalActionRename, newPathId, sub.FileName, newPathId, newSubName)
 				}
 			}
 		}
@@ -291,6 +312,9 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 				helpers.AppLogger.Errorf("OpenList复制图片文件失败: %v", err)
 				return err
 			}
+			for _, file := range files {
+				r.journalFile(mediaFile, models.ScrapeJournalActionCopy, oldPath, file, newPathId, file)
+			}
 			// 改名
 			for _, imageFile := range mediaFile.ImageFiles {
 				// 改名
@@ -303,6 +327,7 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 						return err
 					} else {
 						helpers.AppLogger.Infof("图片文件 %s 重命名成功：%s", newPathId+"/"+imageFile.FileName, newImageName)
+						r.journalFile(mediaFile, models.ScrapeJournalActionRename, newPathId, imageFile.FileName, newPathId, newImageName)
 					}
 				}
 			}
@@ -314,6 +339,8 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 			err := r.client.Copy(oldPath, newPathId, []string{mediaFile.NfoFileName})
 			if err != nil {
 				helpers.AppLogger.Errorf("OpenList复制nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
+			} else {
+				r.journalFile(mediaFile, models.ScrapeJournalActionCopy, oldPath, mediaFile.NfoFileName, newPathId, mediaFile.NfoFileName)
 			}
 			// 检查是否需要改名
 			if newNfoName != mediaFile.NfoFileName {
@@ -323,6 +350,7 @@ func (r *RenameOpenList) copy(mediaFile *models.ScrapeMediaFile, newName, newPat
 					helpers.AppLogger.Errorf("OpenList改名nfo文件 %s 失败: %v", mediaFile.NfoFileName, err)
 				} else {
 					helpers.AppLogger.Infof("nfo文件 %s 成功重命名为 %s", mediaFile.NfoFileName, newNfoName)
+					r.journalFile(mediaFile, models.ScrapeJournalActionRename, newPathId, mediaFile.NfoFileName, newPathId, newNfoName)
 				}
 			}
 		}
@@ -482,3 +510,49 @@ func (r *RenameOpenList) ExistsAndRename(fileId, newName string) (string, error)
 	helpers.AppLogger.Infof("重命名OpenList文件成功, %s => %s", fileId, newName)
 	return filepath.Join(filepath.Dir(fileId), newName), nil
 }
+
+// 记录整理日志，OpenList的文件ID就是完整路径
+func (r *RenameOpenList) journalFile(mediaFile *models.ScrapeMediaFile, action models.ScrapeJournalAction, oldDir, oldName, newDir, newName string) {
+	newPath := filepath.ToSlash(filepath.Join(newDir, newName))
+	r.journal(mediaFile, action, newPath, filepath.ToSlash(filepath.Join(oldDir, oldName)), oldDir, newPath, newDir)
+}
+
+// 撤销整理日志中记录的操作
+func (r *RenameOpenList) Undo(journal *models.ScrapeJournal) error {
+	newDir := filepath.ToSlash(filepath.Dir(journal.NewPath))
+	newName := filepath.Base(journal.NewPath)
+	detail, _ := r.client.FileDetail(journal.NewPath)
+	if detail == nil || detail.Name == "" {
+		return fmt.Errorf("OpenList文件 %s 已不存在", journal.NewPath)
+	}
+	if journal.Action != models.ScrapeJournalActionCopy {
+		// 原位置已经有同名文件时不覆盖
+		existsDetail, _ := r.client.FileDetail(journal.OriginalPath)
+		if existsDetail != nil && existsDetail.Name != "" {
+			return fmt.Errorf("原位置 %s 已存在同名文件", journal.OriginalPath)
+		}
+	}
+	var err error
+	switch journal.Action {
+	case models.ScrapeJournalActionCopy:
+		// 复制出来的文件直接删除
+		err = r.client.Del(newDir, []string{newName})
+	case models.ScrapeJournalActionRename:
+		err = r.client.Rename(newDir, newName, filepath.Base(journal.OriginalPath))
+	case models.ScrapeJournalActionMove:
+		// 整理后来源目录可能已被删除，按原路径重新创建
+		parentPath := filepath.ToSlash(filepath.Dir(journal.OriginalPath))
+		if _, err = r.CheckAndMkDir(parentPath, r.scrapePath.SourcePath, r.scrapePath.SourcePathId); err != nil {
+			return err
+		}
+		err = r.client.Move(newDir, parentPath, []string{newName})
+	default:
+		return fmt.Errorf("不支持撤销的操作类型: %s", journal.Action)
+	}
+	if err != nil {
+		helpers.AppLogger.Errorf("撤销OpenList整理操作失败: %s => %s %v", journal.NewPath, journal.OriginalPath, err)
+		return err
+	}
+	helpers.AppLogger.Infof("撤销OpenList整理操作成功: %s => %s", journal.NewPath, journal.OriginalPath)
+	return nil
+}
@@ -0,0 +1,308 @@
+package rename
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/webdav"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// WebDAV的文件ID就是完整路径，移动、复制、改名都是按路径操作
+type RenameWebDav struct {
+	RenameBase
+	client *webdav.Client
+}
+
+func NewRenameWebDav(ctx context.Context, scrapePath *models.ScrapePath, client *webdav.Client) *RenameWebDav {
+	return &RenameWebDav{
+		RenameBase: RenameBase{
+			scrapePath: scrapePath,
+			ctx:        ctx,
+		},
+		client: client,
+	}
+}
+
+func (r *RenameWebDav) exists(p string) bool {
+	exists, _ := r.client.Exists(r.ctx, p)
+	return exists
+}
+
+func (r *RenameWebDav) RenameAndMove(mediaFile *models.ScrapeMediaFile, destPath, destPathId, newName string) error {
+	oldPath := mediaFile.PathId
+	if oldPath == "" && mediaFile.MediaType == models.MediaTypeTvShow {
+		oldPath = mediaFile.TvshowPathId
+	}
+	destName := filepath.ToSlash(filepath.Join(destPath, newName))
+	if r.exists(destName) {
+		helpers.AppLogger.Infof("文件 %s 已存在，无需移动", destName)
+		return nil
+	}
+	if mediaFile.VideoFilename != newName {
+		// 改名
+		err := r.client.Rename(r.ctx, filepath.ToSlash(filepath.Join(oldPath, mediaFile.VideoFilename)), newName)
+		if err != nil {
+			helpers.AppLogger.Errorf("WebDAV改名文件失败: %v", err)
+			return err
+		}
+		helpers.AppLogger.Infof("文件 %s 重命名成功：%s", oldPath+"/"+mediaFile.VideoFilename, newName)
+		r.journalFile(mediaFile, models.ScrapeJournalActionRename, oldPath, mediaFile.VideoFilename, oldPath, newName)
+	}
+	// 先改名，后移动或复制
+	switch mediaFile.RenameType {
+	case models.RenameTypeMove:
+		return r.transfer(mediaFile, models.ScrapeJournalActionMove, newName, destPathId, oldPath)
+	case models.RenameTypeCopy:
+		return r.transfer(mediaFile, models.ScrapeJournalActionCopy, newName, destPathId, oldPath)
+	}
+	return nil
+}
+
+// 移动或复制一个文件到新目录，并记录整理日志
+func (r *RenameWebDav) transferFile(mediaFile *models.ScrapeMediaFile, action models.ScrapeJournalAction, oldPath, newPathId, name string) error {
+	src := filepath.ToSlash(filepath.Join(oldPath, name))
+	dst := filepath.ToSlash(filepath.Join(newPathId, name))
+	var err error
+	if action == models.ScrapeJournalActionCopy {
+		err = r.client.Copy(r.ctx, src, dst, false)
+	} else {
+		err = r.client.Move(r.ctx, src, dst, false)
+	}
+	if err != nil {
+		helpers.AppLogger.Errorf("WebDAV%s文件 %s => %s 失败: %v", action, src, dst, err)
+		return err
+	}
+	r.journalFile(mediaFile, action, oldPath, name, newPathId, name)
+	return nil
+}
+
+// 在新目录中按新的视频文件名改名，并记录整理日志，返回改名后的文件名
+func (r *RenameWebDav) renameInDir(mediaFile *models.ScrapeMediaFile, dir, name, oldBaseName string) (string, error) {
+	newFileName := strings.Replace(name, oldBaseName, mediaFile.NewVideoBaseName, 1)
+	if newFileName == name {
+		return name, nil
+	}
+	err := r.client.Rename(r.ctx, filepath.ToSlash(filepath.Join(dir, name)), newFileName)
+	if err != nil {
+		helpers.AppLogger.Errorf("WebDAV改名文件 %s 失败: %v", name, err)
+		return name, err
+	}
+	helpers.AppLogger.Infof("文件 %s 重命名成功：%s", dir+"/"+name, newFileName)
+	r.journalFile(mediaFile, models.ScrapeJournalActionRename, dir, name, dir, newFileName)
+	return newFileName, nil
+}
+
+// 将视频和字幕移动或复制到新目录，仅整理的其他类型还要带上图片和nfo
+func (r *RenameWebDav) transfer(mediaFile *models.ScrapeMediaFile, action models.ScrapeJournalAction, newName, newPathId, oldPath string) error {
+	destFullPath := filepath.ToSlash(filepath.Join(newPathId, newName))
+	if r.exists(destFullPath) {
+		helpers.AppLogger.Infof("文件 %s 已存在，无需%s", destFullPath, action)
+	} else if err := r.transferFile(mediaFile, action, oldPath, newPathId, newName); err != nil {
+		return err
+	}
+	if mediaFile.MediaType != models.MediaTypeTvShow {
+		mediaFile.Media.VideoFileId = destFullPath
+		mediaFile.Media.VideoPickCode = destFullPath
+	} else {
+		mediaFile.MediaEpisode.VideoFileId = destFullPath
+		mediaFile.MediaEpisode.VideoPickCode = destFullPath
+	}
+	oldBaseName := strings.TrimSuffix(mediaFile.VideoFilename, mediaFile.VideoExt)
+	if mediaFile.SubtitleFileJson != "" {
+		subtitleFiles := make([]*models.MediaMetaFiles, 0, len(mediaFile.SubtitleFiles))
+		for _, sub := range mediaFile.SubtitleFiles {
+			if err := r.transferFile(mediaFile, action, oldPath, newPathId, sub.FileName); err != nil {
+				continue
+			}
+			subName, _ := r.renameInDir(mediaFile, newPathId, sub.FileName, oldBaseName)
+			subPath := filepath.ToSlash(filepath.Join(newPathId, subName))
+			subtitleFiles = append(subtitleFiles, &models.MediaMetaFiles{
+				FileName: subName,
+				FileId:   subPath,
+				PickCode: subPath,
+			})
+		}
+		if mediaFile.MediaType != models.MediaTypeTvShow {
+			mediaFile.Media.SubtitleFiles = subtitleFiles
+		} else {
+			mediaFile.MediaEpisode.SubtitleFiles = subtitleFiles
+		}
+	}
+	if mediaFile.MediaType != models.MediaTypeTvShow {
+		mediaFile.Media.Save()
+	} else {
+		mediaFile.MediaEpisode.Save()
+	}
+	if mediaFile.ScrapeType == models.ScrapeTypeOnlyRename && mediaFile.MediaType == models.MediaTypeOther {
+		// 其他类型仅整理要把图片和nfo也转移过去
+		for _, imageFile := range mediaFile.ImageFiles {
+			if err := r.transferFile(mediaFile, action, oldPath, newPathId, imageFile.FileName); err != nil {
+				return err
+			}
+			if _, err := r.renameInDir(mediaFile, newPathId, imageFile.FileName, oldBaseName); err != nil {
+				return err
+			}
+		}
+		if mediaFile.NfoFileId != "" {
+			if err := r.transferFile(mediaFile, action, oldPath, newPathId, mediaFile.NfoFileName); err == nil {
+				r.renameInDir(mediaFile, newPathId, mediaFile.NfoFileName, oldBaseName)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *RenameWebDav) CheckAndMkDir(destFullPath string, rootPath, rootPathId string) (string, error) {
+	destFullPath = filepath.ToSlash(destFullPath)
+	if err := r.client.MkdirAll(r.ctx, destFullPath); err != nil {
+		helpers.AppLogger.Errorf("创建文件夹失败: %s 错误：%v", destFullPath, err)
+		return destFullPath, err
+	}
+	return destFullPath, nil
+}
+
+// 检查目录下是否还有视频文件，没有视频或者强制删除时删除目录
+func (r *RenameWebDav) removeDirIfNoVideo(dir string, sp *models.ScrapePath) error {
+	if dir == sp.SourcePath || dir == sp.SourcePathId {
+		helpers.AppLogger.Infof("目录 %s 是来源根路径，不删除", dir)
+		return nil
+	}
+	list, err := r.client.List(r.ctx, dir)
+	if err != nil {
+		helpers.AppLogger.Errorf("获取WebDAV文件列表失败: %s %v", dir, err)
+		return err
+	}
+	if !sp.ForceDeleteSourcePath {
+		for _, file := range list {
+			if file.IsDir || sp.IsVideoFile(file.Name) {
+				helpers.AppLogger.Infof("目录 %s 下有其他视频文件或子目录，不删除", dir)
+				return nil
+			}
+		}
+	}
+	if err := r.client.Delete(r.ctx, dir); err != nil {
+		helpers.AppLogger.Errorf("删除WebDAV目录失败: %s %v", dir, err)
+		return err
+	}
+	helpers.AppLogger.Infof("刮削完成，删除WebDAV文件夹成功, 路径：%s", dir)
+	return nil
+}
+
+func (r *RenameWebDav) RemoveMediaSourcePath(mediaFile *models.ScrapeMediaFile, sp *models.ScrapePath) error {
+	sourcePath := mediaFile.PathId
+	if sourcePath == "" && mediaFile.MediaType == models.MediaTypeTvShow {
+		sourcePath = mediaFile.TvshowPathId
+	}
+	if err := r.removeDirIfNoVideo(sourcePath, sp); err != nil {
+		return err
+	}
+	// 再删除电视剧文件夹
+	if mediaFile.PathId != "" && mediaFile.TvshowPathId != "" && mediaFile.TvshowPathId != sourcePath {
+		return r.removeDirIfNoVideo(mediaFile.TvshowPathId, sp)
+	}
+	return nil
+}
+
+func (r *RenameWebDav) ReadFileContent(fileId string) ([]byte, error) {
+	content, err := r.client.ReadFile(r.ctx, fileId)
+	if err != nil {
+		helpers.AppLogger.Errorf("读取WebDAV文件内容失败: fileId=%s, %v", fileId, err)
+		return nil, err
+	}
+	return content, nil
+}
+
+func (r *RenameWebDav) CheckAndDeleteFiles(mediaFile *models.ScrapeMediaFile, files []models.WillDeleteFile) error {
+	for _, f := range files {
+		if !r.exists(f.FullFilePath) {
+			helpers.AppLogger.Infof("WebDAV文件不存在，无需删除: 路径：%s", f.FullFilePath)
+			continue
+		}
+		if err := r.client.Delete(r.ctx, f.FullFilePath); err != nil {
+			helpers.AppLogger.Errorf("删除WebDAV文件失败: 路径：%s %v", f.FullFilePath, err)
+			continue
+		}
+		helpers.AppLogger.Infof("删除WebDAV文件成功, 路径：%s", f.FullFilePath)
+	}
+	return nil
+}
+
+func (r *RenameWebDav) MoveFiles(f models.MoveNewFileToSourceFile) error {
+	newFileId := filepath.ToSlash(filepath.Join(f.PathId, filepath.Base(f.FileId)))
+	if r.exists(newFileId) {
+		helpers.AppLogger.Infof("WebDAV文件存在，无需移动: 路径：%s", newFileId)
+		return nil
+	}
+	if err := r.client.Move(r.ctx, f.FileId, newFileId, false); err != nil {
+		helpers.AppLogger.Errorf("移动WebDAV文件失败: %s => %s 错误：%v", f.FileId, newFileId, err)
+		return err
+	}
+	helpers.AppLogger.Infof("移动WebDAV文件成功: %s => %s", f.FileId, newFileId)
+	return nil
+}
+
+func (r *RenameWebDav) DeleteDir(path, pathId string) error {
+	return r.client.Delete(r.ctx, pathId)
+}
+
+func (r *RenameWebDav) Rename(fileId, newName string) error {
+	return r.client.Rename(r.ctx, fileId, newName)
+}
+
+// 检查是否存在，存在就改名字，然后返回新的fileId
+func (r *RenameWebDav) ExistsAndRename(fileId, newName string) (string, error) {
+	if !r.exists(fileId) {
+		helpers.AppLogger.Infof("WebDAV文件不存在，无需重命名: 文件ID：%s", fileId)
+		return "", nil
+	}
+	if filepath.Base(fileId) == newName {
+		helpers.AppLogger.Infof("WebDAV文件名字没变，无需重命名: 文件ID：%s", fileId)
+		return fileId, nil
+	}
+	if err := r.Rename(fileId, newName); err != nil {
+		helpers.AppLogger.Errorf("重命名WebDAV文件失败：%s => %s 错误：%v", fileId, newName, err)
+		return "", err
+	}
+	helpers.AppLogger.Infof("重命名WebDAV文件成功, %s => %s", fileId, newName)
+	return filepath.ToSlash(filepath.Join(filepath.Dir(fileId), newName)), nil
+}
+
+// 记录整理日志，WebDAV的文件ID就是完整路径
+func (r *RenameWebDav) journalFile(mediaFile *models.ScrapeMediaFile, action models.ScrapeJournalAction, oldDir, oldName, newDir, newName string) {
+	newPath := filepath.ToSlash(filepath.Join(newDir, newName))
+	r.journal(mediaFile, action, newPath, filepath.ToSlash(filepath.Join(oldDir, oldName)), oldDir, newPath, newDir)
+}
+
+// 撤销整理日志中记录的操作
+func (r *RenameWebDav) Undo(journal *models.ScrapeJournal) error {
+	if !r.exists(journal.NewPath) {
+		return fmt.Errorf("WebDAV文件 %s 已不存在", journal.NewPath)
+	}
+	if journal.Action != models.ScrapeJournalActionCopy && r.exists(journal.OriginalPath) {
+		// 原位置已经有同名文件时不覆盖
+		return fmt.Errorf("原位置 %s 已存在同名文件", journal.OriginalPath)
+	}
+	var err error
+	switch journal.Action {
+	case models.ScrapeJournalActionCopy:
+		// 复制出来的文件直接删除
+		err = r.client.Delete(r.ctx, journal.NewPath)
+	case models.ScrapeJournalActionRename, models.ScrapeJournalActionMove:
+		// 整理后来源目录可能已被删除，MOVE前先按原路径重新创建
+		if _, err = r.CheckAndMkDir(filepath.Dir(journal.OriginalPath), r.scrapePath.SourcePath, r.scrapePath.SourcePathId); err != nil {
+			return err
+		}
+		err = r.client.Move(r.ctx, journal.NewPath, journal.OriginalPath, false)
+	default:
+		return fmt.Errorf("不支持撤销的操作类型: %s", journal.Action)
+	}
+	if err != nil {
+		helpers.AppLogger.Errorf("撤销WebDAV整理操作失败: %s => %s %v", journal.NewPath, journal.OriginalPath, err)
+		return err
+	}
+	helpers.AppLogger.Infof("撤销WebDAV整理操作成功: %s => %s", journal.NewPath, journal.OriginalPath)
+	return nil
+}
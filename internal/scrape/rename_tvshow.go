@@ -7,6 +7,7 @@ import (
 	"Q115-STRM/internal/openlist"
 	"Q115-STRM/internal/scrape/rename"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"errors"
 	"fmt"
@@ -19,7 +20,7 @@ type renameTvShowImpl struct {
 	renameImpl renameImpl
 }
 
-func NewRenameTvShowImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client, baiduPanClient *baidupan.Client) renameImpl {
+func NewRenameTvShowImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client, baiduPanClient *baidupan.Client, webDavClient *webdav.Client) renameImpl {
 	var ri renameImpl
 	switch scrapePath.SourceType {
 	case models.SourceType115:
@@ -28,6 +29,8 @@ func NewRenameTvShowImpl(scrapePath *models.ScrapePath, ctx context.Context, v11
 		ri = rename.NewRenameOpenList(ctx, scrapePath, openlistClient)
 	case models.SourceTypeBaiduPan:
 		ri = rename.NewRenameBaiduPan(ctx, scrapePath, baiduPanClient)
+	case models.SourceTypeWebDav:
+		ri = rename.NewRenameWebDav(ctx, scrapePath, webDavClient)
 	default:
 		ri = rename.NewRenameLocal(ctx, scrapePath)
 	}
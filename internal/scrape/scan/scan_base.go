@@ -224,9 +224,9 @@ videoloop:
 			jsonStr, _ := json.Marshal(imageList)
 			mediaFile.ImageFilesJson = string(jsonStr)
 		}
-		// 如果时电视剧，加入批次号
+		// 加入批次号，整理日志按批次撤销
+		mediaFile.BatchNo = s.BatchNo
 		if s.scrapePath.MediaType == models.MediaTypeTvShow {
-			mediaFile.BatchNo = s.BatchNo
 			// 识别季和集序号
 			// 提取季和集
 			// 填充电视剧和季目录（如果有季的话）
@@ -0,0 +1,161 @@
+package scan
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/webdav"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// 从WebDAV扫描需要刮削的文件入库
+type ScanWebDavImpl struct {
+	scanBaseImpl
+	client *webdav.Client
+}
+
+func NewWebDavScanImpl(scrapePath *models.ScrapePath, client *webdav.Client, ctx context.Context) *ScanWebDavImpl {
+	return &ScanWebDavImpl{scanBaseImpl: scanBaseImpl{ctx: ctx, scrapePath: scrapePath}, client: client}
+}
+
+// 检查来源目录和目标目录是否存在
+func (s *ScanWebDavImpl) CheckPathExists() error {
+	_, err := s.client.Stat(s.ctx, s.scrapePath.SourcePathId)
+	if err != nil {
+		ferr := fmt.Errorf("刮削来源目录 %s 疑似不存在，请检查或编辑重新选择来源目录: %v", s.scrapePath.SourcePathId, err)
+		return ferr
+	}
+	if s.scrapePath.ScrapeType != models.ScrapeTypeOnly {
+		_, err = s.client.Stat(s.ctx, s.scrapePath.DestPathId)
+		if err != nil {
+			ferr := fmt.Errorf("刮削目标目录 %s 疑似不存在，请检查或编辑重新选择目标目录: %v", s.scrapePath.DestPathId, err)
+			return ferr
+		}
+	}
+	return nil
+}
+
+// 递归扫描WebDAV目录下的所有文件
+func (s *ScanWebDavImpl) GetNetFileFiles() error {
+	// 批次号
+	s.BatchNo = time.Now().Format("20060102150405000")
+	// 检查是否停止任务
+	if !s.CheckIsRunning() {
+		return errors.New("任务已停止")
+	}
+	// 检查源目录是否存在
+	_, err := s.client.Stat(s.ctx, s.scrapePath.SourcePathId)
+	if err != nil {
+		return fmt.Errorf("源目录 %s 不存在或者其他错误：%v", s.scrapePath.SourcePathId, err)
+	}
+	// 初始化路径队列，容量为接口线程数
+	s.pathTasks = make(chan string, models.SettingsGlobal.FileDetailThreads)
+	// 启动一个控制buffer的context
+	bufferCtx, cancelBuffer := context.WithCancel(context.Background())
+	// 启动buffer to task
+	go s.bufferMonitor(bufferCtx)
+	// 加入根目录
+	s.wg = sync.WaitGroup{}
+	s.addPathToTasks(s.scrapePath.SourcePathId)
+	helpers.AppLogger.Infof("开始处理目录 %s, 开启 %d 个任务", s.scrapePath.SourcePath, models.SettingsGlobal.FileDetailThreads)
+	for i := 0; i < models.SettingsGlobal.FileDetailThreads; i++ {
+		go s.startPathWork(i)
+	}
+	go func() {
+		<-s.ctx.Done()
+		for range s.pathTasks {
+			s.wg.Done()
+		}
+	}()
+	s.wg.Wait()        // 等待最后一个目录处理完
+	close(s.pathTasks) // 关闭pathTasks，释放资源
+	cancelBuffer()     // 让bufferMonitor退出
+	return nil
+}
+
+// WebDAV一次返回整个目录，不需要分页
+func (s *ScanWebDavImpl) startPathWork(workerID int) {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case pathId, ok := <-s.pathTasks:
+			if !ok {
+				return
+			}
+			if !s.CheckIsRunning() {
+				s.wg.Done()
+				return
+			}
+			helpers.AppLogger.Infof("worker %d 开始处理目录 %s", workerID, pathId)
+			fsList, err := s.client.List(s.ctx, pathId)
+			if err != nil {
+				if errors.Is(err, context.Canceled) {
+					helpers.AppLogger.Infof("worker %d 处理目录 %s 失败 上下文已取消", workerID, pathId)
+					s.wg.Done()
+					return
+				}
+				// 跳过这个目录，下次刮削时会重新扫描
+				helpers.AppLogger.Errorf("worker %d 处理目录 %s 失败: %v", workerID, pathId, err)
+				s.wg.Done()
+				continue
+			}
+			picFiles := make([]*localFile, 0)
+			nfoFiles := make([]*localFile, 0)
+			subFiles := make([]*localFile, 0)
+			videoFiles := make([]*localFile, 0)
+			for _, file := range fsList {
+				if !s.CheckIsRunning() {
+					s.wg.Done()
+					return
+				}
+				if file.IsDir {
+					// 是目录，加入队列
+					s.addPathToTasks(file.Path)
+					continue
+				}
+				// 检查文件是否允许处理
+				if !s.scrapePath.CheckFileIsAllowed(file.Name, file.Size) {
+					continue
+				}
+				// 检查文件是否已处理，如果已在数据库中，则直接跳过
+				if models.CheckExistsFileIdAndName(file.Path, s.scrapePath.ID) {
+					helpers.AppLogger.Infof("文件 %s 已在数据库中，跳过", file.Path)
+					continue
+				}
+				item := &localFile{
+					Id:       file.Path,
+					PickCode: file.Path,
+					Name:     file.Name,
+					Size:     file.Size,
+					Path:     file.Path,
+				}
+				ext := filepath.Ext(file.Name)
+				switch {
+				case slices.Contains(models.SubtitleExtArr, ext):
+					subFiles = append(subFiles, item)
+				case slices.Contains(models.ImageExtArr, ext):
+					picFiles = append(picFiles, item)
+				case ext == ".nfo":
+					nfoFiles = append(nfoFiles, item)
+				case s.scrapePath.IsVideoFile(file.Name):
+					videoFiles = append(videoFiles, item)
+				}
+			}
+			verr := s.processVideoFile(pathId, pathId, videoFiles, picFiles, nfoFiles, subFiles)
+			if verr != nil {
+				s.wg.Done()
+				return
+			}
+			// 任务完成，通知WaitGroup
+			s.wg.Done()
+		default:
+			time.Sleep(1 * time.Second)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"Q115-STRM/internal/openlist"
 	"Q115-STRM/internal/scrape/scan"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"fmt"
 	"os"
@@ -73,6 +74,7 @@ type Scrape struct {
 	V115Client     *v115open.OpenClient
 	OpenlistClient *openlist.Client
 	BaiduPanClient *baidupan.Client
+	WebDavClient   *webdav.Client
 }
 
 // scrapePath 要刮削的目录
@@ -102,6 +104,8 @@ func (s *Scrape) initOpenClient() error {
 		s.OpenlistClient = account.GetOpenListClient()
 	case models.SourceTypeBaiduPan:
 		s.BaiduPanClient = account.GetBaiDuPanClient()
+	case models.SourceTypeWebDav:
+		s.WebDavClient = account.GetWebDavClient()
 	}
 	return nil
 }
@@ -138,12 +142,14 @@ func (s *Scrape) init() {
 		s.scanImpl = scan.NewOpenlistScanImpl(s.scrapePath, s.OpenlistClient, s.ctx)
 	case models.SourceTypeBaiduPan:
 		s.scanImpl = scan.NewBaiduPanScanImpl(s.scrapePath, s.BaiduPanClient, s.ctx)
+	case models.SourceTypeWebDav:
+		s.scanImpl = scan.NewWebDavScanImpl(s.scrapePath, s.WebDavClient, s.ctx)
 	}
 	// 确定扫描接口，识别接口，刮削接口，重命名接口
 	if s.scrapePath.MediaType == models.MediaTypeTvShow {
-		s.scrapeImpl = NewTvShowScrapeImpl(s.scrapePath, s.ctx, s.V115Client, s.OpenlistClient, s.BaiduPanClient)
+		s.scrapeImpl = NewTvShowScrapeImpl(s.scrapePath, s.ctx, s.V115Client, s.OpenlistClient, s.BaiduPanClient, s.WebDavClient)
 	} else {
-		s.scrapeImpl = NewMovieScrapeImpl(s.scrapePath, s.ctx, s.V115Client, s.OpenlistClient, s.BaiduPanClient)
+		s.scrapeImpl = NewMovieScrapeImpl(s.scrapePath, s.ctx, s.V115Client, s.OpenlistClient, s.BaiduPanClient, s.WebDavClient)
 	}
 }
 
@@ -174,6 +180,7 @@ func (s *Scrape) Start() bool {
 	s.scrapePath.V115Client = s.V115Client
 	s.scrapePath.OpenListClient = s.OpenlistClient
 	s.scrapePath.BaiduPanClient = s.BaiduPanClient
+	s.scrapePath.WebDavClient = s.WebDavClient
 	s.scrapePath.GenerateCategory()
 	// 获取视频文件列表并从文件名中提取媒体信息用来刮削
 	eerr := s.scanImpl.GetNetFileFiles()
@@ -5,8 +5,8 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/openlist"
-	"Q115-STRM/internal/tmdb"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"fmt"
 	"net/url"
@@ -28,10 +28,11 @@ type ScrapeBase struct {
 	identifyImpl   IdentifyImpl
 	categoryImpl   categoryImpl
 	renameImpl     renameImpl
-	tmdbClient     *tmdb.Client
+	providers      map[string]MetadataProvider
 	v115Client     *v115open.OpenClient
 	openlistClient *openlist.Client
 	baiduPanClient *baidupan.Client
+	webDavClient   *webdav.Client
 }
 
 // 下载图片到指定文件
@@ -113,6 +114,7 @@ func (sm *ScrapeBase) GetFFprobeInfoFromFileOrUrl(mediaFile *models.ScrapeMediaF
 			mediaFile.VideoCodec.AspectRatio = helpers.CalculateAspectRatio(stream.Width, stream.Height)
 			mediaFile.VideoCodec.Aspect = stream.DisplayAspectRatio
 			mediaFile.VideoCodec.PixelFormat = stream.PixelFormat
+			mediaFile.VideoCodec.HdrType = helpers.DetectHDRType(&stream)
 			bitrate, err := helpers.CalculateBitrate(&stream, &ffprobeJson.Format)
 			if err == nil {
 				mediaFile.VideoCodec.Bitrate = bitrate
@@ -176,6 +178,8 @@ func (s *ScrapeBase) GetDownloadUrl(mediaFile *models.ScrapeMediaFile) string {
 		videoPathOrUrl = s.v115Client.GetDownloadUrl(context.Background(), mediaFile.VideoPickCode, v115open.DEFAULTUA, false)
 	case models.SourceTypeOpenList:
 		videoPathOrUrl = s.openlistClient.GetRawUrl(mediaFile.VideoPickCode)
+	case models.SourceTypeWebDav:
+		videoPathOrUrl = s.webDavClient.GetRawUrl(mediaFile.VideoPickCode)
 	case models.SourceType123:
 	}
 	return videoPathOrUrl
@@ -53,19 +53,10 @@ mainloop:
 
 func (t *tvShowScrapeImpl) ScrapeEpisodeMedia(mediaFile *models.ScrapeMediaFile) error {
 	// 查询集详情
-	episodeDetail, err := t.tmdbClient.GetTvEpisodeDetail(mediaFile.TmdbId, mediaFile.SeasonNumber, mediaFile.EpisodeNumber, models.GlobalScrapeSettings.GetTmdbLanguage())
+	episodeDetail, err := t.getProvider(mediaFile).GetEpisodeDetail(mediaFile.TmdbId, mediaFile.SeasonNumber, mediaFile.EpisodeNumber)
 	if err != nil {
-		helpers.AppLogger.Errorf("查询tmdb电视剧集详情失败,下次重试, 失败原因: %v", err)
 		return err
 	}
-	// 查询集演员
-	credits, err := t.tmdbClient.GetTvEpisodeCredits(mediaFile.TmdbId, mediaFile.SeasonNumber, mediaFile.EpisodeNumber, models.GlobalScrapeSettings.GetTmdbLanguage())
-	if err != nil {
-		helpers.AppLogger.Errorf("查询tmdb电视剧集演员失败,下次重试, 失败原因: %v", err)
-	} else {
-		episodeDetail.Cast = credits.Cast
-		episodeDetail.Crew = credits.Crew
-	}
 	t.MakeMediaEpisodeFromTMDB(mediaFile, episodeDetail)
 	return nil
 }
@@ -98,6 +89,8 @@ func (t *tvShowScrapeImpl) Scrape(mediaFile *models.ScrapeMediaFile) error {
 		episodeImageList := make(map[string]string)
 		episodeImageList[mediaFile.GetEpisodePosterName()] = mediaFile.MediaEpisode.PosterPath
 		t.DownloadImages(episodePath, v115open.DEFAULTUA, episodeImageList)
+		// 下载缺少的字幕
+		t.DownloadSubtitle(mediaFile, episodePath)
 		helpers.AppLogger.Infof("电视剧 %s 季 %d 集 %d 生成nfo和下载图片成功，路径：%s", mediaFile.Name, mediaFile.SeasonNumber, mediaFile.EpisodeNumber, episodePath)
 	}
 	mediaFile.ScrapeFinish()
@@ -348,6 +341,16 @@ func (t *tvShowScrapeImpl) GetEpisodeUploadFiles(mediaFile *models.ScrapeMediaFi
 		SourcePath: filepath.Join(sourcePath, jpgName),
 	}
 	fileList = append(fileList, file)
+	// 下载的字幕
+	for _, subName := range subtitleFilesInDir(sourcePath, mediaFile.NewVideoBaseName) {
+		fileList = append(fileList, uploadFile{
+			ID:         fmt.Sprintf("%d", mediaFile.ID),
+			DestPathId: destPathId,
+			DestPath:   destPath,
+			FileName:   subName,
+			SourcePath: filepath.Join(sourcePath, subName),
+		})
+	}
 	return fileList
 }
 
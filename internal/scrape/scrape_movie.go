@@ -8,8 +8,8 @@ import (
 	"Q115-STRM/internal/notificationmanager"
 	"Q115-STRM/internal/openlist"
 	"Q115-STRM/internal/syncstrm"
-	"Q115-STRM/internal/tmdb"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"errors"
 	"fmt"
@@ -22,21 +22,25 @@ import (
 
 type movieScrapeImpl struct {
 	ScrapeBase
+	versionMutex sync.Mutex // 确定电影版本时使用
 }
 
-func NewMovieScrapeImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client, baiduPanClient *baidupan.Client) scrapeImpl {
-	tmdbImpl := NewTmdbMovieImpl(scrapePath, ctx)
+func NewMovieScrapeImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client, baiduPanClient *baidupan.Client, webDavClient *webdav.Client) scrapeImpl {
+	providers := newMetadataProviders(scrapePath, ctx)
 	return &movieScrapeImpl{
 		ScrapeBase: ScrapeBase{
-			scrapePath:     scrapePath,
-			ctx:            ctx,
-			identifyImpl:   NewIdMovieImpl(scrapePath, ctx, tmdbImpl),
-			tmdbClient:     tmdbImpl.Client,
+			scrapePath: scrapePath,
+			ctx:        ctx,
+			identifyImpl: newProviderIdentifyImpl(scrapePath, providers, func(p MetadataProvider) IdentifyImpl {
+				return NewIdMovieImpl(scrapePath, ctx, p.MovieImpl())
+			}),
+			providers:      providers,
 			categoryImpl:   NewCategoryMovieImpl(scrapePath),
-			renameImpl:     NewRenameMovieImpl(scrapePath, ctx, v115Client, openlistClient, baiduPanClient),
+			renameImpl:     NewRenameMovieImpl(scrapePath, ctx, v115Client, openlistClient, baiduPanClient, webDavClient),
 			v115Client:     v115Client,
 			openlistClient: openlistClient,
 			baiduPanClient: baiduPanClient,
+			webDavClient:   webDavClient,
 		},
 	}
 }
@@ -53,6 +57,8 @@ func (m *movieScrapeImpl) Start() error {
 		helpers.AppLogger.Infof("没有待刮削和待整理的记录，无需启动刮削任务")
 		return nil
 	}
+	// 开启批量AI识别时，先按文件夹批量识别所有待识别的文件
+	m.AiBatchIdentify()
 	threads := min(max, int(total))
 	for i := 0; i < threads; i++ {
 		go m.scrapeWorker(i+1, wg)
@@ -123,6 +129,8 @@ func (m *movieScrapeImpl) Process(mediaFile *models.ScrapeMediaFile) error {
 			return err
 		}
 	}
+	// 确定版本后可能又出现了更好的版本，整理前再检查一次
+	m.SettleMovieVersion(mediaFile, false)
 	// 改为整理中
 	mediaFile.Renaming()
 	m.MakeParentPath(mediaFile, m.scrapePath.CategoryMap)
@@ -149,6 +157,10 @@ func (m *movieScrapeImpl) Process(mediaFile *models.ScrapeMediaFile) error {
 	}
 	// 将自己标记为完成，状态立即完成，网盘的临时文件等网盘上传完成删除
 	m.FinishMovie(mediaFile)
+	// 整理期间出现了更好的版本时，把已经整理好的当前版本移动到duplicates文件夹
+	if err := m.SettleMovieVersion(mediaFile, true); err != nil {
+		helpers.AppLogger.Errorf("电影 %s 的版本 %s 移动到 %s 文件夹失败: %v", mediaFile.Name, mediaFile.VersionLabel, models.DuplicateVersionFolder, err)
+	}
 	return nil
 }
 
@@ -171,6 +183,8 @@ func (m *movieScrapeImpl) Scrape(mediaFile *models.ScrapeMediaFile) error {
 		return cerr
 	}
 	m.GenerateNewName(mediaFile)
+	// 多版本时其他版本已经生成了nfo和图片，或者当前版本被淘汰，不再生成
+	sharedMetadata := m.ApplyMovieVersion(mediaFile)
 	if mediaFile.ScrapeType != models.ScrapeTypeOnlyRename {
 		// 下载图片，生成nfo文件
 		// 生成本地临时路径
@@ -182,71 +196,48 @@ func (m *movieScrapeImpl) Scrape(mediaFile *models.ScrapeMediaFile) error {
 		} else {
 			helpers.AppLogger.Infof("临时目录 %s 创建成功", localTempPath)
 		}
-		nfoName := m.GetMovieRealName(mediaFile, "", "nfo")
-		// 生成nfo
-		m.GenerateMovieNfo(mediaFile, localTempPath, nfoName, m.scrapePath.ExcludeNoImageActor)
-		fileList := map[string]string{}
-		posterExt := filepath.Ext(mediaFile.Media.PosterPath)
-		fileList[m.GetMovieRealName(mediaFile, fmt.Sprintf("poster%s", posterExt), "image")] = mediaFile.Media.PosterPath
-		logoExt := filepath.Ext(mediaFile.Media.LogoPath)
-		fileList[m.GetMovieRealName(mediaFile, fmt.Sprintf("clearlogo%s", logoExt), "image")] = mediaFile.Media.LogoPath
-		fanartExt := filepath.Ext(mediaFile.Media.BackdropPath)
-		fileList[m.GetMovieRealName(mediaFile, fmt.Sprintf("fanart%s", fanartExt), "image")] = mediaFile.Media.BackdropPath
-		m.DownloadImages(localTempPath, v115open.DEFAULTUA, fileList)
-		// 从fanart.tv查询图片并下载
-		if m.scrapePath.EnableFanartTv {
-			fileList = m.DownloadMovieImagesFromFanart(mediaFile)
-			if fileList != nil {
-				m.DownloadImages(localTempPath, v115open.DEFAULTUA, fileList)
-			}
+		if !sharedMetadata {
+			m.GenerateMovieMetadata(mediaFile, localTempPath)
 		}
+		// 下载缺少的字幕
+		m.DownloadSubtitle(mediaFile, localTempPath)
 	}
 	mediaFile.ScrapeFinish()
 	return nil
 }
 
-// 从tmdb刮削元数据和图片信息（不下载，不创建目录）
+// 生成nfo，下载海报、背景等图片
+func (m *movieScrapeImpl) GenerateMovieMetadata(mediaFile *models.ScrapeMediaFile, localTempPath string) {
+	nfoName := m.GetMovieRealName(mediaFile, "", "nfo")
+	// 生成nfo
+	m.GenerateMovieNfo(mediaFile, localTempPath, nfoName, m.scrapePath.ExcludeNoImageActor)
+	fileList := map[string]string{}
+	posterExt := filepath.Ext(mediaFile.Media.PosterPath)
+	fileList[m.GetMovieRealName(mediaFile, fmt.Sprintf("poster%s", posterExt), "image")] = mediaFile.Media.PosterPath
+	logoExt := filepath.Ext(mediaFile.Media.LogoPath)
+	fileList[m.GetMovieRealName(mediaFile, fmt.Sprintf("clearlogo%s", logoExt), "image")] = mediaFile.Media.LogoPath
+	fanartExt := filepath.Ext(mediaFile.Media.BackdropPath)
+	fileList[m.GetMovieRealName(mediaFile, fmt.Sprintf("fanart%s", fanartExt), "image")] = mediaFile.Media.BackdropPath
+	m.DownloadImages(localTempPath, v115open.DEFAULTUA, fileList)
+	// 从fanart.tv查询图片并下载，fanart.tv只支持TMDB ID
+	if m.scrapePath.EnableFanartTv && mediaFile.GetMetadataProvider() == models.MetadataProviderTmdb {
+		fileList = m.DownloadMovieImagesFromFanart(mediaFile)
+		if fileList != nil {
+			m.DownloadImages(localTempPath, v115open.DEFAULTUA, fileList)
+		}
+	}
+}
+
+// 从元数据提供者刮削元数据和图片信息（不下载，不创建目录）
 func (m *movieScrapeImpl) ScrapeMovieMedia(mediaFile *models.ScrapeMediaFile) error {
 	// 如果是其他类型，需要读取nfo文件
 	if mediaFile.MediaType == models.MediaTypeOther {
 		return m.CreateMediaFromNfo(mediaFile)
 	}
-	tmdbInfo := &models.TmdbInfo{}
-	// 查询详情
-	movieDetail, err := m.tmdbClient.GetMovieDetail(mediaFile.TmdbId, models.GlobalScrapeSettings.GetTmdbLanguage())
+	tmdbInfo, err := m.getProvider(mediaFile).GetMovieInfo(mediaFile.TmdbId, mediaFile.ScrapeType == models.ScrapeTypeOnlyRename)
 	if err != nil {
-		helpers.AppLogger.Errorf("查询tmdb电影详情失败, 下次重试, 失败原因: %v", err)
 		return err
 	}
-	tmdbInfo.MovieDetail = movieDetail
-	if mediaFile.ScrapeType != models.ScrapeTypeOnlyRename {
-		// 查询演职人员
-		cast, _ := m.tmdbClient.GetMoviePepoles(mediaFile.TmdbId, models.GlobalScrapeSettings.GetTmdbLanguage())
-		tmdbInfo.Credits = cast
-		// 查询图片
-		images, _ := m.tmdbClient.GetMovieImages(mediaFile.TmdbId, models.GlobalScrapeSettings.GetTmdbImageLanguage())
-		if images != nil {
-			helpers.AppLogger.Infof("查询tmdb电影图片成功, tmdbId: %d, 语言: %s", mediaFile.TmdbId, models.GlobalScrapeSettings.GetTmdbImageLanguage())
-			// 如果图片为空,则使用详情中的图片
-			if len(images.Posters) == 0 && movieDetail.PosterPath != "" {
-				images.Posters = append(images.Posters, tmdb.Image{
-					FilePath: movieDetail.PosterPath,
-				})
-			}
-			if len(images.Backdrops) == 0 && movieDetail.BackdropPath != "" {
-				images.Backdrops = append(images.Backdrops, tmdb.Image{
-					FilePath: movieDetail.BackdropPath,
-				})
-			}
-		}
-		tmdbInfo.Images = images
-		// 查询分级信息
-		releasesDate, err := m.tmdbClient.GetReleasesDate(mediaFile.TmdbId)
-		if err != nil {
-			helpers.AppLogger.Errorf("查询tmdb电影分级信息失败, 下次重试, 失败原因: %v", err)
-		}
-		tmdbInfo.ReleasesDate = releasesDate.Results
-	}
 	m.MakeMediaFromTMDB(mediaFile, tmdbInfo)
 	return nil
 }
@@ -289,7 +280,7 @@ func (m *movieScrapeImpl) GenerateNewName(mediaFile *models.ScrapeMediaFile) {
 	}
 	folderTemplate := m.scrapePath.FolderNameTemplate
 	if m.scrapePath.FolderNameTemplate == "" && remotePath == "" {
-		folderTemplate = "{title} ({year})"
+		folderTemplate = models.DefaultFolderNameTemplate
 	}
 	// 根据命名规则生成文件夹名称
 	if m.scrapePath.FolderNameTemplate == "" {
@@ -315,6 +306,10 @@ func (m *movieScrapeImpl) GenerateNewName(mediaFile *models.ScrapeMediaFile) {
 
 // 先命中一个syncPath，使用newPath
 func (m *movieScrapeImpl) SyncFilesToSTRMPath(mediaFile *models.ScrapeMediaFile, files []uploadFile) {
+	if mediaFile.IsDuplicateVersion {
+		helpers.AppLogger.Infof("电影 %s 的版本 %s 已被淘汰，不同步到STRM目录", mediaFile.Name, mediaFile.VersionLabel)
+		return
+	}
 	syncPath := m.scrapePath.GetSyncPathByPath(mediaFile.Media.Path)
 	if syncPath == nil {
 		helpers.AppLogger.Errorf("未命中任何STRM同步目录, 无法将文件同步到STRM目录 %s", mediaFile.Media.Path)
@@ -579,7 +574,7 @@ func (sm *movieScrapeImpl) GenerateMovieNfo(mediaFile *models.ScrapeMediaFile, l
 	nfoPath := filepath.Join(localTempPath, nfoName)
 	rates := []helpers.Rating{
 		{
-			Name:  "tmdb",
+			Name:  mediaFile.Media.GetMetadataProvider(),
 			Max:   10,
 			Value: mediaFile.Media.VoteAverage,
 			Votes: mediaFile.Media.VoteCount,
@@ -667,7 +662,7 @@ func (sm *movieScrapeImpl) GenerateMovieNfo(mediaFile *models.ScrapeMediaFile, l
 		Tagline:    mediaFile.Media.Tagline,
 		Runtime:    mediaFile.Media.Runtime,
 		Id:         mediaFile.Media.ImdbId,
-		TmdbId:     mediaFile.Media.TmdbId,
+		TmdbId:     mediaFile.Media.GetNfoTmdbId(),
 		ImdbId:     mediaFile.Media.ImdbId,
 		Uniqueid: []helpers.UniqueId{
 			{
@@ -677,7 +672,7 @@ func (sm *movieScrapeImpl) GenerateMovieNfo(mediaFile *models.ScrapeMediaFile, l
 			},
 			{
 				Id:      fmt.Sprintf("%d", mediaFile.Media.TmdbId),
-				Type:    "tmdb",
+				Type:    mediaFile.Media.GetMetadataProvider(),
 				Default: false,
 			},
 		},
@@ -742,6 +737,7 @@ func (m *movieScrapeImpl) MakeMediaFromTMDB(mediaFile *models.ScrapeMediaFile, t
 			TmdbId:       mediaFile.TmdbId,
 			Status:       models.MediaStatusUnScraped,
 		}
+		mediaFile.Media.MetadataProvider = mediaFile.GetMetadataProvider()
 		helpers.AppLogger.Infof("创建新的Media对象: %s, TMDBID=%d, 类型=%s", mediaFile.Media.Name, mediaFile.Media.TmdbId, mediaFile.Media.MediaType)
 	} else {
 		mediaFile.QueryRelation()
@@ -756,6 +752,10 @@ func (m *movieScrapeImpl) MakeMediaFromTMDB(mediaFile *models.ScrapeMediaFile, t
 
 func (m *movieScrapeImpl) GetMovieRealName(sm *models.ScrapeMediaFile, name string, filetype string) string {
 	if filetype == "nfo" {
+		// 多版本共用一个nfo
+		if sm.VersionLabel != "" && sm.ScrapeType != models.ScrapeTypeOnly {
+			return "movie.nfo"
+		}
 		return fmt.Sprintf("%s.nfo", sm.NewVideoBaseName)
 	}
 	if sm.ScrapeType == models.ScrapeTypeOnly {
@@ -777,7 +777,7 @@ func (m *movieScrapeImpl) Rollback(mediaFile *models.ScrapeMediaFile) error {
 		return nil
 	}
 	mediaFile.QueryRelation()
-	newBaseName := fmt.Sprintf("%s (%d) {tmdbid-%d}", mediaFile.Name, mediaFile.Year, mediaFile.TmdbId)
+	newBaseName := strings.TrimSpace(fmt.Sprintf("%s (%d) %s", mediaFile.Name, mediaFile.Year, mediaFile.GetProviderIdTag()))
 	if mediaFile.ScrapeType == models.ScrapeTypeOnly {
 		files := make([]models.WillDeleteFile, 0)
 		// 删除所有上传的元数据
@@ -174,9 +174,8 @@ func (t *tvShowScrapeImpl) ScrapeSeasonMedia(mediaFile *models.ScrapeMediaFile)
 		return nil
 	}
 	// 查询季详情
-	seasonDetail, err := t.tmdbClient.GetTvSeasonDetail(mediaFile.TmdbId, mediaFile.SeasonNumber, models.GlobalScrapeSettings.GetTmdbLanguage())
+	seasonDetail, err := t.getProvider(mediaFile).GetSeasonDetail(mediaFile.TmdbId, mediaFile.SeasonNumber)
 	if err != nil {
-		helpers.AppLogger.Errorf("查询tmdb电视剧季详情失败,下次重试, 失败原因: %v", err)
 		return err
 	}
 	if mediaFile.MediaSeasonId == 0 {
@@ -6,14 +6,15 @@ import (
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
 	"Q115-STRM/internal/openlist"
-	"Q115-STRM/internal/tmdb"
 	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
 	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 )
@@ -29,18 +30,21 @@ type tvshowTask struct {
 	seasons   []uint
 }
 
-func NewTvShowScrapeImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client, baiduPanClient *baidupan.Client) scrapeImpl {
-	tmdbImpl := NewTmdbTvShowImpl(scrapePath, ctx)
+func NewTvShowScrapeImpl(scrapePath *models.ScrapePath, ctx context.Context, v115Client *v115open.OpenClient, openlistClient *openlist.Client, baiduPanClient *baidupan.Client, webDavClient *webdav.Client) scrapeImpl {
+	providers := newMetadataProviders(scrapePath, ctx)
 	return &tvShowScrapeImpl{
 		ScrapeBase: ScrapeBase{
-			scrapePath:     scrapePath,
-			ctx:            ctx,
-			identifyImpl:   NewIdTvShowImpl(scrapePath, ctx, tmdbImpl),
+			scrapePath: scrapePath,
+			ctx:        ctx,
+			identifyImpl: newProviderIdentifyImpl(scrapePath, providers, func(p MetadataProvider) IdentifyImpl {
+				return NewIdTvShowImpl(scrapePath, ctx, p.TvShowImpl())
+			}),
 			categoryImpl:   NewCategoryTvShowImpl(scrapePath),
-			renameImpl:     NewRenameTvShowImpl(scrapePath, ctx, v115Client, openlistClient, baiduPanClient),
-			tmdbClient:     tmdbImpl.Client,
+			renameImpl:     NewRenameTvShowImpl(scrapePath, ctx, v115Client, openlistClient, baiduPanClient, webDavClient),
+			providers:      providers,
 			v115Client:     v115Client,
 			baiduPanClient: baiduPanClient,
+			webDavClient:   webDavClient,
 			openlistClient: openlistClient,
 		},
 	}
@@ -54,6 +58,8 @@ func (t *tvShowScrapeImpl) Start() error {
 		helpers.AppLogger.Infof("没有待刮削和待整理的记录，无需启动刮削任务")
 		return nil
 	}
+	// 开启批量AI识别时，先按电视剧目录批量识别所有待识别的文件，并修正季和集
+	t.AiBatchIdentify()
 	t.fileTasks = make(chan *tvshowTask, t.scrapePath.GetMaxThreads())
 	t.episodeTasks = make(chan uint, 100)
 	// 每次从数据库中查询maxthreads个任务加入队列，等待处理完成后继续下一次查询直到无法查询到数据
@@ -278,45 +284,10 @@ func (t *tvShowScrapeImpl) ScrapeTvshow(mediaFile *models.ScrapeMediaFile) error
 
 func (t *tvShowScrapeImpl) ScrapeTvshowMedia(mediaFile *models.ScrapeMediaFile) error {
 	helpers.AppLogger.Infof("刮削电视剧, 名字=%s，年份=%d, tmdbid=%d", mediaFile.Name, mediaFile.Year, mediaFile.TmdbId)
-	tmdbInfo := &models.TmdbInfo{}
-	// 查询详情
-	tvDetail, err := t.tmdbClient.GetTvDetail(mediaFile.TmdbId, models.GlobalScrapeSettings.GetTmdbLanguage())
+	tmdbInfo, err := t.getProvider(mediaFile).GetTvShowInfo(mediaFile.TmdbId)
 	if err != nil {
-		helpers.AppLogger.Errorf("查询tmdb电视剧详情失败, 下次重试, 失败原因: %v", err)
 		return err
 	}
-	tmdbInfo.TvShowDetail = tvDetail
-	// 查询演职人员
-	cast, _ := t.tmdbClient.GetTvCredits(mediaFile.TmdbId, models.GlobalScrapeSettings.GetTmdbLanguage())
-	tmdbInfo.Credits = cast
-	// 查询图片
-	images, _ := t.tmdbClient.GetTvImages(mediaFile.TmdbId, models.GlobalScrapeSettings.GetTmdbImageLanguage())
-	if images != nil {
-		// 如果图片为空,则使用详情中的图片
-		if len(images.Posters) == 0 && tvDetail.PosterPath != "" {
-			images.Posters = append(images.Posters, tmdb.Image{
-				FilePath: tvDetail.PosterPath,
-			})
-		}
-		if len(images.Backdrops) == 0 && tvDetail.BackdropPath != "" {
-			images.Backdrops = append(images.Backdrops, tmdb.Image{
-				FilePath: tvDetail.BackdropPath,
-			})
-		}
-		tmdbInfo.Images = images
-	} else {
-		tmdbInfo.Images = &tmdb.Images{}
-		if tvDetail.PosterPath != "" {
-			tmdbInfo.Images.Posters = append(tmdbInfo.Images.Posters, tmdb.Image{
-				FilePath: tvDetail.PosterPath,
-			})
-		}
-		if tvDetail.BackdropPath != "" {
-			tmdbInfo.Images.Backdrops = append(tmdbInfo.Images.Backdrops, tmdb.Image{
-				FilePath: tvDetail.BackdropPath,
-			})
-		}
-	}
 	// 使用tmdbinfo补全media的信息
 	t.MakeMediaFromTMDB(mediaFile, tmdbInfo)
 	return nil
@@ -404,6 +375,7 @@ func (t *tvShowScrapeImpl) MakeMediaFromTMDB(mediaFile *models.ScrapeMediaFile,
 			TmdbId:       mediaFile.TmdbId,
 			Status:       models.MediaStatusUnScraped,
 		}
+		mediaFile.Media.MetadataProvider = mediaFile.GetMetadataProvider()
 		helpers.AppLogger.Infof("创建新的Media对象: %s, TMDBID=%d, 类型=%s", mediaFile.Media.Name, mediaFile.Media.TmdbId, mediaFile.Media.MediaType)
 	}
 	mediaFile.Media.FillInfoByTmdbInfo(tmdbInfo)
@@ -443,7 +415,7 @@ func (t *tvShowScrapeImpl) GenerateNewTvshowName(mediaFile *models.ScrapeMediaFi
 	}
 	folderTemplate := t.scrapePath.FolderNameTemplate
 	if folderTemplate == "" && remotePath == "" {
-		folderTemplate = "{title} ({year})"
+		folderTemplate = models.DefaultFolderNameTemplate
 	}
 	// 根据命名规则生成文件夹名称
 	if t.scrapePath.FolderNameTemplate == "" {
@@ -473,7 +445,7 @@ func (t *tvShowScrapeImpl) GenerateTvShowNfo(mediaFile *models.ScrapeMediaFile,
 	nfoPath := filepath.Join(localTempPath, "tvshow.nfo")
 	rates := []helpers.Rating{
 		{
-			Name:  "tmdb",
+			Name:  mediaFile.Media.GetMetadataProvider(),
 			Max:   10,
 			Value: mediaFile.Media.VoteAverage,
 			Votes: mediaFile.Media.VoteCount,
@@ -509,7 +481,7 @@ func (t *tvShowScrapeImpl) GenerateTvShowNfo(mediaFile *models.ScrapeMediaFile,
 		// Actor:      mediaFile.Media.Actors,
 		Director:  mediaFile.Media.Director,
 		Id:        mediaFile.Media.ImdbId,
-		TmdbId:    mediaFile.Media.TmdbId,
+		TmdbId:    mediaFile.Media.GetNfoTmdbId(),
 		ImdbId:    mediaFile.Media.ImdbId,
 		Premiered: mediaFile.Media.ReleaseDate,
 		Aired:     mediaFile.Media.ReleaseDate,
@@ -521,7 +493,7 @@ func (t *tvShowScrapeImpl) GenerateTvShowNfo(mediaFile *models.ScrapeMediaFile,
 			},
 			{
 				Id:      fmt.Sprintf("%d", mediaFile.TmdbId),
-				Type:    "tmdb",
+				Type:    mediaFile.Media.GetMetadataProvider(),
 				Default: false,
 			},
 		},
@@ -593,6 +565,7 @@ func (t *tvShowScrapeImpl) UpdateTvshowDataToAllEpisode(mediaFile *models.Scrape
 		"name":                    mediaFile.Name,
 		"year":                    mediaFile.Year,
 		"tmdb_id":                 mediaFile.TmdbId,
+		"metadata_provider":       mediaFile.MetadataProvider,
 		"new_path_name":           mediaFile.NewPathName,
 		"new_path_id":             mediaFile.NewPathId,
 		"category_name":           mediaFile.CategoryName,
@@ -695,7 +668,7 @@ func (t *tvShowScrapeImpl) Rollback(mediaFile *models.ScrapeMediaFile) error {
 //   - 复制：检查源目录和源视频文件是否依然存在，如果存在则删除目标目录，如果不存在则将目标文件移动回源目录（源目录不存在则新建），并修改videofileid, videofilename, videopickcode,pathid, pathname等值
 //   - 软链接、硬链接：同复制
 func (t *tvShowScrapeImpl) RollbackTvShow(mediaFile *models.ScrapeMediaFile) error {
-	newBaseName := fmt.Sprintf("%s (%d) {tmdbid-%d}", mediaFile.Name, mediaFile.Year, mediaFile.TmdbId)
+	newBaseName := strings.TrimSpace(fmt.Sprintf("%s (%d) %s", mediaFile.Name, mediaFile.Year, mediaFile.GetProviderIdTag()))
 	// 如果是仅刮削则删除所有上传的元数据
 	if mediaFile.ScrapeType == models.ScrapeTypeOnly {
 		uploadFiles := t.GetTvshowUploadFiles(mediaFile)
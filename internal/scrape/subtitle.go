@@ -0,0 +1,123 @@
+package scrape
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/subtitle"
+	"Q115-STRM/internal/v115open"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// 下载字幕到刮削临时目录，和nfo、图片一起上传或移动到整理后的目录
+// 刮削目录未开启字幕下载、未设置字幕提供者或者视频已经有外挂字幕时跳过
+// 字幕下载失败不影响刮削结果
+func (s *ScrapeBase) DownloadSubtitle(mediaFile *models.ScrapeMediaFile, localTempPath string) {
+	if !s.scrapePath.EnableSubtitle || len(mediaFile.SubtitleFiles) > 0 {
+		return
+	}
+	provider, err := models.GlobalScrapeSettings.GetSubtitleProvider()
+	if err != nil || provider == nil {
+		if err != nil {
+			helpers.AppLogger.Warnf("创建字幕提供者失败: %v", err)
+		}
+		return
+	}
+	// 重新刮削时已经下载过的字幕不再重复下载
+	if len(subtitleFilesInDir(localTempPath, mediaFile.NewVideoBaseName)) > 0 {
+		return
+	}
+	languages := models.GlobalScrapeSettings.GetSubtitleLanguages()
+	query := &subtitle.Query{
+		MediaType: subtitle.MediaTypeMovie,
+		FileName:  filepath.Base(mediaFile.VideoFilename),
+		Languages: languages,
+	}
+	// 其他元数据提供者的ID不是TMDB ID，只能使用IMDB ID和文件哈希搜索
+	if mediaFile.GetMetadataProvider() == models.MetadataProviderTmdb {
+		query.TmdbId = mediaFile.TmdbId
+	}
+	if mediaFile.Media != nil {
+		query.ImdbId = mediaFile.Media.ImdbId
+	}
+	if mediaFile.MediaType == models.MediaTypeTvShow {
+		query.MediaType = subtitle.MediaTypeEpisode
+		query.Season = mediaFile.SeasonNumber
+		query.Episode = mediaFile.EpisodeNumber
+	}
+	query.MovieHash = s.subtitleMovieHash(mediaFile)
+	results, err := provider.Search(s.ctx, query)
+	if err != nil {
+		helpers.AppLogger.Warnf("搜索字幕失败, 文件名: %s, 错误: %v", mediaFile.VideoFilename, err)
+		return
+	}
+	picked := subtitle.Pick(results, languages)
+	if picked == nil {
+		helpers.AppLogger.Infof("没有找到 %s 的字幕，语言: %s", mediaFile.VideoFilename, strings.Join(languages, ","))
+		return
+	}
+	file, err := provider.Download(s.ctx, picked)
+	if err != nil {
+		helpers.AppLogger.Warnf("下载字幕失败, 文件名: %s, 错误: %v", mediaFile.VideoFilename, err)
+		return
+	}
+	name := subtitle.FileName(mediaFile.NewVideoBaseName, picked.Language, file.Ext)
+	if err := os.WriteFile(filepath.Join(localTempPath, name), file.Content, 0644); err != nil {
+		helpers.AppLogger.Errorf("保存字幕文件 %s 失败: %v", name, err)
+		return
+	}
+	helpers.AppLogger.Infof("从 %s 下载字幕 %s 成功，哈希匹配: %v", provider.Name(), name, picked.HashMatch)
+}
+
+// 计算视频的OpenSubtitles哈希，本地文件直接读取，网盘文件使用Range请求读取开头和结尾
+func (s *ScrapeBase) subtitleMovieHash(mediaFile *models.ScrapeMediaFile) string {
+	if filepath.Ext(mediaFile.VideoFilename) == ".strm" {
+		return ""
+	}
+	if mediaFile.SourceType == models.SourceTypeLocal {
+		f, err := os.Open(mediaFile.VideoPickCode)
+		if err != nil {
+			return ""
+		}
+		defer f.Close()
+		stat, err := f.Stat()
+		if err != nil {
+			return ""
+		}
+		hash, _ := subtitle.MovieHash(f, stat.Size())
+		return hash
+	}
+	downloadUrl := s.GetDownloadUrl(mediaFile)
+	if !strings.HasPrefix(downloadUrl, "http") {
+		return ""
+	}
+	hash, err := subtitle.MovieHashFromUrl(s.ctx, &http.Client{Timeout: 30 * time.Second}, downloadUrl, v115open.DEFAULTUA)
+	if err != nil {
+		helpers.AppLogger.Debugf("计算视频哈希失败，只按ID搜索字幕: %v", err)
+		return ""
+	}
+	return hash
+}
+
+// 刮削临时目录中属于该视频的字幕文件
+func subtitleFilesInDir(dir, videoBaseName string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	files := make([]string, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, videoBaseName+".") {
+			continue
+		}
+		if slices.Contains(models.SubtitleExtArr, strings.ToLower(filepath.Ext(name))) {
+			files = append(files, name)
+		}
+	}
+	return files
+}
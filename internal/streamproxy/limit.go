@@ -0,0 +1,84 @@
+package streamproxy
+
+import (
+	"context"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// 单个客户端的限速器，同一个客户端的多个连接共用，没有连接时删除
+type clientLimiter struct {
+	limiter *rate.Limiter
+	refs    int
+}
+
+// 全局和单客户端限速
+type limiters struct {
+	mu          sync.Mutex
+	global      *rate.Limiter
+	clientLimit int64
+	clients     map[string]*clientLimiter
+}
+
+func newLimiters() *limiters {
+	return &limiters{
+		global:  rate.NewLimiter(rate.Inf, copyBufferSize),
+		clients: make(map[string]*clientLimiter),
+	}
+}
+
+// 限速为0表示不限速，令牌桶容量至少是一次读取的大小
+func newLimit(bytesPerSecond int64) (rate.Limit, int) {
+	if bytesPerSecond <= 0 {
+		return rate.Inf, copyBufferSize
+	}
+	burst := max(int(bytesPerSecond), copyBufferSize)
+	return rate.Limit(bytesPerSecond), burst
+}
+
+func (l *limiters) setLimit(globalLimit, clientLimit int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	limit, burst := newLimit(globalLimit)
+	l.global.SetLimit(limit)
+	l.global.SetBurst(burst)
+	l.clientLimit = clientLimit
+	limit, burst = newLimit(clientLimit)
+	for _, c := range l.clients {
+		c.limiter.SetLimit(limit)
+		c.limiter.SetBurst(burst)
+	}
+}
+
+func (l *limiters) acquire(client string) *clientLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c, ok := l.clients[client]
+	if !ok {
+		limit, burst := newLimit(l.clientLimit)
+		c = &clientLimiter{limiter: rate.NewLimiter(limit, burst)}
+		l.clients[client] = c
+	}
+	c.refs++
+	return c
+}
+
+func (l *limiters) release(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c, ok := l.clients[client]; ok {
+		c.refs--
+		if c.refs <= 0 {
+			delete(l.clients, client)
+		}
+	}
+}
+
+// 发送n个字节前等待令牌，先等单客户端再等全局
+func (l *limiters) wait(ctx context.Context, c *clientLimiter, n int) error {
+	if err := c.limiter.WaitN(ctx, n); err != nil {
+		return err
+	}
+	return l.global.WaitN(ctx, n)
+}
@@ -0,0 +1,384 @@
+package streamproxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrHostNotAllowed = errors.New("目标域名不在反代白名单中")
+	ErrBadRange       = errors.New("续传响应的范围和请求不一致")
+)
+
+// 默认允许反代的网盘CDN域名后缀
+var DefaultAllowHosts = []string{
+	// 115
+	"115.com", "115cdn.net", "115cdn.com",
+	// 百度网盘
+	"baidu.com", "baidupcs.com",
+	// 123云盘
+	"123pan.com", "123pan.cn", "123912.com", "123957.com", "123295.com", "123865.com", "123684.com", "123952.com",
+}
+
+const (
+	DEFAULT_IDLE_TIMEOUT = 30 * time.Second // 上游连续这么久没有数据就断开
+	maxResume            = 3                // 上游中断后连续续传失败的最大次数
+	copyBufferSize       = 32 * 1024
+)
+
+// Config 反代配置，限速单位是字节/秒，0表示不限速
+type Config struct {
+	AllowHosts  []string
+	GlobalLimit int64
+	ClientLimit int64
+	IdleTimeout time.Duration
+}
+
+// Request 一次反代请求
+type Request struct {
+	Target    string
+	UserAgent string
+	Client    string // 客户端标识，用于单客户端限速和统计，一般是IP
+	Source    string // 来源，例如115、baidupan，只用于统计
+	PickCode  string
+	// 刷新下载链接，链接过期（403/404/410）或者播放中途断开时调用，为nil时不刷新
+	Refresh func(ctx context.Context) (string, error)
+}
+
+// 需要转发给上游的请求头
+var forwardHeaders = []string{"Range", "If-Range", "Cookie", "Referer"}
+
+// 逐跳头，不能转发给客户端
+var hopHeaders = []string{"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+// Proxy 流媒体反代，所有请求共用连接池，不限制总时长，只检查上游读取是否停滞
+type Proxy struct {
+	client   *http.Client
+	mu       sync.RWMutex
+	cfg      Config
+	limiters *limiters
+	stats    *stats
+}
+
+func New(cfg Config) *Proxy {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   15 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   15 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+	}
+	p := &Proxy{
+		limiters: newLimiters(),
+		stats:    newStats(),
+	}
+	p.client = &http.Client{
+		Transport: transport,
+		// CDN可能再跳转一次，每一跳都要检查白名单
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return errors.New("跳转次数过多")
+			}
+			if !p.Allowed(req.URL.String()) {
+				return ErrHostNotAllowed
+			}
+			return nil
+		},
+	}
+	p.SetConfig(cfg)
+	return p
+}
+
+// SetConfig 更新配置，正在进行的请求在下一次读取时使用新的限速
+func (p *Proxy) SetConfig(cfg Config) {
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = DEFAULT_IDLE_TIMEOUT
+	}
+	hosts := make([]string, 0, len(cfg.AllowHosts))
+	for _, h := range cfg.AllowHosts {
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "*.")))
+		if h != "" {
+			hosts = append(hosts, h)
+		}
+	}
+	cfg.AllowHosts = hosts
+	p.mu.Lock()
+	p.cfg = cfg
+	p.mu.Unlock()
+	p.limiters.setLimit(cfg.GlobalLimit, cfg.ClientLimit)
+}
+
+func (p *Proxy) config() Config {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.cfg
+}
+
+// Allowed 检查目标地址是否在白名单中，只允许http(s)，域名按后缀匹配
+func (p *Proxy) Allowed(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allow := range p.config().AllowHosts {
+		if host == allow || strings.HasSuffix(host, "."+allow) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats 当前的反代统计
+func (p *Proxy) Stats() Snapshot {
+	cfg := p.config()
+	snapshot := p.stats.snapshot()
+	snapshot.GlobalLimit = cfg.GlobalLimit
+	snapshot.ClientLimit = cfg.ClientLimit
+	return snapshot
+}
+
+// Serve 反代一次请求，响应头发出之前出错会返回错误由调用方处理，之后的错误只记录到统计
+func (p *Proxy) Serve(w http.ResponseWriter, r *http.Request, req *Request) error {
+	if !p.Allowed(req.Target) {
+		p.stats.reject()
+		return ErrHostNotAllowed
+	}
+	cfg := p.config()
+	stream := p.stats.start(req)
+	defer p.stats.finish(stream)
+	ctx := r.Context()
+
+	target := req.Target
+	resp, err := p.do(ctx, r.Method, target, r.Header, req.UserAgent, "", cfg.IdleTimeout)
+	if err == nil && isExpired(resp.StatusCode) && req.Refresh != nil {
+		resp.Body.Close()
+		if target, err = p.refresh(ctx, req, stream); err == nil {
+			resp, err = p.do(ctx, r.Method, target, r.Header, req.UserAgent, "", cfg.IdleTimeout)
+		}
+	}
+	if err != nil {
+		p.stats.fail(stream)
+		return err
+	}
+	body := resp.Body
+	defer func() { body.Close() }()
+
+	copyHeader(w.Header(), resp.Header)
+	w.WriteHeader(resp.StatusCode)
+	if r.Method == http.MethodHead || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if resp.StatusCode >= 300 {
+			io.Copy(w, io.LimitReader(body, 64*1024))
+		}
+		return nil
+	}
+	offset, end := responseRange(resp)
+	limiter := p.limiters.acquire(req.Client)
+	defer p.limiters.release(req.Client)
+	failures := 0
+	for {
+		n, clientGone, err := p.copy(ctx, w, body, limiter, stream)
+		offset += n
+		if err == nil || clientGone || ctx.Err() != nil {
+			return err
+		}
+		if n > 0 {
+			failures = 0
+		}
+		// 不知道结束位置无法续传，连续多次没有进展也不再重试
+		if end < 0 || offset > end || failures >= maxResume {
+			p.stats.fail(stream)
+			return err
+		}
+		failures++
+		next, err := p.resume(ctx, r, req, stream, &target, offset, end, cfg.IdleTimeout)
+		if err != nil {
+			p.stats.fail(stream)
+			return err
+		}
+		body.Close()
+		body = next.Body
+	}
+}
+
+// 上游中断后从offset继续请求，原链接续传失败（过期或者不支持Range）时刷新链接再试一次
+func (p *Proxy) resume(ctx context.Context, r *http.Request, req *Request, stream *streamStat, target *string, offset, end int64, idleTimeout time.Duration) (*http.Response, error) {
+	resp, err := p.resumeFrom(ctx, r, req, *target, offset, end, idleTimeout)
+	if err != nil && req.Refresh != nil && ctx.Err() == nil {
+		newTarget, refreshErr := p.refresh(ctx, req, stream)
+		if refreshErr != nil {
+			return nil, refreshErr
+		}
+		*target = newTarget
+		resp, err = p.resumeFrom(ctx, r, req, *target, offset, end, idleTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.stats.resume(stream)
+	return resp, nil
+}
+
+func (p *Proxy) resumeFrom(ctx context.Context, r *http.Request, req *Request, target string, offset, end int64, idleTimeout time.Duration) (*http.Response, error) {
+	rangeHeader := fmt.Sprintf("bytes=%d-%d", offset, end)
+	resp, err := p.do(ctx, http.MethodGet, target, r.Header, req.UserAgent, rangeHeader, idleTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if start, _ := responseRange(resp); resp.StatusCode != http.StatusPartialContent || start != offset {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %d %s", ErrBadRange, resp.StatusCode, resp.Header.Get("Content-Range"))
+	}
+	return resp, nil
+}
+
+func (p *Proxy) refresh(ctx context.Context, req *Request, stream *streamStat) (string, error) {
+	p.stats.refresh(stream)
+	target, err := req.Refresh(ctx)
+	if err != nil {
+		return "", fmt.Errorf("刷新下载链接失败: %w", err)
+	}
+	if !p.Allowed(target) {
+		return "", ErrHostNotAllowed
+	}
+	return target, nil
+}
+
+// 发起上游请求，rangeHeader不为空时覆盖客户端的Range（续传时使用）
+func (p *Proxy) do(ctx context.Context, method, target string, header http.Header, ua, rangeHeader string, idleTimeout time.Duration) (*http.Response, error) {
+	if method != http.MethodHead {
+		method = http.MethodGet
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	upstream, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for _, k := range forwardHeaders {
+		if v := header.Values(k); len(v) > 0 {
+			upstream.Header[k] = v
+		}
+	}
+	if rangeHeader != "" {
+		upstream.Header.Set("Range", rangeHeader)
+		upstream.Header.Del("If-Range")
+	}
+	upstream.Header.Set("User-Agent", ua)
+	resp, err := p.client.Do(upstream)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = newIdleReader(resp.Body, idleTimeout, cancel)
+	return resp, nil
+}
+
+// 复制数据并限速，返回复制的字节数，读完返回nil，写入客户端失败时clientGone为true
+func (p *Proxy) copy(ctx context.Context, w http.ResponseWriter, body io.Reader, limiter *clientLimiter, stream *streamStat) (int64, bool, error) {
+	buf := make([]byte, copyBufferSize)
+	flusher, _ := w.(http.Flusher)
+	var written int64
+	for {
+		n, readErr := body.Read(buf)
+		if n > 0 {
+			if err := p.limiters.wait(ctx, limiter, n); err != nil {
+				return written, true, err
+			}
+			m, err := w.Write(buf[:n])
+			written += int64(m)
+			p.stats.add(stream, int64(m))
+			if err != nil {
+				return written, true, err
+			}
+			if flusher != nil && n < copyBufferSize {
+				flusher.Flush()
+			}
+		}
+		if readErr == io.EOF {
+			return written, false, nil
+		}
+		if readErr != nil {
+			return written, false, readErr
+		}
+	}
+}
+
+func isExpired(statusCode int) bool {
+	return statusCode == http.StatusForbidden || statusCode == http.StatusNotFound || statusCode == http.StatusGone || statusCode == http.StatusUnauthorized
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, v := range src {
+		dst[k] = append([]string(nil), v...)
+	}
+	for _, k := range hopHeaders {
+		dst.Del(k)
+	}
+}
+
+// 解析响应的起止位置，206从Content-Range中解析，200从0开始，不知道结束位置时end为-1
+func responseRange(resp *http.Response) (start int64, end int64) {
+	if resp.StatusCode == http.StatusPartialContent {
+		// bytes 100-199/1000
+		cr := strings.TrimPrefix(resp.Header.Get("Content-Range"), "bytes ")
+		rangePart, _, _ := strings.Cut(cr, "/")
+		s, e, ok := strings.Cut(rangePart, "-")
+		if !ok {
+			return 0, -1
+		}
+		start, err1 := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		end, err2 := strconv.ParseInt(strings.TrimSpace(e), 10, 64)
+		if err1 != nil || err2 != nil {
+			return 0, -1
+		}
+		return start, end
+	}
+	if resp.ContentLength > 0 {
+		return 0, resp.ContentLength - 1
+	}
+	return 0, -1
+}
+
+// 上游读取停滞检测，单次读取超过idleTimeout没有返回就取消请求
+// 只在读取时计时，客户端暂停播放导致的等待不算停滞
+type idleReader struct {
+	body   io.ReadCloser
+	timer  *time.Timer
+	idle   time.Duration
+	cancel context.CancelFunc
+}
+
+func newIdleReader(body io.ReadCloser, idle time.Duration, cancel context.CancelFunc) *idleReader {
+	timer := time.AfterFunc(idle, cancel)
+	timer.Stop()
+	return &idleReader{body: body, timer: timer, idle: idle, cancel: cancel}
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	r.timer.Reset(r.idle)
+	n, err := r.body.Read(p)
+	r.timer.Stop()
+	return n, err
+}
+
+func (r *idleReader) Close() error {
+	r.timer.Stop()
+	r.cancel()
+	return r.body.Close()
+}
@@ -0,0 +1,101 @@
+package streamproxy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var testContent = bytes.Repeat([]byte("0123456789"), 10000)
+
+func newTestUpstream(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/good", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "video.mkv", time.Time{}, bytes.NewReader(testContent))
+	})
+	mux.HandleFunc("/expired", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	// 只返回一半内容后断开连接，模拟下载链接中途失效
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(testContent)))
+		w.WriteHeader(http.StatusOK)
+		w.Write(testContent[:len(testContent)/2])
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newTestProxy() *Proxy {
+	return New(Config{AllowHosts: []string{"127.0.0.1"}})
+}
+
+func TestServeRange(t *testing.T) {
+	upstream := newTestUpstream(t)
+	p := newTestProxy()
+	r := httptest.NewRequest(http.MethodGet, "/proxy-115", nil)
+	r.Header.Set("Range", "bytes=10-19")
+	w := httptest.NewRecorder()
+	if err := p.Serve(w, r, &Request{Target: upstream.URL + "/good", Client: "a"}); err != nil {
+		t.Fatalf("反代失败: %v", err)
+	}
+	if w.Code != http.StatusPartialContent || w.Header().Get("Content-Range") != "bytes 10-19/100000" || w.Body.String() != "0123456789" {
+		t.Errorf("Range响应错误: %d %s %q", w.Code, w.Header().Get("Content-Range"), w.Body.String())
+	}
+}
+
+func TestServeNotAllowed(t *testing.T) {
+	p := newTestProxy()
+	r := httptest.NewRequest(http.MethodGet, "/proxy-115", nil)
+	w := httptest.NewRecorder()
+	err := p.Serve(w, r, &Request{Target: "https://example.com/video.mkv"})
+	if !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("期望拒绝反代，实际 %v", err)
+	}
+	if p.Allowed("https://evil115cdn.net/a") || !p.Allowed("http://127.0.0.1:8080/a") {
+		t.Errorf("白名单匹配错误")
+	}
+	if p.Stats().TotalRejected != 1 {
+		t.Errorf("拒绝次数统计错误: %+v", p.Stats())
+	}
+}
+
+func TestServeRefreshExpired(t *testing.T) {
+	upstream := newTestUpstream(t)
+	p := newTestProxy()
+	r := httptest.NewRequest(http.MethodGet, "/proxy-115", nil)
+	w := httptest.NewRecorder()
+	refresh := func(ctx context.Context) (string, error) { return upstream.URL + "/good", nil }
+	if err := p.Serve(w, r, &Request{Target: upstream.URL + "/expired", Refresh: refresh}); err != nil {
+		t.Fatalf("反代失败: %v", err)
+	}
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), testContent) {
+		t.Errorf("刷新链接后内容错误: %d %d", w.Code, w.Body.Len())
+	}
+}
+
+func TestServeResumeMidStream(t *testing.T) {
+	upstream := newTestUpstream(t)
+	p := newTestProxy()
+	r := httptest.NewRequest(http.MethodGet, "/proxy-115", nil)
+	w := httptest.NewRecorder()
+	refresh := func(ctx context.Context) (string, error) { return upstream.URL + "/good", nil }
+	if err := p.Serve(w, r, &Request{Target: upstream.URL + "/broken", Refresh: refresh}); err != nil {
+		t.Fatalf("反代失败: %v", err)
+	}
+	if !bytes.Equal(w.Body.Bytes(), testContent) {
+		t.Errorf("续传后内容不完整: %d", w.Body.Len())
+	}
+	stats := p.Stats()
+	if stats.TotalRefreshes != 1 || stats.TotalResumes != 1 || stats.TotalBytes != int64(len(testContent)) || stats.ActiveCount != 0 {
+		t.Errorf("统计错误: %+v", stats)
+	}
+}
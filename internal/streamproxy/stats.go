@@ -0,0 +1,161 @@
+package streamproxy
+
+import (
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 单个反代连接的统计
+type streamStat struct {
+	id        uint64
+	client    string
+	source    string
+	pickCode  string
+	host      string
+	startedAt time.Time
+	bytes     atomic.Int64
+	refreshes int
+	resumes   int
+}
+
+// StreamInfo 正在进行的反代连接
+type StreamInfo struct {
+	Id        uint64 `json:"id"`
+	Client    string `json:"client"`
+	Source    string `json:"source"`
+	PickCode  string `json:"pick_code"`
+	Host      string `json:"host"`
+	StartedAt int64  `json:"started_at"`
+	Bytes     int64  `json:"bytes"`
+	Rate      int64  `json:"rate"` // 平均速度，字节/秒
+	Refreshes int    `json:"refreshes"`
+	Resumes   int    `json:"resumes"`
+}
+
+// Snapshot 反代统计快照
+type Snapshot struct {
+	Active         []StreamInfo `json:"active"`
+	ActiveCount    int          `json:"active_count"`
+	ActiveRate     int64        `json:"active_rate"` // 正在进行的连接的平均速度之和，字节/秒
+	TotalRequests  int64        `json:"total_requests"`
+	TotalBytes     int64        `json:"total_bytes"`
+	TotalRefreshes int64        `json:"total_refreshes"`
+	TotalResumes   int64        `json:"total_resumes"`
+	TotalFailures  int64        `json:"total_failures"`
+	TotalRejected  int64        `json:"total_rejected"`
+	GlobalLimit    int64        `json:"global_limit"`
+	ClientLimit    int64        `json:"client_limit"`
+}
+
+type stats struct {
+	mu        sync.Mutex
+	nextId    uint64
+	active    map[uint64]*streamStat
+	requests  int64
+	bytes     atomic.Int64
+	refreshes int64
+	resumes   int64
+	failures  int64
+	rejected  int64
+}
+
+func newStats() *stats {
+	return &stats{active: make(map[uint64]*streamStat)}
+}
+
+func (s *stats) start(req *Request) *streamStat {
+	host := ""
+	if u, err := url.Parse(req.Target); err == nil {
+		host = u.Hostname()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextId++
+	s.requests++
+	stream := &streamStat{
+		id:        s.nextId,
+		client:    req.Client,
+		source:    req.Source,
+		pickCode:  req.PickCode,
+		host:      host,
+		startedAt: time.Now(),
+	}
+	s.active[stream.id] = stream
+	return stream
+}
+
+func (s *stats) finish(stream *streamStat) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.active, stream.id)
+}
+
+func (s *stats) add(stream *streamStat, n int64) {
+	stream.bytes.Add(n)
+	s.bytes.Add(n)
+}
+
+func (s *stats) refresh(stream *streamStat) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stream.refreshes++
+	s.refreshes++
+}
+
+func (s *stats) resume(stream *streamStat) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stream.resumes++
+	s.resumes++
+}
+
+func (s *stats) fail(stream *streamStat) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures++
+}
+
+func (s *stats) reject() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejected++
+}
+
+func (s *stats) snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	snapshot := Snapshot{
+		Active:         make([]StreamInfo, 0, len(s.active)),
+		ActiveCount:    len(s.active),
+		TotalRequests:  s.requests,
+		TotalBytes:     s.bytes.Load(),
+		TotalRefreshes: s.refreshes,
+		TotalResumes:   s.resumes,
+		TotalFailures:  s.failures,
+		TotalRejected:  s.rejected,
+	}
+	for _, stream := range s.active {
+		info := StreamInfo{
+			Id:        stream.id,
+			Client:    stream.client,
+			Source:    stream.source,
+			PickCode:  stream.pickCode,
+			Host:      stream.host,
+			StartedAt: stream.startedAt.Unix(),
+			Bytes:     stream.bytes.Load(),
+			Refreshes: stream.refreshes,
+			Resumes:   stream.resumes,
+		}
+		if elapsed := now.Sub(stream.startedAt).Seconds(); elapsed > 0 {
+			info.Rate = int64(float64(info.Bytes) / elapsed)
+		}
+		snapshot.ActiveRate += info.Rate
+		snapshot.Active = append(snapshot.Active, info)
+	}
+	sort.Slice(snapshot.Active, func(i, j int) bool { return snapshot.Active[i].Id < snapshot.Active[j].Id })
+	return snapshot
+}
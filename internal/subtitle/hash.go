@@ -0,0 +1,93 @@
+package subtitle
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// OpenSubtitles哈希使用文件开头和结尾各64KB
+const hashChunkSize = 64 * 1024
+
+// MovieHash 计算OpenSubtitles格式的文件哈希：文件大小加上开头和结尾各64KB按小端uint64累加
+func MovieHash(r io.ReaderAt, size int64) (string, error) {
+	if size < hashChunkSize {
+		return "", fmt.Errorf("文件太小，无法计算哈希: %d", size)
+	}
+	head := make([]byte, hashChunkSize)
+	if _, err := r.ReadAt(head, 0); err != nil && err != io.EOF {
+		return "", err
+	}
+	tail := make([]byte, hashChunkSize)
+	if _, err := r.ReadAt(tail, size-hashChunkSize); err != nil && err != io.EOF {
+		return "", err
+	}
+	return hashChunks(size, head, tail), nil
+}
+
+func hashChunks(size int64, chunks ...[]byte) string {
+	hash := uint64(size)
+	for _, chunk := range chunks {
+		for i := 0; i+8 <= len(chunk); i += 8 {
+			hash += binary.LittleEndian.Uint64(chunk[i:])
+		}
+	}
+	return fmt.Sprintf("%016x", hash)
+}
+
+// MovieHashFromUrl 使用Range请求读取网盘下载链接的开头和结尾计算哈希，不需要下载整个文件
+func MovieHashFromUrl(ctx context.Context, client *http.Client, url, ua string) (string, error) {
+	head, size, err := readRange(ctx, client, url, ua, 0, hashChunkSize-1)
+	if err != nil {
+		return "", err
+	}
+	if size < hashChunkSize {
+		return "", fmt.Errorf("文件太小，无法计算哈希: %d", size)
+	}
+	tail, _, err := readRange(ctx, client, url, ua, size-hashChunkSize, size-1)
+	if err != nil {
+		return "", err
+	}
+	return hashChunks(size, head, tail), nil
+}
+
+// 读取指定范围的内容，返回内容和从Content-Range解析出的文件总大小
+func readRange(ctx context.Context, client *http.Client, url, ua string, start, end int64) ([]byte, int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	if ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		return nil, 0, fmt.Errorf("下载链接不支持Range请求: %s", resp.Status)
+	}
+	contentRange := resp.Header.Get("Content-Range")
+	slash := strings.LastIndex(contentRange, "/")
+	if slash < 0 {
+		return nil, 0, fmt.Errorf("无法解析Content-Range: %s", contentRange)
+	}
+	size, err := strconv.ParseInt(contentRange[slash+1:], 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("无法解析Content-Range: %s", contentRange)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, end-start+1))
+	if err != nil {
+		return nil, 0, err
+	}
+	if int64(len(data)) != end-start+1 {
+		return nil, 0, fmt.Errorf("读取的内容不完整: %d", len(data))
+	}
+	return data, size, nil
+}
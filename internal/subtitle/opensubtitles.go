@@ -0,0 +1,303 @@
+package subtitle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"resty.dev/v3"
+)
+
+const (
+	ProviderOpenSubtitles = "opensubtitles"
+	// OpenSubtitles REST API地址
+	openSubtitlesBaseURL = "https://api.opensubtitles.com/api/v1"
+	// OpenSubtitles要求请求带上应用名称和版本
+	openSubtitlesUserAgent = "QMediaSync v1.0"
+	// 登录token有效期为24小时，提前一小时重新登录
+	openSubtitlesTokenTTL = 23 * time.Hour
+)
+
+var ErrOpenSubtitlesUnauthorized = errors.New("OpenSubtitles授权失败，请检查API Key、用户名和密码")
+
+func init() {
+	Register(ProviderOpenSubtitles, func(config Config) Provider {
+		return NewOpenSubtitles(config)
+	})
+}
+
+// OpenSubtitles REST API客户端
+// 只有API Key时可以搜索和少量下载，登录后下载配额按账号计算
+type OpenSubtitles struct {
+	resty       *resty.Client
+	download    *resty.Client // 下载字幕文件使用，不带API Key和登录token
+	config      Config
+	token       string
+	expiredAt   time.Time
+	tokenMu     sync.Mutex
+	rateLimiter *rate.Limiter
+}
+
+func NewOpenSubtitles(config Config) *OpenSubtitles {
+	rc := resty.New()
+	rc.SetBaseURL(openSubtitlesBaseURL)
+	rc.SetTimeout(30 * time.Second)
+	rc.SetHeader("Accept", "application/json")
+	rc.SetHeader("User-Agent", openSubtitlesUserAgent)
+	rc.SetHeader("Api-Key", config.ApiKey)
+	if config.ProxyUrl != "" {
+		rc.SetProxy(config.ProxyUrl)
+	}
+	dc := resty.New()
+	dc.SetTimeout(30 * time.Second)
+	dc.SetHeader("User-Agent", openSubtitlesUserAgent)
+	if config.ProxyUrl != "" {
+		dc.SetProxy(config.ProxyUrl)
+	}
+	return &OpenSubtitles{
+		resty:       rc,
+		download:    dc,
+		config:      config,
+		rateLimiter: rate.NewLimiter(rate.Every(250*time.Millisecond), 1), // 接口限制每秒5个请求
+	}
+}
+
+func (o *OpenSubtitles) Name() string {
+	return ProviderOpenSubtitles
+}
+
+type openSubtitlesLogin struct {
+	Token   string `json:"token"`
+	BaseUrl string `json:"base_url"`
+}
+
+type openSubtitlesSearch struct {
+	TotalCount int `json:"total_count"`
+	Data       []struct {
+		Id         string `json:"id"`
+		Attributes struct {
+			Language       string `json:"language"`
+			DownloadCount  int64  `json:"download_count"`
+			Release        string `json:"release"`
+			MoviehashMatch bool   `json:"moviehash_match"`
+			Files          []struct {
+				FileId   int64  `json:"file_id"`
+				FileName string `json:"file_name"`
+			} `json:"files"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+type openSubtitlesDownload struct {
+	Link      string `json:"link"`
+	FileName  string `json:"file_name"`
+	Remaining int    `json:"remaining"`
+	Message   string `json:"message"`
+}
+
+// 没有配置用户名时不登录，只使用API Key
+func (o *OpenSubtitles) getToken(ctx context.Context, forceLogin bool) (string, error) {
+	if o.config.Username == "" {
+		return "", nil
+	}
+	o.tokenMu.Lock()
+	defer o.tokenMu.Unlock()
+	if !forceLogin && o.token != "" && time.Now().Before(o.expiredAt) {
+		return o.token, nil
+	}
+	result := openSubtitlesLogin{}
+	resp, err := o.resty.R().SetContext(ctx).
+		SetBody(map[string]string{"username": o.config.Username, "password": o.config.Password}).
+		SetResult(&result).
+		Post("/login")
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode() == http.StatusUnauthorized {
+		return "", ErrOpenSubtitlesUnauthorized
+	}
+	if !resp.IsSuccess() || result.Token == "" {
+		return "", fmt.Errorf("OpenSubtitles登录失败: %s", resp.String())
+	}
+	// VIP账号会返回专用的接口地址
+	if result.BaseUrl != "" {
+		o.resty.SetBaseURL("https://" + strings.TrimPrefix(result.BaseUrl, "https://") + "/api/v1")
+	}
+	o.token = result.Token
+	o.expiredAt = time.Now().Add(openSubtitlesTokenTTL)
+	return o.token, nil
+}
+
+// TestConnection 配置了用户名时测试登录，否则用API Key查询支持的语言
+func (o *OpenSubtitles) TestConnection(ctx context.Context) error {
+	if o.config.ApiKey == "" {
+		return errors.New("未设置OpenSubtitles API Key")
+	}
+	if o.config.Username != "" {
+		_, err := o.getToken(ctx, true)
+		return err
+	}
+	resp, err := o.resty.R().SetContext(ctx).Get("/infos/languages")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() == http.StatusUnauthorized || resp.StatusCode() == http.StatusForbidden {
+		return ErrOpenSubtitlesUnauthorized
+	}
+	if !resp.IsSuccess() {
+		return fmt.Errorf("请求OpenSubtitles失败: %s", resp.String())
+	}
+	return nil
+}
+
+// 执行请求，token失效时重新登录后重试一次
+func (o *OpenSubtitles) do(ctx context.Context, method, url string, query map[string]string, body any, result any) error {
+	if o.config.ApiKey == "" {
+		return errors.New("未设置OpenSubtitles API Key")
+	}
+	for attempt := 0; attempt < 2; attempt++ {
+		if err := o.rateLimiter.Wait(ctx); err != nil {
+			return err
+		}
+		token, err := o.getToken(ctx, attempt > 0)
+		if err != nil {
+			return err
+		}
+		req := o.resty.R().SetContext(ctx).SetResult(result)
+		if token != "" {
+			req.SetAuthToken(token)
+		}
+		if len(query) > 0 {
+			req.SetQueryParams(query)
+		}
+		if body != nil {
+			req.SetBody(body)
+		}
+		resp, err := req.Execute(method, url)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode() == http.StatusUnauthorized && token != "" {
+			continue
+		}
+		if resp.StatusCode() == http.StatusUnauthorized || resp.StatusCode() == http.StatusForbidden {
+			return ErrOpenSubtitlesUnauthorized
+		}
+		if resp.StatusCode() == http.StatusNotAcceptable {
+			return fmt.Errorf("OpenSubtitles下载配额已用完: %s", resp.String())
+		}
+		if !resp.IsSuccess() {
+			return fmt.Errorf("请求OpenSubtitles接口 %s 失败: %s", url, resp.String())
+		}
+		return nil
+	}
+	return ErrOpenSubtitlesUnauthorized
+}
+
+func (o *OpenSubtitles) Search(ctx context.Context, query *Query) ([]*Result, error) {
+	params := map[string]string{}
+	if len(query.Languages) > 0 {
+		langs := make([]string, 0, len(query.Languages))
+		for _, lang := range query.Languages {
+			langs = append(langs, NormalizeLanguage(lang))
+		}
+		params["languages"] = strings.Join(langs, ",")
+	}
+	if query.MovieHash != "" {
+		params["moviehash"] = query.MovieHash
+	}
+	imdbId := strings.TrimPrefix(strings.ToLower(query.ImdbId), "tt")
+	if query.MediaType == MediaTypeEpisode {
+		params["type"] = "episode"
+		// 剧集的ID是整部剧的ID
+		if query.TmdbId > 0 {
+			params["parent_tmdb_id"] = strconv.FormatInt(query.TmdbId, 10)
+		} else if imdbId != "" {
+			params["parent_imdb_id"] = imdbId
+		}
+		params["season_number"] = strconv.Itoa(query.Season)
+		params["episode_number"] = strconv.Itoa(query.Episode)
+	} else {
+		params["type"] = "movie"
+		if query.TmdbId > 0 {
+			params["tmdb_id"] = strconv.FormatInt(query.TmdbId, 10)
+		} else if imdbId != "" {
+			params["imdb_id"] = imdbId
+		}
+	}
+	if params["tmdb_id"] == "" && params["imdb_id"] == "" && params["parent_tmdb_id"] == "" && params["parent_imdb_id"] == "" && query.MovieHash == "" {
+		return nil, errors.New("缺少TMDB ID、IMDB ID或者文件哈希，无法搜索字幕")
+	}
+	result := openSubtitlesSearch{}
+	if err := o.do(ctx, http.MethodGet, "/subtitles", params, nil, &result); err != nil {
+		return nil, err
+	}
+	results := make([]*Result, 0, len(result.Data))
+	for _, item := range result.Data {
+		if len(item.Attributes.Files) == 0 {
+			continue
+		}
+		file := item.Attributes.Files[0]
+		results = append(results, &Result{
+			Provider:  ProviderOpenSubtitles,
+			FileId:    strconv.FormatInt(file.FileId, 10),
+			FileName:  file.FileName,
+			Language:  item.Attributes.Language,
+			Release:   item.Attributes.Release,
+			Downloads: item.Attributes.DownloadCount,
+			HashMatch: item.Attributes.MoviehashMatch,
+		})
+	}
+	return results, nil
+}
+
+func (o *OpenSubtitles) Download(ctx context.Context, result *Result) (*File, error) {
+	fileId, err := strconv.ParseInt(result.FileId, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("字幕文件ID错误: %s", result.FileId)
+	}
+	download := openSubtitlesDownload{}
+	if err := o.do(ctx, http.MethodPost, "/download", nil, map[string]int64{"file_id": fileId}, &download); err != nil {
+		return nil, err
+	}
+	if download.Link == "" {
+		return nil, fmt.Errorf("OpenSubtitles没有返回下载链接: %s", download.Message)
+	}
+	req := o.resty.R().SetContext(ctx)
+	if !isSameHost(download.Link, o.resty.BaseURL()) {
+		// 下载链接在其他域名，不发送API Key和登录token
+		req = o.download.R().SetContext(ctx)
+	}
+	resp, err := req.Get(download.Link)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.IsSuccess() {
+		return nil, fmt.Errorf("下载字幕文件失败: %s", resp.Status())
+	}
+	name := download.FileName
+	if name == "" {
+		name = result.FileName
+	}
+	return &File{Content: resp.Bytes(), Ext: ExtFromFileName(name)}, nil
+}
+
+// 下载链接和接口是否是同一个域名，其他域名不能发送API Key和登录token
+func isSameHost(link, baseUrl string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	base, err := url.Parse(baseUrl)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, base.Host)
+}
@@ -0,0 +1,168 @@
+package subtitle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var ErrNotFound = errors.New("没有找到匹配的字幕")
+
+// 媒体类型
+const (
+	MediaTypeMovie   = "movie"
+	MediaTypeEpisode = "episode"
+)
+
+// Query 字幕搜索条件，电影使用TmdbId/ImdbId，剧集使用剧集的TmdbId/ImdbId加季和集
+type Query struct {
+	MediaType string
+	TmdbId    int64
+	ImdbId    string
+	Season    int
+	Episode   int
+	MovieHash string // OpenSubtitles格式的文件哈希，为空时只按ID搜索
+	FileName  string
+	Languages []string // 按优先级排列的语言代码，例如 zh-cn,zh-tw,en
+}
+
+// Result 字幕搜索结果
+type Result struct {
+	Provider  string `json:"provider"`
+	FileId    string `json:"file_id"`
+	FileName  string `json:"file_name"`
+	Language  string `json:"language"`
+	Release   string `json:"release"`
+	Downloads int64  `json:"downloads"`
+	HashMatch bool   `json:"hash_match"` // 文件哈希一致，说明字幕和视频的时间轴匹配
+}
+
+// File 下载好的字幕文件
+type File struct {
+	Content []byte
+	Ext     string // 含点的扩展名，例如 .srt
+}
+
+// Provider 字幕提供者，新增提供者实现该接口后调用Register注册
+type Provider interface {
+	Name() string
+	Search(ctx context.Context, query *Query) ([]*Result, error)
+	Download(ctx context.Context, result *Result) (*File, error)
+}
+
+// Config 字幕提供者的账号配置
+type Config struct {
+	ApiKey   string
+	Username string
+	Password string
+	ProxyUrl string
+}
+
+// Factory 根据配置创建字幕提供者
+type Factory func(config Config) Provider
+
+var (
+	factories   = map[string]Factory{}
+	factoriesMu sync.RWMutex
+)
+
+// Register 注册字幕提供者
+func Register(name string, factory Factory) {
+	factoriesMu.Lock()
+	defer factoriesMu.Unlock()
+	factories[name] = factory
+}
+
+// Providers 已注册的字幕提供者名称
+func Providers() []string {
+	factoriesMu.RLock()
+	defer factoriesMu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New 创建指定名称的字幕提供者
+func New(name string, config Config) (Provider, error) {
+	factoriesMu.RLock()
+	factory, ok := factories[name]
+	factoriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("不支持的字幕提供者: %s", name)
+	}
+	return factory(config), nil
+}
+
+// Pick 按语言优先级选择字幕，同一语言中哈希匹配的优先，其次是下载次数多的
+// 不在语言列表中的字幕不会被选中
+func Pick(results []*Result, languages []string) *Result {
+	priority := make(map[string]int, len(languages))
+	for i, lang := range languages {
+		lang = NormalizeLanguage(lang)
+		if _, ok := priority[lang]; !ok {
+			priority[lang] = i
+		}
+	}
+	candidates := make([]*Result, 0, len(results))
+	for _, r := range results {
+		if _, ok := priority[NormalizeLanguage(r.Language)]; ok {
+			candidates = append(candidates, r)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		pa, pb := priority[NormalizeLanguage(a.Language)], priority[NormalizeLanguage(b.Language)]
+		if pa != pb {
+			return pa < pb
+		}
+		if a.HashMatch != b.HashMatch {
+			return a.HashMatch
+		}
+		return a.Downloads > b.Downloads
+	})
+	return candidates[0]
+}
+
+// NormalizeLanguage 统一语言代码为小写和中划线，例如 zh_CN -> zh-cn
+func NormalizeLanguage(lang string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(lang)), "_", "-")
+}
+
+// EmbyLanguageTag 生成Emby能识别的语言标记，例如 zh-cn -> zh-CN，en -> en
+func EmbyLanguageTag(lang string) string {
+	lang = NormalizeLanguage(lang)
+	if before, after, ok := strings.Cut(lang, "-"); ok {
+		return before + "-" + strings.ToUpper(after)
+	}
+	return lang
+}
+
+// FileName 字幕保存的文件名，和视频同名并带上语言标记，Emby可以自动识别字幕语言
+func FileName(videoBaseName, lang, ext string) string {
+	if ext == "" {
+		ext = ".srt"
+	}
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return videoBaseName + "." + EmbyLanguageTag(lang) + strings.ToLower(ext)
+}
+
+// ExtFromFileName 从字幕文件名中取扩展名，取不到时使用.srt
+func ExtFromFileName(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == "" || len(ext) > 5 {
+		return ".srt"
+	}
+	return ext
+}
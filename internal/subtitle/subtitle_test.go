@@ -0,0 +1,97 @@
+package subtitle
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPick(t *testing.T) {
+	results := []*Result{
+		{FileId: "1", Language: "en", Downloads: 1000},
+		{FileId: "2", Language: "zh-TW", Downloads: 500},
+		{FileId: "3", Language: "zh-CN", Downloads: 10},
+		{FileId: "4", Language: "zh-CN", Downloads: 5, HashMatch: true},
+		{FileId: "5", Language: "fr", Downloads: 9999},
+	}
+	if r := Pick(results, []string{"zh_cn", "zh-tw", "en"}); r == nil || r.FileId != "4" {
+		t.Errorf("应优先选择哈希匹配的简体中文字幕: %+v", r)
+	}
+	if r := Pick(results, []string{"en", "zh-cn"}); r == nil || r.FileId != "1" {
+		t.Errorf("应按语言优先级选择英文字幕: %+v", r)
+	}
+	if r := Pick(results, []string{"ja"}); r != nil {
+		t.Errorf("没有配置的语言不应被选中: %+v", r)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	if name := FileName("Movie (2020)", "zh-cn", ".SRT"); name != "Movie (2020).zh-CN.srt" {
+		t.Errorf("字幕文件名错误: %s", name)
+	}
+	if name := FileName("S01E01", "en", ""); name != "S01E01.en.srt" {
+		t.Errorf("字幕文件名错误: %s", name)
+	}
+}
+
+func TestMovieHash(t *testing.T) {
+	// 全0文件的哈希等于文件大小
+	size := int64(200 * 1024)
+	hash, err := MovieHash(bytes.NewReader(make([]byte, size)), size)
+	if err != nil || hash != "0000000000032000" {
+		t.Fatalf("哈希错误: %s %v", hash, err)
+	}
+	content := bytes.Repeat([]byte{1, 2, 3, 4, 5, 6, 7, 8}, 30000)
+	local, err := MovieHash(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("计算哈希失败: %v", err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "video.mkv", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+	remote, err := MovieHashFromUrl(context.Background(), server.Client(), server.URL, "")
+	if err != nil || remote != local {
+		t.Errorf("Range读取计算的哈希不一致: %s %s %v", remote, local, err)
+	}
+	if _, err := MovieHash(bytes.NewReader(make([]byte, 10)), 10); err == nil {
+		t.Errorf("文件太小应返回错误")
+	}
+}
+
+func TestOpenSubtitlesDownloadForeignHost(t *testing.T) {
+	dl := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Api-Key") != "" || r.Header.Get("Authorization") != "" {
+			t.Errorf("下载其他域名的字幕不应发送API Key和token: %v", r.Header)
+		}
+		w.Write([]byte("1\n00:00:01,000 --> 00:00:02,000\nhello\n"))
+	}))
+	defer dl.Close()
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/login":
+			w.Write([]byte(`{"token":"tk"}`))
+		case "/download":
+			if r.Header.Get("Api-Key") != "key" || r.Header.Get("Authorization") != "Bearer tk" {
+				t.Errorf("接口请求缺少API Key或token: %v", r.Header)
+			}
+			w.Write([]byte(`{"link":"` + dl.URL + `/a.srt","file_name":"a.srt"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+	o := NewOpenSubtitles(Config{ApiKey: "key", Username: "user", Password: "pass"})
+	o.resty.SetBaseURL(api.URL)
+	file, err := o.Download(context.Background(), &Result{FileId: "1"})
+	if err != nil || file.Ext != ".srt" || !bytes.Contains(file.Content, []byte("hello")) {
+		t.Fatalf("下载字幕失败: %+v %v", file, err)
+	}
+	if isSameHost(dl.URL+"/a.srt", api.URL) || !isSameHost("https://API.opensubtitles.com/x", openSubtitlesBaseURL) {
+		t.Errorf("域名判断错误")
+	}
+}
@@ -68,6 +68,11 @@ func RefreshOAuthAccessToken() {
 	accounts, _ := models.GetAllAccount()
 	now := time.Now().Unix()
 	for _, account := range accounts {
+		if account.SourceType == models.SourceType123 {
+			// 123云盘使用clientID+clientSecret授权时没有refreshToken，单独处理
+			refresh123AccessToken(&account, now)
+			continue
+		}
 		if account.RefreshToken == "" {
 			continue
 		}
@@ -152,6 +157,39 @@ func RefreshOAuthAccessToken() {
 	}
 }
 
+// 刷新123云盘的访问凭证，刷新成功后由Save123TokenEvent事件保存到数据库
+func refresh123AccessToken(account *models.Account, now int64) {
+	if account.AppSecret == "" || (account.Token == "" && account.TokenFailedReason != "") {
+		// 没有凭据或者已经失效等待用户重新填写，不再重复刷新
+		return
+	}
+	if account.TokenExpiriesTime-300 > now {
+		return
+	}
+	helpers.AppLogger.Infof("开始刷新123云盘账号token，账号ID: %d, 123云盘用户名：%s", account.ID, account.Username)
+	client := account.GetOpen123Client()
+	if err := client.RefreshToken(); err != nil {
+		helpers.AppLogger.Errorf("刷新123云盘访问凭证失败: %s", err.Error())
+		// 清空token
+		account.ClearToken(err.Error())
+		ctx := context.Background()
+		notif := &models.Notification{
+			Type:      models.SystemAlert,
+			Title:     "🔐 123云盘开放平台访问凭证已失效",
+			Content:   fmt.Sprintf("账号ID：%d\n用户名：%s\n请检查clientID和clientSecret\n⏰ 时间: %s", int(account.ID), account.Username, time.Now().Format("2006-01-02 15:04:05")),
+			Timestamp: time.Now(),
+			Priority:  models.HighPriority,
+		}
+		if notificationmanager.GlobalEnhancedNotificationManager != nil {
+			if err := notificationmanager.GlobalEnhancedNotificationManager.SendNotification(ctx, notif); err != nil {
+				helpers.AppLogger.Errorf("发送访问凭证失效通知失败: %v", err)
+			}
+		}
+		return
+	}
+	helpers.AppLogger.Infof("刷新123云盘账号token成功，账号ID: %d, 新到期时间: %s", account.ID, client.GetExpiredAt().Format("2006-01-02 15:04:05"))
+}
+
 func startClearDownloadUploadTasks() {
 	helpers.AppLogger.Info("开始清除3天前的上传任务")
 	models.ClearExpireUploadTasks()
@@ -243,6 +281,10 @@ func InitCron() {
 		// helpers.AppLogger.Info("启动刮削回滚任务")
 		StartScrapeRollbackCron()
 	})
+	GlobalCron.AddFunc("* * * * *", func() {
+		// 执行到期的Emby联动删除网盘文件计划
+		models.ProcessDueNetdiskDeletions()
+	})
 	GlobalCron.AddFunc("0 * * * *", func() {
 		// 每小时清理一次请求统计数据，只保留最近24小时
 		if err := models.CleanOldRequestStatsByHours(24); err != nil {
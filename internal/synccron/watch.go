@@ -0,0 +1,109 @@
+package synccron
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/syncstrm"
+	"sync"
+	"time"
+)
+
+// 本地同步路径的实时监控，key为同步路径ID
+var localWatchers = make(map[uint]*helpers.AdvancedFolderWatcher)
+var localWatchersMu sync.Mutex
+
+// 启动所有开启了实时监控的本地同步路径
+func InitLocalWatchers() {
+	syncPaths := models.GetWatchSyncPaths()
+	for _, syncPath := range syncPaths {
+		startLocalWatcher(syncPath)
+	}
+	helpers.AppLogger.Infof("已启动 %d 个本地同步目录的实时监控", len(syncPaths))
+}
+
+// 同步路径新增、修改、删除或者开关实时监控后调用，按最新配置重启监控
+func RefreshLocalWatcher(syncPathId uint) {
+	StopLocalWatcher(syncPathId)
+	syncPath := models.GetSyncPathById(syncPathId)
+	if syncPath == nil || !syncPath.EnableWatch || syncPath.SourceType != models.SourceTypeLocal {
+		return
+	}
+	startLocalWatcher(syncPath)
+}
+
+// 停止同步路径的实时监控
+func StopLocalWatcher(syncPathId uint) {
+	localWatchersMu.Lock()
+	watcher, ok := localWatchers[syncPathId]
+	delete(localWatchers, syncPathId)
+	localWatchersMu.Unlock()
+	if ok {
+		watcher.Close()
+		helpers.AppLogger.Infof("已停止同步目录 %d 的实时监控", syncPathId)
+	}
+}
+
+func startLocalWatcher(syncPath *models.SyncPath) {
+	watcher, err := helpers.NewAdvancedFolderWatcher(syncPath.RemotePath)
+	if err != nil {
+		helpers.AppLogger.Errorf("创建同步目录 %d 的实时监控失败: %v", syncPath.ID, err)
+		return
+	}
+	// 只关心视频和元数据文件
+	extensions := append([]string{}, syncPath.GetVideoExt()...)
+	extensions = append(extensions, syncPath.GetMetaExt()...)
+	watcher.SetExtensions(extensions)
+	syncPathId := syncPath.ID
+	watcher.SetHandler(func(events []helpers.FolderEvent) {
+		handleLocalEvents(watcher, syncPathId, events)
+	})
+	localWatchersMu.Lock()
+	if old, ok := localWatchers[syncPathId]; ok {
+		old.Close()
+	}
+	localWatchers[syncPathId] = watcher
+	localWatchersMu.Unlock()
+	go func() {
+		if err := watcher.Start(); err != nil {
+			helpers.AppLogger.Errorf("同步目录 %d 的实时监控启动失败: %v", syncPathId, err)
+			localWatchersMu.Lock()
+			if localWatchers[syncPathId] == watcher {
+				delete(localWatchers, syncPathId)
+			}
+			localWatchersMu.Unlock()
+			watcher.Close()
+		}
+	}()
+}
+
+// 完整同步排队或运行中时，实时变化延迟重试的间隔
+const localWatchRetryDelay = 30 * time.Second
+
+// 处理一批防抖后的文件变化
+func handleLocalEvents(watcher *helpers.AdvancedFolderWatcher, syncPathId uint, events []helpers.FolderEvent) {
+	// 完整同步在排队或者运行中时不能同时处理，完整同步可能已经处理过变化所在的目录，等同步完成后重新处理
+	if CheckNewTaskStatus(syncPathId, SyncTaskTypeStrm) != TaskStatusNone {
+		helpers.AppLogger.Infof("同步目录 %d 正在同步，%d 个实时变化在 %s 后重试", syncPathId, len(events), localWatchRetryDelay)
+		watcher.RequeueAfter(events, localWatchRetryDelay)
+		return
+	}
+	syncPath := models.GetSyncPathById(syncPathId)
+	if syncPath == nil || !syncPath.EnableWatch {
+		return
+	}
+	syncStrm := syncstrm.NewWatchSyncStrm(syncPath)
+	if syncStrm == nil {
+		helpers.AppLogger.Errorf("同步目录 %d 创建同步实例失败，无法处理实时变化", syncPathId)
+		return
+	}
+	// 删除超过阈值时新增的文件已经处理，仍然需要刷新媒体库
+	if err := syncStrm.ProcessLocalEvents(events); err != nil {
+		helpers.AppLogger.Errorf("同步目录 %d 处理实时变化失败: %v", syncPathId, err)
+	}
+	helpers.AppLogger.Infof("同步目录 %d 处理了 %d 个实时变化，新增STRM %d 个，新增元数据 %d 个", syncPathId, len(events), syncStrm.NewStrm, syncStrm.NewMeta)
+	if syncStrm.NewStrm > 0 || syncStrm.NewMeta > 0 {
+		if err := models.RefreshEmbyLibraryBySyncPathId(syncPathId); err != nil {
+			helpers.AppLogger.Warnf("同步目录 %d 刷新Emby媒体库失败: %v", syncPathId, err)
+		}
+	}
+}
@@ -38,9 +38,15 @@ type SyncFileCache struct {
 	Sha1     string `json:"sha1"`
 	ThumbUrl string `json:"thumb_url"`
 
+	// 123云盘的etag，是文件的MD5
+	Md5 string `json:"md5"`
+
 	// openlist特有字段
 	OpenlistSign string `json:"openlist_sign"`
 
+	// webdav和S3的ETag，内容变化时会改变
+	ETag string `json:"etag"`
+
 	SourceType models.SourceType `json:"source_path"`
 }
 
@@ -50,6 +56,8 @@ func (sfc *SyncFileCache) GetPath() string {
 		return sfc.Path
 	case models.SourceTypeOpenList:
 		return sfc.ParentId
+	case models.SourceTypeWebDav, models.SourceTypeS3:
+		return sfc.ParentId
 	case models.SourceTypeLocal:
 		return sfc.ParentId
 	case models.SourceType123:
@@ -68,6 +76,8 @@ func (sfc *SyncFileCache) GetFileId() string {
 		return sfc.FileId
 	case models.SourceTypeOpenList:
 		return filePath
+	case models.SourceTypeWebDav, models.SourceTypeS3:
+		return filePath
 	case models.SourceTypeLocal:
 		return filePath
 	case models.SourceType123:
@@ -85,6 +95,8 @@ func (sfc *SyncFileCache) GetPickCode(openlistBaseUrl string) string {
 		return helpers.MakeOpenListUrl(openlistBaseUrl, sfc.OpenlistSign, sfc.GetFileId())
 	case models.SourceTypeLocal:
 		return sfc.GetFileId()
+	case models.SourceTypeWebDav, models.SourceTypeS3:
+		return sfc.GetFileId()
 	case models.SourceType123:
 		return sfc.PickCode
 	}
@@ -97,6 +109,8 @@ func (sfc *SyncFileCache) GetFullRemotePath() string {
 		return filepath.ToSlash(filepath.Join(sfc.Path, sfc.FileName))
 	case models.SourceTypeOpenList:
 		return filepath.ToSlash(filepath.Join(sfc.ParentId, sfc.FileName))
+	case models.SourceTypeWebDav, models.SourceTypeS3:
+		return filepath.ToSlash(filepath.Join(sfc.ParentId, sfc.FileName))
 	case models.SourceTypeLocal:
 		return filepath.ToSlash(filepath.Join(sfc.ParentId, sfc.FileName))
 	case models.SourceType123:
@@ -148,8 +162,10 @@ func (d *SyncFileCache) GetSyncFile(s *SyncStrm, openlistBaseUrl string) *models
 		MTime:         d.MTime,
 		PickCode:      d.GetPickCode(openlistBaseUrl),
 		OpenlistSign:  d.OpenlistSign,
+		ETag:          d.ETag,
 		ThumbUrl:      d.ThumbUrl,
 		Sha1:          d.Sha1,
+		Md5:           d.Md5,
 		IsVideo:       d.IsVideo,
 		IsMeta:        d.IsMeta,
 		LocalFilePath: d.GetLocalFilePath(s.TargetPath, s.SourcePath),
@@ -0,0 +1,158 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/baidupan"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/open123"
+	"Q115-STRM/internal/v115open"
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+type open123Driver struct {
+	s      *SyncStrm
+	client *open123.Client
+}
+
+func NewOpen123Driver(client *open123.Client) *open123Driver {
+	return &open123Driver{
+		client: client,
+	}
+}
+
+func (d *open123Driver) SetSyncStrm(s *SyncStrm) {
+	d.s = s
+}
+
+// 123云盘的目录ID是数字，根目录为0
+func (d *open123Driver) GetNetFileFiles(ctx context.Context, parentPath, parentPathId string) ([]*SyncFileCache, error) {
+	parentId, err := strconv.ParseInt(parentPathId, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("目录ID %s 格式错误: %v", parentPathId, err)
+	}
+	files, err := d.client.ListAllFiles(ctx, parentId)
+	if err != nil {
+		d.s.Sync.Logger.Errorf("获取123云盘文件列表失败: %v", err)
+		return nil, err
+	}
+	fileItems := make([]*SyncFileCache, 0, len(files))
+	for _, file := range files {
+		atomic.AddInt64(&d.s.TotalFile, 1)
+		fileId := fmt.Sprintf("%d", file.FileID)
+		fileItem := SyncFileCache{
+			ParentId:   parentPathId,
+			FileId:     fileId,
+			PickCode:   fileId,
+			Path:       parentPath,
+			FileName:   file.FileName,
+			FileType:   v115open.TypeFile,
+			FileSize:   file.Size,
+			Md5:        file.Etag,
+			MTime:      file.GetMtime(),
+			SourceType: models.SourceType123,
+		}
+		if file.IsDir() {
+			fileItem.FileType = v115open.TypeDir
+			fileItem.IsVideo = false
+			fileItem.IsMeta = false
+		}
+		fileItems = append(fileItems, &fileItem)
+	}
+	return fileItems, nil
+}
+
+// 检查每一部分是否存在，不存在就创建
+func (d *open123Driver) CreateDirRecursively(ctx context.Context, path string) (pathId, remotePath string, err error) {
+	relPath, err := filepath.Rel(d.s.TargetPath, path)
+	if err != nil {
+		return "", "", fmt.Errorf("计算相对路径失败: %s 错误：%v", path, err)
+	}
+	relPath = filepath.ToSlash(relPath)
+	// 如果不以/开头，则加上/
+	if !strings.HasPrefix(relPath, "/") {
+		relPath = "/" + relPath
+	}
+	dirId, err := d.client.MkdirAll(ctx, relPath, func(dirId, parentId int64, dirPath string) {
+		// 将新添加的目录加入同步缓存
+		syncFileCache := &SyncFileCache{
+			FileId:     fmt.Sprintf("%d", dirId),
+			ParentId:   fmt.Sprintf("%d", parentId),
+			Path:       filepath.ToSlash(filepath.Dir(dirPath)),
+			FileName:   filepath.Base(dirPath),
+			FileType:   v115open.TypeDir,
+			IsVideo:    false,
+			IsMeta:     false,
+			SourceType: models.SourceType123,
+		}
+		syncFileCache.GetLocalFilePath(d.s.TargetPath, d.s.SourcePath)
+		d.s.memSyncCache.Insert(syncFileCache)
+		d.s.Sync.Logger.Infof("创建目录成功: %s 目录ID: %d", dirPath, dirId)
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("创建目录失败: %s 错误：%v", relPath, err)
+	}
+	return fmt.Sprintf("%d", dirId), relPath, nil
+}
+
+func (d *open123Driver) GetPathIdByPath(ctx context.Context, path string) (string, error) {
+	dirId, err := d.client.GetDirIdByPath(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d", dirId), nil
+}
+
+func (d *open123Driver) MakeStrmContent(sf *SyncFileCache) string {
+	// 生成URL
+	u, _ := url.Parse(d.s.Config.StrmBaseUrl)
+	ext := filepath.Ext(sf.FileName)
+	u.Path = fmt.Sprintf("/123/url/video%s", ext)
+	params := url.Values{}
+	params.Add("pickcode", sf.PickCode)
+	params.Add("userid", d.s.Account.UserId)
+	u.RawQuery = params.Encode()
+	urlStr := u.String()
+	if d.s.Config.StrmUrlNeedPath == 1 {
+		urlStr += fmt.Sprintf("&path=%s", d.s.GetRemoteFilePathUrlEncode(sf.GetFullRemotePath()))
+	}
+	return urlStr
+}
+
+func (d *open123Driver) GetTotalFileCount(ctx context.Context) (int64, string, error) {
+	return 0, "", nil
+}
+
+func (d *open123Driver) GetDirsByPathId(ctx context.Context, pathId string) ([]pathQueueItem, error) {
+	return nil, nil
+}
+
+func (d *open123Driver) GetFilesByPathId(ctx context.Context, rootPathId string, offset, limit int) ([]v115open.File, error) {
+	return nil, nil
+}
+
+// 所有文件详情，含路径
+func (d *open123Driver) DetailByFileId(ctx context.Context, fileId string) (*v115open.FileDetail, error) {
+	return nil, nil
+}
+
+// 删除目录下的某些文件，123云盘是移入回收站
+func (d *open123Driver) DeleteFile(ctx context.Context, parentId string, fileIds []string) error {
+	ids := make([]int64, 0, len(fileIds))
+	for _, fileId := range fileIds {
+		id, err := strconv.ParseInt(fileId, 10, 64)
+		if err != nil {
+			return fmt.Errorf("文件ID %s 格式错误: %v", fileId, err)
+		}
+		ids = append(ids, id)
+	}
+	return d.client.TrashFiles(ctx, ids)
+}
+
+func (d *open123Driver) GetFilesByPathMtime(ctx context.Context, rootPathId string, offset, limit int, mtime int64) (*baidupan.FileListAllResponse, error) {
+	return nil, nil
+}
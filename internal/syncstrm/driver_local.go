@@ -124,6 +124,10 @@ func (d *localDriver) DetailByFileId(ctx context.Context, fileId string) (*v115o
 func (d *localDriver) DeleteFile(ctx context.Context, parentId string, fileIds []string) error {
 	for _, fileId := range fileIds {
 		if err := os.Remove(fileId); err != nil {
+			if d.s == nil {
+				// 不在同步任务中使用时没有同步日志，直接返回错误
+				return err
+			}
 			d.s.Sync.Logger.Errorf("删除文件 %s 失败，错误: %v", fileId, err)
 			continue
 		}
@@ -0,0 +1,162 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/baidupan"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/s3"
+	"Q115-STRM/internal/v115open"
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+// S3兼容对象存储，前缀当作目录，文件ID是以/开头的对象路径
+type s3Driver struct {
+	s      *SyncStrm
+	client *s3.Client
+}
+
+func NewS3Driver(client *s3.Client) *s3Driver {
+	return &s3Driver{
+		client: client,
+	}
+}
+
+func (d *s3Driver) SetSyncStrm(s *SyncStrm) {
+	d.s = s
+}
+
+// 按/分隔列举直接子项，公共前缀作为目录，分页在客户端内部处理
+func (d *s3Driver) GetNetFileFiles(ctx context.Context, parentPath, parentPathId string) ([]*SyncFileCache, error) {
+	list, err := d.client.List(ctx, parentPathId)
+	if err != nil {
+		d.s.Sync.Logger.Errorf("获取S3对象列表失败: %s %v", parentPathId, err)
+		return nil, err
+	}
+	fileItems := make([]*SyncFileCache, 0, len(list))
+	for _, obj := range list {
+		atomic.AddInt64(&d.s.TotalFile, 1)
+		fileItem := SyncFileCache{
+			ParentId:   parentPathId,
+			Path:       parentPathId,
+			FileName:   obj.Name,
+			FileType:   v115open.TypeFile,
+			FileSize:   obj.Size,
+			ETag:       obj.ETag,
+			SourceType: models.SourceTypeS3,
+		}
+		if !obj.LastModified.IsZero() {
+			fileItem.MTime = obj.LastModified.Unix()
+		}
+		if obj.IsDir {
+			fileItem.FileType = v115open.TypeDir
+			fileItem.IsVideo = false
+			fileItem.IsMeta = false
+		}
+		fileItems = append(fileItems, &fileItem)
+	}
+	return fileItems, nil
+}
+
+// S3没有真正的目录，写入占位对象后加入同步缓存
+func (d *s3Driver) CreateDirRecursively(ctx context.Context, path string) (pathId, remotePath string, err error) {
+	relPath, err := filepath.Rel(d.s.TargetPath, path)
+	if err != nil {
+		return "", "", fmt.Errorf("计算相对路径失败:%s %w", path, err)
+	}
+	relPath = filepath.ToSlash(relPath)
+	if !strings.HasPrefix(relPath, "/") {
+		relPath = "/" + relPath
+	}
+	pathParts := strings.Split(strings.TrimPrefix(relPath, "/"), "/")
+	current := ""
+	for _, part := range pathParts {
+		current = current + "/" + part
+		exists, err := d.client.Exists(ctx, current)
+		if err != nil {
+			return "", "", fmt.Errorf("查询目录失败: %s 错误：%v", current, err)
+		}
+		if exists {
+			continue
+		}
+		if err := d.client.Mkdir(ctx, current); err != nil {
+			return "", "", fmt.Errorf("创建目录失败: %s 错误：%v", current, err)
+		}
+		parent := filepath.ToSlash(filepath.Dir(current))
+		syncFileCache := &SyncFileCache{
+			ParentId:   parent,
+			Path:       parent,
+			FileName:   filepath.Base(current),
+			FileType:   v115open.TypeDir,
+			IsVideo:    false,
+			IsMeta:     false,
+			SourceType: models.SourceTypeS3,
+		}
+		syncFileCache.GetLocalFilePath(d.s.TargetPath, d.s.SourcePath)
+		d.s.memSyncCache.Insert(syncFileCache)
+		d.s.Sync.Logger.Infof("创建S3目录: %s", current)
+	}
+	return relPath, relPath, nil
+}
+
+func (d *s3Driver) GetPathIdByPath(ctx context.Context, path string) (string, error) {
+	obj, err := d.client.Stat(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("路径 %s 不存在: %v", path, err)
+	}
+	if !obj.IsDir {
+		return "", fmt.Errorf("路径 %s 不是目录", path)
+	}
+	return obj.Path, nil
+}
+
+// STRM指向本服务的跳转接口，播放时再生成短期有效的预签名地址，用签名防止篡改对象路径
+func (d *s3Driver) MakeStrmContent(sf *SyncFileCache) string {
+	u, _ := url.Parse(d.s.Config.StrmBaseUrl)
+	ext := filepath.Ext(sf.FileName)
+	u.Path = fmt.Sprintf("/s3/url/video%s", ext)
+	pickCode := sf.GetPickCode("")
+	params := url.Values{}
+	params.Add("pickcode", pickCode)
+	params.Add("userid", d.s.Account.UserId)
+	params.Add("sign", d.s.Account.SignS3Path(pickCode))
+	u.RawQuery = params.Encode()
+	urlStr := u.String()
+	if d.s.Config.StrmUrlNeedPath == 1 {
+		urlStr += fmt.Sprintf("&path=%s", d.s.GetRemoteFilePathUrlEncode(sf.GetFullRemotePath()))
+	}
+	return urlStr
+}
+
+func (d *s3Driver) GetTotalFileCount(ctx context.Context) (int64, string, error) {
+	return 0, "", nil
+}
+
+func (d *s3Driver) GetDirsByPathId(ctx context.Context, pathId string) ([]pathQueueItem, error) {
+	return nil, nil
+}
+
+func (d *s3Driver) GetFilesByPathId(ctx context.Context, rootPathId string, offset, limit int) ([]v115open.File, error) {
+	return nil, nil
+}
+
+func (d *s3Driver) DetailByFileId(ctx context.Context, fileId string) (*v115open.FileDetail, error) {
+	return nil, nil
+}
+
+// 删除目录下的某些文件，fileId就是对象路径
+func (d *s3Driver) DeleteFile(ctx context.Context, parentId string, fileIds []string) error {
+	for _, fileId := range fileIds {
+		if err := d.client.Delete(ctx, fileId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *s3Driver) GetFilesByPathMtime(ctx context.Context, rootPathId string, offset, limit int, mtime int64) (*baidupan.FileListAllResponse, error) {
+	return nil, nil
+}
@@ -0,0 +1,155 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/baidupan"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/v115open"
+	"Q115-STRM/internal/webdav"
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+type webDavDriver struct {
+	s      *SyncStrm
+	client *webdav.Client
+}
+
+func NewWebDavDriver(client *webdav.Client) *webDavDriver {
+	return &webDavDriver{
+		client: client,
+	}
+}
+
+func (d *webDavDriver) SetSyncStrm(s *SyncStrm) {
+	d.s = s
+}
+
+// WebDAV的PROPFIND一次返回整个目录，不需要分页
+func (d *webDavDriver) GetNetFileFiles(ctx context.Context, parentPath, parentPathId string) ([]*SyncFileCache, error) {
+	list, err := d.client.List(ctx, parentPath)
+	if err != nil {
+		d.s.Sync.Logger.Errorf("获取WebDAV文件列表失败: %s %v", parentPath, err)
+		return nil, err
+	}
+	fileItems := make([]*SyncFileCache, 0, len(list))
+	for _, file := range list {
+		atomic.AddInt64(&d.s.TotalFile, 1)
+		fileItem := SyncFileCache{
+			ParentId:   parentPathId,
+			FileName:   file.Name,
+			FileType:   v115open.TypeFile,
+			FileSize:   file.Size,
+			MTime:      file.ModTime.Unix(),
+			ETag:       file.ETag,
+			SourceType: models.SourceTypeWebDav,
+		}
+		if file.ModTime.IsZero() {
+			fileItem.MTime = 0
+		}
+		if file.IsDir {
+			fileItem.FileType = v115open.TypeDir
+			fileItem.IsVideo = false
+			fileItem.IsMeta = false
+		}
+		fileItems = append(fileItems, &fileItem)
+	}
+	return fileItems, nil
+}
+
+// 逐级创建目录，并将新创建的目录加入同步缓存
+func (d *webDavDriver) CreateDirRecursively(ctx context.Context, path string) (pathId, remotePath string, err error) {
+	relPath, err := filepath.Rel(d.s.TargetPath, path)
+	if err != nil {
+		return "", "", fmt.Errorf("计算相对路径失败:%s %w", path, err)
+	}
+	relPath = filepath.ToSlash(relPath)
+	if !strings.HasPrefix(relPath, "/") {
+		relPath = "/" + relPath
+	}
+	pathParts := strings.Split(strings.TrimPrefix(relPath, "/"), "/")
+	current := ""
+	for _, part := range pathParts {
+		current = current + "/" + part
+		exists, err := d.client.Exists(ctx, current)
+		if err != nil {
+			return "", "", fmt.Errorf("查询目录失败: %s 错误：%v", current, err)
+		}
+		if exists {
+			continue
+		}
+		if err := d.client.Mkdir(ctx, current); err != nil {
+			return "", "", fmt.Errorf("创建目录失败: %s 错误：%v", current, err)
+		}
+		syncFileCache := &SyncFileCache{
+			ParentId:   filepath.ToSlash(filepath.Dir(current)),
+			FileName:   filepath.Base(current),
+			FileType:   v115open.TypeDir,
+			IsVideo:    false,
+			IsMeta:     false,
+			SourceType: models.SourceTypeWebDav,
+		}
+		syncFileCache.GetLocalFilePath(d.s.TargetPath, d.s.SourcePath)
+		d.s.memSyncCache.Insert(syncFileCache)
+		d.s.Sync.Logger.Infof("创建WebDAV目录: %s", current)
+	}
+	return relPath, relPath, nil
+}
+
+func (d *webDavDriver) GetPathIdByPath(ctx context.Context, path string) (string, error) {
+	info, err := d.client.Stat(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("路径 %s 不存在: %v", path, err)
+	}
+	if !info.IsDir {
+		return "", fmt.Errorf("路径 %s 不是目录", path)
+	}
+	return info.Path, nil
+}
+
+// STRM中不能保存WebDAV的账号密码，所以指向本服务的播放接口，用签名防止篡改路径
+func (d *webDavDriver) MakeStrmContent(sf *SyncFileCache) string {
+	u, _ := url.Parse(d.s.Config.StrmBaseUrl)
+	ext := filepath.Ext(sf.FileName)
+	u.Path = fmt.Sprintf("/webdav/url/video%s", ext)
+	filePath := sf.GetFileId()
+	params := url.Values{}
+	params.Add("pickcode", filePath)
+	params.Add("userid", d.s.Account.UserId)
+	params.Add("sign", d.s.Account.SignWebDavPath(filePath))
+	u.RawQuery = params.Encode()
+	return u.String()
+}
+
+func (d *webDavDriver) GetTotalFileCount(ctx context.Context) (int64, string, error) {
+	return 0, "", nil
+}
+
+func (d *webDavDriver) GetDirsByPathId(ctx context.Context, pathId string) ([]pathQueueItem, error) {
+	return nil, nil
+}
+
+func (d *webDavDriver) GetFilesByPathId(ctx context.Context, rootPathId string, offset, limit int) ([]v115open.File, error) {
+	return nil, nil
+}
+
+func (d *webDavDriver) DetailByFileId(ctx context.Context, fileId string) (*v115open.FileDetail, error) {
+	return nil, nil
+}
+
+// 删除目录下的某些文件，fileId就是文件的完整路径
+func (d *webDavDriver) DeleteFile(ctx context.Context, parentId string, fileIds []string) error {
+	for _, fileId := range fileIds {
+		if err := d.client.Delete(ctx, fileId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *webDavDriver) GetFilesByPathMtime(ctx context.Context, rootPathId string, offset, limit int, mtime int64) (*baidupan.FileListAllResponse, error) {
+	return nil, nil
+}
@@ -0,0 +1,150 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"context"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// 重复文件扫描任务，同一时间只能有一个扫描或者删除在执行
+var (
+	duplicateMutex   sync.Mutex
+	duplicateRunning bool
+	duplicateReport  *models.DuplicateReport
+)
+
+// DuplicateDeleteFailed 删除失败的副本和原因
+type DuplicateDeleteFailed struct {
+	Copy  *models.DuplicateCopy `json:"copy"`
+	Error string                `json:"error"`
+}
+
+// DuplicateDeleteResult 批量删除重复文件的结果，预览时Deleted是将要删除的副本
+type DuplicateDeleteResult struct {
+	Preview   bool                     `json:"preview"`
+	Deleted   []*models.DuplicateCopy  `json:"deleted"`
+	Failed    []*DuplicateDeleteFailed `json:"failed"`
+	FreedSize int64                    `json:"freed_size"`
+}
+
+// StartDuplicateScan 后台扫描所有同步目录中的重复文件
+func StartDuplicateScan() error {
+	duplicateMutex.Lock()
+	if duplicateRunning {
+		duplicateMutex.Unlock()
+		return fmt.Errorf("重复文件扫描或者删除正在进行中")
+	}
+	duplicateRunning = true
+	duplicateMutex.Unlock()
+	go func() {
+		defer func() {
+			duplicateMutex.Lock()
+			duplicateRunning = false
+			duplicateMutex.Unlock()
+		}()
+		helpers.AppLogger.Infof("开始扫描重复文件")
+		report := models.FindDuplicateFiles()
+		if report.Error != "" {
+			helpers.AppLogger.Errorf("扫描重复文件失败: %s", report.Error)
+		} else {
+			helpers.AppLogger.Infof("扫描重复文件完成，共 %d 组，浪费空间 %d 字节", len(report.Groups), report.TotalWasted)
+		}
+		duplicateMutex.Lock()
+		duplicateReport = report
+		duplicateMutex.Unlock()
+	}()
+	return nil
+}
+
+// GetDuplicateReport 返回是否正在扫描和最近一次的扫描结果
+func GetDuplicateReport() (bool, *models.DuplicateReport) {
+	duplicateMutex.Lock()
+	defer duplicateMutex.Unlock()
+	return duplicateRunning, duplicateReport
+}
+
+// DeleteDuplicateCopies 通过网盘驱动删除选中的重复副本
+// reportId必须是最近一次扫描的ID，每组至少保留一个副本，confirm为false时只返回将要删除的副本
+func DeleteDuplicateCopies(ctx context.Context, reportId int64, syncFileIds []uint, confirm bool) (*DuplicateDeleteResult, error) {
+	duplicateMutex.Lock()
+	if duplicateRunning {
+		duplicateMutex.Unlock()
+		return nil, fmt.Errorf("重复文件扫描或者删除正在进行中")
+	}
+	report := duplicateReport
+	if report == nil || report.Id != reportId {
+		duplicateMutex.Unlock()
+		return nil, fmt.Errorf("扫描结果已过期，请重新扫描后再删除")
+	}
+	plan, err := report.PlanDuplicateDelete(syncFileIds)
+	if err != nil {
+		duplicateMutex.Unlock()
+		return nil, err
+	}
+	result := &DuplicateDeleteResult{Preview: !confirm, Deleted: []*models.DuplicateCopy{}, Failed: []*DuplicateDeleteFailed{}}
+	if !confirm {
+		duplicateMutex.Unlock()
+		result.Deleted = plan
+		for _, c := range plan {
+			result.FreedSize += c.FileSize
+		}
+		return result, nil
+	}
+	duplicateRunning = true
+	duplicateMutex.Unlock()
+	defer func() {
+		duplicateMutex.Lock()
+		duplicateRunning = false
+		duplicateMutex.Unlock()
+	}()
+	drivers := make(map[uint]driverImpl)
+	for _, c := range plan {
+		if err := deleteDuplicateCopy(ctx, c, drivers); err != nil {
+			helpers.AppLogger.Errorf("删除重复文件 %s/%s 失败: %v", c.Path, c.FileName, err)
+			result.Failed = append(result.Failed, &DuplicateDeleteFailed{Copy: c, Error: err.Error()})
+			continue
+		}
+		helpers.AppLogger.Infof("已删除重复文件 %s/%s，账号 %s", c.Path, c.FileName, c.AccountName)
+		result.Deleted = append(result.Deleted, c)
+		result.FreedSize += c.FileSize
+		duplicateMutex.Lock()
+		report.RemoveCopy(c.SyncFileId)
+		duplicateMutex.Unlock()
+	}
+	return result, nil
+}
+
+// 删除一个副本：确认同步记录没有变化，通过网盘驱动删除文件，然后删除STRM文件和同步记录
+func deleteDuplicateCopy(ctx context.Context, c *models.DuplicateCopy, drivers map[uint]driverImpl) error {
+	syncFile := models.GetSyncFileById(c.SyncFileId)
+	if syncFile == nil || syncFile.FileId != c.FileId || syncFile.FileSize != c.FileSize {
+		return fmt.Errorf("文件在扫描后已经变化，请重新扫描")
+	}
+	driver, ok := drivers[c.AccountId]
+	if !ok {
+		account, err := models.GetAccountById(c.AccountId)
+		if err != nil {
+			return fmt.Errorf("查询账号失败: %v", err)
+		}
+		driver = newSyncDriver(account)
+		if driver == nil {
+			return fmt.Errorf("不支持的网盘类型: %s", account.SourceType)
+		}
+		drivers[c.AccountId] = driver
+	}
+	if err := driver.DeleteFile(ctx, syncFile.ParentId, []string{syncFile.FileId}); err != nil {
+		return err
+	}
+	for _, file := range models.GetSyncFilesByFileId(c.AccountId, c.FileId) {
+		if file.LocalFilePath == "" || !helpers.PathExists(file.LocalFilePath) {
+			continue
+		}
+		if err := os.Remove(file.LocalFilePath); err != nil {
+			helpers.AppLogger.Warnf("删除重复文件的STRM文件 %s 失败: %v", file.LocalFilePath, err)
+		}
+	}
+	return models.DeleteSyncFilesByFileId(c.AccountId, c.FileId)
+}
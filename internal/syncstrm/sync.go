@@ -35,18 +35,19 @@ type driverImpl interface {
 }
 
 type SyncStrm struct {
-	SyncDriver   driverImpl
-	Account      *models.Account // 网盘账号，如果是本地类型则为nil
-	Sync         *models.Sync    // 同步记录，Start方法会生成
-	SourcePath   string          // 来源路径
-	SourcePathId string
-	LastSyncAt   int64 // 最后同步时间
-	TmpSyncPath  bool
-	TargetPath   string
-	Config       SyncStrmConfig
-	Context      context.Context
-	Cancel       context.CancelFunc
-	FullSync     bool // 是否是全量同步
+	SyncDriver    driverImpl
+	Account       *models.Account // 网盘账号，如果是本地类型则为nil
+	Sync          *models.Sync    // 同步记录，Start方法会生成
+	SourcePath    string          // 来源路径
+	SourcePathId  string
+	LastSyncAt    int64 // 最后同步时间
+	TmpSyncPath   bool
+	TargetPath    string
+	Config        SyncStrmConfig
+	Context       context.Context
+	Cancel        context.CancelFunc
+	FullSync      bool // 是否是全量同步
+	ConfirmDelete bool // 是否已确认本次同步可以超过删除阈值
 
 	// 路径队列
 	PathWorkerMax int64
@@ -69,6 +70,10 @@ type SyncStrm struct {
 	sync115 *Sync115
 
 	memSyncCache *MemorySyncCache // 同步缓存
+
+	preview *SyncPreview // 预览模式的变更计划，不为nil时不写入本地文件、不操作网盘、不添加上传下载任务
+
+	localDeletes []string // 对比本地文件时收集的网盘已不存在的本地文件，检查删除阈值后统一删除
 }
 
 type pathQueueItem struct {
@@ -79,27 +84,55 @@ type pathQueueItem struct {
 }
 
 func NewSyncStrm(account *models.Account, syncPathId uint, sourcePath, sourcePathId, targetPath string, config SyncStrmConfig, IsFullSync bool, lastSyncAt int64) *SyncStrm {
-	var syncDriver driverImpl
+	s := newSyncStrm(account, syncPathId, sourcePath, sourcePathId, targetPath, config, IsFullSync, lastSyncAt)
+	// 新增一条Sync
+	s.Sync = models.CreateSync(s.SyncPathId, s.SourcePath, s.SourcePathId, s.TargetPath)
+	if s.Sync == nil {
+		return nil
+	}
+	s.Sync.InitLogger()
+	s.SyncDriver.SetSyncStrm(s)
+	return s
+}
+
+// 根据账号类型创建网盘驱动
+func newSyncDriver(account *models.Account) driverImpl {
 	switch account.SourceType {
 	case models.SourceType115:
-		syncDriver = NewOpen115Driver(account.Get115Client())
+		return NewOpen115Driver(account.Get115Client())
 	case models.SourceTypeOpenList:
-		syncDriver = NewOpenListDriver(account.GetOpenListClient())
+		return NewOpenListDriver(account.GetOpenListClient())
 	case models.SourceTypeLocal:
-		syncDriver = NewLocalDriver()
+		return NewLocalDriver()
 	case models.SourceTypeBaiduPan:
-		syncDriver = NewBaiduPanDriver(account.GetBaiDuPanClient())
+		return NewBaiduPanDriver(account.GetBaiDuPanClient())
+	case models.SourceType123:
+		return NewOpen123Driver(account.GetOpen123Client())
+	case models.SourceTypeWebDav:
+		return NewWebDavDriver(account.GetWebDavClient())
+	case models.SourceTypeS3:
+		return NewS3Driver(account.GetS3Client())
 	}
+	return nil
+}
+
+// 创建同步器，不创建同步记录
+func newSyncStrm(account *models.Account, syncPathId uint, sourcePath, sourcePathId, targetPath string, config SyncStrmConfig, IsFullSync bool, lastSyncAt int64) *SyncStrm {
+	syncDriver := newSyncDriver(account)
 	pathWorkerMax := int64(models.SettingsGlobal.FileDetailThreads)
 	switch account.SourceType {
 	case models.SourceTypeLocal:
 		pathWorkerMax = int64(10) // 本地类型（CD2会自己限制并发），限制为10个并发
 	case models.SourceTypeOpenList:
 		pathWorkerMax = int64(models.SettingsGlobal.OpenlistQPS)
+	case models.SourceTypeWebDav, models.SourceTypeS3:
+		pathWorkerMax = int64(models.SettingsGlobal.FileDetailThreads)
 	case models.SourceType115:
 		pathWorkerMax = int64(models.SettingsGlobal.FileDetailThreads)
 	case models.SourceTypeBaiduPan:
 		pathWorkerMax = int64(models.SettingsGlobal.FileDetailThreads)
+	case models.SourceType123:
+		pathWorkerMax = int64(models.SettingsGlobal.FileDetailThreads)
 	}
 	if pathWorkerMax <= 1 {
 		pathWorkerMax = 2 // 最小为2，否则并发操作会出错
@@ -138,13 +171,6 @@ func NewSyncStrm(account *models.Account, syncPathId uint, sourcePath, sourcePat
 		s.SyncPathId = uint(time.Now().UnixNano())
 		s.TmpSyncPath = true
 	}
-	// 新增一条Sync
-	s.Sync = models.CreateSync(s.SyncPathId, s.SourcePath, s.SourcePathId, s.TargetPath)
-	if s.Sync == nil {
-		return nil
-	}
-	s.Sync.InitLogger()
-	s.SyncDriver.SetSyncStrm(s)
 	return s
 }
 
@@ -159,7 +185,16 @@ func NewSyncStrmFromSyncPath(syncPath *models.SyncPath) *SyncStrm {
 	} else {
 		account = &models.Account{SourceType: models.SourceTypeLocal}
 	}
-	config := SyncStrmConfig{
+	s := NewSyncStrm(account, syncPath.ID, syncPath.RemotePath, syncPath.BaseCid, syncPath.LocalPath, getSyncPathConfig(syncPath), syncPath.IsFullSync, syncPath.LastSyncAt)
+	if s != nil {
+		s.ConfirmDelete = syncPath.ConfirmDelete
+	}
+	return s
+}
+
+// 使用同步目录的设置生成STRM配置
+func getSyncPathConfig(syncPath *models.SyncPath) SyncStrmConfig {
+	return SyncStrmConfig{
 		EnableDownloadMeta:    int64(syncPath.GetDownloadMeta()),
 		MinVideoSize:          syncPath.GetMinVideoSize(),
 		VideoExt:              syncPath.GetVideoExt(),
@@ -170,8 +205,9 @@ func NewSyncStrmFromSyncPath(syncPath *models.SyncPath) *SyncStrm {
 		DelEmptyLocalDir:      syncPath.GetDeleteDir() == 1,
 		CheckMetaMtime:        syncPath.GetCheckMetaMtime(),
 		StrmBaseUrl:           syncPath.GetStrmBaseUrl(),
+		MaxDeleteCount:        syncPath.MaxDeleteCount,
+		MaxDeletePercent:      syncPath.MaxDeletePercent,
 	}
-	return NewSyncStrm(account, syncPath.ID, syncPath.RemotePath, syncPath.BaseCid, syncPath.LocalPath, config, syncPath.IsFullSync, syncPath.LastSyncAt)
 }
 
 // 直接同步某个路径（可以是目录，也可以是文件）
@@ -260,6 +296,18 @@ func (s *SyncStrm) Start() error {
 		return err
 	default:
 	}
+	// 开始添加需要下载的文件到下载队列
+	s.Sync.Logger.Info("开始将要下载的任务添加到下载队列")
+	s.AddDownloadTaskFromMemCache()
+	s.Sync.Logger.Infof("开始对比本地文件和临时表中的文件，收集多余的本地文件")
+	if err := s.compareLocalFilesWithTempTable(); err != nil {
+		return err
+	}
+	// 删除数量超过阈值时中止同步，保留本地文件
+	if err := s.checkDeleteLimit(); err != nil {
+		s.Sync.Failed(err.Error())
+		return err
+	}
 	// 处理完所有路径和文件后，更新最后同步时间
 	if s.SyncPathId > 0 {
 		syncPath := models.GetSyncPathById(s.SyncPathId)
@@ -267,13 +315,8 @@ func (s *SyncStrm) Start() error {
 			syncPath.UpdateLastSync()
 		}
 	}
-	// 开始添加需要下载的文件到下载队列
-	s.Sync.Logger.Info("开始将要下载的任务添加到下载队列")
-	s.AddDownloadTaskFromMemCache()
-	s.Sync.Logger.Infof("开始对比本地文件和临时表中的文件，删除多余的本地文件")
-	if err := s.compareLocalFilesWithTempTable(); err != nil {
-		return err
-	}
+	s.Sync.Logger.Infof("开始删除网盘已不存在的 %d 个本地文件", len(s.localDeletes))
+	s.applyLocalDeletes()
 	s.Sync.NewMeta = int(s.NewMeta)
 	s.Sync.NewStrm = int(s.NewStrm)
 	s.Sync.NewUpload = int(s.NewUpload)
@@ -286,6 +329,10 @@ func (s *SyncStrm) Start() error {
 		if s.FullSync {
 			db.Db.Model(&models.SyncPath{}).Where("id = ?", s.SyncPathId).Update("is_full_sync", false)
 		}
+		// 删除确认只对一次同步有效
+		if s.ConfirmDelete {
+			db.Db.Model(&models.SyncPath{}).Where("id = ?", s.SyncPathId).Update("confirm_delete", false)
+		}
 		db.Db.Model(&models.SyncPath{}).Where("id = ?", s.SyncPathId).Update("last_sync_at", s.Sync.FinishAt)
 		// 触发刷新Emby媒体库，延迟30s，等待文件下载完成
 		go func() {
@@ -318,6 +365,11 @@ func (s *SyncStrm) processNetFile(file *SyncFileCache) error {
 		if err == nil {
 			// 如果SyncFiles存在，检查是否需要重命名，所在目录必须相同才可以重命名，否则只能走删除重建流程
 			if existingFile.FileName != file.FileName && existingFile.Path == file.Path {
+				if s.preview != nil {
+					// 预览模式只记录重命名，重命名后的文件已经存在，不需要再生成STRM或下载
+					s.preview.add(SyncPreviewActionRename, localFilePath, file.GetFullRemotePath(), fmt.Sprintf("原文件: %s", existingFile.LocalFilePath))
+					return nil
+				}
 				// 需要重命名
 				err := os.Rename(existingFile.LocalFilePath, localFilePath)
 				if err != nil {
@@ -343,6 +395,18 @@ func (s *SyncStrm) processNetFile(file *SyncFileCache) error {
 				// err := models.AddDownloadTaskFromSyncFile(file)
 
 			}
+		} else if (file.SourceType == models.SourceTypeWebDav || file.SourceType == models.SourceTypeS3) && s.Config.EnableDownloadMeta == 1 && file.ETag != "" && existingFile.ETag != "" && file.ETag != existingFile.ETag {
+			// WebDAV和S3文件的ETag变化说明内容已经修改，重新下载
+			if s.preview != nil {
+				s.preview.add(SyncPreviewActionDownloadMeta, localFilePath, file.GetFullRemotePath(), "网盘文件ETag变化")
+				return nil
+			}
+			s.Sync.Logger.Infof("元数据文件 %s 的ETag已变化 %s => %s，重新下载", file.GetFullRemotePath(), existingFile.ETag, file.ETag)
+			if err := os.Remove(localFilePath); err != nil {
+				s.Sync.Logger.Errorf("删除本地元数据文件 %s 失败: %v", localFilePath, err)
+				return nil
+			}
+			s.AddDownloadTaskTemp(file)
 		}
 		return nil
 	}
@@ -389,6 +453,10 @@ func (s *SyncStrm) AddDownloadTaskFromMemCache() {
 			// 已经存在下载任务，跳过
 			continue
 		}
+		if s.preview != nil {
+			s.preview.add(SyncPreviewActionDownloadMeta, file.GetLocalFilePath(s.TargetPath, s.SourcePath), file.GetFullRemotePath(), "本地不存在")
+			continue
+		}
 		// 添加下载任务
 		err := models.AddDownloadTaskFromSyncFile(file.GetSyncFile(s, s.Account.BaseUrl))
 		if err == nil {
@@ -430,6 +498,10 @@ func (s *SyncStrm) compareLocalFilesWithTempTable() error {
 							return nil
 						}
 						if len(dirEntries) == 0 {
+							if s.preview != nil {
+								s.preview.add(SyncPreviewActionDeleteLocalDir, path, "", "空目录")
+								return nil
+							}
 							os.Remove(path)
 							s.Sync.Logger.Infof("删除空目录 %s", path)
 						}
@@ -462,7 +534,7 @@ func (s *SyncStrm) compareLocalFilesWithTempTable() error {
 						return nil
 					}
 					// s.Sync.Logger.Warnf("本地文件在网盘不存在，删除本地STRM文件: %s", path)
-					s.RemoveFileAndCheckDirEmtry(path)
+					s.markLocalDelete(path)
 					return nil
 				}
 				if isMeta {
@@ -473,7 +545,7 @@ func (s *SyncStrm) compareLocalFilesWithTempTable() error {
 					}
 					// 如果选择删除，则检查是否存在，不存在则删除
 					if s.Config.NetNotFoundFileAction == models.SyncTreeItemMetaActionDelete && existsFile == nil {
-						s.RemoveFileAndCheckDirEmtry(path)
+						s.markLocalDelete(path)
 						return nil
 					}
 					// 如果允许上传，则检查是否需要上传（文件在网盘不存在）
@@ -502,9 +574,13 @@ func (s *SyncStrm) compareLocalFilesWithTempTable() error {
 						if existsPath == nil && parentDir != sourceRootPath {
 							if !isAllowedUploadDir {
 								s.Sync.Logger.Infof("父目录 %s 不存在网盘，进入删除流程 %s，", parentDir, path)
-								s.RemoveFileAndCheckDirEmtry(path)
+								s.markLocalDelete(path)
 								return nil
 							} else {
+								if s.preview != nil {
+									s.preview.add(SyncPreviewActionUploadMeta, path, "", "网盘不存在，需要创建父目录")
+									return nil
+								}
 								// 递归创建目录, 调用不同的driver
 								parentPathId, remotePath, err = s.SyncDriver.CreateDirRecursively(s.Context, parentDir)
 								if err != nil {
@@ -543,6 +619,10 @@ func (s *SyncStrm) compareLocalFilesWithTempTable() error {
 						if s.Account.SourceType != models.SourceType115 {
 							db115File.FileId = filepath.ToSlash(filepath.Join(db115File.Path, db115File.FileName))
 						}
+						if s.preview != nil {
+							s.preview.add(SyncPreviewActionUploadMeta, path, filepath.ToSlash(filepath.Join(remotePath, info.Name())), "网盘不存在")
+							return nil
+						}
 						models.AddUploadTaskFromSyncFile(db115File)
 						return nil
 					}
@@ -554,6 +634,10 @@ func (s *SyncStrm) compareLocalFilesWithTempTable() error {
 						// 2. 添加下载任务
 						if localMTime < existsFile.MTime {
 							s.Sync.Logger.Infof("本地元数据文件 %s 由于修改时间比网盘旧 %d < %d 所以需要重新下载", path, localMTime, existsFile.MTime)
+							if s.preview != nil {
+								s.preview.add(SyncPreviewActionDownloadMeta, path, existsFile.GetFullRemotePath(), "网盘文件比本地新")
+								return nil
+							}
 							// 1. 删除本地文件
 							s.RemoveFileAndCheckDirEmtry(path)
 
@@ -565,6 +649,10 @@ func (s *SyncStrm) compareLocalFilesWithTempTable() error {
 						if localMTime > existsFile.MTime && s.Config.NetNotFoundFileAction == models.SyncTreeItemMetaActionUpload {
 							// 本地比网盘新，需要删除网盘旧文件并上传新文件
 							s.Sync.Logger.Infof("本地元数据文件 %s 由于修改时间比网盘新 %d > %d 所以需要上传", path, localMTime, existsFile.MTime)
+							if s.preview != nil {
+								s.preview.add(SyncPreviewActionUploadMeta, path, existsFile.GetFullRemotePath(), "本地文件比网盘新，替换网盘文件")
+								return nil
+							}
 							// 1. 删除网盘旧文件
 							err := s.SyncDriver.DeleteFile(s.Context, existsFile.ParentId, []string{existsFile.GetFileId()})
 							if err != nil {
@@ -625,7 +713,9 @@ func (s *SyncStrm) handleTempTableDiff() error {
 					"local_file_path": syncFileCache.LocalFilePath,
 					"thumb_url":       syncFileCache.ThumbUrl,
 					"openlist_sign":   syncFileCache.OpenlistSign,
+					"e_tag":           syncFileCache.ETag,
 					"sha1":            syncFileCache.Sha1,
+					"md5":             syncFileCache.Md5,
 					"parent_id":       syncFileCache.ParentId,
 				}
 				err := db.Db.Model(&models.SyncFile{}).Where("id = ?", file.ID).Updates(udpateData).Error
@@ -0,0 +1,58 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/models"
+	"fmt"
+)
+
+// 记录一个网盘已经不存在、需要删除的本地文件
+// 对比本地文件时只收集，检查删除阈值后再统一删除，预览模式只记录变更
+func (s *SyncStrm) markLocalDelete(path string) {
+	if s.preview != nil {
+		s.preview.add(SyncPreviewActionDeleteLocal, path, "", "网盘不存在")
+		return
+	}
+	s.localDeletes = append(s.localDeletes, path)
+}
+
+// 删除收集到的本地文件
+func (s *SyncStrm) applyLocalDeletes() {
+	for _, path := range s.localDeletes {
+		if err := s.RemoveFileAndCheckDirEmtry(path); err != nil {
+			s.Sync.Logger.Warnf("删除本地文件 %s 失败: %v", path, err)
+		}
+	}
+	s.localDeletes = nil
+}
+
+// 检查收集到的待删除本地文件是否超过阈值
+// 网盘接口异常或者OpenList token过期时，网盘文件列表可能为空，此时不应该删除本地的STRM和元数据文件
+func (s *SyncStrm) checkDeleteLimit() error {
+	if s.TmpSyncPath || s.ConfirmDelete {
+		return nil
+	}
+	deleteCount := int64(len(s.localDeletes))
+	if deleteCount == 0 || (s.Config.MaxDeleteCount <= 0 && s.Config.MaxDeletePercent <= 0) {
+		return nil
+	}
+	var knownCount int64
+	if s.Config.MaxDeletePercent > 0 {
+		knownCount = models.CountFilesBySyncPathId(s.SyncPathId)
+	}
+	if err := deleteLimitError(deleteCount, knownCount, s.Config.MaxDeleteCount, s.Config.MaxDeletePercent); err != nil {
+		return err
+	}
+	s.Sync.Logger.Infof("本次同步将删除 %d 个本地文件，未超过删除阈值", deleteCount)
+	return nil
+}
+
+// 删除数量超过最大数量，或者占已知文件的比例超过最大百分比时返回错误，阈值为0表示不限制
+func deleteLimitError(deleteCount, knownCount, maxDeleteCount, maxDeletePercent int64) error {
+	if maxDeleteCount > 0 && deleteCount > maxDeleteCount {
+		return fmt.Errorf("本次同步将删除 %d 个本地文件，超过了最大删除数量 %d，已中止同步并保留所有文件，请检查网盘是否正常，确认需要删除后在同步目录中确认删除并重新同步", deleteCount, maxDeleteCount)
+	}
+	if maxDeletePercent > 0 && knownCount > 0 && deleteCount*100 > knownCount*maxDeletePercent {
+		return fmt.Errorf("本次同步将删除 %d 个本地文件，占已知文件 %d 的比例超过了 %d%%，已中止同步并保留所有文件，请检查网盘是否正常，确认需要删除后在同步目录中确认删除并重新同步", deleteCount, knownCount, maxDeletePercent)
+	}
+	return nil
+}
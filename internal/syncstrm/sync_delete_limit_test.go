@@ -0,0 +1,78 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeleteLimitError(t *testing.T) {
+	cases := []struct {
+		deleteCount, knownCount, maxCount, maxPercent int64
+		exceeded                                      bool
+	}{
+		{10, 100, 0, 0, false},
+		{10, 100, 10, 0, false},
+		{11, 100, 10, 0, true},
+		{10, 100, 0, 10, false},
+		{11, 100, 0, 10, true},
+		{11, 0, 0, 10, false},
+	}
+	for _, c := range cases {
+		err := deleteLimitError(c.deleteCount, c.knownCount, c.maxCount, c.maxPercent)
+		if (err != nil) != c.exceeded {
+			t.Errorf("deleteLimitError(%d, %d, %d, %d) = %v", c.deleteCount, c.knownCount, c.maxCount, c.maxPercent, err)
+		}
+	}
+}
+
+func TestLocalDeletesCheckedBeforeApply(t *testing.T) {
+	dir := t.TempDir()
+	oldConfigDir := helpers.ConfigDir
+	helpers.ConfigDir = dir
+	t.Cleanup(func() {
+		helpers.ConfigDir = oldConfigDir
+	})
+	files := []string{filepath.Join(dir, "a.strm"), filepath.Join(dir, "b.strm")}
+	for _, file := range files {
+		if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := &SyncStrm{
+		Config: SyncStrmConfig{MaxDeleteCount: 1},
+		Sync:   &models.Sync{Logger: helpers.NewLogger("test.log", false, false)},
+	}
+	for _, file := range files {
+		s.markLocalDelete(file)
+	}
+	if err := s.checkDeleteLimit(); err == nil {
+		t.Fatalf("删除 %d 个文件应该超过阈值 1", len(files))
+	}
+	for _, file := range files {
+		if !helpers.PathExists(file) {
+			t.Fatalf("超过阈值时不应该删除文件 %s", file)
+		}
+	}
+	s.ConfirmDelete = true
+	if err := s.checkDeleteLimit(); err != nil {
+		t.Fatalf("确认删除后不应该检查阈值: %v", err)
+	}
+	s.applyLocalDeletes()
+	for _, file := range files {
+		if helpers.PathExists(file) {
+			t.Errorf("文件 %s 应该已经删除", file)
+		}
+	}
+	if len(s.localDeletes) != 0 {
+		t.Errorf("删除后应该清空待删除列表: %v", s.localDeletes)
+	}
+	// 预览模式只记录变更
+	s.preview = &SyncPreview{}
+	s.markLocalDelete(files[0])
+	if len(s.localDeletes) != 0 || s.preview.Counts()[SyncPreviewActionDeleteLocal] != 1 {
+		t.Errorf("预览模式不应该收集待删除文件")
+	}
+}
@@ -0,0 +1,217 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 预览中的变更类型
+type SyncPreviewAction string
+
+const (
+	SyncPreviewActionCreateStrm     SyncPreviewAction = "create_strm"      // 新建STRM文件
+	SyncPreviewActionUpdateStrm     SyncPreviewAction = "update_strm"      // 重写STRM文件
+	SyncPreviewActionRename         SyncPreviewAction = "rename"           // 重命名本地文件
+	SyncPreviewActionDownloadMeta   SyncPreviewAction = "download_meta"    // 下载元数据
+	SyncPreviewActionUploadMeta     SyncPreviewAction = "upload_meta"      // 上传元数据
+	SyncPreviewActionDeleteLocal    SyncPreviewAction = "delete_local"     // 删除本地文件
+	SyncPreviewActionDeleteLocalDir SyncPreviewAction = "delete_local_dir" // 删除本地空目录
+)
+
+type SyncPreviewStatus string
+
+const (
+	SyncPreviewStatusRunning   SyncPreviewStatus = "running"
+	SyncPreviewStatusCompleted SyncPreviewStatus = "completed"
+	SyncPreviewStatusFailed    SyncPreviewStatus = "failed"
+)
+
+type SyncPreviewItem struct {
+	Action     SyncPreviewAction `json:"action"`
+	LocalPath  string            `json:"local_path"`  // 本地文件路径
+	RemotePath string            `json:"remote_path"` // 网盘文件路径，删除本地文件时为空
+	Reason     string            `json:"reason"`      // 变更原因
+}
+
+type SyncPreviewState struct {
+	SyncPathId uint              `json:"sync_path_id"`
+	Status     SyncPreviewStatus `json:"status"`
+	FailReason string            `json:"fail_reason"`
+	StartAt    int64             `json:"start_at"`
+	FinishAt   int64             `json:"finish_at"`
+}
+
+// 同步预览（dry-run），记录一次同步将要执行的所有变更
+type SyncPreview struct {
+	SyncPreviewState
+
+	mu    sync.RWMutex
+	items []*SyncPreviewItem
+}
+
+var (
+	syncPreviews     = make(map[uint]*SyncPreview)
+	syncPreviewsLock sync.Mutex
+)
+
+func (p *SyncPreview) add(action SyncPreviewAction, localPath, remotePath, reason string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.items = append(p.items, &SyncPreviewItem{
+		Action:     action,
+		LocalPath:  localPath,
+		RemotePath: remotePath,
+		Reason:     reason,
+	})
+}
+
+func (p *SyncPreview) finish(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.FinishAt = time.Now().Unix()
+	if err != nil {
+		p.Status = SyncPreviewStatusFailed
+		p.FailReason = err.Error()
+		return
+	}
+	p.Status = SyncPreviewStatusCompleted
+}
+
+// 按变更类型统计数量
+func (p *SyncPreview) Counts() map[SyncPreviewAction]int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	counts := map[SyncPreviewAction]int{
+		SyncPreviewActionCreateStrm:     0,
+		SyncPreviewActionUpdateStrm:     0,
+		SyncPreviewActionRename:         0,
+		SyncPreviewActionDownloadMeta:   0,
+		SyncPreviewActionUploadMeta:     0,
+		SyncPreviewActionDeleteLocal:    0,
+		SyncPreviewActionDeleteLocalDir: 0,
+	}
+	for _, item := range p.items {
+		counts[item.Action]++
+	}
+	return counts
+}
+
+// 分页查询变更，action为空则返回所有类型
+func (p *SyncPreview) GetItems(action SyncPreviewAction, page, pageSize int) ([]*SyncPreviewItem, int) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	items := p.items
+	if action != "" {
+		items = make([]*SyncPreviewItem, 0)
+		for _, item := range p.items {
+			if item.Action == action {
+				items = append(items, item)
+			}
+		}
+	}
+	total := len(items)
+	start := (page - 1) * pageSize
+	if start >= total {
+		return []*SyncPreviewItem{}, total
+	}
+	end := min(start+pageSize, total)
+	return items[start:end], total
+}
+
+// 获取状态快照，避免调用方读取时和预览任务并发写入
+func (p *SyncPreview) Snapshot() SyncPreviewState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.SyncPreviewState
+}
+
+// 查询同步目录最近一次的预览结果
+func GetSyncPreview(syncPathId uint) *SyncPreview {
+	syncPreviewsLock.Lock()
+	defer syncPreviewsLock.Unlock()
+	return syncPreviews[syncPathId]
+}
+
+// 启动同步目录的预览，同一个同步目录同时只能有一个预览在运行
+// 预览会完整对比网盘和本地文件，但不会写入本地文件、操作网盘或添加上传下载任务
+func StartSyncPreview(syncPath *models.SyncPath) (*SyncPreview, error) {
+	syncPreviewsLock.Lock()
+	defer syncPreviewsLock.Unlock()
+	if old, ok := syncPreviews[syncPath.ID]; ok && old.Snapshot().Status == SyncPreviewStatusRunning {
+		return nil, errors.New("该同步目录的预览正在运行")
+	}
+	account := &models.Account{SourceType: models.SourceTypeLocal}
+	if syncPath.AccountId != 0 {
+		var err error
+		account, err = models.GetAccountById(syncPath.AccountId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s := newSyncStrm(account, syncPath.ID, syncPath.RemotePath, syncPath.BaseCid, syncPath.LocalPath, getSyncPathConfig(syncPath), syncPath.IsFullSync, syncPath.LastSyncAt)
+	// 预览使用不入库的同步记录，日志写入应用日志
+	s.Sync = &models.Sync{
+		SyncPathId: s.SyncPathId,
+		LocalPath:  s.TargetPath,
+		RemotePath: s.SourcePath,
+		BaseCid:    s.SourcePathId,
+		Logger:     helpers.AppLogger,
+	}
+	s.SyncDriver.SetSyncStrm(s)
+	s.preview = &SyncPreview{
+		SyncPreviewState: SyncPreviewState{
+			SyncPathId: syncPath.ID,
+			Status:     SyncPreviewStatusRunning,
+			StartAt:    time.Now().Unix(),
+		},
+		items: make([]*SyncPreviewItem, 0),
+	}
+	syncPreviews[syncPath.ID] = s.preview
+	go func() {
+		err := s.runPreview()
+		if err != nil {
+			helpers.AppLogger.Errorf("同步目录 %d 预览失败: %v", syncPath.ID, err)
+		} else {
+			helpers.AppLogger.Infof("同步目录 %d 预览完成", syncPath.ID)
+		}
+		s.preview.finish(err)
+	}()
+	return s.preview, nil
+}
+
+// 执行和Start相同的对比流程，所有写操作都由preview拦截并记录
+func (s *SyncStrm) runPreview() error {
+	newPathId, err := s.SyncDriver.GetPathIdByPath(s.Context, s.SourcePath)
+	if err != nil {
+		return err
+	}
+	s.SourcePathId = newPathId
+	if !s.checkPathExists(s.TargetPath) {
+		return fmt.Errorf("目标路径 %s 不存在", s.TargetPath)
+	}
+	switch s.Account.SourceType {
+	case models.SourceType115:
+		s.Start115Sync()
+	case models.SourceTypeBaiduPan:
+		s.StartBaiduPanSync()
+	default:
+		s.StartOther()
+	}
+	select {
+	case <-s.Context.Done():
+		return fmt.Errorf("预览被取消: %v", s.Context.Err())
+	case err := <-s.PathErrChan:
+		return fmt.Errorf("路径队列处理失败: %v", err)
+	default:
+	}
+	s.AddDownloadTaskFromMemCache()
+	if err := s.compareLocalFilesWithTempTable(); err != nil {
+		return err
+	}
+	s.Cancel()
+	return nil
+}
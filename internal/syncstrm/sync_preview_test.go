@@ -0,0 +1,27 @@
+package syncstrm
+
+import "testing"
+
+func TestSyncPreviewItems(t *testing.T) {
+	p := &SyncPreview{}
+	for i := 0; i < 5; i++ {
+		p.add(SyncPreviewActionCreateStrm, "a.strm", "a.mkv", "")
+	}
+	p.add(SyncPreviewActionDeleteLocal, "b.strm", "", "网盘不存在")
+	counts := p.Counts()
+	if counts[SyncPreviewActionCreateStrm] != 5 || counts[SyncPreviewActionDeleteLocal] != 1 || counts[SyncPreviewActionUploadMeta] != 0 {
+		t.Errorf("变更数量统计错误: %+v", counts)
+	}
+	items, total := p.GetItems("", 2, 4)
+	if total != 6 || len(items) != 2 {
+		t.Errorf("分页错误: total=%d len=%d", total, len(items))
+	}
+	items, total = p.GetItems(SyncPreviewActionDeleteLocal, 1, 10)
+	if total != 1 || len(items) != 1 || items[0].LocalPath != "b.strm" {
+		t.Errorf("按类型筛选错误: total=%d items=%+v", total, items)
+	}
+	items, _ = p.GetItems("", 3, 10)
+	if len(items) != 0 {
+		t.Errorf("超出范围的页应该为空: %+v", items)
+	}
+}
@@ -31,6 +31,15 @@ func (s *SyncStrm) ProcessStrmFile(sf *SyncFileCache) error {
 	}
 	// localFilePath := sf.GetLocalFilePath()
 	strmFullPath := sf.GetLocalFilePath(s.TargetPath, s.SourcePath)
+	if s.preview != nil {
+		if helpers.PathExists(strmFullPath) {
+			s.preview.add(SyncPreviewActionUpdateStrm, strmFullPath, sf.GetFullRemotePath(), "STRM内容需要更新")
+		} else {
+			s.preview.add(SyncPreviewActionCreateStrm, strmFullPath, sf.GetFullRemotePath(), "")
+		}
+		atomic.AddInt64(&s.NewStrm, 1)
+		return nil
+	}
 	strmContent := s.SyncDriver.MakeStrmContent(sf)
 	// 写入文件并设置所有者
 	err := helpers.WriteFileWithPerm(strmFullPath, []byte(strmContent), 0777)
@@ -83,7 +92,26 @@ func (s *SyncStrm) CompareStrm(st *SyncFileCache) int {
 			return 0
 		}
 	}
-	if st.SourceType == models.SourceType115 || st.SourceType == models.SourceTypeBaiduPan {
+	if st.SourceType == models.SourceTypeWebDav {
+		if before, ok := strings.CutSuffix(s.Config.StrmBaseUrl, "/"); ok {
+			s.Config.StrmBaseUrl = before
+		}
+		if strmData.BaseUrl != s.Config.StrmBaseUrl {
+			s.Sync.Logger.Warnf("文件 %s 的STRM内容的主机名称与本地不一致, 本地: %s, 远程: %s", st.GetFullRemotePath(), s.Config.StrmBaseUrl, strmData.BaseUrl)
+			return 0
+		}
+		// pickcode是文件的完整路径，签名和地址、账号密码相关，账号变化后需要重新生成
+		filePath := st.GetFileId()
+		if strmData.PickCode != filePath || strmData.UserId != s.Account.UserId || strmData.Sign != s.Account.SignWebDavPath(filePath) {
+			s.Sync.Logger.Warnf("文件 %s 的STRM内容的路径或签名与本地不一致，重新生成", st.GetFullRemotePath())
+			return 0
+		}
+		if !strings.HasSuffix(strmData.UrlPath, filepath.Ext(st.FileName)) {
+			s.Sync.Logger.Warnf("文件 %s 的STRM内容的Url路径 %s 没有以 %s 结尾，重新生成", st.GetFullRemotePath(), strmData.UrlPath, filepath.Ext(st.FileName))
+			return 0
+		}
+	}
+	if st.SourceType == models.SourceType115 || st.SourceType == models.SourceTypeBaiduPan || st.SourceType == models.SourceType123 || st.SourceType == models.SourceTypeS3 {
 		// 比较路径是否相同
 		if s.Config.StrmUrlNeedPath == 1 {
 			stPath := filepath.ToSlash(filepath.Join(st.Path, st.FileName))
@@ -115,6 +143,10 @@ func (s *SyncStrm) CompareStrm(st *SyncFileCache) int {
 			s.Sync.Logger.Warnf("文件 %s 的STRM内容的用户ID与本地不一致, 本地: %s, 远程: %s", filepath.Join(st.Path, st.FileName), s.Account.UserId, strmData.UserId)
 			return 0
 		}
+		if st.SourceType == models.SourceTypeS3 && strmData.Sign != s.Account.SignS3Path(strmData.PickCode) {
+			s.Sync.Logger.Warnf("文件 %s 的STRM内容的签名与本地不一致，重新生成", filepath.Join(st.Path, st.FileName))
+			return 0
+		}
 		// 比较UrlPath，如果是iso文件，UrlPath必须以.iso结尾
 		ext := filepath.Ext(st.FileName)
 		if !strings.HasSuffix(strmData.UrlPath, ext) {
@@ -46,6 +46,10 @@ func (s *SyncStrm) MakeFullLocalPath(file *models.SyncFile) string {
 }
 
 func (s *SyncStrm) RemoveFileAndCheckDirEmtry(filePath string) error {
+	if s.preview != nil {
+		s.preview.add(SyncPreviewActionDeleteLocal, filePath, "", "网盘不存在")
+		return nil
+	}
 	// 删除文件
 	if err := os.Remove(filePath); err != nil {
 		return fmt.Errorf("删除文件失败: %w", err)
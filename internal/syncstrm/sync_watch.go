@@ -0,0 +1,184 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/v115open"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// NewWatchSyncStrm 创建处理实时变化的同步器
+// 实时变化很频繁，使用不入库的同步记录，日志写入应用日志
+func NewWatchSyncStrm(syncPath *models.SyncPath) *SyncStrm {
+	if syncPath.SourceType != models.SourceTypeLocal {
+		return nil
+	}
+	s := newSyncStrm(&models.Account{SourceType: models.SourceTypeLocal}, syncPath.ID, syncPath.RemotePath, syncPath.BaseCid, syncPath.LocalPath, getSyncPathConfig(syncPath), false, syncPath.LastSyncAt)
+	s.ConfirmDelete = syncPath.ConfirmDelete
+	s.Sync = &models.Sync{
+		SyncPathId: s.SyncPathId,
+		LocalPath:  s.TargetPath,
+		RemotePath: s.SourcePath,
+		BaseCid:    s.SourcePathId,
+		Logger:     helpers.AppLogger,
+	}
+	s.SyncDriver.SetSyncStrm(s)
+	return s
+}
+
+// 处理本地同步源的文件变化事件（实时监控模式）
+// 新增或修改的文件直接走processNetFile生成strm或者添加下载任务
+// 删除或移走的文件删除对应的strm或元数据文件，和完整同步一样受删除阈值限制
+// 不更新SyncFile表，下一次完整同步时会补齐
+func (s *SyncStrm) ProcessLocalEvents(events []helpers.FolderEvent) error {
+	if s.Account.SourceType != models.SourceTypeLocal {
+		return fmt.Errorf("只有本地同步源支持实时监控")
+	}
+	sourceRoot := filepath.ToSlash(filepath.Clean(s.SourcePath))
+	removedDirs := make([]string, 0)
+	for _, event := range events {
+		path := filepath.ToSlash(filepath.Clean(event.Path))
+		relPath, err := filepath.Rel(sourceRoot, path)
+		if err != nil || relPath == "." || strings.HasPrefix(relPath, "..") {
+			continue
+		}
+		if s.IsExcludePath(filepath.ToSlash(relPath)) {
+			s.Sync.Logger.Infof("文件 %s 被排除，跳过", path)
+			continue
+		}
+		if event.Removed {
+			removedDirs = append(removedDirs, s.removeLocalTarget(path)...)
+			continue
+		}
+		if event.IsDir {
+			// 新目录下的文件会作为单独的事件传入
+			continue
+		}
+		stat, err := os.Stat(path)
+		if err != nil {
+			s.Sync.Logger.Warnf("获取文件 %s 信息失败，跳过，错误: %v", path, err)
+			continue
+		}
+		atomic.AddInt64(&s.TotalFile, 1)
+		fileItem := &SyncFileCache{
+			ParentId:   filepath.ToSlash(filepath.Dir(path)),
+			FileName:   filepath.Base(path),
+			FileType:   v115open.TypeFile,
+			FileSize:   stat.Size(),
+			MTime:      stat.ModTime().Unix(),
+			SourceType: models.SourceTypeLocal,
+		}
+		if !s.ValidFile(fileItem) {
+			continue
+		}
+		fileItem.GetLocalFilePath(s.TargetPath, s.SourcePath)
+		s.memSyncCache.Insert(fileItem)
+		if err := s.processNetFile(fileItem); err != nil {
+			s.Sync.Logger.Errorf("处理文件 %s 失败: %v", path, err)
+		}
+	}
+	// 元数据需要添加下载（复制）任务
+	s.AddDownloadTaskFromMemCache()
+	// 删除数量超过阈值时保留本地文件
+	if err := s.checkDeleteLimit(); err != nil {
+		s.localDeletes = nil
+		return err
+	}
+	s.applyLocalDeletes()
+	for _, dir := range removedDirs {
+		s.removeEmptyDirs(dir)
+	}
+	return nil
+}
+
+// 收集来源路径对应的本地文件，来源已经不存在，无法判断是文件还是目录，按目标路径的实际情况处理
+// 返回被删除或移走的目录，文件删除后再清理其中的空目录
+func (s *SyncStrm) removeLocalTarget(sourcePath string) []string {
+	relPath, err := filepath.Rel(s.SourcePath, sourcePath)
+	if err != nil {
+		return nil
+	}
+	targetPath := filepath.ToSlash(filepath.Join(s.TargetPath, relPath))
+	candidates := []string{targetPath}
+	if s.IsValidVideoExt(targetPath) {
+		ext := filepath.Ext(targetPath)
+		candidates = append(candidates, strings.TrimSuffix(targetPath, ext)+".strm")
+	}
+	dirs := make([]string, 0)
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err != nil {
+			continue
+		}
+		if !info.IsDir() {
+			if s.canDeleteLocalFile(candidate) {
+				s.markLocalDelete(candidate)
+			}
+			continue
+		}
+		// 目录被删除或移走，删除目录下的STRM文件和按设置需要删除的元数据文件
+		filepath.Walk(candidate, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() && s.canDeleteLocalFile(path) {
+				s.markLocalDelete(path)
+			}
+			return nil
+		})
+		dirs = append(dirs, candidate)
+	}
+	return dirs
+}
+
+// 来源已经不存在时本地文件是否可以删除，和完整同步对比本地文件的规则一致
+// 只处理STRM文件和元数据文件，元数据按网盘不存在时的处理方式决定，Emby生成的其他文件和用户文件都保留
+func (s *SyncStrm) canDeleteLocalFile(path string) bool {
+	name := filepath.Base(path)
+	if filepath.Ext(name) == ".strm" {
+		return true
+	}
+	if !s.IsValidMetaExt(name) || s.Config.EnableDownloadMeta == 0 {
+		return false
+	}
+	switch s.Config.NetNotFoundFileAction {
+	case models.SyncTreeItemMetaActionDelete:
+		return true
+	case models.SyncTreeItemMetaActionUpload:
+		// 来源父目录还在时，完整同步会重新上传（复制）到来源，这里不删除
+		parentDir := filepath.Dir(path)
+		if slices.Contains(uploadDirNames, strings.ToLower(filepath.Base(parentDir))) {
+			return false
+		}
+		relPath, err := filepath.Rel(s.TargetPath, parentDir)
+		if err != nil {
+			return false
+		}
+		return !helpers.PathExists(filepath.Join(s.SourcePath, relPath))
+	}
+	return false
+}
+
+// 从最深的目录开始删除目录中的空目录
+func (s *SyncStrm) removeEmptyDirs(dir string) {
+	if !s.Config.DelEmptyLocalDir {
+		return
+	}
+	dirs := make([]string, 0)
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	slices.Reverse(dirs)
+	for _, d := range dirs {
+		if entries, err := os.ReadDir(d); err == nil && len(entries) == 0 {
+			if err := os.Remove(d); err == nil {
+				s.Sync.Logger.Infof("删除空目录成功: %s", d)
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package syncstrm
+
+import (
+	"Q115-STRM/internal/db"
+	"Q115-STRM/internal/helpers"
+	"Q115-STRM/internal/models"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// 使用临时的sqlite数据库和日志文件，测试结束后恢复
+func openWatchTestDb(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	conn, err := gorm.Open(sqlite.Open(filepath.Join(dir, "test.db")), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	if err := conn.AutoMigrate(&models.Sync{}, &models.DbDownloadTask{}); err != nil {
+		t.Fatalf("创建表失败: %v", err)
+	}
+	oldDb, oldConfigDir, oldLogger := db.Db, helpers.ConfigDir, helpers.AppLogger
+	db.Db = conn
+	helpers.ConfigDir = dir
+	helpers.AppLogger = helpers.NewLogger("test.log", false, false)
+	t.Cleanup(func() {
+		db.Db, helpers.ConfigDir, helpers.AppLogger = oldDb, oldConfigDir, oldLogger
+	})
+	return dir
+}
+
+func TestProcessLocalEventsDeleteLimit(t *testing.T) {
+	dir := openWatchTestDb(t)
+	source := filepath.ToSlash(filepath.Join(dir, "source"))
+	target := filepath.ToSlash(filepath.Join(dir, "target"))
+	files := []string{
+		filepath.Join(target, "Movie", "Movie.strm"),
+		filepath.Join(target, "Movie", "Movie.nfo"),
+		filepath.Join(target, "Movie", "extras", "Trailer.strm"),
+		filepath.Join(target, "Other.strm"),
+	}
+	// Emby生成的图片和用户自己的文件不是同步生成的，不能删除
+	keeps := []string{
+		filepath.Join(target, "Movie", "poster.jpg"),
+		filepath.Join(target, "Movie", "notes.txt"),
+	}
+	for _, file := range append(append([]string{}, files...), keeps...) {
+		if err := os.MkdirAll(filepath.Dir(file), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	syncPath := &models.SyncPath{
+		SourceType: models.SourceTypeLocal,
+		RemotePath: source,
+		BaseCid:    source,
+		LocalPath:  target,
+		SettingStrm: models.SettingStrm{
+			VideoExtArr:    []string{".mkv"},
+			MetaExtArr:     []string{".nfo"},
+			ExcludeNameArr: []string{"@eaDir"},
+			DownloadMeta:   1,
+			UploadMeta:     int(models.SyncTreeItemMetaActionDelete),
+			DeleteDir:      1,
+		},
+		MaxDeleteCount: 3,
+	}
+	syncPath.ID = 1
+	events := []helpers.FolderEvent{
+		{Path: filepath.Join(source, "Movie"), Removed: true},
+		{Path: filepath.Join(source, "Other.mkv"), Removed: true},
+	}
+	s := NewWatchSyncStrm(syncPath)
+	if err := s.ProcessLocalEvents(events); err == nil {
+		t.Fatalf("删除 %d 个文件应该超过阈值 3", len(files))
+	}
+	for _, file := range files {
+		if !helpers.PathExists(file) {
+			t.Fatalf("超过阈值时不应该删除文件 %s", file)
+		}
+	}
+	syncPath.ConfirmDelete = true
+	s = NewWatchSyncStrm(syncPath)
+	if err := s.ProcessLocalEvents(events); err != nil {
+		t.Fatalf("确认删除后处理实时变化失败: %v", err)
+	}
+	for _, file := range files {
+		if helpers.PathExists(file) {
+			t.Errorf("文件 %s 应该已经删除", file)
+		}
+	}
+	for _, file := range keeps {
+		if !helpers.PathExists(file) {
+			t.Errorf("不是同步生成的文件 %s 不应该删除", file)
+		}
+	}
+	if helpers.PathExists(filepath.Join(target, "Movie", "extras")) {
+		t.Errorf("被删除的目录中的空目录应该删除")
+	}
+	var total int64
+	db.Db.Model(&models.Sync{}).Count(&total)
+	if total != 0 {
+		t.Errorf("实时变化不应该创建同步记录，实际 %d 条", total)
+	}
+}
+
+func TestCanDeleteLocalFile(t *testing.T) {
+	dir := t.TempDir()
+	s := &SyncStrm{
+		SourcePath: filepath.Join(dir, "source"),
+		TargetPath: filepath.Join(dir, "target"),
+		Config:     SyncStrmConfig{MetaExt: []string{".nfo"}, EnableDownloadMeta: 1},
+	}
+	nfo := filepath.Join(s.TargetPath, "Movie", "Movie.nfo")
+	tests := []struct {
+		name   string
+		action models.SyncTreeItemMetaAction
+		path   string
+		want   bool
+	}{
+		{"STRM文件", models.SyncTreeItemMetaActionKeep, filepath.Join(s.TargetPath, "Movie", "Movie.strm"), true},
+		{"保留元数据", models.SyncTreeItemMetaActionKeep, nfo, false},
+		{"删除元数据", models.SyncTreeItemMetaActionDelete, nfo, true},
+		{"上传元数据且来源目录已删除", models.SyncTreeItemMetaActionUpload, nfo, true},
+		{"其他文件", models.SyncTreeItemMetaActionDelete, filepath.Join(s.TargetPath, "Movie", "poster.jpg"), false},
+	}
+	for _, tt := range tests {
+		s.Config.NetNotFoundFileAction = tt.action
+		if got := s.canDeleteLocalFile(tt.path); got != tt.want {
+			t.Errorf("%s: canDeleteLocalFile = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(s.SourcePath, "Movie"), 0777); err != nil {
+		t.Fatal(err)
+	}
+	s.Config.NetNotFoundFileAction = models.SyncTreeItemMetaActionUpload
+	if s.canDeleteLocalFile(nfo) {
+		t.Errorf("来源目录还在时上传元数据不应该删除")
+	}
+	s.Config.EnableDownloadMeta = 0
+	s.Config.NetNotFoundFileAction = models.SyncTreeItemMetaActionDelete
+	if s.canDeleteLocalFile(nfo) {
+		t.Errorf("关闭元数据下载时不处理元数据")
+	}
+}
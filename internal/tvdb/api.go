@@ -0,0 +1,87 @@
+package tvdb
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// 搜索剧集或者电影，searchType: series 或 movie，year为0时不限制年份
+func (c *Client) Search(name string, year int, searchType string, language string) ([]SearchResult, error) {
+	query := map[string]string{
+		"query": name,
+		"type":  searchType,
+		"limit": "10",
+	}
+	if year > 0 {
+		query["year"] = strconv.Itoa(year)
+	}
+	if language != "" {
+		query["language"] = language
+	}
+	result := Response[[]SearchResult]{}
+	if err := c.doGet("/search", query, &result); err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// 查询剧集详情，包含演职人员、图片和季列表
+func (c *Client) GetSeriesExtended(seriesId int64) (*SeriesExtended, error) {
+	result := Response[SeriesExtended]{}
+	if err := c.doGet(fmt.Sprintf("/series/%d/extended", seriesId), map[string]string{"short": "false"}, &result); err != nil {
+		return nil, err
+	}
+	return &result.Data, nil
+}
+
+// 查询剧集的翻译，language为三位语言代码，例如：zho、eng
+func (c *Client) GetSeriesTranslation(seriesId int64, language string) (*Translation, error) {
+	result := Response[Translation]{}
+	if err := c.doGet(fmt.Sprintf("/series/%d/translations/%s", seriesId, language), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result.Data, nil
+}
+
+// 查询剧集某一季的所有集，seasonType默认为official
+func (c *Client) GetSeriesEpisodes(seriesId int64, seasonNumber int, language string) ([]Episode, error) {
+	episodes := make([]Episode, 0)
+	for page := 0; ; page++ {
+		result := Response[SeriesEpisodes]{}
+		url := fmt.Sprintf("/series/%d/episodes/official", seriesId)
+		if language != "" {
+			url = fmt.Sprintf("%s/%s", url, language)
+		}
+		query := map[string]string{
+			"page":   strconv.Itoa(page),
+			"season": strconv.Itoa(seasonNumber),
+		}
+		if err := c.doGet(url, query, &result); err != nil {
+			return nil, err
+		}
+		episodes = append(episodes, result.Data.Episodes...)
+		// 每页最多500条
+		if len(result.Data.Episodes) < 500 {
+			break
+		}
+	}
+	return episodes, nil
+}
+
+// 查询电影详情，包含演职人员、图片
+func (c *Client) GetMovieExtended(movieId int64) (*MovieExtended, error) {
+	result := Response[MovieExtended]{}
+	if err := c.doGet(fmt.Sprintf("/movies/%d/extended", movieId), map[string]string{"short": "false"}, &result); err != nil {
+		return nil, err
+	}
+	return &result.Data, nil
+}
+
+// 查询电影的翻译，language为三位语言代码
+func (c *Client) GetMovieTranslation(movieId int64, language string) (*Translation, error) {
+	result := Response[Translation]{}
+	if err := c.doGet(fmt.Sprintf("/movies/%d/translations/%s", movieId, language), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result.Data, nil
+}
@@ -0,0 +1,175 @@
+package tvdb
+
+import (
+	"Q115-STRM/internal/helpers"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"resty.dev/v3"
+)
+
+const (
+	// TheTVDB v4 API地址
+	DefaultBaseURL = "https://api4.thetvdb.com/v4"
+	// 超时配置
+	DEFAULT_TIMEOUT = 30 // 秒
+	// token有效期为1个月，提前一天重新登录
+	TOKEN_TTL = 29 * 24 * time.Hour
+)
+
+var ErrUnauthorized = errors.New("TVDB授权失败，请检查API Key和PIN")
+
+// TheTVDB API客户端
+type Client struct {
+	resty       *resty.Client
+	apiKey      string
+	pin         string
+	proxyUrl    string
+	proxyMu     sync.Mutex
+	token       string
+	expiredAt   time.Time
+	tokenMu     sync.Mutex
+	rateLimiter *rate.Limiter
+}
+
+var GlobalTvdbClient *Client
+var globalTvdbClientMu sync.Mutex
+
+// 创建或者更新全局客户端，apiKey或pin变化时会重新登录
+func NewClient(apiKey, pin, proxyUrl string) *Client {
+	globalTvdbClientMu.Lock()
+	defer globalTvdbClientMu.Unlock()
+	if GlobalTvdbClient != nil {
+		GlobalTvdbClient.SetCredentials(apiKey, pin)
+		GlobalTvdbClient.SetProxyUrl(proxyUrl)
+		return GlobalTvdbClient
+	}
+	GlobalTvdbClient = NewStandaloneClient(apiKey, pin, proxyUrl)
+	return GlobalTvdbClient
+}
+
+// 创建独立的客户端，不修改全局客户端，测试未保存的设置时使用
+func NewStandaloneClient(apiKey, pin, proxyUrl string) *Client {
+	rc := resty.New()
+	rc.SetBaseURL(DefaultBaseURL)
+	rc.SetTimeout(DEFAULT_TIMEOUT * time.Second)
+	rc.SetHeader("Accept", "application/json")
+	client := &Client{
+		resty:       rc,
+		apiKey:      apiKey,
+		pin:         pin,
+		rateLimiter: rate.NewLimiter(rate.Every(100*time.Millisecond), 10), // 每秒10个请求
+	}
+	client.SetProxyUrl(proxyUrl)
+	return client
+}
+
+func (c *Client) SetCredentials(apiKey, pin string) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if c.apiKey != apiKey || c.pin != pin {
+		c.apiKey = apiKey
+		c.pin = pin
+		c.token = ""
+	}
+}
+
+func (c *Client) SetProxyUrl(proxyUrl string) {
+	c.proxyMu.Lock()
+	defer c.proxyMu.Unlock()
+	if c.proxyUrl == proxyUrl {
+		return
+	}
+	c.proxyUrl = proxyUrl
+	if proxyUrl != "" {
+		c.resty.SetProxy(proxyUrl)
+	} else {
+		c.resty.RemoveProxy()
+	}
+}
+
+// 测试API Key是否可用
+func (c *Client) TestToken() bool {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	c.token = ""
+	if err := c.loginLocked(); err != nil {
+		helpers.AppLogger.Errorf("测试TVDB API Key失败: %v", err)
+		return false
+	}
+	return true
+}
+
+func (c *Client) loginLocked() error {
+	if c.apiKey == "" {
+		return errors.New("未设置TVDB API Key")
+	}
+	body := map[string]string{"apikey": c.apiKey}
+	if c.pin != "" {
+		body["pin"] = c.pin
+	}
+	result := Response[loginData]{}
+	resp, err := c.resty.R().SetBody(body).SetResult(&result).Post("/login")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode() == http.StatusUnauthorized {
+		return ErrUnauthorized
+	}
+	if !resp.IsSuccess() || result.Data.Token == "" {
+		return fmt.Errorf("TVDB登录失败: %s", resp.String())
+	}
+	c.token = result.Data.Token
+	c.expiredAt = time.Now().Add(TOKEN_TTL)
+	return nil
+}
+
+func (c *Client) getToken(forceLogin bool) (string, error) {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	if forceLogin || c.token == "" || time.Now().After(c.expiredAt) {
+		if err := c.loginLocked(); err != nil {
+			return "", err
+		}
+	}
+	return c.token, nil
+}
+
+// 执行GET请求，token失效时重新登录后重试一次
+func (c *Client) doGet(url string, query map[string]string, result any) error {
+	for attempt := 0; attempt < 2; attempt++ {
+		// 重新登录后的重试也是一次请求，同样需要限速
+		if err := c.rateLimiter.Wait(context.Background()); err != nil {
+			return err
+		}
+		token, err := c.getToken(attempt > 0)
+		if err != nil {
+			return err
+		}
+		req := c.resty.R().SetAuthToken(token).SetResult(result)
+		if len(query) > 0 {
+			req.SetQueryParams(query)
+		}
+		resp, err := req.Get(url)
+		if err != nil {
+			helpers.AppLogger.Errorf("请求TVDB接口 %s 失败: %v", url, err)
+			return err
+		}
+		if resp.StatusCode() == http.StatusUnauthorized {
+			continue
+		}
+		if resp.StatusCode() == http.StatusNotFound {
+			return fmt.Errorf("TVDB没有数据: %s", url)
+		}
+		if !resp.IsSuccess() {
+			return fmt.Errorf("请求TVDB接口 %s 失败: %s", url, resp.String())
+		}
+		return nil
+	}
+	return ErrUnauthorized
+}
@@ -0,0 +1,164 @@
+package tvdb
+
+// 通用返回结构
+type Response[T any] struct {
+	Status string `json:"status"`
+	Data   T      `json:"data"`
+}
+
+type loginData struct {
+	Token string `json:"token"`
+}
+
+// 搜索结果
+type SearchResult struct {
+	ObjectID        string            `json:"objectID"`
+	Type            string            `json:"type"`    // series 或 movie
+	TvdbId          string            `json:"tvdb_id"` // 字符串格式的ID
+	Name            string            `json:"name"`
+	Year            string            `json:"year"`
+	FirstAirTime    string            `json:"first_air_time"`
+	Overview        string            `json:"overview"`
+	ImageUrl        string            `json:"image_url"`
+	PrimaryLanguage string            `json:"primary_language"`
+	Country         string            `json:"country"`
+	Aliases         []string          `json:"aliases"`
+	Translations    map[string]string `json:"translations"` // key为三位语言代码
+}
+
+type Genre struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+// 图片
+type Artwork struct {
+	ID       int64   `json:"id"`
+	Image    string  `json:"image"`
+	Type     int     `json:"type"` // 图片类型，剧集：2-海报 3-背景 23-logo，电影：14-海报 15-背景 25-logo
+	Language string  `json:"language"`
+	Score    float64 `json:"score"`
+	Width    int     `json:"width"`
+	Height   int     `json:"height"`
+}
+
+// 演职人员
+type Character struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"` // 角色名称
+	PeopleId   int64  `json:"peopleId"`
+	PersonName string `json:"personName"`
+	PeopleType string `json:"peopleType"` // Actor、Director、Writer等
+	Image      string `json:"image"`
+	PersonImg  string `json:"personImgURL"`
+	Sort       int64  `json:"sort"`
+}
+
+type RemoteId struct {
+	ID         string `json:"id"`
+	Type       int    `json:"type"`
+	SourceName string `json:"sourceName"` // IMDB、TheMovieDB.com等
+}
+
+type Company struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	Country string `json:"country"`
+}
+
+type Status struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type SeasonType struct {
+	ID   int    `json:"id"`
+	Type string `json:"type"` // official、dvd、absolute等
+	Name string `json:"name"`
+}
+
+type Season struct {
+	ID       int64      `json:"id"`
+	SeriesId int64      `json:"seriesId"`
+	Number   int        `json:"number"`
+	Name     string     `json:"name"`
+	Image    string     `json:"image"`
+	Type     SeasonType `json:"type"`
+}
+
+// 剧集详情
+type SeriesExtended struct {
+	ID                   int64       `json:"id"`
+	Name                 string      `json:"name"`
+	Slug                 string      `json:"slug"`
+	Image                string      `json:"image"`
+	FirstAired           string      `json:"firstAired"`
+	LastAired            string      `json:"lastAired"`
+	Year                 string      `json:"year"`
+	Overview             string      `json:"overview"`
+	OriginalCountry      string      `json:"originalCountry"`
+	OriginalLanguage     string      `json:"originalLanguage"`
+	AverageRuntime       int         `json:"averageRuntime"`
+	Score                float64     `json:"score"`
+	Status               Status      `json:"status"`
+	Genres               []Genre     `json:"genres"`
+	Artworks             []Artwork   `json:"artworks"`
+	Characters           []Character `json:"characters"`
+	RemoteIds            []RemoteId  `json:"remoteIds"`
+	Seasons              []Season    `json:"seasons"`
+	Companies            []Company   `json:"companies"`
+	NameTranslations     []string    `json:"nameTranslations"`
+	OverviewTranslations []string    `json:"overviewTranslations"`
+}
+
+// 电影详情
+type MovieExtended struct {
+	ID               int64       `json:"id"`
+	Name             string      `json:"name"`
+	Slug             string      `json:"slug"`
+	Image            string      `json:"image"`
+	Year             string      `json:"year"`
+	Runtime          int64       `json:"runtime"`
+	Score            float64     `json:"score"`
+	OriginalCountry  string      `json:"originalCountry"`
+	OriginalLanguage string      `json:"originalLanguage"`
+	Status           Status      `json:"status"`
+	Genres           []Genre     `json:"genres"`
+	Artworks         []Artwork   `json:"artworks"`
+	Characters       []Character `json:"characters"`
+	RemoteIds        []RemoteId  `json:"remoteIds"`
+	Releases         []Release   `json:"releases"`
+	FirstRelease     Release     `json:"first_release"`
+}
+
+type Release struct {
+	Country string `json:"country"`
+	Date    string `json:"date"`
+	Detail  string `json:"detail"`
+}
+
+// 翻译
+type Translation struct {
+	Name     string `json:"name"`
+	Overview string `json:"overview"`
+	Language string `json:"language"`
+	Tagline  string `json:"tagline"`
+}
+
+// 集
+type Episode struct {
+	ID           int64  `json:"id"`
+	SeriesId     int64  `json:"seriesId"`
+	Name         string `json:"name"`
+	Overview     string `json:"overview"`
+	Aired        string `json:"aired"`
+	Runtime      int    `json:"runtime"`
+	SeasonNumber int    `json:"seasonNumber"`
+	Number       int    `json:"number"`
+	Image        string `json:"image"`
+}
+
+type SeriesEpisodes struct {
+	Episodes []Episode `json:"episodes"`
+}
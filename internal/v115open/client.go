@@ -60,7 +60,7 @@ func (c *OpenClient) SetAuthToken(token string, refreshToken string) {
 	c.RefreshTokenStr = refreshToken
 }
 
-// doRequest 带重试的请求方法（使用全局队列）
+// doRequest 带重试的请求方法（使用账号的请求队列）
 func (c *OpenClient) doRequest(url string, req *resty.Request, options *RequestConfig) (*resty.Response, *RespBase[json.RawMessage], error) {
 	// 设置超时时间
 	req.SetTimeout(options.Timeout)
@@ -71,8 +71,8 @@ func (c *OpenClient) doRequest(url string, req *resty.Request, options *RequestC
 
 	var lastErr error
 	for attempt := 0; attempt <= options.MaxRetries; attempt++ {
-		// 使用全局队列执行器处理请求
-		executor := GetGlobalExecutor()
+		// 使用账号的队列执行器处理请求，每个账号单独限流
+		executor := GetExecutor(c.AccountId)
 		respChan := make(chan *RequestResponse, 1)
 
 		queuedReq := &QueuedRequest{
@@ -169,8 +169,8 @@ func (c *OpenClient) doAuthRequest(ctx context.Context, url string, req *resty.R
 	var lastErr error
 	var lastRespBytes []byte
 	for attempt := 0; attempt <= options.MaxRetries; attempt++ {
-		// 使用全局队列执行器处理请求
-		executor := GetGlobalExecutor()
+		// 使用账号的队列执行器处理请求，每个账号单独限流
+		executor := GetExecutor(c.AccountId)
 		respChan := make(chan *RequestResponse, 1)
 
 		queuedReq := &QueuedRequest{
@@ -13,11 +13,13 @@ import (
 )
 
 // RequestStatSaver 请求统计保存回调函数类型
-type RequestStatSaver func(requestTime int64, url, method string, duration int64, isThrottled bool)
+type RequestStatSaver func(accountId uint, requestTime int64, url, method string, duration int64, isThrottled bool)
 
 // QueueExecutor 请求队列执行器，负责管理所有API请求的队列和执行
 type QueueExecutor struct {
 	sync.RWMutex
+	// 所属的115账号ID，每个账号单独限流
+	accountId uint
 	// 请求队列通道，缓冲100
 	requestQueue chan *QueuedRequest
 	// Worker数量
@@ -44,30 +46,130 @@ type QueueExecutor struct {
 	statSaver RequestStatSaver
 }
 
-// 全局队列执行器实例
-var globalExecutor *QueueExecutor
-var executorOnce sync.Once
+// 速率限制配置
+type RateLimitConfig struct {
+	QPS int `json:"qps"`
+	QPM int `json:"qpm"`
+	QPH int `json:"qph"`
+}
 
-// GetGlobalExecutor 获取全局队列执行器实例（单例）
-func GetGlobalExecutor() *QueueExecutor {
-	executorOnce.Do(func() {
-		// 默认QPS=10，QPM=500，QPH=10000
-		globalExecutor = NewQueueExecutor(3, 200, 12000)
-		globalExecutor.Start()
-	})
-	return globalExecutor
+// 按账号ID保存的队列执行器，每个账号有独立的速率限制、限流状态和统计数据
+var (
+	executors      = make(map[uint]*QueueExecutor)
+	executorsMutex sync.Mutex
+	// 默认配置，QPS=3，QPM=200，QPH=12000
+	defaultRateLimit = RateLimitConfig{QPS: 3, QPM: 200, QPH: 12000}
+	// 单独设置过速率限制的账号
+	accountRateLimits = make(map[uint]RateLimitConfig)
+	executorStatSaver RequestStatSaver
+)
+
+// GetExecutor 获取账号的队列执行器，不存在时使用账号的速率限制配置创建并启动
+func GetExecutor(accountId uint) *QueueExecutor {
+	executorsMutex.Lock()
+	defer executorsMutex.Unlock()
+	if executor, ok := executors[accountId]; ok {
+		return executor
+	}
+	config, ok := accountRateLimits[accountId]
+	if !ok {
+		config = defaultRateLimit
+	}
+	executor := NewQueueExecutor(config.QPS, config.QPM, config.QPH)
+	executor.accountId = accountId
+	executor.statSaver = executorStatSaver
+	executor.Start()
+	executors[accountId] = executor
+	return executor
 }
 
-// SetGlobalExecutorConfig 设置全局执行器的速率限制配置
-func SetGlobalExecutorConfig(qps, qpm, qph int) {
-	executor := GetGlobalExecutor()
-	executor.SetRateLimitConfig(qps, qpm, qph)
+// LookupExecutor 查询账号已经创建的队列执行器，不存在时不创建
+func LookupExecutor(accountId uint) (*QueueExecutor, bool) {
+	executorsMutex.Lock()
+	defer executorsMutex.Unlock()
+	executor, ok := executors[accountId]
+	return executor, ok
 }
 
-// SetGlobalExecutorStatSaver 设置全局执行器的统计保存回调函数
-func SetGlobalExecutorStatSaver(saver RequestStatSaver) {
-	executor := GetGlobalExecutor()
-	executor.SetStatSaver(saver)
+// GetExecutorConfig 获取账号生效的速率限制配置，没有单独设置时返回默认配置
+func GetExecutorConfig(accountId uint) RateLimitConfig {
+	executorsMutex.Lock()
+	defer executorsMutex.Unlock()
+	if config, ok := accountRateLimits[accountId]; ok {
+		return config
+	}
+	return defaultRateLimit
+}
+
+// RemoveExecutor 停止并删除账号的队列执行器和单独设置的速率限制，账号删除时调用
+func RemoveExecutor(accountId uint) {
+	executorsMutex.Lock()
+	executor, ok := executors[accountId]
+	delete(executors, accountId)
+	delete(accountRateLimits, accountId)
+	executorsMutex.Unlock()
+	if ok {
+		executor.Stop()
+	}
+}
+
+// GetExecutors 获取所有已创建的队列执行器
+func GetExecutors() map[uint]*QueueExecutor {
+	executorsMutex.Lock()
+	defer executorsMutex.Unlock()
+	result := make(map[uint]*QueueExecutor, len(executors))
+	for accountId, executor := range executors {
+		result[accountId] = executor
+	}
+	return result
+}
+
+// SetDefaultExecutorConfig 设置默认的速率限制配置，应用到所有没有单独设置的账号
+func SetDefaultExecutorConfig(qps, qpm, qph int) {
+	executorsMutex.Lock()
+	defaultRateLimit = RateLimitConfig{QPS: qps, QPM: qpm, QPH: qph}
+	toUpdate := make([]*QueueExecutor, 0, len(executors))
+	for accountId, executor := range executors {
+		if _, ok := accountRateLimits[accountId]; !ok {
+			toUpdate = append(toUpdate, executor)
+		}
+	}
+	executorsMutex.Unlock()
+	for _, executor := range toUpdate {
+		executor.SetRateLimitConfig(qps, qpm, qph)
+	}
+}
+
+// SetExecutorConfig 单独设置某个账号的速率限制配置
+func SetExecutorConfig(accountId uint, qps, qpm, qph int) {
+	executorsMutex.Lock()
+	accountRateLimits[accountId] = RateLimitConfig{QPS: qps, QPM: qpm, QPH: qph}
+	executor, ok := executors[accountId]
+	executorsMutex.Unlock()
+	if ok {
+		executor.SetRateLimitConfig(qps, qpm, qph)
+	}
+}
+
+// GetDefaultExecutorConfig 获取默认的速率限制配置
+func GetDefaultExecutorConfig() RateLimitConfig {
+	executorsMutex.Lock()
+	defer executorsMutex.Unlock()
+	return defaultRateLimit
+}
+
+// SetExecutorStatSaver 设置所有执行器的统计保存回调函数
+func SetExecutorStatSaver(saver RequestStatSaver) {
+	executorsMutex.Lock()
+	executorStatSaver = saver
+	toUpdate := make([]*QueueExecutor, 0, len(executors))
+	for _, executor := range executors {
+		toUpdate = append(toUpdate, executor)
+	}
+	executorsMutex.Unlock()
+	for _, executor := range toUpdate {
+		executor.SetStatSaver(saver)
+	}
 }
 
 // NewQueueExecutor 创建新的队列执行器
@@ -159,8 +261,8 @@ func (qe *QueueExecutor) Start() {
 	}
 	qe.Unlock()
 
-	helpers.V115Log.Infof("启动115 OpenAPI队列执行器，Worker数量: %d, QPS: %d, QPM: %d, QPH: %d",
-		qe.workerCount, qe.qpsConfig, qe.qpmConfig, qe.qphConfig)
+	helpers.V115Log.Infof("启动115 OpenAPI队列执行器，账号ID: %d, Worker数量: %d, QPS: %d, QPM: %d, QPH: %d",
+		qe.accountId, qe.workerCount, qe.qpsConfig, qe.qpmConfig, qe.qphConfig)
 
 	// 启动Worker
 	for i := 0; i < qe.workerCount; i++ {
@@ -180,7 +282,7 @@ func (qe *QueueExecutor) Stop() {
 	qe.running = false
 	qe.Unlock()
 
-	helpers.V115Log.Infof("停止115 OpenAPI队列执行器")
+	helpers.V115Log.Infof("停止115 OpenAPI队列执行器，账号ID: %d", qe.accountId)
 
 	// 停止所有Worker
 	for _, stopChan := range qe.workerStopChans {
@@ -220,7 +322,7 @@ func (qe *QueueExecutor) handleRequest(req *QueuedRequest) {
 
 	// 检查限流状态
 	if qe.throttleManager.IsThrottled() {
-		helpers.V115Log.Debugf("系统处于限流状态，等待恢复...")
+		helpers.V115Log.Debugf("账号 %d 处于限流状态，等待恢复...", qe.accountId)
 		qe.throttleManager.WaitThrottleRecovery(req.Ctx)
 	}
 
@@ -282,8 +384,11 @@ func (qe *QueueExecutor) handleRequest(req *QueuedRequest) {
 	})
 
 	// 异步写入数据库（如果设置了回调函数）
-	if qe.statSaver != nil {
-		go qe.statSaver(time.Now().Unix(), req.URL, req.Method, duration, isThrottled)
+	qe.RLock()
+	statSaver := qe.statSaver
+	qe.RUnlock()
+	if statSaver != nil {
+		go statSaver(qe.accountId, time.Now().Unix(), req.URL, req.Method, duration, isThrottled)
 	}
 
 	// 发送响应
@@ -393,6 +498,13 @@ func (qe *QueueExecutor) GetStats(duration time.Duration) *StatsSnapshot {
 	return qe.stats.GetStats(duration)
 }
 
+// GetRateLimitConfig 获取当前的速率限制配置
+func (qe *QueueExecutor) GetRateLimitConfig() RateLimitConfig {
+	qe.RLock()
+	defer qe.RUnlock()
+	return RateLimitConfig{QPS: qe.qpsConfig, QPM: qe.qpmConfig, QPH: qe.qphConfig}
+}
+
 // GetThrottleStatus 获取限流状态
 func (qe *QueueExecutor) GetThrottleStatus() ThrottleStatus {
 	return qe.throttleManager.GetThrottleStatus()
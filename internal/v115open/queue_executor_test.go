@@ -0,0 +1,28 @@
+package v115open
+
+import "testing"
+
+func TestExecutorLookupAndRemove(t *testing.T) {
+	const accountId = 9001
+	defer RemoveExecutor(accountId)
+	SetExecutorConfig(accountId, 5, 100, 1000)
+	if config := GetExecutorConfig(accountId); config.QPS != 5 || config.QPM != 100 || config.QPH != 1000 {
+		t.Errorf("账号的速率限制错误: %+v", config)
+	}
+	if _, ok := LookupExecutor(accountId); ok {
+		t.Fatalf("查询和设置速率限制不应该创建执行器")
+	}
+	executorsMutex.Lock()
+	executors[accountId] = NewQueueExecutor(5, 100, 1000)
+	executorsMutex.Unlock()
+	if _, ok := LookupExecutor(accountId); !ok {
+		t.Fatalf("应该查询到已创建的执行器")
+	}
+	RemoveExecutor(accountId)
+	if _, ok := LookupExecutor(accountId); ok {
+		t.Errorf("删除账号后执行器应该被移除")
+	}
+	if config := GetExecutorConfig(accountId); config != GetDefaultExecutorConfig() {
+		t.Errorf("删除账号后应该恢复默认速率限制: %+v", config)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"os"
 	"strings"
 
 	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
@@ -155,26 +154,14 @@ func (c *OpenClient) GetVideoPlayUrl(ctx context.Context, pickCode string, userA
 	return respData
 }
 
+// 上传文件，不保存分片状态
+func (c *OpenClient) Upload(ctx context.Context, filePath string, parentFileId string) (string, error) {
+	return c.UploadResume(ctx, filePath, parentFileId, &MultipartState{}, nil)
+}
+
 // 初始化上传进程
 // POST 域名 + /open/upload/init
-func (c *OpenClient) Upload(ctx context.Context, filePath string, parentFileId string, signKey string, signVal string) (string, error) {
-	fileInfo, err := os.Stat(filePath)
-	if err != nil {
-		helpers.V115Log.Errorf("获取文件信息失败: %v", err)
-		return "", err
-	}
-	fileName := fileInfo.Name()
-	fileSize := fileInfo.Size()
-	fileSha1, err := helpers.FileSHA1(filePath)
-	if err != nil {
-		helpers.V115Log.Errorf("计算文件 SHA1 失败: %v", err)
-		return "", err
-	}
-	preSha1, err := helpers.FileSHA1Partial(filePath, 0, 128)
-	if err != nil {
-		helpers.V115Log.Errorf("计算文件前128位 SHA1 失败: %v", err)
-		return "", err
-	}
+func (c *OpenClient) uploadInit(ctx context.Context, filePath string, fileName string, fileSize int64, fileSha1 string, preSha1 string, parentFileId string, signKey string, signVal string) (*UploadResult[json.RawMessage], error) {
 	params := map[string]string{
 		"file_name": fileName,
 		"file_size": fmt.Sprintf("%d", fileSize),
@@ -194,10 +181,9 @@ func (c *OpenClient) Upload(ctx context.Context, filePath string, parentFileId s
 	_, _, uErr := c.doAuthRequest(ctx, url, req, MakeRequestConfig(1, 1, 15), respData)
 	if uErr != nil {
 		helpers.V115Log.Errorf("上传失败: %v", uErr)
-		return "", uErr
+		return nil, uErr
 	}
-	status := respData.Status
-	if status == 7 {
+	if respData.Status == 7 {
 		// 需要二次认证
 		signCheck := respData.SignCheck
 		signKey := respData.SignKey
@@ -205,63 +191,16 @@ func (c *OpenClient) Upload(ctx context.Context, filePath string, parentFileId s
 		signParts := strings.Split(signCheck, "-")
 		if len(signParts) != 2 {
 			helpers.V115Log.Errorf("签名检查格式错误: %v", signParts)
-			return "", fmt.Errorf("签名检查格式错误: %v", signParts)
+			return nil, fmt.Errorf("签名检查格式错误: %v", signParts)
 		}
 		offset := helpers.StringToInt64(signParts[0])
 		length := helpers.StringToInt64(signParts[1])
 		helpers.V115Log.Warnf("需要二次认证: offset=%d, length=%d, sign_key=%s\n", offset, length, signKey)
 		signVal, _ := helpers.FileSHA1Partial(filePath, offset, length)
-		params["sign_key"] = signKey
-		params["sign_val"] = signVal
-		// fmt.Printf("二次认证参数: sign_key=%s, sign_val=%s\n", params["sign_key"], params["sign_val"])
 		// 需要二次认证，再次请求接口
-		return c.Upload(ctx, filePath, parentFileId, signKey, signVal)
-	}
-	if status == 2 {
-		// 秒传成功
-		return respData.FileId, nil
-	}
-	if status == 6 {
-		helpers.V115Log.Error("签名验证后失败")
-		return "", fmt.Errorf("签名验证后失败")
-	}
-	if status == 8 {
-		helpers.V115Log.Error("签名认证失败")
-		return "", fmt.Errorf("签名认证失败")
+		return c.uploadInit(ctx, filePath, fileName, fileSize, fileSha1, preSha1, parentFileId, signKey, signVal)
 	}
-	if status == 1 {
-		// 非秒传，开始普通上传流程
-		// 获取上传凭证
-		uploadToken := c.GetUploadToken(ctx)
-		if uploadToken == nil {
-			helpers.V115Log.Error("获取上传凭证失败")
-			return "", fmt.Errorf("获取上传凭证失败")
-		}
-		// 准备调用OSS对象存储上传文件，准备参数
-		callbackData := &UploadResultCallBack{}
-		json.Unmarshal(respData.Callback, callbackData)
-		callback := callbackData.Callback
-		callbackVar := callbackData.CallbackVar
-		bucket := respData.Bucket
-		objectId := respData.Object
-		helpers.V115Log.Infof("OSS上传的参数: callback=%s, callback_var=%s, bucket=%s, object_id=%s, endpoint=%s, AccessKeyId=%s, AccessKeySecret=%s, SecurityToken=%s", callback, callbackVar, bucket, objectId, uploadToken.Endpoint, uploadToken.AccessKeyId, uploadToken.AccessKeySecret, uploadToken.SecurityToken)
-		callbackResult, ossErr := OssUploadFile(uploadToken.Endpoint, uploadToken.AccessKeyId, uploadToken.AccessKeySecret, uploadToken.SecurityToken, bucket, objectId, callback, callbackVar, filePath, fileSize, fileSha1)
-		if ossErr != nil {
-			// helpers.V115Log.Error("OSS上传失败: %v", ossErr)
-			return "", ossErr
-		}
-		if callbackResult == nil {
-			helpers.V115Log.Error("OSS上传回调结果为空")
-			return "", fmt.Errorf("OSS上传回调结果为空")
-		}
-		if callbackResult["message"].(string) != "" {
-			helpers.V115Log.Errorf("OSS上传回调失败: %v", callbackResult["message"])
-			return "", fmt.Errorf("OSS上传回调失败: %v", callbackResult["message"])
-		}
-
-		return callbackResult["data"].(map[string]interface{})["file_id"].(string), nil
-	}
-	return respData.FileId, nil
+	return respData, nil
 }
 
 // 获取115上传凭证
@@ -278,17 +217,17 @@ func (c *OpenClient) GetUploadToken(ctx context.Context) *UploadToken {
 	return respData
 }
 
-// 上传分片
-func (c *OpenClient) UploadResume(ctx context.Context, pickCode string, fileSize int, parentFileId string, fileSha1 string) {
-}
-
-func OssUploadFile(endPoint string, accessKeyId string, accessKeySecret string, securityToken string, bucketName string, objectId string, callback string, callbackVar string, filePath string, fileSize int64, fileSha1 string) (map[string]any, error) {
+func newOssClient(uploadToken *UploadToken) *oss.Client {
 	cfg := oss.LoadDefaultConfig().
-		WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKeyId, accessKeySecret, securityToken)).
-		WithRegion("cn-shenzhen"). // 填写Bucket所在地域，以华东1（杭州）为例，Region填写为cn-hangzhou
-		WithEndpoint(endPoint)     // 填写Bucket所在地域对应的公网Endpoint。以华东1（杭州）为例，Endpoint填写为'https://oss-cn-hangzhou.aliyuncs.com'
+		WithCredentialsProvider(credentials.NewStaticCredentialsProvider(uploadToken.AccessKeyId, uploadToken.AccessKeySecret, uploadToken.SecurityToken)).
+		WithRegion("cn-shenzhen").         // 填写Bucket所在地域，以华东1（杭州）为例，Region填写为cn-hangzhou
+		WithEndpoint(uploadToken.Endpoint) // 填写Bucket所在地域对应的公网Endpoint。以华东1（杭州）为例，Endpoint填写为'https://oss-cn-hangzhou.aliyuncs.com'
 	// 创建OSS客户端
-	client := oss.NewClient(cfg)
+	return oss.NewClient(cfg)
+}
+
+// 将115返回的回调参数转换为OSS需要的Base64编码的callback和callback-var
+func makeOssCallback(bucketName string, objectId string, callback string, callbackVar string, fileSize int64, fileSha1 string) (string, string) {
 	// 将回调参数转换为JSON并进行Base64编码，以便将其作为回调参数传递
 	callbackJson := map[string]string{}
 	json.Unmarshal([]byte(callback), &callbackJson)
@@ -309,15 +248,16 @@ func OssUploadFile(endPoint string, accessKeyId string, accessKeySecret string,
 	callbackVarJsonMap["object"] = objectId
 	callbackVarJsonMap["size"] = helpers.Int64ToString(fileSize)
 	callbackVarJsonMap["sha1"] = fileSha1
-	body := callbackJson["callbackBody"]
 	for k, v := range callbackVarJsonMap {
 		callbackVarMap[k] = v
-		key := fmt.Sprintf("${%s}", k)
-		body = strings.ReplaceAll(body, key, v)
 	}
 	callbackVarStr, _ := json.Marshal(callbackVarMap)
-	callbackBase64 := base64.StdEncoding.EncodeToString(callbackStr)
-	callbackVarBase64 := base64.StdEncoding.EncodeToString(callbackVarStr)
+	return base64.StdEncoding.EncodeToString(callbackStr), base64.StdEncoding.EncodeToString(callbackVarStr)
+}
+
+func OssUploadFile(uploadToken *UploadToken, bucketName string, objectId string, callback string, callbackVar string, filePath string, fileSize int64, fileSha1 string) (map[string]any, error) {
+	client := newOssClient(uploadToken)
+	callbackBase64, callbackVarBase64 := makeOssCallback(bucketName, objectId, callback, callbackVar, fileSize, fileSha1)
 	// 创建上传对象的请求
 	putRequest := &oss.PutObjectRequest{
 		Bucket:       oss.Ptr(bucketName),      // 存储空间名称
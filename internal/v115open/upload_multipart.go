@@ -0,0 +1,330 @@
+package v115open
+
+import (
+	"Q115-STRM/internal/helpers"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/aliyun/alibabacloud-oss-go-sdk-v2/oss"
+)
+
+const (
+	// 超过该大小的文件使用分片上传
+	MULTIPART_THRESHOLD = 20 * 1024 * 1024
+	// 默认分片大小
+	MULTIPART_PART_SIZE = 10 * 1024 * 1024
+	// OSS最多支持10000个分片
+	MULTIPART_MAX_PARTS = 10000
+	// 单个分片失败后的重试次数，每次重试都会重新获取上传凭证
+	MULTIPART_PART_RETRIES = 3
+)
+
+// 已上传的分片
+type MultipartPart struct {
+	PartNumber int32  `json:"part_number"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size"`
+}
+
+// 分片上传状态，由调用方持久化，中断后传回即可续传
+type MultipartState struct {
+	FileSize     int64           `json:"file_size"`
+	FileModTime  int64           `json:"file_mod_time"`
+	FileSha1     string          `json:"file_sha1"` // 大文件计算SHA1很慢，文件未变化时复用
+	PreSha1      string          `json:"pre_sha1"`
+	Bucket       string          `json:"bucket"`
+	Object       string          `json:"object"`
+	UploadId     string          `json:"upload_id"`
+	Callback     string          `json:"callback"`
+	CallbackVar  string          `json:"callback_var"`
+	PartSize     int64           `json:"part_size"`
+	Parts        []MultipartPart `json:"parts"`
+	UploadedSize int64           `json:"uploaded_size"`
+}
+
+// 分片状态变化时回调，用于持久化状态和更新进度
+type MultipartProgressFunc func(state *MultipartState)
+
+// 清空分片信息，保留文件的SHA1
+func (s *MultipartState) reset() {
+	s.Bucket = ""
+	s.Object = ""
+	s.UploadId = ""
+	s.Callback = ""
+	s.CallbackVar = ""
+	s.PartSize = 0
+	s.Parts = nil
+	s.UploadedSize = 0
+}
+
+func (s *MultipartState) hasPart(partNumber int32) bool {
+	return slices.ContainsFunc(s.Parts, func(p MultipartPart) bool {
+		return p.PartNumber == partNumber
+	})
+}
+
+// 根据文件大小计算分片大小，保证分片数量不超过OSS的限制
+func multipartPartSize(fileSize int64) int64 {
+	partSize := int64(MULTIPART_PART_SIZE)
+	for (fileSize+partSize-1)/partSize > MULTIPART_MAX_PARTS {
+		partSize *= 2
+	}
+	return partSize
+}
+
+// 上传文件，大文件使用OSS分片上传
+// state保存了上次未完成的分片信息时从断点继续上传，onProgress在每个分片完成后调用
+func (c *OpenClient) UploadResume(ctx context.Context, filePath string, parentFileId string, state *MultipartState, onProgress MultipartProgressFunc) (string, error) {
+	if state == nil {
+		state = &MultipartState{}
+	}
+	notify := func() {
+		if onProgress != nil {
+			onProgress(state)
+		}
+	}
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		helpers.V115Log.Errorf("获取文件信息失败: %v", err)
+		return "", err
+	}
+	fileName := fileInfo.Name()
+	fileSize := fileInfo.Size()
+	if state.FileSize != fileSize || state.FileModTime != fileInfo.ModTime().Unix() || state.FileSha1 == "" {
+		// 文件有变化，之前的分片作废
+		state.reset()
+		state.FileSize = fileSize
+		state.FileModTime = fileInfo.ModTime().Unix()
+		state.FileSha1, err = helpers.FileSHA1(filePath)
+		if err != nil {
+			helpers.V115Log.Errorf("计算文件 SHA1 失败: %v", err)
+			return "", err
+		}
+		state.PreSha1, err = helpers.FileSHA1Partial(filePath, 0, 128)
+		if err != nil {
+			helpers.V115Log.Errorf("计算文件前128位 SHA1 失败: %v", err)
+			return "", err
+		}
+		notify()
+	}
+	if state.UploadId != "" {
+		helpers.V115Log.Infof("继续分片上传文件: %s, UploadId: %s, 已上传 %d 个分片", fileName, state.UploadId, len(state.Parts))
+		fileId, err := c.uploadParts(ctx, filePath, state, notify)
+		if err == nil || !errors.Is(err, errMultipartExpired) {
+			return fileId, err
+		}
+		// 分片任务已失效，重新初始化上传
+		helpers.V115Log.Warnf("分片上传任务 %s 已失效，重新上传文件: %s", state.UploadId, fileName)
+		state.reset()
+		notify()
+	}
+	respData, err := c.uploadInit(ctx, filePath, fileName, fileSize, state.FileSha1, state.PreSha1, parentFileId, "", "")
+	if err != nil {
+		return "", err
+	}
+	switch respData.Status {
+	case 2:
+		// 秒传成功
+		return respData.FileId, nil
+	case 6:
+		helpers.V115Log.Error("签名验证后失败")
+		return "", fmt.Errorf("签名验证后失败")
+	case 8:
+		helpers.V115Log.Error("签名认证失败")
+		return "", fmt.Errorf("签名认证失败")
+	case 1:
+		// 非秒传，开始普通上传流程
+	default:
+		return respData.FileId, nil
+	}
+	// 准备调用OSS对象存储上传文件，准备参数
+	callbackData := &UploadResultCallBack{}
+	json.Unmarshal(respData.Callback, callbackData)
+	if fileSize < MULTIPART_THRESHOLD {
+		// 获取上传凭证
+		uploadToken := c.GetUploadToken(ctx)
+		if uploadToken == nil {
+			helpers.V115Log.Error("获取上传凭证失败")
+			return "", fmt.Errorf("获取上传凭证失败")
+		}
+		helpers.V115Log.Infof("OSS上传的参数: callback=%s, callback_var=%s, bucket=%s, object_id=%s, endpoint=%s", callbackData.Callback, callbackData.CallbackVar, respData.Bucket, respData.Object, uploadToken.Endpoint)
+		callbackResult, ossErr := OssUploadFile(uploadToken, respData.Bucket, respData.Object, callbackData.Callback, callbackData.CallbackVar, filePath, fileSize, state.FileSha1)
+		if ossErr != nil {
+			return "", ossErr
+		}
+		return parseOssCallbackResult(callbackResult)
+	}
+	state.Bucket = respData.Bucket
+	state.Object = respData.Object
+	state.Callback = callbackData.Callback
+	state.CallbackVar = callbackData.CallbackVar
+	state.PartSize = multipartPartSize(fileSize)
+	return c.uploadParts(ctx, filePath, state, notify)
+}
+
+var errMultipartExpired = errors.New("分片上传任务已失效")
+
+// 上传未完成的分片并合并
+func (c *OpenClient) uploadParts(ctx context.Context, filePath string, state *MultipartState, notify func()) (string, error) {
+	uploadToken := c.GetUploadToken(ctx)
+	if uploadToken == nil {
+		helpers.V115Log.Error("获取上传凭证失败")
+		return "", fmt.Errorf("获取上传凭证失败")
+	}
+	client := newOssClient(uploadToken)
+	if state.UploadId == "" {
+		initResult, err := client.InitiateMultipartUpload(ctx, &oss.InitiateMultipartUploadRequest{
+			Bucket: oss.Ptr(state.Bucket),
+			Key:    oss.Ptr(state.Object),
+		})
+		if err != nil {
+			helpers.V115Log.Errorf("OSS初始化分片上传失败: %v", err)
+			return "", err
+		}
+		state.UploadId = oss.ToString(initResult.UploadId)
+		state.Parts = nil
+		state.UploadedSize = 0
+		helpers.V115Log.Infof("OSS初始化分片上传成功, bucket=%s, object=%s, UploadId=%s, 分片大小=%d", state.Bucket, state.Object, state.UploadId, state.PartSize)
+		notify()
+	} else {
+		// 以OSS上已存在的分片为准，避免本地记录和服务端不一致
+		parts, err := listOssParts(ctx, client, state)
+		if err != nil {
+			return "", err
+		}
+		state.Parts = parts
+		state.UploadedSize = 0
+		for _, part := range parts {
+			state.UploadedSize += part.Size
+		}
+		notify()
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	partCount := int32((state.FileSize + state.PartSize - 1) / state.PartSize)
+	for partNumber := int32(1); partNumber <= partCount; partNumber++ {
+		if state.hasPart(partNumber) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		offset := int64(partNumber-1) * state.PartSize
+		size := min(state.PartSize, state.FileSize-offset)
+		var etag string
+		for retry := 0; ; retry++ {
+			result, err := client.UploadPart(ctx, &oss.UploadPartRequest{
+				Bucket:     oss.Ptr(state.Bucket),
+				Key:        oss.Ptr(state.Object),
+				UploadId:   oss.Ptr(state.UploadId),
+				PartNumber: partNumber,
+				Body:       io.NewSectionReader(file, offset, size),
+			})
+			if err == nil {
+				etag = oss.ToString(result.ETag)
+				break
+			}
+			if isOssNoSuchUpload(err) {
+				return "", errMultipartExpired
+			}
+			if retry >= MULTIPART_PART_RETRIES || ctx.Err() != nil {
+				helpers.V115Log.Errorf("OSS上传分片 %d/%d 失败: %v", partNumber, partCount, err)
+				return "", err
+			}
+			helpers.V115Log.Warnf("OSS上传分片 %d/%d 失败，刷新上传凭证后重试: %v", partNumber, partCount, err)
+			// 上传凭证可能已过期
+			if uploadToken = c.GetUploadToken(ctx); uploadToken != nil {
+				client = newOssClient(uploadToken)
+			}
+		}
+		state.Parts = append(state.Parts, MultipartPart{PartNumber: partNumber, ETag: etag, Size: size})
+		state.UploadedSize += size
+		notify()
+	}
+	// 合并分片
+	parts := make([]oss.UploadPart, 0, len(state.Parts))
+	for _, part := range state.Parts {
+		parts = append(parts, oss.UploadPart{PartNumber: part.PartNumber, ETag: oss.Ptr(part.ETag)})
+	}
+	slices.SortFunc(parts, func(a, b oss.UploadPart) int {
+		return int(a.PartNumber - b.PartNumber)
+	})
+	callbackBase64, callbackVarBase64 := makeOssCallback(state.Bucket, state.Object, state.Callback, state.CallbackVar, state.FileSize, state.FileSha1)
+	result, err := client.CompleteMultipartUpload(ctx, &oss.CompleteMultipartUploadRequest{
+		Bucket:                  oss.Ptr(state.Bucket),
+		Key:                     oss.Ptr(state.Object),
+		UploadId:                oss.Ptr(state.UploadId),
+		CompleteMultipartUpload: &oss.CompleteMultipartUpload{Parts: parts},
+		Acl:                     oss.ObjectACLPrivate,
+		Callback:                oss.Ptr(callbackBase64),
+		CallbackVar:             oss.Ptr(callbackVarBase64),
+	})
+	if err != nil {
+		helpers.V115Log.Errorf("OSS合并分片失败: %v", err)
+		if isOssNoSuchUpload(err) {
+			return "", errMultipartExpired
+		}
+		return "", err
+	}
+	helpers.V115Log.Infof("OSS分片上传完成, bucket=%s, object=%s, 分片数=%d", state.Bucket, state.Object, len(parts))
+	return parseOssCallbackResult(result.CallbackResult)
+}
+
+func listOssParts(ctx context.Context, client *oss.Client, state *MultipartState) ([]MultipartPart, error) {
+	parts := make([]MultipartPart, 0)
+	var marker int32
+	for {
+		result, err := client.ListParts(ctx, &oss.ListPartsRequest{
+			Bucket:           oss.Ptr(state.Bucket),
+			Key:              oss.Ptr(state.Object),
+			UploadId:         oss.Ptr(state.UploadId),
+			PartNumberMarker: marker,
+		})
+		if err != nil {
+			if isOssNoSuchUpload(err) {
+				return nil, errMultipartExpired
+			}
+			helpers.V115Log.Errorf("OSS查询已上传分片失败: %v", err)
+			return nil, err
+		}
+		for _, part := range result.Parts {
+			parts = append(parts, MultipartPart{PartNumber: part.PartNumber, ETag: oss.ToString(part.ETag), Size: part.Size})
+		}
+		if !result.IsTruncated {
+			break
+		}
+		marker = result.NextPartNumberMarker
+	}
+	return parts, nil
+}
+
+func isOssNoSuchUpload(err error) bool {
+	var serviceErr *oss.ServiceError
+	return errors.As(err, &serviceErr) && serviceErr.Code == "NoSuchUpload"
+}
+
+// 解析115的OSS回调结果，返回新文件的ID
+func parseOssCallbackResult(callbackResult map[string]any) (string, error) {
+	if callbackResult == nil {
+		helpers.V115Log.Error("OSS上传回调结果为空")
+		return "", fmt.Errorf("OSS上传回调结果为空")
+	}
+	if message, _ := callbackResult["message"].(string); message != "" {
+		helpers.V115Log.Errorf("OSS上传回调失败: %v", message)
+		return "", fmt.Errorf("OSS上传回调失败: %v", message)
+	}
+	data, _ := callbackResult["data"].(map[string]any)
+	fileId, _ := data["file_id"].(string)
+	if fileId == "" {
+		return "", fmt.Errorf("OSS上传回调结果中没有文件ID")
+	}
+	return fileId, nil
+}
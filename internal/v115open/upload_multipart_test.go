@@ -0,0 +1,53 @@
+package v115open
+
+import "testing"
+
+func TestMultipartPartSize(t *testing.T) {
+	cases := []struct {
+		fileSize int64
+		expected int64
+	}{
+		{fileSize: 50 * 1024 * 1024, expected: MULTIPART_PART_SIZE},
+		{fileSize: MULTIPART_PART_SIZE * MULTIPART_MAX_PARTS, expected: MULTIPART_PART_SIZE},
+		{fileSize: MULTIPART_PART_SIZE*MULTIPART_MAX_PARTS + 1, expected: MULTIPART_PART_SIZE * 2},
+	}
+	for _, c := range cases {
+		partSize := multipartPartSize(c.fileSize)
+		if partSize != c.expected {
+			t.Errorf("文件大小 %d 的分片大小为 %d，预期 %d", c.fileSize, partSize, c.expected)
+		}
+		if (c.fileSize+partSize-1)/partSize > MULTIPART_MAX_PARTS {
+			t.Errorf("文件大小 %d 的分片数量超过了 %d", c.fileSize, MULTIPART_MAX_PARTS)
+		}
+	}
+}
+
+func TestMultipartStateReset(t *testing.T) {
+	state := &MultipartState{
+		FileSize:     100,
+		FileSha1:     "sha1",
+		UploadId:     "upload-id",
+		Parts:        []MultipartPart{{PartNumber: 1, ETag: "etag", Size: 100}},
+		UploadedSize: 100,
+	}
+	if !state.hasPart(1) || state.hasPart(2) {
+		t.Errorf("分片判断错误")
+	}
+	state.reset()
+	if state.UploadId != "" || len(state.Parts) != 0 || state.UploadedSize != 0 {
+		t.Errorf("重置后仍然保留了分片信息: %+v", state)
+	}
+	if state.FileSha1 != "sha1" || state.FileSize != 100 {
+		t.Errorf("重置后不应该清空文件信息: %+v", state)
+	}
+}
+
+func TestParseOssCallbackResult(t *testing.T) {
+	fileId, err := parseOssCallbackResult(map[string]any{
+		"message": "",
+		"data":    map[string]any{"file_id": "123"},
+	})
+	if err != nil || fileId != "123" {
+		t.Errorf("解析回调结果失败: %s, %v", fileId, err)
+	}
+}
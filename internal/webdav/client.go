@@ -0,0 +1,113 @@
+package webdav
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client WebDAV客户端，所有路径都是相对于BaseUrl的完整路径，以/开头
+type Client struct {
+	AccountId uint
+	BaseUrl   string
+	Username  string
+	Password  string
+	client    *http.Client // 普通接口使用，带超时
+	stream    *http.Client // 读取文件内容使用，不限制总时长
+}
+
+var cachedClients map[uint]*Client = make(map[uint]*Client, 0)
+var cachedClientsMutex sync.Mutex
+
+// NewClient 创建新的客户端，同一个账号复用同一个客户端
+func NewClient(accountId uint, baseUrl, username, password string) *Client {
+	cachedClientsMutex.Lock()
+	defer cachedClientsMutex.Unlock()
+	baseUrl = strings.TrimSuffix(baseUrl, "/")
+	// 配置没变时复用缓存的客户端，变化时创建新的客户端替换，不修改正在使用的客户端
+	if client, exists := cachedClients[accountId]; exists && accountId > 0 {
+		if client.BaseUrl == baseUrl && client.Username == username && client.Password == password {
+			return client
+		}
+	}
+	client := &Client{
+		AccountId: accountId,
+		BaseUrl:   baseUrl,
+		Username:  username,
+		Password:  password,
+		client:    &http.Client{Timeout: time.Duration(DEFAULT_TIMEOUT) * time.Second},
+		stream:    &http.Client{},
+	}
+	if accountId > 0 {
+		cachedClients[accountId] = client
+	}
+	return client
+}
+
+// 删除缓存的客户端，账号删除时调用
+func RemoveClient(accountId uint) {
+	cachedClientsMutex.Lock()
+	defer cachedClientsMutex.Unlock()
+	delete(cachedClients, accountId)
+}
+
+// 规范化路径，以/开头，不以/结尾
+func cleanPath(p string) string {
+	p = strings.ReplaceAll(p, "\\", "/")
+	p = path.Clean("/" + p)
+	return p
+}
+
+// 将路径转换为完整的请求地址，每一段都做url编码
+func (c *Client) fileUrl(p string) string {
+	p = cleanPath(p)
+	u := c.BaseUrl + (&url.URL{Path: p}).EscapedPath()
+	return u
+}
+
+func (c *Client) newRequest(ctx context.Context, method, p string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.fileUrl(p), body)
+	if err != nil {
+		return nil, err
+	}
+	if c.Username != "" || c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	return req, nil
+}
+
+// 执行请求，只有状态码在okCodes中才算成功，404返回ErrNotFound
+func (c *Client) do(req *http.Request, p string, okCodes ...int) (*http.Response, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range okCodes {
+		if resp.StatusCode == code {
+			return resp, nil
+		}
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	return nil, &StatusError{Method: req.Method, Path: p, StatusCode: resp.StatusCode}
+}
+
+// 带账号密码的文件地址，只能给ffprobe等内部工具使用，不能写入STRM文件
+func (c *Client) GetRawUrl(p string) string {
+	u, err := url.Parse(c.fileUrl(p))
+	if err != nil {
+		return ""
+	}
+	if c.Username != "" || c.Password != "" {
+		u.User = url.UserPassword(c.Username, c.Password)
+	}
+	return u.String()
+}
@@ -0,0 +1,318 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 查询属性，depth为0时只查询自身，为1时同时查询直接子项
+func (c *Client) propfind(ctx context.Context, p string, depth string) ([]*FileInfo, error) {
+	req, err := c.newRequest(ctx, "PROPFIND", p, strings.NewReader(propfindBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", depth)
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	resp, err := c.do(req, p, http.StatusMultiStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return parseMultiStatus(resp.Body, c.basePath())
+}
+
+// BaseUrl中的路径部分，解析href时需要去掉
+func (c *Client) basePath() string {
+	u, err := url.Parse(c.BaseUrl)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// 解析PROPFIND返回的multistatus
+func parseMultiStatus(body io.Reader, basePath string) ([]*FileInfo, error) {
+	var ms multiStatus
+	if err := xml.NewDecoder(body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("解析WebDAV响应失败: %v", err)
+	}
+	files := make([]*FileInfo, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		var found *prop
+		for i := range r.PropStats {
+			if strings.Contains(r.PropStats[i].Status, " 200") {
+				found = &r.PropStats[i].Prop
+				break
+			}
+		}
+		if found == nil {
+			continue
+		}
+		u, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		p := u.Path
+		if basePath != "" && strings.HasPrefix(p, basePath) {
+			p = p[len(basePath):]
+		}
+		p = cleanPath(p)
+		info := &FileInfo{
+			Name:        path.Base(p),
+			Path:        p,
+			IsDir:       found.ResourceType.Collection != nil,
+			ETag:        strings.Trim(strings.TrimPrefix(found.ETag, "W/"), "\""),
+			ContentType: found.ContentType,
+		}
+		if p == "/" {
+			info.Name = ""
+		}
+		if found.ContentLength != "" {
+			info.Size, _ = strconv.ParseInt(found.ContentLength, 10, 64)
+		}
+		if found.LastModified != "" {
+			if t, err := http.ParseTime(found.LastModified); err == nil {
+				info.ModTime = t
+			}
+		}
+		files = append(files, info)
+	}
+	return files, nil
+}
+
+// 查询文件或目录详情，不存在时返回ErrNotFound
+func (c *Client) Stat(ctx context.Context, p string) (*FileInfo, error) {
+	files, err := c.propfind(ctx, p, "0")
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, ErrNotFound
+	}
+	return files[0], nil
+}
+
+// 列出目录下的文件和子目录，不包含目录自身
+func (c *Client) List(ctx context.Context, dir string) ([]*FileInfo, error) {
+	dir = cleanPath(dir)
+	files, err := c.propfind(ctx, dir, "1")
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*FileInfo, 0, len(files))
+	for _, f := range files {
+		if f.Path == dir {
+			continue
+		}
+		list = append(list, f)
+	}
+	return list, nil
+}
+
+// 检查路径是否存在
+func (c *Client) Exists(ctx context.Context, p string) (bool, error) {
+	_, err := c.Stat(ctx, p)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// 创建目录，父目录必须存在，目录已存在时不报错
+func (c *Client) Mkdir(ctx context.Context, p string) error {
+	req, err := c.newRequest(ctx, "MKCOL", p, nil)
+	if err != nil {
+		return err
+	}
+	// 405表示目录已存在
+	resp, err := c.do(req, p, http.StatusCreated, http.StatusOK, http.StatusMethodNotAllowed)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// 逐级创建目录
+func (c *Client) MkdirAll(ctx context.Context, p string) error {
+	p = cleanPath(p)
+	if p == "/" {
+		return nil
+	}
+	if exists, err := c.Exists(ctx, p); err == nil && exists {
+		return nil
+	}
+	if err := c.MkdirAll(ctx, path.Dir(p)); err != nil {
+		return err
+	}
+	return c.Mkdir(ctx, p)
+}
+
+// 删除文件或目录，目录会递归删除
+func (c *Client) Delete(ctx context.Context, p string) error {
+	if cleanPath(p) == "/" {
+		return errors.New("不能删除WebDAV根目录")
+	}
+	req, err := c.newRequest(ctx, http.MethodDelete, p, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req, p, http.StatusNoContent, http.StatusOK, http.StatusAccepted)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// 移动或者复制文件，目标路径包含文件名，可以同时改名
+func (c *Client) moveOrCopy(ctx context.Context, method, src, dst string, overwrite bool) error {
+	req, err := c.newRequest(ctx, method, src, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Destination", c.fileUrl(dst))
+	req.Header.Set("Overwrite", "F")
+	if overwrite {
+		req.Header.Set("Overwrite", "T")
+	}
+	resp, err := c.do(req, src, http.StatusCreated, http.StatusNoContent)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// 移动文件或目录，dst是包含文件名的完整路径
+func (c *Client) Move(ctx context.Context, src, dst string, overwrite bool) error {
+	return c.moveOrCopy(ctx, "MOVE", src, dst, overwrite)
+}
+
+// 复制文件或目录，dst是包含文件名的完整路径
+func (c *Client) Copy(ctx context.Context, src, dst string, overwrite bool) error {
+	return c.moveOrCopy(ctx, "COPY", src, dst, overwrite)
+}
+
+// 原地改名
+func (c *Client) Rename(ctx context.Context, p, newName string) error {
+	return c.Move(ctx, p, path.Join(path.Dir(cleanPath(p)), newName), false)
+}
+
+// 上传本地文件，remotePath是包含文件名的完整路径，父目录不存在时自动创建
+func (c *Client) Upload(ctx context.Context, localFile, remotePath string) error {
+	f, err := os.Open(localFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := c.MkdirAll(ctx, path.Dir(cleanPath(remotePath))); err != nil {
+		return err
+	}
+	req, err := c.newRequest(ctx, http.MethodPut, remotePath, f)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = stat.Size()
+	resp, err := c.do(req, remotePath, http.StatusCreated, http.StatusNoContent, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// 打开远程文件读取内容，header会原样转发（例如Range），调用方负责关闭Body
+func (c *Client) Open(ctx context.Context, p string, header http.Header) (*http.Response, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, p, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		for _, v := range values {
+			req.Header.Add(key, v)
+		}
+	}
+	resp, err := c.stream.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		return nil, &StatusError{Method: http.MethodGet, Path: p, StatusCode: resp.StatusCode}
+	}
+	return resp, nil
+}
+
+// PlayUrl 带账号密码的文件地址，播放接口校验签名后跳转到这个地址，STRM中不保存账号密码
+func (c *Client) PlayUrl(p string) string {
+	u, err := url.Parse(c.fileUrl(p))
+	if err != nil {
+		return c.fileUrl(p)
+	}
+	if c.Username != "" || c.Password != "" {
+		u.User = url.UserPassword(c.Username, c.Password)
+	}
+	return u.String()
+}
+
+// 读取远程文件的全部内容，适合nfo等小文件
+func (c *Client) ReadFile(ctx context.Context, p string) ([]byte, error) {
+	resp, err := c.Open(ctx, p, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// 下载远程文件到本地，先写入临时文件，完成后再改名，避免留下不完整的文件
+func (c *Client) Download(ctx context.Context, remotePath, localFile string) error {
+	resp, err := c.Open(ctx, remotePath, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := os.MkdirAll(filepath.Dir(localFile), 0777); err != nil {
+		return err
+	}
+	tmpFile := localFile + ".downloading"
+	f, err := os.Create(tmpFile)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		f.Close()
+		os.Remove(tmpFile)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpFile)
+		return err
+	}
+	if lm := resp.Header.Get("Last-Modified"); lm != "" {
+		if t, err := http.ParseTime(lm); err == nil {
+			os.Chtimes(tmpFile, time.Now(), t)
+		}
+	}
+	return os.Rename(tmpFile, localFile)
+}
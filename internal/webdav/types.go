@@ -0,0 +1,73 @@
+package webdav
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const DEFAULT_TIMEOUT = 60 // 默认超时时间，单位秒
+
+var ErrNotFound = errors.New("WebDAV文件不存在")
+
+// StatusError WebDAV接口返回了非预期的状态码
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("WebDAV %s %s 失败，状态码: %d", e.Method, e.Path, e.StatusCode)
+}
+
+// FileInfo 文件或目录信息
+type FileInfo struct {
+	Name        string    `json:"name"`
+	Path        string    `json:"path"` // 相对于BaseUrl的完整路径，以/开头
+	IsDir       bool      `json:"is_dir"`
+	Size        int64     `json:"size"`
+	ModTime     time.Time `json:"mod_time"`
+	ETag        string    `json:"etag"`
+	ContentType string    `json:"content_type"`
+}
+
+// PROPFIND请求体，只查询需要的属性
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:">
+  <d:prop>
+    <d:resourcetype/>
+    <d:getcontentlength/>
+    <d:getlastmodified/>
+    <d:getetag/>
+    <d:getcontenttype/>
+  </d:prop>
+</d:propfind>`
+
+type multiStatus struct {
+	XMLName   xml.Name           `xml:"DAV: multistatus"`
+	Responses []propfindResponse `xml:"DAV: response"`
+}
+
+type propfindResponse struct {
+	Href      string     `xml:"DAV: href"`
+	PropStats []propStat `xml:"DAV: propstat"`
+}
+
+type propStat struct {
+	Prop   prop   `xml:"DAV: prop"`
+	Status string `xml:"DAV: status"`
+}
+
+type prop struct {
+	ResourceType  resourceType `xml:"DAV: resourcetype"`
+	ContentLength string       `xml:"DAV: getcontentlength"`
+	LastModified  string       `xml:"DAV: getlastmodified"`
+	ETag          string       `xml:"DAV: getetag"`
+	ContentType   string       `xml:"DAV: getcontenttype"`
+}
+
+type resourceType struct {
+	Collection *struct{} `xml:"DAV: collection"`
+}
@@ -0,0 +1,336 @@
+package webdavserver
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+const (
+	ModeStrm  = "strm"  // 原样提供.strm文件，适合能解析STRM的播放器
+	ModeVideo = "video" // 把.strm伪装成视频文件，播放时302到STRM里的直链地址
+)
+
+// Root 顶层虚拟目录，对应一个同步目录的本地路径
+type Root struct {
+	Name string // 顶层目录名
+	Path string // 本地路径
+}
+
+// SizeFunc 查询虚拟视频文件的大小，参数是.strm文件的本地路径（/分隔），查不到的不返回
+type SizeFunc func(strmPaths []string) map[string]int64
+
+// FileSystem 只读的WebDAV文件系统，根目录列出所有同步目录，所有写操作都返回没有权限
+type FileSystem struct {
+	roots  []Root
+	mode   string
+	sizeOf SizeFunc
+}
+
+func NewFileSystem(roots []Root, mode string, sizeOf SizeFunc) *FileSystem {
+	if mode != ModeVideo {
+		mode = ModeStrm
+	}
+	return &FileSystem{roots: roots, mode: mode, sizeOf: sizeOf}
+}
+
+func (f *FileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	return os.ErrPermission
+}
+
+func (f *FileSystem) RemoveAll(ctx context.Context, name string) error {
+	return os.ErrPermission
+}
+
+func (f *FileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	return os.ErrPermission
+}
+
+func (f *FileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+		return nil, os.ErrPermission
+	}
+	name = cleanName(name)
+	if name == "/" {
+		return &rootDir{fs: f}, nil
+	}
+	localPath, err := f.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if v, err := f.virtualVideo(name, localPath); err == nil {
+		return &virtualFile{info: v}, nil
+	}
+	file, err := os.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	return &localFile{File: file, fs: f, localPath: localPath, name: path.Base(name)}, nil
+}
+
+func (f *FileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	name = cleanName(name)
+	if name == "/" {
+		return &dirInfo{name: "/"}, nil
+	}
+	localPath, err := f.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	if v, err := f.virtualVideo(name, localPath); err == nil {
+		return v, nil
+	}
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return nil, err
+	}
+	return &namedInfo{FileInfo: info, name: path.Base(name)}, nil
+}
+
+// StrmUrl 虚拟视频文件对应的直链地址，不是虚拟视频文件时返回false
+func (f *FileSystem) StrmUrl(name string) (string, bool) {
+	name = cleanName(name)
+	localPath, err := f.resolve(name)
+	if err != nil {
+		return "", false
+	}
+	v, err := f.virtualVideo(name, localPath)
+	if err != nil {
+		return "", false
+	}
+	return v.url, true
+}
+
+func cleanName(name string) string {
+	return path.Clean("/" + strings.ReplaceAll(name, "\\", "/"))
+}
+
+// 把WebDAV路径转换成本地路径，第一级是同步目录名
+func (f *FileSystem) resolve(name string) (string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(name, "/"), "/", 2)
+	for _, root := range f.roots {
+		if root.Name != parts[0] {
+			continue
+		}
+		if len(parts) == 1 {
+			return root.Path, nil
+		}
+		return filepath.Join(root.Path, filepath.FromSlash(parts[1])), nil
+	}
+	return "", os.ErrNotExist
+}
+
+// 视频模式下，xxx.mkv不存在但同目录有指向.mkv直链的xxx.strm时，返回虚拟视频文件
+func (f *FileSystem) virtualVideo(name, localPath string) (*virtualInfo, error) {
+	if f.mode != ModeVideo {
+		return nil, os.ErrNotExist
+	}
+	ext := filepath.Ext(localPath)
+	if ext == "" || strings.EqualFold(ext, ".strm") {
+		return nil, os.ErrNotExist
+	}
+	if _, err := os.Lstat(localPath); err == nil {
+		// 真实文件优先
+		return nil, os.ErrNotExist
+	}
+	strmPath := strings.TrimSuffix(localPath, ext) + ".strm"
+	info, err := os.Stat(strmPath)
+	if err != nil || info.IsDir() {
+		return nil, os.ErrNotExist
+	}
+	strmUrl, videoExt := readStrm(strmPath)
+	if strmUrl == "" || videoExt != ext {
+		return nil, os.ErrNotExist
+	}
+	v := &virtualInfo{name: path.Base(name), url: strmUrl, modTime: info.ModTime()}
+	if f.sizeOf != nil {
+		key := filepath.ToSlash(strmPath)
+		v.size = f.sizeOf([]string{key})[key]
+	}
+	return v, nil
+}
+
+// 读取STRM文件中的直链地址和视频扩展名，只有http(s)地址才能作为虚拟视频文件
+// 本服务生成的地址路径最后一段是video.扩展名，其他地址从路径中取扩展名
+func readStrm(strmPath string) (string, string) {
+	data, err := os.ReadFile(strmPath)
+	if err != nil {
+		return "", ""
+	}
+	strmUrl := strings.TrimSpace(string(data))
+	u, err := url.Parse(strmUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", ""
+	}
+	ext := path.Ext(u.Path)
+	if ext == "" || strings.Contains(ext, "/") {
+		return "", ""
+	}
+	return strmUrl, ext
+}
+
+// 把目录项中的.strm转换成虚拟视频文件
+func (f *FileSystem) convertEntries(dir string, infos []os.FileInfo) []os.FileInfo {
+	if f.mode != ModeVideo {
+		return infos
+	}
+	result := make([]os.FileInfo, 0, len(infos))
+	virtuals := make(map[string]*virtualInfo)
+	strmPaths := make([]string, 0)
+	for _, info := range infos {
+		if info.IsDir() || !strings.EqualFold(filepath.Ext(info.Name()), ".strm") {
+			result = append(result, info)
+			continue
+		}
+		strmPath := filepath.Join(dir, info.Name())
+		strmUrl, ext := readStrm(strmPath)
+		if strmUrl == "" {
+			result = append(result, info)
+			continue
+		}
+		name := strings.TrimSuffix(info.Name(), filepath.Ext(info.Name())) + ext
+		if _, err := os.Lstat(filepath.Join(dir, name)); err == nil {
+			// 同名的真实文件已经在列表中
+			continue
+		}
+		key := filepath.ToSlash(strmPath)
+		v := &virtualInfo{name: name, url: strmUrl, modTime: info.ModTime()}
+		virtuals[key] = v
+		strmPaths = append(strmPaths, key)
+		result = append(result, v)
+	}
+	if f.sizeOf != nil && len(strmPaths) > 0 {
+		for key, size := range f.sizeOf(strmPaths) {
+			if v, ok := virtuals[key]; ok {
+				v.size = size
+			}
+		}
+	}
+	return result
+}
+
+// 根目录，列出所有同步目录
+type rootDir struct {
+	fs     *FileSystem
+	offset int
+}
+
+func (d *rootDir) Close() error                                 { return nil }
+func (d *rootDir) Read(p []byte) (int, error)                   { return 0, os.ErrInvalid }
+func (d *rootDir) Seek(offset int64, whence int) (int64, error) { return 0, nil }
+func (d *rootDir) Write(p []byte) (int, error)                  { return 0, os.ErrPermission }
+func (d *rootDir) Stat() (os.FileInfo, error)                   { return &dirInfo{name: "/"}, nil }
+
+func (d *rootDir) Readdir(count int) ([]os.FileInfo, error) {
+	infos := make([]os.FileInfo, 0, len(d.fs.roots))
+	for _, root := range d.fs.roots {
+		info, err := os.Stat(root.Path)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		infos = append(infos, &dirInfo{name: root.Name, modTime: info.ModTime()})
+	}
+	if d.offset >= len(infos) {
+		if count > 0 {
+			return nil, io.EOF
+		}
+		return []os.FileInfo{}, nil
+	}
+	infos = infos[d.offset:]
+	if count > 0 && count < len(infos) {
+		infos = infos[:count]
+	}
+	d.offset += len(infos)
+	return infos, nil
+}
+
+// 本地文件或目录，只读
+type localFile struct {
+	*os.File
+	fs        *FileSystem
+	localPath string
+	name      string
+}
+
+func (l *localFile) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+func (l *localFile) Stat() (os.FileInfo, error) {
+	info, err := l.File.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &namedInfo{FileInfo: info, name: l.name}, nil
+}
+
+func (l *localFile) Readdir(count int) ([]os.FileInfo, error) {
+	infos, err := l.File.Readdir(count)
+	if err != nil {
+		return infos, err
+	}
+	return l.fs.convertEntries(l.localPath, infos), nil
+}
+
+// 虚拟视频文件，内容由调用方302到直链，这里不提供内容
+type virtualFile struct {
+	info *virtualInfo
+}
+
+func (v *virtualFile) Close() error                                 { return nil }
+func (v *virtualFile) Read(p []byte) (int, error)                   { return 0, io.EOF }
+func (v *virtualFile) Seek(offset int64, whence int) (int64, error) { return 0, nil }
+func (v *virtualFile) Write(p []byte) (int, error)                  { return 0, os.ErrPermission }
+func (v *virtualFile) Stat() (os.FileInfo, error)                   { return v.info, nil }
+func (v *virtualFile) Readdir(count int) ([]os.FileInfo, error)     { return nil, os.ErrInvalid }
+
+// 使用WebDAV中的名称，顶层目录的名称和本地目录名不一定相同
+type namedInfo struct {
+	os.FileInfo
+	name string
+}
+
+func (n *namedInfo) Name() string { return n.name }
+
+type virtualInfo struct {
+	name    string
+	url     string
+	size    int64
+	modTime time.Time
+}
+
+func (v *virtualInfo) Name() string       { return v.name }
+func (v *virtualInfo) Size() int64        { return v.size }
+func (v *virtualInfo) Mode() fs.FileMode  { return 0444 }
+func (v *virtualInfo) ModTime() time.Time { return v.modTime }
+func (v *virtualInfo) IsDir() bool        { return false }
+func (v *virtualInfo) Sys() any           { return nil }
+
+// 内容类型按扩展名判断，避免webdav打开文件嗅探内容
+func (v *virtualInfo) ContentType(ctx context.Context) (string, error) {
+	if ctype := mime.TypeByExtension(filepath.Ext(v.name)); ctype != "" {
+		return ctype, nil
+	}
+	return "application/octet-stream", nil
+}
+
+type dirInfo struct {
+	name    string
+	modTime time.Time
+}
+
+func (d *dirInfo) Name() string       { return d.name }
+func (d *dirInfo) Size() int64        { return 0 }
+func (d *dirInfo) Mode() fs.FileMode  { return fs.ModeDir | 0555 }
+func (d *dirInfo) ModTime() time.Time { return d.modTime }
+func (d *dirInfo) IsDir() bool        { return true }
+func (d *dirInfo) Sys() any           { return nil }
@@ -0,0 +1,109 @@
+package webdavserver
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestFileSystem(t *testing.T, mode string) (*FileSystem, string) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "电影", "Movie (2020).strm"), "http://127.0.0.1:12333/115/url/video.mkv?pickcode=abc&userid=1\n")
+	writeTestFile(t, filepath.Join(dir, "电影", "Movie (2020).nfo"), "<movie></movie>")
+	writeTestFile(t, filepath.Join(dir, "电影", "Local.strm"), "/mnt/media/Local.mp4")
+	sizeOf := func(paths []string) map[string]int64 {
+		sizes := make(map[string]int64)
+		for _, p := range paths {
+			sizes[p] = 1024
+		}
+		return sizes
+	}
+	return NewFileSystem([]Root{{Name: "media", Path: dir}}, mode, sizeOf), dir
+}
+
+func readdirNames(t *testing.T, fs *FileSystem, name string) map[string]int64 {
+	t.Helper()
+	f, err := fs.OpenFile(context.Background(), name, os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("打开目录失败: %v", err)
+	}
+	defer f.Close()
+	infos, err := f.Readdir(0)
+	if err != nil {
+		t.Fatalf("列出目录失败: %v", err)
+	}
+	names := make(map[string]int64)
+	for _, info := range infos {
+		names[info.Name()] = info.Size()
+	}
+	return names
+}
+
+func TestStrmMode(t *testing.T) {
+	fs, _ := newTestFileSystem(t, ModeStrm)
+	if names := readdirNames(t, fs, "/"); len(names) != 1 {
+		t.Fatalf("根目录应该只有一个同步目录: %v", names)
+	}
+	if info, err := fs.Stat(context.Background(), "/media"); err != nil || info.Name() != "media" || !info.IsDir() {
+		t.Errorf("顶层目录名称错误: %v %v", info, err)
+	}
+	names := readdirNames(t, fs, "/media/电影")
+	if _, ok := names["Movie (2020).strm"]; !ok || len(names) != 3 {
+		t.Errorf("strm模式应该原样列出文件: %v", names)
+	}
+	if _, ok := fs.StrmUrl("/media/电影/Movie (2020).mkv"); ok {
+		t.Errorf("strm模式不应该有虚拟视频文件")
+	}
+}
+
+func TestVideoMode(t *testing.T) {
+	fs, _ := newTestFileSystem(t, ModeVideo)
+	names := readdirNames(t, fs, "/media/电影")
+	if size, ok := names["Movie (2020).mkv"]; !ok || size != 1024 {
+		t.Errorf("视频模式应该列出虚拟视频文件: %v", names)
+	}
+	if _, ok := names["Local.strm"]; !ok {
+		t.Errorf("不是http地址的STRM应该原样列出: %v", names)
+	}
+	info, err := fs.Stat(context.Background(), "/media/电影/Movie (2020).mkv")
+	if err != nil || info.Size() != 1024 {
+		t.Errorf("查询虚拟视频文件失败: %v %v", info, err)
+	}
+	u, ok := fs.StrmUrl("/media/电影/Movie (2020).mkv")
+	if !ok || u != "http://127.0.0.1:12333/115/url/video.mkv?pickcode=abc&userid=1" {
+		t.Errorf("虚拟视频文件直链错误: %s", u)
+	}
+	if _, ok := fs.StrmUrl("/media/电影/Movie (2020).mp4"); ok {
+		t.Errorf("扩展名不匹配时不应该是虚拟视频文件")
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	fs, dir := newTestFileSystem(t, ModeStrm)
+	ctx := context.Background()
+	if _, err := fs.OpenFile(ctx, "/media/new.strm", os.O_RDWR|os.O_CREATE, 0666); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("写入应该被拒绝: %v", err)
+	}
+	if err := fs.RemoveAll(ctx, "/media/电影"); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("删除应该被拒绝: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "电影")); err != nil {
+		t.Errorf("目录不应该被删除: %v", err)
+	}
+	// ..不能跳出同步目录
+	if _, err := fs.Stat(ctx, "/media/../../etc/passwd"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("路径越界应该返回不存在: %v", err)
+	}
+}
@@ -5,11 +5,13 @@ import (
 	"Q115-STRM/emby302/util/logs/colors"
 	"Q115-STRM/emby302/web"
 	"Q115-STRM/internal/backup"
+	"Q115-STRM/internal/configio"
 	"Q115-STRM/internal/controllers"
 	"Q115-STRM/internal/db"
 	"Q115-STRM/internal/db/database"
 	"Q115-STRM/internal/helpers"
 	"Q115-STRM/internal/models"
+	"Q115-STRM/internal/openai"
 	"Q115-STRM/internal/synccron"
 	"Q115-STRM/internal/v115open"
 	"context"
@@ -38,6 +40,7 @@ var DEFAULT_TMDB_API_KEY = ""
 var DEFAULT_SC_API_KEY = ""
 var ENCRYPTION_KEY = ""
 var Update bool = false
+var ImportConfigFile string = "" // 启动时导入的YAML配置文件，可以把配置放在git中管理
 
 var AppName string = "QMediaSync"
 var QMSApp *App
@@ -342,6 +345,9 @@ func startEmby302() {
 		return
 	}
 	config.C.Emby.Host = models.GlobalEmbyConfig.EmbyUrl
+	if models.GlobalEmbyConfig.ServerType == string(config.ServerTypeJellyfin) {
+		config.C.Emby.ServerType = config.ServerTypeJellyfin
+	}
 	config.C.Emby.EpisodesUnplayPrior = false // 关闭剧集排序
 	certFile := filepath.Join(dataRoot, "server.crt")
 	keyFile := filepath.Join(dataRoot, "server.key")
@@ -383,7 +389,8 @@ func initOthers() {
 	if qps <= 0 {
 		qps = 2
 	}
-	v115open.SetGlobalExecutorConfig(qps, qps*60, qps*3600)
+	v115open.SetDefaultExecutorConfig(qps, qps*60, qps*3600)
+	models.LoadQueueRateLimits()         // 恢复115账号单独设置的请求队列速率限制
 	models.LoadScrapeSettings()          // 从数据库加载刮削设置
 	models.InitDQ()                      // 初始化下载队列
 	models.InitUQ()                      // 初始化上传队列
@@ -392,29 +399,33 @@ func initOthers() {
 	models.GetEmbyConfig()               // 加载Emby配置
 	helpers.SubscribeSync(helpers.V115TokenInValidEvent, models.HandleV115TokenInvalid)
 	helpers.SubscribeSync(helpers.SaveOpenListTokenEvent, models.HandleOpenListTokenSaveSync)
+	helpers.SubscribeSync(helpers.Save123TokenEvent, models.HandleOpen123TokenSaveSync)
 	models.FailAllRunningSyncTasks()   // 将所有运行中的同步任务设置为失败状态
 	synccron.RefreshOAuthAccessToken() // 启动时刷新一次115的访问凭证，防止有过期的token导致同步失败
 
 	// 设置115请求队列的统计保存回调函数
-	v115open.SetGlobalExecutorStatSaver(func(requestTime int64, url, method string, duration int64, isThrottled bool) {
+	v115open.SetExecutorStatSaver(func(accountId uint, requestTime int64, url, method string, duration int64, isThrottled bool) {
 		stat := &models.RequestStat{
 			RequestTime: requestTime,
 			URL:         url,
 			Method:      method,
 			Duration:    duration,
 			IsThrottled: isThrottled,
-			AccountID:   0, // 可以后续扩展传入账号ID
+			AccountID:   accountId,
 		}
 		if err := models.CreateRequestStat(stat); err != nil {
 			helpers.V115Log.Errorf("写入请求统计失败: %v", err)
 		}
 	})
+	// 累计AI识别的token用量
+	openai.SetUsageRecorder(models.RecordAiUsage)
 
 	// if helpers.IsRelease {
 	// 启动同步任务队列管理器
 	synccron.InitNewSyncQueueManager()
-	synccron.InitCron()     // 初始化定时任务（包含备份定时任务）
-	synccron.InitSyncCron() // 初始化同步目录的定时任务
+	synccron.InitCron()          // 初始化定时任务（包含备份定时任务）
+	synccron.InitSyncCron()      // 初始化同步目录的定时任务
+	synccron.InitLocalWatchers() // 启动本地同步目录的实时监控
 	// 初始化备份服务
 	models.InitBackupService()
 	// }
@@ -457,10 +468,25 @@ func setRouter(r *gin.Engine) {
 	r.GET("/115/url/*filename", controllers.Get115UrlByPickCode)           // 查询115直链 by pickcode 支持iso，路径最后一部分是.扩展名格式
 	r.GET("/115/newurl", controllers.Get115UrlByPickCode)                  // 查询115直链 by pickcode
 	r.GET("/baidupan/url/*filename", controllers.GetBaiduPanUrlByPickCode) // 查询百度网盘直链 by fsid 支持iso，路径最后一部分是.扩展名格式
+	r.GET("/123/url/*filename", controllers.Get123UrlByPickCode)           // 查询123云盘直链 by fileId 支持iso，路径最后一部分是.扩展名格式
+
+	r.GET("/openlist/url", controllers.GetOpenListFileUrl)        // 查询OpenList直链
+	r.GET("/webdav/url/*filename", controllers.GetWebDavFileUrl)  // 播放WebDAV文件，由本服务转发文件内容
+	r.HEAD("/webdav/url/*filename", controllers.GetWebDavFileUrl) // 播放器探测文件信息
+	r.GET("/s3/url/*filename", controllers.GetS3UrlByPickCode)    // 跳转到S3预签名地址 支持iso，路径最后一部分是.扩展名格式
+
+	r.GET("/proxy-115", controllers.Proxy115)  // 115CDN反代路由
+	r.HEAD("/proxy-115", controllers.Proxy115) // 播放器探测文件信息
 
-	r.GET("/openlist/url", controllers.GetOpenListFileUrl) // 查询OpenList直链
+	r.GET("/metrics", controllers.Metrics) // Prometheus监控指标，使用管理员的API Key认证
 
-	r.GET("/proxy-115", controllers.Proxy115) // 115CDN反代路由
+	// 内置只读WebDAV服务，提供同步目录生成的STRM和元数据文件，使用API Key认证
+	for _, method := range []string{http.MethodOptions, http.MethodGet, http.MethodHead, "PROPFIND"} {
+		r.Handle(method, "/dav/*path", controllers.WebDavServer)
+	}
+	for _, method := range []string{http.MethodPut, http.MethodDelete, http.MethodPost, "MKCOL", "COPY", "MOVE", "PROPPATCH", "LOCK", "UNLOCK"} {
+		r.Handle(method, "/dav/*path", controllers.WebDavServerReadOnly)
+	}
 
 	r.GET("/api/scrape/tmp-image", controllers.ScrapeTmpImage)           // 获取临时图片
 	r.GET("/api/scrape/records/export", controllers.ExportScrapeRecords) // 导出刮削记录
@@ -474,6 +500,7 @@ func setRouter(r *gin.Engine) {
 	api := r.Group("/api")
 	api.Use(controllers.JWTAuthMiddleware())
 	{
+		adminOnly := controllers.RequireRole(models.UserRoleAdmin) // 仅管理员可以访问的接口
 		api.GET("/version", func(c *gin.Context) {
 			c.JSON(http.StatusOK, map[string]interface{}{
 				"version":   Version,
@@ -484,89 +511,111 @@ func setRouter(r *gin.Engine) {
 		})
 		// api.GET("/announce", controllers.GetAnnounce) // 获取公告
 
-		api.POST("/auth/115-qrcode-open", controllers.GetLoginQrCodeOpen) // 获取115开放平台登录二维码
-		api.POST("/auth/115-qrcode-status", controllers.GetQrCodeStatus)  // 查询115二维码扫码状态
-		api.GET("/115/status", controllers.Get115Status)                  // 查询115状态
-		api.GET("/115/oauth-url", controllers.GetOAuthUrl)                // 获取115 OAuth登录地址
-		api.POST("115/oauth-confirm", controllers.ConfirmOAuthCode)       // 确认OAuth登录
-		api.GET("/115/queue/stats", controllers.GetQueueStats)            // 获取115 OpenAPI请求队列统计数据
-		api.POST("/115/queue/rate-limit", controllers.SetQueueRateLimit)  // 设置115 OpenAPI请求队列速率限制
-		api.GET("/115/stats/daily", controllers.GetRequestStatsByDay)     // 获取115请求统计（按天）
-		api.GET("/115/stats/hourly", controllers.GetRequestStatsByHour)   // 获取115请求统计（按小时）
-		api.POST("/115/stats/clean", controllers.CleanOldRequestStats)    // 清理旧的请求统计数据
+		api.POST("/auth/115-qrcode-open", adminOnly, controllers.GetLoginQrCodeOpen) // 获取115开放平台登录二维码
+		api.POST("/auth/115-qrcode-status", adminOnly, controllers.GetQrCodeStatus)  // 查询115二维码扫码状态
+		api.GET("/115/status", controllers.Get115Status)                             // 查询115状态
+		api.GET("/115/oauth-url", adminOnly, controllers.GetOAuthUrl)                // 获取115 OAuth登录地址
+		api.POST("115/oauth-confirm", adminOnly, controllers.ConfirmOAuthCode)       // 确认OAuth登录
+		api.GET("/115/queue/stats", controllers.GetQueueStats)                       // 获取115 OpenAPI请求队列统计数据
+		api.POST("/115/queue/rate-limit", adminOnly, controllers.SetQueueRateLimit)  // 设置115 OpenAPI请求队列速率限制
+		api.GET("/115/stats/daily", controllers.GetRequestStatsByDay)                // 获取115请求统计（按天）
+		api.GET("/115/stats/hourly", controllers.GetRequestStatsByHour)              // 获取115请求统计（按小时）
+		api.POST("/115/stats/clean", adminOnly, controllers.CleanOldRequestStats)    // 清理旧的请求统计数据
 		// 百度网盘相关路由
-		api.GET("/baidupan/oauth-url", controllers.GetBaiDuPanOAuthUrl)           // 获取百度网盘OAuth登录地址
-		api.POST("/baidupan/oauth-confirm", controllers.ConfirmBaiDuPanOAuthCode) // 确认百度网盘OAuth登录
-		api.GET("/baidupan/status", controllers.GetBaiDuPanStatus)                // 查询百度网盘状态
+		api.GET("/baidupan/oauth-url", adminOnly, controllers.GetBaiDuPanOAuthUrl)           // 获取百度网盘OAuth登录地址
+		api.POST("/baidupan/oauth-confirm", adminOnly, controllers.ConfirmBaiDuPanOAuthCode) // 确认百度网盘OAuth登录
+		api.GET("/baidupan/status", controllers.GetBaiDuPanStatus)                           // 查询百度网盘状态
+		api.GET("/123/status", controllers.Get123Status)                                     // 查询123云盘状态
 
-		api.GET("/update/last", controllers.GetLastRelease)         // 获取最新版本
-		api.POST("/update/to-version", controllers.UpdateToVersion) // 获取更新版本
-		api.GET("/update/progress", controllers.UpdateProgress)     // 获取更新进度
-		api.POST("/update/cancel", controllers.CancelUpdate)        // 取消更新
+		api.GET("/update/last", controllers.GetLastRelease)                    // 获取最新版本
+		api.POST("/update/to-version", adminOnly, controllers.UpdateToVersion) // 获取更新版本
+		api.GET("/update/progress", controllers.UpdateProgress)                // 获取更新进度
+		api.POST("/update/cancel", adminOnly, controllers.CancelUpdate)        // 取消更新
 
 		api.GET("/user/info", controllers.GetUserInfo)
-		api.GET("/path/list", controllers.GetPathList)     // 目录列表
-		api.POST("/path/create", controllers.CreateDir)    // 创建目录接口
-		api.GET("/path/files", controllers.GetNetFileList) // 查询网盘文件列表
+		api.GET("/path/list", adminOnly, controllers.GetPathList)     // 目录列表
+		api.POST("/path/create", adminOnly, controllers.CreateDir)    // 创建目录接口
+		api.GET("/path/files", adminOnly, controllers.GetNetFileList) // 查询网盘文件列表
 		api.POST("/user/change", controllers.ChangePassword)
-
-		api.POST("/setting/http-proxy", controllers.UpdateHttpProxy)    // 更改HTTP代理
-		api.GET("/setting/http-proxy", controllers.GetHttpProxy)        // 获取HTTP代理
-		api.POST("/setting/test-http-proxy", controllers.TestHttpProxy) // 测试HTTP代理
+		api.GET("/user/list", adminOnly, controllers.GetUserList)   // 用户列表
+		api.POST("/user/create", adminOnly, controllers.CreateUser) // 创建用户
+		api.POST("/user/update", adminOnly, controllers.UpdateUser) // 修改用户角色和权限
+		api.DELETE("/user/:id", adminOnly, controllers.DeleteUser)  // 删除用户
+
+		api.POST("/setting/http-proxy", adminOnly, controllers.UpdateHttpProxy)             // 更改HTTP代理
+		api.GET("/setting/http-proxy", adminOnly, controllers.GetHttpProxy)                 // 获取HTTP代理
+		api.GET("/setting/webdav-server", adminOnly, controllers.GetWebDavServerConfig)     // 获取内置WebDAV服务设置
+		api.GET("/setting/proxy", adminOnly, controllers.GetProxyConfig)                    // 获取本地代理白名单和带宽限制
+		api.POST("/setting/proxy", adminOnly, controllers.UpdateProxyConfig)                // 更新本地代理白名单和带宽限制
+		api.GET("/proxy/stats", adminOnly, controllers.GetProxyStats)                       // 本地代理实时统计
+		api.POST("/setting/webdav-server", adminOnly, controllers.UpdateWebDavServerConfig) // 更新内置WebDAV服务设置
+		api.POST("/setting/test-http-proxy", adminOnly, controllers.TestHttpProxy)          // 测试HTTP代理
 		// api.GET("/setting/telegram", controllers.GetTelegram)                                      // 获取telegram消息通知配置
 		// api.POST("/setting/telegram", controllers.UpdateTelegram)                                  // 更改telegram消息通知配置
 		// api.POST("/telegram/test", controllers.TestTelegram)                                       // 测试telegram连通性
-		api.GET("/setting/notification/channels", controllers.GetNotificationChannels)             // 获取所有通知渠道
-		api.POST("/setting/notification/channels/telegram", controllers.CreateTelegramChannel)     // 创建Telegram渠道
-		api.GET("/setting/notification/channels/telegram/:id", controllers.GetTelegramChannel)     // 查询Telegram渠道
-		api.PUT("/setting/notification/channels/telegram", controllers.UpdateTelegramChannel)      // 更新Telegram渠道
-		api.POST("/setting/notification/channels/meow", controllers.CreateMeoWChannel)             // 创建MeoW渠道
-		api.GET("/setting/notification/channels/meow/:id", controllers.GetMeoWChannel)             // 查询MeoW渠道
-		api.PUT("/setting/notification/channels/meow", controllers.UpdateMeoWChannel)              // 更新MeoW渠道
-		api.POST("/setting/notification/channels/bark", controllers.CreateBarkChannel)             // 创建Bark渠道
-		api.GET("/setting/notification/channels/bark/:id", controllers.GetBarkChannel)             // 查询Bark渠道
-		api.PUT("/setting/notification/channels/bark", controllers.UpdateBarkChannel)              // 更新Bark渠道
-		api.POST("/setting/notification/channels/serverchan", controllers.CreateServerChanChannel) // 创建Server酱渠道
-		api.GET("/setting/notification/channels/serverchan/:id", controllers.GetServerChanChannel) // 查询Server酱渠道
-		api.PUT("/setting/notification/channels/serverchan", controllers.UpdateServerChanChannel)  // 更新Server酱渠道
-		api.POST("/setting/notification/channels/webhook", controllers.CreateCustomWebhookChannel) // 创建自定义Webhook渠道
-		api.GET("/setting/notification/channels/webhook/:id", controllers.GetCustomWebhookChannel) // 查询自定义Webhook渠道
-		api.PUT("/setting/notification/channels/webhook", controllers.UpdateCustomWebhookChannel)  // 更新自定义Webhook渠道
-		api.POST("/setting/notification/channels/status", controllers.UpdateChannelStatus)         // 启用/禁用渠道
-		api.DELETE("/setting/notification/channels/:id", controllers.DeleteChannel)                // 删除渠道
-		api.GET("/setting/notification/rules", controllers.GetNotificationRules)                   // 获取通知规则
-		api.PUT("/setting/notification/rules", controllers.UpdateNotificationRule)                 // 更新通知规则
-		api.POST("/setting/notification/channels/test", controllers.TestChannelConnection)         // 测试通知渠道连接
-		api.GET("/setting/strm-config", controllers.GetStrmConfig)                                 // 获取STRM配置
-		api.POST("/setting/strm-config", controllers.UpdateStrmConfig)                             // 更新STRM配置
-		api.GET("/setting/cron", controllers.GetCronNextTime)                                      // 获取Cron表达式的下5次执行时间
-		api.POST("/setting/emby/parse", controllers.ParseEmby)                                     // 解析Emby媒体信息
-		api.GET("/setting/emby-config", controllers.GetEmbyConfig)                                 // 获取新的Emby配置
-		api.POST("/setting/emby-config", controllers.UpdateEmbyConfig)                             // 更新新的Emby配置
-		api.POST("/setting/threads", controllers.UpdateThreads)                                    // 更新线程数
-		api.GET("/setting/threads", controllers.GetThreads)                                        // 获取线程数
-
-		api.POST("/emby/sync/start", controllers.StartEmbySync)     // 手动启动Emby同步
-		api.GET("/emby/sync/status", controllers.GetEmbySyncStatus) // 获取Emby同步状态           // 删除媒体库与同步目录关联
-
-		api.POST("/sync/start", controllers.StartSync)                   // 启动同步
-		api.GET("/sync/records", controllers.GetSyncRecords)             // 同步列表
-		api.GET("/sync/task", controllers.GetSyncTask)                   // 获取同步任务详情
-		api.GET("/sync/path-list", controllers.GetSyncPathList)          // 获取同步路径列表
-		api.POST("/sync/path-add", controllers.AddSyncPath)              // 创建同步路径
-		api.POST("/sync/path-update", controllers.UpdateSyncPath)        // 更新同步路径
-		api.POST("/sync/path-delete", controllers.DeleteSyncPath)        // 删除同步路径
-		api.POST("/sync/path/stop", controllers.StopSyncByPath)          // 停止同步路径的同步任务
-		api.POST("/sync/path/start", controllers.StartSyncByPath)        // 启动同步路径的同步任务
-		api.POST("/sync/path/full-start", controllers.FullStart115Sync)  // 启动115的全量同步任务
-		api.POST("/sync/delete-records", controllers.DelSyncRecords)     // 批量删除同步记录
-		api.POST("/sync/path/toggle-cron", controllers.ToggleSyncByPath) // 关闭或开启同步目录的定时同步
-		api.GET("/sync/path/:id", controllers.GetSyncPathById)           // 获取同步路径详情
-
-		api.GET("/account/list", controllers.GetAccountList)             // 获取开放平台账号列表
-		api.POST("/account/add", controllers.CreateTmpAccount)           // 创建开放平台账号
-		api.POST("/account/delete", controllers.DeleteAccount)           // 删除开放平台账号
-		api.POST("/account/openlist", controllers.CreateOpenListAccount) // 创建openlist账号
+		api.GET("/setting/notification/channels", adminOnly, controllers.GetNotificationChannels)             // 获取所有通知渠道
+		api.POST("/setting/notification/channels/telegram", adminOnly, controllers.CreateTelegramChannel)     // 创建Telegram渠道
+		api.GET("/setting/notification/channels/telegram/:id", adminOnly, controllers.GetTelegramChannel)     // 查询Telegram渠道
+		api.PUT("/setting/notification/channels/telegram", adminOnly, controllers.UpdateTelegramChannel)      // 更新Telegram渠道
+		api.POST("/setting/notification/channels/meow", adminOnly, controllers.CreateMeoWChannel)             // 创建MeoW渠道
+		api.GET("/setting/notification/channels/meow/:id", adminOnly, controllers.GetMeoWChannel)             // 查询MeoW渠道
+		api.PUT("/setting/notification/channels/meow", adminOnly, controllers.UpdateMeoWChannel)              // 更新MeoW渠道
+		api.POST("/setting/notification/channels/bark", adminOnly, controllers.CreateBarkChannel)             // 创建Bark渠道
+		api.GET("/setting/notification/channels/bark/:id", adminOnly, controllers.GetBarkChannel)             // 查询Bark渠道
+		api.PUT("/setting/notification/channels/bark", adminOnly, controllers.UpdateBarkChannel)              // 更新Bark渠道
+		api.POST("/setting/notification/channels/serverchan", adminOnly, controllers.CreateServerChanChannel) // 创建Server酱渠道
+		api.GET("/setting/notification/channels/serverchan/:id", adminOnly, controllers.GetServerChanChannel) // 查询Server酱渠道
+		api.PUT("/setting/notification/channels/serverchan", adminOnly, controllers.UpdateServerChanChannel)  // 更新Server酱渠道
+		api.POST("/setting/notification/channels/webhook", adminOnly, controllers.CreateCustomWebhookChannel) // 创建自定义Webhook渠道
+		api.GET("/setting/notification/channels/webhook/:id", adminOnly, controllers.GetCustomWebhookChannel) // 查询自定义Webhook渠道
+		api.PUT("/setting/notification/channels/webhook", adminOnly, controllers.UpdateCustomWebhookChannel)  // 更新自定义Webhook渠道
+		api.POST("/setting/notification/channels/status", adminOnly, controllers.UpdateChannelStatus)         // 启用/禁用渠道
+		api.DELETE("/setting/notification/channels/:id", adminOnly, controllers.DeleteChannel)                // 删除渠道
+		api.GET("/setting/notification/rules", adminOnly, controllers.GetNotificationRules)                   // 获取通知规则
+		api.PUT("/setting/notification/rules", adminOnly, controllers.UpdateNotificationRule)                 // 更新通知规则
+		api.POST("/setting/notification/channels/test", adminOnly, controllers.TestChannelConnection)         // 测试通知渠道连接
+		api.GET("/setting/strm-config", controllers.GetStrmConfig)                                            // 获取STRM配置
+		api.POST("/setting/strm-config", adminOnly, controllers.UpdateStrmConfig)                             // 更新STRM配置
+		api.GET("/setting/cron", controllers.GetCronNextTime)                                                 // 获取Cron表达式的下5次执行时间
+		api.POST("/setting/emby/parse", adminOnly, controllers.ParseEmby)                                     // 解析Emby媒体信息
+		api.GET("/setting/emby-config", adminOnly, controllers.GetEmbyConfig)                                 // 获取新的Emby配置
+		api.POST("/setting/emby-config", adminOnly, controllers.UpdateEmbyConfig)                             // 更新新的Emby配置
+		api.POST("/setting/threads", adminOnly, controllers.UpdateThreads)                                    // 更新线程数
+		api.GET("/setting/threads", adminOnly, controllers.GetThreads)                                        // 获取线程数
+
+		api.POST("/emby/sync/start", adminOnly, controllers.StartEmbySync)                            // 手动启动Emby同步
+		api.GET("/emby/sync/status", controllers.GetEmbySyncStatus)                                   // 获取Emby同步状态           // 删除媒体库与同步目录关联
+		api.GET("/emby/pending-deletions", adminOnly, controllers.GetEmbyPendingDeletions)            // 待删除的网盘文件列表
+		api.POST("/emby/pending-deletions/cancel", adminOnly, controllers.CancelEmbyPendingDeletions) // 取消待删除的网盘文件
+
+		api.POST("/sync/start", adminOnly, controllers.StartSync)               // 启动同步
+		api.GET("/sync/records", controllers.GetSyncRecords)                    // 同步列表
+		api.GET("/sync/task", controllers.GetSyncTask)                          // 获取同步任务详情
+		api.GET("/sync/path-list", controllers.GetSyncPathList)                 // 获取同步路径列表
+		api.POST("/sync/path-add", adminOnly, controllers.AddSyncPath)          // 创建同步路径
+		api.POST("/sync/path-update", controllers.UpdateSyncPath)               // 更新同步路径
+		api.POST("/sync/path-delete", adminOnly, controllers.DeleteSyncPath)    // 删除同步路径
+		api.POST("/sync/path/stop", controllers.StopSyncByPath)                 // 停止同步路径的同步任务
+		api.POST("/sync/path/start", controllers.StartSyncByPath)               // 启动同步路径的同步任务
+		api.POST("/sync/path/full-start", controllers.FullStart115Sync)         // 启动115的全量同步任务
+		api.POST("/sync/delete-records", adminOnly, controllers.DelSyncRecords) // 批量删除同步记录
+		api.POST("/sync/path/toggle-cron", controllers.ToggleSyncByPath)        // 关闭或开启同步目录的定时同步
+		api.POST("/sync/path/toggle-watch", controllers.ToggleWatchByPath)      // 关闭或开启本地同步目录的实时监控
+		api.POST("/sync/path/preview", controllers.StartSyncPreview)            // 启动同步预览（不写入文件）
+		api.GET("/sync/path/preview", controllers.GetSyncPreview)               // 获取同步预览的变更计划
+		api.GET("/sync/path/:id", controllers.GetSyncPathById)                  // 获取同步路径详情
+
+		api.GET("/sync/duplicates", adminOnly, controllers.GetDuplicateReport)           // 查询重复文件扫描结果
+		api.POST("/sync/duplicates/scan", adminOnly, controllers.StartDuplicateScan)     // 启动重复文件扫描
+		api.POST("/sync/duplicates/delete", adminOnly, controllers.DeleteDuplicateFiles) // 预览或删除选中的重复文件
+
+		api.GET("/account/list", adminOnly, controllers.GetAccountList)             // 获取开放平台账号列表
+		api.POST("/account/add", adminOnly, controllers.CreateTmpAccount)           // 创建开放平台账号
+		api.POST("/account/delete", adminOnly, controllers.DeleteAccount)           // 删除开放平台账号
+		api.POST("/account/openlist", adminOnly, controllers.CreateOpenListAccount) // 创建openlist账号
+		api.POST("/account/123", adminOnly, controllers.Create123Account)           // 创建或更新123云盘账号
+		api.POST("/account/webdav", adminOnly, controllers.CreateWebDavAccount)     // 创建或更新WebDAV账号
+		api.POST("/account/s3", adminOnly, controllers.CreateS3Account)             // 创建或更新S3兼容对象存储账号
 
 		// API Key管理接口
 		api.POST("/api-keys", controllers.CreateAPIKey)                 // 创建API Key
@@ -574,66 +623,87 @@ func setRouter(r *gin.Engine) {
 		api.PUT("/api-keys/:id/status", controllers.UpdateAPIKeyStatus) // 更新API Key状态
 		api.DELETE("/api-keys/:id", controllers.DeleteAPIKey)           // 删除API Key
 
-		api.GET("/scrape/movie-genre", controllers.GetMovieGenre)                     // 获取电影类别
-		api.GET("/scrape/tvshow-genre", controllers.GetTvshowGenre)                   // 获取电视剧类别
-		api.GET("/scrape/language", controllers.GetLanguage)                          // 获取语言数组
-		api.GET("/scrape/countries", controllers.GetCountries)                        // 获取国家数组
-		api.GET("/scrape/tmdb", controllers.GetTmdbSettings)                          // 获取TMDB设置
-		api.POST("/scrape/tmdb", controllers.SaveTmdbSettings)                        // 保存TMDB设置
-		api.POST("/scrape/tmdb-test", controllers.TestTmdbSettings)                   // 测试TMDB设置
-		api.GET("/scrape/ai-settings", controllers.GetAiSettings)                     // 获取AI识别设置
-		api.POST("/scrape/ai-settings", controllers.SaveAiSettings)                   // 保存AI识别设置
-		api.POST("/scrape/ai-test", controllers.TestAiSettings)                       // 测试AI识别设置
-		api.GET("/scrape/movie-categories", controllers.GetMovieCategories)           // 获取电影分类列表
-		api.GET("/scrape/tvshow-categories", controllers.GetTvshowCategories)         // 获取电视剧分类列表
-		api.POST("/scrape/movie-categories", controllers.SaveMovieCategory)           // 保存电影分类
-		api.POST("/scrape/tvshow-categories", controllers.SaveTvshowCategory)         // 保存电视剧分类
-		api.DELETE("/scrape/movie-categories/:id", controllers.DeleteMovieCategory)   // 删除电影分类
-		api.DELETE("/scrape/tvshow-categories/:id", controllers.DeleteTvshowCategory) // 删除电视剧分类
-		api.GET("/scrape/pathes", controllers.GetScrapePathes)                        // 获取刮削路径列表
-		api.POST("/scrape/pathes", controllers.SaveScrapePath)                        // 保存刮削路径列表
-		api.DELETE("/scrape/pathes/:id", controllers.DeleteScrapePath)                // 删除刮削路径
-		api.GET("/scrape/pathes/:id", controllers.GetScrapePath)                      // 获取刮削路径详情
-		api.POST("/scrape/pathes/start", controllers.ScanScrapePath)                  // 扫描刮削路径
-		api.POST("/scrape/pathes/stop", controllers.StopScrape)                       // 停止刮削任务
-		api.POST("/scrape/pathes/toggle-cron", controllers.ToggleScrapePathCron)      // 关闭或开启刮削路径的定时刮削
-		api.GET("/scrape/records", controllers.GetScrapeRecords)                      // 获取刮削记录
-		api.POST("/scrape/re-scrape", controllers.ReScrape)                           // 重新刮削记录
-		api.POST("/scrape/clear-failed", controllers.ClearFailedScrapeRecords)        // 清除所有刮削失败的记录
-		api.POST("/scrape/truncate-all", controllers.TruncateAllScrapeRecords)        // 一键清空所有刮削记录
-		api.DELETE("/scrape/records", controllers.DeleteScrapeMediaFile)              // 删除刮削记录
-		api.POST("/scrape/finish", controllers.FinishScrapeMediaFile)                 // 完成刮削记录
-		api.POST("/scrape/rename-failed", controllers.RenameFailedScrapeMediaFile)    // 标记所有失败的记录为待整理
-		api.POST("/scrape/sync-pathes", controllers.SaveScrapeStrmPath)               // 保存刮削目录关联的同步目录
-		api.GET("/scrape/sync-pathes", controllers.GetScrapeStrmPaths)                // 获取刮削目录关联的同步目录
-		api.GET("/scrape/tmdb-search", controllers.TmdbSearch)                        // 搜索TMDB媒体
-
-		api.GET("/upload/queue", controllers.UploadList)                                             // 获取上传队列列表
-		api.POST("/upload/queue/clear-pending", controllers.ClearPendingUploadTasks)                 // 清除上传队列中未开始的任务
-		api.POST("/upload/queue/start", controllers.StartUploadQueue)                                // 启动上传队列
-		api.POST("/upload/queue/stop", controllers.StopUploadQueue)                                  // 停止上传队列
-		api.GET("/upload/queue/status", controllers.UploadQueueStatus)                               // 查询上传队列状态
-		api.POST("/upload/queue/clear-success-failed", controllers.ClearUploadSuccessAndFailedTasks) // 清除上传队列中已完成和失败的任务
-		api.POST("/upload/queue/retry-failed", controllers.RetryFailedUploadTasks)                   // 重试所有失败的上传任务
-
-		api.GET("/download/queue", controllers.DownloadList)                                             // 获取下载队列列表
-		api.POST("/download/queue/clear-pending", controllers.ClearPendingDownloadTasks)                 // 清除下载队列中未开始的任务
-		api.POST("/download/queue/start", controllers.StartDownloadQueue)                                // 启动下载队列
-		api.POST("/download/queue/stop", controllers.StopDownloadQueue)                                  // 停止下载队列
-		api.GET("/download/queue/status", controllers.DownloadQueueStatus)                               // 查询下载队列状态
-		api.POST("/download/queue/clear-success-failed", controllers.ClearDownloadSuccessAndFailedTasks) // 清除下载队列中已完成和失败的任务
+		api.GET("/scrape/movie-genre", controllers.GetMovieGenre)                                   // 获取电影类别
+		api.GET("/scrape/tvshow-genre", controllers.GetTvshowGenre)                                 // 获取电视剧类别
+		api.GET("/scrape/language", controllers.GetLanguage)                                        // 获取语言数组
+		api.GET("/scrape/countries", controllers.GetCountries)                                      // 获取国家数组
+		api.GET("/scrape/tmdb", adminOnly, controllers.GetTmdbSettings)                             // 获取TMDB设置
+		api.POST("/scrape/tmdb", adminOnly, controllers.SaveTmdbSettings)                           // 保存TMDB设置
+		api.POST("/scrape/tmdb-test", adminOnly, controllers.TestTmdbSettings)                      // 测试TMDB设置
+		api.GET("/scrape/metadata-providers", adminOnly, controllers.GetMetadataProviderSettings)   // 获取元数据提供者设置
+		api.POST("/scrape/metadata-providers", adminOnly, controllers.SaveMetadataProviderSettings) // 保存元数据提供者设置
+		api.POST("/scrape/tvdb-test", adminOnly, controllers.TestTvdbSettings)                      // 测试TVDB设置
+		api.GET("/scrape/subtitle-settings", adminOnly, controllers.GetSubtitleSettings)            // 获取字幕下载设置
+		api.POST("/scrape/subtitle-settings", adminOnly, controllers.SaveSubtitleSettings)          // 保存字幕下载设置
+		api.POST("/scrape/subtitle-test", adminOnly, controllers.TestSubtitleSettings)              // 测试字幕提供者设置
+		api.GET("/scrape/ai-settings", adminOnly, controllers.GetAiSettings)                        // 获取AI识别设置
+		api.POST("/scrape/ai-settings", adminOnly, controllers.SaveAiSettings)                      // 保存AI识别设置
+		api.POST("/scrape/ai-test", adminOnly, controllers.TestAiSettings)                          // 测试AI识别设置
+		api.POST("/scrape/ai-usage/reset", adminOnly, controllers.ResetAiUsage)                     // 清空AI识别用量统计
+		api.GET("/scrape/category-rules", adminOnly, controllers.GetCategoryRules)                  // 获取分类规则列表
+		api.POST("/scrape/category-rules", adminOnly, controllers.SaveCategoryRule)                 // 新建或更新分类规则
+		api.DELETE("/scrape/category-rules/:id", adminOnly, controllers.DeleteCategoryRule)         // 删除分类规则
+		api.POST("/scrape/category-rules/dry-run", adminOnly, controllers.DryRunCategoryRules)      // 用刮削文件测试分类规则
+		api.POST("/scrape/name-template/preview", adminOnly, controllers.PreviewNameTemplate)       // 预览文件夹和文件名称模板
+		api.GET("/scrape/movie-categories", controllers.GetMovieCategories)                         // 获取电影分类列表
+		api.GET("/scrape/tvshow-categories", controllers.GetTvshowCategories)                       // 获取电视剧分类列表
+		api.POST("/scrape/movie-categories", adminOnly, controllers.SaveMovieCategory)              // 保存电影分类
+		api.POST("/scrape/tvshow-categories", adminOnly, controllers.SaveTvshowCategory)            // 保存电视剧分类
+		api.DELETE("/scrape/movie-categories/:id", adminOnly, controllers.DeleteMovieCategory)      // 删除电影分类
+		api.DELETE("/scrape/tvshow-categories/:id", adminOnly, controllers.DeleteTvshowCategory)    // 删除电视剧分类
+		api.GET("/scrape/pathes", controllers.GetScrapePathes)                                      // 获取刮削路径列表
+		api.POST("/scrape/pathes", controllers.SaveScrapePath)                                      // 保存刮削路径列表
+		api.DELETE("/scrape/pathes/:id", adminOnly, controllers.DeleteScrapePath)                   // 删除刮削路径
+		api.GET("/scrape/pathes/:id", controllers.GetScrapePath)                                    // 获取刮削路径详情
+		api.POST("/scrape/pathes/start", controllers.ScanScrapePath)                                // 扫描刮削路径
+		api.POST("/scrape/pathes/stop", controllers.StopScrape)                                     // 停止刮削任务
+		api.POST("/scrape/pathes/toggle-cron", controllers.ToggleScrapePathCron)                    // 关闭或开启刮削路径的定时刮削
+		api.GET("/scrape/records", controllers.GetScrapeRecords)                                    // 获取刮削记录
+		api.POST("/scrape/re-scrape", controllers.ReScrape)                                         // 重新刮削记录
+		api.POST("/scrape/clear-failed", adminOnly, controllers.ClearFailedScrapeRecords)           // 清除所有刮削失败的记录
+		api.POST("/scrape/truncate-all", adminOnly, controllers.TruncateAllScrapeRecords)           // 一键清空所有刮削记录
+		api.DELETE("/scrape/records", adminOnly, controllers.DeleteScrapeMediaFile)                 // 删除刮削记录
+		api.POST("/scrape/finish", controllers.FinishScrapeMediaFile)                               // 完成刮削记录
+		api.GET("/scrape/journals", adminOnly, controllers.GetScrapeJournals)                       // 获取整理日志
+		api.POST("/scrape/journals/rollback", adminOnly, controllers.StartScrapeJournalRollback)    // 按批次或时间范围批量撤销整理
+		api.GET("/scrape/journals/rollback", adminOnly, controllers.GetScrapeJournalRollback)       // 查询批量撤销结果
+		api.POST("/scrape/rename-failed", adminOnly, controllers.RenameFailedScrapeMediaFile)       // 标记所有失败的记录为待整理
+		api.POST("/scrape/sync-pathes", controllers.SaveScrapeStrmPath)                             // 保存刮削目录关联的同步目录
+		api.GET("/scrape/sync-pathes", controllers.GetScrapeStrmPaths)                              // 获取刮削目录关联的同步目录
+		api.GET("/scrape/tmdb-search", controllers.TmdbSearch)                                      // 搜索TMDB媒体
+
+		api.GET("/upload/queue", adminOnly, controllers.UploadList)                                             // 获取上传队列列表
+		api.POST("/upload/queue/clear-pending", adminOnly, controllers.ClearPendingUploadTasks)                 // 清除上传队列中未开始的任务
+		api.POST("/upload/queue/start", adminOnly, controllers.StartUploadQueue)                                // 启动上传队列
+		api.POST("/upload/queue/stop", adminOnly, controllers.StopUploadQueue)                                  // 停止上传队列
+		api.GET("/upload/queue/status", controllers.UploadQueueStatus)                                          // 查询上传队列状态
+		api.POST("/upload/queue/clear-success-failed", adminOnly, controllers.ClearUploadSuccessAndFailedTasks) // 清除上传队列中已完成和失败的任务
+		api.POST("/upload/queue/retry-failed", adminOnly, controllers.RetryFailedUploadTasks)                   // 重试所有失败的上传任务
+
+		api.GET("/download/queue", adminOnly, controllers.DownloadList)                                             // 获取下载队列列表
+		api.POST("/download/queue/clear-pending", adminOnly, controllers.ClearPendingDownloadTasks)                 // 清除下载队列中未开始的任务
+		api.POST("/download/queue/start", adminOnly, controllers.StartDownloadQueue)                                // 启动下载队列
+		api.POST("/download/queue/stop", adminOnly, controllers.StopDownloadQueue)                                  // 停止下载队列
+		api.GET("/download/queue/status", controllers.DownloadQueueStatus)                                          // 查询下载队列状态
+		api.POST("/download/queue/clear-success-failed", adminOnly, controllers.ClearDownloadSuccessAndFailedTasks) // 清除下载队列中已完成和失败的任务
 
 		// 备份与恢复相关路由
-		api.GET("/backup/list", controllers.GetBackupList)               // 获取备份列表
-		api.GET("/backup/records/:id", controllers.GetBackupRecord)      // 获取备份记录详情
-		api.POST("/backup/create", controllers.CreateBackup)             // 创建手动备份
-		api.DELETE("/backup/records/:id", controllers.DeleteBackup)      // 删除备份记录
-		api.POST("/backup/restore", controllers.RestoreFromBackup)       // 从备份恢复
-		api.POST("/backup/upload-restore", controllers.UploadAndRestore) // 上传文件并恢复
-		api.GET("/backup/download/:id", controllers.DownloadBackup)      // 下载备份文件
-		api.GET("/backup/config", controllers.GetBackupConfig)           // 获取备份配置
-		api.PUT("/backup/config", controllers.UpdateBackupConfig)        // 更新备份配置
-		api.GET("/backup/status", controllers.GetBackupStatus)           // 获取备份状态
+		api.GET("/backup/list", adminOnly, controllers.GetBackupList)                     // 获取备份列表
+		api.GET("/backup/records/:id", adminOnly, controllers.GetBackupRecord)            // 获取备份记录详情
+		api.POST("/backup/create", adminOnly, controllers.CreateBackup)                   // 创建手动备份
+		api.DELETE("/backup/records/:id", adminOnly, controllers.DeleteBackup)            // 删除备份记录
+		api.POST("/backup/restore", adminOnly, controllers.RestoreFromBackup)             // 从备份恢复
+		api.POST("/backup/upload-restore", adminOnly, controllers.UploadAndRestore)       // 上传文件并恢复
+		api.GET("/backup/download/:id", adminOnly, controllers.DownloadBackup)            // 下载备份文件
+		api.GET("/backup/config", adminOnly, controllers.GetBackupConfig)                 // 获取备份配置
+		api.PUT("/backup/config", adminOnly, controllers.UpdateBackupConfig)              // 更新备份配置
+		api.GET("/backup/status", adminOnly, controllers.GetBackupStatus)                 // 获取备份状态
+		api.GET("/backup/targets", adminOnly, controllers.GetBackupTargets)               // 获取异地备份目标列表
+		api.POST("/backup/targets", adminOnly, controllers.SaveBackupTarget)              // 新建或更新异地备份目标
+		api.DELETE("/backup/targets/:id", adminOnly, controllers.DeleteBackupTarget)      // 删除异地备份目标
+		api.GET("/backup/targets/:id/files", adminOnly, controllers.GetBackupTargetFiles) // 列出备份目标中的备份文件
+		api.GET("/config/export", adminOnly, controllers.ExportConfig)                    // 导出YAML配置，redact=0时包含密码和密钥
+		api.POST("/config/import", adminOnly, controllers.ImportConfig)                   // 导入YAML配置，plan=1时只返回导入计划
 
 	}
 }
@@ -715,16 +785,45 @@ func initEnv() bool {
 		return false
 	}
 	db.InitCache() // 初始化内存缓存
+	if ImportConfigFile != "" {
+		importConfigOnStartup(ImportConfigFile)
+	}
 	initOthers()
 	return true
 }
 
+// 启动时导入配置文件，配置文件有错误时不导入任何配置，继续使用数据库中的配置
+func importConfigOnStartup(file string) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		helpers.AppLogger.Errorf("读取配置文件 %s 失败: %v", file, err)
+		return
+	}
+	plan, err := configio.Import(data, true)
+	if err != nil {
+		helpers.AppLogger.Errorf("导入配置文件 %s 失败: %v", file, err)
+		return
+	}
+	for _, msg := range plan.Errors {
+		helpers.AppLogger.Errorf("配置文件 %s 存在错误: %s", file, msg)
+	}
+	for _, change := range plan.Changes {
+		if change.Action != configio.ActionUnchanged {
+			helpers.AppLogger.Infof("导入配置 %s %s: %s %v", change.Resource, change.Key, change.Action, change.Fields)
+		}
+	}
+	if plan.Applied {
+		helpers.AppLogger.Infof("已导入配置文件 %s", file)
+	}
+}
+
 func parseParams() {
 	// 定义 guid 参数
 	var update string
 	flag.StringVar(&helpers.Guid, "guid", "", "GUID 参数")
 	flag.BoolVar(&helpers.IsFnOS, "fnos", false, "是否是飞牛环境")
 	flag.StringVar(&update, "update", "", "更新参数")
+	flag.StringVar(&ImportConfigFile, "import-config", "", "启动时导入的YAML配置文件")
 	// 解析命令行参数
 	flag.Parse()
 	// 使用参数